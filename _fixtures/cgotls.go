@@ -0,0 +1,38 @@
+package main
+
+/*
+__thread int tlsvar;
+volatile int ready;
+volatile int stop;
+
+void set_tlsvar(int n) { tlsvar = n; }
+int get_tlsvar(void) { return tlsvar; }
+
+void spin(int n) {
+	tlsvar = n;
+	ready = 1;
+	while (!stop) {
+	}
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func main() {
+	runtime.LockOSThread()
+	C.set_tlsvar(1)
+	go func() {
+		runtime.LockOSThread()
+		C.spin(2)
+	}()
+	for C.ready == 0 {
+		runtime.Gosched()
+	}
+	runtime.Breakpoint()
+	fmt.Println(C.get_tlsvar())
+	C.stop = 1
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func main() {
+	ch1 := make(chan int, 10)
+	ch2 := make(chan int, 10)
+	runtime.Breakpoint()
+	ch2 <- 1
+	ch1 <- 2
+	v := <-ch1
+	close(ch1)
+	fmt.Println(v, <-ch2)
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func main() {
+	x, y := 1, "hello"
+	f := func() int {
+		x++
+		return x + len(y)
+	}
+	runtime.Breakpoint()
+	fmt.Println(f(), x)
+}
@@ -25,6 +25,13 @@ const (
 	bitFour
 )
 
+type FlagType uint64
+
+const (
+	flagLow  FlagType = 1
+	flagHigh FlagType = 1 << 63
+)
+
 func main() {
 	a := constTwo
 	b := constThree
@@ -32,7 +39,9 @@ func main() {
 	d := BitFieldType(33)
 	e := ConstType(10)
 	f := BitFieldType(0)
+	g := flagLow | flagHigh
+	h := flagHigh
 	runtime.Breakpoint()
 	pkg.SomeVar.AnotherMethod(2)
-	fmt.Println(a, b, c, d, e, f, pkg.SomeConst)
+	fmt.Println(a, b, c, d, e, f, g, h, pkg.SomeConst)
 }
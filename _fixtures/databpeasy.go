@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+var globalvar1 = 0
+var globalvar2 = 0
+
+func main() { // Position 0
+	runtime.LockOSThread()
+	globalvar1 = 2
+	fmt.Printf("%d\n", globalvar1) // Position 1
+	globalvar2 = globalvar1 + 1
+	globalvar1 = globalvar2 + 1
+	fmt.Printf("%d %d\n", globalvar1, globalvar2) // Position 2
+	fmt.Printf("done\n")                          // Position 3
+}
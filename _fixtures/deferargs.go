@@ -0,0 +1,21 @@
+package main
+
+import "runtime"
+
+func sum(a, b int) int {
+	return a + b
+}
+
+func greet(name string, n int) {
+	println(name, n)
+}
+
+func f(x int) {
+	defer sum(x, x*2)
+	defer greet("world", x+1)
+	runtime.Breakpoint()
+}
+
+func main() {
+	f(10)
+}
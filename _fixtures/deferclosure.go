@@ -0,0 +1,26 @@
+package main
+
+import "runtime"
+
+func greet(name string, n int) {
+	println(name, n)
+}
+
+func f(x int) {
+	name := "world"
+	y := x * 2
+	// defers in loops are not open-coded
+	for i := 0; i < 1; i++ {
+		defer func() {
+			greet(name, x)
+		}()
+		defer func() {
+			println(y)
+		}()
+	}
+	runtime.Breakpoint()
+}
+
+func main() {
+	f(10)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type node struct {
+	name string
+	val  int
+	next *node
+}
+
+type config struct {
+	Name    string
+	Retries int
+	Tags    []string
+	Limits  map[string]int
+	Head    *node
+}
+
+func main() {
+	a := &node{name: "a", val: 1}
+	a.next = a
+	b := &node{name: "a", val: 1}
+	b.next = b
+	c1 := config{Name: "c", Retries: 3, Tags: []string{"x", "y"}, Limits: map[string]int{"cpu": 1, "mem": 2}, Head: a}
+	c2 := config{Name: "c", Retries: 4, Tags: []string{"x", "z", "w"}, Limits: map[string]int{"cpu": 1, "disk": 3}, Head: b}
+	runtime.Breakpoint()
+	c1.Retries = 5
+	runtime.Breakpoint()
+	fmt.Println(c1.Name, c1.Retries, c2.Name)
+}
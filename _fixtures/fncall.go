@@ -27,11 +27,74 @@ func callpanic() {
 	panic("callpanic panicked")
 }
 
+type astruct struct {
+	X int
+}
+
+func (a astruct) VRcvrable(b int) int {
+	return a.X + b
+}
+
+func (pa *astruct) PRcvrable(b int) int {
+	return pa.X + b
+}
+
+func makeastruct(x int) astruct {
+	return astruct{X: x}
+}
+
+type bstruct struct {
+	astruct
+}
+
+type dstruct struct {
+	Y int
+}
+
+func (d dstruct) VRcvrable(b int) int {
+	return d.Y * b
+}
+
+// cstruct gets VRcvrable from dstruct, shallower than bstruct.astruct
+type cstruct struct {
+	bstruct
+	dstruct
+}
+
+// estruct has VRcvrable at the same depth in astruct and dstruct
+type estruct struct {
+	astruct
+	dstruct
+}
+
+type vrcvrabler interface {
+	VRcvrable(int) int
+}
+
+type customError struct {
+	msg string
+}
+
+func (err *customError) Error() string {
+	return "custom: " + err.msg
+}
+
 var zero = 0
 
 func main() {
 	one, two := 1, 2
+	a := astruct{X: 3}
+	pa := &astruct{X: 6}
+	pb := &bstruct{astruct{X: 9}}
+	pc := &cstruct{bstruct{astruct{X: 1}}, dstruct{Y: 4}}
+	e := estruct{astruct{X: 1}, dstruct{Y: 2}}
+	var vr vrcvrabler = pc
+	var ipc, ie interface{} = pc, e
+	var err error = &customError{"failed"}
+	m := map[string]int{"one": 1}
+	mi := map[int]int{1: 1}
+	s := "two"
 	runtime.Breakpoint()
 	call1(one, two)
-	fmt.Println(one, two, zero, callpanic, callstacktrace)
+	fmt.Println(one, two, zero, callpanic, callstacktrace, makeastruct, a, pa, pb, pc, e, vr, ipc, ie, err, m, mi, s)
 }
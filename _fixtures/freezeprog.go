@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+var frozenCount, otherCount int64
+
+func spinFrozen() {
+	for {
+		atomic.AddInt64(&frozenCount, 1)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func spinOther() {
+	for {
+		atomic.AddInt64(&otherCount, 1)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func main() {
+	go spinFrozen()
+	go spinOther()
+	time.Sleep(10 * time.Millisecond)
+	runtime.Breakpoint()
+	time.Sleep(100 * time.Millisecond)
+	runtime.Breakpoint()
+	fmt.Println(atomic.LoadInt64(&frozenCount), atomic.LoadInt64(&otherCount))
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type pair[K comparable, V any] struct {
+	k K
+	v V
+}
+
+func F[T any](x T) T {
+	y := []T{x, x}
+	p := pair[string, T]{"a", x}
+	px := &x
+	m := map[string]T{"a": x}
+	runtime.Breakpoint()
+	fmt.Println(y, p, px, m)
+	return x
+}
+
+type point struct{ X, Y int }
+
+func main() {
+	F(1)
+	F("hello")
+	F(point{1, 2})
+	F(&point{3, 4})
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"sync"
+)
+
+func handle(id string) {
+	fmt.Println("handling", id) // breakpoint here
+}
+
+func main() {
+	var wg sync.WaitGroup
+	for _, id := range []string{"abc121", "abc122", "abc123", "abc124"} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			pprof.Do(context.Background(), pprof.Labels("request-id", id), func(context.Context) {
+				handle(id)
+			})
+		}(id)
+	}
+	wg.Wait()
+}
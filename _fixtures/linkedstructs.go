@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type listNode struct {
+	val  int
+	next *listNode
+}
+
+type treeNode struct {
+	name        string
+	left, right *treeNode
+	parent      *treeNode
+}
+
+type graphNode struct {
+	id    int
+	edges []*graphNode
+}
+
+func main() {
+	// 0 -> 1 -> 2 -> 3 -> 4 -> 2
+	var nodes [5]*listNode
+	for i := range nodes {
+		nodes[i] = &listNode{val: i}
+	}
+	for i := 0; i < len(nodes)-1; i++ {
+		nodes[i].next = nodes[i+1]
+	}
+	nodes[4].next = nodes[2]
+	list := nodes[0]
+
+	root := &treeNode{name: "root"}
+	root.left = &treeNode{name: "l", parent: root}
+	root.right = &treeNode{name: "r", parent: root}
+	root.left.left = &treeNode{name: "ll", parent: root.left}
+
+	g := []*graphNode{{id: 0}, {id: 1}, {id: 2}}
+	g[0].edges = []*graphNode{g[1], g[2]}
+	g[1].edges = []*graphNode{g[2]}
+	g[2].edges = []*graphNode{g[0]}
+
+	runtime.Breakpoint()
+	fmt.Println(list.val, root.name, g[0].id)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"runtime"
+	"sync"
+	"time"
+)
+
+type person struct {
+	Name string
+	Age  int
+	Boss *person
+}
+
+func main() {
+	tm := time.Date(2018, time.March, 4, 10, 30, 0, 500, time.UTC)
+	tz := time.Date(2018, time.March, 4, 10, 30, 0, 0, time.FixedZone("CET", 3600))
+	n, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	ip4 := netip.MustParseAddr("192.168.1.10")
+	ip6 := netip.MustParseAddr("2001:db8::1")
+	var mu sync.Mutex
+	mu.Lock()
+	boss := &person{"Bob", 50, nil}
+	p := person{"Alice", 30, boss}
+	runtime.Breakpoint()
+	fmt.Println(tm, tz, n, ip4, ip6, &mu, p)
+}
@@ -0,0 +1,14 @@
+package main
+
+import "fmt"
+
+func compute(a, b int) int {
+	c := a * b
+	d := c + a
+	e := d - b
+	return e
+}
+
+func main() {
+	fmt.Println(compute(3, 4))
+}
@@ -0,0 +1,29 @@
+package main
+
+import "fmt"
+
+func cleanup() {
+	fmt.Println("cleanup")
+}
+
+func handler() {
+	if r := recover(); r != nil {
+		fmt.Println("recovered:", r)
+	}
+}
+
+func work() {
+	defer cleanup()
+	panic("boom")
+}
+
+func run() {
+	defer handler()
+	defer cleanup()
+	work()
+}
+
+func main() {
+	run()
+	fmt.Println("done")
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"runtime"
+	"time"
+)
+
+var recvch = make(chan int)
+var sendch = make(chan int)
+var nilch chan int
+
+func selector() {
+	select {
+	case <-recvch:
+	case sendch <- 1:
+	case <-nilch:
+	}
+}
+
+func main() {
+	go selector()
+	time.Sleep(10 * time.Millisecond)
+	runtime.Breakpoint()
+}
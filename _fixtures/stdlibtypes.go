@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type ctxKey string
+
+func main() {
+	d := 1500 * time.Millisecond
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var n atomic.Int64
+	n.Store(-42)
+	var b atomic.Bool
+	b.Store(true)
+	var p atomic.Pointer[int]
+	var v atomic.Value
+	v.Store("hello")
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = context.WithValue(ctx, ctxKey("user"), "alice")
+	_, err := os.Open("/nonexistent")
+	werr := fmt.Errorf("loading config: %w", err)
+	jerr := errors.Join(errors.New("first"), werr)
+	runtime.Breakpoint()
+	fmt.Println(d, &wg, &n, &b, &p, &v, ctx, werr, jerr)
+	cancel()
+	wg.Done()
+	wg.Done()
+}
@@ -0,0 +1,20 @@
+package main
+
+import "fmt"
+
+func g() int {
+	return 1
+}
+
+func h() int {
+	return 2
+}
+
+func f(a, b int) int {
+	return a + b
+}
+
+func main() {
+	x := f(g(), h())
+	fmt.Println(x)
+}
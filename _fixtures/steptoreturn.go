@@ -0,0 +1,22 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+func divide(a, b int) (q int, err error) {
+	defer func() {
+		if err != nil {
+			q = -1
+		}
+	}()
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+func main() {
+	fmt.Println(divide(10, 0))
+}
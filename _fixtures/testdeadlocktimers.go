@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"time"
+)
+
+func main() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	select {
+	case <-sig:
+	case <-time.After(time.Hour):
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func compute(a, b int) int {
+	x := a + b
+	y := x * a
+	z := y - b
+	return z * 2
+}
+
+func main() {
+	a, b := len(os.Args), 4
+	c := compute(a, b) + a
+	fmt.Println(a, b, c)
+	fmt.Println(wrap(a, b))
+}
+
+//go:noinline
+func wrap(a, b int) int {
+	return compute(a, b)
+}
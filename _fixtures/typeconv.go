@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type celsius float64
+
+type point struct {
+	X, Y int
+}
+
+type vec struct {
+	X, Y int
+}
+
+type name string
+
+type bytes []byte
+
+func main() {
+	i := 300
+	f := 2.75
+	c := celsius(36.6)
+	b := []byte("hello")
+	r := []rune("héllo")
+	n := name("alice")
+	bs := bytes("raw")
+	pt := point{1, 2}
+	ppt := &pt
+	v := vec{3, 4}
+	runtime.Breakpoint()
+	fmt.Println(i, f, c, b, r, n, bs, pt, ppt, v)
+}
@@ -0,0 +1,17 @@
+package main
+
+import "fmt"
+
+func compute(n int) int {
+	return n * 2
+}
+
+func main() {
+	a := compute(1)
+	b := compute(a)
+	{
+		c := compute(b)
+		fmt.Println(c)
+	}
+	fmt.Println(a, b)
+}
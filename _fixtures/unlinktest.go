@@ -0,0 +1,11 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	err := os.Remove("/nonexistent/delve-unlinktest")
+	fmt.Println(err)
+}
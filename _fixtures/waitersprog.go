@@ -0,0 +1,30 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+func main() {
+	ch := make(chan int)
+	var mu sync.Mutex
+	mu.Lock()
+	for i := 0; i < 3; i++ {
+		go func() {
+			ch <- 1
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		go func() {
+			mu.Lock()
+			mu.Unlock()
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	runtime.Breakpoint()
+	mu.Unlock()
+	for i := 0; i < 3; i++ {
+		<-ch
+	}
+}
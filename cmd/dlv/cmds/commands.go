@@ -47,6 +47,9 @@ var (
 	// Backend selection
 	Backend string
 
+	// StopOnEntry is the function a newly launched process is continued to.
+	StopOnEntry string
+
 	// RootCommand is the root of the command tree.
 	RootCommand *cobra.Command
 
@@ -109,6 +112,8 @@ Defaults to "debugger" when logging is enabled with --log.`)
 	lldb		Uses lldb-server or debugserver.
 	rr		Uses mozilla rr (https://github.com/mozilla/rr).
 `)
+	RootCommand.PersistentFlags().StringVar(&StopOnEntry, "stop-on-entry", "", "Continue a newly launched program to the specified function, runtime.main if none is specified, before executing any command.")
+	RootCommand.PersistentFlags().Lookup("stop-on-entry").NoOptDefVal = "runtime.main"
 
 	// 'attach' subcommand.
 	attachCommand := &cobra.Command{
@@ -516,6 +521,7 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 			Backend:     Backend,
 			CoreFile:    coreFile,
 			Foreground:  Headless,
+			StopOnEntry: StopOnEntry,
 
 			DisconnectChan: disconnectChan,
 		})
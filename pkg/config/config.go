@@ -39,6 +39,14 @@ type Config struct {
 	// MaxArrayValues is the maximum number of array items that the commands
 	// print, locals, args and vars should read (in verbose mode).
 	MaxArrayValues *int `yaml:"max-array-values,omitempty"`
+	// MaxVariableRecurse is the maximum depth of nested structs, arrays and
+	// maps that the commands print, locals, args and vars should read (in
+	// verbose mode).
+	MaxVariableRecurse *int `yaml:"max-variable-recurse,omitempty"`
+	// MaxMapEntries is the maximum number of map entries that the commands
+	// print, locals, args and vars should read (in verbose mode), if it is
+	// not set MaxArrayValues is used.
+	MaxMapEntries *int `yaml:"max-map-entries,omitempty"`
 
 	// If ShowLocationExpr is true whatis will print the DWARF location
 	// expression for its argument.
@@ -147,6 +155,12 @@ substitute-path:
 # Maximum loaded string length.
 # max-string-len: 64
 
+# Maximum depth of nested values loaded.
+# max-variable-recurse: 1
+
+# Maximum number of entries loaded from a map, defaults to max-array-values.
+# max-map-entries: 64
+
 # Uncomment the following line to make the whatis command also print the DWARF location expression of its argument.
 # show-location-expr: true
 `)
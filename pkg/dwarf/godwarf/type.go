@@ -26,6 +26,8 @@ const (
 	AttrGoElem          dwarf.Attr = 0x2902
 	AttrGoEmbeddedField dwarf.Attr = 0x2903
 	AttrGoRuntimeType   dwarf.Attr = 0x2904
+	AttrGoDictIndex     dwarf.Attr = 0x2906
+	AttrGoClosureOffset dwarf.Attr = 0x2907
 )
 
 // Basic type encodings -- the value for AttrEncoding in a TagBaseType Entry.
@@ -330,6 +332,15 @@ func (t *TypedefType) String() string { return t.Name }
 
 func (t *TypedefType) Size() int64 { return t.Type.Size() }
 
+// A ParametricType represents a type parameter of a generic function, its
+// Type is the shape type shared by the instantiations of the function, the
+// concrete type is stored at index DictIndex of the dictionary passed to
+// the function.
+type ParametricType struct {
+	TypedefType
+	DictIndex int64
+}
+
 // A MapType represents a Go map type. It looks like a TypedefType, describing
 // the runtime-internal structure, with extra fields.
 type MapType struct {
@@ -793,6 +804,7 @@ func readType(d *dwarf.Data, name string, r *dwarf.Reader, off dwarf.Offset, typ
 		//	AttrType: type definition [required]
 		//	AttrGoKey: present for maps.
 		//	AttrGoElem: present for maps and channels.
+		//	AttrGoDictIndex: present for type parameters of generic functions.
 		t := new(TypedefType)
 		t.ReflectKind = getKind(e)
 		switch t.ReflectKind {
@@ -812,7 +824,14 @@ func readType(d *dwarf.Data, name string, r *dwarf.Reader, off dwarf.Offset, typ
 			t = &it.TypedefType
 			typ = it
 		default:
-			typ = t
+			if dictIndex, ok := e.Val(AttrGoDictIndex).(int64); ok {
+				pt := new(ParametricType)
+				pt.DictIndex = dictIndex
+				t = &pt.TypedefType
+				typ = pt
+			} else {
+				typ = t
+			}
 		}
 		typeCache[off] = typ
 		t.Name, _ = e.Val(dwarf.AttrName).(string)
@@ -841,6 +860,8 @@ func readType(d *dwarf.Data, name string, r *dwarf.Reader, off dwarf.Offset, typ
 			switch t := typ.(type) {
 			case *TypedefType:
 				b = t.Type.Size()
+			case *ParametricType:
+				b = t.Type.Size()
 			case *MapType:
 				b = t.Type.Size()
 			case *ChanType:
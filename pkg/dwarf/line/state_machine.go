@@ -287,6 +287,45 @@ func (lineInfo *DebugLineInfo) LineToPC(filename string, lineno int) uint64 {
 	return fallbackPC
 }
 
+// LineColumnToPC returns the first PC address associated with
+// filename:lineno:column. If no instruction is assigned exactly to column
+// the instruction with the closest following column on the same line is
+// returned. Returns 0 if the line table does not contain column information
+// for filename:lineno.
+func (lineInfo *DebugLineInfo) LineColumnToPC(filename string, lineno, column int) uint64 {
+	if lineInfo == nil {
+		return 0
+	}
+
+	sm := newStateMachine(lineInfo, lineInfo.Instructions)
+
+	var (
+		bestPC     uint64
+		bestColumn uint
+		bestIsStmt bool
+	)
+
+	for {
+		if err := sm.next(); err != nil {
+			if lineInfo.Logf != nil && err != io.EOF {
+				lineInfo.Logf("LineColumnToPC error: %v", err)
+			}
+			break
+		}
+		if sm.line != lineno || sm.file != filename || !sm.valid || sm.column < uint(column) {
+			continue
+		}
+		switch {
+		case bestPC == 0, sm.column < bestColumn:
+		case sm.column == bestColumn && sm.isStmt && !bestIsStmt:
+		default:
+			continue
+		}
+		bestPC, bestColumn, bestIsStmt = sm.address, sm.column, sm.isStmt
+	}
+	return bestPC
+}
+
 // PrologueEndPC returns the first PC address marked as prologue_end in the half open interval [start, end)
 func (lineInfo *DebugLineInfo) PrologueEndPC(start, end uint64) (pc uint64, file string, line int, ok bool) {
 	sm := lineInfo.stateMachineForEntry(start)
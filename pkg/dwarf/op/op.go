@@ -161,12 +161,62 @@ func consts(opcode Opcode, ctxt *context) error {
 	return nil
 }
 
+func constnu(opcode Opcode, ctxt *context) error {
+	var num uint64
+	switch opcode {
+	case DW_OP_const1u:
+		b, _ := ctxt.buf.ReadByte()
+		num = uint64(b)
+	case DW_OP_const2u:
+		num = uint64(binary.LittleEndian.Uint16(ctxt.buf.Next(2)))
+	case DW_OP_const4u:
+		num = uint64(binary.LittleEndian.Uint32(ctxt.buf.Next(4)))
+	case DW_OP_const8u:
+		num = binary.LittleEndian.Uint64(ctxt.buf.Next(8))
+	default:
+		num, _ = util.DecodeULEB128(ctxt.buf)
+	}
+	ctxt.stack = append(ctxt.stack, int64(num))
+	return nil
+}
+
+func constns(opcode Opcode, ctxt *context) error {
+	var num int64
+	switch opcode {
+	case DW_OP_const1s:
+		b, _ := ctxt.buf.ReadByte()
+		num = int64(int8(b))
+	case DW_OP_const2s:
+		num = int64(int16(binary.LittleEndian.Uint16(ctxt.buf.Next(2))))
+	case DW_OP_const4s:
+		num = int64(int32(binary.LittleEndian.Uint32(ctxt.buf.Next(4))))
+	case DW_OP_const8s:
+		num = int64(binary.LittleEndian.Uint64(ctxt.buf.Next(8)))
+	}
+	ctxt.stack = append(ctxt.stack, num)
+	return nil
+}
+
 func framebase(opcode Opcode, ctxt *context) error {
 	num, _ := util.DecodeSLEB128(ctxt.buf)
 	ctxt.stack = append(ctxt.stack, ctxt.FrameBase+num)
 	return nil
 }
 
+// formtlsaddress replaces the offset on top of the stack, relative to the
+// thread-local storage block of the executable, with the corresponding
+// address for the current thread.
+func formtlsaddress(opcode Opcode, ctxt *context) error {
+	if len(ctxt.stack) == 0 {
+		return errors.New("empty OP stack")
+	}
+	if ctxt.TLS == 0 {
+		return errors.New("thread-local storage not available")
+	}
+	ctxt.stack[len(ctxt.stack)-1] += ctxt.TLS
+	return nil
+}
+
 func register(opcode Opcode, ctxt *context) error {
 	ctxt.reg = true
 	if opcode == DW_OP_regx {
@@ -16,3 +16,19 @@ func TestExecuteStackProgram(t *testing.T) {
 		t.Fatalf("actual %d != expected %d", actual, expected)
 	}
 }
+
+func TestFormTLSAddress(t *testing.T) {
+	// DW_OP_const8u 8; DW_OP_form_tls_address
+	instructions := []byte{byte(DW_OP_const8u), 8, 0, 0, 0, 0, 0, 0, 0, byte(DW_OP_form_tls_address)}
+	actual, _, err := ExecuteStackProgram(DwarfRegisters{TLS: 0x7f0000001000}, instructions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := int64(0x7f0000001008); actual != expected {
+		t.Fatalf("actual %#x != expected %#x", actual, expected)
+	}
+
+	if _, _, err := ExecuteStackProgram(DwarfRegisters{}, instructions); err == nil {
+		t.Fatal("no error without thread-local storage")
+	}
+}
@@ -9,7 +9,10 @@ type DwarfRegisters struct {
 	CFA       int64
 	FrameBase int64
 	ObjBase   int64
-	Regs      []*DwarfRegister
+	// TLS is the address of the thread-local storage block of the
+	// executable for the current thread, zero if it is not known.
+	TLS  int64
+	Regs []*DwarfRegister
 
 	ByteOrder binary.ByteOrder
 	PCRegNum  uint64
@@ -13,6 +13,7 @@ type VariableReader struct {
 	reader      *dwarf.Reader
 	entry       *dwarf.Entry
 	depth       int
+	scopes      []*dwarf.Entry
 	onlyVisible bool
 	pc          uint64
 	line        int
@@ -43,6 +44,7 @@ func (vrdr *VariableReader) Next() bool {
 		switch vrdr.entry.Tag {
 		case 0:
 			vrdr.depth--
+			vrdr.scopes = vrdr.scopes[:len(vrdr.scopes)-1]
 			if vrdr.depth == 0 {
 				return false
 			}
@@ -58,6 +60,7 @@ func (vrdr *VariableReader) Next() bool {
 
 			if recur && vrdr.entry.Children {
 				vrdr.depth++
+				vrdr.scopes = append(vrdr.scopes, vrdr.entry)
 			} else {
 				if vrdr.depth == 0 {
 					return false
@@ -100,6 +103,15 @@ func (vrdr *VariableReader) Depth() int {
 	return vrdr.depth
 }
 
+// ScopeRanges returns the address ranges of the innermost lexical block, or
+// function, containing the current variable.
+func (vrdr *VariableReader) ScopeRanges() ([][2]uint64, error) {
+	if len(vrdr.scopes) == 0 {
+		return nil, nil
+	}
+	return vrdr.dwarf.Ranges(vrdr.scopes[len(vrdr.scopes)-1])
+}
+
 // Err returns the error if there was one.
 func (vrdr *VariableReader) Err() error {
 	return vrdr.err
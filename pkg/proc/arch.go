@@ -2,6 +2,7 @@ package proc
 
 import (
 	"encoding/binary"
+	"strings"
 
 	"github.com/derekparker/delve/pkg/dwarf/frame"
 	"github.com/derekparker/delve/pkg/dwarf/op"
@@ -18,6 +19,7 @@ type Arch interface {
 	FixFrameUnwindContext(fctxt *frame.FrameContext, pc uint64, bi *BinaryInfo) *frame.FrameContext
 	RegSize(uint64) int
 	RegistersToDwarfRegisters(Registers) op.DwarfRegisters
+	DwarfRegisterByName(string) (uint64, bool)
 	GoroutineToDwarfRegisters(*G) op.DwarfRegisters
 }
 
@@ -253,6 +255,32 @@ var amd64DwarfToName = map[int]string{
 	66: "SW",
 }
 
+// DwarfRegisterByName returns the DWARF register number of the register
+// called name, the name is case insensitive. The names pc, sp and bp are
+// accepted as aliases of rip, rsp and rbp.
+func (a *AMD64) DwarfRegisterByName(name string) (uint64, bool) {
+	name = strings.ToUpper(name)
+	switch name {
+	case "PC", "RIP":
+		return amd64DwarfIPRegNum, true
+	case "SP", "RSP":
+		return amd64DwarfSPRegNum, true
+	case "BP", "RBP":
+		return amd64DwarfBPRegNum, true
+	}
+	for dwarfReg, asmReg := range asm64DwarfToHardware {
+		if asmReg.String() == name {
+			return uint64(dwarfReg), true
+		}
+	}
+	for dwarfReg, regName := range amd64DwarfToName {
+		if strings.ToUpper(regName) == name {
+			return uint64(dwarfReg), true
+		}
+	}
+	return 0, false
+}
+
 func maxAmd64DwarfRegister() int {
 	max := int(amd64DwarfIPRegNum)
 	for i := range asm64DwarfToHardware {
@@ -43,6 +43,10 @@ type BinaryInfo struct {
 	types         map[string]dwarf.Offset
 	packageVars   []packageVar // packageVars is a list of all global/package variables in debug_info, sorted by address
 	gStructOffset uint64
+	// tlsBlockSize is the size of the thread-local storage block of the
+	// executable, which ends at the TLS base of each thread, zero if it is
+	// not known.
+	tlsBlockSize uint64
 
 	// Functions is a list of all DW_TAG_subprogram entries in debug_info, sorted by entry point
 	Functions []Function
@@ -50,6 +54,9 @@ type BinaryInfo struct {
 	Sources []string
 	// LookupFunc maps function names to a description of the function.
 	LookupFunc map[string]*Function
+	// InlinedCalls maps the names of inlined functions to the entry points
+	// of each of their inlined calls, sorted by address.
+	InlinedCalls map[string][]uint64
 
 	typeCache map[dwarf.Offset]godwarf.Type
 
@@ -70,6 +77,10 @@ type BinaryInfo struct {
 	loadErr   error
 
 	dwarfReader *dwarf.Reader
+
+	// formatters are the formatters registered by the user, see
+	// SetFormatters.
+	formatters *Formatters
 }
 
 var UnsupportedLinuxArchErr = errors.New("unsupported architecture - only linux/amd64 is supported")
@@ -108,6 +119,9 @@ type Function struct {
 	Entry, End uint64 // same as DW_AT_lowpc and DW_AT_highpc
 	offset     dwarf.Offset
 	cu         *compileUnit
+	// closure is true if the function has variables captured from its
+	// closure context.
+	closure bool
 }
 
 // PackageName returns the package part of the symbol name,
@@ -287,10 +301,31 @@ func (bi *BinaryInfo) GStructOffset() uint64 {
 	return bi.gStructOffset
 }
 
+// threadTLSBlock returns the address of the thread-local storage block of
+// the executable for thread, where the thread-local variables of C code
+// linked into a cgo program are stored, or zero if it is not known.
+func (bi *BinaryInfo) threadTLSBlock(thread Thread) uint64 {
+	if bi.tlsBlockSize == 0 {
+		return 0
+	}
+	regs, err := thread.Registers(false)
+	if err != nil || regs.TLS() == 0 {
+		return 0
+	}
+	return regs.TLS() - bi.tlsBlockSize
+}
+
 func (bi *BinaryInfo) LastModified() time.Time {
 	return bi.lastModified
 }
 
+// SetFormatters sets the formatters, registered by the user, used for the
+// struct variables of the target. The formatters of the standard library
+// are always used.
+func (bi *BinaryInfo) SetFormatters(f *Formatters) {
+	bi.formatters = f
+}
+
 // DwarfReader returns a reader for the dwarf data
 func (bi *BinaryInfo) DwarfReader() *reader.Reader {
 	return reader.New(bi.dwarf)
@@ -330,6 +365,33 @@ func (bi *BinaryInfo) LineToPC(filename string, lineno int) (pc uint64, fn *Func
 	return
 }
 
+// LineColumnToPC converts a file:line:column into a memory address.
+func (bi *BinaryInfo) LineColumnToPC(filename string, lineno, column int) (pc uint64, fn *Function, err error) {
+	for _, cu := range bi.compileUnits {
+		if cu.lineInfo.Lookup[filename] != nil {
+			pc = cu.lineInfo.LineColumnToPC(filename, lineno, column)
+			fn = bi.PCToFunc(pc)
+			if fn != nil {
+				return
+			}
+		}
+	}
+	err = fmt.Errorf("could not find %s:%d:%d (column information may be missing)", filename, lineno, column)
+	return
+}
+
+// InlinedCallName returns the name of the function inlined at pc if pc is
+// the entry point of an inlined call, or the empty string otherwise.
+func (bi *BinaryInfo) InlinedCallName(pc uint64) string {
+	for name, pcs := range bi.InlinedCalls {
+		i := sort.Search(len(pcs), func(i int) bool { return pcs[i] >= pc })
+		if i < len(pcs) && pcs[i] == pc {
+			return name
+		}
+	}
+	return ""
+}
+
 // AllPCsForFileLine returns all PC addresses for the given filename:lineno.
 func (bi *BinaryInfo) AllPCsForFileLine(filename string, lineno int) []uint64 {
 	r := make([]uint64, 0, 1)
@@ -465,6 +527,16 @@ func (bi *BinaryInfo) findCompileUnit(pc uint64) *compileUnit {
 	return nil
 }
 
+// packageVarAddr returns the address of the package variable name.
+func (bi *BinaryInfo) packageVarAddr(name string) (uint64, bool) {
+	for _, pv := range bi.packageVars {
+		if pv.name == name {
+			return pv.addr, true
+		}
+	}
+	return 0, false
+}
+
 func (bi *BinaryInfo) Producer() string {
 	for _, cu := range bi.compileUnits {
 		if cu.isgo && cu.producer != "" {
@@ -629,10 +701,6 @@ func (bi *BinaryInfo) setGStructOffsetElf(exe *elf.File, wg *sync.WaitGroup) {
 			break
 		}
 	}
-	if tlsg == nil {
-		bi.gStructOffset = ^uint64(8) + 1 // -8
-		return
-	}
 	var tls *elf.Prog
 	for _, prog := range exe.Progs {
 		if prog.Type == elf.PT_TLS {
@@ -640,9 +708,21 @@ func (bi *BinaryInfo) setGStructOffsetElf(exe *elf.File, wg *sync.WaitGroup) {
 			break
 		}
 	}
+	if tls != nil {
+		// The offset of the block from the TLS base is its size rounded up
+		// to its alignment.
+		bi.tlsBlockSize = tls.Memsz
+		if tls.Align > 1 {
+			bi.tlsBlockSize = (tls.Memsz + tls.Align - 1) &^ (tls.Align - 1)
+		}
+	}
+	if tlsg == nil {
+		bi.gStructOffset = ^uint64(8) + 1 // -8
+		return
+	}
 	// The TLS register points to the end of the TLS block, which is
-	// tls.Memsz long. runtime.tlsg is an offset from the beginning of that block.
-	bi.gStructOffset = ^(tls.Memsz) + 1 + tlsg.Value // -tls.Memsz + tlsg.Value
+	// tlsBlockSize long. runtime.tlsg is an offset from the beginning of that block.
+	bi.gStructOffset = ^(bi.tlsBlockSize) + 1 + tlsg.Value // -tlsBlockSize + tlsg.Value
 }
 
 // PE ////////////////////////////////////////////////////////////////
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"go/ast"
 	"go/constant"
+	"go/token"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 // Breakpoint represents a breakpoint. Stores information on the break
@@ -21,6 +24,12 @@ type Breakpoint struct {
 	OriginalData []byte // If software breakpoint, the data we replace with breakpoint instruction.
 	Name         string // User defined name of the breakpoint
 	ID           int    // Monotonically increasing ID.
+	Group        string // Name of the breakpoint group this breakpoint belongs to
+
+	// Disabled is true if the user breakpoint is disabled, disabled
+	// breakpoints are removed from the target's memory unless an internal
+	// breakpoint is set on the same address.
+	Disabled bool
 
 	// Kind describes whether this is an internal breakpoint (for next'ing or
 	// stepping).
@@ -28,12 +37,25 @@ type Breakpoint struct {
 	// internal breakpoint, but it can not be two different kinds of internal
 	// breakpoint.
 	Kind BreakpointKind
+	// Origin is the name of the operation that set the internal breakpoint
+	// ("next", "step" or "stepout").
+	Origin string
 
 	// Breakpoint information
-	Tracepoint    bool     // Tracepoint flag
-	Goroutine     bool     // Retrieve goroutine information
-	Stacktrace    int      // Number of stack frames to retrieve
-	Variables     []string // Variables to evaluate
+	Tracepoint bool     // Tracepoint flag
+	Goroutine  bool     // Retrieve goroutine information
+	Stacktrace int      // Number of stack frames to retrieve
+	Variables  []string // Variables to evaluate
+	// LogMessage: if not empty this breakpoint is a logpoint, when it is hit
+	// the logpoint function of the process is called and execution is
+	// resumed without stopping.
+	LogMessage string
+	// RecordStack: if not zero every time the breakpoint is hit a
+	// stacktrace, RecordStack frames deep, of the goroutine that hit it is
+	// recorded and execution is resumed without stopping. Recorded stacks
+	// are retrieved with TakeStackRecords.
+	RecordStack   int
+	stackRecords  []StackRecord
 	LoadArgs      *LoadConfig
 	LoadLocals    *LoadConfig
 	HitCount      map[int]uint64 // Number of times a breakpoint has been reached in a certain goroutine
@@ -53,12 +75,61 @@ type Breakpoint struct {
 	Cond ast.Expr
 	// internalCond is the same as Cond but used for the condition of internal breakpoints
 	internalCond ast.Expr
+	// internalGoroutineID is the ID of the goroutine internalCond is
+	// restricted to, by a runtime.curg.goid == X clause, or 0. It is checked
+	// using GetG before evaluating internalCond so that threads that are not
+	// running the goroutine never trigger the internal breakpoint, even when
+	// evaluating internalCond on them fails.
+	internalGoroutineID int
+	// HitCond: if not nil the breakpoint will be triggered only if the total
+	// hit count, including the current hit, satisfies the hit condition.
+	HitCond *HitCondition
+	// MaxHits: if not zero the breakpoint stops triggering, and is
+	// disabled, once TotalHitCount reaches MaxHits.
+	MaxHits uint64
+	// ThreadID: if not zero the breakpoint will only be triggered by the
+	// thread with this ID, other threads will step over it.
+	ThreadID int
+	// Actions are executed, in order, every time the breakpoint is
+	// triggered.
+	Actions []BreakpointAction
 
 	// ReturnInfo describes how to collect return variables when this
 	// breakpoint is hit as a return breakpoint.
 	returnInfo *returnBreakpointInfo
+	// Suspend determines which threads are stopped when this breakpoint is
+	// hit.
+	Suspend SuspendPolicy
+
+	// OnReturn is true if this breakpoint is set on a return instruction of
+	// FunctionName, the return values of the function are collected every
+	// time it is hit.
+	OnReturn bool
+	// CgoCatch is non-zero if this breakpoint was set by SetCgoCatchpoints
+	// to catch crossings of the Go/C boundary in the given direction.
+	CgoCatch CgoDirection
+
+	// WatchType is non-zero if this is a hardware watchpoint on the WatchSize
+	// bytes of memory starting at Addr.
+	WatchType WatchType
+	WatchSize int
+	// HWBreakIndex is the index of the debug register used by this
+	// watchpoint.
+	HWBreakIndex uint8
 }
 
+// StackRecord is a stacktrace recorded by a breakpoint with RecordStack
+// set.
+type StackRecord struct {
+	GoroutineID int
+	ThreadID    int
+	Stack       []Location
+}
+
+// maxStackRecords is the maximum number of stack records buffered by a
+// breakpoint, once it is reached the oldest records are discarded.
+const maxStackRecords = 4096
+
 // Breakpoint Kind determines the behavior of delve when the
 // breakpoint is reached.
 type BreakpointKind uint16
@@ -78,9 +149,128 @@ const (
 	// Continue will set a new breakpoint (of NextBreakpoint kind) on the
 	// destination of CALL, delete this breakpoint and then continue again
 	StepBreakpoint
+	// FreezeBreakpoint is the breakpoint set on runtime.execute while a
+	// goroutine is frozen or the scheduling history is recorded, see
+	// FreezeGoroutine and RecordSchedHistory. Continue never stops on it and
+	// it is not removed by ClearInternalBreakpoints.
+	FreezeBreakpoint
+)
+
+// steppingBreakpoints are the kinds of the internal breakpoints set by
+// next, step and stepout.
+const steppingBreakpoints = NextBreakpoint | NextDeferBreakpoint | StepBreakpoint
+
+// SuspendPolicy determines which threads of the target are stopped when a
+// user breakpoint is hit.
+type SuspendPolicy uint8
+
+const (
+	// SuspendAll stops all threads when the breakpoint is hit.
+	SuspendAll SuspendPolicy = iota
+	// SuspendThread only stops the thread that hit the breakpoint, all other
+	// threads keep running. Backends that do not support this policy stop
+	// all threads.
+	SuspendThread
+)
+
+// ChanOp is a set of channel operations, used by channel catchpoints.
+type ChanOp uint8
+
+const (
+	// ChanSend stops on sends to the channel.
+	ChanSend ChanOp = 1 << iota
+	// ChanRecv stops on receives from the channel.
+	ChanRecv
+	// ChanClose stops when the channel is closed.
+	ChanClose
+)
+
+// BreakpointActionKind is the kind of a breakpoint action.
+type BreakpointActionKind uint8
+
+const (
+	// EvalAction evaluates Expr in the scope of the goroutine that hit the
+	// breakpoint.
+	EvalAction BreakpointActionKind = iota
+	// StackAction collects a stacktrace, Depth frames deep, of the goroutine
+	// that hit the breakpoint.
+	StackAction
+	// ContinueAction resumes execution after all actions have been executed,
+	// instead of stopping.
+	ContinueAction
+	// EnableAction enables the user breakpoint with ID BreakpointID.
+	EnableAction
+)
+
+// BreakpointAction is an action executed every time a breakpoint is
+// triggered.
+type BreakpointAction struct {
+	Kind         BreakpointActionKind
+	Expr         string
+	Depth        int
+	BreakpointID int
+}
+
+// BreakpointActionResult is the result of executing a breakpoint action.
+type BreakpointActionResult struct {
+	Action   *BreakpointAction
+	Variable *Variable    // result of EvalAction
+	Stack    []Stackframe // result of StackAction
+	Err      error
+}
+
+// BreakpointActionsFunc is called with the results of the actions of bp
+// every time they are executed.
+type BreakpointActionsFunc func(thread Thread, bp *Breakpoint, results []BreakpointActionResult)
+
+// WatchType describes the kind of memory access that triggers a
+// watchpoint.
+type WatchType uint8
+
+const (
+	// WatchRead triggers the watchpoint when the memory is read (or
+	// written, hardware does not support read-only watchpoints).
+	WatchRead WatchType = 1 << iota
+	// WatchWrite triggers the watchpoint when the memory is written.
+	WatchWrite
+	// WatchExecute triggers the watchpoint when the instruction at its
+	// address is executed, it is used to implement hardware breakpoints.
+	WatchExecute
+)
+
+// Read returns true if the watchpoint triggers on reads.
+func (wtype WatchType) Read() bool {
+	return wtype&WatchRead != 0
+}
+
+// Write returns true if the watchpoint triggers on writes.
+func (wtype WatchType) Write() bool {
+	return wtype&WatchWrite != 0
+}
+
+// Execute returns true if the watchpoint triggers on execution.
+func (wtype WatchType) Execute() bool {
+	return wtype&WatchExecute != 0
+}
+
+// MaxHWWatchpoints is the number of hardware watchpoints that can be set
+// at the same time, on amd64 there are four debug address registers
+// (DR0-DR3).
+const MaxHWWatchpoints = 4
+
+var (
+	// ErrTooManyWatchpoints is returned by SetWatchpoint when all the debug
+	// registers are in use.
+	ErrTooManyWatchpoints = errors.New("too many hardware watchpoints")
+	// ErrWatchpointsUnsupportedBackend is returned by SetWatchpoint when the
+	// backend does not support hardware watchpoints.
+	ErrWatchpointsUnsupportedBackend = errors.New("backend does not support hardware watchpoints")
 )
 
 func (bp *Breakpoint) String() string {
+	if bp.WatchType != 0 && !bp.WatchType.Execute() {
+		return fmt.Sprintf("Watchpoint %d at %#x (%d bytes) (%d)", bp.ID, bp.Addr, bp.WatchSize, bp.TotalHitCount)
+	}
 	return fmt.Sprintf("Breakpoint %d at %#v %s:%d (%d)", bp.ID, bp.Addr, bp.File, bp.Line, bp.TotalHitCount)
 }
 
@@ -106,6 +296,76 @@ func (iae InvalidAddressError) Error() string {
 	return fmt.Sprintf("Invalid address %#v\n", iae.Address)
 }
 
+// HitCondition is a predicate on the hit count of a breakpoint. Op can be
+// one of token.EQL, token.NEQ, token.GTR, token.LSS, token.GEQ, token.LEQ
+// or token.REM; for token.REM the condition is satisfied every Val hits.
+type HitCondition struct {
+	Op  token.Token
+	Val int
+}
+
+// ParseHitCondition parses a hit condition, in the form "OP N" where OP
+// is one of ==, !=, >, <, >=, <= or %. A number without an operator is
+// interpreted as "== N".
+func ParseHitCondition(s string) (*HitCondition, error) {
+	s = strings.TrimSpace(s)
+	opstr := strings.TrimRight(s, " 0123456789")
+	opstr = strings.TrimSpace(opstr)
+	n, err := strconv.Atoi(strings.TrimSpace(s[len(opstr):]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hit condition %q: %v", s, err)
+	}
+	var op token.Token
+	switch opstr {
+	case "", "==":
+		op = token.EQL
+	case "!=":
+		op = token.NEQ
+	case ">":
+		op = token.GTR
+	case "<":
+		op = token.LSS
+	case ">=":
+		op = token.GEQ
+	case "<=":
+		op = token.LEQ
+	case "%":
+		if n == 0 {
+			return nil, fmt.Errorf("invalid hit condition %q: division by zero", s)
+		}
+		op = token.REM
+	default:
+		return nil, fmt.Errorf("invalid hit condition operator %q", opstr)
+	}
+	return &HitCondition{Op: op, Val: n}, nil
+}
+
+// Check returns true if hitCount satisfies the hit condition.
+func (hc *HitCondition) Check(hitCount uint64) bool {
+	n := uint64(hc.Val)
+	switch hc.Op {
+	case token.EQL:
+		return hitCount == n
+	case token.NEQ:
+		return hitCount != n
+	case token.GTR:
+		return hitCount > n
+	case token.LSS:
+		return hitCount < n
+	case token.GEQ:
+		return hitCount >= n
+	case token.LEQ:
+		return hitCount <= n
+	case token.REM:
+		return hitCount%n == 0
+	}
+	return false
+}
+
+func (hc *HitCondition) String() string {
+	return fmt.Sprintf("%s %d", hc.Op, hc.Val)
+}
+
 type returnBreakpointInfo struct {
 	retFrameCond ast.Expr
 	fn           *Function
@@ -113,12 +373,36 @@ type returnBreakpointInfo struct {
 	spOffset     int64
 }
 
-// CheckCondition evaluates bp's condition on thread.
+// CheckCondition evaluates bp's condition on thread and updates its hit
+// counts.
 func (bp *Breakpoint) CheckCondition(thread Thread) BreakpointState {
+	bpstate := bp.checkCondition(thread)
+	if bpstate.Active && !bpstate.Internal && bp.MaxHits > 0 && bp.TotalHitCount >= bp.MaxHits {
+		bpstate.Active = false
+		return bpstate
+	}
+	if bpstate.Active {
+		if g, err := GetG(thread); err == nil && g != nil {
+			bp.HitCount[g.ID]++
+		}
+		bp.TotalHitCount++
+		if !bpstate.Internal && bp.HitCond != nil {
+			bpstate.Active = bp.HitCond.Check(bp.TotalHitCount)
+		}
+	}
+	return bpstate
+}
+
+func (bp *Breakpoint) checkCondition(thread Thread) BreakpointState {
 	bpstate := BreakpointState{Breakpoint: bp, Active: false, Internal: false, CondError: nil}
+	internal := bp.Kind&steppingBreakpoints != 0
+	user := bp.Kind&UserBreakpoint != 0 && !bp.Disabled && bp.threadMatches(thread)
+	if !internal && !user {
+		return bpstate
+	}
 	if bp.Cond == nil && bp.internalCond == nil {
 		bpstate.Active = true
-		bpstate.Internal = bp.Kind != UserBreakpoint
+		bpstate.Internal = internal
 		return bpstate
 	}
 	nextDeferOk := true
@@ -138,7 +422,7 @@ func (bp *Breakpoint) CheckCondition(thread Thread) BreakpointState {
 			nextDeferOk = ispanic || isdeferreturn
 		}
 	}
-	if bp.Kind != UserBreakpoint {
+	if internal && bp.onInternalGoroutine(thread) {
 		// Check internalCondition if this is also an internal breakpoint
 		bpstate.Active, bpstate.CondError = evalBreakpointCondition(thread, bp.internalCond)
 		bpstate.Active = bpstate.Active && nextDeferOk
@@ -147,13 +431,70 @@ func (bp *Breakpoint) CheckCondition(thread Thread) BreakpointState {
 			return bpstate
 		}
 	}
-	if bp.Kind&UserBreakpoint != 0 {
+	if user {
 		// Check normal condition if this is also a user breakpoint
 		bpstate.Active, bpstate.CondError = evalBreakpointCondition(thread, bp.Cond)
 	}
 	return bpstate
 }
 
+// recordStack appends a stacktrace of the goroutine running on thread to
+// the stack records of bp.
+func (bp *Breakpoint) recordStack(thread Thread) {
+	rec := StackRecord{ThreadID: thread.ThreadID()}
+	var frames []Stackframe
+	var err error
+	g, _ := GetG(thread)
+	if g != nil {
+		rec.GoroutineID = g.ID
+		frames, err = g.Stacktrace(bp.RecordStack, false)
+	} else {
+		frames, err = ThreadStacktrace(thread, bp.RecordStack)
+	}
+	if err != nil {
+		return
+	}
+	rec.Stack = make([]Location, len(frames))
+	for i := range frames {
+		rec.Stack[i] = frames[i].Call
+	}
+	if len(bp.stackRecords) >= maxStackRecords {
+		bp.stackRecords = bp.stackRecords[1:]
+	}
+	bp.stackRecords = append(bp.stackRecords, rec)
+}
+
+// TakeStackRecords returns the stacktraces recorded by bp since the last
+// call to TakeStackRecords.
+func (bp *Breakpoint) TakeStackRecords() []StackRecord {
+	r := bp.stackRecords
+	bp.stackRecords = nil
+	return r
+}
+
+// onInternalGoroutine returns true if thread is running the goroutine the
+// internal breakpoint is restricted to, or if it isn't restricted to a
+// goroutine.
+func (bp *Breakpoint) onInternalGoroutine(thread Thread) bool {
+	if bp.internalGoroutineID == 0 {
+		return true
+	}
+	g, err := GetG(thread)
+	return err == nil && g != nil && g.ID == bp.internalGoroutineID
+}
+
+// threadMatches returns true if the user breakpoint can be triggered by
+// thread.
+func (bp *Breakpoint) threadMatches(thread Thread) bool {
+	return bp.ThreadID == 0 || bp.ThreadID == thread.ThreadID()
+}
+
+// Disarmed returns true if bp is not currently written to the memory of
+// the target because it is a disabled user breakpoint.
+func (bp *Breakpoint) Disarmed() bool {
+	return bp.Disabled && bp.Kind == UserBreakpoint && bp.WatchType == 0
+}
+
 // IsInternal returns true if bp is an internal breakpoint.
 // User-set breakpoints can overlap with internal breakpoints, in that case
 // both IsUser and IsInternal will be true.
@@ -161,6 +502,12 @@ func (bp *Breakpoint) IsInternal() bool {
 	return bp.Kind != UserBreakpoint
 }
 
+// InternalCond returns the condition of the internal breakpoint set at the
+// address of bp.
+func (bp *Breakpoint) InternalCond() ast.Expr {
+	return bp.internalCond
+}
+
 // IsUser returns true if bp is a user-set breakpoint.
 // User-set breakpoints can overlap with internal breakpoints, in that case
 // both IsUser and IsInternal will be true.
@@ -174,7 +521,12 @@ func evalBreakpointCondition(thread Thread, cond ast.Expr) (bool, error) {
 	}
 	scope, err := GoroutineScope(thread)
 	if err != nil {
-		return true, err
+		// Conditions of internal breakpoints set on threads without a
+		// goroutine only check the frame offset.
+		scope, err = ThreadScope(thread)
+		if err != nil {
+			return true, err
+		}
 	}
 	v, err := scope.evalAST(cond)
 	if err != nil {
@@ -222,6 +574,7 @@ func (bpmap *BreakpointMap) ResetBreakpointIDCounter() {
 
 type writeBreakpointFn func(addr uint64) (file string, line int, fn *Function, originalData []byte, err error)
 type clearBreakpointFn func(*Breakpoint) error
+type writeWatchpointFn func(*Breakpoint) error
 
 // Set creates a breakpoint at addr calling writeBreakpoint. Do not call this
 // function, call proc.Process.SetBreakpoint instead, this function exists
@@ -231,13 +584,24 @@ func (bpmap *BreakpointMap) Set(addr uint64, kind BreakpointKind, cond ast.Expr,
 		// We can overlap one internal breakpoint with one user breakpoint, we
 		// need to support this otherwise a conditional breakpoint can mask a
 		// breakpoint set by next or step.
-		if (kind != UserBreakpoint && bp.Kind != UserBreakpoint) || (kind == UserBreakpoint && bp.Kind&UserBreakpoint != 0) {
+		if kind&bp.Kind != 0 || (kind&steppingBreakpoints != 0 && bp.Kind&steppingBreakpoints != 0) {
 			return bp, BreakpointExistsError{bp.File, bp.Line, bp.Addr}
 		}
+		if bp.Disarmed() {
+			// the user breakpoint is disabled, write it back for the internal
+			// breakpoint.
+			_, _, _, originalData, err := writeBreakpoint(addr)
+			if err != nil {
+				return nil, err
+			}
+			bp.OriginalData = originalData
+		}
 		bp.Kind |= kind
-		if kind != UserBreakpoint {
+		switch {
+		case kind&steppingBreakpoints != 0:
 			bp.internalCond = cond
-		} else {
+			bp.internalGoroutineID = condGoroutineID(cond)
+		case kind == UserBreakpoint:
 			bp.Cond = cond
 		}
 		return bp, nil
@@ -249,7 +613,6 @@ func (bpmap *BreakpointMap) Set(addr uint64, kind BreakpointKind, cond ast.Expr,
 	}
 
 	newBreakpoint := &Breakpoint{
-		FunctionName: fn.Name,
 		File:         f,
 		Line:         l,
 		Addr:         addr,
@@ -257,11 +620,15 @@ func (bpmap *BreakpointMap) Set(addr uint64, kind BreakpointKind, cond ast.Expr,
 		OriginalData: originalData,
 		HitCount:     map[int]uint64{},
 	}
+	if fn != nil {
+		newBreakpoint.FunctionName = fn.Name
+	}
 
 	if kind != UserBreakpoint {
 		bpmap.internalBreakpointIDCounter++
 		newBreakpoint.ID = bpmap.internalBreakpointIDCounter
 		newBreakpoint.internalCond = cond
+		newBreakpoint.internalGoroutineID = condGoroutineID(cond)
 	} else {
 		bpmap.breakpointIDCounter++
 		newBreakpoint.ID = bpmap.breakpointIDCounter
@@ -273,6 +640,80 @@ func (bpmap *BreakpointMap) Set(addr uint64, kind BreakpointKind, cond ast.Expr,
 	return newBreakpoint, nil
 }
 
+// SetWatchpoint creates a hardware watchpoint for the size bytes of memory
+// starting at addr, calling writeWatchpoint to program the debug
+// registers. Do not call this function, call proc.Process.SetWatchpoint
+// instead, this function exists to implement proc.Process.SetWatchpoint.
+func (bpmap *BreakpointMap) SetWatchpoint(addr uint64, size int, wtype WatchType, cond ast.Expr, writeWatchpoint writeWatchpointFn) (*Breakpoint, error) {
+	switch size {
+	case 1, 2, 4, 8:
+		// ok
+	default:
+		return nil, fmt.Errorf("invalid watchpoint size %d", size)
+	}
+	if addr%uint64(size) != 0 {
+		return nil, fmt.Errorf("watchpoint address %#x not aligned to its size %d", addr, size)
+	}
+	switch {
+	case wtype.Execute():
+		if wtype != WatchExecute || size != 1 {
+			return nil, errors.New("invalid hardware breakpoint")
+		}
+	case wtype&(WatchRead|WatchWrite) == 0:
+		return nil, errors.New("invalid watchpoint type")
+	}
+	if bp, ok := bpmap.M[addr]; ok {
+		return bp, BreakpointExistsError{bp.File, bp.Line, bp.Addr}
+	}
+
+	used := make([]bool, MaxHWWatchpoints)
+	for _, bp := range bpmap.M {
+		if bp.WatchType != 0 {
+			used[bp.HWBreakIndex] = true
+		}
+	}
+	hwidx := -1
+	for i := range used {
+		if !used[i] {
+			hwidx = i
+			break
+		}
+	}
+	if hwidx < 0 {
+		return nil, ErrTooManyWatchpoints
+	}
+
+	newBreakpoint := &Breakpoint{
+		Addr:         addr,
+		Kind:         UserBreakpoint,
+		HitCount:     map[int]uint64{},
+		Cond:         cond,
+		WatchType:    wtype,
+		WatchSize:    size,
+		HWBreakIndex: uint8(hwidx),
+	}
+
+	if err := writeWatchpoint(newBreakpoint); err != nil {
+		return nil, err
+	}
+
+	bpmap.breakpointIDCounter++
+	newBreakpoint.ID = bpmap.breakpointIDCounter
+	bpmap.M[addr] = newBreakpoint
+
+	return newBreakpoint, nil
+}
+
+// FindWatchpoint returns the watchpoint using the debug register hwidx.
+func (bpmap *BreakpointMap) FindWatchpoint(hwidx uint8) (*Breakpoint, bool) {
+	for _, bp := range bpmap.M {
+		if bp.WatchType != 0 && bp.HWBreakIndex == hwidx {
+			return bp, true
+		}
+	}
+	return nil, false
+}
+
 // SetWithID creates a breakpoint at addr, with the specified ID.
 func (bpmap *BreakpointMap) SetWithID(id int, addr uint64, writeBreakpoint writeBreakpointFn) (*Breakpoint, error) {
 	bp, err := bpmap.Set(addr, UserBreakpoint, nil, writeBreakpoint)
@@ -291,14 +732,26 @@ func (bpmap *BreakpointMap) Clear(addr uint64, clearBreakpoint clearBreakpointFn
 		return nil, NoBreakpointError{Addr: addr}
 	}
 
+	disarmed := bp.Disarmed()
 	bp.Kind &= ^UserBreakpoint
+	bp.Disabled = false
+	bp.Group = ""
 	bp.Cond = nil
+	bp.HitCond = nil
+	bp.MaxHits = 0
+	bp.OnReturn = false
+	bp.CgoCatch = 0
+	bp.RecordStack = 0
+	bp.stackRecords = nil
+	bp.Suspend = SuspendAll
 	if bp.Kind != 0 {
 		return bp, nil
 	}
 
-	if err := clearBreakpoint(bp); err != nil {
-		return nil, err
+	if !disarmed {
+		if err := clearBreakpoint(bp); err != nil {
+			return nil, err
+		}
 	}
 
 	delete(bpmap.M, addr)
@@ -306,16 +759,104 @@ func (bpmap *BreakpointMap) Clear(addr uint64, clearBreakpoint clearBreakpointFn
 	return bp, nil
 }
 
+// ClearKind removes kind from the breakpoint at addr, calling
+// clearBreakpoint if no other kind is left or if what is left is a
+// disabled user breakpoint.
+// Do not call this function call proc.Process.ClearBreakpointKind instead.
+func (bpmap *BreakpointMap) ClearKind(addr uint64, kind BreakpointKind, clearBreakpoint clearBreakpointFn) (*Breakpoint, error) {
+	if kind == UserBreakpoint {
+		return bpmap.Clear(addr, clearBreakpoint)
+	}
+	bp, ok := bpmap.M[addr]
+	if !ok || bp.Kind&kind == 0 {
+		return nil, NoBreakpointError{Addr: addr}
+	}
+
+	bp.Kind &^= kind
+	if bp.Kind&steppingBreakpoints == 0 {
+		bp.internalCond = nil
+		bp.internalGoroutineID = 0
+	}
+	if bp.Kind != 0 && !bp.Disarmed() {
+		return bp, nil
+	}
+
+	if err := clearBreakpoint(bp); err != nil {
+		return nil, err
+	}
+	if bp.Kind == 0 {
+		delete(bpmap.M, addr)
+	}
+
+	return bp, nil
+}
+
+// SetDisabled disables or enables the user breakpoints in bps, calling
+// clearBreakpoint or writeBreakpoint for the ones that need to be removed
+// from or written back to memory. If an error occurs the breakpoints that
+// were already changed are restored, either all of bps are changed or none
+// of them is. Do not call this function, call
+// proc.Process.SetBreakpointsDisabled instead, this function exists to
+// implement proc.Process.SetBreakpointsDisabled.
+func (bpmap *BreakpointMap) SetDisabled(bps []*Breakpoint, disabled bool, writeBreakpoint writeBreakpointFn, clearBreakpoint clearBreakpointFn) error {
+	var changed []*Breakpoint
+	for _, bp := range bps {
+		if !bp.IsUser() || bp.Disabled == disabled {
+			continue
+		}
+		if err := bp.setDisabled(disabled, writeBreakpoint, clearBreakpoint); err != nil {
+			for i := len(changed) - 1; i >= 0; i-- {
+				if err1 := changed[i].setDisabled(!disabled, writeBreakpoint, clearBreakpoint); err1 != nil {
+					return fmt.Errorf("%v, additionally breakpoint %d could not be restored: %v", err, changed[i].ID, err1)
+				}
+			}
+			return err
+		}
+		changed = append(changed, bp)
+	}
+	return nil
+}
+
+// setDisabled sets bp.Disabled to disabled, removing bp from memory or
+// writing it back if needed. If an error is returned bp is unchanged.
+func (bp *Breakpoint) setDisabled(disabled bool, writeBreakpoint writeBreakpointFn, clearBreakpoint clearBreakpointFn) error {
+	wasDisarmed := bp.Disarmed()
+	bp.Disabled = disabled
+	switch {
+	case !wasDisarmed && bp.Disarmed():
+		if err := clearBreakpoint(bp); err != nil {
+			bp.Disabled = !disabled
+			return err
+		}
+	case wasDisarmed && !bp.Disarmed():
+		_, _, _, originalData, err := writeBreakpoint(bp.Addr)
+		if err != nil {
+			bp.Disabled = !disabled
+			return err
+		}
+		bp.OriginalData = originalData
+	}
+	return nil
+}
+
 // ClearInternalBreakpoints removes all internal breakpoints from the map,
 // calling clearBreakpoint on each one.
 // Do not call this function, call proc.Process.ClearInternalBreakpoints
 // instead, this function is used to implement that.
 func (bpmap *BreakpointMap) ClearInternalBreakpoints(clearBreakpoint clearBreakpointFn) error {
 	for addr, bp := range bpmap.M {
-		bp.Kind = bp.Kind & UserBreakpoint
+		wasInternal := bp.Kind&steppingBreakpoints != 0
+		bp.Kind = bp.Kind & (UserBreakpoint | FreezeBreakpoint)
 		bp.internalCond = nil
+		bp.internalGoroutineID = 0
 		bp.returnInfo = nil
+		bp.Origin = ""
 		if bp.Kind != 0 {
+			if wasInternal && bp.Disarmed() {
+				if err := clearBreakpoint(bp); err != nil {
+					return err
+				}
+			}
 			continue
 		}
 		if err := clearBreakpoint(bp); err != nil {
@@ -326,17 +867,82 @@ func (bpmap *BreakpointMap) ClearInternalBreakpoints(clearBreakpoint clearBreakp
 	return nil
 }
 
+// setInternalOrigin sets the Origin of all internal breakpoints that
+// don't have one yet.
+func (bpmap *BreakpointMap) setInternalOrigin(origin string) {
+	for _, bp := range bpmap.M {
+		if bp.Kind&steppingBreakpoints != 0 && bp.Origin == "" {
+			bp.Origin = origin
+		}
+	}
+}
+
 // HasInternalBreakpoints returns true if bpmap has at least one internal
-// breakpoint set.
+// breakpoint set by next, step or stepout.
 func (bpmap *BreakpointMap) HasInternalBreakpoints() bool {
 	for _, bp := range bpmap.M {
-		if bp.Kind != UserBreakpoint {
+		if bp.Kind&steppingBreakpoints != 0 {
 			return true
 		}
 	}
 	return false
 }
 
+// disableExhaustedBreakpoints disables the user breakpoints whose
+// TotalHitCount reached MaxHits, including logpoints, that never stop the
+// target.
+func disableExhaustedBreakpoints(p Process) error {
+	var bps []*Breakpoint
+	for _, bp := range p.Breakpoints().M {
+		if bp.IsUser() && !bp.Disabled && bp.MaxHits > 0 && bp.TotalHitCount >= bp.MaxHits {
+			bps = append(bps, bp)
+		}
+	}
+	if len(bps) == 0 {
+		return nil
+	}
+	return p.SetBreakpointsDisabled(bps, true)
+}
+
+// runBreakpointActions executes the actions of bp, that was hit by
+// thread, and calls the breakpoint actions function of the process with
+// their results. Returns true if execution should be resumed.
+func runBreakpointActions(p Process, thread Thread, bp *Breakpoint) bool {
+	resume := false
+	results := make([]BreakpointActionResult, 0, len(bp.Actions))
+	for i := range bp.Actions {
+		action := &bp.Actions[i]
+		result := BreakpointActionResult{Action: action}
+		switch action.Kind {
+		case EvalAction:
+			scope, err := GoroutineScope(thread)
+			if err != nil {
+				result.Err = err
+				break
+			}
+			result.Variable, result.Err = scope.EvalVariable(action.Expr, LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1})
+		case StackAction:
+			result.Stack, result.Err = ThreadStacktrace(thread, action.Depth)
+		case ContinueAction:
+			resume = true
+			continue
+		case EnableAction:
+			result.Err = fmt.Errorf("no breakpoint with ID %d", action.BreakpointID)
+			for _, other := range p.Breakpoints().M {
+				if other.IsUser() && other.ID == action.BreakpointID {
+					result.Err = p.SetBreakpointsDisabled([]*Breakpoint{other}, false)
+					break
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	if fn := p.Common().actionsFn; fn != nil {
+		fn(thread, bp, results)
+	}
+	return resume
+}
+
 // BreakpointState describes the state of a breakpoint in a thread.
 type BreakpointState struct {
 	*Breakpoint
@@ -348,6 +954,9 @@ type BreakpointState struct {
 	// CondError contains any error encountered while evaluating the
 	// breakpoint's condition.
 	CondError error
+	// WatchAddr is the address of the memory access that triggered the
+	// watchpoint, if Breakpoint is a watchpoint.
+	WatchAddr uint64
 }
 
 func (bpstate *BreakpointState) Clear() {
@@ -355,6 +964,7 @@ func (bpstate *BreakpointState) Clear() {
 	bpstate.Active = false
 	bpstate.Internal = false
 	bpstate.CondError = nil
+	bpstate.WatchAddr = 0
 }
 
 func (bpstate *BreakpointState) String() string {
@@ -412,6 +1022,11 @@ func (rbpi *returnBreakpointInfo) Collect(thread Thread) []*Variable {
 		return returnInfoError("could not read function entry", err, thread)
 	}
 
+	return returnVariables(scope, thread)
+}
+
+// returnVariables returns the return variables of the function of scope.
+func returnVariables(scope *EvalScope, thread Thread) []*Variable {
 	vars, err := scope.Locals()
 	if err != nil {
 		return returnInfoError("could not evaluate return variables", err, thread)
@@ -429,6 +1044,16 @@ func (rbpi *returnBreakpointInfo) Collect(thread Thread) []*Variable {
 	return vars
 }
 
+// collectReturnValues returns the return values of the function thread is
+// stopped in, thread must be stopped on one of its return instructions.
+func collectReturnValues(thread Thread) []*Variable {
+	scope, err := GoroutineScope(thread)
+	if err != nil {
+		return returnInfoError("could not get scope", err, thread)
+	}
+	return returnVariables(scope, thread)
+}
+
 func returnInfoError(descr string, err error, mem MemoryReadWriter) []*Variable {
 	v := newConstant(constant.MakeString(fmt.Sprintf("%s: %v", descr, err.Error())), mem)
 	v.Name = "return value read error"
@@ -0,0 +1,150 @@
+package proc
+
+import (
+	"errors"
+	"strings"
+)
+
+// CgoDirection is the direction of a crossing of the boundary between Go
+// and C code.
+type CgoDirection uint8
+
+const (
+	// CgoCall is a call from Go into a C function, through
+	// runtime.asmcgocall.
+	CgoCall CgoDirection = iota + 1
+	// CgoReturn is the return from a C function back into Go.
+	CgoReturn
+	// CgoCallback is a call from C back into a Go function, through
+	// runtime.cgocallbackg.
+	CgoCallback
+)
+
+func (dir CgoDirection) String() string {
+	switch dir {
+	case CgoCall:
+		return "call"
+	case CgoReturn:
+		return "return"
+	case CgoCallback:
+		return "callback"
+	default:
+		return "unknown"
+	}
+}
+
+// CgoEvent describes a cgo catchpoint stop, see SetCgoCatchpoints.
+type CgoEvent struct {
+	Direction CgoDirection
+	// Fn is the address of the C function being called, only valid if
+	// Direction is CgoCall. If the C function can not be found it is the
+	// address of the wrapper generated by cgo to call it.
+	Fn uint64
+	// FnName is the name of the C function at Fn, if known.
+	FnName string
+}
+
+// SetCgoCatchpoints sets user breakpoints that stop the target every time
+// it calls a C function, when the C function returns and when C code
+// calls back into Go. When one of them is hit the Cgo field of the thread
+// describes the crossing.
+// Since runtime.asmcgocall is also used by the runtime to switch to the
+// system stack, calls that do not go through cgo may be reported too.
+func SetCgoCatchpoints(p Process) ([]*Breakpoint, error) {
+	bi := p.BinInfo()
+	if bi.LookupFunc["runtime.cgocallbackg"] == nil {
+		return nil, errors.New("the target does not use cgo")
+	}
+	var bps []*Breakpoint
+	clearAll := func() {
+		for _, bp := range bps {
+			p.ClearBreakpoint(bp.Addr)
+		}
+	}
+	setbp := func(addr uint64, dir CgoDirection) error {
+		bp, err := p.SetBreakpoint(addr, UserBreakpoint, nil)
+		if err != nil {
+			return err
+		}
+		bp.CgoCatch = dir
+		bps = append(bps, bp)
+		return nil
+	}
+	addr, err := FindFunctionLocation(p, "runtime.asmcgocall", false, 0)
+	if err == nil {
+		err = setbp(addr, CgoCall)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rets, err := FunctionReturnLocations(p, "runtime.asmcgocall")
+	if err != nil {
+		clearAll()
+		return nil, err
+	}
+	for _, ret := range rets {
+		if err := setbp(ret, CgoReturn); err != nil {
+			clearAll()
+			return nil, err
+		}
+	}
+	addr, err = FindFunctionLocation(p, "runtime.cgocallbackg", true, 0)
+	if err == nil {
+		err = setbp(addr, CgoCallback)
+	}
+	if err != nil {
+		clearAll()
+		return nil, err
+	}
+	return bps, nil
+}
+
+// ClearCgoCatchpoints clears all breakpoints set by SetCgoCatchpoints.
+func ClearCgoCatchpoints(p Process) error {
+	for _, bp := range p.Breakpoints().M {
+		if bp.CgoCatch == 0 {
+			continue
+		}
+		if _, err := p.ClearBreakpoint(bp.Addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cgoEvent returns a description of the cgo boundary crossing done by
+// thread, stopped at cgo catchpoint bp.
+func cgoEvent(thread Thread, bp *Breakpoint) *CgoEvent {
+	ev := &CgoEvent{Direction: bp.CgoCatch}
+	if bp.CgoCatch != CgoCall {
+		return ev
+	}
+	regs, err := thread.Registers(false)
+	if err != nil {
+		return ev
+	}
+	// runtime.asmcgocall uses the stack based calling convention, its first
+	// argument is the address of the C function, right above the return
+	// address.
+	ptrSize := int64(thread.Arch().PtrSize())
+	ev.Fn, err = readUintRaw(thread, uintptr(int64(regs.SP())+ptrSize), ptrSize)
+	if err != nil {
+		return ev
+	}
+	if fn := thread.BinInfo().PCToFunc(ev.Fn); fn != nil {
+		ev.FnName = fn.Name
+	}
+	// Calls to C functions go through a wrapper generated by cgo, named
+	// _cgo_<hash>_Cfunc_<name>, that unpacks the arguments.
+	if i := strings.Index(ev.FnName, cgoWrapperInfix); strings.HasPrefix(ev.FnName, "_cgo_") && i >= 0 {
+		ev.FnName = ev.FnName[i+len(cgoWrapperInfix):]
+		if fn := thread.BinInfo().LookupFunc[ev.FnName]; fn != nil {
+			ev.Fn = fn.Entry
+		}
+	}
+	return ev
+}
+
+// cgoWrapperInfix precedes the name of the C function in the name of the
+// wrappers generated by cgo.
+const cgoWrapperInfix = "_Cfunc_"
@@ -283,6 +283,10 @@ func (p *Process) ClearBreakpoint(addr uint64) (*proc.Breakpoint, error) {
 	return nil, proc.NoBreakpointError{Addr: addr}
 }
 
+func (p *Process) ClearBreakpointKind(addr uint64, kind proc.BreakpointKind) (*proc.Breakpoint, error) {
+	return nil, proc.NoBreakpointError{Addr: addr}
+}
+
 func (p *Process) ClearInternalBreakpoints() error {
 	return nil
 }
@@ -334,6 +338,14 @@ func (p *Process) SetBreakpoint(addr uint64, kind proc.BreakpointKind, cond ast.
 	return nil, ErrWriteCore
 }
 
+func (p *Process) SetWatchpoint(addr uint64, size int, wtype proc.WatchType, cond ast.Expr) (*proc.Breakpoint, error) {
+	return nil, ErrWriteCore
+}
+
+func (p *Process) SetBreakpointsDisabled(bps []*proc.Breakpoint, disabled bool) error {
+	return ErrWriteCore
+}
+
 func (p *Process) SwitchGoroutine(gid int) error {
 	g, err := proc.FindGoroutine(p, gid)
 	if err != nil {
@@ -0,0 +1,172 @@
+package proc
+
+import (
+	"fmt"
+	"go/constant"
+)
+
+// blockingWaitReasons is the set of wait reasons of goroutines that can
+// only be woken up by another goroutine.
+var blockingWaitReasons = map[string]bool{
+	"chan receive":            true,
+	"chan send":               true,
+	"chan receive (nil chan)": true,
+	"chan send (nil chan)":    true,
+	"select":                  true,
+	"select (no cases)":       true,
+	"semacquire":              true,
+	"sync.Cond.Wait":          true,
+}
+
+// maxSudogChain is the maximum number of sudogs of a goroutine read by
+// G.waitingChans, it bounds the number of cases of select statements.
+const maxSudogChain = 256
+
+// Deadlock describes a deadlock of the target, see DetectDeadlock.
+type Deadlock struct {
+	Goroutines []BlockedGoroutine
+}
+
+// BlockedGoroutine is a goroutine waiting for a resource that no other
+// goroutine will ever release.
+type BlockedGoroutine struct {
+	G *G
+	// Reason is the wait reason of the goroutine.
+	Reason string
+	// Chans contains the addresses of the channels the goroutine is blocked
+	// on, goroutines blocked in a select statement can wait on more than
+	// one channel.
+	Chans []uint64
+}
+
+// DetectDeadlock returns a description of the deadlock the target is in,
+// or nil if it isn't deadlocked. The target is deadlocked if all its
+// goroutines, except the ones started by the runtime, are parked waiting on
+// a channel, a select statement, a semaphore or a condition variable, and
+// at least one such goroutine exists.
+// Like runtime.checkdead the target isn't considered deadlocked while the
+// runtime has pending timers, that can wake up goroutines sleeping or
+// waiting on a channel of time.After or of a ticker, or while signals are
+// delivered to channels registered with signal.Notify.
+func DetectDeadlock(p Process) (*Deadlock, error) {
+	if _, err := p.Valid(); err != nil {
+		return nil, err
+	}
+	gs, err := GoroutinesInfo(p)
+	if err != nil {
+		return nil, err
+	}
+	dl := &Deadlock{}
+	for _, g := range gs {
+		if g.Status&^gscanStatus == Gdead {
+			continue
+		}
+		if fn := g.StartLoc().Fn; fn != nil && fn.Name == signalLoopFunctionName {
+			return nil, nil
+		}
+		if g.System() {
+			continue
+		}
+		if !g.Waiting() || !blockingWaitReasons[g.WaitReason] {
+			return nil, nil
+		}
+		dl.Goroutines = append(dl.Goroutines, BlockedGoroutine{G: g, Reason: g.WaitReason, Chans: g.waitingChans()})
+	}
+	if len(dl.Goroutines) == 0 {
+		return nil, nil
+	}
+	scope, err := ThreadScope(p.CurrentThread())
+	if err != nil {
+		return nil, err
+	}
+	if pendingTimers(scope) {
+		return nil, nil
+	}
+	return dl, nil
+}
+
+// signalLoopFunctionName is the function of the goroutine started by
+// signal.Notify to deliver signals to the registered channels.
+const signalLoopFunctionName = "os/signal.loop"
+
+// pendingTimers returns true if the runtime has timers that did not fire
+// yet. Since Go 1.14 timers are stored in the heaps of the Ps, a struct
+// since Go 1.23, before in 64 buckets and before Go 1.10 in a single heap.
+// If the timers can not be read they are assumed to be pending, so that
+// DetectDeadlock doesn't report deadlocks that aren't.
+func pendingTimers(scope *EvalScope) bool {
+	if nprocs, err := evalInt(scope, "len(runtime.allp)"); err == nil {
+		for i := int64(0); i < nprocs; i++ {
+			n, err := evalInt(scope, fmt.Sprintf("len(runtime.allp[%d].timers.heap)", i))
+			if err != nil {
+				n, err = evalInt(scope, fmt.Sprintf("len(runtime.allp[%d].timers)", i))
+			}
+			if err != nil || n > 0 {
+				return true
+			}
+		}
+		return false
+	}
+	if n, err := evalInt(scope, "len(runtime.timers)"); err == nil {
+		for i := int64(0); i < n; i++ {
+			if cnt, err := evalInt(scope, fmt.Sprintf("len(runtime.timers[%d].t)", i)); err != nil || cnt > 0 {
+				return true
+			}
+		}
+		return false
+	}
+	n, err := evalInt(scope, "len(runtime.timers.t)")
+	return err != nil || n > 0
+}
+
+// evalInt evaluates expr, which must have an integer value, in scope.
+func evalInt(scope *EvalScope, expr string) (int64, error) {
+	v, err := scope.EvalExpression(expr, loadSingleValue)
+	if err != nil {
+		return 0, err
+	}
+	if v.Unreadable != nil {
+		return 0, v.Unreadable
+	}
+	if v.Value == nil || v.Value.Kind() != constant.Int {
+		return 0, fmt.Errorf("%s is not an integer", expr)
+	}
+	n, _ := constant.Int64Val(v.Value)
+	return n, nil
+}
+
+// waitingChans returns the addresses of the channels g is blocked on,
+// following the list of sudogs starting at g.waiting.
+func (g *G) waitingChans() []uint64 {
+	if g.variable == nil || g.variable.Unreadable != nil {
+		return nil
+	}
+	sv, err := g.variable.structMember("waiting")
+	if err != nil {
+		return nil
+	}
+	ptrSize := int64(g.variable.bi.Arch.PtrSize())
+	var r []uint64
+	for i := 0; i < maxSudogChain; i++ {
+		sv = sv.maybeDereference()
+		if sv.Unreadable != nil || sv.Addr == 0 {
+			break
+		}
+		cv, err := sv.structMember("c")
+		if err != nil {
+			break
+		}
+		c, err := readUintRaw(cv.mem, uintptr(cv.Addr), ptrSize)
+		if err != nil {
+			break
+		}
+		if c != 0 {
+			r = append(r, c)
+		}
+		sv, err = sv.structMember("waitlink")
+		if err != nil {
+			break
+		}
+	}
+	return r
+}
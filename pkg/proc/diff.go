@@ -0,0 +1,255 @@
+package proc
+
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxVariableDifferences is the maximum number of differences returned by
+// DiffVariables.
+const maxVariableDifferences = 1000
+
+// VariableDifference is a difference between two values found by
+// DiffVariables.
+type VariableDifference struct {
+	// Path is the path of the differing values relative to the compared
+	// values, as it would be written in an expression: it is empty if the
+	// compared values themselves differ, otherwise it is a sequence of
+	// field selectors and indexes, like .a[2].b or ["key"].
+	Path string
+	// A and B are the differing values, one of them is nil if the element
+	// only exists in one of the compared slices, arrays or maps.
+	A, B *Variable
+}
+
+// maxDiffLoadedElements bounds the number of elements of nested slices,
+// arrays and maps loaded with DiffLoadConfig.
+const maxDiffLoadedElements = 1 << 16
+
+// DiffLoadConfig returns the configuration to load the values compared by
+// DiffVariables, following pointers up to maxDepth levels deep. The number
+// of elements loaded for each slice, array and map shrinks as maxDepth
+// grows, so that loading maxDepth levels of nested collections does not
+// load more than maxDiffLoadedElements elements.
+func DiffLoadConfig(maxDepth int) LoadConfig {
+	n := 1024
+	if maxDepth > 1 {
+		if m := int(math.Pow(maxDiffLoadedElements, 1/float64(maxDepth))); m < n {
+			n = m
+		}
+	}
+	return LoadConfig{FollowPointers: true, MaxVariableRecurse: maxDepth, MaxStringLen: 1024, MaxArrayValues: n, MaxStructFields: -1, MaxMapEntries: n}
+}
+
+type diffVisit struct {
+	a, b uintptr
+	typ  string
+}
+
+type variablesDiff struct {
+	maxDepth int
+	visited  map[diffVisit]bool
+	r        []VariableDifference
+}
+
+// DiffVariables compares a and b, usually loaded with DiffLoadConfig, and
+// returns the paths where they differ. Values are compared like
+// reflect.DeepEqual does: pointers are followed and are equal if they
+// point to equal values, slices are equal if they have the same elements
+// and the elements of maps are matched by key. Values nested more than
+// maxDepth levels deep, or that were not loaded, are not compared, nor are
+// values reached again through a cycle of pointers. At most 1000
+// differences are returned.
+func DiffVariables(a, b *Variable, maxDepth int) []VariableDifference {
+	d := &variablesDiff{maxDepth: maxDepth, visited: make(map[diffVisit]bool)}
+	d.diff("", a, b, 0)
+	return d.r
+}
+
+func (d *variablesDiff) add(path string, a, b *Variable) {
+	if len(d.r) < maxVariableDifferences {
+		d.r = append(d.r, VariableDifference{Path: path, A: a, B: b})
+	}
+}
+
+func (d *variablesDiff) diff(path string, a, b *Variable, depth int) {
+	if len(d.r) >= maxVariableDifferences || depth > d.maxDepth {
+		return
+	}
+	if (a.Unreadable == nil) != (b.Unreadable == nil) || (a.Unreadable != nil && a.Unreadable.Error() != b.Unreadable.Error()) {
+		d.add(path, a, b)
+		return
+	}
+	if a.Unreadable != nil || a.OnlyAddr || b.OnlyAddr {
+		return
+	}
+	if a.Kind != b.Kind || diffTypeString(a) != diffTypeString(b) {
+		d.add(path, a, b)
+		return
+	}
+
+	switch a.Kind {
+	case reflect.Ptr:
+		if len(a.Children) == 0 || len(b.Children) == 0 {
+			return
+		}
+		pa, pb := &a.Children[0], &b.Children[0]
+		if pa.Addr == 0 || pb.Addr == 0 {
+			if pa.Addr != pb.Addr {
+				d.add(path, a, b)
+			}
+			return
+		}
+		visit := diffVisit{uintptr(pa.Addr), uintptr(pb.Addr), diffTypeString(pa)}
+		if d.visited[visit] {
+			return
+		}
+		d.visited[visit] = true
+		d.diff(path, pa, pb, depth)
+
+	case reflect.Interface:
+		if len(a.Children) == 0 || len(b.Children) == 0 {
+			if len(a.Children) != len(b.Children) {
+				d.add(path, a, b)
+			}
+			return
+		}
+		d.diff(path, &a.Children[0], &b.Children[0], depth)
+
+	case reflect.Struct:
+		if len(a.Children) != len(b.Children) {
+			return
+		}
+		for i := range a.Children {
+			d.diff(path+"."+a.Children[i].Name, &a.Children[i], &b.Children[i], depth+1)
+		}
+
+	case reflect.Array, reflect.Slice:
+		if a.Kind == reflect.Slice && (a.Base == 0) != (b.Base == 0) {
+			d.add(path, a, b)
+			return
+		}
+		n := len(a.Children)
+		if len(b.Children) < n {
+			n = len(b.Children)
+		}
+		for i := 0; i < n; i++ {
+			d.diff(path+"["+strconv.Itoa(i)+"]", &a.Children[i], &b.Children[i], depth+1)
+		}
+		for i := n; i < len(a.Children) && int64(i) >= b.Len; i++ {
+			d.add(path+"["+strconv.Itoa(i)+"]", &a.Children[i], nil)
+		}
+		for i := n; i < len(b.Children) && int64(i) >= a.Len; i++ {
+			d.add(path+"["+strconv.Itoa(i)+"]", nil, &b.Children[i])
+		}
+
+	case reflect.Map:
+		if (a.Base == 0) != (b.Base == 0) {
+			d.add(path, a, b)
+			return
+		}
+		// keys missing from a map that was not loaded completely may just
+		// not have been loaded
+		complete := a.Len == int64(len(a.Children)/2) && b.Len == int64(len(b.Children)/2)
+		bvals := make(map[string]*Variable)
+		for i := 0; i+1 < len(b.Children); i += 2 {
+			bvals[diffMapKey(&b.Children[i])] = &b.Children[i+1]
+		}
+		akeys := make(map[string]bool)
+		for i := 0; i+1 < len(a.Children); i += 2 {
+			key := diffMapKey(&a.Children[i])
+			akeys[key] = true
+			if bv, ok := bvals[key]; ok {
+				d.diff(path+"["+key+"]", &a.Children[i+1], bv, depth+1)
+			} else if complete {
+				d.add(path+"["+key+"]", &a.Children[i+1], nil)
+			}
+		}
+		if complete {
+			for i := 0; i+1 < len(b.Children); i += 2 {
+				if key := diffMapKey(&b.Children[i]); !akeys[key] {
+					d.add(path+"["+key+"]", nil, &b.Children[i+1])
+				}
+			}
+		}
+
+	case reflect.String:
+		if a.Len != b.Len || !diffConstEqual(a.Value, b.Value) {
+			d.add(path, a, b)
+		}
+
+	case reflect.Chan, reflect.Func:
+		if a.Base != b.Base {
+			d.add(path, a, b)
+		}
+
+	default:
+		if !diffConstEqual(a.Value, b.Value) {
+			d.add(path, a, b)
+			return
+		}
+		if len(a.Children) != len(b.Children) {
+			d.add(path, a, b)
+			return
+		}
+		for i := range a.Children {
+			if a.Children[i].Addr != b.Children[i].Addr {
+				d.add(path, a, b)
+				return
+			}
+		}
+	}
+}
+
+func diffTypeString(v *Variable) string {
+	if v.RealType == nil {
+		return ""
+	}
+	return v.RealType.String()
+}
+
+func diffConstEqual(a, b constant.Value) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	return constant.Compare(a, token.EQL, b)
+}
+
+// diffMapKey returns a string identifying the map key k, written like the
+// key would be in an expression when possible.
+func diffMapKey(k *Variable) string {
+	switch k.Kind {
+	case reflect.String:
+		if k.Value != nil {
+			return strconv.Quote(constant.StringVal(k.Value))
+		}
+	case reflect.Ptr, reflect.UnsafePointer:
+		if len(k.Children) > 0 {
+			return fmt.Sprintf("(%s)(%#x)", diffTypeString(k), k.Children[0].Addr)
+		}
+	case reflect.Chan:
+		return fmt.Sprintf("(%s)(%#x)", diffTypeString(k), k.Base)
+	case reflect.Interface:
+		if len(k.Children) > 0 {
+			return diffMapKey(&k.Children[0])
+		}
+	case reflect.Struct, reflect.Array:
+		fields := make([]string, len(k.Children))
+		for i := range k.Children {
+			fields[i] = diffMapKey(&k.Children[i])
+		}
+		return diffTypeString(k) + "{" + strings.Join(fields, ", ") + "}"
+	}
+	if k.Value != nil {
+		return k.Value.ExactString()
+	}
+	return ""
+}
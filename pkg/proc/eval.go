@@ -7,11 +7,11 @@ import (
 	"fmt"
 	"go/ast"
 	"go/constant"
-	"go/parser"
 	"go/printer"
 	"go/token"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/derekparker/delve/pkg/dwarf/godwarf"
 	"github.com/derekparker/delve/pkg/dwarf/reader"
@@ -19,13 +19,87 @@ import (
 
 var OperationOnSpecialFloatError = errors.New("operations on non-finite floats not implemented")
 
+// errKeyNotFound is returned by mapAccess when the map does not contain the
+// key.
+var errKeyNotFound = errors.New("key not found")
+
 // EvalExpression returns the value of the given expression.
 func (scope *EvalScope) EvalExpression(expr string, cfg LoadConfig) (*Variable, error) {
-	t, err := parser.ParseExpr(expr)
+	t, err := ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return scope.evalParsedExpression(t, expr, cfg)
+}
+
+// EvalExpressionChildren evaluates expr, which must be a map, a slice or an
+// array, loading only limit of its children starting from the one at index
+// offset, key/value pairs for maps and elements for slices and arrays.
+// The length of the returned variable is the length of the whole value, so
+// that a client can request one page of it at a time.
+// Maps are read by iterating over them, loading entries far from the start
+// of large maps takes longer than loading the first ones.
+func (scope *EvalScope) EvalExpressionChildren(expr string, offset, limit int, cfg LoadConfig) (*Variable, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("invalid offset %d or limit %d", offset, limit)
+	}
+	t, err := ParseExpr(expr)
 	if err != nil {
 		return nil, err
 	}
+	ev, err := scope.evalAST(t)
+	if err != nil {
+		return nil, err
+	}
+	if ev.Unreadable != nil {
+		return nil, ev.Unreadable
+	}
+	if ev.Name == "" {
+		ev.Name = expr
+	}
+
+	switch ev.Kind {
+	case reflect.Map:
+		ev.mapSkip += offset
+		cfg.MaxMapEntries = limit
+		ev.loadValue(cfg)
+		if ev.Unreadable != nil {
+			return nil, ev.Unreadable
+		}
+		if offset > 0 {
+			ev.Flags |= VariableTruncated
+		}
+
+	case reflect.Slice, reflect.Array:
+		if int64(offset) > ev.Len {
+			return nil, fmt.Errorf("index out of bounds")
+		}
+		high := ev.Len
+		if int64(offset+limit) < high {
+			high = int64(offset + limit)
+		}
+		ev.loaded = true
+		if int64(offset) < high {
+			page, err := ev.reslice(int64(offset), high, -1)
+			if err != nil {
+				return nil, err
+			}
+			cfg.MaxArrayValues = limit
+			page.loadValue(cfg)
+			ev.Children = page.Children
+		}
+		if int64(len(ev.Children)) < ev.Len {
+			ev.Flags |= VariableTruncated
+		}
+
+	default:
+		return nil, fmt.Errorf("%s (type %s) is not a map, a slice or an array", expr, ev.TypeString())
+	}
+	return ev, nil
+}
 
+// evalParsedExpression evaluates t, the result of parsing expr.
+func (scope *EvalScope) evalParsedExpression(t ast.Expr, expr string, cfg LoadConfig) (*Variable, error) {
 	ev, err := scope.evalToplevelTypeCast(t, cfg)
 	if ev == nil && err == nil {
 		ev, err = scope.evalAST(t)
@@ -150,18 +224,19 @@ func (scope *EvalScope) evalToplevelTypeCast(t ast.Expr, cfg LoadConfig) (*Varia
 func (scope *EvalScope) evalAST(t ast.Expr) (*Variable, error) {
 	switch node := t.(type) {
 	case *ast.CallExpr:
+		if v, ok := scope.callResults[node]; ok {
+			return v, nil
+		}
 		if len(node.Args) == 1 {
 			v, err := scope.evalTypeCast(node)
 			if err == nil {
 				return v, nil
 			}
 			_, isident := node.Fun.(*ast.Ident)
-			// we don't support function calls at the moment except for a few
-			// builtin functions so just return the type error here if the function
+			// unless function calls are enabled only a few builtin functions
+			// can be called so just return the type error here if the function
 			// isn't an identifier.
-			// More sophisticated logic will be required when function calls
-			// are implemented.
-			if err != reader.TypeNotFoundErr || !isident {
+			if err != reader.TypeNotFoundErr || (!isident && scope.callResults == nil) {
 				return v, err
 			}
 		}
@@ -241,7 +316,7 @@ func (scope *EvalScope) evalAST(t ast.Expr) (*Variable, error) {
 func exprToString(t ast.Expr) string {
 	var buf bytes.Buffer
 	printer.Fprint(&buf, token.NewFileSet(), t)
-	return buf.String()
+	return strings.Replace(buf.String(), registerPrefix, "$", -1)
 }
 
 func removeParen(n ast.Expr) ast.Expr {
@@ -287,8 +362,20 @@ func (scope *EvalScope) evalTypeCast(node *ast.CallExpr) (*Variable, error) {
 		switch argv.Kind {
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			// ok
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 			// ok
+		case reflect.Ptr, reflect.UnsafePointer:
+			// any pointer can be converted to and from unsafe.Pointer, other
+			// pointers must point to types with the same underlying type
+			_, tovoid := ttyp.Type.(*godwarf.VoidType)
+			if argv.Kind != reflect.UnsafePointer && !tovoid && !sameUnderlyingType(argv.RealType.(*godwarf.PtrType).Type, ttyp.Type) {
+				return nil, converr
+			}
+			if len(argv.Children) == 0 {
+				return nil, converr
+			}
+			v.Children = []Variable{*(scope.newVariable("", argv.Children[0].Addr, ttyp.Type, DereferenceMemory(argv.mem)))}
+			return v, nil
 		default:
 			return nil, converr
 		}
@@ -304,15 +391,15 @@ func (scope *EvalScope) evalTypeCast(node *ast.CallExpr) (*Variable, error) {
 			n, _ := constant.Int64Val(argv.Value)
 			v.Value = constant.MakeUint64(convertInt(uint64(n), false, ttyp.Size()))
 			return v, nil
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 			n, _ := constant.Uint64Val(argv.Value)
 			v.Value = constant.MakeUint64(convertInt(n, false, ttyp.Size()))
 			return v, nil
 		case reflect.Float32, reflect.Float64:
 			x, _ := constant.Float64Val(argv.Value)
-			v.Value = constant.MakeUint64(uint64(x))
+			v.Value = constant.MakeUint64(convertInt(uint64(x), false, ttyp.Size()))
 			return v, nil
-		case reflect.Ptr:
+		case reflect.Ptr, reflect.UnsafePointer:
 			v.Value = constant.MakeUint64(uint64(argv.Children[0].Addr))
 			return v, nil
 		}
@@ -322,13 +409,13 @@ func (scope *EvalScope) evalTypeCast(node *ast.CallExpr) (*Variable, error) {
 			n, _ := constant.Int64Val(argv.Value)
 			v.Value = constant.MakeInt64(int64(convertInt(uint64(n), true, ttyp.Size())))
 			return v, nil
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 			n, _ := constant.Uint64Val(argv.Value)
 			v.Value = constant.MakeInt64(int64(convertInt(n, true, ttyp.Size())))
 			return v, nil
 		case reflect.Float32, reflect.Float64:
 			x, _ := constant.Float64Val(argv.Value)
-			v.Value = constant.MakeInt64(int64(x))
+			v.Value = constant.MakeInt64(int64(convertInt(uint64(int64(x)), true, ttyp.Size())))
 			return v, nil
 		}
 	case *godwarf.FloatType:
@@ -338,9 +425,21 @@ func (scope *EvalScope) evalTypeCast(node *ast.CallExpr) (*Variable, error) {
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 			fallthrough
 		case reflect.Float32, reflect.Float64:
+			v.Value = argv.Value
+			if ttyp.Size() == 4 {
+				x, _ := constant.Float64Val(argv.Value)
+				v.Value = constant.MakeFloat64(float64(float32(x)))
+			}
+			v.FloatSpecial = argv.FloatSpecial
+			return v, nil
+		}
+	case *godwarf.BoolType:
+		if argv.Kind == reflect.Bool {
 			v.Value = argv.Value
 			return v, nil
 		}
+	case *godwarf.StringType:
+		return scope.convertToString(argv, styp, converr)
 	case *godwarf.ComplexType:
 		switch argv.Kind {
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -353,9 +452,75 @@ func (scope *EvalScope) evalTypeCast(node *ast.CallExpr) (*Variable, error) {
 		}
 	}
 
+	// conversions between named types with the same underlying type, the
+	// result has the address of the argument and the new type.
+	if argv.Addr != 0 && argv.DwarfType != nil && sameUnderlyingType(argv.DwarfType, styp) {
+		return scope.newVariable("", argv.Addr, styp, argv.mem), nil
+	}
+
 	return nil, converr
 }
 
+// convertToString converts argv, a string, an integer or a slice of bytes or
+// runes, to styp, a string type.
+func (scope *EvalScope) convertToString(argv *Variable, styp godwarf.Type, converr error) (*Variable, error) {
+	v := newVariable("", 0, styp, scope.BinInfo, scope.Mem)
+	v.loaded = true
+
+	switch argv.Kind {
+	case reflect.String:
+		if argv.Addr != 0 {
+			return scope.newVariable("", argv.Addr, styp, argv.mem), nil
+		}
+		v.Value = argv.Value
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, _ := constant.Int64Val(argv.Value)
+		v.Value = constant.MakeString(string(rune(n)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, _ := constant.Uint64Val(argv.Value)
+		v.Value = constant.MakeString(string(rune(n)))
+	case reflect.Slice:
+		if argv.Len > maxComparisonStringLen {
+			return nil, fmt.Errorf("slice too long for conversion to string")
+		}
+		elems := argv.clone()
+		elems.loaded = false
+		elems.Children = nil
+		elems.loadValue(LoadConfig{MaxArrayValues: int(argv.Len)})
+		if elems.Unreadable != nil {
+			return nil, elems.Unreadable
+		}
+		switch elemType := argv.RealType.(*godwarf.SliceType).ElemType.(type) {
+		case *godwarf.UintType:
+			if elemType.Name != "uint8" && elemType.Name != "byte" {
+				return nil, converr
+			}
+			bytes := make([]byte, len(elems.Children))
+			for i := range elems.Children {
+				n, _ := constant.Int64Val(elems.Children[i].Value)
+				bytes[i] = byte(n)
+			}
+			v.Value = constant.MakeString(string(bytes))
+		case *godwarf.IntType:
+			if elemType.Name != "int32" && elemType.Name != "rune" {
+				return nil, converr
+			}
+			runes := make([]rune, len(elems.Children))
+			for i := range elems.Children {
+				n, _ := constant.Int64Val(elems.Children[i].Value)
+				runes[i] = rune(n)
+			}
+			v.Value = constant.MakeString(string(runes))
+		default:
+			return nil, converr
+		}
+	default:
+		return nil, converr
+	}
+	v.Len = int64(len(constant.StringVal(v.Value)))
+	return v, nil
+}
+
 func convertInt(n uint64, signed bool, size int64) uint64 {
 	buf := make([]byte, 64/8)
 	binary.BigEndian.PutUint64(buf, n)
@@ -371,9 +536,25 @@ func convertInt(n uint64, signed bool, size int64) uint64 {
 }
 
 func (scope *EvalScope) evalBuiltinCall(node *ast.CallExpr) (*Variable, error) {
+	if sel, ok := node.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Error" && len(node.Args) == 0 {
+		return scope.evalErrorMethod(node, sel.X)
+	}
+
+	if sel, ok := node.Fun.(*ast.SelectorExpr); ok {
+		if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "unsafe" {
+			return scope.evalUnsafeCall(sel.Sel.Name, node)
+		}
+		if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "strings" && stringsBuiltins[sel.Sel.Name] {
+			if _, err := scope.evalIdent(pkg); err != nil {
+				// not shadowed by a variable called strings
+				return scope.evalStringsCall(sel.Sel.Name, node)
+			}
+		}
+	}
+
 	fnnode, ok := node.Fun.(*ast.Ident)
 	if !ok {
-		return nil, fmt.Errorf("function calls are not supported")
+		return nil, scope.funcCallNeeded(node)
 	}
 
 	args := make([]*Variable, len(node.Args))
@@ -399,7 +580,213 @@ func (scope *EvalScope) evalBuiltinCall(node *ast.CallExpr) (*Variable, error) {
 		return realBuiltin(args, node.Args)
 	}
 
-	return nil, fmt.Errorf("function calls are not supported")
+	return nil, scope.funcCallNeeded(node)
+}
+
+// evalUnsafeCall evaluates calls to the functions of package unsafe:
+// Sizeof, Offsetof and Add.
+func (scope *EvalScope) evalUnsafeCall(name string, node *ast.CallExpr) (*Variable, error) {
+	nargs := map[string]int{"Sizeof": 1, "Offsetof": 1, "Add": 2}
+	n, ok := nargs[name]
+	if !ok {
+		return nil, fmt.Errorf("unsafe.%s not supported", name)
+	}
+	if len(node.Args) != n {
+		return nil, fmt.Errorf("wrong number of arguments to unsafe.%s: %d", name, len(node.Args))
+	}
+
+	switch name {
+	case "Sizeof":
+		arg, err := scope.evalAST(node.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		if arg.RealType == nil {
+			return nil, fmt.Errorf("invalid argument %s for unsafe.Sizeof", exprToString(node.Args[0]))
+		}
+		return newConstant(constant.MakeInt64(arg.RealType.Size()), scope.Mem), nil
+
+	case "Offsetof":
+		sel, ok := removeParen(node.Args[0]).(*ast.SelectorExpr)
+		if !ok {
+			return nil, fmt.Errorf("invalid argument %s for unsafe.Offsetof", exprToString(node.Args[0]))
+		}
+		xv, err := scope.evalAST(sel.X)
+		if err != nil {
+			return nil, err
+		}
+		fv, err := scope.evalAST(sel)
+		if err != nil {
+			return nil, err
+		}
+		xv = xv.maybeDereference()
+		if xv.Kind != reflect.Struct || xv.Addr == 0 || fv.Addr < xv.Addr {
+			return nil, fmt.Errorf("invalid argument %s for unsafe.Offsetof", exprToString(node.Args[0]))
+		}
+		return newConstant(constant.MakeInt64(int64(fv.Addr-xv.Addr)), scope.Mem), nil
+
+	default: // Add
+		ptr, err := scope.evalAST(node.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		ptr.loadValue(loadSingleValue)
+		if ptr.Unreadable != nil {
+			return nil, ptr.Unreadable
+		}
+		if ptr.Kind != reflect.UnsafePointer || len(ptr.Children) == 0 {
+			return nil, fmt.Errorf("invalid argument %s (type %s) for unsafe.Add", exprToString(node.Args[0]), ptr.TypeString())
+		}
+		lenv, err := scope.evalAST(node.Args[1])
+		if err != nil {
+			return nil, err
+		}
+		off, err := lenv.asInt()
+		if err != nil {
+			return nil, fmt.Errorf("can not convert \"%s\" to int: %v", exprToString(node.Args[1]), err)
+		}
+		r := newVariable("", 0, ptr.DwarfType, scope.BinInfo, scope.Mem)
+		r.loaded = true
+		elem := ptr.Children[0]
+		r.Children = []Variable{*(scope.newVariable("", uintptr(int64(elem.Addr)+off), elem.DwarfType, elem.mem))}
+		return r, nil
+	}
+}
+
+// stringsBuiltins are the functions of package strings that are evaluated
+// without calling the target, see evalStringsCall.
+var stringsBuiltins = map[string]bool{"HasPrefix": true, "HasSuffix": true, "Contains": true, "Index": true}
+
+// evalStringsCall evaluates calls to strings.HasPrefix, strings.HasSuffix,
+// strings.Contains and strings.Index without calling the target, so that
+// they can be used in breakpoint conditions. HasPrefix and HasSuffix only
+// read from the target as many bytes as the length of the prefix or
+// suffix.
+func (scope *EvalScope) evalStringsCall(name string, node *ast.CallExpr) (*Variable, error) {
+	if len(node.Args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments to strings.%s: %d", name, len(node.Args))
+	}
+	var args [2]*Variable
+	for i := range node.Args {
+		v, err := scope.evalAST(node.Args[i])
+		if err != nil {
+			return nil, err
+		}
+		if v.Unreadable != nil {
+			return nil, v.Unreadable
+		}
+		if v.Kind != reflect.String {
+			return nil, fmt.Errorf("invalid argument %s (type %s) for strings.%s", exprToString(node.Args[i]), v.TypeString(), name)
+		}
+		if v.Len > maxComparisonStringLen {
+			return nil, fmt.Errorf("string too long for strings.%s", name)
+		}
+		args[i] = v
+	}
+	s, sub := args[0], args[1]
+	subs, err := sub.stringRange(0, sub.Len)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "HasPrefix", "HasSuffix":
+		if s.Len < sub.Len {
+			return newConstant(constant.MakeBool(false), scope.Mem), nil
+		}
+		off := int64(0)
+		if name == "HasSuffix" {
+			off = s.Len - sub.Len
+		}
+		ss, err := s.stringRange(off, sub.Len)
+		if err != nil {
+			return nil, err
+		}
+		return newConstant(constant.MakeBool(ss == subs), scope.Mem), nil
+	default: // Contains, Index
+		ss, err := s.stringRange(0, s.Len)
+		if err != nil {
+			return nil, err
+		}
+		if name == "Contains" {
+			return newConstant(constant.MakeBool(strings.Contains(ss, subs)), scope.Mem), nil
+		}
+		return newConstant(constant.MakeInt64(int64(strings.Index(ss, subs))), scope.Mem), nil
+	}
+}
+
+// callNeededError is returned by the evaluation of a call to a function
+// that is not a builtin when function calls are enabled on the scope, see
+// EvalExpressionWithCalls.
+type callNeededError struct {
+	node *ast.CallExpr
+}
+
+func (err *callNeededError) Error() string {
+	return "function calls are not supported"
+}
+
+// funcCallNeeded returns the error for node, a call to a function that
+// the evaluator can not execute. If function calls are enabled the calls
+// nested in node are requested first, so that they are executed before
+// the call that uses their results.
+func (scope *EvalScope) funcCallNeeded(node *ast.CallExpr) error {
+	if scope.callResults == nil {
+		return fmt.Errorf("function calls are not supported")
+	}
+	nested := node.Args
+	if sel, ok := node.Fun.(*ast.SelectorExpr); ok {
+		nested = append([]ast.Expr{sel.X}, nested...)
+	}
+	for _, expr := range nested {
+		if _, err := scope.evalAST(expr); err != nil {
+			if _, ok := err.(*callNeededError); ok {
+				return err
+			}
+		}
+	}
+	return &callNeededError{node}
+}
+
+// errorMessageFields maps the concrete types of errors created by the
+// standard library to the field holding the value returned by their Error
+// method.
+var errorMessageFields = map[string]string{
+	"*errors.errorString": "s",
+	"*fmt.wrapError":      "msg",
+}
+
+// evalErrorMethod evaluates node, x.Error(), without calling the method,
+// which is only possible for errors whose concrete type is in
+// errorMessageFields. For other errors, and for types that are not errors,
+// the method must be called, see funcCallNeeded.
+func (scope *EvalScope) evalErrorMethod(node *ast.CallExpr, x ast.Expr) (*Variable, error) {
+	v, err := scope.evalAST(x)
+	if err != nil {
+		return nil, err
+	}
+	if v.Unreadable != nil {
+		return nil, v.Unreadable
+	}
+	if v.Kind != reflect.Interface || v.DwarfType == nil || v.DwarfType.String() != "error" {
+		if scope.callResults == nil {
+			return nil, fmt.Errorf("%s is not an error value", exprToString(x))
+		}
+		// some other type with an Error method
+		return nil, scope.funcCallNeeded(node)
+	}
+	v.loadInterface(0, false, LoadConfig{})
+	if v.Unreadable != nil {
+		return nil, v.Unreadable
+	}
+	if len(v.Children) == 0 || v.Children[0].Kind == reflect.Invalid || v.Children[0].DwarfType == nil {
+		return nil, fmt.Errorf("%s is nil", exprToString(x))
+	}
+	field, ok := errorMessageFields[v.Children[0].DwarfType.String()]
+	if !ok {
+		return nil, scope.funcCallNeeded(node)
+	}
+	return v.structMember(field)
 }
 
 func capBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
@@ -573,6 +960,10 @@ func (scope *EvalScope) evalIdent(node *ast.Ident) (*Variable, error) {
 		return nilVariable, nil
 	}
 
+	if strings.HasPrefix(node.Name, registerPrefix) {
+		return scope.evalRegister(node.Name[len(registerPrefix):])
+	}
+
 	vars, err := scope.Locals()
 	if err != nil {
 		return nil, err
@@ -625,6 +1016,9 @@ func (scope *EvalScope) evalTypeAssert(node *ast.TypeAssertExpr) (*Variable, err
 	if err != nil {
 		return nil, err
 	}
+	if ityp, isiface := typ.(*godwarf.InterfaceType); isiface {
+		return scope.evalInterfaceAssert(xv, ityp)
+	}
 	if xv.Children[0].DwarfType.Common().Name != typ.Common().Name {
 		return nil, fmt.Errorf("interface conversion: %s is %s, not %s", xv.DwarfType.Common().Name, xv.Children[0].TypeString(), typ.Common().Name)
 	}
@@ -636,10 +1030,181 @@ func (scope *EvalScope) evalTypeAssert(node *ast.TypeAssertExpr) (*Variable, err
 	return &xv.Children[0], nil
 }
 
+// evalInterfaceAssert implements type assertions of xv, a non-nil interface,
+// to the interface type ityp. If the concrete type of xv has all the
+// methods of ityp the result is a new interface value of type ityp holding
+// the same concrete value.
+func (scope *EvalScope) evalInterfaceAssert(xv *Variable, ityp *godwarf.InterfaceType) (*Variable, error) {
+	data := &xv.Children[0]
+	methods, err := interfaceMethodNames(scope, ityp)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range methods {
+		if !hasMethod(scope.BinInfo, data.DwarfType, name) {
+			return nil, fmt.Errorf("interface conversion: %s is not %s: missing method %s", data.TypeString(), ityp.Common().Name, name)
+		}
+	}
+
+	typeAddr, dataAddr, err := xv.interfaceWords()
+	if err != nil {
+		return nil, err
+	}
+
+	// Makes up the value of the new interface. Non-empty interfaces point to
+	// an itab, which only needs the inter and _type fields to be read by
+	// loadInterface, it is stored after the interface value.
+	istruct := resolveTypedef(&ityp.TypedefType).(*godwarf.StructType)
+	ptrtyp, hastab := resolveTypedef(istruct.Field[0].Type).(*godwarf.PtrType)
+	size := istruct.Size()
+	if hastab && istruct.Field[0].Name == "tab" {
+		size += ptrtyp.Type.Size()
+	}
+	mem := newFakeMemory(DereferenceMemory(xv.mem), int(size))
+	for _, f := range istruct.Field {
+		addr := uint64(fakeAddress + f.ByteOffset)
+		switch f.Name {
+		case "_type":
+			err = writePointer(scope.BinInfo, mem, addr, typeAddr)
+		case "data":
+			err = writePointer(scope.BinInfo, mem, addr, dataAddr)
+		case "tab":
+			tabAddr := uint64(fakeAddress + istruct.Size())
+			if err = writePointer(scope.BinInfo, mem, addr, tabAddr); err != nil {
+				break
+			}
+			tabtyp, ok := resolveTypedef(ptrtyp.Type).(*godwarf.StructType)
+			if !ok {
+				return nil, fmt.Errorf("unsupported interface layout")
+			}
+			var interAddr uintptr
+			interAddr, err = runtimeTypeAddr(scope.BinInfo, scope.Mem, ityp)
+			if err != nil {
+				break
+			}
+			for _, tf := range tabtyp.Field {
+				switch tf.Name {
+				case "inter":
+					err = writePointer(scope.BinInfo, mem, tabAddr+uint64(tf.ByteOffset), uint64(interAddr))
+				case "_type":
+					err = writePointer(scope.BinInfo, mem, tabAddr+uint64(tf.ByteOffset), typeAddr)
+				}
+				if err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	r := scope.newVariable(xv.Name, fakeAddress, ityp, mem)
+	r.loadInterface(0, false, loadFullValue)
+	if r.Unreadable != nil {
+		return nil, r.Unreadable
+	}
+	return r, nil
+}
+
+// interfaceWords returns the address of the runtime type of the concrete
+// value of v, an interface, and its data word.
+func (v *Variable) interfaceWords() (typeAddr, data uint64, err error) {
+	ptrSize := int64(v.bi.Arch.PtrSize())
+	ityp := resolveTypedef(&v.RealType.(*godwarf.InterfaceType).TypedefType).(*godwarf.StructType)
+	for _, f := range ityp.Field {
+		fv, err := v.toField(f)
+		if err != nil {
+			return 0, 0, err
+		}
+		switch f.Name {
+		case "tab":
+			tab := fv.maybeDereference()
+			if tab.Unreadable != nil {
+				return 0, 0, tab.Unreadable
+			}
+			tv, err := tab.structMember("_type")
+			if err != nil {
+				return 0, 0, err
+			}
+			typeAddr, err = readUintRaw(tv.mem, tv.Addr, ptrSize)
+			if err != nil {
+				return 0, 0, err
+			}
+		case "_type":
+			typeAddr, err = readUintRaw(fv.mem, fv.Addr, ptrSize)
+			if err != nil {
+				return 0, 0, err
+			}
+		case "data":
+			data, err = readUintRaw(fv.mem, fv.Addr, ptrSize)
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	return typeAddr, data, nil
+}
+
+// interfaceMethodNames returns the names of the methods of the interface
+// type ityp, read from its runtime type.
+func interfaceMethodNames(scope *EvalScope, ityp *godwarf.InterfaceType) ([]string, error) {
+	addr, err := runtimeTypeAddr(scope.BinInfo, scope.Mem, ityp)
+	if err != nil {
+		return nil, err
+	}
+	rtyp, err := scope.BinInfo.findType("runtime._type")
+	if err != nil {
+		return nil, err
+	}
+	_type, err := specificRuntimeType(scope.newVariable("", addr, rtyp, scope.Mem), int64(reflect.Interface))
+	if err != nil {
+		return nil, err
+	}
+	methods, err := _type.structMember(interfacetypeFieldMhdr)
+	if err != nil {
+		return nil, err
+	}
+	methods.loadArrayValues(0, LoadConfig{MaxVariableRecurse: 1, MaxArrayValues: 4096, MaxStructFields: -1})
+	if methods.Unreadable != nil {
+		return nil, methods.Unreadable
+	}
+	r := make([]string, 0, len(methods.Children))
+	for _, im := range methods.Children {
+		for i := range im.Children {
+			if im.Children[i].Name != imethodFieldName {
+				continue
+			}
+			nameoff, _ := constant.Int64Val(im.Children[i].Value)
+			name, _, _, err := resolveNameOff(scope.BinInfo, _type.Addr, uintptr(nameoff), _type.mem)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, name)
+		}
+	}
+	return r, nil
+}
+
+// hasMethod returns true if the method set of typ contains method name,
+// including the methods promoted from its embedded fields, see
+// lookupMethod. Values of pointer types have the methods with both value
+// and pointer receivers, values of other types only those with value
+// receivers.
+func hasMethod(bi *BinaryInfo, typ godwarf.Type, name string) bool {
+	if typ == nil {
+		return false
+	}
+	m, _ := lookupMethod(bi, typ, name)
+	return m != nil && m.inMethodSet
+}
+
 // Evaluates expressions <subexpr>[<subexpr>] (subscript access to arrays, slices and maps)
 func (scope *EvalScope) evalIndex(node *ast.IndexExpr) (*Variable, error) {
 	xev, err := scope.evalAST(node.X)
 	if err != nil {
+		if ident, ok := node.X.(*ast.Ident); ok && ident.Name == goroutineLabelsName {
+			return scope.evalGoroutineLabel(node)
+		}
 		return nil, err
 	}
 	if xev.Unreadable != nil {
@@ -668,13 +1233,22 @@ func (scope *EvalScope) evalIndex(node *ast.IndexExpr) (*Variable, error) {
 		fallthrough
 
 	case reflect.Slice, reflect.Array, reflect.String:
-		if xev.Base == 0 {
-			return nil, fmt.Errorf("can not index \"%s\"", exprToString(node.X))
-		}
 		n, err := idxev.asInt()
 		if err != nil {
 			return nil, err
 		}
+		if xev.Base == 0 {
+			if xev.Kind == reflect.String && xev.Value != nil {
+				// constant strings, and strings that are not stored in the
+				// target, like the result of a conversion, are indexed in memory
+				s := constant.StringVal(xev.Value)
+				if n < 0 || n >= int64(len(s)) {
+					return nil, fmt.Errorf("index out of bounds")
+				}
+				return newConstant(constant.MakeInt64(int64(s[n])), scope.Mem), nil
+			}
+			return nil, fmt.Errorf("can not index \"%s\"", exprToString(node.X))
+		}
 		return xev.sliceAccess(int(n))
 
 	case reflect.Map:
@@ -688,6 +1262,31 @@ func (scope *EvalScope) evalIndex(node *ast.IndexExpr) (*Variable, error) {
 	}
 }
 
+// goroutineLabelsName is the name of the pseudo-variable containing the
+// pprof labels of the current goroutine, it can only be indexed and is
+// shadowed by variables with the same name.
+const goroutineLabelsName = "labels"
+
+// evalGoroutineLabel evaluates labels[<subexpr>], returning the value of
+// a pprof label of the current goroutine or the empty string if the
+// goroutine does not have the label.
+func (scope *EvalScope) evalGoroutineLabel(node *ast.IndexExpr) (*Variable, error) {
+	idxev, err := scope.evalAST(node.Index)
+	if err != nil {
+		return nil, err
+	}
+	idxev.loadValue(loadFullValue)
+	if idxev.Unreadable != nil {
+		return nil, idxev.Unreadable
+	}
+	if idxev.Kind != reflect.String || idxev.Value == nil {
+		return nil, fmt.Errorf("goroutine labels can only be indexed by strings")
+	}
+	idxev.loadFullString()
+	val := loadGoroutineLabels(scope.Gvar)[constant.StringVal(idxev.Value)]
+	return newConstant(constant.MakeString(val), scope.Mem), nil
+}
+
 // Evaluates expressions <subexpr>[<subexpr>:<subexpr>]
 // HACK: slicing a map expression with [0:0] will return the whole map
 func (scope *EvalScope) evalReslice(node *ast.SliceExpr) (*Variable, error) {
@@ -700,6 +1299,7 @@ func (scope *EvalScope) evalReslice(node *ast.SliceExpr) (*Variable, error) {
 	}
 
 	var low, high int64
+	max := int64(-1)
 
 	if node.Low != nil {
 		lowv, err := scope.evalAST(node.Low)
@@ -725,12 +1325,36 @@ func (scope *EvalScope) evalReslice(node *ast.SliceExpr) (*Variable, error) {
 		}
 	}
 
+	if node.Max != nil {
+		if xev.Kind == reflect.String {
+			return nil, fmt.Errorf("3-index slice of string")
+		}
+		maxv, err := scope.evalAST(node.Max)
+		if err != nil {
+			return nil, err
+		}
+		max, err = maxv.asInt()
+		if err != nil {
+			return nil, fmt.Errorf("can not convert \"%s\" to int: %v", exprToString(node.Max), err)
+		}
+	}
+
 	switch xev.Kind {
 	case reflect.Slice, reflect.Array, reflect.String:
 		if xev.Base == 0 {
+			if xev.Kind == reflect.String && xev.Value != nil {
+				s := constant.StringVal(xev.Value)
+				if node.High == nil {
+					high = int64(len(s))
+				}
+				if low < 0 || low > high || high > int64(len(s)) {
+					return nil, fmt.Errorf("index out of bounds")
+				}
+				return newConstant(constant.MakeString(s[low:high]), scope.Mem), nil
+			}
 			return nil, fmt.Errorf("can not slice \"%s\"", exprToString(node.X))
 		}
-		return xev.reslice(low, high)
+		return xev.reslice(low, high, max)
 	case reflect.Map:
 		if node.High != nil {
 			return nil, fmt.Errorf("second slice argument must be empty for maps")
@@ -781,17 +1405,55 @@ func (scope *EvalScope) evalAddrOf(node *ast.UnaryExpr) (*Variable, error) {
 	if xev.Addr == 0 || xev.DwarfType == nil {
 		return nil, fmt.Errorf("can not take address of \"%s\"", exprToString(node.X))
 	}
+	if err := xev.checkAddressable(); err != nil {
+		return nil, fmt.Errorf("can not take address of \"%s\": %v", exprToString(node.X), err)
+	}
 
 	xev.OnlyAddr = true
 
-	typename := "*" + xev.DwarfType.Common().Name
-	rv := scope.newVariable("", 0, &godwarf.PtrType{CommonType: godwarf.CommonType{ByteSize: int64(scope.BinInfo.Arch.PtrSize()), Name: typename}, Type: xev.DwarfType}, scope.Mem)
+	rv := scope.newVariable("", 0, pointerTo(xev.DwarfType, scope.BinInfo.Arch), scope.Mem)
 	rv.Children = []Variable{*xev}
 	rv.loaded = true
 
 	return rv, nil
 }
 
+// checkAddressable returns an error if v is not stored in the memory of
+// the target, so that a pointer to it would not point to its value: the
+// compiler can keep variables in registers, or split them between
+// registers and the stack, some values are made up by the evaluator and
+// the results of function calls are copied out of the stack of the call.
+func (v *Variable) checkAddressable() error {
+	mem := v.mem
+	for unwrapped := false; !unwrapped; {
+		switch mc := mem.(type) {
+		case *memCache:
+			mem = mc.mem
+		case *memSnapshot:
+			if v.RealType != nil && mc.contains(v.Addr, int(v.RealType.Size())) {
+				return errors.New("value is the result of a function call")
+			}
+			mem = mc.mem
+		default:
+			unwrapped = true
+		}
+	}
+	switch mem := mem.(type) {
+	case *compositeMemory:
+		for _, piece := range mem.pieces {
+			if piece.IsRegister {
+				return errors.New("value is stored in registers")
+			}
+		}
+		return errors.New("value is not stored contiguously in memory")
+	case *fakeMemory:
+		if v.RealType != nil && mem.contains(v.Addr, int(v.RealType.Size())) {
+			return errors.New("value is not stored in the memory of the target")
+		}
+	}
+	return nil
+}
+
 func constantUnaryOp(op token.Token, y constant.Value) (r constant.Value, err error) {
 	defer func() {
 		if ierr := recover(); ierr != nil {
@@ -927,11 +1589,17 @@ func (scope *EvalScope) evalBinary(node *ast.BinaryExpr) (*Variable, error) {
 		return nil, fmt.Errorf("operator %s not supported", node.Op.String())
 	}
 
+	// strings compared for equality are not loaded, compareOp only reads
+	// them from the target if their lengths are the same
+	lazyStrings := node.Op == token.EQL || node.Op == token.NEQ
+
 	xv, err := scope.evalAST(node.X)
 	if err != nil {
 		return nil, err
 	}
-	xv.loadValue(loadFullValue)
+	if xv.Kind != reflect.String || !lazyStrings {
+		xv.loadValue(loadFullValue)
+	}
 	if xv.Unreadable != nil {
 		return nil, xv.Unreadable
 	}
@@ -952,7 +1620,12 @@ func (scope *EvalScope) evalBinary(node *ast.BinaryExpr) (*Variable, error) {
 	if err != nil {
 		return nil, err
 	}
-	yv.loadValue(loadFullValue)
+	if yv.Kind != reflect.String || !lazyStrings || xv.Kind != reflect.String {
+		yv.loadValue(loadFullValue)
+	}
+	if xv.Kind == reflect.String && yv.Kind != reflect.String {
+		xv.loadValue(loadFullValue)
+	}
 	if yv.Unreadable != nil {
 		return nil, yv.Unreadable
 	}
@@ -961,6 +1634,10 @@ func (scope *EvalScope) evalBinary(node *ast.BinaryExpr) (*Variable, error) {
 		return nil, OperationOnSpecialFloatError
 	}
 
+	if r, ok := compareFormatted(node.Op, xv, yv); ok {
+		return newConstant(constant.MakeBool(r), xv.mem), nil
+	}
+
 	typ, err := negotiateType(node.Op, xv, yv)
 	if err != nil {
 		return nil, err
@@ -993,6 +1670,15 @@ func (scope *EvalScope) evalBinary(node *ast.BinaryExpr) (*Variable, error) {
 			return nil, fmt.Errorf("operator %s can not be applied to \"%s\"", node.Op.String(), exprToString(node.Y))
 		}
 
+		if op == token.ADD && xv.Kind == reflect.String {
+			// concatenation uses the whole strings, not the part that was loaded
+			xv.loadFullString()
+			yv.loadFullString()
+			if int64(len(constant.StringVal(xv.Value))) != xv.Len || int64(len(constant.StringVal(yv.Value))) != yv.Len {
+				return nil, fmt.Errorf("string too long for concatenation")
+			}
+		}
+
 		rc, err := constantBinaryOp(op, xv.Value, yv.Value)
 		if err != nil {
 			return nil, err
@@ -1011,8 +1697,55 @@ func (scope *EvalScope) evalBinary(node *ast.BinaryExpr) (*Variable, error) {
 	}
 }
 
+// compareFormatted compares the summary of a struct formatted by a
+// pretty-printer with a string constant, so that conditions like
+// `t == "2006-01-02 15:04:05 +0000 UTC"` can be written.
+// The second return value is false if op is not a comparison or if the
+// operands are not such a struct and string constant.
+func compareFormatted(op token.Token, xv, yv *Variable) (bool, bool) {
+	switch op {
+	case token.EQL, token.NEQ, token.LSS, token.GTR, token.LEQ, token.GEQ:
+	default:
+		return false, false
+	}
+	isString := func(v *Variable) bool {
+		return v.DwarfType == nil && v.Value != nil && v.Value.Kind() == constant.String
+	}
+	xf, yf := xv.Flags&VariableFormatted != 0, yv.Flags&VariableFormatted != 0
+	if !(xf && isString(yv)) && !(yf && isString(xv)) {
+		return false, false
+	}
+	return constant.Compare(xv.Value, op, yv.Value), true
+}
+
+// stringsEqual compares the strings xv and yv, which have the same length,
+// reading them from the target one chunk at a time, strings that differ
+// early are not read in full.
+func stringsEqual(xv, yv *Variable) (bool, error) {
+	const chunkSize = 4096
+	for off := int64(0); off < xv.Len; off += chunkSize {
+		n := xv.Len - off
+		if n > chunkSize {
+			n = chunkSize
+		}
+		xs, err := xv.stringRange(off, n)
+		if err != nil {
+			return false, err
+		}
+		ys, err := yv.stringRange(off, n)
+		if err != nil {
+			return false, err
+		}
+		if xs != ys {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 // Compares xv to yv using operator op
 // Both xv and yv must be loaded and have a compatible type (as determined by negotiateType)
+// Strings compared for equality do not need to be loaded.
 func compareOp(op token.Token, xv *Variable, yv *Variable) (bool, error) {
 	switch xv.Kind {
 	case reflect.Bool:
@@ -1032,6 +1765,12 @@ func compareOp(op token.Token, xv *Variable, yv *Variable) (bool, error) {
 				return true, nil
 			}
 		}
+		if op == token.EQL || op == token.NEQ {
+			eql, err := stringsEqual(xv, yv)
+			return eql == (op == token.EQL), err
+		}
+		xv.loadFullString()
+		yv.loadFullString()
 		if int64(len(constant.StringVal(xv.Value))) != xv.Len || int64(len(constant.StringVal(yv.Value))) != yv.Len {
 			return false, fmt.Errorf("string too long for comparison")
 		}
@@ -1134,7 +1873,16 @@ func (v *Variable) asInt() (int64, error) {
 		if v.Unreadable != nil {
 			return 0, v.Unreadable
 		}
-		if _, ok := v.DwarfType.(*godwarf.IntType); !ok {
+		switch v.DwarfType.(type) {
+		case *godwarf.IntType:
+		case *godwarf.UintType:
+			// unsigned integers are valid indexes as long as they fit in an int
+			n, _ := constant.Uint64Val(v.Value)
+			if int64(n) < 0 {
+				return 0, fmt.Errorf("value %d out of range for int", n)
+			}
+			return int64(n), nil
+		default:
 			return 0, fmt.Errorf("can not convert value of type %s to int", v.DwarfType.String())
 		}
 	}
@@ -1261,21 +2009,28 @@ func (v *Variable) mapAccess(idx *Variable) (*Variable, error) {
 		return nil, v.Unreadable
 	}
 	// go would return zero for the map value type here, we do not have the ability to create zeroes
-	return nil, fmt.Errorf("key not found")
+	return nil, errKeyNotFound
 }
 
-func (v *Variable) reslice(low int64, high int64) (*Variable, error) {
-	if low < 0 || low >= v.Len || high < 0 || high > v.Len {
+// reslice returns v[low:high:max], or v[low:high] if max is negative.
+// Like in Go high can be greater than the length of a slice, up to its
+// capacity. The elements of the result are read from the memory of the
+// target when it is loaded, not copied from v.
+func (v *Variable) reslice(low, high, max int64) (*Variable, error) {
+	bound := v.Len
+	if v.Kind == reflect.Slice {
+		bound = v.Cap
+	}
+	if max >= 0 && (max > bound || high > max) {
+		return nil, fmt.Errorf("index out of bounds")
+	}
+	if low < 0 || low > high || high > bound || (v.Kind != reflect.Slice && high > v.Len) {
 		return nil, fmt.Errorf("index out of bounds")
 	}
 
 	base := v.Base + uintptr(int64(low)*v.stride)
 	len := high - low
 
-	if high-low < 0 {
-		return nil, fmt.Errorf("index out of bounds")
-	}
-
 	typ := v.DwarfType
 	if _, isarr := v.DwarfType.(*godwarf.ArrayType); isarr {
 		typ = fakeSliceType(v.fieldType)
@@ -1288,6 +2043,9 @@ func (v *Variable) reslice(low int64, high int64) (*Variable, error) {
 
 	r := v.newVariable("", 0, typ, mem)
 	r.Cap = len
+	if max >= 0 {
+		r.Cap = max - low
+	}
 	r.Len = len
 	r.Base = base
 	r.stride = v.stride
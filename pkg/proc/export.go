@@ -0,0 +1,431 @@
+package proc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/constant"
+	"go/format"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportLoadConfig returns the configuration to load the values exported
+// by GoLiteral and JSON, following pointers up to maxDepth levels deep.
+func ExportLoadConfig(maxDepth int) LoadConfig {
+	return LoadConfig{FollowPointers: true, MaxVariableRecurse: maxDepth, MaxStringLen: 4096, MaxArrayValues: 4096, MaxStructFields: -1, MaxMapEntries: 4096}
+}
+
+var (
+	// packagePathRe matches the directories of import paths in type names.
+	packagePathRe = regexp.MustCompile(`[\w\-~.]+/`)
+	// mainPackageRe matches the qualifier of the types of package main.
+	mainPackageRe = regexp.MustCompile(`\bmain\.`)
+)
+
+// literalTypeName returns the name of the type of v as it would be written
+// in the package of the type: package qualifiers are reduced to the
+// package name and removed for package main.
+func literalTypeName(v *Variable) string {
+	if v.DwarfType == nil {
+		return ""
+	}
+	name := v.DwarfType.Common().Name
+	if name == "" {
+		name = v.DwarfType.String()
+	}
+	if name == "*void" {
+		return "unsafe.Pointer"
+	}
+	name = packagePathRe.ReplaceAllString(name, "")
+	return mainPackageRe.ReplaceAllString(name, "")
+}
+
+// GoLiteral returns a Go expression evaluating to the value of v, usually
+// loaded with ExportLoadConfig: structs, arrays, slices and maps are
+// written as composite literals and pointers as the address of the value
+// they point to. Parts of v that were not loaded, unreadable values,
+// functions and values that can not be written in Go are replaced with
+// nil or zero values followed by a comment. Types and fields that are not
+// exported by their package, and cycles of pointers, can not be written in
+// a literal and will not compile outside of their package.
+func (v *Variable) GoLiteral() string {
+	var buf bytes.Buffer
+	v.writeGoLiteral(&buf, true)
+	const prefix = "package p\n\nvar _ = "
+	out, err := format.Source([]byte(prefix + buf.String() + "\n"))
+	if err != nil {
+		return buf.String()
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(out), prefix), "\n")
+}
+
+// writeGoLiteral writes v as a Go expression to buf. If typed is set the
+// expression must have the type of v, otherwise it will be assigned to a
+// variable of the type of v and untyped constants can be used.
+func (v *Variable) writeGoLiteral(buf *bytes.Buffer, typed bool) {
+	typ := literalTypeName(v)
+	if v.Unreadable != nil {
+		fmt.Fprintf(buf, "%s /* unreadable: %v */", v.zeroLiteral(typ), v.Unreadable)
+		return
+	}
+	if v.OnlyAddr || (v.Value == nil && v.zeroLiteral(typ) != "nil" && v.Kind != reflect.Struct && v.Kind != reflect.Array) {
+		fmt.Fprintf(buf, "%s /* not loaded */", v.zeroLiteral(typ))
+		return
+	}
+
+	switch v.Kind {
+	case reflect.Ptr:
+		if len(v.Children) == 0 || v.Children[0].Addr == 0 {
+			writeNilLiteral(buf, typ, typed)
+			return
+		}
+		pointee := &v.Children[0]
+		if pointee.OnlyAddr || pointee.Unreadable != nil {
+			fmt.Fprintf(buf, "nil /* (%s)(%#x) */", typ, pointee.Addr)
+			return
+		}
+		switch pointee.Kind {
+		case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
+			buf.WriteString("&")
+			pointee.writeGoLiteral(buf, true)
+		default:
+			fmt.Fprintf(buf, "func() %s { v := ", typ)
+			pointee.writeGoLiteral(buf, true)
+			buf.WriteString("; return &v }()")
+		}
+
+	case reflect.UnsafePointer:
+		if len(v.Children) == 0 || v.Children[0].Addr == 0 {
+			writeNilLiteral(buf, typ, typed)
+			return
+		}
+		fmt.Fprintf(buf, "unsafe.Pointer(uintptr(%#x))", v.Children[0].Addr)
+
+	case reflect.Struct:
+		if len(v.Children) == 0 && v.Len > 0 {
+			fmt.Fprintf(buf, "%s{ /* not loaded */ }", typ)
+			return
+		}
+		buf.WriteString(typ)
+		buf.WriteString("{")
+		for i := range v.Children {
+			if i == 0 {
+				buf.WriteString("\n")
+			}
+			fmt.Fprintf(buf, "%s: ", v.Children[i].Name)
+			v.Children[i].writeGoLiteral(buf, false)
+			buf.WriteString(",\n")
+		}
+		buf.WriteString("}")
+
+	case reflect.Array, reflect.Slice:
+		if v.Kind == reflect.Slice && v.Base == 0 {
+			writeNilLiteral(buf, typ, typed)
+			return
+		}
+		buf.WriteString(typ)
+		buf.WriteString("{")
+		nl := len(v.Children) > 0 && isCompositeKind(v.Children[0].Kind)
+		for i := range v.Children {
+			if nl {
+				buf.WriteString("\n")
+			} else if i > 0 {
+				buf.WriteString(", ")
+			}
+			v.Children[i].writeGoLiteral(buf, false)
+			if nl {
+				buf.WriteString(",")
+			}
+		}
+		if n := v.Len - int64(len(v.Children)); n > 0 {
+			fmt.Fprintf(buf, " /* +%d more */", n)
+		}
+		if nl {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("}")
+
+	case reflect.Map:
+		if v.Base == 0 {
+			writeNilLiteral(buf, typ, typed)
+			return
+		}
+		entries := make([][2]string, 0, len(v.Children)/2)
+		for i := 0; i+1 < len(v.Children); i += 2 {
+			var kbuf, vbuf bytes.Buffer
+			v.Children[i].writeGoLiteral(&kbuf, false)
+			v.Children[i+1].writeGoLiteral(&vbuf, false)
+			entries = append(entries, [2]string{kbuf.String(), vbuf.String()})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i][0] < entries[j][0] })
+		buf.WriteString(typ)
+		buf.WriteString("{")
+		for i, entry := range entries {
+			if i == 0 {
+				buf.WriteString("\n")
+			}
+			fmt.Fprintf(buf, "%s: %s,\n", entry[0], entry[1])
+		}
+		if n := v.Len - int64(len(entries)); n > 0 {
+			fmt.Fprintf(buf, "/* +%d more */\n", n)
+		}
+		buf.WriteString("}")
+
+	case reflect.Interface:
+		if len(v.Children) == 0 || v.Children[0].Kind == reflect.Invalid {
+			buf.WriteString("nil")
+			return
+		}
+		v.Children[0].writeGoLiteral(buf, true)
+
+	case reflect.String:
+		s := constant.StringVal(v.Value)
+		writeTypedLiteral(buf, strconv.Quote(s), typ, "string", typed)
+		if n := v.Len - int64(len(s)); n > 0 {
+			fmt.Fprintf(buf, " /* +%d more bytes */", n)
+		}
+
+	case reflect.Bool:
+		writeTypedLiteral(buf, v.Value.ExactString(), typ, "bool", typed)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeTypedLiteral(buf, v.Value.ExactString(), typ, "int", typed)
+
+	case reflect.Float32, reflect.Float64:
+		switch v.FloatSpecial {
+		case FloatIsPosInf:
+			writeTypedLiteral(buf, "math.Inf(1)", typ, "float64", typed)
+		case FloatIsNegInf:
+			writeTypedLiteral(buf, "math.Inf(-1)", typ, "float64", typed)
+		case FloatIsNaN:
+			writeTypedLiteral(buf, "math.NaN()", typ, "float64", typed)
+		default:
+			writeTypedLiteral(buf, floatLiteral(v.Value, v.Kind), typ, "float64", typed)
+		}
+
+	case reflect.Complex64, reflect.Complex128:
+		lit := fmt.Sprintf("complex(%s, %s)", floatLiteral(constant.Real(v.Value), v.Kind), floatLiteral(constant.Imag(v.Value), v.Kind))
+		writeTypedLiteral(buf, lit, typ, "complex128", typed)
+
+	case reflect.Chan:
+		if v.Base == 0 {
+			writeNilLiteral(buf, typ, typed)
+			return
+		}
+		capacity := "0"
+		for i := range v.Children {
+			if v.Children[i].Name == "dataqsiz" && v.Children[i].Value != nil {
+				capacity = v.Children[i].Value.ExactString()
+			}
+		}
+		fmt.Fprintf(buf, "make(%s, %s)", typ, capacity)
+
+	case reflect.Func:
+		if v.Value == nil || v.Base == 0 {
+			writeNilLiteral(buf, typ, typed)
+			return
+		}
+		fmt.Fprintf(buf, "nil /* %s */", constant.StringVal(v.Value))
+
+	default:
+		fmt.Fprintf(buf, "nil /* %s */", v.Kind)
+	}
+}
+
+func isCompositeKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func writeNilLiteral(buf *bytes.Buffer, typ string, typed bool) {
+	if typed && typ != "" {
+		fmt.Fprintf(buf, "(%s)(nil)", typ)
+		return
+	}
+	buf.WriteString("nil")
+}
+
+// writeTypedLiteral writes lit, converted to typ if typed is set and typ
+// is not defaultTyp, the type lit has when it is not converted.
+func writeTypedLiteral(buf *bytes.Buffer, lit, typ, defaultTyp string, typed bool) {
+	if typed && typ != "" && typ != defaultTyp {
+		fmt.Fprintf(buf, "%s(%s)", typ, lit)
+		return
+	}
+	buf.WriteString(lit)
+}
+
+// floatLiteral returns the constant val as a floating point literal.
+func floatLiteral(val constant.Value, kind reflect.Kind) string {
+	bits := 64
+	if kind == reflect.Float32 || kind == reflect.Complex64 {
+		bits = 32
+	}
+	f, _ := constant.Float64Val(val)
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func (v *Variable) zeroLiteral(typ string) string {
+	switch v.Kind {
+	case reflect.Bool:
+		return "false"
+	case reflect.String:
+		return `""`
+	case reflect.Struct, reflect.Array:
+		return typ + "{}"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return "0"
+	}
+	return "nil"
+}
+
+// JSON returns the value of v, usually loaded with ExportLoadConfig, as an
+// indented JSON document. Structs and maps are written as objects, with
+// the keys of maps that are not strings written as Go literals, arrays
+// and slices as arrays and pointers and interfaces as the value they
+// point to. Nil values, unreadable values, functions, channels and parts
+// of v that were not loaded are written as null. Infinities, NaNs and
+// complex numbers are written as strings.
+func (v *Variable) JSON() []byte {
+	var buf bytes.Buffer
+	v.writeJSON(&buf)
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "\t"); err != nil {
+		return buf.Bytes()
+	}
+	return out.Bytes()
+}
+
+func (v *Variable) writeJSON(buf *bytes.Buffer) {
+	if v.Unreadable != nil || v.OnlyAddr || (v.Value == nil && v.zeroLiteral("") != "nil" && v.Kind != reflect.Struct && v.Kind != reflect.Array) {
+		buf.WriteString("null")
+		return
+	}
+
+	switch v.Kind {
+	case reflect.Ptr, reflect.Interface:
+		if len(v.Children) == 0 || v.Children[0].Kind == reflect.Invalid || (v.Kind == reflect.Ptr && v.Children[0].Addr == 0) {
+			buf.WriteString("null")
+			return
+		}
+		v.Children[0].writeJSON(buf)
+
+	case reflect.Struct:
+		if len(v.Children) == 0 && v.Len > 0 {
+			buf.WriteString("null")
+			return
+		}
+		buf.WriteString("{")
+		for i := range v.Children {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			writeJSONString(buf, v.Children[i].Name)
+			buf.WriteString(":")
+			v.Children[i].writeJSON(buf)
+		}
+		buf.WriteString("}")
+
+	case reflect.Array, reflect.Slice:
+		if v.Kind == reflect.Slice && v.Base == 0 {
+			buf.WriteString("null")
+			return
+		}
+		buf.WriteString("[")
+		for i := range v.Children {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			v.Children[i].writeJSON(buf)
+		}
+		buf.WriteString("]")
+
+	case reflect.Map:
+		if v.Base == 0 {
+			buf.WriteString("null")
+			return
+		}
+		type entry struct {
+			key string
+			val *Variable
+		}
+		entries := make([]entry, 0, len(v.Children)/2)
+		for i := 0; i+1 < len(v.Children); i += 2 {
+			k := &v.Children[i]
+			var key string
+			if k.Kind == reflect.String && k.Value != nil {
+				key = constant.StringVal(k.Value)
+			} else {
+				var kbuf bytes.Buffer
+				k.writeGoLiteral(&kbuf, false)
+				key = kbuf.String()
+			}
+			entries = append(entries, entry{key, &v.Children[i+1]})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+		buf.WriteString("{")
+		for i, e := range entries {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			writeJSONString(buf, e.key)
+			buf.WriteString(":")
+			e.val.writeJSON(buf)
+		}
+		buf.WriteString("}")
+
+	case reflect.String:
+		writeJSONString(buf, constant.StringVal(v.Value))
+
+	case reflect.Bool:
+		buf.WriteString(v.Value.ExactString())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		buf.WriteString(v.Value.ExactString())
+
+	case reflect.Float32, reflect.Float64:
+		switch v.FloatSpecial {
+		case FloatIsPosInf:
+			buf.WriteString(`"+Inf"`)
+		case FloatIsNegInf:
+			buf.WriteString(`"-Inf"`)
+		case FloatIsNaN:
+			buf.WriteString(`"NaN"`)
+		default:
+			bits := 64
+			if v.Kind == reflect.Float32 {
+				bits = 32
+			}
+			f, _ := constant.Float64Val(v.Value)
+			buf.WriteString(strconv.FormatFloat(f, 'g', -1, bits))
+		}
+
+	case reflect.Complex64, reflect.Complex128:
+		re, _ := constant.Float64Val(constant.Real(v.Value))
+		im, _ := constant.Float64Val(constant.Imag(v.Value))
+		writeJSONString(buf, fmt.Sprint(complex(re, im)))
+
+	default:
+		buf.WriteString("null")
+	}
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
@@ -7,9 +7,9 @@ import (
 	"fmt"
 	"go/ast"
 	"go/constant"
-	"go/parser"
 	"reflect"
 	"sort"
+	"strings"
 
 	"github.com/derekparker/delve/pkg/dwarf/godwarf"
 	"github.com/derekparker/delve/pkg/dwarf/op"
@@ -24,11 +24,16 @@ import (
 // The protocol is described in $GOROOT/src/runtime/asm_amd64.s in the
 // comments for function runtime·debugCallV1.
 //
-// There are two main entry points here. The first one is CallFunction which
+// There are three main entry points here. The first one is CallFunction which
 // evaluates a function call expression, sets up the function call on the
 // selected goroutine and resumes execution of the process.
 //
-// The second one is (*FunctionCallState).step() which is called every time
+// The second one is EvalExpressionWithCalls which evaluates an expression
+// that can contain function calls, each call is set up like CallFunction
+// does and the process is resumed until the call returns, its result is
+// then used to evaluate the rest of the expression.
+//
+// The third one is (*FunctionCallState).step() which is called every time
 // the process stops at a breakpoint inside one of the debug injcetion
 // functions.
 
@@ -50,6 +55,7 @@ var (
 	ErrNotEnoughArguments         = errors.New("not enough arguments")
 	ErrNoAddrUnsupported          = errors.New("arguments to a function call must have an address")
 	ErrNotAGoFunction             = errors.New("not a Go function")
+	ErrFuncCallInterrupted        = errors.New("function call interrupted before it returned")
 )
 
 type functionCallState struct {
@@ -80,6 +86,277 @@ type functionCallState struct {
 // See runtime.debugCallV1 in $GOROOT/src/runtime/asm_amd64.s for a
 // description of the protocol.
 func CallFunction(p Process, expr string, retLoadCfg *LoadConfig) error {
+	t, err := ParseExpr(expr)
+	if err != nil {
+		return err
+	}
+	callexpr, iscall := t.(*ast.CallExpr)
+	if !iscall {
+		return ErrNotACallExpr
+	}
+	scope, err := GoroutineScope(p.CurrentThread())
+	if err != nil {
+		return err
+	}
+	evalCall := func() (*Function, []*Variable, error) {
+		return funcCallEvalExpr(scope, callexpr)
+	}
+	if err := startFunctionCall(p, expr, evalCall, retLoadCfg); err != nil {
+		return err
+	}
+	return Continue(p)
+}
+
+// EvalExpressionWithCalls evaluates expr in the topmost frame of the
+// selected goroutine, like EvalScope.EvalExpression, calling the functions
+// of the target used by the expression, for example p.String() or f(x).
+// Each call is injected on the selected goroutine, which must be running
+// on a thread, and the target is resumed until the call returns. An error
+// is returned if the target stops for a different reason, the call is
+// still in progress and will complete when the target is resumed. The
+// resumed result is true if at least one call was injected: variables
+// loaded before the call may no longer match the memory of the target.
+//
+// The protocol of runtime.debugCallV1 checks that the goroutine is at a
+// safe point and runs the call on a frame that can grow the stack, as a
+// consequence pointers to the stack of the goroutine are not accepted as
+// arguments and the results of a call are copied out of the stack before
+// the next call starts. The garbage collector can run during a call and
+// free objects referenced only by the results of an earlier call of the
+// same expression, they are not roots for the garbage collector.
+func EvalExpressionWithCalls(p Process, expr string, cfg LoadConfig) (v *Variable, resumed bool, err error) {
+	t, err := ParseExpr(expr)
+	if err != nil {
+		return nil, false, err
+	}
+	thread := p.CurrentThread()
+	returnValues := thread.Common().returnValues
+	defer func() {
+		thread.Common().returnValues = returnValues
+	}()
+	results := make(map[*ast.CallExpr]*Variable)
+	for {
+		scope, err := ConvertEvalScope(p, -1, 0)
+		if err != nil {
+			return nil, len(results) > 0, err
+		}
+		scope.callResults = results
+		v, err := scope.evalParsedExpression(t, expr, cfg)
+		callerr, needed := err.(*callNeededError)
+		if !needed {
+			return v, len(results) > 0, err
+		}
+		results[callerr.node], err = evalFunctionCall(p, scope, callerr.node, cfg)
+		if err != nil {
+			return nil, true, err
+		}
+	}
+}
+
+// evalFunctionCall executes callexpr, which must have a single result, on
+// the selected goroutine and returns its result.
+func evalFunctionCall(p Process, scope *EvalScope, callexpr *ast.CallExpr, cfg LoadConfig) (*Variable, error) {
+	expr := exprToString(callexpr)
+	evalCall := func() (*Function, []*Variable, error) {
+		return funcCallEvalExpr(scope, callexpr)
+	}
+	v, err := runFunctionCall(p, expr, evalCall, cfg)
+	if err != nil {
+		return nil, err
+	}
+	v.Name = expr
+	return v, nil
+}
+
+// SetVariableWithCalls sets name to value in the topmost frame of the
+// selected goroutine, like EvalScope.SetVariable. Assigning to a key that
+// is not in a map adds the key to the map, allocating its entry by calling
+// the runtime on the selected goroutine, see EvalExpressionWithCalls. This
+// is only possible for maps with string and 32 or 64 bit integer keys,
+// that use the fast variants of runtime.mapassign, and string keys must be
+// variables.
+func SetVariableWithCalls(p Process, name, value string) error {
+	scope, err := ConvertEvalScope(p, -1, 0)
+	if err != nil {
+		return err
+	}
+	t, err := ParseExpr(name)
+	if err != nil {
+		return err
+	}
+	idx, isindex := removeParen(t).(*ast.IndexExpr)
+	if !isindex {
+		return scope.SetVariable(name, value)
+	}
+	mapv, err := scope.evalAST(idx.X)
+	if err != nil || mapv.Kind != reflect.Map || mapv.Unreadable != nil {
+		return scope.SetVariable(name, value)
+	}
+	key, err := scope.evalAST(idx.Index)
+	if err != nil {
+		return err
+	}
+	key.loadValue(loadFullValue)
+	if key.Unreadable != nil {
+		return key.Unreadable
+	}
+	if _, err := mapv.mapAccess(key); err != errKeyNotFound {
+		return scope.SetVariable(name, value)
+	}
+
+	mt := mapv.RealType.(*godwarf.MapType)
+	xv := scope.newVariable(name, 0, mt.ElemType, scope.Mem)
+	yv, err := scope.evalAssignedValue(value, xv)
+	if err != nil {
+		return err
+	}
+	elemAddr, err := mapAssign(p, scope, mapv, key, exprToString(idx.Index))
+	if err != nil {
+		return err
+	}
+	xv.Addr = elemAddr
+	xv.mem = p.CurrentThread()
+	return xv.setValue(yv)
+}
+
+// maxFastMapElemSize is the maximum size of the values of the maps that
+// use the fast variants of runtime.mapassign.
+const maxFastMapElemSize = 128
+
+// mapAssign adds key to mapv by calling runtime.mapassign_fast32,
+// runtime.mapassign_fast64 or runtime.mapassign_faststr on the selected
+// goroutine and returns the address of the value of the new entry.
+func mapAssign(p Process, scope *EvalScope, mapv, key *Variable, keyexpr string) (uintptr, error) {
+	bi := scope.BinInfo
+	mt := mapv.RealType.(*godwarf.MapType)
+	if mt.ElemType.Size() > maxFastMapElemSize {
+		return 0, fmt.Errorf("can not add keys to maps of type %s, values are too large", mt.String())
+	}
+
+	var fnname string
+	keyarg := key
+	switch ktyp := resolveTypedef(mt.KeyType).(type) {
+	case *godwarf.StringType:
+		if key.Addr == 0 {
+			return 0, fmt.Errorf("can not add key %s to map, string keys must be variables", keyexpr)
+		}
+		strtyp, err := bi.findType("string")
+		if err != nil {
+			return 0, err
+		}
+		fnname = "runtime.mapassign_faststr"
+		keyarg = scope.newVariable(keyexpr, key.Addr, strtyp, key.mem)
+	case *godwarf.IntType, *godwarf.UintType:
+		if key.Value == nil {
+			return 0, fmt.Errorf("can not add key %s to map", keyexpr)
+		}
+		switch ktyp.Size() {
+		case 4:
+			fnname = "runtime.mapassign_fast32"
+		case 8:
+			fnname = "runtime.mapassign_fast64"
+		default:
+			return 0, fmt.Errorf("can not add keys to maps of type %s", mt.String())
+		}
+		// the key is passed as an unsigned integer of the same size
+		n := constant.ToInt(key.Value)
+		if _, isint := ktyp.(*godwarf.IntType); isint {
+			k, _ := constant.Int64Val(n)
+			n = constant.MakeUint64(convertInt(uint64(k), false, ktyp.Size()))
+		}
+		keyarg = newConstant(n, scope.Mem)
+		keyarg.Name = keyexpr
+	default:
+		return 0, fmt.Errorf("can not add keys to maps of type %s", mt.String())
+	}
+	fn := bi.LookupFunc[fnname]
+	if fn == nil {
+		return 0, fmt.Errorf("could not find function %s", fnname)
+	}
+
+	maptypeAddr, err := runtimeTypeAddr(bi, scope.Mem, mapv.DwarfType)
+	if err != nil {
+		return 0, err
+	}
+	hmapAddr, err := readUintRaw(mapv.mem, mapv.Addr, int64(bi.Arch.PtrSize()))
+	if err != nil {
+		return 0, err
+	}
+	if hmapAddr == 0 {
+		return 0, errors.New("assignment to entry in nil map")
+	}
+	maptype, err := scope.fakePointer("runtime.maptype", uintptr(maptypeAddr))
+	if err != nil {
+		return 0, err
+	}
+	hmap, err := scope.fakePointer("runtime.hmap", uintptr(hmapAddr))
+	if err != nil {
+		return 0, err
+	}
+	hmap.Name = mapv.Name
+
+	evalCall := func() (*Function, []*Variable, error) {
+		return fn, []*Variable{maptype, hmap, keyarg}, nil
+	}
+	expr := fmt.Sprintf("%s(%s)", fnname, keyexpr)
+	ret, err := runFunctionCall(p, expr, evalCall, loadSingleValue)
+	if err != nil {
+		return 0, err
+	}
+	elemAddr, err := readUintRaw(ret.mem, ret.Addr, int64(bi.Arch.PtrSize()))
+	if err != nil {
+		return 0, err
+	}
+	return uintptr(elemAddr), nil
+}
+
+// fakePointer returns a pointer to the value of type typename at addr,
+// created by the evaluator.
+func (scope *EvalScope) fakePointer(typename string, addr uintptr) (*Variable, error) {
+	typ, err := scope.BinInfo.findType(typename)
+	if err != nil {
+		return nil, err
+	}
+	v := scope.newVariable("", 0, pointerTo(typ, scope.BinInfo.Arch), scope.Mem)
+	v.Children = []Variable{*scope.newVariable("", addr, typ, scope.Mem)}
+	v.loaded = true
+	return v, nil
+}
+
+// runFunctionCall executes the function call returned by evalCall on the
+// selected goroutine, waits for it to return and returns its result, the
+// function must have a single result. Expr describes the call in errors.
+func runFunctionCall(p Process, expr string, evalCall func() (*Function, []*Variable, error), cfg LoadConfig) (*Variable, error) {
+	if err := startFunctionCall(p, expr, evalCall, &cfg); err != nil {
+		return nil, err
+	}
+	if err := Continue(p); err != nil {
+		return nil, err
+	}
+	fncall := &p.Common().fncallState
+	if fncall.inProgress {
+		return nil, ErrFuncCallInterrupted
+	}
+	if fncall.panicvar != nil {
+		if len(fncall.panicvar.Children) > 0 && fncall.panicvar.Children[0].Value != nil {
+			return nil, fmt.Errorf("%s panicked: %s", expr, fncall.panicvar.Children[0].Value.String())
+		}
+		return nil, fmt.Errorf("%s panicked", expr)
+	}
+	switch len(fncall.retvars) {
+	case 0:
+		return nil, fmt.Errorf("%s (no value) used as value", expr)
+	case 1:
+		return fncall.retvars[0], nil
+	default:
+		return nil, fmt.Errorf("multiple-value %s in single-value context", expr)
+	}
+}
+
+// startFunctionCall sets up the call of the function returned by evalCall,
+// with the arguments it returns, on the selected goroutine. The call starts
+// when the target is resumed, expr describes it.
+func startFunctionCall(p Process, expr string, evalCall func() (*Function, []*Variable, error), retLoadCfg *LoadConfig) error {
 	bi := p.BinInfo()
 	if !p.Common().fncallEnabled {
 		return ErrFuncCallUnsupportedBackend
@@ -118,7 +395,7 @@ func CallFunction(p Process, expr string, retLoadCfg *LoadConfig) error {
 		return ErrFuncCallUnsupportedBackend
 	}
 
-	fn, argvars, err := funcCallEvalExpr(p, expr)
+	fn, argvars, err := evalCall()
 	if err != nil {
 		return err
 	}
@@ -145,7 +422,7 @@ func CallFunction(p Process, expr string, retLoadCfg *LoadConfig) error {
 
 	fncallLog("function call initiated %v frame size %d\n", fn, len(argmem))
 
-	return Continue(p)
+	return nil
 }
 
 func fncallLog(fmtstr string, args ...interface{}) {
@@ -189,52 +466,216 @@ func callOP(bi *BinaryInfo, thread Thread, regs Registers, callAddr uint64) erro
 	return thread.SetPC(callAddr)
 }
 
-// funcCallEvalExpr evaluates expr, which must be a function call, returns
-// the function being called and its arguments.
-func funcCallEvalExpr(p Process, expr string) (fn *Function, argvars []*Variable, err error) {
-	bi := p.BinInfo()
-	scope, err := GoroutineScope(p.CurrentThread())
-	if err != nil {
-		return nil, nil, err
-	}
-
-	t, err := parser.ParseExpr(expr)
-	if err != nil {
-		return nil, nil, err
-	}
-	callexpr, iscall := t.(*ast.CallExpr)
-	if !iscall {
-		return nil, nil, ErrNotACallExpr
-	}
+// funcCallEvalExpr evaluates callexpr in scope, returns the function being
+// called and its arguments. For method calls the receiver is the first
+// argument.
+func funcCallEvalExpr(scope *EvalScope, callexpr *ast.CallExpr) (fn *Function, argvars []*Variable, err error) {
+	bi := scope.BinInfo
 
-	//TODO(aarzilli): must evaluate <var>.<method> and treat them appropriately
 	fnvar, err := scope.evalAST(callexpr.Fun)
+	if sel, ok := callexpr.Fun.(*ast.SelectorExpr); ok && err != nil {
+		if xv, xerr := scope.evalAST(sel.X); xerr == nil {
+			if xv.Name == "" {
+				xv.Name = exprToString(sel.X)
+			}
+			var recv *Variable
+			fn, recv, err = funcCallMethod(scope, xv, sel.Sel.Name)
+			if err != nil {
+				return nil, nil, err
+			}
+			argvars = append(argvars, recv)
+		}
+	}
 	if err != nil {
 		return nil, nil, err
 	}
-	if fnvar.Kind != reflect.Func {
-		return nil, nil, fmt.Errorf("expression %q is not a function", exprToString(callexpr.Fun))
-	}
-	fn = bi.PCToFunc(uint64(fnvar.Base))
 	if fn == nil {
-		return nil, nil, fmt.Errorf("could not find DIE for function %q", exprToString(callexpr.Fun))
+		if fnvar.Kind != reflect.Func {
+			return nil, nil, fmt.Errorf("expression %q is not a function", exprToString(callexpr.Fun))
+		}
+		fn = bi.PCToFunc(uint64(fnvar.Base))
+		if fn == nil {
+			return nil, nil, fmt.Errorf("could not find DIE for function %q", exprToString(callexpr.Fun))
+		}
 	}
 	if !fn.cu.isgo {
 		return nil, nil, ErrNotAGoFunction
 	}
 
-	argvars = make([]*Variable, len(callexpr.Args))
 	for i := range callexpr.Args {
-		argvars[i], err = scope.evalAST(callexpr.Args[i])
+		argvar, err := scope.evalAST(callexpr.Args[i])
 		if err != nil {
 			return nil, nil, err
 		}
-		argvars[i].Name = exprToString(callexpr.Args[i])
+		argvar.Name = exprToString(callexpr.Args[i])
+		argvars = append(argvars, argvar)
 	}
 
 	return fn, argvars, nil
 }
 
+// maxEmbeddedDepth is the maximum depth of embedded fields searched for
+// promoted methods by lookupMethod.
+const maxEmbeddedDepth = 5
+
+// funcCallMethod returns the function implementing method name of xv and
+// the receiver to pass to it. Interfaces are replaced by the value they
+// contain, methods promoted from embedded fields are called on the field,
+// the address of the receiver is taken for methods with a pointer receiver
+// and pointers are dereferenced for methods with a value receiver.
+func funcCallMethod(scope *EvalScope, xv *Variable, name string) (*Function, *Variable, error) {
+	if xv.Unreadable != nil {
+		return nil, nil, xv.Unreadable
+	}
+	if xv.Kind == reflect.Interface {
+		xv.loadInterface(0, false, LoadConfig{})
+		if xv.Unreadable != nil {
+			return nil, nil, xv.Unreadable
+		}
+		if len(xv.Children) == 0 || xv.Children[0].Kind == reflect.Invalid {
+			return nil, nil, fmt.Errorf("%s is nil", xv.Name)
+		}
+		data := xv.Children[0]
+		data.Name = xv.Name
+		xv = &data
+	}
+	if xv.DwarfType == nil {
+		return nil, nil, fmt.Errorf("%s has no methods", xv.Name)
+	}
+	fn, recv, err := findMethod(scope, xv, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fn == nil {
+		return nil, nil, fmt.Errorf("%s (type %s) has no method %s", xv.Name, xv.DwarfType.Common().Name, name)
+	}
+	return fn, recv, nil
+}
+
+// findMethod searches method name of xv and of its embedded fields, see
+// lookupMethod, it returns a nil function if the method does not exist.
+func findMethod(scope *EvalScope, xv *Variable, name string) (*Function, *Variable, error) {
+	m, ambiguous := lookupMethod(scope.BinInfo, xv.DwarfType, name)
+	if ambiguous {
+		return nil, nil, fmt.Errorf("ambiguous selector %s.%s", xv.Name, name)
+	}
+	if m == nil {
+		return nil, nil, nil
+	}
+	for _, field := range m.path {
+		sv := xv.maybeDereference()
+		if sv.Unreadable != nil {
+			return nil, nil, sv.Unreadable
+		}
+		fv, err := sv.toField(field)
+		if err != nil {
+			return nil, nil, err
+		}
+		if fv.Unreadable != nil {
+			return nil, nil, fv.Unreadable
+		}
+		fv.Name = xv.Name + "." + field.Name
+		xv = fv
+	}
+
+	_, isptr := xv.RealType.(*godwarf.PtrType)
+	if m.ptrRecv {
+		if isptr {
+			return m.fn, xv, nil
+		}
+		if xv.Addr == 0 {
+			return nil, nil, fmt.Errorf("can not take address of %s to call %s", xv.Name, m.fn.Name)
+		}
+		recv := scope.newVariable(xv.Name, 0, pointerTo(xv.DwarfType, scope.BinInfo.Arch), scope.Mem)
+		recv.Children = []Variable{*xv}
+		recv.loaded = true
+		return m.fn, recv, nil
+	}
+	if !isptr {
+		return m.fn, xv, nil
+	}
+	recv := xv.maybeDereference()
+	if recv.Unreadable != nil {
+		return nil, nil, recv.Unreadable
+	}
+	recv.Name = xv.Name
+	return m.fn, recv, nil
+}
+
+// methodLookup is a method found by lookupMethod.
+type methodLookup struct {
+	fn          *Function
+	ptrRecv     bool                   // fn has a pointer receiver
+	path        []*godwarf.StructField // embedded fields leading to the receiver
+	inMethodSet bool                   // the method is in the method set of the type
+}
+
+// lookupMethod searches method name of typ and of its embedded fields. Like
+// the compiler it searches the embedded fields breadth first, the method at
+// the shallowest depth wins and two methods at the same depth make the
+// selector ambiguous.
+func lookupMethod(bi *BinaryInfo, typ godwarf.Type, name string) (m *methodLookup, ambiguous bool) {
+	type candidate struct {
+		typ    godwarf.Type
+		path   []*godwarf.StructField
+		viaPtr bool // a pointer leads to typ, its pointer methods are promoted
+	}
+	level := []candidate{{typ: typ}}
+	for depth := 0; depth <= maxEmbeddedDepth && len(level) > 0; depth++ {
+		var next []candidate
+		for _, c := range level {
+			if c.typ == nil {
+				continue
+			}
+			base, isptr := c.typ, false
+			if ptyp, ok := resolveTypedef(c.typ).(*godwarf.PtrType); ok {
+				base, isptr = ptyp.Type, true
+			}
+			valueRecvName, ptrRecvName := methodFuncNames(base.Common().Name, name)
+			var found *methodLookup
+			if fn := bi.LookupFunc[ptrRecvName]; fn != nil {
+				found = &methodLookup{fn: fn, ptrRecv: true, inMethodSet: isptr || c.viaPtr}
+			} else if fn := bi.LookupFunc[valueRecvName]; fn != nil {
+				found = &methodLookup{fn: fn, inMethodSet: true}
+			}
+			if found != nil {
+				if m != nil {
+					return nil, true
+				}
+				found.path = c.path
+				m = found
+				continue
+			}
+			st, ok := resolveTypedef(base).(*godwarf.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Field {
+				if field.Embedded {
+					path := append(c.path[:len(c.path):len(c.path)], field)
+					next = append(next, candidate{field.Type, path, c.viaPtr || isptr})
+				}
+			}
+		}
+		if m != nil {
+			return m, false
+		}
+		level = next
+	}
+	return nil, false
+}
+
+// methodFuncNames returns the names of the functions implementing method
+// of typename with a value receiver and with a pointer receiver.
+func methodFuncNames(typename, method string) (valueRecv, ptrRecv string) {
+	dot := strings.LastIndex(typename, ".")
+	if dot < 0 {
+		return typename + "." + method, "(*" + typename + ")." + method
+	}
+	pkg, name := typename[:dot], typename[dot+1:]
+	return pkg + "." + name + "." + method, pkg + ".(*" + name + ")." + method
+}
+
 type funcCallArg struct {
 	name string
 	typ  godwarf.Type
@@ -299,12 +740,12 @@ func funcCallArgFrame(fn *Function, actualArgs []*Variable, g *G, bi *BinaryInfo
 		formalArg := &formalArgs[i]
 		actualArg := actualArgs[i]
 
-		if actualArg.Addr == 0 {
-			//TODO(aarzilli): at least some of this needs to be supported
-			return nil, ErrNoAddrUnsupported
-		}
+		argv := newVariable(formalArg.name, uintptr(formalArg.off), formalArg.typ, bi, argFrameMemory(argmem))
 
-		if actualArg.RealType != formalArg.typ {
+		if err := actualArg.isType(formalArg.typ, argv.Kind); err != nil {
+			if actualArg.DwarfType == nil {
+				return nil, fmt.Errorf("cannot use %s as type %s in argument to %s: %v", actualArg.Name, formalArg.typ.String(), fn.Name, err)
+			}
 			return nil, fmt.Errorf("cannot use %s (type %s) as type %s in argument to %s", actualArg.Name, actualArg.DwarfType.String(), formalArg.typ.String(), fn.Name)
 		}
 
@@ -313,6 +754,18 @@ func funcCallArgFrame(fn *Function, actualArgs []*Variable, g *G, bi *BinaryInfo
 			return nil, fmt.Errorf("can not pass %s to %s: %v", actualArg.Name, formalArg.name, err)
 		}
 
+		if actualArg.Addr == 0 {
+			// constants and pointers created by the evaluator, like the
+			// receiver of a method call, have no memory to copy the value from.
+			if err := argv.setValue(actualArg); err != nil {
+				if actualArg.Kind == reflect.Ptr || actualArg.DwarfType == nil {
+					return nil, fmt.Errorf("can not pass %s to %s: %v", actualArg.Name, formalArg.name, err)
+				}
+				return nil, ErrNoAddrUnsupported
+			}
+			continue
+		}
+
 		//TODO(aarzilli): automatic type conversions
 		//TODO(aarzilli): automatic wrapping in interfaces?
 
@@ -331,9 +784,31 @@ func funcCallArgFrame(fn *Function, actualArgs []*Variable, g *G, bi *BinaryInfo
 	return argmem, nil
 }
 
+// argFrameMemory is the memory of an argument frame being built by
+// funcCallArgFrame, addresses are offsets from the start of the frame.
+type argFrameMemory []byte
+
+func (mem argFrameMemory) ReadMemory(buf []byte, addr uintptr) (int, error) {
+	if addr > uintptr(len(mem)) {
+		return 0, fmt.Errorf("offset %#x outside of the argument frame", addr)
+	}
+	return copy(buf, mem[addr:]), nil
+}
+
+func (mem argFrameMemory) WriteMemory(addr uintptr, data []byte) (int, error) {
+	if addr > uintptr(len(mem)) {
+		return 0, fmt.Errorf("offset %#x outside of the argument frame", addr)
+	}
+	return copy(mem[addr:], data), nil
+}
+
 func escapeCheck(v *Variable, name string, g *G) error {
 	switch v.Kind {
 	case reflect.Ptr:
+		if v.Addr == 0 && len(v.Children) == 1 {
+			// pointer created by the evaluator
+			return escapeCheckPointer(v.Children[0].Addr, name, g)
+		}
 		w := v.maybeDereference()
 		return escapeCheckPointer(w.Addr, name, g)
 	case reflect.Chan, reflect.String, reflect.Slice:
@@ -471,6 +946,20 @@ func (fncall *functionCallState) step(p Process) {
 			return (v.Flags & VariableReturnArgument) != 0
 		})
 
+		// the stack of the goroutine is reused by the next function call,
+		// return values must be copied to stay readable.
+		for _, v := range fncall.retvars {
+			if v.Unreadable != nil || v.RealType == nil {
+				continue
+			}
+			mem, err := snapshotMemory(v.mem, v.Addr, int(v.RealType.Size()))
+			if err != nil {
+				v.Unreadable = err
+				continue
+			}
+			v.mem = mem
+		}
+
 		loadValues(fncall.retvars, *fncall.retLoadCfg)
 
 	case debugCallAXReadPanic:
@@ -0,0 +1,883 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"go/constant"
+	"math/big"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derekparker/delve/pkg/dwarf/godwarf"
+)
+
+// VariableFormatter returns a summary of the value of v, a struct, that is
+// shown instead of its fields.
+type VariableFormatter func(v *Variable) (string, error)
+
+// maxBigIntWords is the maximum number of words of a math/big.Int read by its
+// formatter.
+const maxBigIntWords = 64
+
+// maxContextChain is the maximum number of contexts, starting from a
+// context and following their parents, described by formatContext.
+const maxContextChain = 32
+
+// maxErrorChain is the maximum number of errors, starting from an error and
+// following the errors wrapped by it, described by formatWrapError.
+const maxErrorChain = 16
+
+// builtinFormatters contains the formatters of the types of the standard
+// library, by type name. It is filled by init because the formatters
+// indirectly refer to it.
+var builtinFormatters = map[string]VariableFormatter{}
+
+func init() {
+	builtinFormatters["time.Time"] = formatTime
+	builtinFormatters["math/big.Int"] = formatBigInt
+	builtinFormatters["net/netip.Addr"] = formatNetipAddr
+	builtinFormatters["sync.Mutex"] = formatMutex
+	builtinFormatters["sync.WaitGroup"] = formatWaitGroup
+	for _, name := range []string{"Bool", "Int32", "Int64", "Uint32", "Uint64", "Uintptr", "Pointer", "Value"} {
+		builtinFormatters["sync/atomic."+name] = formatAtomic
+	}
+	for _, name := range []string{"context.valueCtx", "context.cancelCtx", "context.timerCtx", "context.withoutCancelCtx"} {
+		builtinFormatters[name] = formatContext
+	}
+	builtinFormatters["fmt.wrapError"] = formatWrapError
+	builtinFormatters["fmt.wrapErrors"] = formatWrapErrors
+	builtinFormatters["errors.joinError"] = formatWrapErrors
+}
+
+// Formatters contains the formatters registered by the user for struct
+// types, by type name, they take precedence over the formatters of the
+// standard library. The debugger keeps its own Formatters, so that they
+// survive restarts of the target, and sets it on the BinaryInfo of every
+// target with BinaryInfo.SetFormatters.
+type Formatters struct {
+	mu        sync.RWMutex
+	m         map[string]VariableFormatter
+	templates map[string]string
+}
+
+// NewFormatters returns an empty set of formatters.
+func NewFormatters() *Formatters {
+	return &Formatters{m: map[string]VariableFormatter{}, templates: map[string]string{}}
+}
+
+// intFormatters contains the formatters used for integer types, by type
+// name, see Variable.IntString.
+var intFormatters = map[string]func(n int64) string{
+	"time.Duration": func(n int64) string { return time.Duration(n).String() },
+}
+
+// Register registers fn as the formatter of the struct type typename,
+// replacing the previous formatter of the type, if any. If fn is nil the
+// formatter of typename is removed.
+func (f *Formatters) Register(typename string, fn VariableFormatter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.templates, typename)
+	if fn == nil {
+		delete(f.m, typename)
+		return
+	}
+	f.m[typename] = fn
+}
+
+// RegisterTemplate registers a formatter for the struct type typename that
+// prints tmpl, replacing every occurrence of {path} with the value of the
+// field path of the struct, path can contain dots to select fields of
+// nested structs. Braces can be escaped by doubling them.
+func (f *Formatters) RegisterTemplate(typename, tmpl string) error {
+	parts, err := parseFormatTemplate(tmpl)
+	if err != nil {
+		return err
+	}
+	f.Register(typename, func(v *Variable) (string, error) {
+		return formatTemplate(v, parts)
+	})
+	f.mu.Lock()
+	f.templates[typename] = tmpl
+	f.mu.Unlock()
+	return nil
+}
+
+// Templates returns the type names and templates registered with
+// RegisterTemplate, sorted by type name.
+func (f *Formatters) Templates() [][2]string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	r := make([][2]string, 0, len(f.templates))
+	for typename, tmpl := range f.templates {
+		r = append(r, [2]string{typename, tmpl})
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i][0] < r[j][0] })
+	return r
+}
+
+// lookup returns the formatter of typename, f can be nil.
+func (f *Formatters) lookup(typename string) VariableFormatter {
+	if f != nil {
+		f.mu.RLock()
+		fn := f.m[typename]
+		f.mu.RUnlock()
+		if fn != nil {
+			return fn
+		}
+	}
+	return builtinFormatters[typename]
+}
+
+// applyFormatter sets the value of v, a struct, to the summary returned by
+// the formatter of its type. Formatters that fail leave v unchanged.
+func (v *Variable) applyFormatter() {
+	t, ok := v.RealType.(*godwarf.StructType)
+	if !ok || v.Unreadable != nil {
+		return
+	}
+	formatters := v.bi.formatters
+	fn := formatters.lookup(t.StructName)
+	if fn == nil && v.DwarfType != nil {
+		fn = formatters.lookup(v.DwarfType.Common().Name)
+	}
+	if i := strings.IndexByte(t.StructName, '['); fn == nil && i >= 0 {
+		// instances of generic types use the formatter of the generic type
+		fn = formatters.lookup(t.StructName[:i])
+	}
+	if fn == nil {
+		return
+	}
+	s, err := fn(v)
+	if err != nil {
+		return
+	}
+	v.Value = constant.MakeString(s)
+	v.Flags |= VariableFormatted
+}
+
+// formatterField returns the field name of v, loaded with loadSingleValue.
+func (v *Variable) formatterField(name string) (*Variable, error) {
+	fv, err := v.structMember(name)
+	if err != nil {
+		return nil, err
+	}
+	fv.loadValue(loadSingleValue)
+	if fv.Unreadable != nil {
+		return nil, fv.Unreadable
+	}
+	return fv, nil
+}
+
+func (v *Variable) formatterInt(name string) (int64, error) {
+	fv, err := v.formatterField(name)
+	if err != nil {
+		return 0, err
+	}
+	if fv.Value == nil {
+		return 0, fmt.Errorf("field %s is not a number", name)
+	}
+	switch fv.Value.Kind() {
+	case constant.Int:
+		n, _ := constant.Int64Val(fv.Value)
+		return n, nil
+	case constant.Bool:
+		if constant.BoolVal(fv.Value) {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("field %s is not a number", name)
+}
+
+func (v *Variable) formatterUint(name string) (uint64, error) {
+	fv, err := v.formatterField(name)
+	if err != nil {
+		return 0, err
+	}
+	if fv.Value == nil || fv.Value.Kind() != constant.Int {
+		return 0, fmt.Errorf("field %s is not a number", name)
+	}
+	n, _ := constant.Uint64Val(fv.Value)
+	return n, nil
+}
+
+// formatterPointer returns the value of the pointer field name of v.
+func (v *Variable) formatterPointer(name string) (uint64, error) {
+	fv, err := v.toFieldNamed(name)
+	if err != nil {
+		return 0, err
+	}
+	return readUintRaw(fv.mem, fv.Addr, int64(v.bi.Arch.PtrSize()))
+}
+
+// formatterIface returns the concrete value of the interface field name of
+// v, without loading it, or nil if the interface is nil.
+func (v *Variable) formatterIface(name string) (*Variable, error) {
+	fv, err := v.toFieldNamed(name)
+	if err != nil {
+		return nil, err
+	}
+	if fv.Kind != reflect.Interface {
+		return nil, fmt.Errorf("field %s is not an interface", name)
+	}
+	fv.loadInterface(0, false, LoadConfig{})
+	if fv.Unreadable != nil {
+		return nil, fv.Unreadable
+	}
+	if len(fv.Children) == 0 || fv.Children[0].Kind == reflect.Invalid {
+		return nil, nil
+	}
+	return &fv.Children[0], nil
+}
+
+// toFieldNamed returns the field name of v without dereferencing it.
+func (v *Variable) toFieldNamed(name string) (*Variable, error) {
+	t, ok := v.RealType.(*godwarf.StructType)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", v.TypeString())
+	}
+	for _, field := range t.Field {
+		if field.Name == name {
+			return v.toField(field)
+		}
+	}
+	return nil, fmt.Errorf("%s has no field %s", v.TypeString(), name)
+}
+
+const (
+	timeHasMonotonic   = 1 << 63
+	timeNsecMask       = 1<<30 - 1
+	timeNsecShift      = 30
+	timeWallToInternal = (1884*365 + 1884/4 - 1884/100 + 1884/400) * 86400
+	timeUnixToInternal = (1969*365 + 1969/4 - 1969/100 + 1969/400) * 86400
+)
+
+// formatTime formats a time.Time like its String method, without the
+// monotonic clock reading. Since Go 1.9 the time is encoded in the wall and
+// ext fields, before it was stored in the sec and nsec fields.
+func formatTime(v *Variable) (string, error) {
+	var sec, nsec int64
+	if wall, err := v.formatterUint("wall"); err == nil {
+		ext, err := v.formatterInt("ext")
+		if err != nil {
+			return "", err
+		}
+		nsec = int64(wall & timeNsecMask)
+		if wall&timeHasMonotonic != 0 {
+			sec = timeWallToInternal + int64(wall<<1>>(timeNsecShift+1))
+		} else {
+			sec = ext
+		}
+	} else {
+		if sec, err = v.formatterInt("sec"); err != nil {
+			return "", err
+		}
+		if nsec, err = v.formatterInt("nsec"); err != nil {
+			return "", err
+		}
+	}
+	unix := sec - timeUnixToInternal
+	loc, err := v.timeLocation(unix)
+	if err != nil {
+		return "", err
+	}
+	return time.Unix(unix, nsec).In(loc).Format("2006-01-02 15:04:05.999999999 -0700 MST"), nil
+}
+
+// timeLocation returns the location of v, a time.Time. The zone cached by
+// the *time.Location of the target is used if it covers unix, otherwise
+// the zone is looked up in the transitions of the location, like the
+// lookup method of time.Location does.
+func (v *Variable) timeLocation(unix int64) (*time.Location, error) {
+	fv, err := v.formatterField("loc")
+	if err != nil {
+		return nil, err
+	}
+	loc := fv.maybeDereference()
+	if loc.Addr == 0 {
+		return time.UTC, nil
+	}
+	if zone, err := loc.formatterField("cacheZone"); err == nil && len(zone.Children) > 0 && zone.Children[0].Addr != 0 {
+		start, err1 := loc.formatterInt("cacheStart")
+		end, err2 := loc.formatterInt("cacheEnd")
+		if err1 == nil && err2 == nil && start <= unix && unix < end {
+			return timeZone(zone.maybeDereference())
+		}
+	}
+	return lookupTimeZone(loc, unix)
+}
+
+// timeZone returns a fixed location for zv, a time.zone.
+func timeZone(zv *Variable) (*time.Location, error) {
+	name, err := zv.formatterField("name")
+	if err != nil {
+		return nil, err
+	}
+	offset, err := zv.formatterInt("offset")
+	if err != nil {
+		return nil, err
+	}
+	return time.FixedZone(constant.StringVal(name.Value), int(offset)), nil
+}
+
+// lookupTimeZone returns the zone of loc, a time.Location, in effect at
+// unix, using the transitions stored in the location.
+func lookupTimeZone(loc *Variable, unix int64) (*time.Location, error) {
+	zones, err := loc.structMember("zone")
+	if err != nil {
+		return nil, err
+	}
+	tx, err := loc.structMember("tx")
+	if err != nil {
+		return nil, err
+	}
+	if zones.Unreadable != nil {
+		return nil, zones.Unreadable
+	}
+	if tx.Unreadable != nil {
+		return nil, tx.Unreadable
+	}
+	if zones.Len == 0 {
+		if name, err := loc.formatterField("name"); err == nil && constant.StringVal(name.Value) == "Local" {
+			// the local time zone is loaded the first time it is used
+			return nil, errors.New("local time zone not loaded by the target")
+		}
+		return time.UTC, nil
+	}
+	zone := func(i int64) *Variable {
+		return newVariable("", zones.Base+uintptr(i*zones.stride), zones.fieldType, zones.bi, zones.mem)
+	}
+	isDST := func(i int64) bool {
+		n, _ := zone(i).formatterInt("isDST")
+		return n != 0
+	}
+
+	// zi is the index of the zone in effect, found in the last transition
+	// before unix, see firstTimeZone if there is none.
+	zi := int64(-1)
+	var first, last int64 = -1, 0
+	firstZoneUsed := false
+	for i := int64(0); i < tx.Len; i++ {
+		t := newVariable("", tx.Base+uintptr(i*tx.stride), tx.fieldType, tx.bi, tx.mem)
+		when, err := t.formatterInt("when")
+		if err != nil {
+			return nil, err
+		}
+		index, err := t.formatterInt("index")
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			first = index
+		}
+		if index == 0 {
+			firstZoneUsed = true
+		}
+		if when <= unix {
+			zi, last = index, i
+		}
+	}
+	if zi >= 0 && last == tx.Len-1 {
+		if extend, err := loc.formatterField("extend"); err == nil && constant.StringVal(extend.Value) != "" {
+			return nil, fmt.Errorf("time zone rule %q not supported", constant.StringVal(extend.Value))
+		}
+	}
+	if zi < 0 {
+		zi = firstTimeZone(zones.Len, first, firstZoneUsed, isDST)
+	}
+	if zi >= zones.Len {
+		return nil, errors.New("malformed time.Location")
+	}
+	return timeZone(zone(zi))
+}
+
+// firstTimeZone returns the index of the zone in effect before the first
+// transition of a time.Location, like its lookupFirstZone method. first is
+// the zone of the first transition, -1 if there are none, firstZoneUsed is
+// true if a transition uses the zone at index 0.
+func firstTimeZone(nzones, first int64, firstZoneUsed bool, isDST func(int64) bool) int64 {
+	if !firstZoneUsed {
+		return 0
+	}
+	if first >= 0 && isDST(first) {
+		for i := first - 1; i >= 0; i-- {
+			if !isDST(i) {
+				return i
+			}
+		}
+	}
+	for i := int64(0); i < nzones; i++ {
+		if !isDST(i) {
+			return i
+		}
+	}
+	return 0
+}
+
+// formatBigInt formats a math/big.Int in base 10.
+func formatBigInt(v *Variable) (string, error) {
+	neg, err := v.formatterInt("neg")
+	if err != nil {
+		return "", err
+	}
+	abs, err := v.structMember("abs")
+	if err != nil {
+		return "", err
+	}
+	abs.loadValue(LoadConfig{MaxArrayValues: maxBigIntWords})
+	if abs.Unreadable != nil {
+		return "", abs.Unreadable
+	}
+	if abs.Len > maxBigIntWords {
+		return "", errors.New("number too large")
+	}
+	wordBits := uint(v.bi.Arch.PtrSize() * 8)
+	x := new(big.Int)
+	for i := len(abs.Children) - 1; i >= 0; i-- {
+		w, _ := constant.Uint64Val(abs.Children[i].Value)
+		x.Lsh(x, wordBits)
+		x.Or(x, new(big.Int).SetUint64(w))
+	}
+	if neg != 0 {
+		x.Neg(x)
+	}
+	return x.String(), nil
+}
+
+// formatNetipAddr formats a net/netip.Addr. The zone of IPv6 addresses is
+// not printed.
+func formatNetipAddr(v *Variable) (string, error) {
+	addr, err := v.structMember("addr")
+	if err != nil {
+		return "", err
+	}
+	hi, err := addr.formatterUint("hi")
+	if err != nil {
+		return "", err
+	}
+	lo, err := addr.formatterUint("lo")
+	if err != nil {
+		return "", err
+	}
+	z, err := v.formatterPointer("z")
+	if err != nil {
+		return "", err
+	}
+	if z == 0 {
+		return "invalid IP", nil
+	}
+	ip := make(net.IP, net.IPv6len)
+	for i := 0; i < 8; i++ {
+		ip[i] = byte(hi >> uint(56-8*i))
+		ip[8+i] = byte(lo >> uint(56-8*i))
+	}
+	if z4addr, ok := v.bi.packageVarAddr("net/netip.z4"); ok {
+		z4, err := readUintRaw(v.mem, uintptr(z4addr), int64(v.bi.Arch.PtrSize()))
+		if err == nil && z == z4 {
+			return ip.To4().String(), nil
+		}
+	}
+	return ip.String(), nil
+}
+
+// formatMutex formats a sync.Mutex as locked or unlocked followed by the
+// state flags and the number of waiters. Since Go 1.24 the state is stored
+// in the mu field, an internal/sync.Mutex.
+func formatMutex(v *Variable) (string, error) {
+	state, err := mutexState(v)
+	if err != nil {
+		return "", err
+	}
+	waiterShift := mutexWaiterShift(v.bi)
+	var r []string
+	if state&mutexLocked != 0 {
+		r = append(r, "locked")
+	} else {
+		r = append(r, "unlocked")
+	}
+	if state&mutexWoken != 0 {
+		r = append(r, "woken")
+	}
+	if waiterShift > 2 && state&mutexStarving != 0 {
+		r = append(r, "starving")
+	}
+	if waiters := uint32(state) >> waiterShift; waiters > 0 {
+		r = append(r, fmt.Sprintf("waiters: %d", waiters))
+	}
+	return strings.Join(r, ", "), nil
+}
+
+// formatWaitGroup formats a sync.WaitGroup as its counter followed by the
+// number of goroutines waiting for it. Since Go 1.20 the counter and the
+// waiters are stored in the state field, an atomic.Uint64, before they were
+// stored in state1, either a uint64 or, before Go 1.18, in the 64bit
+// aligned part of an array of 3 uint32s.
+func formatWaitGroup(v *Variable) (string, error) {
+	var state uint64
+	if sv, err := v.toFieldNamed("state"); err == nil {
+		if state, err = sv.formatterUint("v"); err != nil {
+			return "", err
+		}
+	} else {
+		sv, err := v.formatterField("state1")
+		if err != nil {
+			return "", err
+		}
+		switch sv.Kind {
+		case reflect.Array:
+			sv.loadValue(LoadConfig{MaxArrayValues: 3})
+			if len(sv.Children) != 3 {
+				return "", errors.New("malformed sync.WaitGroup")
+			}
+			i := 0
+			if sv.Addr%8 != 0 {
+				i = 1
+			}
+			lo, _ := constant.Uint64Val(sv.Children[i].Value)
+			hi, _ := constant.Uint64Val(sv.Children[i+1].Value)
+			state = hi<<32 | lo
+		default:
+			if state, err = v.formatterUint("state1"); err != nil {
+				return "", err
+			}
+		}
+	}
+	// the highest bit of the waiters is used by testing/synctest since Go 1.25
+	return fmt.Sprintf("counter: %d, waiters: %d", int32(state>>32), uint32(state)&0x7fffffff), nil
+}
+
+// formatAtomic formats the types of package sync/atomic as the value they
+// contain, stored in their v field.
+func formatAtomic(v *Variable) (string, error) {
+	name := v.RealType.(*godwarf.StructType).StructName
+	switch {
+	case name == "sync/atomic.Bool":
+		n, err := v.formatterUint("v")
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(n != 0), nil
+	case name == "sync/atomic.Value":
+		data, err := v.formatterIface("v")
+		if err != nil {
+			return "", err
+		}
+		if data == nil {
+			return "nil", nil
+		}
+		return formatFieldValue(data), nil
+	case strings.HasPrefix(name, "sync/atomic.Pointer["):
+		p, err := v.formatterPointer("v")
+		if err != nil {
+			return "", err
+		}
+		if p == 0 {
+			return "nil", nil
+		}
+		return fmt.Sprintf("%#x", p), nil
+	}
+	fv, err := v.formatterField("v")
+	if err != nil {
+		return "", err
+	}
+	return formatFieldValue(fv), nil
+}
+
+// formatContext formats the contexts of package context as the chain of
+// contexts starting from v and following their parents, for example:
+//
+//	key: value -> cancel -> background
+//
+// Since Go 1.21 the root contexts have types backgroundCtx and todoCtx,
+// before they were both an *emptyCtx.
+func formatContext(v *Variable) (string, error) {
+	var r []string
+	for i := 0; i < maxContextChain; i++ {
+		if v.Kind == reflect.Ptr {
+			v = v.maybeDereference()
+			if v.Unreadable != nil {
+				return "", v.Unreadable
+			}
+		}
+		var name string
+		if v.DwarfType != nil {
+			name = v.DwarfType.Common().Name
+		}
+		parent := "Context"
+		switch name {
+		case "context.valueCtx":
+			key, err := v.formatterIface("key")
+			if err != nil {
+				return "", err
+			}
+			val, err := v.formatterIface("val")
+			if err != nil {
+				return "", err
+			}
+			r = append(r, formatIfaceValue(key)+": "+formatIfaceValue(val))
+		case "context.cancelCtx":
+			r = append(r, "cancel")
+		case "context.timerCtx":
+			deadline, err := v.toFieldNamed("deadline")
+			if err != nil {
+				return "", err
+			}
+			t, err := formatTime(deadline)
+			if err != nil {
+				return "", err
+			}
+			r = append(r, "deadline "+t)
+			if v, err = v.toFieldNamed("cancelCtx"); err != nil {
+				return "", err
+			}
+		case "context.withoutCancelCtx":
+			r = append(r, "without cancel")
+			parent = "c"
+		case "context.backgroundCtx":
+			return strings.Join(append(r, "background"), " -> "), nil
+		case "context.todoCtx":
+			return strings.Join(append(r, "todo"), " -> "), nil
+		case "context.emptyCtx":
+			return strings.Join(append(r, "empty"), " -> "), nil
+		default:
+			return strings.Join(append(r, v.TypeString()), " -> "), nil
+		}
+		var err error
+		if v, err = v.formatterIface(parent); err != nil {
+			return "", err
+		}
+		if v == nil {
+			return strings.Join(append(r, "nil"), " -> "), nil
+		}
+	}
+	return strings.Join(append(r, "..."), " -> "), nil
+}
+
+// formatIfaceValue returns a short description of v, the concrete value of
+// an interface returned by formatterIface.
+func formatIfaceValue(v *Variable) string {
+	if v == nil {
+		return "nil"
+	}
+	return formatFieldValue(v)
+}
+
+// errorUnwrapFields maps the concrete types of errors created by the
+// standard library that wrap a single error to the field holding the error
+// returned by their Unwrap method.
+var errorUnwrapFields = map[string]string{
+	"*fmt.wrapError":   "err",
+	"*io/fs.PathError": "Err",
+	"*os.LinkError":    "Err",
+	"*os.SyscallError": "Err",
+	"*os/exec.Error":   "Err",
+	"*net.OpError":     "Err",
+	"*net/url.Error":   "Err",
+}
+
+// formatWrapError formats a fmt.wrapError, as returned by fmt.Errorf with
+// the %w verb, as its message followed by the chain of errors it wraps,
+// following the Unwrap methods of the errors in errorUnwrapFields.
+func formatWrapError(v *Variable) (string, error) {
+	msg, err := v.formatterField("msg")
+	if err != nil {
+		return "", err
+	}
+	err1, err := v.formatterIface("err")
+	if err != nil {
+		return "", err
+	}
+	var chain []string
+	for i := 0; err1 != nil; i++ {
+		if i >= maxErrorChain {
+			chain = append(chain, "...")
+			break
+		}
+		typename := err1.TypeString()
+		chain = append(chain, describeError(err1))
+		field := errorUnwrapFields[typename]
+		if field == "" {
+			break
+		}
+		ev := err1.maybeDereference()
+		if ev.Unreadable != nil {
+			return "", ev.Unreadable
+		}
+		if err1, err = ev.formatterIface(field); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%s (wraps %s)", formatFieldValue(msg), strings.Join(chain, " -> ")), nil
+}
+
+// formatWrapErrors formats a fmt.wrapErrors, returned by fmt.Errorf with
+// more than one %w verb, or an errors.joinError, returned by errors.Join,
+// as the list of errors it wraps. The message of fmt.wrapErrors is printed
+// before the list.
+func formatWrapErrors(v *Variable) (string, error) {
+	errs, err := v.structMember("errs")
+	if err != nil {
+		return "", err
+	}
+	errs.loadValue(LoadConfig{MaxArrayValues: maxErrorChain})
+	if errs.Unreadable != nil {
+		return "", errs.Unreadable
+	}
+	r := make([]string, 0, len(errs.Children))
+	for i := range errs.Children {
+		errs.Children[i].loadInterface(0, false, LoadConfig{})
+		if len(errs.Children[i].Children) == 0 || errs.Children[i].Children[0].Kind == reflect.Invalid {
+			r = append(r, "nil")
+			continue
+		}
+		r = append(r, describeError(&errs.Children[i].Children[0]))
+	}
+	if int64(len(errs.Children)) < errs.Len {
+		r = append(r, fmt.Sprintf("...+%d more", errs.Len-int64(len(errs.Children))))
+	}
+	list := "[" + strings.Join(r, ", ") + "]"
+	if _, err := v.toFieldNamed("msg"); err != nil {
+		return list, nil
+	}
+	msg, err := v.formatterField("msg")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (wraps %s)", formatFieldValue(msg), list), nil
+}
+
+// describeError returns the type of v, the concrete value of an error,
+// followed by its message if it is stored in one of the fields of
+// errorMessageFields or by its value if it is an integer, like
+// syscall.Errno.
+func describeError(v *Variable) string {
+	typename := v.TypeString()
+	if field, ok := errorMessageFields[typename]; ok {
+		if fv, err := v.maybeDereference().formatterField(field); err == nil {
+			return fmt.Sprintf("%s(%q)", typename, formatFieldValue(fv))
+		}
+	}
+	switch v.Kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fmt.Sprintf("%s(%s)", typename, formatFieldValue(v))
+	}
+	return typename
+}
+
+// formatTemplatePart is a part of a template registered with
+// Formatters.RegisterTemplate, either literal text or the path of a field.
+type formatTemplatePart struct {
+	text  string
+	field []string
+}
+
+func parseFormatTemplate(tmpl string) ([]formatTemplatePart, error) {
+	var parts []formatTemplatePart
+	var text []byte
+	for i := 0; i < len(tmpl); i++ {
+		switch tmpl[i] {
+		case '{':
+			if i+1 < len(tmpl) && tmpl[i+1] == '{' {
+				text = append(text, '{')
+				i++
+				continue
+			}
+			end := strings.IndexByte(tmpl[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated field at offset %d of format template", i)
+			}
+			path := strings.TrimSpace(tmpl[i+1 : i+end])
+			if path == "" {
+				return nil, fmt.Errorf("empty field at offset %d of format template", i)
+			}
+			if len(text) > 0 {
+				parts = append(parts, formatTemplatePart{text: string(text)})
+				text = nil
+			}
+			parts = append(parts, formatTemplatePart{field: strings.Split(path, ".")})
+			i += end
+		case '}':
+			if i+1 < len(tmpl) && tmpl[i+1] == '}' {
+				i++
+			}
+			text = append(text, '}')
+		default:
+			text = append(text, tmpl[i])
+		}
+	}
+	if len(text) > 0 {
+		parts = append(parts, formatTemplatePart{text: string(text)})
+	}
+	return parts, nil
+}
+
+func formatTemplate(v *Variable, parts []formatTemplatePart) (string, error) {
+	var buf []byte
+	for _, part := range parts {
+		if part.field == nil {
+			buf = append(buf, part.text...)
+			continue
+		}
+		fv := v
+		isnil := false
+		for _, name := range part.field {
+			if fv.Kind == reflect.Ptr {
+				fv = fv.maybeDereference()
+				if isnil = fv.Addr == 0 && fv.Unreadable == nil; isnil {
+					break
+				}
+			}
+			var err error
+			fv, err = fv.structMember(name)
+			if err != nil {
+				return "", err
+			}
+		}
+		if isnil {
+			buf = append(buf, "nil"...)
+			continue
+		}
+		buf = append(buf, formatFieldValue(fv)...)
+	}
+	return string(buf), nil
+}
+
+// formatFieldValue returns a short description of the value of v, used by
+// the formatters registered with Formatters.RegisterTemplate.
+func formatFieldValue(v *Variable) string {
+	v.loadValue(loadSingleValue)
+	if v.Unreadable != nil {
+		return fmt.Sprintf("(unreadable %v)", v.Unreadable)
+	}
+	switch v.Kind {
+	case reflect.String:
+		s := constant.StringVal(v.Value)
+		if int64(len(s)) != v.Len {
+			s = fmt.Sprintf("%s...+%d more", s, v.Len-int64(len(s)))
+		}
+		return s
+	case reflect.Ptr, reflect.UnsafePointer:
+		if len(v.Children) == 0 || v.Children[0].Addr == 0 {
+			return "nil"
+		}
+		return fmt.Sprintf("%#x", v.Children[0].Addr)
+	case reflect.Struct:
+		if v.Flags&VariableFormatted != 0 {
+			return constant.StringVal(v.Value)
+		}
+		return v.TypeString() + "{...}"
+	case reflect.Float32, reflect.Float64:
+		f, _ := constant.Float64Val(v.Value)
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	if v.Value == nil {
+		return v.TypeString() + "{...}"
+	}
+	return v.Value.ExactString()
+}
@@ -0,0 +1,190 @@
+package proc
+
+import (
+	"fmt"
+	"go/constant"
+	"sort"
+)
+
+// FreezeGoroutine freezes goroutine gid: the thread running it, and every
+// thread that is about to schedule it, are held stopped when the target is
+// resumed, while all other threads keep running.
+// Held threads own a P of the runtime, a stop-the-world started while they
+// are held can not complete until the target is stopped and resumed, which
+// releases them, see releaseHeldThreadsForStopTheWorld.
+// Only the linux native backend holds threads, other backends resume them
+// normally.
+func FreezeGoroutine(p Process, gid int) error {
+	if _, err := p.Valid(); err != nil {
+		return err
+	}
+	g, err := FindGoroutine(p, gid)
+	if err != nil {
+		return err
+	}
+	if g == nil {
+		return fmt.Errorf("unknown goroutine %d", gid)
+	}
+	common := p.Common()
+	if common.frozen[g.ID] {
+		return nil
+	}
+	if err := setFreezeBreakpoint(p); err != nil {
+		return err
+	}
+	if common.frozen == nil {
+		common.frozen = make(map[int]bool)
+	}
+	common.frozen[g.ID] = true
+	if g.Thread != nil {
+		g.Thread.Common().heldGoroutine = g.ID
+	}
+	return nil
+}
+
+// ThawGoroutine undoes the effect of FreezeGoroutine on goroutine gid.
+func ThawGoroutine(p Process, gid int) error {
+	common := p.Common()
+	if !common.frozen[gid] {
+		return fmt.Errorf("goroutine %d is not frozen", gid)
+	}
+	delete(common.frozen, gid)
+	for _, thread := range p.ThreadList() {
+		if thread.Common().heldGoroutine == gid {
+			thread.Common().heldGoroutine = 0
+		}
+	}
+	return clearFreezeBreakpoint(p)
+}
+
+// FrozenGoroutines returns the IDs of the goroutines frozen with
+// FreezeGoroutine, sorted in increasing order.
+func (p *CommonProcess) FrozenGoroutines() []int {
+	r := make([]int, 0, len(p.frozen))
+	for gid := range p.frozen {
+		r = append(r, gid)
+	}
+	sort.Ints(r)
+	return r
+}
+
+// setFreezeBreakpoint sets a FreezeBreakpoint on runtime.execute, the
+// function used by the scheduler to run a goroutine on the current thread,
+// if it isn't set already.
+func setFreezeBreakpoint(p Process) error {
+	if freezeBreakpoint(p) != nil {
+		return nil
+	}
+	addr, err := FindFunctionLocation(p, "runtime.execute", true, 0)
+	if err != nil {
+		return err
+	}
+	_, err = p.SetBreakpoint(addr, FreezeBreakpoint, nil)
+	return err
+}
+
+// clearFreezeBreakpoint clears the FreezeBreakpoint if no goroutine is
+// frozen and the scheduling history isn't recorded.
+func clearFreezeBreakpoint(p Process) error {
+	common := p.Common()
+	if len(common.frozen) > 0 || common.schedHistory != nil {
+		return nil
+	}
+	if bp := freezeBreakpoint(p); bp != nil {
+		_, err := p.ClearBreakpointKind(bp.Addr, FreezeBreakpoint)
+		return err
+	}
+	return nil
+}
+
+// freezeBreakpoint returns the FreezeBreakpoint, or nil if it isn't set.
+func freezeBreakpoint(p Process) *Breakpoint {
+	for _, bp := range p.Breakpoints().M {
+		if bp.Kind&FreezeBreakpoint != 0 {
+			return bp
+		}
+	}
+	return nil
+}
+
+// executedGoroutine returns the ID of the goroutine thread, stopped at the
+// FreezeBreakpoint, is about to run.
+func executedGoroutine(thread Thread) (int, bool) {
+	scope, err := ThreadScope(thread)
+	if err != nil {
+		return 0, false
+	}
+	v, err := scope.EvalVariable("gp.goid", loadSingleValue)
+	if err != nil || v.Unreadable != nil || v.Value == nil {
+		return 0, false
+	}
+	goid, _ := constant.Int64Val(v.Value)
+	return int(goid), true
+}
+
+// CheckFreezeBreakpoint records thread in the scheduling history and holds
+// it if it is about to run a frozen goroutine, when bpstate is the
+// FreezeBreakpoint.
+// Backends must call this after every call to CheckCondition on a
+// software breakpoint.
+func (p *CommonProcess) CheckFreezeBreakpoint(thread Thread, bpstate *BreakpointState) {
+	if bpstate.Breakpoint == nil || bpstate.Kind&FreezeBreakpoint == 0 {
+		return
+	}
+	if goid, ok := executedGoroutine(thread); ok {
+		p.recordSchedEvent(thread, goid)
+		p.holdIfFrozen(thread, goid)
+	}
+}
+
+// holdIfFrozen holds thread, stopped at the FreezeBreakpoint, if
+// goroutine goid, which it is about to run, is frozen.
+func (p *CommonProcess) holdIfFrozen(thread Thread, goid int) {
+	if p.frozen[goid] {
+		thread.Common().heldGoroutine = goid
+	}
+}
+
+// Held returns true if the thread is held stopped because it is running,
+// or about to run, a frozen goroutine. Backends must not resume held
+// threads.
+func (t *CommonThread) Held() bool {
+	return t.heldGoroutine != 0
+}
+
+// releaseHeldThreadsForStopTheWorld releases the held threads if the
+// runtime is stopping the world, for a garbage collection or a change of
+// GOMAXPROCS. Held threads own a P, the runtime waits for all Ps to stop
+// before it can proceed and holding them would hang the target. Frozen
+// goroutines stay frozen, the threads that schedule them are held again.
+func releaseHeldThreadsForStopTheWorld(p Process) {
+	threads := p.ThreadList()
+	held := false
+	for _, thread := range threads {
+		held = held || thread.Common().Held()
+	}
+	if !held {
+		return
+	}
+	scope, err := ThreadScope(p.CurrentThread())
+	if err != nil {
+		return
+	}
+	if stopwait, err := evalInt(scope, "runtime.sched.stopwait"); err != nil || stopwait <= 0 {
+		return
+	}
+	for _, thread := range threads {
+		thread.Common().heldGoroutine = 0
+	}
+}
+
+// allThreadsHeld returns true if every thread of p is held.
+func allThreadsHeld(p Process) bool {
+	threads := p.ThreadList()
+	for _, thread := range threads {
+		if !thread.Common().Held() {
+			return false
+		}
+	}
+	return len(threads) > 0
+}
@@ -922,7 +922,7 @@ func (p *Process) Checkpoints() ([]proc.Checkpoint, error) {
 		if err != nil {
 			return nil, fmt.Errorf("can not parse \"info checkpoints\" output line %q: %v", line, err)
 		}
-		r = append(r, proc.Checkpoint{cpid, fields[1], fields[2]})
+		r = append(r, proc.Checkpoint{ID: cpid, When: fields[1], Where: fields[2]})
 	}
 	return r, nil
 }
@@ -966,21 +966,20 @@ func (p *Process) Breakpoints() *proc.BreakpointMap {
 
 func (p *Process) FindBreakpoint(pc uint64) (*proc.Breakpoint, bool) {
 	// Check to see if address is past the breakpoint, (i.e. breakpoint was hit).
-	if bp, ok := p.breakpoints.M[pc-uint64(p.bi.Arch.BreakpointSize())]; ok {
+	if bp, ok := p.breakpoints.M[pc-uint64(p.bi.Arch.BreakpointSize())]; ok && !bp.Disarmed() {
 		return bp, true
 	}
 	// Directly use addr to lookup breakpoint.
-	if bp, ok := p.breakpoints.M[pc]; ok {
+	if bp, ok := p.breakpoints.M[pc]; ok && !bp.Disarmed() {
 		return bp, true
 	}
 	return nil, false
 }
 
 func (p *Process) writeBreakpoint(addr uint64) (string, int, *proc.Function, []byte, error) {
+	// fn can be nil here, breakpoints can be set on addresses without
+	// symbols.
 	f, l, fn := p.bi.PCToLine(uint64(addr))
-	if fn == nil {
-		return "", 0, nil, nil, proc.InvalidAddressError{Address: addr}
-	}
 
 	if err := p.conn.setBreakpoint(addr); err != nil {
 		return "", 0, nil, nil, err
@@ -996,6 +995,10 @@ func (p *Process) SetBreakpoint(addr uint64, kind proc.BreakpointKind, cond ast.
 	return p.breakpoints.Set(addr, kind, cond, p.writeBreakpoint)
 }
 
+func (p *Process) SetWatchpoint(addr uint64, size int, wtype proc.WatchType, cond ast.Expr) (*proc.Breakpoint, error) {
+	return nil, proc.ErrWatchpointsUnsupportedBackend
+}
+
 func (p *Process) ClearBreakpoint(addr uint64) (*proc.Breakpoint, error) {
 	if p.exited {
 		return nil, &proc.ProcessExitedError{Pid: p.conn.pid}
@@ -1005,6 +1008,24 @@ func (p *Process) ClearBreakpoint(addr uint64) (*proc.Breakpoint, error) {
 	})
 }
 
+func (p *Process) ClearBreakpointKind(addr uint64, kind proc.BreakpointKind) (*proc.Breakpoint, error) {
+	if p.exited {
+		return nil, &proc.ProcessExitedError{Pid: p.conn.pid}
+	}
+	return p.breakpoints.ClearKind(addr, kind, func(bp *proc.Breakpoint) error {
+		return p.conn.clearBreakpoint(bp.Addr)
+	})
+}
+
+func (p *Process) SetBreakpointsDisabled(bps []*proc.Breakpoint, disabled bool) error {
+	if p.exited {
+		return &proc.ProcessExitedError{Pid: p.conn.pid}
+	}
+	return p.breakpoints.SetDisabled(bps, disabled, p.writeBreakpoint, func(bp *proc.Breakpoint) error {
+		return p.conn.clearBreakpoint(bp.Addr)
+	})
+}
+
 func (p *Process) ClearInternalBreakpoints() error {
 	return p.breakpoints.ClearInternalBreakpoints(func(bp *proc.Breakpoint) error {
 		if err := p.conn.clearBreakpoint(bp.Addr); err != nil {
@@ -1207,7 +1228,7 @@ func (t *Thread) Common() *proc.CommonThread {
 
 func (t *Thread) stepInstruction(tu *threadUpdater) error {
 	pc := t.regs.PC()
-	if _, atbp := t.p.breakpoints.M[pc]; atbp {
+	if bp, atbp := t.p.breakpoints.M[pc]; atbp && !bp.Disarmed() {
 		err := t.p.conn.clearBreakpoint(pc)
 		if err != nil {
 			return err
@@ -1508,12 +1529,8 @@ func (thread *Thread) SetCurrentBreakpoint() error {
 			}
 		}
 		thread.CurrentBreakpoint = bp.CheckCondition(thread)
-		if thread.CurrentBreakpoint.Breakpoint != nil && thread.CurrentBreakpoint.Active {
-			if g, err := proc.GetG(thread); err == nil {
-				thread.CurrentBreakpoint.HitCount[g.ID]++
-			}
-			thread.CurrentBreakpoint.TotalHitCount++
-		}
+		thread.p.common.CheckFreezeBreakpoint(thread, &thread.CurrentBreakpoint)
+		thread.p.common.CheckLogpoint(thread, &thread.CurrentBreakpoint)
 	}
 	return nil
 }
@@ -160,6 +160,67 @@ func TestReverseBreakpointCounts(t *testing.T) {
 	})
 }
 
+func TestReverseNext(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestRecording("testnextprog", t, func(p *gdbserial.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture.Source, 42)
+		assertNoError(proc.Continue(p), t, "Continue()")
+
+		assertNoError(proc.ReverseNext(p), t, "ReverseNext()")
+		if loc, _ := p.CurrentThread().Location(); loc.Line != 41 {
+			t.Fatalf("wrong line after ReverseNext %s:%d", loc.File, loc.Line)
+		}
+		assertNoError(proc.ReverseStep(p), t, "ReverseStep()")
+		if loc, _ := p.CurrentThread().Location(); loc.Line != 40 {
+			t.Fatalf("wrong line after ReverseStep %s:%d", loc.File, loc.Line)
+		}
+		assertNoError(proc.ReverseStep(p), t, "ReverseStep()")
+		if loc, _ := p.CurrentThread().Location(); loc.Fn == nil || loc.Fn.Name != "main.testnext" {
+			t.Fatalf("did not step backwards into main.testnext %s:%d", loc.File, loc.Line)
+		}
+		if p.Breakpoints().HasInternalBreakpoints() {
+			t.Fatal("internal breakpoints not cleared")
+		}
+
+		// the direction must be restored once ReverseNext returns
+		assertNoError(proc.Next(p), t, "Next()")
+		if loc, _ := p.CurrentThread().Location(); loc.Line != 39 {
+			t.Fatalf("wrong line after Next %s:%d", loc.File, loc.Line)
+		}
+	})
+}
+
+func TestReverseStepInstruction(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestRecording("testnextprog", t, func(p *gdbserial.Process, fixture protest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.main")
+		assertNoError(proc.Continue(p), t, "Continue()")
+		regs, err := p.CurrentThread().Registers(false)
+		assertNoError(err, t, "Registers()")
+		pc, sp := regs.PC(), regs.SP()
+
+		for i := 0; i < 3; i++ {
+			assertNoError(p.StepInstruction(), t, "StepInstruction()")
+		}
+		for i := 0; i < 3; i++ {
+			assertNoError(proc.ReverseStepInstruction(p), t, "ReverseStepInstruction()")
+		}
+		regs, err = p.CurrentThread().Registers(false)
+		assertNoError(err, t, "Registers()")
+		if regs.PC() != pc || regs.SP() != sp {
+			t.Fatalf("wrong registers after ReverseStepInstruction pc=%#x sp=%#x (expected pc=%#x sp=%#x)", regs.PC(), regs.SP(), pc, sp)
+		}
+
+		// the direction must be restored once ReverseStepInstruction returns
+		assertNoError(p.StepInstruction(), t, "StepInstruction()")
+		regs, err = p.CurrentThread().Registers(false)
+		assertNoError(err, t, "Registers()")
+		if regs.PC() == pc {
+			t.Fatalf("StepInstruction did not move forward")
+		}
+	})
+}
+
 func getPosition(p *gdbserial.Process, t *testing.T) (when string, loc *proc.Location) {
 	var err error
 	when, err = p.When()
@@ -251,3 +312,30 @@ func TestCheckpoints(t *testing.T) {
 		}
 	})
 }
+
+func TestSeekEvent(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestRecording("continuetestprog", t, func(p *gdbserial.Process, fixture protest.Fixture) {
+		bp := setFunctionBreakpoint(p, t, "main.main")
+		assertNoError(proc.Continue(p), t, "Continue")
+		event0, err := proc.CurrentEvent(p)
+		assertNoError(err, t, "CurrentEvent")
+		_, err = p.ClearBreakpoint(bp.Addr)
+		assertNoError(err, t, "ClearBreakpoint")
+
+		setFunctionBreakpoint(p, t, "main.sayhi")
+		assertNoError(proc.Continue(p), t, "Continue")
+		event1, err := proc.CurrentEvent(p)
+		assertNoError(err, t, "CurrentEvent")
+		if event1 <= event0 {
+			t.Fatalf("event did not advance: %d -> %d", event0, event1)
+		}
+
+		assertNoError(proc.SeekEvent(p, event0), t, "SeekEvent")
+		event, err := proc.CurrentEvent(p)
+		assertNoError(err, t, "CurrentEvent")
+		if event != event0 {
+			t.Fatalf("wrong event after SeekEvent %d (expected %d)", event, event0)
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package proc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/derekparker/delve/pkg/goversion"
+)
+
+// gscanStatus is the bit set in the status of a goroutine while its stack
+// is being scanned by the garbage collector.
+const gscanStatus = 0x1000
+
+// goroutineStatusNames maps the status of goroutines to the names returned
+// by G.StatusName and accepted by GoroutineStatusByName.
+var goroutineStatusNames = map[uint64]string{
+	Gidle:      "idle",
+	Grunnable:  "runnable",
+	Grunning:   "running",
+	Gsyscall:   "syscall",
+	Gwaiting:   "waiting",
+	Gdead:      "dead",
+	Gcopystack: "copystack",
+}
+
+// goroutineStatusVersions lists the statuses used by the runtime only
+// since a given version of Go, with the name they are reported as.
+var goroutineStatusVersions = []struct {
+	major, minor int
+	status       uint64
+	name         string
+}{
+	{1, 14, Gpreempted, "waiting"},
+}
+
+// StatusName returns the name of the status of g, one of idle, runnable,
+// running, syscall, waiting, dead, copystack and unknown. Unlike the values
+// of the status, which change between versions of Go, names are stable:
+// goroutines stopped by a preemption request, since Go 1.14, are waiting.
+func (g *G) StatusName() string {
+	status := g.Status &^ gscanStatus
+	if name, ok := goroutineStatusNames[status]; ok {
+		return name
+	}
+	producer := ""
+	if g.variable != nil {
+		producer = g.variable.bi.Producer()
+	}
+	for _, sv := range goroutineStatusVersions {
+		if sv.status == status && (producer == "" || goversion.ProducerAfterOrEqual(producer, sv.major, sv.minor)) {
+			return sv.name
+		}
+	}
+	return "unknown"
+}
+
+// GoroutineStatusByName returns the goroutine status called name, one of
+// idle, runnable, running, syscall, waiting, dead and copystack.
+func GoroutineStatusByName(name string) (uint64, error) {
+	for status, statusName := range goroutineStatusNames {
+		if statusName == name {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown goroutine state %q", name)
+}
+
+// maxGoroutineFilterDepth is the maximum number of frames of each goroutine
+// searched for GoroutineFilter.Function.
+const maxGoroutineFilterDepth = 50
+
+// GoroutineFilter describes the goroutines returned by FilterGoroutines, a
+// goroutine is returned if it satisfies every non-empty field.
+type GoroutineFilter struct {
+	// States is a list of goroutine statuses, the status of the goroutine
+	// must be one of them.
+	States []uint64
+	// Function must be a substring of the name of one of the functions in
+	// the stack of the goroutine.
+	Function string
+	// Labels are pprof labels that the goroutine must have, with the same
+	// values.
+	Labels map[string]string
+	// ExcludeSystem excludes the goroutines started by the runtime, see
+	// G.System.
+	ExcludeSystem bool
+}
+
+func (filter *GoroutineFilter) match(g *G) bool {
+	if filter.ExcludeSystem && g.System() {
+		return false
+	}
+	if len(filter.States) > 0 {
+		found := false
+		for _, status := range filter.States {
+			if g.StatusName() == goroutineStatusNames[status] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(filter.Labels) > 0 {
+		labels := g.Labels()
+		for k, v := range filter.Labels {
+			if lv, ok := labels[k]; !ok || lv != v {
+				return false
+			}
+		}
+	}
+	if filter.Function != "" {
+		frames, err := g.Stacktrace(maxGoroutineFilterDepth, false)
+		if err != nil {
+			return false
+		}
+		found := false
+		for _, frame := range frames {
+			if frame.Current.Fn != nil && strings.Contains(frame.Current.Fn.Name, filter.Function) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterGoroutines returns the goroutines matching filter, starting from
+// the goroutine at index start of the list returned by GoroutinesInfo. If
+// count is greater than zero at most count goroutines are returned.
+// The second return value is the index the next call should start from to
+// get the following goroutines, or -1 if there are no more goroutines.
+func FilterGoroutines(p Process, filter GoroutineFilter, start, count int) ([]*G, int, error) {
+	gs, err := GoroutinesInfo(p)
+	if err != nil {
+		return nil, -1, err
+	}
+	if start < 0 {
+		start = 0
+	}
+	r := []*G{}
+	for i := start; i < len(gs); i++ {
+		if !filter.match(gs[i]) {
+			continue
+		}
+		r = append(r, gs[i])
+		if count > 0 && len(r) >= count {
+			if i+1 < len(gs) {
+				return r, i + 1, nil
+			}
+			break
+		}
+	}
+	return r, -1, nil
+}
+
+// GoroutineGroupBy is the criterion used by GroupGoroutines to group
+// goroutines.
+type GoroutineGroupBy uint8
+
+const (
+	// GroupByCurrentFunc groups goroutines by the function of their topmost
+	// frame.
+	GroupByCurrentFunc GoroutineGroupBy = iota + 1
+	// GroupByUserFunc groups goroutines by the function of their topmost
+	// frame that is not part of the runtime, see G.UserCurrent.
+	GroupByUserFunc
+	// GroupByWaitReason groups goroutines by the reason they are parked, or
+	// by their status if they are not parked.
+	GroupByWaitReason
+)
+
+// GoroutineGroup is a group of goroutines returned by GroupGoroutines.
+type GoroutineGroup struct {
+	// Name is the name of the function or the wait reason shared by the
+	// goroutines of the group.
+	Name string
+	// Count is the number of goroutines in the group.
+	Count int
+	// IDs contains the IDs of some of the goroutines in the group.
+	IDs []int
+}
+
+// GroupGoroutines groups the goroutines matching filter according to
+// groupBy. Groups are sorted by decreasing size and contain at most maxIDs
+// goroutine IDs, or all of them if maxIDs is less than zero.
+func GroupGoroutines(p Process, filter GoroutineFilter, groupBy GoroutineGroupBy, maxIDs int) ([]GoroutineGroup, error) {
+	gs, _, err := FilterGoroutines(p, filter, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	groupIdx := make(map[string]int)
+	groups := []GoroutineGroup{}
+	for _, g := range gs {
+		var name string
+		switch groupBy {
+		case GroupByCurrentFunc:
+			name = locationFuncName(g.CurrentLoc)
+		case GroupByUserFunc:
+			name = locationFuncName(g.UserCurrent())
+		case GroupByWaitReason:
+			name = g.WaitReason
+			if name == "" || g.Status&^gscanStatus != Gwaiting {
+				name = g.StatusName()
+			}
+		default:
+			return nil, fmt.Errorf("unknown goroutine grouping %d", groupBy)
+		}
+		i, ok := groupIdx[name]
+		if !ok {
+			i = len(groups)
+			groupIdx[name] = i
+			groups = append(groups, GoroutineGroup{Name: name})
+		}
+		groups[i].Count++
+		if maxIDs < 0 || len(groups[i].IDs) < maxIDs {
+			groups[i].IDs = append(groups[i].IDs, g.ID)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+// System returns true if g was started by the runtime, like the garbage
+// collector workers, the finalizer goroutine and the timer goroutine. The
+// goroutine running runtime.main is the only goroutine started by a
+// function of the runtime that runs user code.
+func (g *G) System() bool {
+	if g.variable == nil {
+		return false
+	}
+	fn := g.StartLoc().Fn
+	return fn != nil && strings.HasPrefix(fn.Name, "runtime.") && fn.Name != "runtime.main"
+}
+
+func locationFuncName(loc Location) string {
+	if loc.Fn == nil {
+		return fmt.Sprintf("%#x", loc.PC)
+	}
+	return loc.Fn.Name
+}
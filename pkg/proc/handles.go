@@ -0,0 +1,91 @@
+package proc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// VariableHandles assigns handles to the variables whose children were not
+// loaded, because of the limits of the LoadConfig used to load them, so
+// that their children can be loaded later with Expand. The same handle is
+// assigned every time to a variable at the same address with the same
+// type. Handles are only valid while the target is stopped: Reset must be
+// called when it is resumed.
+type VariableHandles struct {
+	vars    []*Variable
+	handles map[variableHandleKey]int
+}
+
+type variableHandleKey struct {
+	addr uintptr
+	typ  string
+}
+
+// Register assigns a handle to v, and to the variables it contains, if
+// their children were not completely loaded. Handles are stored in the
+// Handle field of the variables.
+func (vh *VariableHandles) Register(v *Variable) {
+	if v.Unreadable != nil {
+		return
+	}
+	for i := range v.Children {
+		vh.Register(&v.Children[i])
+	}
+	if !v.childrenTruncated() {
+		return
+	}
+	key := variableHandleKey{v.Addr, v.TypeString()}
+	if h := vh.handles[key]; v.Addr != 0 && h != 0 {
+		v.Handle = h
+		return
+	}
+	sv := v.clone()
+	sv.Children = nil
+	sv.Handle = 0
+	vh.vars = append(vh.vars, sv)
+	v.Handle = len(vh.vars)
+	if v.Addr != 0 {
+		if vh.handles == nil {
+			vh.handles = make(map[variableHandleKey]int)
+		}
+		vh.handles[key] = v.Handle
+	}
+}
+
+// childrenTruncated returns true if some of the children of v were not
+// loaded.
+func (v *Variable) childrenTruncated() bool {
+	switch v.Kind {
+	case reflect.Struct, reflect.Array, reflect.Slice:
+		return int64(len(v.Children)) < v.Len
+	case reflect.Map:
+		return int64(len(v.Children)/2) < v.Len
+	case reflect.Ptr, reflect.Interface:
+		return len(v.Children) > 0 && v.Children[0].OnlyAddr && v.Children[0].Addr != 0
+	}
+	return false
+}
+
+// Expand loads the variable with handle h according to cfg and registers
+// the variables it contains. Pointers are always followed, expanding a
+// pointer loads the value it points to. Expanding a slice, array or map
+// loads its first elements again, unless cfg allows more elements than the
+// configuration used to load it.
+func (vh *VariableHandles) Expand(h int, cfg LoadConfig) (*Variable, error) {
+	if h <= 0 || h > len(vh.vars) {
+		return nil, fmt.Errorf("invalid variable handle %d", h)
+	}
+	v := vh.vars[h-1].clone()
+	v.loaded = false
+	v.Flags &^= VariableTruncated
+	cfg.FollowPointers = true
+	v.loadValue(cfg)
+	vh.Register(v)
+	return v, nil
+}
+
+// Reset invalidates all handles.
+func (vh *VariableHandles) Reset() {
+	vh.vars = nil
+	vh.handles = nil
+}
@@ -2,6 +2,7 @@ package proc
 
 import (
 	"go/ast"
+	"regexp"
 )
 
 // Process represents the target of the debugger. This
@@ -102,8 +103,17 @@ type ProcessManipulation interface {
 type BreakpointManipulation interface {
 	Breakpoints() *BreakpointMap
 	SetBreakpoint(addr uint64, kind BreakpointKind, cond ast.Expr) (*Breakpoint, error)
+	// SetWatchpoint sets a hardware watchpoint on the size bytes of memory
+	// starting at addr.
+	SetWatchpoint(addr uint64, size int, wtype WatchType, cond ast.Expr) (*Breakpoint, error)
 	ClearBreakpoint(addr uint64) (*Breakpoint, error)
+	// ClearBreakpointKind removes kind from the breakpoint at addr, the
+	// breakpoint is cleared once no kind is left.
+	ClearBreakpointKind(addr uint64, kind BreakpointKind) (*Breakpoint, error)
 	ClearInternalBreakpoints() error
+	// SetBreakpointsDisabled disables or enables all the user breakpoints
+	// in bps.
+	SetBreakpointsDisabled(bps []*Breakpoint, disabled bool) error
 }
 
 // CommonProcess contains fields used by this package, common to all
@@ -112,8 +122,62 @@ type CommonProcess struct {
 	allGCache     []*G
 	fncallState   functionCallState
 	fncallEnabled bool
+	logpointFn    LogpointFunc
+	actionsFn     BreakpointActionsFunc
+	signalPolicy  map[int]SignalPolicy
+	syscallCatch  map[int]bool
+	stepFilters   []string
+	stepFilterRx  []*regexp.Regexp
+	blackbox      []string
+	resumeMode    ResumeMode
+	frozen        map[int]bool // goroutines frozen by FreezeGoroutine
+	// schedHistory is the scheduling history recorded since the target was
+	// last resumed, nil if RecordSchedHistory is not enabled.
+	schedHistory []SchedEvent
+	// selectedFrame is the frame of goroutine selectedFrameG selected by
+	// SwitchFrame.
+	selectedFrame  int
+	selectedFrameG int
+	// stepIntoRuntime is true if Step enters unexported runtime functions.
+	stepIntoRuntime bool
+	// manualStop is true if the last call to Continue was interrupted by
+	// RequestManualStop.
+	manualStop bool
 }
 
+// SignalPolicy determines what happens when the target receives a signal.
+type SignalPolicy uint8
+
+const (
+	// SignalPass delivers the signal to the target without stopping it.
+	SignalPass SignalPolicy = iota
+	// SignalStop stops the target, the signal is delivered to the target
+	// when it is resumed.
+	SignalStop
+	// SignalIgnore discards the signal without stopping the target.
+	SignalIgnore
+)
+
+// ResumeMode determines which threads are resumed by ContinueOnce and
+// which threads are stopped when the target stops.
+type ResumeMode uint8
+
+const (
+	// ResumeAll resumes all threads and stops all threads when one of them
+	// stops.
+	ResumeAll ResumeMode = iota
+	// ResumeSelected only resumes the thread running the selected goroutine,
+	// or the current thread, all other threads stay halted.
+	ResumeSelected
+	// ResumeNonStop resumes all threads and, when a thread stops at a user
+	// breakpoint, leaves all other threads running, as if every breakpoint
+	// had the SuspendThread policy.
+	ResumeNonStop
+)
+
+// LogpointFunc is called when thread hits the logpoint bp.
+type LogpointFunc func(thread Thread, bp *Breakpoint)
+
 func NewCommonProcess(fncallEnabled bool) CommonProcess {
 	return CommonProcess{fncallEnabled: fncallEnabled}
 }
@@ -122,3 +186,102 @@ func NewCommonProcess(fncallEnabled bool) CommonProcess {
 func (p *CommonProcess) ClearAllGCache() {
 	p.allGCache = nil
 }
+
+// SetSignalPolicy sets the policy used when the target receives signal
+// sig. Only the linux native backend honors this setting, other backends
+// always use SignalPass.
+func (p *CommonProcess) SetSignalPolicy(sig int, policy SignalPolicy) {
+	if policy == SignalPass {
+		delete(p.signalPolicy, sig)
+		return
+	}
+	if p.signalPolicy == nil {
+		p.signalPolicy = make(map[int]SignalPolicy)
+	}
+	p.signalPolicy[sig] = policy
+}
+
+// SignalPolicy returns the policy used when the target receives signal
+// sig.
+func (p *CommonProcess) SignalPolicy(sig int) SignalPolicy {
+	return p.signalPolicy[sig]
+}
+
+// StoppedManually returns true if the last call to Continue returned
+// because of a call to RequestManualStop, rather than because of a
+// breakpoint or another event.
+func (p *CommonProcess) StoppedManually() bool {
+	return p.manualStop
+}
+
+// SetResumeMode sets which threads are resumed and stopped when the target
+// is continued. Only the linux native backend honors this setting, other
+// backends always use ResumeAll.
+func (p *CommonProcess) SetResumeMode(mode ResumeMode) {
+	p.resumeMode = mode
+}
+
+// ResumeMode returns the mode set with SetResumeMode.
+func (p *CommonProcess) ResumeMode() ResumeMode {
+	return p.resumeMode
+}
+
+// SetSyscallCatchpoints sets the list of syscalls, identified by their
+// number, that stop the target when they are entered or exited. An empty
+// list disables syscall catchpoints. Only the linux native backend honors
+// this setting, the syscall that stopped a thread is available through the
+// Syscall field of CommonThread.
+func (p *CommonProcess) SetSyscallCatchpoints(nums []int) {
+	p.syscallCatch = nil
+	if len(nums) == 0 {
+		return
+	}
+	p.syscallCatch = make(map[int]bool)
+	for _, num := range nums {
+		p.syscallCatch[num] = true
+	}
+}
+
+// CatchingSyscalls returns true if there is at least one syscall
+// catchpoint.
+func (p *CommonProcess) CatchingSyscalls() bool {
+	return len(p.syscallCatch) > 0
+}
+
+// CatchesSyscall returns true if there is a catchpoint on syscall num.
+func (p *CommonProcess) CatchesSyscall(num int) bool {
+	return p.syscallCatch[num]
+}
+
+// SetLogpointFunc sets the function that will be called every time a
+// logpoint is hit.
+func (p *CommonProcess) SetLogpointFunc(fn LogpointFunc) {
+	p.logpointFn = fn
+}
+
+// SetBreakpointActionsFunc sets the function that will be called with the
+// results of the actions of a breakpoint every time they are executed.
+func (p *CommonProcess) SetBreakpointActionsFunc(fn BreakpointActionsFunc) {
+	p.actionsFn = fn
+}
+
+// CheckLogpoint calls the logpoint function if bpstate is an active
+// logpoint and records the stack if bpstate is a stack recording
+// breakpoint, then deactivates bpstate so that Continue will resume
+// execution transparently.
+// Backends must call this after every call to CheckCondition.
+func (p *CommonProcess) CheckLogpoint(thread Thread, bpstate *BreakpointState) {
+	if bpstate.Breakpoint == nil || !bpstate.Active || bpstate.Internal {
+		return
+	}
+	if bpstate.LogMessage == "" && bpstate.RecordStack <= 0 {
+		return
+	}
+	if bpstate.RecordStack > 0 {
+		bpstate.recordStack(thread)
+	}
+	if bpstate.LogMessage != "" && p.logpointFn != nil {
+		p.logpointFn(thread, bpstate.Breakpoint)
+	}
+	bpstate.Active = false
+}
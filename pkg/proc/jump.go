@@ -0,0 +1,84 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/derekparker/delve/pkg/dwarf/frame"
+)
+
+// Jump moves the execution of the selected goroutine to pc, without
+// executing any of the instructions in between. The destination must be
+// in the current function, which can not be inlined, if it is inside the
+// prologue of the function the first instruction after the prologue is
+// used instead.
+// Jumps that would leave the stack in an inconsistent state, because the
+// size of the stack frame at the destination is different from the size
+// of the current stack frame, are refused.
+func Jump(dbp Process, pc uint64) error {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return errors.New("can not jump while nexting")
+	}
+
+	selg := dbp.SelectedGoroutine()
+	thread := dbp.CurrentThread()
+	if selg != nil {
+		if selg.Thread == nil {
+			return fmt.Errorf("can not jump on goroutine %d, it is not running on a thread", selg.ID)
+		}
+		thread = selg.Thread
+	}
+	topframe, _, err := topframe(selg, thread)
+	if err != nil {
+		return err
+	}
+	if topframe.Inlined {
+		return errors.New("can not jump inside an inlined function")
+	}
+	fn := topframe.Current.Fn
+	if fn == nil {
+		return errors.New("could not find current function")
+	}
+	if pc < fn.Entry || pc >= fn.End {
+		return fmt.Errorf("destination %#x is not in the current function %s", pc, fn.Name)
+	}
+	if start, err := FirstPCAfterPrologue(dbp, fn, false); err == nil && pc < start {
+		pc = start
+	}
+
+	bi := dbp.BinInfo()
+	curfde, err := bi.frameEntries.FDEForPC(topframe.Current.PC)
+	if err != nil {
+		return err
+	}
+	dstfde, err := bi.frameEntries.FDEForPC(pc)
+	if err != nil {
+		return err
+	}
+	if curfde != dstfde {
+		return fmt.Errorf("destination %#x is not in the same frame as the current instruction", pc)
+	}
+	if !sameCFARule(bi, curfde, topframe.Current.PC, pc) {
+		return fmt.Errorf("the size of the stack frame at %#x is different from the current one", pc)
+	}
+
+	// The breakpoint state of the thread is left untouched: if the thread
+	// was stopped at a breakpoint it is still stepped over it, at its new
+	// location, when resumed, which is harmless.
+	if err := thread.SetPC(pc); err != nil {
+		return err
+	}
+	dbp.Common().ClearAllGCache()
+	return dbp.SwitchThread(thread.ThreadID())
+}
+
+// sameCFARule returns true if the canonical frame address is computed in
+// the same way at addresses pc1 and pc2, both covered by fde.
+func sameCFARule(bi *BinaryInfo, fde *frame.FrameDescriptionEntry, pc1, pc2 uint64) bool {
+	ctx1 := bi.Arch.FixFrameUnwindContext(fde.EstablishFrame(pc1), pc1, bi)
+	ctx2 := bi.Arch.FixFrameUnwindContext(fde.EstablishFrame(pc2), pc2, bi)
+	return ctx1.CFA.Rule == ctx2.CFA.Rule && ctx1.CFA.Reg == ctx2.CFA.Reg && ctx1.CFA.Offset == ctx2.CFA.Offset
+}
@@ -71,11 +71,61 @@ func cacheMemory(mem MemoryReadWriter, addr uintptr, size int) MemoryReadWriter
 	return &memCache{false, addr, make([]byte, size), mem}
 }
 
+// memSnapshot is a copy of the memory of the target made by
+// snapshotMemory. The copied values are no longer stored at their address
+// in the memory of the target.
+type memSnapshot struct {
+	memCache
+}
+
+// snapshotMemory returns a copy of the size bytes of mem starting at addr,
+// reads outside of the copied range are forwarded to mem.
+func snapshotMemory(mem MemoryReadWriter, addr uintptr, size int) (MemoryReadWriter, error) {
+	if _, isComposite := mem.(*compositeMemory); isComposite || size <= 0 {
+		return mem, nil
+	}
+	snapshot := &memSnapshot{memCache{true, addr, make([]byte, size), mem}}
+	if _, err := mem.ReadMemory(snapshot.cache, addr); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
 // fakeAddress used by extractVarInfoFromEntry for variables that do not
 // have a memory address, we can't use 0 because a lot of code (likely
 // including client code) assumes that addr == 0 is nil
 const fakeAddress = 0xbeef0000
 
+// fakeMemory contains values made up by the expression evaluator, that do
+// not exist in the memory of the target, starting at fakeAddress. Reads
+// outside of them are forwarded to realmem, writes outside of them fail.
+type fakeMemory struct {
+	realmem MemoryReadWriter
+	data    []byte
+}
+
+func newFakeMemory(realmem MemoryReadWriter, size int) *fakeMemory {
+	return &fakeMemory{realmem: realmem, data: make([]byte, size)}
+}
+
+func (mem *fakeMemory) contains(addr uintptr, size int) bool {
+	return addr >= fakeAddress && addr+uintptr(size) <= fakeAddress+uintptr(len(mem.data))
+}
+
+func (mem *fakeMemory) ReadMemory(data []byte, addr uintptr) (int, error) {
+	if !mem.contains(addr, len(data)) {
+		return mem.realmem.ReadMemory(data, addr)
+	}
+	return copy(data, mem.data[addr-fakeAddress:]), nil
+}
+
+func (mem *fakeMemory) WriteMemory(addr uintptr, data []byte) (int, error) {
+	if !mem.contains(addr, len(data)) {
+		return 0, errors.New("can't write outside of values made up by the evaluator")
+	}
+	return copy(mem.data[addr-fakeAddress:], data), nil
+}
+
 // compositeMemory represents a chunk of memory that is stored in CPU
 // registers or non-contiguously.
 //
@@ -0,0 +1,220 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"go/constant"
+	"strings"
+
+	"github.com/derekparker/delve/pkg/dwarf/godwarf"
+	"github.com/derekparker/delve/pkg/goversion"
+)
+
+// Bits of the state word of sync.Mutex.
+const (
+	mutexLocked = 1 << iota
+	mutexWoken
+	mutexStarving // since Go 1.9
+)
+
+// maxMutexHolderDepth is the maximum number of frames of each goroutine
+// searched by MutexInfo for variables referencing the mutex.
+const maxMutexHolderDepth = 20
+
+// MutexState describes a sync.Mutex, see MutexInfo.
+type MutexState struct {
+	// Addr is the address of the mutex.
+	Addr     uint64
+	Locked   bool
+	Woken    bool
+	Starving bool
+	// Waiters is the number of waiters recorded in the state word of the
+	// mutex.
+	Waiters int
+	// Holders contains the goroutines that could be holding the mutex.
+	// The runtime does not record which goroutine locked a mutex, holders
+	// are the goroutines that are not blocked on the mutex and that have,
+	// in one of their frames, a variable that contains the mutex or points
+	// to a value containing it.
+	Holders []*G
+}
+
+// MutexInfo decodes the state of v, which must be a sync.Mutex or a
+// pointer to one, and searches for the goroutines that could be holding
+// it.
+// The holders are guessed by referencesAddr, they are not read from the
+// mutex: a goroutine that merely has a reference to the mutex is reported
+// as a holder and the goroutine that locked it is missing if none of its
+// frames references the mutex.
+func MutexInfo(p Process, v *Variable) (*MutexState, error) {
+	if v.Unreadable != nil {
+		return nil, v.Unreadable
+	}
+	v = v.maybeDereference()
+	if v.Unreadable != nil {
+		return nil, v.Unreadable
+	}
+	if !isMutex(v) {
+		return nil, fmt.Errorf("%s is not a sync.Mutex", v.TypeString())
+	}
+	state, err := mutexState(v)
+	if err != nil {
+		return nil, err
+	}
+	waiterShift := mutexWaiterShift(p.BinInfo())
+	ms := &MutexState{
+		Addr:     uint64(v.Addr),
+		Locked:   state&mutexLocked != 0,
+		Woken:    state&mutexWoken != 0,
+		Starving: waiterShift > 2 && state&mutexStarving != 0,
+		Waiters:  int(uint32(state) >> waiterShift),
+	}
+	if !ms.Locked {
+		return ms, nil
+	}
+
+	waiters, err := Waiters(p, v)
+	if err != nil {
+		return nil, err
+	}
+	waiting := make(map[int]bool)
+	for _, w := range waiters {
+		waiting[w.G.ID] = true
+	}
+	gs, err := GoroutinesInfo(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range gs {
+		if waiting[g.ID] {
+			continue
+		}
+		if referencesAddr(p, g, ms.Addr) {
+			ms.Holders = append(ms.Holders, g)
+		}
+	}
+	return ms, nil
+}
+
+// isMutex returns true if v is a sync.Mutex or an internal/sync.Mutex, the
+// type sync.Mutex is implemented with since Go 1.24.
+func isMutex(v *Variable) bool {
+	t, ok := v.RealType.(*godwarf.StructType)
+	return ok && (t.StructName == "sync.Mutex" || t.StructName == "internal/sync.Mutex")
+}
+
+// mutexField returns the field called name of the mutex m. Since Go 1.24
+// the fields of sync.Mutex are stored in its mu field, an
+// internal/sync.Mutex.
+func mutexField(m *Variable, name string) (*Variable, error) {
+	if mu, err := m.toFieldNamed("mu"); err == nil {
+		m = mu
+	}
+	return m.toFieldNamed(name)
+}
+
+// mutexState returns the state word of the mutex m.
+func mutexState(m *Variable) (int64, error) {
+	sv, err := mutexField(m, "state")
+	if err != nil {
+		return 0, err
+	}
+	sv.loadValue(loadSingleValue)
+	if sv.Unreadable != nil {
+		return 0, sv.Unreadable
+	}
+	state, _ := constant.Int64Val(sv.Value)
+	return state, nil
+}
+
+// mutexWaiterShift returns the position of the waiter count in the state
+// word of sync.Mutex, it moved when the starving bit was added in Go 1.9.
+func mutexWaiterShift(bi *BinaryInfo) uint {
+	if producer := bi.Producer(); producer != "" && !goversion.ProducerAfterOrEqual(producer, 1, 9) {
+		return 2
+	}
+	return 3
+}
+
+// BlockingMutex returns the sync.Mutex g is blocked on, read from the
+// receiver of the call to sync.(*Mutex).Lock in its stack. Since Go 1.24
+// the mutex can only be read from the receiver of the call to
+// internal/sync.(*Mutex).lockSlow if sync.(*Mutex).Lock was inlined.
+func BlockingMutex(p Process, g *G) (*Variable, error) {
+	frames, err := g.Stacktrace(maxMutexHolderDepth, false)
+	if err != nil {
+		return nil, err
+	}
+	var mem MemoryReadWriter = p.CurrentThread()
+	if g.Thread != nil {
+		mem = g.Thread
+	}
+	var fallback *Variable
+	for i := range frames {
+		fn := frames[i].Current.Fn
+		if fn == nil {
+			continue
+		}
+		switch fn.Name {
+		case "sync.(*Mutex).Lock", "sync.(*Mutex).lockSlow":
+			scope := FrameToScope(p.BinInfo(), mem, g, frames[i:]...)
+			if v, err := scope.EvalVariable("m", loadSingleValue); err == nil || fallback == nil {
+				return v, err
+			}
+		case "internal/sync.(*Mutex).Lock", "internal/sync.(*Mutex).lockSlow":
+			if fallback == nil {
+				scope := FrameToScope(p.BinInfo(), mem, g, frames[i:]...)
+				if v, err := scope.EvalVariable("m", loadSingleValue); err == nil {
+					fallback = v
+				}
+			}
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, errors.New("goroutine is not blocked on a mutex")
+}
+
+// referencesAddr returns true if one of the frames of g outside of the
+// runtime and of package sync has a variable that contains addr or points
+// to a value that contains it.
+func referencesAddr(p Process, g *G, addr uint64) bool {
+	frames, err := g.Stacktrace(maxMutexHolderDepth, false)
+	if err != nil {
+		return false
+	}
+	var mem MemoryReadWriter = p.CurrentThread()
+	if g.Thread != nil {
+		mem = g.Thread
+	}
+	ptrSize := int64(p.BinInfo().Arch.PtrSize())
+	contains := func(base uint64, typ godwarf.Type) bool {
+		return base != 0 && typ != nil && addr >= base && addr < base+uint64(typ.Size())
+	}
+	for i := range frames {
+		fn := frames[i].Current.Fn
+		if fn == nil || strings.HasPrefix(fn.Name, "runtime.") || strings.HasPrefix(fn.Name, "sync.") {
+			continue
+		}
+		vars, err := FrameToScope(p.BinInfo(), mem, g, frames[i:]...).Locals()
+		if err != nil {
+			continue
+		}
+		for _, v := range vars {
+			if v.Unreadable != nil {
+				continue
+			}
+			if contains(uint64(v.Addr), v.RealType) {
+				return true
+			}
+			if t, ok := v.RealType.(*godwarf.PtrType); ok {
+				ptr, err := readUintRaw(v.mem, uintptr(v.Addr), ptrSize)
+				if err == nil && contains(ptr, t.Type) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
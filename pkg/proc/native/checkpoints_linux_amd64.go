@@ -0,0 +1,276 @@
+package native
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	sys "golang.org/x/sys/unix"
+
+	"github.com/derekparker/delve/pkg/proc"
+)
+
+// checkpoint is a snapshot of the target process: a copy of the process,
+// created with a clone syscall, that is kept stopped until it is restored
+// or cleared.
+type checkpoint struct {
+	id    int
+	pid   int
+	where string
+	// breakpoints maps the addresses of the software breakpoints that were
+	// written to memory when the checkpoint was created to their original
+	// data.
+	breakpoints map[uint64][]byte
+}
+
+// syscallInstruction is the encoding of the amd64 SYSCALL instruction.
+var syscallInstruction = []byte{0x0f, 0x05}
+
+// Checkpoint creates a copy of the target process that can later be
+// restored with Restart.
+//
+// Only the current thread is copied, the process created by forkStopped
+// has a single thread. The runtime of the copy still has records of the
+// other threads it had started (idle Ms, sysmon, threads blocked in
+// syscalls) and nothing runs on them after the checkpoint is restored:
+// work handed to one of them is never done, sysmon no longer preempts
+// goroutines or polls the network and a lock held by one of them is never
+// released. An error is returned if any thread other than the current one
+// is running a goroutine, but even when the checkpoint is created the
+// restored process can hang or misbehave as soon as the runtime relies on
+// one of the missing threads.
+func (dbp *Process) Checkpoint(where string) (int, error) {
+	if dbp.exited {
+		return -1, &proc.ProcessExitedError{Pid: dbp.Pid()}
+	}
+	var running []string
+	for _, th := range dbp.threads {
+		if th == dbp.currentThread {
+			continue
+		}
+		if g, _ := proc.GetG(th); g != nil {
+			running = append(running, fmt.Sprintf("%d (thread %d)", g.ID, th.ID))
+		}
+	}
+	if len(running) > 0 {
+		sort.Strings(running)
+		return -1, fmt.Errorf("could not create checkpoint: only the current thread is copied but goroutines %s are running on other threads", strings.Join(running, ", "))
+	}
+	cp := &checkpoint{where: where, breakpoints: make(map[uint64][]byte)}
+	for addr, bp := range dbp.breakpoints.M {
+		if bp.WatchType == 0 && !bp.Disarmed() {
+			cp.breakpoints[addr] = bp.OriginalData
+		}
+	}
+	var err error
+	dbp.execPtraceFunc(func() { cp.pid, err = forkStopped(dbp.currentThread.ID) })
+	if err != nil {
+		return -1, fmt.Errorf("could not create checkpoint: %v", err)
+	}
+	dbp.os.lastCheckpointID++
+	cp.id = dbp.os.lastCheckpointID
+	dbp.os.checkpoints = append(dbp.os.checkpoints, cp)
+	return cp.id, nil
+}
+
+// Checkpoints returns the list of checkpoints created with Checkpoint.
+func (dbp *Process) Checkpoints() ([]proc.Checkpoint, error) {
+	r := make([]proc.Checkpoint, len(dbp.os.checkpoints))
+	for i, cp := range dbp.os.checkpoints {
+		r[i] = proc.Checkpoint{ID: cp.id, Where: cp.where}
+	}
+	return r, nil
+}
+
+// ClearCheckpoint kills the copy of the process backing checkpoint id.
+func (dbp *Process) ClearCheckpoint(id int) error {
+	for i, cp := range dbp.os.checkpoints {
+		if cp.id == id {
+			killStopped(cp.pid)
+			dbp.os.checkpoints = append(dbp.os.checkpoints[:i], dbp.os.checkpoints[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("checkpoint c%d does not exist", id)
+}
+
+// killCheckpoints kills all checkpoints, it must be called before we stop
+// tracing them, otherwise they would be resumed.
+func (dbp *Process) killCheckpoints() {
+	for _, cp := range dbp.os.checkpoints {
+		killStopped(cp.pid)
+	}
+	dbp.os.checkpoints = nil
+}
+
+// Restart replaces the target process with a copy of the checkpoint
+// specified by pos, which must be a checkpoint ID prefixed by 'c'. The
+// checkpoint itself is left untouched, it can be restored again.
+func (dbp *Process) Restart(pos string) error {
+	if !strings.HasPrefix(pos, "c") {
+		return proc.NotRecordedErr
+	}
+	id, err := strconv.Atoi(pos[1:])
+	if err != nil {
+		return fmt.Errorf("invalid checkpoint %q", pos)
+	}
+	var cp *checkpoint
+	for i := range dbp.os.checkpoints {
+		if dbp.os.checkpoints[i].id == id {
+			cp = dbp.os.checkpoints[i]
+		}
+	}
+	if cp == nil {
+		return fmt.Errorf("checkpoint %s does not exist", pos)
+	}
+	if dbp.exited {
+		return &proc.ProcessExitedError{Pid: dbp.Pid()}
+	}
+	if err := dbp.stopRunningThreads(); err != nil {
+		return err
+	}
+
+	var pid int
+	dbp.execPtraceFunc(func() { pid, err = forkStopped(cp.pid) })
+	if err != nil {
+		return fmt.Errorf("could not restore checkpoint: %v", err)
+	}
+
+	// kill the current process, without killing the rest of its process
+	// group, which contains the checkpoints.
+	sys.Kill(dbp.pid, sys.SIGKILL)
+	for tid := range dbp.threads {
+		if tid != dbp.pid {
+			dbp.waitFast(tid)
+		}
+	}
+	dbp.waitFast(dbp.pid)
+
+	dbp.pid = pid
+	dbp.threads = make(map[int]*Thread)
+	dbp.currentThread = nil
+	dbp.selectedGoroutine = nil
+	dbp.common.ClearAllGCache()
+	if _, err := dbp.addThread(pid, false); err != nil {
+		return err
+	}
+	// read the threads of the copy instead of assuming that it only
+	// contains the thread the checkpoint was created from.
+	if err := dbp.updateThreadList(); err != nil {
+		return err
+	}
+
+	// The memory of the checkpoint contains the breakpoints that existed
+	// when it was created, make it match the current breakpoints.
+	for addr, data := range cp.breakpoints {
+		if bp, ok := dbp.breakpoints.M[addr]; ok && bp.WatchType == 0 && !bp.Disarmed() {
+			continue
+		}
+		if _, err := dbp.currentThread.WriteMemory(uintptr(addr), data); err != nil {
+			return err
+		}
+	}
+	for addr, bp := range dbp.breakpoints.M {
+		if _, ok := cp.breakpoints[addr]; ok || bp.WatchType != 0 || bp.Disarmed() {
+			continue
+		}
+		if err := dbp.writeSoftwareBreakpoint(dbp.currentThread, addr); err != nil {
+			return err
+		}
+	}
+
+	if err := dbp.currentThread.SetCurrentBreakpoint(); err != nil {
+		return err
+	}
+	dbp.selectedGoroutine, _ = proc.GetG(dbp.currentThread)
+	return nil
+}
+
+// forkStopped creates a copy of the process of thread tid, which must be
+// in a ptrace stop, by making tid execute a clone syscall with the
+// CLONE_PTRACE flag. The registers and memory of tid are restored after
+// the syscall and the new process is left stopped, with the same registers
+// as tid. The new process only contains a copy of tid, its other threads
+// are not copied.
+// Must be called from the ptrace goroutine.
+func forkStopped(tid int) (int, error) {
+	var regs sys.PtraceRegs
+	if err := sys.PtraceGetRegs(tid, &regs); err != nil {
+		return 0, err
+	}
+	pc := uintptr(regs.Rip)
+	orig := make([]byte, len(syscallInstruction))
+	if _, err := sys.PtracePeekData(tid, pc, orig); err != nil {
+		return 0, err
+	}
+	restore := func(pid int) error {
+		if _, err := sys.PtracePokeData(pid, pc, orig); err != nil {
+			return err
+		}
+		return sys.PtraceSetRegs(pid, &regs)
+	}
+
+	cloneRegs := regs
+	cloneRegs.Rax = sys.SYS_CLONE
+	// orig_rax is set to -1 so that the kernel does not try to restart the
+	// syscall tid was stopped in, if any.
+	cloneRegs.Orig_rax = ^uint64(0)
+	cloneRegs.Rdi = sys.CLONE_PTRACE | uint64(sys.SIGCHLD)
+	cloneRegs.Rsi, cloneRegs.Rdx, cloneRegs.R10, cloneRegs.R8 = 0, 0, 0, 0
+	if _, err := sys.PtracePokeData(tid, pc, syscallInstruction); err != nil {
+		return 0, err
+	}
+	if err := sys.PtraceSetRegs(tid, &cloneRegs); err != nil {
+		restore(tid)
+		return 0, err
+	}
+	for {
+		if err := sys.PtraceSingleStep(tid); err != nil {
+			restore(tid)
+			return 0, err
+		}
+		var status sys.WaitStatus
+		if _, err := sys.Wait4(tid, &status, sys.WALL, nil); err != nil {
+			return 0, err
+		}
+		if status.Exited() || status.Signaled() {
+			return 0, errors.New("thread exited")
+		}
+		if status.StopSignal() == sys.SIGTRAP {
+			break
+		}
+	}
+	var after sys.PtraceRegs
+	err := sys.PtraceGetRegs(tid, &after)
+	if err == nil {
+		err = restore(tid)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	pid := int(int64(after.Rax))
+	if pid < 0 {
+		return 0, fmt.Errorf("clone failed: %v", syscall.Errno(-pid))
+	}
+	// the new process starts with a SIGSTOP because it is traced
+	var status sys.WaitStatus
+	if _, err := sys.Wait4(pid, &status, sys.WALL, nil); err != nil {
+		return 0, err
+	}
+	if err := restore(pid); err != nil {
+		killStopped(pid)
+		return 0, err
+	}
+	return pid, nil
+}
+
+// killStopped kills the traced process pid and waits for it to exit.
+func killStopped(pid int) {
+	sys.Kill(pid, sys.SIGKILL)
+	var status sys.WaitStatus
+	sys.Wait4(pid, &status, sys.WALL, nil)
+}
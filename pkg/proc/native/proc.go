@@ -38,6 +38,9 @@ type Process struct {
 	ptraceDoneChan      chan interface{}
 	childProcess        bool // this process was launched, not attached to
 	manualStopRequested bool
+	// running is true while ContinueOnce has resumed the target and is
+	// waiting for it to stop, protected by stopMu.
+	running bool
 
 	exited, detached bool
 }
@@ -65,13 +68,9 @@ func (dbp *Process) BinInfo() *proc.BinaryInfo {
 	return &dbp.bi
 }
 
-func (dbp *Process) Recorded() (bool, string)                { return false, "" }
-func (dbp *Process) Restart(string) error                    { return proc.NotRecordedErr }
-func (dbp *Process) Direction(proc.Direction) error          { return proc.NotRecordedErr }
-func (dbp *Process) When() (string, error)                   { return "", nil }
-func (dbp *Process) Checkpoint(string) (int, error)          { return -1, proc.NotRecordedErr }
-func (dbp *Process) Checkpoints() ([]proc.Checkpoint, error) { return nil, proc.NotRecordedErr }
-func (dbp *Process) ClearCheckpoint(int) error               { return proc.NotRecordedErr }
+func (dbp *Process) Recorded() (bool, string)       { return false, "" }
+func (dbp *Process) Direction(proc.Direction) error { return proc.NotRecordedErr }
+func (dbp *Process) When() (string, error)          { return "", nil }
 
 // Detach from the process being debugged, optionally killing it.
 func (dbp *Process) Detach(kill bool) (err error) {
@@ -87,6 +86,9 @@ func (dbp *Process) Detach(kill bool) (err error) {
 		return nil
 	}
 	if !kill {
+		if err := dbp.stopRunningThreads(); err != nil {
+			return err
+		}
 		// Clean up any breakpoints we've set.
 		for _, bp := range dbp.breakpoints.M {
 			if bp != nil {
@@ -175,8 +177,13 @@ func (dbp *Process) LoadInformation(path string) error {
 	return err
 }
 
-// RequestManualStop sets the `halt` flag and
-// sends SIGSTOP to all threads.
+// RequestManualStop sets the `halt` flag and, if the target is running,
+// interrupts it. It is safe to call RequestManualStop concurrently with
+// ContinueOnce: if the target is not running the request is recorded, the
+// next call to Continue returns without resuming the target, or
+// ContinueOnce interrupts the target as soon as it is resumed if the
+// request arrives after Continue checked it, and the stop is reported as a
+// manual stop.
 func (dbp *Process) RequestManualStop() error {
 	if dbp.exited {
 		return &proc.ProcessExitedError{Pid: dbp.Pid()}
@@ -184,6 +191,9 @@ func (dbp *Process) RequestManualStop() error {
 	dbp.stopMu.Lock()
 	defer dbp.stopMu.Unlock()
 	dbp.manualStopRequested = true
+	if !dbp.running {
+		return nil
+	}
 	return dbp.requestManualStop()
 }
 
@@ -196,10 +206,9 @@ func (dbp *Process) CheckAndClearManualStopRequest() bool {
 }
 
 func (dbp *Process) writeBreakpoint(addr uint64) (string, int, *proc.Function, []byte, error) {
+	// fn can be nil here, breakpoints can be set on addresses without
+	// symbols.
 	f, l, fn := dbp.bi.PCToLine(uint64(addr))
-	if fn == nil {
-		return "", 0, nil, nil, proc.InvalidAddressError{Address: addr}
-	}
 
 	originalData := make([]byte, dbp.bi.Arch.BreakpointSize())
 	_, err := dbp.currentThread.ReadMemory(originalData, uintptr(addr))
@@ -219,12 +228,61 @@ func (dbp *Process) SetBreakpoint(addr uint64, kind proc.BreakpointKind, cond as
 	return dbp.breakpoints.Set(addr, kind, cond, dbp.writeBreakpoint)
 }
 
+// SetWatchpoint sets a hardware watchpoint on the size bytes of memory
+// starting at addr, on all threads of the process.
+func (dbp *Process) SetWatchpoint(addr uint64, size int, wtype proc.WatchType, cond ast.Expr) (*proc.Breakpoint, error) {
+	if dbp.exited {
+		return nil, &proc.ProcessExitedError{Pid: dbp.Pid()}
+	}
+	return dbp.breakpoints.SetWatchpoint(addr, size, wtype, cond, dbp.writeWatchpoint)
+}
+
+func (dbp *Process) writeWatchpoint(bp *proc.Breakpoint) error {
+	for _, thread := range dbp.threads {
+		if err := thread.writeHardwareBreakpoint(bp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ClearBreakpoint clears the breakpoint at addr.
 func (dbp *Process) ClearBreakpoint(addr uint64) (*proc.Breakpoint, error) {
 	if dbp.exited {
 		return nil, &proc.ProcessExitedError{Pid: dbp.Pid()}
 	}
-	return dbp.breakpoints.Clear(addr, dbp.currentThread.ClearBreakpoint)
+	return dbp.breakpoints.Clear(addr, dbp.clearBreakpoint)
+}
+
+// ClearBreakpointKind removes kind from the breakpoint at addr.
+func (dbp *Process) ClearBreakpointKind(addr uint64, kind proc.BreakpointKind) (*proc.Breakpoint, error) {
+	if dbp.exited {
+		return nil, &proc.ProcessExitedError{Pid: dbp.Pid()}
+	}
+	return dbp.breakpoints.ClearKind(addr, kind, dbp.clearBreakpoint)
+}
+
+// SetBreakpointsDisabled disables or enables the user breakpoints in bps.
+func (dbp *Process) SetBreakpointsDisabled(bps []*proc.Breakpoint, disabled bool) error {
+	if dbp.exited {
+		return &proc.ProcessExitedError{Pid: dbp.Pid()}
+	}
+	return dbp.breakpoints.SetDisabled(bps, disabled, dbp.writeBreakpoint, dbp.clearBreakpoint)
+}
+
+func (dbp *Process) clearBreakpoint(bp *proc.Breakpoint) error {
+	if bp.WatchType == 0 {
+		return dbp.currentThread.ClearBreakpoint(bp)
+	}
+	for _, thread := range dbp.threads {
+		if err := thread.clearHardwareBreakpoint(bp); err != nil {
+			return err
+		}
+		if thread.CurrentBreakpoint.Breakpoint == bp {
+			thread.CurrentBreakpoint.Clear()
+		}
+	}
+	return nil
 }
 
 func (dbp *Process) ContinueOnce() (proc.Thread, error) {
@@ -232,9 +290,25 @@ func (dbp *Process) ContinueOnce() (proc.Thread, error) {
 		return nil, &proc.ProcessExitedError{Pid: dbp.Pid()}
 	}
 
+	dbp.stopMu.Lock()
+	dbp.running = true
+	pendingStop := dbp.manualStopRequested
+	dbp.stopMu.Unlock()
+	defer func() {
+		dbp.stopMu.Lock()
+		dbp.running = false
+		dbp.stopMu.Unlock()
+	}()
+
 	if err := dbp.resume(); err != nil {
 		return nil, err
 	}
+	if pendingStop {
+		// RequestManualStop was called while the target was stopped.
+		if err := dbp.requestManualStop(); err != nil {
+			return nil, err
+		}
+	}
 
 	dbp.common.ClearAllGCache()
 	for _, th := range dbp.threads {
@@ -328,11 +402,11 @@ func (dbp *Process) SwitchGoroutine(gid int) error {
 // FindBreakpoint finds the breakpoint for the given pc.
 func (dbp *Process) FindBreakpoint(pc uint64) (*proc.Breakpoint, bool) {
 	// Check to see if address is past the breakpoint, (i.e. breakpoint was hit).
-	if bp, ok := dbp.breakpoints.M[pc-uint64(dbp.bi.Arch.BreakpointSize())]; ok {
+	if bp, ok := dbp.breakpoints.M[pc-uint64(dbp.bi.Arch.BreakpointSize())]; ok && bp.WatchType == 0 && !bp.Disarmed() {
 		return bp, true
 	}
 	// Directly use addr to lookup breakpoint.
-	if bp, ok := dbp.breakpoints.M[pc]; ok {
+	if bp, ok := dbp.breakpoints.M[pc]; ok && bp.WatchType == 0 && !bp.Disarmed() {
 		return bp, true
 	}
 	return nil, false
@@ -393,6 +467,7 @@ func (dbp *Process) execPtraceFunc(fn func()) {
 
 func (dbp *Process) postExit() {
 	dbp.exited = true
+	dbp.killCheckpoints()
 	close(dbp.ptraceChan)
 	close(dbp.ptraceDoneChan)
 	dbp.bi.Close()
@@ -458,6 +458,16 @@ func (dbp *Process) stop(trapthread *Thread) (err error) {
 	return nil
 }
 
+func (dbp *Process) stopRunningThreads() error {
+	return nil
+}
+
 func (dbp *Process) detach(kill bool) error {
 	return PtraceDetach(dbp.pid, 0)
 }
+
+func (dbp *Process) Restart(string) error                    { return proc.NotRecordedErr }
+func (dbp *Process) Checkpoint(string) (int, error)          { return -1, proc.NotRecordedErr }
+func (dbp *Process) Checkpoints() ([]proc.Checkpoint, error) { return nil, proc.NotRecordedErr }
+func (dbp *Process) ClearCheckpoint(int) error               { return proc.NotRecordedErr }
+func (dbp *Process) killCheckpoints()                        {}
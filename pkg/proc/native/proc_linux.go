@@ -40,6 +40,9 @@ const (
 // process details.
 type OSProcessDetails struct {
 	comm string
+	// checkpoints created with Checkpoint, see checkpoints_linux.go.
+	checkpoints      []*checkpoint
+	lastCheckpointID int
 }
 
 // Launch creates and begins debugging a new process. First entry in
@@ -161,12 +164,12 @@ func (dbp *Process) addThread(tid int, attach bool) (*Thread, error) {
 		}
 	}
 
-	dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, syscall.PTRACE_O_TRACECLONE) })
+	dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, syscall.PTRACE_O_TRACECLONE|syscall.PTRACE_O_TRACESYSGOOD) })
 	if err == syscall.ESRCH {
 		if _, _, err = dbp.waitFast(tid); err != nil {
 			return nil, fmt.Errorf("error while waiting after adding thread: %d %s", tid, err)
 		}
-		dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, syscall.PTRACE_O_TRACECLONE) })
+		dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, syscall.PTRACE_O_TRACECLONE|syscall.PTRACE_O_TRACESYSGOOD) })
 		if err == syscall.ESRCH {
 			return nil, err
 		}
@@ -180,6 +183,15 @@ func (dbp *Process) addThread(tid int, attach bool) (*Thread, error) {
 		dbp: dbp,
 		os:  new(OSSpecificDetails),
 	}
+	// Debug registers are not inherited by new threads, copy all
+	// watchpoints to them.
+	for _, bp := range dbp.breakpoints.M {
+		if bp.WatchType != 0 {
+			if err := dbp.threads[tid].writeHardwareBreakpoint(bp); err != nil {
+				return nil, err
+			}
+		}
+	}
 	if dbp.currentThread == nil {
 		dbp.SwitchThread(tid)
 	}
@@ -278,13 +290,43 @@ func (dbp *Process) trapWaitInternal(pid int, halt bool) (*Thread, error) {
 			// Sometimes we get an unknown thread, ignore it?
 			continue
 		}
+		if status.StopSignal() == sys.SIGTRAP|0x80 {
+			// syscall entry or exit stop, see PTRACE_O_TRACESYSGOOD
+			if !halt {
+				if ev, err := th.syscallEvent(); err == nil && dbp.common.CatchesSyscall(ev.Num) {
+					th.common.Syscall = ev
+					th.os.running = false
+					return th, nil
+				}
+			}
+			if err := th.resumeWithSig(0); err != nil {
+				if err == sys.ESRCH {
+					return nil, proc.ProcessExitedError{Pid: dbp.pid}
+				}
+				return nil, err
+			}
+			continue
+		}
 		if (halt && status.StopSignal() == sys.SIGSTOP) || (status.StopSignal() == sys.SIGTRAP) {
 			th.os.running = false
 			return th, nil
 		}
 		if th != nil {
-			// TODO(dp) alert user about unexpected signals here.
-			if err := th.resumeWithSig(int(status.StopSignal())); err != nil {
+			sig := int(status.StopSignal())
+			switch dbp.common.SignalPolicy(sig) {
+			case proc.SignalStop:
+				th.common.Signal = sig
+				if !halt {
+					th.os.running = false
+					return th, nil
+				}
+				// we are already stopping, the signal will be delivered when
+				// the thread is resumed.
+				sig = 0
+			case proc.SignalIgnore:
+				sig = 0
+			}
+			if err := th.resumeWithSig(sig); err != nil {
 				if err == sys.ESRCH {
 					return nil, proc.ProcessExitedError{Pid: dbp.pid}
 				}
@@ -397,8 +439,20 @@ func (dbp *Process) exitGuard(err error) error {
 }
 
 func (dbp *Process) resume() error {
-	// all threads stopped over a breakpoint are made to step over it
+	// with ResumeSelected only the thread of the selected goroutine runs.
+	var only *Thread
+	if dbp.common.ResumeMode() == proc.ResumeSelected {
+		only = dbp.currentThread
+		if dbp.selectedGoroutine != nil && dbp.selectedGoroutine.Thread != nil {
+			only = dbp.selectedGoroutine.Thread.(*Thread)
+		}
+	}
+	// all threads stopped over a breakpoint are made to step over it, threads
+	// held by frozen goroutines stay stopped.
 	for _, thread := range dbp.threads {
+		if (only != nil && thread != only) || thread.common.Held() {
+			continue
+		}
 		if thread.CurrentBreakpoint.Breakpoint != nil {
 			if err := thread.StepInstruction(); err != nil {
 				return err
@@ -408,6 +462,15 @@ func (dbp *Process) resume() error {
 	}
 	// everything is resumed
 	for _, thread := range dbp.threads {
+		if (only != nil && thread != only) || thread.common.Held() {
+			continue
+		}
+		if thread.os.running {
+			// This thread was left running by a breakpoint with the
+			// SuspendThread policy, if it stopped in the meantime trapWait will
+			// collect its status.
+			continue
+		}
 		if err := thread.resume(); err != nil && err != sys.ESRCH {
 			return err
 		}
@@ -420,6 +483,18 @@ func (dbp *Process) stop(trapthread *Thread) (err error) {
 	if dbp.exited {
 		return &proc.ProcessExitedError{Pid: dbp.Pid()}
 	}
+
+	if trapthread != nil {
+		// If trapthread hit a breakpoint with the SuspendThread policy, or we
+		// are in non-stop mode, leave all other threads running.
+		if err := trapthread.SetCurrentBreakpoint(); err != nil {
+			return err
+		}
+		if bpstate := trapthread.CurrentBreakpoint; bpstate.Breakpoint != nil && bpstate.Active && !bpstate.Internal && (bpstate.Suspend == proc.SuspendThread || dbp.common.ResumeMode() == proc.ResumeNonStop) {
+			return nil
+		}
+	}
+
 	for _, th := range dbp.threads {
 		if !th.Stopped() {
 			if err := th.stop(); err != nil {
@@ -457,7 +532,19 @@ func (dbp *Process) stop(trapthread *Thread) (err error) {
 	return nil
 }
 
+// stopRunningThreads stops all threads that were left running by a
+// breakpoint with the SuspendThread policy, or by non-stop mode.
+func (dbp *Process) stopRunningThreads() error {
+	for _, th := range dbp.threads {
+		if th.os.running {
+			return dbp.stop(nil)
+		}
+	}
+	return nil
+}
+
 func (dbp *Process) detach(kill bool) error {
+	dbp.killCheckpoints()
 	for threadID := range dbp.threads {
 		err := PtraceDetach(threadID, 0)
 		if err != nil {
@@ -467,6 +467,10 @@ func (dbp *Process) stop(trapthread *Thread) (err error) {
 	return nil
 }
 
+func (dbp *Process) stopRunningThreads() error {
+	return nil
+}
+
 func (dbp *Process) detach(kill bool) error {
 	if !kill {
 		for _, thread := range dbp.threads {
@@ -488,3 +492,9 @@ func killProcess(pid int) error {
 
 	return p.Kill()
 }
+
+func (dbp *Process) Restart(string) error                    { return proc.NotRecordedErr }
+func (dbp *Process) Checkpoint(string) (int, error)          { return -1, proc.NotRecordedErr }
+func (dbp *Process) Checkpoints() ([]proc.Checkpoint, error) { return nil, proc.NotRecordedErr }
+func (dbp *Process) ClearCheckpoint(int) error               { return proc.NotRecordedErr }
+func (dbp *Process) killCheckpoints()                        {}
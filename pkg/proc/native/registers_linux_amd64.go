@@ -293,6 +293,33 @@ func registers(thread *Thread, floatingPoint bool) (proc.Registers, error) {
 	return r, nil
 }
 
+// syscallEvent returns the syscall that thread is entering or exiting,
+// thread must be stopped at a syscall stop.
+func (thread *Thread) syscallEvent() (*proc.SyscallEvent, error) {
+	var (
+		regs sys.PtraceRegs
+		err  error
+	)
+	thread.dbp.execPtraceFunc(func() { err = sys.PtraceGetRegs(thread.ID, &regs) })
+	if err != nil {
+		return nil, err
+	}
+	ev := &proc.SyscallEvent{
+		Num:  int(regs.Orig_rax),
+		Args: [6]uint64{regs.Rdi, regs.Rsi, regs.Rdx, regs.R10, regs.R8, regs.R9},
+	}
+	ev.Name = proc.SyscallName(ev.Num)
+	// on syscall entry the kernel sets rax to -ENOSYS
+	if int64(regs.Rax) != -int64(sys.ENOSYS) {
+		ev.Exit = true
+		ev.Ret = int64(regs.Rax)
+	}
+	if i := proc.SyscallPathArg(ev.Num); i >= 0 {
+		ev.Path = proc.ReadSyscallPath(thread, ev.Args[i])
+	}
+	return ev, nil
+}
+
 const (
 	_X86_XSTATE_MAX_SIZE = 2688
 	_NT_X86_XSTATE       = 0x202
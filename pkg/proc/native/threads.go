@@ -73,6 +73,19 @@ func (thread *Thread) StepInstruction() (err error) {
 		}()
 	}
 
+	if hwbp, ok := thread.dbp.breakpoints.M[pc]; ok && hwbp.WatchType.Execute() {
+		// Disable the hardware breakpoint on this thread, otherwise it would
+		// trigger again before the instruction is executed.
+		if err := thread.clearHardwareBreakpoint(hwbp); err != nil {
+			return err
+		}
+		defer func() {
+			if err1 := thread.writeHardwareBreakpoint(hwbp); err == nil {
+				err = err1
+			}
+		}()
+	}
+
 	err = thread.singleStep()
 	if err != nil {
 		if _, exited := err.(proc.ProcessExitedError); exited {
@@ -111,6 +124,16 @@ func (thread *Thread) Common() *proc.CommonThread {
 // thread is stopped at as CurrentBreakpoint on the thread struct.
 func (thread *Thread) SetCurrentBreakpoint() error {
 	thread.CurrentBreakpoint.Clear()
+	wp, err := thread.findHardwareBreakpoint()
+	if err != nil {
+		return err
+	}
+	if wp != nil {
+		thread.CurrentBreakpoint = wp.CheckCondition(thread)
+		thread.CurrentBreakpoint.WatchAddr = wp.Addr
+		thread.dbp.common.CheckLogpoint(thread, &thread.CurrentBreakpoint)
+		return nil
+	}
 	pc, err := thread.PC()
 	if err != nil {
 		return err
@@ -120,12 +143,8 @@ func (thread *Thread) SetCurrentBreakpoint() error {
 			return err
 		}
 		thread.CurrentBreakpoint = bp.CheckCondition(thread)
-		if thread.CurrentBreakpoint.Breakpoint != nil && thread.CurrentBreakpoint.Active {
-			if g, err := proc.GetG(thread); err == nil {
-				thread.CurrentBreakpoint.HitCount[g.ID]++
-			}
-			thread.CurrentBreakpoint.TotalHitCount++
-		}
+		thread.dbp.common.CheckFreezeBreakpoint(thread, &thread.CurrentBreakpoint)
+		thread.dbp.common.CheckLogpoint(thread, &thread.CurrentBreakpoint)
 	}
 	return nil
 }
@@ -36,11 +36,19 @@ func (t *Thread) Stopped() bool {
 }
 
 func (t *Thread) resume() error {
-	return t.resumeWithSig(0)
+	// deliver the signal that stopped the thread, if any
+	sig := t.common.Signal
+	t.common.Signal = 0
+	return t.resumeWithSig(sig)
 }
 
 func (t *Thread) resumeWithSig(sig int) (err error) {
 	t.os.running = true
+	t.common.Syscall = nil
+	if t.dbp.common.CatchingSyscalls() {
+		t.dbp.execPtraceFunc(func() { err = sys.PtraceSyscall(t.ID, sig) })
+		return
+	}
 	t.dbp.execPtraceFunc(func() { err = PtraceCont(t.ID, sig) })
 	return
 }
@@ -128,3 +136,116 @@ func (t *Thread) ReadMemory(data []byte, addr uintptr) (n int, err error) {
 	}
 	return
 }
+
+// debugRegOffset is the offset of the debug registers (u_debugreg) inside
+// the user struct, see sys/user.h.
+const debugRegOffset = 848
+
+const (
+	dr6Status     = 6
+	dr7Control    = 7
+	dr7EnableBits = 2 // enable bits for each debug register
+	dr7CtrlStart  = 16
+	dr7CtrlBits   = 4 // R/W and LEN bits for each debug register
+)
+
+func (t *Thread) peekDebugReg(n int) (val uintptr, err error) {
+	t.dbp.execPtraceFunc(func() { val, err = PtracePeekUser(t.ID, uintptr(debugRegOffset+n*8)) })
+	return
+}
+
+func (t *Thread) pokeDebugReg(n int, val uintptr) (err error) {
+	t.dbp.execPtraceFunc(func() { err = PtracePokeUser(t.ID, uintptr(debugRegOffset+n*8), val) })
+	return
+}
+
+// writeHardwareBreakpoint programs debug register bp.HWBreakIndex of this
+// thread to watch the memory described by bp. See Intel 64 and IA-32
+// Architectures Software Developer's Manual, Volume 3B, section 17.2.
+func (t *Thread) writeHardwareBreakpoint(bp *proc.Breakpoint) error {
+	idx := uint(bp.HWBreakIndex)
+	if idx >= proc.MaxHWWatchpoints {
+		return proc.ErrTooManyWatchpoints
+	}
+
+	var rw uintptr
+	switch {
+	case bp.WatchType.Execute():
+		rw = 0x0 // break on instruction execution
+	case bp.WatchType.Read():
+		rw = 0x3 // break on data reads or writes
+	case bp.WatchType.Write():
+		rw = 0x1 // break on data writes only
+	}
+
+	var ln uintptr
+	switch bp.WatchSize {
+	case 1: // also the only valid length for instruction breakpoints
+		ln = 0x0
+	case 2:
+		ln = 0x1
+	case 4:
+		ln = 0x3
+	case 8:
+		ln = 0x2
+	default:
+		return fmt.Errorf("invalid watchpoint size %d", bp.WatchSize)
+	}
+
+	dr7, err := t.peekDebugReg(dr7Control)
+	if err != nil {
+		return err
+	}
+	if err := t.pokeDebugReg(int(idx), uintptr(bp.Addr)); err != nil {
+		return fmt.Errorf("could not set hardware watchpoint: %v", err)
+	}
+	ctrlShift := dr7CtrlStart + idx*dr7CtrlBits
+	dr7 &^= 0xf << ctrlShift
+	dr7 |= (rw | ln<<2) << ctrlShift
+	dr7 |= 1 << (idx * dr7EnableBits) // local enable
+	if err := t.pokeDebugReg(dr7Control, dr7); err != nil {
+		return fmt.Errorf("could not set hardware watchpoint: %v", err)
+	}
+	return nil
+}
+
+// clearHardwareBreakpoint disables debug register bp.HWBreakIndex of this
+// thread.
+func (t *Thread) clearHardwareBreakpoint(bp *proc.Breakpoint) error {
+	idx := uint(bp.HWBreakIndex)
+	dr7, err := t.peekDebugReg(dr7Control)
+	if err != nil {
+		return err
+	}
+	dr7 &^= 0x3 << (idx * dr7EnableBits)
+	dr7 &^= 0xf << (dr7CtrlStart + idx*dr7CtrlBits)
+	if err := t.pokeDebugReg(dr7Control, dr7); err != nil {
+		return fmt.Errorf("could not clear hardware watchpoint: %v", err)
+	}
+	return t.pokeDebugReg(int(idx), 0)
+}
+
+// findHardwareBreakpoint returns the watchpoint that caused this thread
+// to stop, if any, and resets the debug status register.
+func (t *Thread) findHardwareBreakpoint() (*proc.Breakpoint, error) {
+	dr6, err := t.peekDebugReg(dr6Status)
+	if err != nil {
+		return nil, err
+	}
+	if dr6&0xf == 0 {
+		return nil, nil
+	}
+	// The processor never clears DR6, we have to do it.
+	if err := t.pokeDebugReg(dr6Status, 0); err != nil {
+		return nil, err
+	}
+	for idx := uint8(0); idx < proc.MaxHWWatchpoints; idx++ {
+		if dr6&(1<<idx) == 0 {
+			continue
+		}
+		if bp, ok := t.dbp.breakpoints.FindWatchpoint(idx); ok {
+			return bp, nil
+		}
+	}
+	return nil, nil
+}
@@ -153,3 +153,15 @@ func (t *Thread) ReadMemory(buf []byte, addr uintptr) (int, error) {
 func (t *Thread) restoreRegisters(sr *savedRegisters) error {
 	return errors.New("not implemented")
 }
+
+func (t *Thread) writeHardwareBreakpoint(bp *proc.Breakpoint) error {
+	return proc.ErrWatchpointsUnsupportedBackend
+}
+
+func (t *Thread) clearHardwareBreakpoint(bp *proc.Breakpoint) error {
+	return proc.ErrWatchpointsUnsupportedBackend
+}
+
+func (t *Thread) findHardwareBreakpoint() (*proc.Breakpoint, error) {
+	return nil, nil
+}
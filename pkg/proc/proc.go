@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/constant"
+	"go/parser"
 	"go/token"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -14,7 +17,14 @@ import (
 var NotExecutableErr = errors.New("not an executable file")
 var NotRecordedErr = errors.New("not a recording")
 
-const UnrecoveredPanic = "unrecovered-panic"
+const (
+	// UnrecoveredPanic is the name of the breakpoint set on the runtime
+	// function handling unrecovered panics.
+	UnrecoveredPanic = "unrecovered-panic"
+	// FatalThrow is the name of the breakpoint set on runtime.throw, which
+	// is called for fatal runtime errors.
+	FatalThrow = "fatal-throw"
+)
 
 // ProcessExitedError indicates that the process has exited and contains both
 // process id and exit status.
@@ -48,6 +58,19 @@ func FindFileLocation(p Process, fileName string, lineno int) (uint64, error) {
 	return pc, nil
 }
 
+// FindFileLocationColumn returns the PC for a given file:line:column.
+// Assumes that `file` is normalized to lower case and '/' on Windows.
+func FindFileLocationColumn(p Process, fileName string, lineno, column int) (uint64, error) {
+	pc, fn, err := p.BinInfo().LineColumnToPC(fileName, lineno, column)
+	if err != nil {
+		return 0, err
+	}
+	if fn.Entry == pc {
+		pc, _ = FirstPCAfterPrologue(p, fn, true)
+	}
+	return pc, nil
+}
+
 type FunctionNotFoundError struct {
 	FuncName string
 }
@@ -56,6 +79,23 @@ func (err *FunctionNotFoundError) Error() string {
 	return fmt.Sprintf("Could not find function %s\n", err.FuncName)
 }
 
+// HasDynamicModules returns true if p loaded Go modules other than the main
+// executable, either plugins opened with plugin.Open or the shared
+// libraries of a program built with -linkshared. Only the debug
+// information of the main executable is read, functions and source files
+// of the other modules can not be found.
+func HasDynamicModules(p Process) bool {
+	scope, err := ThreadScope(p.CurrentThread())
+	if err != nil {
+		return false
+	}
+	v, err := scope.EvalExpression("runtime.firstmoduledata.next != nil", loadSingleValue)
+	if err != nil || v.Unreadable != nil || v.Value == nil || v.Value.Kind() != constant.Bool {
+		return false
+	}
+	return constant.BoolVal(v.Value)
+}
+
 // FindFunctionLocation finds address of a function's line
 // If firstLine == true is passed FindFunctionLocation will attempt to find the first line of the function
 // If lineOffset is passed FindFunctionLocation will return the address of that line
@@ -80,8 +120,194 @@ func FindFunctionLocation(p Process, funcName string, firstLine bool, lineOffset
 	return origfn.Entry, nil
 }
 
+// SetFunctionBreakpointsRegexp sets a user breakpoint, with condition
+// cond, on the first line of every function whose name matches rx.
+// Functions that already have a breakpoint on their first line are
+// skipped. Returns the list of breakpoints created, if setting one of the
+// breakpoints fails all the breakpoints created so far are cleared.
+func SetFunctionBreakpointsRegexp(p Process, rx *regexp.Regexp, cond ast.Expr) ([]*Breakpoint, error) {
+	bi := p.BinInfo()
+	var bps []*Breakpoint
+	for i := range bi.Functions {
+		fn := &bi.Functions[i]
+		if fn.Entry == 0 || fn.cu == nil || !rx.MatchString(fn.Name) {
+			continue
+		}
+		addr, err := FirstPCAfterPrologue(p, fn, false)
+		if err != nil {
+			continue
+		}
+		bp, err := p.SetBreakpoint(addr, UserBreakpoint, cond)
+		if err != nil {
+			if _, exists := err.(BreakpointExistsError); exists {
+				continue
+			}
+			for _, bp := range bps {
+				p.ClearBreakpoint(bp.Addr)
+			}
+			return nil, err
+		}
+		bps = append(bps, bp)
+	}
+	if len(bps) == 0 {
+		return nil, fmt.Errorf("no function matches %q", rx.String())
+	}
+	return bps, nil
+}
+
+// SetFunctionExitBreakpoints sets a user breakpoint, with condition cond,
+// on every return instruction of function fnName. When one of those
+// breakpoints is hit the return values of the function will be available
+// through the ReturnValues method of the thread.
+func SetFunctionExitBreakpoints(p Process, fnName string, cond ast.Expr) ([]*Breakpoint, error) {
+	pcs, err := FunctionReturnLocations(p, fnName)
+	if err != nil {
+		return nil, err
+	}
+	var bps []*Breakpoint
+	for _, pc := range pcs {
+		bp, err := p.SetBreakpoint(pc, UserBreakpoint, cond)
+		if err != nil {
+			for _, bp := range bps {
+				p.ClearBreakpoint(bp.Addr)
+			}
+			return nil, err
+		}
+		bp.OnReturn = true
+		bps = append(bps, bp)
+	}
+	return bps, nil
+}
+
+// FunctionReturnLocations returns the addresses of all the return
+// instructions of function fnName.
+func FunctionReturnLocations(p Process, fnName string) ([]uint64, error) {
+	bi := p.BinInfo()
+	fn := bi.LookupFunc[fnName]
+	if fn == nil {
+		return nil, &FunctionNotFoundError{fnName}
+	}
+	text, err := disassemble(p.CurrentThread(), nil, p.Breakpoints(), bi, fn.Entry, fn.End, false)
+	if err != nil {
+		return nil, err
+	}
+	var pcs []uint64
+	for _, instr := range text {
+		if instr.Inst != nil && instr.IsRet() {
+			pcs = append(pcs, instr.Loc.PC)
+		}
+	}
+	if len(pcs) == 0 {
+		return nil, fmt.Errorf("could not find return instructions in %s", fnName)
+	}
+	return pcs, nil
+}
+
+// SetHardwareBreakpoint sets a user breakpoint at addr using a debug
+// register instead of writing a breakpoint instruction to memory. This is
+// useful for code that can not be patched, for example code that
+// checksums itself, but only a few hardware breakpoints, shared with
+// hardware watchpoints, can be set at the same time.
+func SetHardwareBreakpoint(p Process, addr uint64, cond ast.Expr) (*Breakpoint, error) {
+	bp, err := p.SetWatchpoint(addr, 1, WatchExecute, cond)
+	if err != nil {
+		return bp, err
+	}
+	f, l, fn := p.BinInfo().PCToLine(addr)
+	bp.File, bp.Line = f, l
+	if fn != nil {
+		bp.FunctionName = fn.Name
+	}
+	return bp, nil
+}
+
+// SetBreakpointGroupDisabled disables or enables, with a single call to
+// SetBreakpointsDisabled, all the user breakpoints belonging to group.
+func SetBreakpointGroupDisabled(p Process, group string, disabled bool) error {
+	var bps []*Breakpoint
+	for _, bp := range p.Breakpoints().M {
+		if bp.IsUser() && bp.Group == group {
+			bps = append(bps, bp)
+		}
+	}
+	if len(bps) == 0 {
+		return fmt.Errorf("no breakpoints in group %q", group)
+	}
+	return p.SetBreakpointsDisabled(bps, disabled)
+}
+
+// SetChannelCatchpoint sets user breakpoints on the runtime functions
+// implementing the channel operations in ops, conditioned on the channel
+// being the one whose hchan struct is at chanAddr.
+// Receives and sends done by select statements with more than one case do
+// not go through these functions and will not be caught.
+func SetChannelCatchpoint(p Process, chanAddr uint64, ops ChanOp) ([]*Breakpoint, error) {
+	if chanAddr == 0 {
+		return nil, errors.New("nil channel")
+	}
+	fns := []struct {
+		op   ChanOp
+		name string
+	}{
+		{ChanSend, "runtime.chansend"},
+		{ChanRecv, "runtime.chanrecv"},
+		{ChanClose, "runtime.closechan"},
+	}
+	cond, err := parser.ParseExpr(fmt.Sprintf("uintptr(c) == %#x", chanAddr))
+	if err != nil {
+		return nil, err
+	}
+	var bps []*Breakpoint
+	clearAll := func() {
+		for _, bp := range bps {
+			p.ClearBreakpoint(bp.Addr)
+		}
+	}
+	for _, fn := range fns {
+		if ops&fn.op == 0 {
+			continue
+		}
+		addr, err := FindFunctionLocation(p, fn.name, true, 0)
+		if err != nil {
+			clearAll()
+			return nil, err
+		}
+		bp, err := p.SetBreakpoint(addr, UserBreakpoint, cond)
+		if err != nil {
+			clearAll()
+			return nil, err
+		}
+		bps = append(bps, bp)
+	}
+	if len(bps) == 0 {
+		return nil, errors.New("no channel operation specified")
+	}
+	return bps, nil
+}
+
+// DeferMode determines what Next and Step do when the current function
+// returns and runs its deferred functions.
+type DeferMode uint8
+
+const (
+	// DeferStop stops inside the most recently deferred function, after its
+	// prologue.
+	DeferStop DeferMode = iota
+	// DeferSkip does not stop inside deferred functions.
+	DeferSkip
+	// DeferStopAtEntry stops at the entry point of the most recently
+	// deferred function.
+	DeferStopAtEntry
+)
+
 // Next continues execution until the next source line.
 func Next(dbp Process) (err error) {
+	return NextDefer(dbp, DeferStop)
+}
+
+// NextDefer is like Next but uses defers to decide whether to stop inside
+// deferred functions.
+func NextDefer(dbp Process, defers DeferMode) (err error) {
 	if _, err := dbp.Valid(); err != nil {
 		return err
 	}
@@ -89,14 +315,161 @@ func Next(dbp Process) (err error) {
 		return fmt.Errorf("next while nexting")
 	}
 
-	if err = next(dbp, false, false); err != nil {
+	if done, err := rangeStep(dbp); done || err != nil {
+		return err
+	}
+	if err = next(dbp, nextOptions{defers: defers}); err != nil {
 		dbp.ClearInternalBreakpoints()
 		return
 	}
+	dbp.Breakpoints().setInternalOrigin("next")
+
+	return Continue(dbp)
+}
+
+// ContinueUntil continues execution until one of the addresses in pcs is
+// reached, by any goroutine, or until another breakpoint is hit. The
+// internal breakpoints set on pcs are always cleared before returning.
+func ContinueUntil(dbp Process, pcs []uint64) error {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+	if len(pcs) == 0 {
+		return errors.New("no location to continue to")
+	}
+
+	defer dbp.ClearInternalBreakpoints()
+	for _, pc := range pcs {
+		if _, err := dbp.SetBreakpoint(pc, NextBreakpoint, nil); err != nil {
+			if _, ok := err.(BreakpointExistsError); !ok {
+				return err
+			}
+		}
+	}
+	dbp.Breakpoints().setInternalOrigin("continue")
+
+	return Continue(dbp)
+}
+
+// CurrentEvent returns the number of the current event of a recording,
+// parsed from the position returned by When.
+func CurrentEvent(p Process) (int64, error) {
+	when, err := p.When()
+	if err != nil {
+		return 0, err
+	}
+	return parseEvent(when)
+}
+
+// parseEvent parses the event number contained in the output of When,
+// which has the form "Current event: <number>".
+func parseEvent(when string) (int64, error) {
+	s := when
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		s = s[i+1:]
+	}
+	event, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("can not parse recording position %q", when)
+	}
+	return event, nil
+}
+
+// SeekEvent moves the recording to the start of event, going backward or
+// forward as needed. Breakpoints are not hit while seeking.
+func SeekEvent(p Process, event int64) error {
+	if recorded, _ := p.Recorded(); !recorded {
+		return NotRecordedErr
+	}
+	if event < 0 {
+		return fmt.Errorf("invalid event %d", event)
+	}
+	return p.Restart(strconv.FormatInt(event, 10))
+}
+
+// ContinueToGoroutine continues execution until goroutine gid runs again,
+// stopping when it returns to the first of its frames that is not part of
+// the runtime, or until another breakpoint is hit.
+// This is useful to get back to the goroutine that was being stepped after
+// a step operation stopped on a different goroutine.
+func ContinueToGoroutine(dbp Process, gid int) error {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+	if selg := dbp.SelectedGoroutine(); selg != nil && selg.ID == gid {
+		return fmt.Errorf("goroutine %d is already selected", gid)
+	}
+	return continueToGoroutine(dbp, gid)
+}
+
+func continueToGoroutine(dbp Process, gid int) error {
+	g, err := FindGoroutine(dbp, gid)
+	if err != nil {
+		return err
+	}
+	if g == nil {
+		return fmt.Errorf("Unknown goroutine %d", gid)
+	}
+	frames, err := g.Stacktrace(maxContinueToGoroutineDepth, false)
+	if err != nil {
+		return err
+	}
+	var frame *Stackframe
+	for i := range frames {
+		if fn := frames[i].Current.Fn; fn != nil && (!strings.HasPrefix(fn.Name, "runtime.") || isExportedRuntime(fn.Name)) {
+			frame = &frames[i]
+			break
+		}
+	}
+	if frame == nil {
+		return fmt.Errorf("could not find where goroutine %d will resume", gid)
+	}
+
+	defer dbp.ClearInternalBreakpoints()
+	if _, err := dbp.SetBreakpoint(frame.Current.PC, NextBreakpoint, andFrameoffCondition(SameGoroutineCondition(g), frame.FrameOffset())); err != nil {
+		if _, ok := err.(BreakpointExistsError); !ok {
+			return err
+		}
+	}
+	dbp.Breakpoints().setInternalOrigin("continue")
 
 	return Continue(dbp)
 }
 
+// RunGoroutine selects goroutine gid and, if it is parked, continues
+// execution until it runs on a thread, see ContinueToGoroutine. It returns
+// an error if the target stops for another reason before the goroutine
+// runs. Operations that need a running goroutine, like CallFunction, can
+// be used on the selected goroutine after RunGoroutine returns.
+func RunGoroutine(dbp Process, gid int) error {
+	if err := dbp.SwitchGoroutine(gid); err != nil {
+		return err
+	}
+	if selg := dbp.SelectedGoroutine(); selg == nil || selg.Thread != nil {
+		return nil
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+	if err := continueToGoroutine(dbp, gid); err != nil {
+		return err
+	}
+	if selg := dbp.SelectedGoroutine(); selg == nil || selg.ID != gid || selg.Thread == nil {
+		return fmt.Errorf("stopped before goroutine %d could run", gid)
+	}
+	return nil
+}
+
+// maxContinueToGoroutineDepth is the maximum number of frames
+// ContinueToGoroutine searches for a frame outside of the runtime.
+const maxContinueToGoroutineDepth = 50
+
 // Continue continues execution of the debugged
 // process. It will continue until it hits a breakpoint
 // or is otherwise stopped.
@@ -104,26 +477,42 @@ func Continue(dbp Process) error {
 	if _, err := dbp.Valid(); err != nil {
 		return err
 	}
+	if allThreadsHeld(dbp) {
+		return errors.New("all threads are held by frozen goroutines")
+	}
+	dbp.Common().selectedFrame = 0
+	if dbp.Common().schedHistory != nil {
+		dbp.Common().schedHistory = dbp.Common().schedHistory[:0]
+	}
 	for _, thread := range dbp.ThreadList() {
 		thread.Common().returnValues = nil
+		thread.Common().Cgo = nil
 	}
-	dbp.CheckAndClearManualStopRequest()
+	// A manual stop requested while the target was stopped is kept: Continue
+	// returns immediately and reports it.
+	dbp.Common().manualStop = false
 	defer func() {
 		// Make sure we clear internal breakpoints if we simultaneously receive a
 		// manual stop request and hit a breakpoint.
 		if dbp.CheckAndClearManualStopRequest() {
+			dbp.Common().manualStop = true
 			dbp.ClearInternalBreakpoints()
 		}
 	}()
 	for {
 		if dbp.CheckAndClearManualStopRequest() {
+			dbp.Common().manualStop = true
 			dbp.ClearInternalBreakpoints()
 			return nil
 		}
+		releaseHeldThreadsForStopTheWorld(dbp)
 		trapthread, err := dbp.ContinueOnce()
 		if err != nil {
 			return err
 		}
+		if err := disableExhaustedBreakpoints(dbp); err != nil {
+			return err
+		}
 
 		threads := dbp.ThreadList()
 
@@ -198,24 +587,32 @@ func Continue(dbp Process) error {
 				}
 			} else {
 				curthread.Common().returnValues = curbp.Breakpoint.returnInfo.Collect(curthread)
+				origin := curbp.Origin
 				if err := dbp.ClearInternalBreakpoints(); err != nil {
 					return err
 				}
+				if origin == "stepreturn" {
+					// stopped on one of the return instructions set by StepToReturn
+					curthread.Common().returnValues = collectReturnValues(curthread)
+				}
+				if (origin == "next" || origin == "step") && stoppedInFilteredFunction(dbp, curthread) {
+					// We landed inside a function hidden by the step filters,
+					// continue until it returns.
+					if err := setStepOutBreakpoints(dbp); err == nil {
+						dbp.Breakpoints().setInternalOrigin(origin)
+						break
+					}
+				}
 				return conditionErrors(threads)
 			}
 		case curbp.Active:
-			onNextGoroutine, err := onNextGoroutine(curthread, dbp.Breakpoints())
+			resume, err := userBreakpointHit(dbp, curthread, curbp.Breakpoint)
 			if err != nil {
 				return err
 			}
-			if onNextGoroutine {
-				err := dbp.ClearInternalBreakpoints()
-				if err != nil {
-					return err
-				}
-			}
-			if curbp.Name == UnrecoveredPanic {
-				dbp.ClearInternalBreakpoints()
+			if resume {
+				// a ContinueAction asked to resume execution
+				break
 			}
 			return conditionErrors(threads)
 		default:
@@ -224,6 +621,36 @@ func Continue(dbp Process) error {
 	}
 }
 
+// userBreakpointHit handles thread stopping at the active user breakpoint
+// bp: it runs the actions of bp and collects the return values and cgo
+// events reported by the stop. Returns true if an action asked to resume
+// execution.
+func userBreakpointHit(dbp Process, thread Thread, bp *Breakpoint) (bool, error) {
+	if len(bp.Actions) > 0 && runBreakpointActions(dbp, thread, bp) {
+		return true, nil
+	}
+	onNextGoroutine, err := onNextGoroutine(thread, dbp.Breakpoints())
+	if err != nil {
+		return false, err
+	}
+	if onNextGoroutine {
+		err := dbp.ClearInternalBreakpoints()
+		if err != nil {
+			return false, err
+		}
+	}
+	if bp.Name == UnrecoveredPanic || bp.Name == FatalThrow {
+		dbp.ClearInternalBreakpoints()
+	}
+	if bp.OnReturn {
+		thread.Common().returnValues = collectReturnValues(thread)
+	}
+	if bp.CgoCatch != 0 {
+		thread.Common().Cgo = cgoEvent(thread, bp)
+	}
+	return false, nil
+}
+
 func conditionErrors(threads []Thread) error {
 	var condErr error
 	for _, th := range threads {
@@ -281,6 +708,12 @@ func stepInstructionOut(dbp Process, curthread Thread, fnname1, fnname2 string)
 // Step will continue until another source line is reached.
 // Will step into functions.
 func Step(dbp Process) (err error) {
+	return StepDefer(dbp, DeferStop)
+}
+
+// StepDefer is like Step but uses defers to decide whether to stop inside
+// deferred functions.
+func StepDefer(dbp Process, defers DeferMode) (err error) {
 	if _, err := dbp.Valid(); err != nil {
 		return err
 	}
@@ -288,7 +721,10 @@ func Step(dbp Process) (err error) {
 		return fmt.Errorf("next while nexting")
 	}
 
-	if err = next(dbp, true, false); err != nil {
+	if done, err := rangeStep(dbp); done || err != nil {
+		return err
+	}
+	if err = next(dbp, nextOptions{stepInto: true, defers: defers}); err != nil {
 		switch err.(type) {
 		case ThreadBlockedError: // Noop
 		default:
@@ -296,10 +732,226 @@ func Step(dbp Process) (err error) {
 			return
 		}
 	}
+	dbp.Breakpoints().setInternalOrigin("step")
 
 	return Continue(dbp)
 }
 
+// Until continues execution until a source line of the current function
+// greater than the current one is reached, or the current function
+// returns. Unlike Next it does not stop when a loop jumps back to one of
+// its previous lines.
+func Until(dbp Process) (err error) {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+
+	if err = next(dbp, nextOptions{until: true}); err != nil {
+		dbp.ClearInternalBreakpoints()
+		return
+	}
+	dbp.Breakpoints().setInternalOrigin("until")
+
+	return Continue(dbp)
+}
+
+// NextInstruction executes a single instruction of the selected goroutine,
+// like StepInstruction, but if the instruction is a CALL execution
+// continues until the called function returns.
+func NextInstruction(dbp Process) error {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+
+	selg := dbp.SelectedGoroutine()
+	thread := dbp.CurrentThread()
+	if selg != nil {
+		if selg.Thread == nil {
+			// parked goroutines are always stopped inside a call to gopark
+			return dbp.StepInstruction()
+		}
+		thread = selg.Thread
+	}
+	regs, err := thread.Registers(false)
+	if err != nil {
+		return err
+	}
+	pc := regs.PC()
+	text, err := disassemble(thread, regs, dbp.Breakpoints(), dbp.BinInfo(), pc, pc+maxInstructionLength, true)
+	if err != nil {
+		return err
+	}
+	if len(text) == 0 || !text[0].IsCall() {
+		return dbp.StepInstruction()
+	}
+
+	if _, err := dbp.SetBreakpoint(pc+uint64(len(text[0].Bytes)), NextBreakpoint, SameGoroutineCondition(selg)); err != nil {
+		if _, ok := err.(BreakpointExistsError); !ok {
+			dbp.ClearInternalBreakpoints()
+			return err
+		}
+	}
+	dbp.Breakpoints().setInternalOrigin("nexti")
+
+	return Continue(dbp)
+}
+
+// RepeatStep calls step, one of Next, Step, StepInstruction or a similar
+// stepping function, count times. It returns early if the target stops
+// for a reason other than the completion of a step: a user breakpoint is
+// hit, a manual stop is requested or a step is interrupted by a breakpoint
+// on another goroutine.
+// If progress is not nil it is called after every completed step with the
+// number of steps completed so far.
+// RepeatStep returns the number of steps that were completed.
+func RepeatStep(dbp Process, count int, step func(Process) error, progress func(int)) (int, error) {
+	if count <= 0 {
+		return 0, fmt.Errorf("invalid step count %d", count)
+	}
+	for n := 0; n < count; n++ {
+		if dbp.CheckAndClearManualStopRequest() {
+			dbp.Common().manualStop = true
+			return n, nil
+		}
+		dbp.Common().manualStop = false
+		if err := step(dbp); err != nil {
+			return n, err
+		}
+		if progress != nil {
+			progress(n + 1)
+		}
+		if stepInterrupted(dbp) {
+			return n + 1, nil
+		}
+	}
+	return count, nil
+}
+
+// stepInterrupted returns true if the last step operation stopped for a
+// reason other than its completion.
+func stepInterrupted(dbp Process) bool {
+	if dbp.Breakpoints().HasInternalBreakpoints() || dbp.Common().StoppedManually() {
+		return true
+	}
+	bp := dbp.CurrentThread().Breakpoint()
+	return bp.Breakpoint != nil && bp.Active && bp.IsUser()
+}
+
+// StepInto is like Step but only enters the function called by the CALL
+// instruction at callPC, which must be one of the calls returned by
+// StepIntoTargets. All other calls on the current line are stepped over.
+func StepInto(dbp Process, callPC uint64) (err error) {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+
+	calls, err := stepIntoCalls(dbp)
+	if err != nil {
+		return err
+	}
+	var call []AsmInstruction
+	for i := range calls {
+		if calls[i].Loc.PC == callPC {
+			call = calls[i : i+1]
+			break
+		}
+	}
+	if call == nil {
+		return fmt.Errorf("no call to step into at %#x", callPC)
+	}
+
+	if err = next(dbp, nextOptions{}); err != nil {
+		dbp.ClearInternalBreakpoints()
+		return
+	}
+	if err = setStepIntoBreakpoint(dbp, call, SameGoroutineCondition(dbp.SelectedGoroutine())); err != nil {
+		dbp.ClearInternalBreakpoints()
+		return
+	}
+	dbp.Breakpoints().setInternalOrigin("step")
+
+	return Continue(dbp)
+}
+
+// ReverseNext moves the execution of a recording backwards to the start of
+// the source line executed before the current one, without entering
+// function calls. If another breakpoint is hit before reaching it the
+// operation is canceled.
+func ReverseNext(dbp Process) error {
+	return reverseNext(dbp, false)
+}
+
+// ReverseStep is like ReverseNext but if the previous source line called
+// a function execution stops inside the called function, at the return
+// instruction it returned from.
+func ReverseStep(dbp Process) error {
+	return reverseNext(dbp, true)
+}
+
+// ReverseStepInstruction moves the selected goroutine back by a single
+// instruction, undoing the last instruction it executed: after it returns
+// the registers of its thread are the ones it had before executing it.
+// The target must be a recording.
+func ReverseStepInstruction(dbp Process) (err error) {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+	if selg := dbp.SelectedGoroutine(); selg != nil && selg.Thread == nil {
+		return fmt.Errorf("can not reverse step goroutine %d, it is not running on a thread", selg.ID)
+	}
+	if err := dbp.Direction(Backward); err != nil {
+		return err
+	}
+	defer func() {
+		if err2 := dbp.Direction(Forward); err == nil {
+			err = err2
+		}
+	}()
+	return dbp.StepInstruction()
+}
+
+func reverseNext(dbp Process, stepInto bool) (err error) {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+	if err := dbp.Direction(Backward); err != nil {
+		return err
+	}
+	defer func() {
+		// internal breakpoints must be cleared before the direction can be
+		// changed back.
+		dbp.ClearInternalBreakpoints()
+		if err2 := dbp.Direction(Forward); err == nil {
+			err = err2
+		}
+	}()
+
+	if err := setReverseNextBreakpoints(dbp, stepInto); err != nil {
+		return err
+	}
+	if stepInto {
+		dbp.Breakpoints().setInternalOrigin("step")
+	} else {
+		dbp.Breakpoints().setInternalOrigin("next")
+	}
+	return Continue(dbp)
+}
+
 // SameGoroutineCondition returns an expression that evaluates to true when
 // the current goroutine is g.
 func SameGoroutineCondition(g *G) ast.Expr {
@@ -330,9 +982,13 @@ func frameoffCondition(frameoff int64) ast.Expr {
 	}
 }
 
+// andFrameoffCondition returns cond && runtime.frameoff == frameoff. When
+// cond is nil, which happens when there is no current goroutine, only the
+// frame offset is checked so that breakpoints set by next and stepout are
+// still limited to a single frame of recursive functions.
 func andFrameoffCondition(cond ast.Expr, frameoff int64) ast.Expr {
 	if cond == nil {
-		return nil
+		return frameoffCondition(frameoff)
 	}
 	return &ast.BinaryExpr{
 		Op: token.LAND,
@@ -351,10 +1007,69 @@ func StepOut(dbp Process) error {
 		return fmt.Errorf("next while nexting")
 	}
 
+	if err := setStepOutBreakpoints(dbp); err != nil {
+		return err
+	}
+	dbp.Breakpoints().setInternalOrigin("stepout")
+	return Continue(dbp)
+}
+
+// StepToReturn continues until the current function of the selected
+// goroutine is about to return, after its deferred functions have run,
+// stopping on its return instruction. The return values of the function
+// are then available through the ReturnValues method of the thread.
+func StepToReturn(dbp Process) error {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+
 	selg := dbp.SelectedGoroutine()
 	curthread := dbp.CurrentThread()
+	topframe, _, err := topframe(selg, curthread)
+	if err != nil {
+		return err
+	}
+	if topframe.Inlined {
+		return errors.New("can not step to the return of an inlined function")
+	}
+	if topframe.Current.Fn == nil {
+		return errors.New("could not find current function")
+	}
+	rets, err := FunctionReturnLocations(dbp, topframe.Current.Fn.Name)
+	if err != nil {
+		return err
+	}
+	for _, ret := range rets {
+		if ret == topframe.Current.PC {
+			// already stopped on a return instruction
+			curthread.Common().returnValues = collectReturnValues(curthread)
+			return nil
+		}
+	}
 
-	topframe, retframe, err := topframe(selg, curthread)
+	sameFrameCond := andFrameoffCondition(SameGoroutineCondition(selg), topframe.FrameOffset())
+	for _, ret := range rets {
+		if _, err := dbp.SetBreakpoint(ret, NextBreakpoint, sameFrameCond); err != nil {
+			if _, isexists := err.(BreakpointExistsError); !isexists {
+				dbp.ClearInternalBreakpoints()
+				return err
+			}
+		}
+	}
+	dbp.Breakpoints().setInternalOrigin("stepreturn")
+	return Continue(dbp)
+}
+
+// setStepOutBreakpoints sets the internal breakpoints used by StepOut to
+// stop when the current function of the selected goroutine returns.
+func setStepOutBreakpoints(dbp Process) error {
+	selg := dbp.SelectedGoroutine()
+	curthread := dbp.CurrentThread()
+
+	topframe, retframe, err := selectedFrames(dbp)
 	if err != nil {
 		return err
 	}
@@ -367,12 +1082,11 @@ func StepOut(dbp Process) error {
 	}()
 
 	if topframe.Inlined {
-		if err := next(dbp, false, true); err != nil {
+		if err := next(dbp, nextOptions{inlinedStepOut: true}); err != nil {
 			return err
 		}
-
 		success = true
-		return Continue(dbp)
+		return nil
 	}
 
 	sameGCond := SameGoroutineCondition(selg)
@@ -425,7 +1139,7 @@ func StepOut(dbp Process) error {
 	}
 
 	success = true
-	return Continue(dbp)
+	return nil
 }
 
 // GoroutinesInfo returns an array of G structures representing the information
@@ -532,6 +1246,14 @@ func FindGoroutine(dbp Process, gid int) (*G, error) {
 // ConvertEvalScope returns a new EvalScope in the context of the
 // specified goroutine ID and stack frame.
 func ConvertEvalScope(dbp Process, gid, frame int) (*EvalScope, error) {
+	return ConvertEvalScopeDeferred(dbp, gid, frame, 0)
+}
+
+// ConvertEvalScopeDeferred is like ConvertEvalScope but, if deferCall is
+// greater than zero, the scope is relative to the arguments of the
+// deferCall-th function deferred by the frame, in the order they will be
+// called, see Defer.EvalScope.
+func ConvertEvalScopeDeferred(dbp Process, gid, frame, deferCall int) (*EvalScope, error) {
 	if _, err := dbp.Valid(); err != nil {
 		return nil, err
 	}
@@ -551,7 +1273,7 @@ func ConvertEvalScope(dbp Process, gid, frame int) (*EvalScope, error) {
 		thread = g.Thread
 	}
 
-	locs, err := g.Stacktrace(frame+1, false)
+	locs, err := g.Stacktrace(frame+1, deferCall > 0)
 	if err != nil {
 		return nil, err
 	}
@@ -560,7 +1282,62 @@ func ConvertEvalScope(dbp Process, gid, frame int) (*EvalScope, error) {
 		return nil, fmt.Errorf("Frame %d does not exist in goroutine %d", frame, gid)
 	}
 
-	return FrameToScope(dbp.BinInfo(), thread, g, locs[frame:]...), nil
+	scope := FrameToScope(dbp.BinInfo(), thread, g, locs[frame:]...)
+	if deferCall <= 0 {
+		return scope, nil
+	}
+	if deferCall > len(locs[frame].Defers) {
+		return nil, fmt.Errorf("Frame %d only has %d deferred calls", frame, len(locs[frame].Defers))
+	}
+	return locs[frame].Defers[deferCall-1].EvalScope(scope)
+}
+
+// SwitchFrame selects frame of the selected goroutine, SelectedScope and
+// the Next, Step and StepOut operations work relative to the selected
+// frame. The selection is reset to the topmost frame when the target is
+// resumed or a different goroutine is selected.
+func SwitchFrame(p Process, frame int) error {
+	if _, err := p.Valid(); err != nil {
+		return err
+	}
+	common := p.Common()
+	if frame == 0 {
+		common.selectedFrame = 0
+		return nil
+	}
+	if frame < 0 {
+		return fmt.Errorf("invalid frame %d", frame)
+	}
+	selg := p.SelectedGoroutine()
+	if selg == nil {
+		return errors.New("no selected goroutine")
+	}
+	frames, err := selg.Stacktrace(frame, false)
+	if err != nil {
+		return err
+	}
+	if frame >= len(frames) {
+		return fmt.Errorf("Frame %d does not exist in goroutine %d", frame, selg.ID)
+	}
+	common.selectedFrame = frame
+	common.selectedFrameG = selg.ID
+	return nil
+}
+
+// SelectedFrame returns the frame of the selected goroutine selected by
+// SwitchFrame, or 0 if the selection was reset.
+func SelectedFrame(p Process) int {
+	common := p.Common()
+	if selg := p.SelectedGoroutine(); selg == nil || selg.ID != common.selectedFrameG {
+		return 0
+	}
+	return common.selectedFrame
+}
+
+// SelectedScope returns the scope of the selected frame of the selected
+// goroutine, or of the current thread if there is no selected goroutine.
+func SelectedScope(p Process) (*EvalScope, error) {
+	return ConvertEvalScope(p, -1, SelectedFrame(p))
 }
 
 // FrameToScope returns a new EvalScope for frames[0].
@@ -574,6 +1351,13 @@ func FrameToScope(bi *BinaryInfo, thread MemoryReadWriter, g *G, frames ...Stack
 		gvar = g.variable
 	}
 
+	// The thread-local variables of C code are only available if the
+	// goroutine is running on a thread.
+	var tls uint64
+	if t, isthread := thread.(Thread); isthread && (g == nil || g.Thread != nil) {
+		tls = bi.threadTLSBlock(t)
+	}
+
 	// Creates a cacheMem that will preload the entire stack frame the first
 	// time any local variable is read.
 	// Remember that the stack grows downward in memory.
@@ -590,10 +1374,12 @@ func FrameToScope(bi *BinaryInfo, thread MemoryReadWriter, g *G, frames ...Stack
 
 	s := &EvalScope{Location: frames[0].Call, Regs: frames[0].Regs, Mem: thread, Gvar: gvar, BinInfo: bi, frameOffset: frames[0].FrameOffset()}
 	s.PC = frames[0].lastpc
+	s.Regs.TLS = int64(tls)
 	return s
 }
 
-// CreateUnrecoverablePanicBreakpoint creates the unrecoverable-panic breakpoint.
+// CreateUnrecoverablePanicBreakpoint creates the unrecoverable-panic
+// breakpoint and the fatal-throw breakpoint.
 // This function is meant to be called by implementations of the Process interface.
 func CreateUnrecoveredPanicBreakpoint(p Process, writeBreakpoint writeBreakpointFn, breakpoints *BreakpointMap) {
 	panicpc, err := FindFunctionLocation(p, "runtime.startpanic", true, 0)
@@ -608,6 +1394,32 @@ func CreateUnrecoveredPanicBreakpoint(p Process, writeBreakpoint writeBreakpoint
 		}
 	}
 
+	throwpc, err := FindFunctionLocation(p, "runtime.throw", true, 0)
+	if err == nil {
+		bp, err := breakpoints.SetWithID(-2, throwpc, writeBreakpoint)
+		if err == nil {
+			bp.Name = FatalThrow
+			bp.Variables = []string{"s"}
+		}
+	}
+}
+
+// PanicFrame returns the index of the frame that called panic or
+// runtime.throw in the stack trace frames, or -1 if frames is not the
+// stack trace of a panicking goroutine.
+func PanicFrame(frames []Stackframe) int {
+	for i := range frames {
+		if frames[i].Current.Fn == nil {
+			continue
+		}
+		switch frames[i].Current.Fn.Name {
+		case "runtime.gopanic", "runtime.throw":
+			if i+1 < len(frames) {
+				return i + 1
+			}
+		}
+	}
+	return -1
 }
 
 // FirstPCAfterPrologue returns the address of the first
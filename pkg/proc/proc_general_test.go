@@ -1,7 +1,17 @@
 package proc
 
 import (
+	"errors"
+	"go/ast"
+	"go/constant"
+	"go/scanner"
+	"go/token"
+	"math"
+	"reflect"
 	"testing"
+
+	"github.com/derekparker/delve/pkg/dwarf/godwarf"
+	"github.com/derekparker/delve/pkg/dwarf/op"
 )
 
 func TestIssue554(t *testing.T) {
@@ -12,3 +22,319 @@ func TestIssue554(t *testing.T) {
 		t.Fatalf("should be false")
 	}
 }
+
+func TestCondGoroutineID(t *testing.T) {
+	g := &G{ID: 42}
+	sameGCond := SameGoroutineCondition(g)
+	for _, cond := range []ast.Expr{
+		sameGCond,
+		andFrameoffCondition(sameGCond, 16),
+		&ast.BinaryExpr{
+			Op: token.LAND,
+			X:  sameGCond,
+			Y: &ast.BinaryExpr{
+				Op: token.LOR,
+				X:  frameoffCondition(16),
+				Y:  frameoffCondition(32),
+			},
+		},
+	} {
+		if id := condGoroutineID(cond); id != 42 {
+			t.Errorf("wrong goroutine ID for %s: %d", exprToString(cond), id)
+		}
+	}
+	if id := condGoroutineID(frameoffCondition(16)); id != 0 {
+		t.Errorf("wrong goroutine ID for condition without goroutine: %d", id)
+	}
+}
+
+func TestFrameoffConditionNoGoroutine(t *testing.T) {
+	// Without a goroutine the breakpoints set by next on recursive functions
+	// must still be limited to the starting frame.
+	cond := andFrameoffCondition(SameGoroutineCondition(nil), 16)
+	if cond == nil {
+		t.Fatal("no condition without goroutine")
+	}
+	if s, tgt := exprToString(cond), exprToString(frameoffCondition(16)); s != tgt {
+		t.Errorf("wrong condition %q, expected %q", s, tgt)
+	}
+}
+
+func TestParseEvent(t *testing.T) {
+	for _, tc := range []struct {
+		when  string
+		event int64
+	}{
+		{"Current event: 366", 366},
+		{"Current event: 0\n", 0},
+		{"12", 12},
+	} {
+		event, err := parseEvent(tc.when)
+		if err != nil {
+			t.Errorf("parseEvent(%q): %v", tc.when, err)
+		} else if event != tc.event {
+			t.Errorf("parseEvent(%q) = %d, expected %d", tc.when, event, tc.event)
+		}
+	}
+	if _, err := parseEvent("Current event: unknown"); err == nil {
+		t.Errorf("parseEvent succeeded on an invalid position")
+	}
+}
+
+func TestReadableWaitReason(t *testing.T) {
+	for _, tc := range []struct {
+		name, reason string
+	}{
+		{"runtime.waitReasonChanReceive", "chan receive"},
+		{"waitReasonSelectNoCases", "select (no cases)"},
+		{"runtime.waitReasonZero", ""},
+		{"runtime.waitReasonSomethingNew", "something new"},
+		{"chan send", "chan send"},
+	} {
+		if reason := readableWaitReason(tc.name); reason != tc.reason {
+			t.Errorf("readableWaitReason(%q) = %q, expected %q", tc.name, reason, tc.reason)
+		}
+	}
+}
+
+func TestGoroutineStatusName(t *testing.T) {
+	for _, tc := range []struct {
+		status uint64
+		name   string
+	}{
+		{Grunning, "running"},
+		{Gwaiting | gscanStatus, "waiting"},
+		{Gpreempted, "waiting"},
+		{Gpreempted | gscanStatus, "waiting"},
+		{GmoribundUnused, "unknown"},
+	} {
+		g := &G{Status: tc.status}
+		if name := g.StatusName(); name != tc.name {
+			t.Errorf("status %#x: got %q, expected %q", tc.status, name, tc.name)
+		}
+	}
+}
+
+func TestBlackboxed(t *testing.T) {
+	var p CommonProcess
+	p.SetBlackbox([]string{"main.(*T).DeepCopy", "zz_generated.deepcopy.go", "/abs/path/gen.go"})
+	tc := []struct {
+		fnname, file string
+		tgt          bool
+	}{
+		{"main.(*T).DeepCopy", "/src/main.go", true},
+		{"main.(*T).DeepCopyInto", "/src/main.go", false},
+		{"main.f", "/src/pkg/zz_generated.deepcopy.go", true},
+		{"main.f", "/src/pkg/xzz_generated.deepcopy.go", false},
+		{"main.f", "/abs/path/gen.go", true},
+		{"main.f", "/other/abs/path/gen.go", false},
+	}
+	for _, c := range tc {
+		if out := p.stepFiltered(c.fnname, c.file); out != c.tgt {
+			t.Errorf("stepFiltered(%q, %q) = %v, expected %v", c.fnname, c.file, out, c.tgt)
+		}
+	}
+}
+
+func TestParseFormatTemplate(t *testing.T) {
+	testcases := []struct {
+		tmpl  string
+		parts []formatTemplatePart
+		err   bool
+	}{
+		{"plain", []formatTemplatePart{{text: "plain"}}, false},
+		{"{A} and {B.C}", []formatTemplatePart{{field: []string{"A"}}, {text: " and "}, {field: []string{"B", "C"}}}, false},
+		{"{{A}}", []formatTemplatePart{{text: "{A}"}}, false},
+		{"{A", nil, true},
+		{"{}", nil, true},
+	}
+	for _, tc := range testcases {
+		parts, err := parseFormatTemplate(tc.tmpl)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tc.tmpl)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tc.tmpl, err)
+			continue
+		}
+		if !reflect.DeepEqual(parts, tc.parts) {
+			t.Errorf("%q: expected %#v got %#v", tc.tmpl, tc.parts, parts)
+		}
+	}
+}
+
+func TestRewriteRegisters(t *testing.T) {
+	testcases := []struct {
+		expr     string
+		expected string
+	}{
+		{"x + 1", "x + 1"},
+		{"$rax", registerPrefix + "rax"},
+		{"$pc == 0x401000 && $rsp > 0", registerPrefix + "pc == 0x401000 && " + registerPrefix + "rsp > 0"},
+		{"*(*int)($rsp+8)", "*(*int)(" + registerPrefix + "rsp+8)"},
+		{`s == "$rax"`, `s == "$rax"`},
+		{"'$' == c", "'$' == c"},
+		{"$ 1", "$ 1"},
+	}
+	for _, tc := range testcases {
+		if out, _ := rewriteRegisters(tc.expr); out != tc.expected {
+			t.Errorf("%q: expected %q got %q", tc.expr, tc.expected, out)
+		}
+	}
+}
+
+func TestParseExprErrorPos(t *testing.T) {
+	testcases := []struct {
+		expr string
+		off  int
+	}{
+		{"x = 1", 2},
+		{"$rax = 1", 5},
+		{"m[$rax] = $rbx", 8},
+		{"$rax + $rbx = 1", 12},
+	}
+	for _, tc := range testcases {
+		_, err := ParseExpr(tc.expr)
+		el, ok := err.(scanner.ErrorList)
+		if !ok {
+			t.Errorf("%q: expected syntax error, got %v", tc.expr, err)
+			continue
+		}
+		if el[0].Pos.Offset != tc.off || el[0].Pos.Column != tc.off+1 {
+			t.Errorf("%q: expected error at offset %d, got %d (column %d)", tc.expr, tc.off, el[0].Pos.Offset, el[0].Pos.Column)
+		}
+	}
+}
+
+func TestFormatInt(t *testing.T) {
+	testcases := []struct {
+		val      constant.Value
+		verb     byte
+		expected string
+	}{
+		{constant.MakeInt64(31), 'x', "0x1f"},
+		{constant.MakeInt64(-31), 'x', "-0x1f"},
+		{constant.MakeUint64(1<<64 - 1), 'x', "0xffffffffffffffff"},
+		{constant.MakeInt64(8), 'o', "010"},
+		{constant.MakeInt64(5), 'b', "0b101"},
+		{constant.MakeInt64(0), 'b', "0b0"},
+		{constant.MakeInt64('a'), 'c', "'a'"},
+		{constant.MakeInt64(0x4e16), 'c', "'世'"},
+		{constant.MakeInt64(31), 'd', ""},
+		{constant.MakeInt64(31), 0, ""},
+	}
+	for _, tc := range testcases {
+		if out := formatInt(tc.val, tc.verb); out != tc.expected {
+			t.Errorf("%v %c: expected %q got %q", tc.val, tc.verb, tc.expected, out)
+		}
+	}
+}
+
+func TestDiffVariables(t *testing.T) {
+	intv := func(name string, n int64) Variable {
+		return Variable{Name: name, Kind: reflect.Int, Value: constant.MakeInt64(n)}
+	}
+	// a list node pointing to itself at addr, with a val field
+	node := func(addr uintptr, val int64) *Variable {
+		n := &Variable{Addr: addr, Kind: reflect.Struct}
+		next := Variable{Name: "next", Kind: reflect.Ptr, Children: []Variable{*n}}
+		n.Children = []Variable{intv("val", val), next}
+		next.Children[0].Children = n.Children
+		n.Children[1] = next
+		return n
+	}
+	ptr := func(v *Variable) *Variable {
+		return &Variable{Kind: reflect.Ptr, Children: []Variable{*v}}
+	}
+
+	if diffs := DiffVariables(ptr(node(0x100, 1)), ptr(node(0x200, 1)), 10); len(diffs) != 0 {
+		t.Errorf("differences between equal lists: %#v", diffs)
+	}
+	diffs := DiffVariables(ptr(node(0x100, 1)), ptr(node(0x200, 2)), 10)
+	if len(diffs) != 1 || diffs[0].Path != ".val" {
+		t.Errorf("wrong differences: %#v", diffs)
+	}
+	if diffs := DiffVariables(ptr(node(0x100, 1)), ptr(node(0x200, 2)), 0); len(diffs) != 0 {
+		t.Errorf("differences found beyond the maximum depth: %#v", diffs)
+	}
+
+	slice := func(vals ...int64) *Variable {
+		v := &Variable{Kind: reflect.Slice, Base: 0x300, Len: int64(len(vals))}
+		for _, val := range vals {
+			v.Children = append(v.Children, intv("", val))
+		}
+		return v
+	}
+	diffs = DiffVariables(slice(1, 2), slice(1, 3, 4), 10)
+	if len(diffs) != 2 || diffs[0].Path != "[1]" || diffs[1].Path != "[2]" || diffs[1].A != nil {
+		t.Errorf("wrong differences: %#v", diffs)
+	}
+
+	for depth := 1; depth <= 20; depth++ {
+		cfg := DiffLoadConfig(depth)
+		if n := math.Pow(float64(cfg.MaxArrayValues), float64(depth)); n > maxDiffLoadedElements && cfg.MaxArrayValues > 1 {
+			t.Errorf("depth %d: %d elements per level can load %g elements", depth, cfg.MaxArrayValues, n)
+		}
+	}
+}
+
+func TestCheckAddressable(t *testing.T) {
+	inttyp := &godwarf.IntType{BasicType: godwarf.BasicType{CommonType: godwarf.CommonType{ByteSize: 8}}}
+	regmem := &compositeMemory{pieces: []op.Piece{{Size: 8, IsRegister: true}}}
+	splitmem := &compositeMemory{pieces: []op.Piece{{Size: 4, Addr: 0x1000}, {Size: 4, Addr: 0x2000}}}
+	fakemem := newFakeMemory(nil, 8)
+
+	testcases := []struct {
+		v           *Variable
+		addressable bool
+	}{
+		{&Variable{Addr: 0x1000, RealType: inttyp}, true},
+		{&Variable{Addr: fakeAddress, RealType: inttyp, mem: regmem}, false},
+		{&Variable{Addr: fakeAddress, RealType: inttyp, mem: cacheMemory(regmem, fakeAddress, 8)}, false},
+		{&Variable{Addr: fakeAddress, RealType: inttyp, mem: splitmem}, false},
+		{&Variable{Addr: fakeAddress, RealType: inttyp, mem: fakemem}, false},
+		{&Variable{Addr: 0x1000, RealType: inttyp, mem: fakemem}, true},
+		{&Variable{Addr: 0x1000, RealType: inttyp, mem: &memCache{true, 0x1000, make([]byte, 8), nil}}, true},
+		{&Variable{Addr: 0x1000, RealType: inttyp, mem: &memSnapshot{memCache{true, 0x1000, make([]byte, 8), nil}}}, false},
+		{&Variable{Addr: 0x1000, RealType: inttyp, mem: cacheMemory(&memSnapshot{memCache{true, 0x1000, make([]byte, 8), nil}}, 0x1000, 8)}, false},
+		{&Variable{Addr: 0x2000, RealType: inttyp, mem: &memSnapshot{memCache{true, 0x1000, make([]byte, 8), nil}}}, true},
+	}
+	for i, tc := range testcases {
+		if err := tc.v.checkAddressable(); (err == nil) != tc.addressable {
+			t.Errorf("%d: wrong result: %v", i, err)
+		}
+	}
+}
+
+func TestSetDisabledRollback(t *testing.T) {
+	bpmap := NewBreakpointMap()
+	bps := []*Breakpoint{
+		{ID: 1, Addr: 0x1000, Kind: UserBreakpoint},
+		{ID: 2, Addr: 0x2000, Kind: UserBreakpoint},
+		{ID: 3, Addr: 0x3000, Kind: UserBreakpoint},
+	}
+	written := map[uint64]bool{0x1000: true, 0x2000: true, 0x3000: true}
+	writeBreakpoint := func(addr uint64) (string, int, *Function, []byte, error) {
+		written[addr] = true
+		return "", 0, nil, []byte{0x90}, nil
+	}
+	clearBreakpoint := func(bp *Breakpoint) error {
+		if bp.Addr == 0x3000 {
+			return errors.New("clear failed")
+		}
+		written[bp.Addr] = false
+		return nil
+	}
+
+	if err := bpmap.SetDisabled(bps, true, writeBreakpoint, clearBreakpoint); err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, bp := range bps {
+		if bp.Disabled || !written[bp.Addr] {
+			t.Errorf("breakpoint %d not restored (disabled %v, written %v)", bp.ID, bp.Disabled, written[bp.Addr])
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
 	"strings"
 	"testing"
@@ -27,7 +28,7 @@ import (
 	protest "github.com/derekparker/delve/pkg/proc/test"
 )
 
-var normalLoadConfig = proc.LoadConfig{true, 1, 64, 64, -1}
+var normalLoadConfig = proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
 var testBackend string
 
 func init() {
@@ -231,6 +232,30 @@ func TestHalt(t *testing.T) {
 	})
 }
 
+func TestHaltBeforeContinue(t *testing.T) {
+	// A manual stop requested while the target is stopped must interrupt the
+	// next Continue and be reported as a manual stop.
+	withTestProcess("loopprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.loop")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+		if p.Common().StoppedManually() {
+			t.Fatal("breakpoint stop reported as a manual stop")
+		}
+		for _, bp := range p.Breakpoints().M {
+			if bp.ID > 0 {
+				_, err := p.ClearBreakpoint(bp.Addr)
+				assertNoError(err, t, "ClearBreakpoint")
+			}
+		}
+		assertNoError(p.RequestManualStop(), t, "RequestManualStop")
+		assertNoError(proc.Continue(p), t, "Continue")
+		if !p.Common().StoppedManually() {
+			t.Fatal("manual stop not reported")
+		}
+	})
+}
+
 func TestStep(t *testing.T) {
 	protest.AllowRecording(t)
 	withTestProcess("testprog", t, func(p proc.Process, fixture protest.Fixture) {
@@ -797,6 +822,46 @@ func TestCGONext(t *testing.T) {
 	})
 }
 
+func TestCgoCatchpoints(t *testing.T) {
+	if os.Getenv("CGO_ENABLED") == "" {
+		return
+	}
+
+	protest.AllowRecording(t)
+	withTestProcess("cgotest", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.main")
+		assertNoError(err, t, "setFunctionBreakpoint()")
+		assertNoError(proc.Continue(p), t, "Continue()")
+		_, err = proc.SetCgoCatchpoints(p)
+		assertNoError(err, t, "SetCgoCatchpoints()")
+
+		// runtime.asmcgocall is also used to call runtime functions on
+		// the system stack, look for the call to C.foo, reported with the
+		// name of the C function instead of the one of its cgo wrapper.
+		for {
+			assertNoError(proc.Continue(p), t, "Continue()")
+			ev := p.CurrentThread().Common().Cgo
+			if ev == nil {
+				t.Fatal("not stopped at a cgo catchpoint")
+			}
+			if ev.Direction == proc.CgoCall && ev.FnName == "foo" {
+				break
+			}
+		}
+
+		assertNoError(proc.Continue(p), t, "Continue()")
+		if ev := p.CurrentThread().Common().Cgo; ev == nil || ev.Direction != proc.CgoReturn {
+			t.Fatalf("expected cgo return, got %#v", ev)
+		}
+
+		assertNoError(proc.ClearCgoCatchpoints(p), t, "ClearCgoCatchpoints()")
+		err = proc.Continue(p)
+		if _, exited := err.(proc.ProcessExitedError); !exited {
+			t.Fatalf("expected process exit, got %v", err)
+		}
+	})
+}
+
 type loc struct {
 	line int
 	fn   string
@@ -1422,6 +1487,135 @@ func BenchmarkArray(b *testing.B) {
 
 const doTestBreakpointCountsWithDetection = false
 
+func TestBreakpointHitCondition(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("bpcountstest", t, func(p proc.Process, fixture protest.Fixture) {
+		addr, _, err := p.BinInfo().LineToPC(fixture.Source, 12)
+		assertNoError(err, t, "LineToPC")
+		bp, err := p.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+		assertNoError(err, t, "SetBreakpoint()")
+		bp.HitCond, err = proc.ParseHitCondition("% 10")
+		assertNoError(err, t, "ParseHitCondition")
+
+		stops := 0
+		for {
+			if err := proc.Continue(p); err != nil {
+				if _, exited := err.(proc.ProcessExitedError); exited {
+					break
+				}
+				assertNoError(err, t, "Continue()")
+			}
+			stops++
+			if bp.TotalHitCount%10 != 0 {
+				t.Fatalf("stopped with TotalHitCount %d", bp.TotalHitCount)
+			}
+		}
+
+		if bp.TotalHitCount != 200 {
+			t.Fatalf("Wrong TotalHitCount for the breakpoint (%d)", bp.TotalHitCount)
+		}
+		if stops != 20 {
+			t.Fatalf("Wrong number of stops %d", stops)
+		}
+	})
+}
+
+func TestBreakpointMaxHits(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("bpcountstest", t, func(p proc.Process, fixture protest.Fixture) {
+		addr, _, err := p.BinInfo().LineToPC(fixture.Source, 12)
+		assertNoError(err, t, "LineToPC")
+		bp, err := p.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+		assertNoError(err, t, "SetBreakpoint()")
+		bp.MaxHits = 3
+
+		stops := 0
+		for {
+			if err := proc.Continue(p); err != nil {
+				if _, exited := err.(proc.ProcessExitedError); exited {
+					break
+				}
+				assertNoError(err, t, "Continue()")
+			}
+			stops++
+			if disabled := bp.Disabled; disabled != (stops >= 3) {
+				t.Errorf("breakpoint disabled %v after %d stops", disabled, stops)
+			}
+		}
+
+		if bp.TotalHitCount != 3 {
+			t.Fatalf("Wrong TotalHitCount for the breakpoint (%d)", bp.TotalHitCount)
+		}
+		if stops != 3 {
+			t.Fatalf("Wrong number of stops %d", stops)
+		}
+	})
+}
+
+func TestBreakpointThreadID(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("bpcountstest", t, func(p proc.Process, fixture protest.Fixture) {
+		addr, _, err := p.BinInfo().LineToPC(fixture.Source, 12)
+		assertNoError(err, t, "LineToPC")
+		bp, err := p.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+		assertNoError(err, t, "SetBreakpoint()")
+
+		assertNoError(proc.Continue(p), t, "Continue()")
+		bp.ThreadID = p.CurrentThread().ThreadID()
+
+		for i := 0; i < 10; i++ {
+			if err := proc.Continue(p); err != nil {
+				if _, exited := err.(proc.ProcessExitedError); exited {
+					break
+				}
+				assertNoError(err, t, "Continue()")
+			}
+			if tid := p.CurrentThread().ThreadID(); tid != bp.ThreadID {
+				t.Fatalf("breakpoint pinned to thread %d triggered on thread %d", bp.ThreadID, tid)
+			}
+		}
+	})
+}
+
+func TestBreakpointGroups(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("bpcountstest", t, func(p proc.Process, fixture protest.Fixture) {
+		var bps []*proc.Breakpoint
+		for _, line := range []int{12, 13} {
+			addr, _, err := p.BinInfo().LineToPC(fixture.Source, line)
+			assertNoError(err, t, "LineToPC")
+			bp, err := p.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+			assertNoError(err, t, "SetBreakpoint()")
+			bp.Group = "loop"
+			bps = append(bps, bp)
+		}
+
+		assertNoError(proc.Continue(p), t, "Continue()")
+		assertNoError(proc.SetBreakpointGroupDisabled(p, "loop", true), t, "SetBreakpointGroupDisabled")
+
+		for _, bp := range bps {
+			if !bp.Disabled {
+				t.Fatalf("breakpoint %d not disabled", bp.ID)
+			}
+			buf := make([]byte, len(bp.OriginalData))
+			_, err := p.CurrentThread().ReadMemory(buf, uintptr(bp.Addr))
+			assertNoError(err, t, "ReadMemory")
+			if testBackend == "native" && !bytes.Equal(buf, bp.OriginalData) {
+				t.Fatalf("breakpoint %d still written to memory: %x", bp.ID, buf)
+			}
+		}
+
+		total := bps[0].TotalHitCount + bps[1].TotalHitCount
+		err := proc.Continue(p)
+		if _, exited := err.(proc.ProcessExitedError); !exited {
+			t.Fatalf("expected process to exit, got %v", err)
+		}
+		if bps[0].TotalHitCount+bps[1].TotalHitCount != total {
+			t.Fatalf("disabled breakpoints were hit")
+		}
+	})
+}
+
 func TestBreakpointCountsWithDetection(t *testing.T) {
 	if !doTestBreakpointCountsWithDetection {
 		return
@@ -1659,6 +1853,37 @@ func TestCondBreakpointError(t *testing.T) {
 	})
 }
 
+func TestCondBreakpointGoroutineLabels(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("goroutinelabels", t, func(p proc.Process, fixture protest.Fixture) {
+		addr, _, err := p.BinInfo().LineToPC(fixture.Source, 11)
+		assertNoError(err, t, "LineToPC")
+		bp, err := p.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+		assertNoError(err, t, "SetBreakpoint()")
+		bp.Cond = &ast.BinaryExpr{
+			Op: token.EQL,
+			X: &ast.IndexExpr{
+				X:     &ast.Ident{Name: "labels"},
+				Index: &ast.BasicLit{Kind: token.STRING, Value: `"request-id"`},
+			},
+			Y: &ast.BasicLit{Kind: token.STRING, Value: `"abc123"`},
+		}
+
+		assertNoError(proc.Continue(p), t, "Continue()")
+		if id := constant.StringVal(evalVariable(p, t, "id").Value); id != "abc123" {
+			t.Fatalf("stopped on wrong goroutine %q", id)
+		}
+		if labels := p.SelectedGoroutine().Labels(); labels["request-id"] != "abc123" {
+			t.Fatalf("wrong goroutine labels %v", labels)
+		}
+
+		err = proc.Continue(p)
+		if _, exited := err.(proc.ProcessExitedError); !exited {
+			t.Fatalf("expected process exit, got %v", err)
+		}
+	})
+}
+
 func TestIssue356(t *testing.T) {
 	// slice with a typedef does not get printed correctly
 	protest.AllowRecording(t)
@@ -1861,6 +2086,26 @@ func TestPanicBreakpoint(t *testing.T) {
 		if bp.Breakpoint == nil || bp.Name != proc.UnrecoveredPanic {
 			t.Fatalf("not on unrecovered-panic breakpoint: %v", bp)
 		}
+		frames, err := proc.ThreadStacktrace(p.CurrentThread(), 20)
+		assertNoError(err, t, "ThreadStacktrace")
+		idx := proc.PanicFrame(frames)
+		if idx < 0 {
+			t.Fatalf("could not find panic frame")
+		}
+		if frame := frames[idx]; frame.Call.Fn == nil || frame.Call.Fn.Name != "main.main" || frame.Call.Line != 5 {
+			t.Fatalf("wrong panic frame %s:%d", frame.Call.File, frame.Call.Line)
+		}
+	})
+}
+
+func TestFatalThrowBreakpoint(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testdeadlock", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue()")
+		bp := p.CurrentThread().Breakpoint()
+		if bp.Breakpoint == nil || bp.Name != proc.FatalThrow {
+			t.Fatalf("not on fatal-throw breakpoint: %v", bp)
+		}
 	})
 }
 
@@ -2630,7 +2875,7 @@ func BenchmarkTrace(b *testing.B) {
 			assertNoError(proc.Continue(p), b, "Continue()")
 			s, err := proc.GoroutineScope(p.CurrentThread())
 			assertNoError(err, b, "Scope()")
-			_, err = s.FunctionArguments(proc.LoadConfig{false, 0, 64, 0, 3})
+			_, err = s.FunctionArguments(proc.LoadConfig{MaxStringLen: 64, MaxStructFields: 3})
 			assertNoError(err, b, "FunctionArguments()")
 		}
 		b.StopTimer()
@@ -3356,6 +3601,49 @@ func TestCgoSources(t *testing.T) {
 	})
 }
 
+func TestCgoThreadLocalVariables(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("thread-local variables are only supported on linux")
+	}
+	protest.AllowRecording(t)
+	withTestProcess("cgotls", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue()")
+
+		evalTLS := func(th proc.Thread) int64 {
+			scope, err := proc.ThreadScope(th)
+			assertNoError(err, t, "ThreadScope()")
+			v, err := scope.EvalVariable("C.tlsvar", normalLoadConfig)
+			assertNoError(err, t, "EvalVariable(C.tlsvar)")
+			if v.Unreadable != nil {
+				t.Fatalf("C.tlsvar unreadable on thread %d: %v", th.ThreadID(), v.Unreadable)
+			}
+			n, _ := constant.Int64Val(v.Value)
+			return n
+		}
+
+		found := map[int64]bool{}
+		var spinning proc.Thread
+		for _, th := range p.ThreadList() {
+			n := evalTLS(th)
+			found[n] = true
+			if n == 2 {
+				spinning = th
+			}
+		}
+		if !found[1] || !found[2] {
+			t.Fatalf("values of C.tlsvar on the threads of the target: %v", found)
+		}
+
+		assertNoError(setVariable(p, "C.tlsvar", "3"), t, "SetVariable(C.tlsvar)")
+		if n := evalTLS(p.CurrentThread()); n != 3 {
+			t.Errorf("wrong value of C.tlsvar after setting it: %d", n)
+		}
+		if n := evalTLS(spinning); n != 2 {
+			t.Errorf("C.tlsvar changed on a different thread: %d", n)
+		}
+	})
+}
+
 func TestSystemstackStacktrace(t *testing.T) {
 	// check that we can follow a stack switch initiated by runtime.systemstack()
 	withTestProcess("panic", t, func(p proc.Process, fixture protest.Fixture) {
@@ -3375,6 +3663,26 @@ func TestSystemstackStacktrace(t *testing.T) {
 	})
 }
 
+func TestSystemStacktrace(t *testing.T) {
+	// check that SystemStacktrace unwinds the system stack up to the call to
+	// runtime.systemstack before switching to the goroutine stack
+	withTestProcess("panic", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "runtime.startpanic_m")
+		assertNoError(err, t, "setFunctionBreakpoint()")
+		assertNoError(proc.Continue(p), t, "first continue")
+		assertNoError(proc.Continue(p), t, "second continue")
+		g, err := proc.GetG(p.CurrentThread())
+		assertNoError(err, t, "GetG")
+		frames, err := g.SystemStacktrace(100, false)
+		assertNoError(err, t, "SystemStacktrace")
+		logStacktrace(t, p.BinInfo(), frames)
+		m := stacktraceCheck(t, []string{"!runtime.startpanic_m", "!runtime.systemstack", "runtime.gopanic", "main.main"}, frames)
+		if m == nil {
+			t.Fatal("see previous loglines")
+		}
+	})
+}
+
 func TestSystemstackOnRuntimeNewstack(t *testing.T) {
 	// The bug being tested here manifests as follows:
 	// - set a breakpoint somewhere or interrupt the program with Ctrl-C
@@ -3546,6 +3854,38 @@ func TestIssue1145(t *testing.T) {
 	})
 }
 
+func TestInternalBreakpointsOrigin(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testnextprog", t, func(p proc.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 34)
+		_, err := setFunctionBreakpoint(p, "main.helloworld")
+		assertNoError(err, t, "setFunctionBreakpoint()")
+		assertNoError(proc.Continue(p), t, "Continue()")
+		// interrupted by the breakpoint on main.helloworld
+		assertNoError(proc.Next(p), t, "Next()")
+		assertLineNumber(p, t, 14, "Next()")
+
+		found := false
+		for _, bp := range p.Breakpoints().M {
+			if !bp.IsInternal() {
+				continue
+			}
+			found = true
+			if bp.Origin != "next" {
+				t.Fatalf("wrong origin %q for internal breakpoint at %#x", bp.Origin, bp.Addr)
+			}
+		}
+		if !found {
+			t.Fatal("no internal breakpoints after interrupted next")
+		}
+
+		assertNoError(p.ClearInternalBreakpoints(), t, "ClearInternalBreakpoints()")
+		if p.Breakpoints().HasInternalBreakpoints() {
+			t.Fatal("internal breakpoints not cleared")
+		}
+	})
+}
+
 func TestDisassembleGlobalVars(t *testing.T) {
 	withTestProcess("teststepconcurrent", t, func(p proc.Process, fixture protest.Fixture) {
 		mainfn := p.BinInfo().LookupFunc["main.main"]
@@ -3699,6 +4039,41 @@ func TestInlinedStacktraceAndVariables(t *testing.T) {
 	})
 }
 
+func TestInlinedCallBreakpoints(t *testing.T) {
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 10, -1, 0, 0, ""}) {
+		// Versions of go before 1.10 do not have DWARF information for inlined calls
+		t.Skip("inlining not supported")
+	}
+	withTestProcessArgs("testinline", t, ".", []string{}, protest.EnableInlining, func(p proc.Process, fixture protest.Fixture) {
+		pcs := p.BinInfo().InlinedCalls["main.inlineThis"]
+		if len(pcs) != 2 {
+			t.Fatalf("expected two inlined calls of main.inlineThis, got %#x", pcs)
+		}
+		for _, pc := range pcs {
+			if fn := p.BinInfo().PCToFunc(pc); fn == nil || fn.Name != "main.main" {
+				t.Fatalf("inlined call at %#x not inside main.main", pc)
+			}
+			if name := p.BinInfo().InlinedCallName(pc); name != "main.inlineThis" {
+				t.Fatalf("wrong inlined call name at %#x: %q", pc, name)
+			}
+			_, err := p.SetBreakpoint(pc, proc.UserBreakpoint, nil)
+			assertNoError(err, t, fmt.Sprintf("SetBreakpoint(%#x)", pc))
+		}
+
+		for _, line := range []int{18, 19} {
+			assertNoError(proc.Continue(p), t, "Continue")
+			frames, err := proc.ThreadStacktrace(p.CurrentThread(), 20)
+			assertNoError(err, t, "ThreadStacktrace")
+			if err := checkFrame(frames[0], "main.inlineThis", fixture.Source, 6, true); err != nil {
+				t.Fatalf("Wrong frame 0: %v", err)
+			}
+			if err := checkFrame(frames[1], "main.main", fixture.Source, line, false); err != nil {
+				t.Fatalf("Wrong frame 1: %v", err)
+			}
+		}
+	})
+}
+
 func TestInlineStep(t *testing.T) {
 	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 10, -1, 0, 0, ""}) {
 		// Versions of go before 1.10 do not have DWARF information for inlined calls
@@ -3949,3 +4324,1392 @@ func TestReadDefer(t *testing.T) {
 		}
 	})
 }
+
+func TestWatchpointsBasic(t *testing.T) {
+	if testBackend != "native" || runtime.GOOS != "linux" {
+		t.Skip("hardware watchpoints only supported on linux/native")
+	}
+
+	withTestProcess("databpeasy", t, func(p proc.Process, fixture protest.Fixture) {
+		setFunctionBreakpoint(p, "main.main")
+		assertNoError(proc.Continue(p), t, "Continue 0")
+		assertLineNumber(p, t, 11, "Continue 0") // Position 0
+
+		scope, err := proc.GoroutineScope(p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope")
+		v, err := scope.EvalVariable("globalvar1", normalLoadConfig)
+		assertNoError(err, t, "EvalVariable")
+
+		wp, err := p.SetWatchpoint(uint64(v.Addr), int(v.RealType.Size()), proc.WatchWrite, nil)
+		assertNoError(err, t, "SetWatchpoint")
+
+		assertNoError(proc.Continue(p), t, "Continue 1")
+		assertLineNumber(p, t, 14, "Continue 1") // Position 1
+		if bpstate := p.CurrentThread().Breakpoint(); bpstate.Breakpoint != wp || bpstate.WatchAddr != uint64(v.Addr) {
+			t.Fatalf("wrong breakpoint state %v (watch address %#x)", bpstate.Breakpoint, bpstate.WatchAddr)
+		}
+
+		_, err = p.ClearBreakpoint(wp.Addr)
+		assertNoError(err, t, "ClearBreakpoint")
+
+		setFileBreakpoint(p, t, fixture, 18)
+		assertNoError(proc.Continue(p), t, "Continue 2")
+		assertLineNumber(p, t, 18, "Continue 2") // Position 3
+	})
+}
+
+func TestHardwareBreakpoint(t *testing.T) {
+	if testBackend != "native" || runtime.GOOS != "linux" {
+		t.Skip("hardware breakpoints only supported on linux/native")
+	}
+
+	withTestProcess("databpeasy", t, func(p proc.Process, fixture protest.Fixture) {
+		addr, _, err := p.BinInfo().LineToPC(fixture.Source, 15)
+		assertNoError(err, t, "LineToPC")
+		bp, err := proc.SetHardwareBreakpoint(p, addr, nil)
+		assertNoError(err, t, "SetHardwareBreakpoint")
+		if !bp.WatchType.Execute() {
+			t.Fatalf("wrong watch type %v", bp.WatchType)
+		}
+
+		assertNoError(proc.Continue(p), t, "Continue 1")
+		assertLineNumber(p, t, 15, "Continue 1")
+		if bpstate := p.CurrentThread().Breakpoint(); bpstate.Breakpoint != bp {
+			t.Fatalf("wrong breakpoint state %v", bpstate.Breakpoint)
+		}
+
+		setFileBreakpoint(p, t, fixture, 18)
+		assertNoError(proc.Continue(p), t, "Continue 2")
+		assertLineNumber(p, t, 18, "Continue 2")
+	})
+}
+
+func TestLogpoint(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("bpcountstest", t, func(p proc.Process, fixture protest.Fixture) {
+		addr, _, err := p.BinInfo().LineToPC(fixture.Source, 12)
+		assertNoError(err, t, "LineToPC")
+		bp, err := p.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+		assertNoError(err, t, "SetBreakpoint()")
+		bp.LogMessage = "i = {i}"
+
+		hits := 0
+		p.Common().SetLogpointFunc(func(thread proc.Thread, lbp *proc.Breakpoint) {
+			if lbp != bp {
+				t.Fatalf("wrong logpoint %v", lbp)
+			}
+			hits++
+		})
+
+		err = proc.Continue(p)
+		if _, exited := err.(proc.ProcessExitedError); !exited {
+			t.Fatalf("expected process to exit without stopping, got %v", err)
+		}
+
+		if hits != 200 || bp.TotalHitCount != 200 {
+			t.Fatalf("wrong number of logpoint hits %d (TotalHitCount %d)", hits, bp.TotalHitCount)
+		}
+	})
+}
+
+func TestBreakpointRecordStack(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("bpcountstest", t, func(p proc.Process, fixture protest.Fixture) {
+		addr, _, err := p.BinInfo().LineToPC(fixture.Source, 12)
+		assertNoError(err, t, "LineToPC")
+		bp, err := p.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+		assertNoError(err, t, "SetBreakpoint()")
+		bp.RecordStack = 2
+
+		err = proc.Continue(p)
+		if _, exited := err.(proc.ProcessExitedError); !exited {
+			t.Fatalf("expected process to exit without stopping, got %v", err)
+		}
+
+		recs := bp.TakeStackRecords()
+		if len(recs) != 200 {
+			t.Fatalf("wrong number of stack records %d", len(recs))
+		}
+		for _, rec := range recs {
+			if len(rec.Stack) < 2 || rec.Stack[0].Fn == nil || rec.Stack[0].Fn.Name != "main.demo" || rec.Stack[0].Line != 12 {
+				t.Fatalf("wrong stack record %#v", rec)
+			}
+		}
+		if recs := bp.TakeStackRecords(); len(recs) != 0 {
+			t.Fatalf("stack records not cleared: %d", len(recs))
+		}
+	})
+}
+
+func TestBreakpointActions(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("bpcountstest", t, func(p proc.Process, fixture protest.Fixture) {
+		addr, _, err := p.BinInfo().LineToPC(fixture.Source, 12)
+		assertNoError(err, t, "LineToPC")
+		bp, err := p.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+		assertNoError(err, t, "SetBreakpoint()")
+		addr2, _, err := p.BinInfo().LineToPC(fixture.Source, 13)
+		assertNoError(err, t, "LineToPC")
+		bp2, err := p.SetBreakpoint(addr2, proc.UserBreakpoint, nil)
+		assertNoError(err, t, "SetBreakpoint()")
+		assertNoError(p.SetBreakpointsDisabled([]*proc.Breakpoint{bp2}, true), t, "SetBreakpointsDisabled()")
+
+		bp.Actions = []proc.BreakpointAction{
+			{Kind: proc.EvalAction, Expr: "i"},
+			{Kind: proc.StackAction, Depth: 5},
+			{Kind: proc.EnableAction, BreakpointID: bp2.ID},
+			{Kind: proc.ContinueAction},
+		}
+
+		hits := 0
+		p.Common().SetBreakpointActionsFunc(func(thread proc.Thread, abp *proc.Breakpoint, results []proc.BreakpointActionResult) {
+			if abp != bp {
+				t.Fatalf("wrong breakpoint %v", abp)
+			}
+			if len(results) != 3 {
+				t.Fatalf("wrong number of results %d", len(results))
+			}
+			for _, result := range results {
+				assertNoError(result.Err, t, "breakpoint action")
+			}
+			if results[0].Variable == nil || results[0].Variable.Name != "i" {
+				t.Fatalf("wrong eval result %v", results[0].Variable)
+			}
+			if len(results[1].Stack) == 0 {
+				t.Fatalf("empty stacktrace")
+			}
+			hits++
+		})
+
+		assertNoError(proc.Continue(p), t, "Continue()")
+		assertLineNumber(p, t, 13, "Continue()")
+		if hits == 0 {
+			t.Fatalf("breakpoint actions not executed")
+		}
+		if bp2.Disabled {
+			t.Fatalf("breakpoint not enabled by action")
+		}
+	})
+}
+
+func TestSetFunctionBreakpointsRegexp(t *testing.T) {
+	withTestProcess("teststep", t, func(p proc.Process, fixture protest.Fixture) {
+		bps, err := proc.SetFunctionBreakpointsRegexp(p, regexp.MustCompile(`^main\.call`), nil)
+		assertNoError(err, t, "SetFunctionBreakpointsRegexp")
+		if len(bps) != 1 || bps[0].FunctionName != "main.callme" {
+			t.Fatalf("wrong breakpoints %v", bps)
+		}
+		assertNoError(proc.Continue(p), t, "Continue") // runtime.Breakpoint
+		assertNoError(proc.Continue(p), t, "Continue")
+		if bp := p.CurrentThread().Breakpoint(); bp.Breakpoint != bps[0] {
+			t.Fatalf("wrong breakpoint %v", bp.Breakpoint)
+		}
+
+		if _, err := proc.SetFunctionBreakpointsRegexp(p, regexp.MustCompile(`^main\.nonexistent`), nil); err == nil {
+			t.Fatalf("expected error for regexp not matching any function")
+		}
+	})
+}
+
+func TestFunctionExitBreakpoints(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("stepoutret", t, func(p proc.Process, fixture protest.Fixture) {
+		bps, err := proc.SetFunctionExitBreakpoints(p, "main.stepout", nil)
+		assertNoError(err, t, "SetFunctionExitBreakpoints")
+		assertNoError(proc.Continue(p), t, "Continue")
+		if bp := p.CurrentThread().Breakpoint(); bp.Breakpoint == nil || !bp.OnReturn {
+			t.Fatalf("not stopped at a function exit breakpoint %v (%v)", bp.Breakpoint, bps)
+		}
+		ret := p.CurrentThread().Common().ReturnValues(normalLoadConfig)
+		if len(ret) != 2 {
+			t.Fatalf("wrong number of return values %v", ret)
+		}
+		if ret[0].Name != "str" || constant.StringVal(ret[0].Value) != "return 47" {
+			t.Fatalf("(str) bad return value %s = %v", ret[0].Name, ret[0].Value)
+		}
+		if n, _ := constant.Int64Val(ret[1].Value); ret[1].Name != "num" || n != 48 {
+			t.Fatalf("(num) bad return value %s = %v", ret[1].Name, ret[1].Value)
+		}
+	})
+}
+
+func TestBreakpointSuspendThread(t *testing.T) {
+	if testBackend != "native" || runtime.GOOS != "linux" {
+		t.Skip("SuspendThread policy only supported on linux/native")
+	}
+	withTestProcess("bpcountstest", t, func(p proc.Process, fixture protest.Fixture) {
+		addr, _, err := p.BinInfo().LineToPC(fixture.Source, 12)
+		assertNoError(err, t, "LineToPC")
+		bp, err := p.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+		assertNoError(err, t, "SetBreakpoint()")
+		bp.Suspend = proc.SuspendThread
+
+		for {
+			if err := proc.Continue(p); err != nil {
+				if _, exited := err.(proc.ProcessExitedError); exited {
+					break
+				}
+				assertNoError(err, t, "Continue()")
+			}
+			if curbp := p.CurrentThread().Breakpoint(); curbp.Breakpoint != bp {
+				t.Fatalf("stopped at wrong breakpoint %v", curbp.Breakpoint)
+			}
+		}
+
+		if bp.TotalHitCount != 200 {
+			t.Fatalf("Wrong TotalHitCount for the breakpoint (%d)", bp.TotalHitCount)
+		}
+	})
+}
+
+func TestChannelCatchpoint(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("chancatch", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue()")
+		ch1 := evalVariable(p, t, "ch1")
+		_, err := proc.SetChannelCatchpoint(p, uint64(ch1.Base), proc.ChanSend|proc.ChanRecv|proc.ChanClose)
+		assertNoError(err, t, "SetChannelCatchpoint")
+		for _, fnName := range []string{"runtime.chansend", "runtime.chanrecv", "runtime.closechan"} {
+			assertNoError(proc.Continue(p), t, "Continue()")
+			loc, err := p.CurrentThread().Location()
+			assertNoError(err, t, "Location()")
+			if loc.Fn == nil || loc.Fn.Name != fnName {
+				t.Fatalf("expected stop in %s, got %v", fnName, loc)
+			}
+		}
+	})
+}
+
+func TestNativeCheckpoints(t *testing.T) {
+	if runtime.GOOS != "linux" || testBackend != "native" {
+		t.Skip("native checkpoints are only supported on linux")
+	}
+	withTestProcess("continuetestprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.main")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+		_, line0 := currentLineNumber(p, t)
+
+		cpid, err := p.Checkpoint("checkpoint1")
+		assertNoError(err, t, "Checkpoint")
+		checkpoints, err := p.Checkpoints()
+		assertNoError(err, t, "Checkpoints")
+		if len(checkpoints) != 1 || checkpoints[0].ID != cpid || checkpoints[0].Where != "checkpoint1" {
+			t.Fatalf("wrong checkpoints %v", checkpoints)
+		}
+
+		assertNoError(proc.Next(p), t, "Next")
+		assertNoError(proc.Next(p), t, "Next")
+		if _, line1 := currentLineNumber(p, t); line1 == line0 {
+			t.Fatalf("next did not move the process")
+		}
+
+		assertNoError(p.Restart(fmt.Sprintf("c%d", cpid)), t, "Restart")
+		if _, line2 := currentLineNumber(p, t); line2 != line0 {
+			t.Fatalf("wrong line after restoring checkpoint, expected %d got %d", line0, line2)
+		}
+		assertNoError(proc.Next(p), t, "Next")
+		assertNoError(proc.Next(p), t, "Next")
+
+		assertNoError(p.ClearCheckpoint(cpid), t, "ClearCheckpoint")
+		checkpoints, err = p.Checkpoints()
+		assertNoError(err, t, "Checkpoints")
+		if len(checkpoints) != 0 {
+			t.Fatalf("checkpoint not cleared %v", checkpoints)
+		}
+	})
+}
+
+func TestStepIntoTarget(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("stepintotarget", t, func(p proc.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 18)
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		tgts, err := proc.StepIntoTargets(p)
+		assertNoError(err, t, "StepIntoTargets")
+		names := []string{}
+		for _, tgt := range tgts {
+			names = append(names, tgt.Fn.Name)
+		}
+		if len(names) != 3 || names[0] != "main.g" || names[1] != "main.h" || names[2] != "main.f" {
+			t.Fatalf("wrong step into targets %v", names)
+		}
+
+		assertNoError(proc.StepInto(p, tgts[1].CallPC), t, "StepInto")
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "main.h" || loc.Line != 10 {
+			t.Fatalf("wrong location after StepInto %s:%d", loc.File, loc.Line)
+		}
+	})
+}
+
+func TestNextInstruction(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("stepintotarget", t, func(p proc.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 18)
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		// step until the first call of the line
+		var call proc.AsmInstruction
+		for {
+			pc := currentPC(p, t)
+			text, err := proc.Disassemble(p, nil, pc, pc+maxInstructionLength)
+			assertNoError(err, t, "Disassemble")
+			if text[0].IsCall() {
+				call = text[0]
+				break
+			}
+			assertNoError(p.StepInstruction(), t, "StepInstruction")
+		}
+
+		assertNoError(proc.NextInstruction(p), t, "NextInstruction")
+		if pc := currentPC(p, t); pc != call.Loc.PC+uint64(len(call.Bytes)) {
+			t.Fatalf("wrong PC after NextInstruction %#x (call at %#x)", pc, call.Loc.PC)
+		}
+		if f, ln := currentLineNumber(p, t); ln != 18 {
+			t.Fatalf("wrong line after NextInstruction %s:%d", f, ln)
+		}
+	})
+}
+
+func TestUntil(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testnextprog", t, func(p proc.Process, fixture protest.Fixture) {
+		bp := setFileBreakpoint(p, t, fixture, 31)
+		assertNoError(proc.Continue(p), t, "Continue")
+		_, err := p.ClearBreakpoint(bp.Addr)
+		assertNoError(err, t, "ClearBreakpoint")
+		assertNoError(proc.Until(p), t, "Until")
+		if f, ln := currentLineNumber(p, t); ln != 34 {
+			t.Fatalf("wrong location after Until %s:%d, expected line 34", f, ln)
+		}
+	})
+}
+
+func TestStepFilters(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("stepintotarget", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(p.Common().SetStepFilters([]string{"main.g", "*/nonexistent/*"}), t, "SetStepFilters")
+		setFileBreakpoint(p, t, fixture, 18)
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		tgts, err := proc.StepIntoTargets(p)
+		assertNoError(err, t, "StepIntoTargets")
+		for _, tgt := range tgts {
+			if tgt.Fn.Name == "main.g" {
+				t.Fatalf("filtered function main.g returned by StepIntoTargets")
+			}
+		}
+
+		assertNoError(proc.Step(p), t, "Step")
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "main.h" {
+			t.Fatalf("wrong location after Step %s:%d", loc.File, loc.Line)
+		}
+	})
+}
+
+func TestContinueUntil(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testnextprog", t, func(p proc.Process, fixture protest.Fixture) {
+		bp := setFileBreakpoint(p, t, fixture, 24)
+		addr, err := proc.FindFileLocation(p, fixture.Source, 34)
+		assertNoError(err, t, "FindFileLocation")
+
+		// the breakpoint is hit before reaching the location
+		assertNoError(proc.ContinueUntil(p, []uint64{addr}), t, "ContinueUntil")
+		assertLineNumber(p, t, 24, "wrong line after ContinueUntil")
+		if p.Breakpoints().HasInternalBreakpoints() {
+			t.Fatal("internal breakpoints not cleared")
+		}
+
+		_, err = p.ClearBreakpoint(bp.Addr)
+		assertNoError(err, t, "ClearBreakpoint")
+		assertNoError(proc.ContinueUntil(p, []uint64{addr}), t, "ContinueUntil")
+		assertLineNumber(p, t, 34, "wrong line after ContinueUntil")
+		if p.Breakpoints().HasInternalBreakpoints() {
+			t.Fatal("internal breakpoints not cleared")
+		}
+	})
+}
+
+func TestNextDeferMode(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testnextdefer", t, func(p proc.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 10)
+		assertNoError(proc.Continue(p), t, "Continue")
+		assertNoError(proc.NextDefer(p, proc.DeferSkip), t, "NextDefer")
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn != nil && loc.Fn.Name == "main.main.func1" {
+			t.Fatalf("stopped inside deferred function %s:%d", loc.File, loc.Line)
+		}
+	})
+	withTestProcess("testnextdefer", t, func(p proc.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 10)
+		assertNoError(proc.Continue(p), t, "Continue")
+		assertNoError(proc.NextDefer(p, proc.DeferStopAtEntry), t, "NextDefer")
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "main.main.func1" || loc.PC != loc.Fn.Entry {
+			t.Fatalf("not stopped at the entry point of the deferred function %#x %s:%d", loc.PC, loc.File, loc.Line)
+		}
+	})
+}
+
+func TestResumeSelected(t *testing.T) {
+	if runtime.GOOS != "linux" || testBackend != "native" {
+		t.Skip("resume modes are only supported by the native backend on linux")
+	}
+	withTestProcess("parallel_next", t, func(p proc.Process, fixture protest.Fixture) {
+		bp, err := setFunctionBreakpoint(p, "main.sayhi")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+		_, err = p.ClearBreakpoint(bp.Addr)
+		assertNoError(err, t, "ClearBreakpoint")
+
+		p.Common().SetResumeMode(proc.ResumeSelected)
+		curtid := p.CurrentThread().ThreadID()
+		pcs := map[int]uint64{}
+		for _, th := range p.ThreadList() {
+			regs, err := th.Registers(false)
+			assertNoError(err, t, "Registers")
+			pcs[th.ThreadID()] = regs.PC()
+		}
+
+		assertNoError(proc.Next(p), t, "Next")
+		assertLineNumber(p, t, 10, "wrong line after Next")
+		if tid := p.CurrentThread().ThreadID(); tid != curtid {
+			t.Fatalf("stopped on a different thread %d (expected %d)", tid, curtid)
+		}
+		for _, th := range p.ThreadList() {
+			if th.ThreadID() == curtid {
+				continue
+			}
+			regs, err := th.Registers(false)
+			assertNoError(err, t, "Registers")
+			if pc, ok := pcs[th.ThreadID()]; ok && pc != regs.PC() {
+				t.Errorf("thread %d was resumed (%#x -> %#x)", th.ThreadID(), pc, regs.PC())
+			}
+		}
+	})
+}
+
+func TestRepeatStep(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testnextprog", t, func(p proc.Process, fixture protest.Fixture) {
+		bp := setFileBreakpoint(p, t, fixture, 17)
+		assertNoError(proc.Continue(p), t, "Continue")
+		_, err := p.ClearBreakpoint(bp.Addr)
+		assertNoError(err, t, "ClearBreakpoint")
+
+		progress := []int{}
+		n, err := proc.RepeatStep(p, 4, proc.Next, func(n int) { progress = append(progress, n) })
+		assertNoError(err, t, "RepeatStep")
+		if n != 4 || len(progress) != 4 {
+			t.Fatalf("wrong number of steps %d (progress %v)", n, progress)
+		}
+		assertLineNumber(p, t, 24, "wrong line after RepeatStep")
+
+		// a breakpoint interrupts the sequence of steps
+		setFileBreakpoint(p, t, fixture, 31)
+		n, err = proc.RepeatStep(p, 10, proc.Next, nil)
+		assertNoError(err, t, "RepeatStep")
+		if n != 2 {
+			t.Fatalf("wrong number of steps %d, expected 2", n)
+		}
+		assertLineNumber(p, t, 31, "wrong line after interrupted RepeatStep")
+	})
+}
+
+func TestBlackbox(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("stepintotarget", t, func(p proc.Process, fixture protest.Fixture) {
+		p.Common().SetBlackbox([]string{"main.g", "main.h"})
+		setFileBreakpoint(p, t, fixture, 18)
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		assertNoError(proc.Step(p), t, "Step")
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "main.f" {
+			t.Fatalf("wrong location after Step %s:%d", loc.File, loc.Line)
+		}
+	})
+}
+
+func TestStepToReturn(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("steptoreturn", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.divide")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+		assertNoError(proc.StepToReturn(p), t, "StepToReturn")
+
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "main.divide" {
+			t.Fatalf("wrong location after StepToReturn %s:%d", loc.File, loc.Line)
+		}
+
+		ret := p.CurrentThread().Common().ReturnValues(normalLoadConfig)
+		if len(ret) != 2 {
+			t.Fatalf("wrong number of return values %v", ret)
+		}
+		// the deferred function has already run
+		if n, _ := constant.Int64Val(ret[0].Value); ret[0].Name != "q" || n != -1 {
+			t.Fatalf("bad return value %s = %v", ret[0].Name, ret[0].Value)
+		}
+		if ret[1].Name != "err" || ret[1].Kind != reflect.Interface || len(ret[1].Children) != 1 {
+			t.Fatalf("bad return value %s (%v)", ret[1].Name, ret[1].Kind)
+		}
+		if typ := ret[1].Children[0].TypeString(); !strings.Contains(typ, "errorString") {
+			t.Fatalf("wrong dynamic type of err %s", typ)
+		}
+	})
+}
+
+func TestContinueToRecover(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("recoverpoint", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.run")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+		if _, err := proc.FindRecoverPoint(p); err == nil {
+			t.Fatal("FindRecoverPoint succeeded on a goroutine that is not panicking")
+		}
+
+		bp, err := setFunctionBreakpoint(p, "runtime.gopanic")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+		_, err = p.ClearBreakpoint(bp.Addr)
+		assertNoError(err, t, "ClearBreakpoint")
+
+		rp, err := proc.FindRecoverPoint(p)
+		assertNoError(err, t, "FindRecoverPoint")
+		if rp.Fn.Name != "main.handler" {
+			t.Fatalf("wrong recover point %s", rp.Fn.Name)
+		}
+		assertNoError(proc.ContinueToRecover(p), t, "ContinueToRecover")
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "main.handler" || loc.Line != 10 {
+			t.Fatalf("wrong location after ContinueToRecover %s:%d", loc.File, loc.Line)
+		}
+	})
+}
+
+func TestInlineNextBody(t *testing.T) {
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 10, -1, 0, 0, ""}) {
+		// Versions of go before 1.10 do not have DWARF information for inlined calls
+		t.Skip("inlining not supported")
+	}
+	protest.AllowRecording(t)
+	withTestProcessArgs("testinlinebody", t, ".", []string{}, protest.EnableInlining, func(p proc.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 17)
+		assertNoError(proc.Continue(p), t, "Continue")
+		assertNoError(proc.Step(p), t, "Step")
+		assertLineNumber(p, t, 9, "wrong line after Step")
+
+		// next must go through the lines of the inlined call, in order,
+		// without stopping on the lines of the caller.
+		for _, ln := range []int{10, 11, 12} {
+			assertNoError(proc.Next(p), t, "Next")
+			assertLineNumber(p, t, ln, "wrong line after Next inside inlined call")
+		}
+		assertNoError(proc.Next(p), t, "Next")
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "main.main" || (loc.Line != 17 && loc.Line != 18) {
+			t.Fatalf("wrong location after leaving inlined call %s:%d", loc.File, loc.Line)
+		}
+
+		// the result of the inlined call in main.wrap is returned directly,
+		// next stops where main.wrap returns.
+		setFileBreakpoint(p, t, fixture, 24)
+		assertNoError(proc.Continue(p), t, "Continue")
+		assertNoError(proc.Step(p), t, "Step")
+		assertLineNumber(p, t, 9, "wrong line after Step")
+		for _, ln := range []int{10, 11, 12} {
+			assertNoError(proc.Next(p), t, "Next")
+			assertLineNumber(p, t, ln, "wrong line after Next inside inlined call")
+		}
+		assertNoError(proc.Next(p), t, "Next")
+		loc, err = p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || (loc.Fn.Name != "main.wrap" && loc.Fn.Name != "main.main") {
+			t.Fatalf("wrong location after leaving inlined call %s:%d", loc.File, loc.Line)
+		}
+	})
+}
+
+func TestStepIntoRuntime(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("stepruntime", t, func(p proc.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 6)
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		// runtime.makechan is skipped by default
+		assertNoError(proc.Step(p), t, "Step")
+		assertLineNumber(p, t, 7, "wrong line after Step")
+
+		p.Common().SetStepIntoRuntime(true)
+		assertNoError(proc.Step(p), t, "Step")
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "runtime.chansend1" {
+			t.Fatalf("did not step into runtime.chansend1 %s:%d", loc.File, loc.Line)
+		}
+	})
+}
+
+func TestJump(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testnextprog", t, func(p proc.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 34)
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		// jumping outside of the current function is refused
+		pc, err := proc.FindFileLocation(p, fixture.Source, 42)
+		assertNoError(err, t, "FindFileLocation")
+		if err := proc.Jump(p, pc); err == nil {
+			t.Fatal("jump to another function succeeded")
+		}
+
+		pc, err = proc.FindFileLocation(p, fixture.Source, 31)
+		assertNoError(err, t, "FindFileLocation")
+		assertNoError(proc.Jump(p, pc), t, "Jump")
+		assertLineNumber(p, t, 31, "wrong line after Jump")
+		assertNoError(proc.Next(p), t, "Next")
+		assertLineNumber(p, t, 23, "wrong line after Next")
+	})
+}
+
+func TestContinueToGoroutine(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("goroutinestackprog", t, func(p proc.Process, fixture protest.Fixture) {
+		bp, err := setFunctionBreakpoint(p, "main.stacktraceme")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+		_, err = p.ClearBreakpoint(bp.Addr)
+		assertNoError(err, t, "ClearBreakpoint")
+
+		if err := proc.ContinueToGoroutine(p, p.SelectedGoroutine().ID); err == nil {
+			t.Fatal("ContinueToGoroutine succeeded on the selected goroutine")
+		}
+
+		gs, err := proc.GoroutinesInfo(p)
+		assertNoError(err, t, "GoroutinesInfo")
+		gid := 0
+		for _, g := range gs {
+			if loc := g.UserCurrent(); loc.Fn != nil && loc.Fn.Name == "main.agoroutine" {
+				gid = g.ID
+				break
+			}
+		}
+		if gid == 0 {
+			t.Fatal("could not find a goroutine running main.agoroutine")
+		}
+
+		assertNoError(proc.ContinueToGoroutine(p, gid), t, "ContinueToGoroutine")
+		if selg := p.SelectedGoroutine(); selg == nil || selg.ID != gid {
+			t.Fatalf("wrong goroutine selected after ContinueToGoroutine, expected %d", gid)
+		}
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "main.agoroutine" || loc.Line != 9 {
+			t.Fatalf("wrong location after ContinueToGoroutine %s:%d", loc.File, loc.Line)
+		}
+		if p.Breakpoints().HasInternalBreakpoints() {
+			t.Fatal("internal breakpoints not cleared")
+		}
+	})
+}
+
+func TestRangeStep(t *testing.T) {
+	// Lines of main.compute don't contain any call, next steps over them
+	// without setting breakpoints.
+	protest.AllowRecording(t)
+	testseq("rangestep", contNext, []nextTest{
+		{6, 7},
+		{7, 8},
+		{8, 9},
+	}, "main.compute", t)
+}
+
+func TestRangeStepBreakpoints(t *testing.T) {
+	// Breakpoints hit while next single steps over a line check their
+	// conditions and run their actions like Continue does.
+	protest.AllowRecording(t)
+	withTestProcess("rangestep", t, func(p proc.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 6)
+		bp := setFileBreakpoint(p, t, fixture, 7)
+		bp.Actions = []proc.BreakpointAction{{Kind: proc.EvalAction, Expr: "c"}}
+		condbp := setFileBreakpoint(p, t, fixture, 8)
+		condbp.Cond = &ast.Ident{Name: "false"}
+		hits := 0
+		p.Common().SetBreakpointActionsFunc(func(thread proc.Thread, abp *proc.Breakpoint, results []proc.BreakpointActionResult) {
+			if abp != bp {
+				t.Fatalf("wrong breakpoint %v", abp)
+			}
+			hits++
+		})
+
+		assertNoError(proc.Continue(p), t, "Continue()")
+		assertLineNumber(p, t, 6, "Continue()")
+		assertNoError(proc.Next(p), t, "Next()")
+		assertLineNumber(p, t, 7, "Next()")
+		if hits != 1 || bp.TotalHitCount != 1 {
+			t.Fatalf("breakpoint actions executed %d times, hit count %d", hits, bp.TotalHitCount)
+		}
+		assertNoError(proc.Next(p), t, "Next()")
+		assertLineNumber(p, t, 8, "Next()")
+		if p.CurrentThread().Breakpoint().Active || condbp.TotalHitCount != 0 {
+			t.Fatalf("breakpoint with a false condition hit")
+		}
+	})
+}
+
+func TestFilterGoroutines(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("goroutinestackprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.stacktraceme")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		filter := proc.GoroutineFilter{Function: "main.agoroutine"}
+		gs, nextg, err := proc.FilterGoroutines(p, filter, 0, 0)
+		assertNoError(err, t, "FilterGoroutines")
+		if len(gs) != 10 || nextg != -1 {
+			t.Fatalf("wrong number of goroutines %d (nextg %d)", len(gs), nextg)
+		}
+
+		// the same goroutines, three at a time
+		var paged []*proc.G
+		for start := 0; start >= 0; {
+			var page []*proc.G
+			page, start, err = proc.FilterGoroutines(p, filter, start, 3)
+			assertNoError(err, t, "FilterGoroutines")
+			if len(page) > 3 {
+				t.Fatalf("too many goroutines in page %d", len(page))
+			}
+			paged = append(paged, page...)
+		}
+		if len(paged) != len(gs) {
+			t.Fatalf("wrong number of goroutines with paging %d", len(paged))
+		}
+		for i := range gs {
+			if paged[i].ID != gs[i].ID {
+				t.Fatalf("mismatched goroutine %d: %d %d", i, paged[i].ID, gs[i].ID)
+			}
+		}
+
+		running, err := proc.GoroutineStatusByName("running")
+		assertNoError(err, t, "GoroutineStatusByName")
+		gs, _, err = proc.FilterGoroutines(p, proc.GoroutineFilter{States: []uint64{running}, Function: "main.stacktraceme"}, 0, 0)
+		assertNoError(err, t, "FilterGoroutines")
+		if len(gs) != 1 || gs[0].ID != p.SelectedGoroutine().ID {
+			t.Fatalf("wrong running goroutines %v", gs)
+		}
+
+		gs, _, err = proc.FilterGoroutines(p, proc.GoroutineFilter{Labels: map[string]string{"request-id": "abc123"}}, 0, 0)
+		assertNoError(err, t, "FilterGoroutines")
+		if len(gs) != 0 {
+			t.Fatalf("goroutines with labels found in a program without labels %v", gs)
+		}
+	})
+}
+
+func TestGroupGoroutines(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("goroutinestackprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.stacktraceme")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		groups, err := proc.GroupGoroutines(p, proc.GoroutineFilter{}, proc.GroupByUserFunc, 3)
+		assertNoError(err, t, "GroupGoroutines")
+		if len(groups) == 0 || groups[0].Name != "main.agoroutine" || groups[0].Count != 10 || len(groups[0].IDs) != 3 {
+			t.Fatalf("wrong first group %#v", groups)
+		}
+		for i := 1; i < len(groups); i++ {
+			if groups[i].Count > groups[i-1].Count {
+				t.Fatalf("groups not sorted by size %#v", groups)
+			}
+		}
+
+		groups, err = proc.GroupGoroutines(p, proc.GoroutineFilter{Function: "main.agoroutine"}, proc.GroupByWaitReason, -1)
+		assertNoError(err, t, "GroupGoroutines")
+		total := 0
+		for _, group := range groups {
+			if len(group.IDs) != group.Count {
+				t.Fatalf("wrong number of IDs in group %#v", group)
+			}
+			total += group.Count
+		}
+		if total != 10 {
+			t.Fatalf("wrong number of goroutines grouped by wait reason %d", total)
+		}
+	})
+}
+
+func TestStacktraces(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("goroutinestackprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.stacktraceme")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		stacks, err := proc.Stacktraces(p, proc.GoroutineFilter{}, 20, false)
+		assertNoError(err, t, "Stacktraces")
+		gs, err := proc.GoroutinesInfo(p)
+		assertNoError(err, t, "GoroutinesInfo")
+		if len(stacks) != len(gs) {
+			t.Fatalf("wrong number of stacktraces %d, expected %d", len(stacks), len(gs))
+		}
+		for _, stack := range stacks {
+			if stack.Err != nil {
+				continue
+			}
+			frames, err := stack.G.Stacktrace(20, false)
+			assertNoError(err, t, "Stacktrace")
+			if len(frames) != len(stack.Frames) {
+				t.Fatalf("goroutine %d: wrong number of frames %d, expected %d", stack.G.ID, len(stack.Frames), len(frames))
+			}
+			for i := range frames {
+				if frames[i].Current != stack.Frames[i].Current || frames[i].Call != stack.Frames[i].Call {
+					t.Fatalf("goroutine %d: frame %d mismatch %v %v", stack.G.ID, i, frames[i].Current, stack.Frames[i].Current)
+				}
+			}
+		}
+	})
+}
+
+func TestGoroutineWaitReason(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("goroutinestackprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.stacktraceme")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		gs, _, err := proc.FilterGoroutines(p, proc.GoroutineFilter{Function: "main.agoroutine"}, 0, 0)
+		assertNoError(err, t, "FilterGoroutines")
+		if len(gs) != 10 {
+			t.Fatalf("wrong number of goroutines %d", len(gs))
+		}
+		for _, g := range gs {
+			if !g.Waiting() || g.WaitReason != "chan send" {
+				t.Errorf("goroutine %d: wrong wait reason %q (status %d)", g.ID, g.WaitReason, g.Status)
+			}
+			if d := g.WaitDuration(g.WaitSince - 1); d != 0 {
+				t.Errorf("goroutine %d: wait duration %v for a time before it was parked", g.ID, d)
+			}
+		}
+	})
+}
+
+func TestDetectDeadlock(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testdeadlock", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue()")
+		dl, err := proc.DetectDeadlock(p)
+		assertNoError(err, t, "DetectDeadlock")
+		if dl == nil {
+			t.Fatal("deadlock not detected")
+		}
+		if len(dl.Goroutines) != 1 {
+			t.Fatalf("wrong number of blocked goroutines %d", len(dl.Goroutines))
+		}
+		bg := dl.Goroutines[0]
+		if bg.G.ID != 1 || bg.Reason != "chan receive" {
+			t.Errorf("wrong blocked goroutine %d %q", bg.G.ID, bg.Reason)
+		}
+		if len(bg.Chans) != 1 || bg.Chans[0] == 0 {
+			t.Errorf("wrong channels %#x", bg.Chans)
+		}
+	})
+}
+
+func TestDetectDeadlockTimers(t *testing.T) {
+	// Goroutines waiting on a timer or on a signal are not deadlocked.
+	withTestProcess("testdeadlocktimers", t, func(p proc.Process, fixture protest.Fixture) {
+		go func() {
+			time.Sleep(time.Second)
+			p.RequestManualStop()
+		}()
+		assertNoError(proc.Continue(p), t, "Continue()")
+		dl, err := proc.DetectDeadlock(p)
+		assertNoError(err, t, "DetectDeadlock")
+		if dl != nil {
+			t.Fatalf("deadlock detected %v", dl.Goroutines)
+		}
+	})
+}
+
+func TestRunGoroutine(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("goroutinestackprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.stacktraceme")
+		assertNoError(err, t, "setFunctionBreakpoint()")
+		assertNoError(proc.Continue(p), t, "Continue()")
+
+		gs, _, err := proc.FilterGoroutines(p, proc.GoroutineFilter{Function: "main.agoroutine"}, 0, 1)
+		assertNoError(err, t, "FilterGoroutines")
+		if len(gs) != 1 || gs[0].Thread != nil {
+			t.Fatalf("could not find a parked goroutine: %v", gs)
+		}
+		gid := gs[0].ID
+
+		assertNoError(proc.RunGoroutine(p, gid), t, "RunGoroutine")
+		selg := p.SelectedGoroutine()
+		if selg == nil || selg.ID != gid || selg.Thread == nil {
+			t.Fatalf("goroutine %d is not running: %#v", gid, selg)
+		}
+		if loc := selg.UserCurrent(); loc.Fn == nil || loc.Fn.Name != "main.agoroutine" {
+			t.Fatalf("goroutine %d stopped at the wrong location %#v", gid, loc)
+		}
+	})
+}
+
+func TestWaiters(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("waitersprog", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue()")
+		scope, err := proc.ConvertEvalScope(p, -1, 1)
+		assertNoError(err, t, "ConvertEvalScope")
+
+		for _, tc := range []struct {
+			expr string
+			op   string
+			n    int
+		}{
+			{"ch", "chan send", 3},
+			{"mu", "lock", 2},
+			{"&mu", "lock", 2},
+		} {
+			v, err := scope.EvalVariable(tc.expr, normalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", tc.expr))
+			waiters, err := proc.Waiters(p, v)
+			assertNoError(err, t, fmt.Sprintf("Waiters(%s)", tc.expr))
+			if len(waiters) != tc.n {
+				t.Errorf("%s: wrong number of waiters %d (expected %d)", tc.expr, len(waiters), tc.n)
+			}
+			for _, w := range waiters {
+				if w.Op != tc.op {
+					t.Errorf("%s: goroutine %d: wrong operation %q", tc.expr, w.G.ID, w.Op)
+				}
+			}
+		}
+	})
+}
+
+func TestMutexInfo(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("waitersprog", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue()")
+		scope, err := proc.ConvertEvalScope(p, -1, 1)
+		assertNoError(err, t, "ConvertEvalScope")
+		v, err := scope.EvalVariable("mu", normalLoadConfig)
+		assertNoError(err, t, "EvalVariable(mu)")
+
+		ms, err := proc.MutexInfo(p, v)
+		assertNoError(err, t, "MutexInfo")
+		t.Logf("%#v", ms)
+		if !ms.Locked || ms.Addr != uint64(v.Addr) {
+			t.Fatalf("wrong mutex state %#v", ms)
+		}
+		if len(ms.Holders) != 1 || ms.Holders[0].ID != p.SelectedGoroutine().ID {
+			t.Errorf("wrong holders %v", ms.Holders)
+		}
+
+		waiters, err := proc.Waiters(p, v)
+		assertNoError(err, t, "Waiters")
+		if len(waiters) == 0 {
+			t.Fatal("no waiters")
+		}
+		bv, err := proc.BlockingMutex(p, waiters[0].G)
+		assertNoError(err, t, "BlockingMutex")
+		if bv.Kind != reflect.Ptr || len(bv.Children) != 1 || bv.Children[0].Addr != v.Addr {
+			t.Errorf("wrong blocking mutex %#v", bv)
+		}
+	})
+}
+
+func TestSystemGoroutines(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("goroutinestackprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.stacktraceme")
+		assertNoError(err, t, "setFunctionBreakpoint()")
+		assertNoError(proc.Continue(p), t, "Continue()")
+
+		gs, err := proc.GoroutinesInfo(p)
+		assertNoError(err, t, "GoroutinesInfo")
+		nsystem := 0
+		for _, g := range gs {
+			startfn := g.StartLoc().Fn
+			if startfn == nil {
+				continue
+			}
+			switch {
+			case startfn.Name == "runtime.main" || startfn.Name == "main.agoroutine":
+				if g.System() {
+					t.Errorf("goroutine %d starting at %s is a system goroutine", g.ID, startfn.Name)
+				}
+			case g.System():
+				nsystem++
+			}
+		}
+		if nsystem == 0 {
+			t.Errorf("no system goroutines found")
+		}
+
+		gs, _, err = proc.FilterGoroutines(p, proc.GoroutineFilter{ExcludeSystem: true}, 0, 0)
+		assertNoError(err, t, "FilterGoroutines")
+		if len(gs) != 11 {
+			t.Errorf("wrong number of user goroutines %d", len(gs))
+		}
+	})
+}
+
+func TestAncestors(t *testing.T) {
+	if !goversion.VersionAfterOrEqual(runtime.Version(), 1, 11) {
+		t.Skip("not supported on Go <= 1.10")
+	}
+	godebugOld := os.Getenv("GODEBUG")
+	defer os.Setenv("GODEBUG", godebugOld)
+	os.Setenv("GODEBUG", "tracebackancestors=100")
+	withTestProcess("goroutinestackprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.agoroutine")
+		assertNoError(err, t, "setFunctionBreakpoint()")
+		assertNoError(proc.Continue(p), t, "Continue()")
+
+		ancestors, err := p.SelectedGoroutine().Ancestors(1000)
+		assertNoError(err, t, "Ancestors")
+		t.Logf("ancestors: %#v", ancestors)
+		if len(ancestors) != 1 {
+			t.Fatalf("expected only one ancestor got %d", len(ancestors))
+		}
+		if ancestors[0].ID != 1 {
+			t.Fatalf("expected ancestor to be goroutine 1, got %d", ancestors[0].ID)
+		}
+
+		mainFound := false
+		frames, err := ancestors[0].Stack(100)
+		assertNoError(err, t, "Stack")
+		for _, frame := range frames {
+			if frame.Current.Fn != nil && frame.Current.Fn.Name == "main.main" {
+				mainFound = true
+			}
+		}
+		if !mainFound {
+			t.Fatal("could not find main.main function in ancestor's stack")
+		}
+	})
+}
+
+func TestFreezeGoroutine(t *testing.T) {
+	if testBackend != "native" || runtime.GOOS != "linux" {
+		t.Skip("only supported on linux with the native backend")
+	}
+	withTestProcess("freezeprog", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue")
+		gs, _, err := proc.FilterGoroutines(p, proc.GoroutineFilter{Function: "main.spinFrozen"}, 0, 0)
+		assertNoError(err, t, "FilterGoroutines")
+		if len(gs) != 1 {
+			t.Fatalf("wrong number of goroutines %d", len(gs))
+		}
+		assertNoError(proc.FreezeGoroutine(p, gs[0].ID), t, "FreezeGoroutine")
+		if frozen := p.Common().FrozenGoroutines(); len(frozen) != 1 || frozen[0] != gs[0].ID {
+			t.Fatalf("wrong frozen goroutines %v", frozen)
+		}
+
+		before, _ := constant.Int64Val(evalVariable(p, t, "main.frozenCount").Value)
+		otherBefore, _ := constant.Int64Val(evalVariable(p, t, "main.otherCount").Value)
+		assertNoError(proc.Continue(p), t, "Continue")
+		after, _ := constant.Int64Val(evalVariable(p, t, "main.frozenCount").Value)
+		otherAfter, _ := constant.Int64Val(evalVariable(p, t, "main.otherCount").Value)
+		t.Logf("frozen %d -> %d, other %d -> %d", before, after, otherBefore, otherAfter)
+		if after != before {
+			t.Errorf("frozen goroutine ran")
+		}
+		if otherAfter == otherBefore {
+			t.Errorf("other goroutine did not run")
+		}
+
+		assertNoError(proc.ThawGoroutine(p, gs[0].ID), t, "ThawGoroutine")
+		for _, bp := range p.Breakpoints().M {
+			if bp.Kind&proc.FreezeBreakpoint != 0 {
+				t.Errorf("freeze breakpoint not cleared")
+			}
+		}
+		for _, thread := range p.ThreadList() {
+			if thread.Common().Held() {
+				t.Errorf("thread %d still held", thread.ThreadID())
+			}
+		}
+	})
+}
+
+func TestSchedHistory(t *testing.T) {
+	withTestProcess("freezeprog", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue")
+		gs, _, err := proc.FilterGoroutines(p, proc.GoroutineFilter{Function: "main.spin"}, 0, 0)
+		assertNoError(err, t, "FilterGoroutines")
+		if len(gs) != 2 {
+			t.Fatalf("wrong number of goroutines %d", len(gs))
+		}
+		assertNoError(proc.RecordSchedHistory(p, true), t, "RecordSchedHistory")
+		assertNoError(proc.Continue(p), t, "Continue")
+
+		threads := make(map[int]bool)
+		for _, thread := range p.ThreadList() {
+			threads[thread.ThreadID()] = true
+		}
+		scheduled := make(map[int]bool)
+		for _, ev := range p.Common().SchedHistory() {
+			scheduled[ev.GoroutineID] = true
+			if !threads[ev.ThreadID] {
+				t.Errorf("unknown thread %d in scheduling history", ev.ThreadID)
+			}
+		}
+		for _, g := range gs {
+			if !scheduled[g.ID] {
+				t.Errorf("goroutine %d not in scheduling history", g.ID)
+			}
+		}
+
+		assertNoError(proc.RecordSchedHistory(p, false), t, "RecordSchedHistory")
+		if p.Common().SchedHistory() != nil {
+			t.Errorf("scheduling history not cleared")
+		}
+		for _, bp := range p.Breakpoints().M {
+			if bp.Kind&proc.FreezeBreakpoint != 0 {
+				t.Errorf("freeze breakpoint not cleared")
+			}
+		}
+	})
+}
+
+func TestSelectCases(t *testing.T) {
+	withTestProcess("selectprog", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue")
+		gs, _, err := proc.FilterGoroutines(p, proc.GoroutineFilter{Function: "main.selector"}, 0, 0)
+		assertNoError(err, t, "FilterGoroutines")
+		if len(gs) != 1 {
+			t.Fatalf("wrong number of goroutines %d", len(gs))
+		}
+		cases, err := gs[0].SelectCases()
+		assertNoError(err, t, "SelectCases")
+		recvch := uint64(evalVariable(p, t, "main.recvch").Base)
+		sendch := uint64(evalVariable(p, t, "main.sendch").Base)
+		found := map[proc.SelectCase]bool{}
+		for _, cas := range cases {
+			found[cas] = true
+		}
+		if len(cases) != 2 || !found[proc.SelectCase{Chan: recvch}] || !found[proc.SelectCase{Chan: sendch, Send: true}] {
+			t.Errorf("wrong select cases %#v (recvch %#x sendch %#x)", cases, recvch, sendch)
+		}
+	})
+}
+
+func TestSwitchFrame(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("goroutinestackprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.stacktraceme")
+		assertNoError(err, t, "SetBreakpoint")
+		assertNoError(proc.Continue(p), t, "Continue 1")
+		assertNoError(proc.Continue(p), t, "Continue 2")
+
+		assertNoError(proc.SwitchFrame(p, 1), t, "SwitchFrame(1)")
+		if frame := proc.SelectedFrame(p); frame != 1 {
+			t.Fatalf("wrong selected frame %d", frame)
+		}
+		scope, err := proc.SelectedScope(p)
+		assertNoError(err, t, "SelectedScope")
+		v, err := scope.EvalVariable("n", normalLoadConfig)
+		assertNoError(err, t, "EvalVariable(n)")
+		if n, _ := constant.Int64Val(v.Value); n != 3 {
+			t.Fatalf("wrong value of n in frame 1: %d", n)
+		}
+
+		if err := proc.SwitchFrame(p, 100); err == nil {
+			t.Fatal("SwitchFrame succeeded on a frame that does not exist")
+		}
+
+		assertNoError(proc.SwitchFrame(p, 1), t, "SwitchFrame(1)")
+		assertNoError(proc.Next(p), t, "Next")
+		loc, err := p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "main.func3" || loc.Line != 47 {
+			t.Fatalf("wrong location after Next on frame 1: %v", loc)
+		}
+		if frame := proc.SelectedFrame(p); frame != 0 {
+			t.Fatalf("frame selection not reset after Next: %d", frame)
+		}
+
+		assertNoError(proc.SwitchFrame(p, 1), t, "SwitchFrame(1)")
+		assertNoError(proc.StepOut(p), t, "StepOut")
+		loc, err = p.CurrentThread().Location()
+		assertNoError(err, t, "Location")
+		if loc.Fn == nil || loc.Fn.Name != "main.func1" {
+			t.Fatalf("wrong location after StepOut from frame 1: %v", loc)
+		}
+		if frame := proc.SelectedFrame(p); frame != 0 {
+			t.Fatalf("frame selection not reset after StepOut: %d", frame)
+		}
+	})
+}
+
+func TestDeferredCallScope(t *testing.T) {
+	// Since Go 1.17 the arguments of deferred calls are captured by a closure
+	// and the deferred function doesn't have any.
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && ver.AfterOrEqual(goversion.GoVersion{1, 17, -1, 0, 0, ""}) {
+		t.Skip("arguments of deferred calls are stored in a closure")
+	}
+	protest.AllowRecording(t)
+	withTestProcess("deferargs", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue()")
+		for _, tc := range []struct {
+			deferCall int
+			fn        string
+			vars      map[string]string
+		}{
+			{1, "main.greet", map[string]string{"name": `"world"`, "n": "11"}},
+			{2, "main.sum", map[string]string{"a": "10", "b": "20"}},
+		} {
+			scope, err := proc.ConvertEvalScopeDeferred(p, -1, 1, tc.deferCall)
+			assertNoError(err, t, fmt.Sprintf("ConvertEvalScopeDeferred(%d)", tc.deferCall))
+			if scope.Fn == nil || scope.Fn.Name != tc.fn {
+				t.Errorf("deferred call %d: wrong function %v", tc.deferCall, scope.Fn)
+				continue
+			}
+			for name, expected := range tc.vars {
+				v, err := scope.EvalVariable(name, normalLoadConfig)
+				assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", name))
+				if v.Value == nil || v.Value.ExactString() != expected {
+					t.Errorf("deferred call %d: %s = %v, expected %s", tc.deferCall, name, v.Value, expected)
+				}
+			}
+		}
+		if _, err := proc.ConvertEvalScopeDeferred(p, -1, 1, 3); err == nil {
+			t.Errorf("ConvertEvalScopeDeferred(3) did not return an error")
+		}
+	})
+}
+
+func TestDeferredClosureScope(t *testing.T) {
+	// Since Go 1.17 the arguments of deferred calls are captured by a
+	// closure, its captured variables are described since Go 1.23.
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 23, -1, 0, 0, ""}) {
+		t.Skip("captured variables are not described before go1.23")
+	}
+	protest.AllowRecording(t)
+	withTestProcess("deferclosure", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue()")
+		for _, tc := range []struct {
+			deferCall int
+			vars      map[string]string
+		}{
+			{1, map[string]string{"y": "20"}},
+			{2, map[string]string{"name": `"world"`, "x": "10"}},
+		} {
+			scope, err := proc.ConvertEvalScopeDeferred(p, -1, 1, tc.deferCall)
+			assertNoError(err, t, fmt.Sprintf("ConvertEvalScopeDeferred(%d)", tc.deferCall))
+			args, err := scope.FunctionArguments(normalLoadConfig)
+			assertNoError(err, t, "FunctionArguments")
+			if len(args) != len(tc.vars) {
+				t.Errorf("deferred call %d: wrong number of arguments %d", tc.deferCall, len(args))
+			}
+			for name, expected := range tc.vars {
+				v, err := scope.EvalVariable(name, normalLoadConfig)
+				assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", name))
+				if v.Value == nil || v.Value.ExactString() != expected {
+					t.Errorf("deferred call %d: %s = %v, expected %s", tc.deferCall, name, v.Value, expected)
+				}
+			}
+		}
+	})
+}
+
+func TestLocalInstances(t *testing.T) {
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 9, -1, 0, 0, ""}) {
+		t.Skip("scopes not implemented in <=go1.8")
+	}
+
+	withTestProcess("issue951", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue()")
+		scope, err := proc.GoroutineScope(p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope")
+		instances, err := scope.LocalInstances("i", normalLoadConfig)
+		assertNoError(err, t, "LocalInstances")
+		if len(instances) != 3 {
+			t.Fatalf("wrong number of instances %d", len(instances))
+		}
+		for i, expected := range []struct {
+			value    int64
+			declLine int64
+			inScope  bool
+		}{
+			{42, 12, false},
+			{0, 13, false},
+			{20, 14, true},
+		} {
+			v := instances[i]
+			t.Logf("instance %d: %#v ranges %#x", i, v.Variable, v.Ranges)
+			if v.InScope != expected.inScope || v.DeclLine != expected.declLine || len(v.Ranges) == 0 {
+				t.Errorf("instance %d: wrong declaration", i)
+			}
+			if v.Value == nil {
+				t.Errorf("instance %d: unreadable %v", i, v.Unreadable)
+			} else if n, _ := constant.Int64Val(v.Value); n != expected.value {
+				t.Errorf("instance %d: wrong value %v", i, v.Value)
+			}
+			if shadowed := v.Flags&proc.VariableShadowed != 0; shadowed == expected.inScope {
+				t.Errorf("instance %d: wrong shadowed flag", i)
+			}
+		}
+	})
+}
+
+func TestUninitializedLocals(t *testing.T) {
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 9, -1, 0, 0, ""}) {
+		t.Skip("scopes not implemented in <=go1.8")
+	}
+	protest.AllowRecording(t)
+	withTestProcess("uninitvars", t, func(p proc.Process, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture, 11)
+		assertNoError(proc.Continue(p), t, "Continue()")
+		scope, err := proc.GoroutineScope(p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope")
+		vars, err := scope.LocalVariables(normalLoadConfig)
+		assertNoError(err, t, "LocalVariables")
+		flags := map[string]proc.VariableFlags{}
+		for _, v := range vars {
+			flags[v.Name] = v.Flags
+		}
+		t.Logf("%v", flags)
+		if f, ok := flags["a"]; !ok || f&proc.VariableUninitialized != 0 {
+			t.Errorf("a: missing or uninitialized")
+		}
+		if f, ok := flags["b"]; !ok || f&proc.VariableUninitialized == 0 {
+			t.Errorf("b: missing or not uninitialized")
+		}
+		if _, ok := flags["c"]; ok {
+			t.Errorf("c: declared in a lexical block that does not contain the current PC")
+		}
+	})
+}
+
+func TestHasDynamicModules(t *testing.T) {
+	withTestProcess("testnextprog", t, func(p proc.Process, fixture protest.Fixture) {
+		_, err := setFunctionBreakpoint(p, "main.main")
+		assertNoError(err, t, "setFunctionBreakpoint()")
+		assertNoError(proc.Continue(p), t, "Continue()")
+		if proc.HasDynamicModules(p) {
+			t.Fatal("a program that did not open any plugin has dynamic modules")
+		}
+	})
+}
@@ -70,3 +70,52 @@ func TestIssue419(t *testing.T) {
 		}
 	}
 }
+
+func TestSignalPolicyStop(t *testing.T) {
+	if testBackend != "native" || runtime.GOOS != "linux" {
+		t.Skip("signal policies only supported on linux/native")
+	}
+	withTestProcess("sigusr1", t, func(p proc.Process, fixture protest.Fixture) {
+		p.Common().SetSignalPolicy(int(syscall.SIGUSR1), proc.SignalStop)
+		assertNoError(proc.Continue(p), t, "Continue()")
+		if sig := p.CurrentThread().Common().Signal; sig != int(syscall.SIGUSR1) {
+			t.Fatalf("expected stop on SIGUSR1, got signal %d", sig)
+		}
+		// the signal is delivered on resume and the program exits normally
+		err := proc.Continue(p)
+		if pe, exited := err.(proc.ProcessExitedError); !exited || pe.Status != 0 {
+			t.Fatalf("expected normal process exit, got %v", err)
+		}
+	})
+}
+
+func TestSyscallCatchpoint(t *testing.T) {
+	if testBackend != "native" || runtime.GOOS != "linux" {
+		t.Skip("syscall catchpoints only supported on linux/native")
+	}
+	withTestProcess("unlinktest", t, func(p proc.Process, fixture protest.Fixture) {
+		unlinkat, _ := proc.SyscallNumber("unlinkat")
+		unlink, _ := proc.SyscallNumber("unlink")
+		p.Common().SetSyscallCatchpoints([]int{unlinkat, unlink})
+
+		for _, exit := range []bool{false, true} {
+			assertNoError(proc.Continue(p), t, "Continue()")
+			ev := p.CurrentThread().Common().Syscall
+			if ev == nil {
+				t.Fatal("not stopped at a syscall catchpoint")
+			}
+			if ev.Exit != exit || ev.Path != "/nonexistent/delve-unlinktest" {
+				t.Fatalf("wrong syscall event %#v", ev)
+			}
+			if exit && ev.Ret != -int64(syscall.ENOENT) {
+				t.Fatalf("wrong return value %d", ev.Ret)
+			}
+		}
+
+		p.Common().SetSyscallCatchpoints(nil)
+		err := proc.Continue(p)
+		if _, exited := err.(proc.ProcessExitedError); !exited {
+			t.Fatalf("expected process exit, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// recoverPointDepth is the maximum depth of the stack searched by
+// FindRecoverPoint.
+const recoverPointDepth = 100
+
+// RecoverPoint is a deferred function that will recover the panic of a
+// goroutine, see FindRecoverPoint.
+type RecoverPoint struct {
+	// Fn is the deferred function that calls recover.
+	Fn *Function
+	// DeferPC is the address of the defer statement that deferred Fn.
+	DeferPC uint64
+	// Frame is the index, in the stacktrace of the goroutine, of the frame
+	// that deferred Fn.
+	Frame int
+}
+
+// FindRecoverPoint walks the defer chain of the selected goroutine, which
+// must be panicking, in the order its deferred functions will be called
+// and returns the first one that calls recover.
+// Since recover only stops a panic when it is called directly by a
+// deferred function only direct calls to recover are considered.
+func FindRecoverPoint(dbp Process) (*RecoverPoint, error) {
+	if _, err := dbp.Valid(); err != nil {
+		return nil, err
+	}
+	g := dbp.SelectedGoroutine()
+	if g == nil {
+		return nil, errors.New("no selected goroutine")
+	}
+	if g.variable.Unreadable != nil {
+		return nil, g.variable.Unreadable
+	}
+	frames, err := g.Stacktrace(recoverPointDepth, true)
+	if err != nil {
+		return nil, err
+	}
+	if !panicking(g, frames) {
+		return nil, fmt.Errorf("goroutine %d is not panicking", g.ID)
+	}
+	for i := range frames {
+		for _, d := range frames[i].Defers {
+			if d.Unreadable != nil {
+				return nil, d.Unreadable
+			}
+			fn := dbp.BinInfo().PCToFunc(d.DeferredPC)
+			if fn == nil {
+				continue
+			}
+			ok, err := callsRecover(dbp, fn)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return &RecoverPoint{Fn: fn, DeferPC: d.DeferPC, Frame: i}, nil
+			}
+		}
+	}
+	return nil, errors.New("no deferred function will recover the panic")
+}
+
+// panicking returns true if g is panicking, frames is the stacktrace of g.
+// A goroutine stopped at the start of runtime.gopanic is considered to be
+// panicking, even though its _panic field has not been set yet.
+func panicking(g *G, frames []Stackframe) bool {
+	if g.variable.fieldVariable("_panic").maybeDereference().Addr != 0 {
+		return true
+	}
+	for _, frame := range frames {
+		if frame.Current.Fn != nil && frame.Current.Fn.Name == "runtime.gopanic" {
+			return true
+		}
+	}
+	return false
+}
+
+// callsRecover returns true if fn contains a call to recover.
+func callsRecover(dbp Process, fn *Function) (bool, error) {
+	text, err := disassemble(dbp.CurrentThread(), nil, dbp.Breakpoints(), dbp.BinInfo(), fn.Entry, fn.End, false)
+	if err != nil {
+		return false, err
+	}
+	for _, instr := range text {
+		if instr.Inst != nil && instr.IsCall() && instr.DestLoc != nil && instr.DestLoc.Fn != nil && instr.DestLoc.Fn.Name == "runtime.gorecover" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ContinueToRecover continues execution until the deferred function
+// returned by FindRecoverPoint is called by the selected goroutine,
+// stopping after its prologue, right before it recovers the panic.
+// It must be called while the panic is in progress, for example on a
+// breakpoint set on runtime.gopanic, once the unrecovered-panic
+// breakpoint is hit all deferred functions have already been called.
+func ContinueToRecover(dbp Process) error {
+	if _, err := dbp.Valid(); err != nil {
+		return err
+	}
+	if dbp.Breakpoints().HasInternalBreakpoints() {
+		return fmt.Errorf("next while nexting")
+	}
+	rp, err := FindRecoverPoint(dbp)
+	if err != nil {
+		return err
+	}
+	pc, err := FirstPCAfterPrologue(dbp, rp.Fn, false)
+	if err != nil {
+		return err
+	}
+	if _, err := dbp.SetBreakpoint(pc, NextBreakpoint, SameGoroutineCondition(dbp.SelectedGoroutine())); err != nil {
+		if _, isexists := err.(BreakpointExistsError); !isexists {
+			return err
+		}
+	}
+	dbp.Breakpoints().setInternalOrigin("recover")
+	return Continue(dbp)
+}
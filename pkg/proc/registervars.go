@@ -0,0 +1,109 @@
+package proc
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// registerPrefix replaces the '$' in front of register names in
+// expressions, so that they can be parsed by go/parser.
+const registerPrefix = "__dlvreg_"
+
+// ParseExpr parses expr like go/parser.ParseExpr, it also accepts the CPU
+// registers of the frame the expression is evaluated in written as
+// pseudo-variables, the name of the register prefixed by '$', for example
+// $rax, $rsp or $pc. The positions of syntax errors refer to expr.
+func ParseExpr(expr string) (ast.Expr, error) {
+	rewritten, regs := rewriteRegisters(expr)
+	t, err := parser.ParseExpr(rewritten)
+	if el, ok := err.(scanner.ErrorList); ok && len(regs) > 0 {
+		for _, e := range el {
+			lineStart := originalOffset(regs, e.Pos.Offset-e.Pos.Column+1)
+			e.Pos.Offset = originalOffset(regs, e.Pos.Offset)
+			e.Pos.Column = e.Pos.Offset - lineStart + 1
+		}
+	}
+	return t, err
+}
+
+// rewriteRegisters replaces every '$' immediately followed by an
+// identifier, outside of string and character literals, with
+// registerPrefix. It also returns the offsets in expr of the replaced
+// '$'.
+func rewriteRegisters(expr string) (string, []int) {
+	if !strings.Contains(expr, "$") {
+		return expr, nil
+	}
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(expr))
+	var s scanner.Scanner
+	s.Init(file, []byte(expr), func(token.Position, string) {}, 0)
+
+	var buf bytes.Buffer
+	var regs []int
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.ILLEGAL || lit != "$" {
+			continue
+		}
+		off := file.Offset(pos)
+		if off+1 >= len(expr) || !isIdentStart(expr[off+1]) {
+			continue
+		}
+		buf.WriteString(expr[last:off])
+		buf.WriteString(registerPrefix)
+		last = off + 1
+		regs = append(regs, off)
+	}
+	buf.WriteString(expr[last:])
+	return buf.String(), regs
+}
+
+// originalOffset converts off, an offset in the output of
+// rewriteRegisters, to the corresponding offset in its input, regs are the
+// offsets of the replaced '$' returned by rewriteRegisters.
+func originalOffset(regs []int, off int) int {
+	for i, reg := range regs {
+		start := reg + i*(len(registerPrefix)-1)
+		switch {
+		case off < start:
+			return off - i*(len(registerPrefix)-1)
+		case off < start+len(registerPrefix):
+			return reg
+		}
+	}
+	return off - len(regs)*(len(registerPrefix)-1)
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z')
+}
+
+// evalRegister returns the value of register name in the frame of scope.
+// Registers that fit in 64 bits are returned as integer constants, wider
+// registers, like the XMM registers, as the integer represented by their
+// bytes in little endian order.
+func (scope *EvalScope) evalRegister(name string) (*Variable, error) {
+	regnum, ok := scope.BinInfo.Arch.DwarfRegisterByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown register $%s", name)
+	}
+	reg := scope.Regs.Reg(regnum)
+	if reg == nil {
+		return nil, fmt.Errorf("register $%s is not available in this frame", name)
+	}
+	if len(reg.Bytes) <= 8 {
+		return newConstant(constant.MakeUint64(reg.Uint64Val), scope.Mem), nil
+	}
+	return newConstant(constant.MakeFromBytes(reg.Bytes), scope.Mem), nil
+}
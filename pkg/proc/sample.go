@@ -0,0 +1,90 @@
+package proc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StackSample is a stack that was seen one or more times by a
+// StackSampler.
+type StackSample struct {
+	// Stack contains the call locations of the topmost frames of the stack.
+	Stack []Location
+	// Count is the number of times the stack was sampled.
+	Count int
+}
+
+// StackSampler aggregates the stacks of the goroutines running on the
+// threads of the target each time Sample is called, giving an
+// approximation of where the target spends its time.
+// This is stop-the-world sampling: every sample is taken with all the
+// threads of the target stopped, the cost of stopping and resuming them
+// is paid on every sample and slows the target down.
+type StackSampler struct {
+	depth   int
+	samples map[string]*StackSample
+	total   int
+}
+
+// NewStackSampler returns a StackSampler recording the depth topmost
+// frames of each stack.
+func NewStackSampler(depth int) *StackSampler {
+	return &StackSampler{depth: depth, samples: make(map[string]*StackSample)}
+}
+
+// Sample records the stacks of the goroutines currently running on the
+// threads of p, p must be stopped. Threads that are not running a
+// goroutine, or that are running one parked in a system call, are
+// ignored.
+func (s *StackSampler) Sample(p Process) error {
+	if _, err := p.Valid(); err != nil {
+		return err
+	}
+	for _, thread := range p.ThreadList() {
+		g, _ := GetG(thread)
+		if g == nil || g.Status&^gscanStatus != Grunning {
+			continue
+		}
+		frames, err := g.Stacktrace(s.depth, false)
+		if err != nil || len(frames) == 0 {
+			continue
+		}
+		stack := make([]Location, len(frames))
+		pcs := make([]string, len(frames))
+		for i := range frames {
+			stack[i] = frames[i].Call
+			pcs[i] = strconv.FormatUint(frames[i].Call.PC, 16)
+		}
+		key := strings.Join(pcs, " ")
+		sample := s.samples[key]
+		if sample == nil {
+			sample = &StackSample{Stack: stack}
+			s.samples[key] = sample
+		}
+		sample.Count++
+		s.total++
+	}
+	return nil
+}
+
+// Total returns the number of stacks recorded by Sample.
+func (s *StackSampler) Total() int {
+	return s.total
+}
+
+// Samples returns the distinct stacks recorded by Sample, sorted by
+// decreasing number of occurrences.
+func (s *StackSampler) Samples() []StackSample {
+	r := make([]StackSample, 0, len(s.samples))
+	for _, sample := range s.samples {
+		r = append(r, *sample)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Count != r[j].Count {
+			return r[i].Count > r[j].Count
+		}
+		return r[i].Stack[0].PC < r[j].Stack[0].PC
+	})
+	return r
+}
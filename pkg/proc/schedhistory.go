@@ -0,0 +1,54 @@
+package proc
+
+// maxSchedHistory is the maximum number of events kept in the scheduling
+// history, older events are discarded.
+const maxSchedHistory = 10000
+
+// SchedEvent records that a goroutine was scheduled on a thread, see
+// RecordSchedHistory.
+type SchedEvent struct {
+	GoroutineID int
+	ThreadID    int
+}
+
+// RecordSchedHistory enables or disables the recording of the scheduling
+// history of the target: every time the scheduler runs a goroutine on a
+// thread the target is stopped on runtime.execute, the goroutine and the
+// thread are recorded and the target is resumed. Since the target stops
+// every time a goroutine is scheduled it runs considerably slower while the
+// history is recorded.
+func RecordSchedHistory(p Process, enabled bool) error {
+	common := p.Common()
+	if !enabled {
+		common.schedHistory = nil
+		return clearFreezeBreakpoint(p)
+	}
+	if common.schedHistory != nil {
+		return nil
+	}
+	if err := setFreezeBreakpoint(p); err != nil {
+		return err
+	}
+	common.schedHistory = []SchedEvent{}
+	return nil
+}
+
+// SchedHistory returns the goroutines that were scheduled, and the threads
+// they ran on, in the order they were scheduled, since the target was last
+// resumed by Continue. It returns nil if the scheduling history isn't
+// recorded.
+func (p *CommonProcess) SchedHistory() []SchedEvent {
+	return p.schedHistory
+}
+
+// recordSchedEvent appends the scheduling of goroutine goid on thread to
+// the scheduling history, if it is recorded.
+func (p *CommonProcess) recordSchedEvent(thread Thread, goid int) {
+	if p.schedHistory == nil {
+		return
+	}
+	if len(p.schedHistory) >= maxSchedHistory {
+		p.schedHistory = p.schedHistory[1:]
+	}
+	p.schedHistory = append(p.schedHistory, SchedEvent{GoroutineID: goid, ThreadID: thread.ThreadID()})
+}
@@ -0,0 +1,108 @@
+package proc
+
+import (
+	"errors"
+	"go/constant"
+
+	"github.com/derekparker/delve/pkg/goversion"
+)
+
+// maxSelectDepth is the maximum number of frames of a goroutine searched for
+// the call to runtime.selectgo.
+const maxSelectDepth = 5
+
+// maxSelectCases is the maximum number of cases of a select statement read
+// by G.SelectCases, the runtime does not allow more than 65536.
+const maxSelectCases = 1 << 16
+
+// SelectCase is a case of the select statement a goroutine is blocked in,
+// see G.SelectCases.
+type SelectCase struct {
+	// Chan is the address of the channel of the case.
+	Chan uint64
+	// Send is true if the case sends to the channel, false if it receives
+	// from it.
+	Send bool
+}
+
+// SelectCases returns the cases of the select statement g is blocked in,
+// decoded from the array of scase structs used by runtime.selectgo.
+// Default cases and cases on nil channels, which can not wake up the
+// goroutine, are omitted.
+func (g *G) SelectCases() ([]SelectCase, error) {
+	if g.variable == nil || g.variable.Unreadable != nil {
+		return nil, errors.New("goroutine is not blocked in a select statement")
+	}
+	frames, err := g.Stacktrace(maxSelectDepth, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range frames {
+		if fn := frames[i].Current.Fn; fn == nil || fn.Name != "runtime.selectgo" {
+			continue
+		}
+		var mem MemoryReadWriter = g.variable.mem
+		if g.Thread != nil {
+			mem = g.Thread
+		}
+		return decodeSelectCases(FrameToScope(g.variable.bi, mem, g, frames[i:]...))
+	}
+	return nil, errors.New("goroutine is not blocked in a select statement")
+}
+
+// decodeSelectCases reads the cases of a select statement from the scases
+// slice of the runtime.selectgo frame scope.
+// Before Go 1.16 each scase has a kind field, the values of the kinds
+// changed in Go 1.11 when caseNil was introduced. Since Go 1.16 send cases
+// come first in scases and are followed by receive cases, there are nsends
+// of them.
+func decodeSelectCases(scope *EvalScope) ([]SelectCase, error) {
+	scases, err := scope.EvalVariable("scases", LoadConfig{MaxVariableRecurse: 1, MaxArrayValues: maxSelectCases, MaxStructFields: -1})
+	if err != nil {
+		return nil, err
+	}
+	if scases.Unreadable != nil {
+		return nil, scases.Unreadable
+	}
+	caseRecv, caseSend := int64(0), int64(1)
+	if producer := scope.BinInfo.Producer(); producer == "" || goversion.ProducerAfterOrEqual(producer, 1, 11) {
+		caseRecv, caseSend = 1, 2
+	}
+	nsends := int64(-1)
+	r := []SelectCase{}
+	for i := range scases.Children {
+		cas := &scases.Children[i]
+		if cas.Unreadable != nil {
+			return nil, cas.Unreadable
+		}
+		var send bool
+		if kind := cas.fieldVariable("kind"); kind != nil {
+			if kind.Value == nil {
+				return nil, errors.New("malformed select case")
+			}
+			k, _ := constant.Int64Val(kind.Value)
+			if k != caseRecv && k != caseSend {
+				continue
+			}
+			send = k == caseSend
+		} else {
+			if nsends < 0 {
+				v, err := scope.EvalVariable("nsends", loadSingleValue)
+				if err != nil {
+					return nil, err
+				}
+				if v.Unreadable != nil {
+					return nil, v.Unreadable
+				}
+				nsends, _ = constant.Int64Val(v.Value)
+			}
+			send = int64(i) < nsends
+		}
+		c := sudogPtrField(cas, "c")
+		if c == 0 {
+			continue
+		}
+		r = append(r, SelectCase{Chan: c, Send: send})
+	}
+	return r, nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/derekparker/delve/pkg/dwarf/frame"
 	"github.com/derekparker/delve/pkg/dwarf/op"
 	"github.com/derekparker/delve/pkg/dwarf/reader"
+	"github.com/derekparker/delve/pkg/goversion"
 )
 
 // This code is partly adapted from runtime.gentraceback in
@@ -123,10 +124,27 @@ func (g *G) stackIterator() (*stackIterator, error) {
 // Stacktrace returns the stack trace for a goroutine.
 // Note the locations in the array are return addresses not call addresses.
 func (g *G) Stacktrace(depth int, readDefers bool) ([]Stackframe, error) {
+	return g.stacktrace(depth, readDefers, false, nil)
+}
+
+// SystemStacktrace is like Stacktrace but, if g is executing on a system
+// stack (the g0 stack of its thread or the stack of a cgo call), it
+// returns all the frames of the system stack before the frames of the
+// goroutine stack. Stacktrace stops unwinding the system stack at its
+// topmost function if it belongs to the runtime.
+// Frames of the system stack have SystemStack set, the frame following the
+// last one of them is where the goroutine switched to the system stack.
+func (g *G) SystemStacktrace(depth int, readDefers bool) ([]Stackframe, error) {
+	return g.stacktrace(depth, readDefers, true, nil)
+}
+
+func (g *G) stacktrace(depth int, readDefers, fullSystemStack bool, lineCache pcLineCache) ([]Stackframe, error) {
 	it, err := g.stackIterator()
 	if err != nil {
 		return nil, err
 	}
+	it.lineCache = lineCache
+	it.fullSystemStack = fullSystemStack
 	frames, err := it.stacktrace(depth)
 	if err != nil {
 		return nil, err
@@ -137,6 +155,43 @@ func (g *G) Stacktrace(depth int, readDefers bool) ([]Stackframe, error) {
 	return frames, nil
 }
 
+// GoroutineStacktrace is the stack trace of a goroutine returned by
+// Stacktraces.
+type GoroutineStacktrace struct {
+	G      *G
+	Frames []Stackframe
+	// Err is the error that stopped the stack trace of G, if any.
+	Err error
+}
+
+// Stacktraces returns the stack traces, up to depth frames, of all the
+// goroutines matching filter. It is faster than calling G.Stacktrace for
+// each goroutine because the source lines of return addresses, which are
+// usually shared by many goroutines, are only looked up once.
+func Stacktraces(p Process, filter GoroutineFilter, depth int, readDefers bool) ([]GoroutineStacktrace, error) {
+	gs, _, err := FilterGoroutines(p, filter, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	lineCache := make(pcLineCache)
+	r := make([]GoroutineStacktrace, len(gs))
+	for i, g := range gs {
+		r[i].G = g
+		r[i].Frames, r[i].Err = g.stacktrace(depth, readDefers, false, lineCache)
+	}
+	return r, nil
+}
+
+// pcLineCache caches the results of BinaryInfo.PCToLine for the stack
+// iterators created by Stacktraces.
+type pcLineCache map[uint64]pcLine
+
+type pcLine struct {
+	file string
+	line int
+	fn   *Function
+}
+
 // NullAddrError is an error for a null address.
 type NullAddrError struct{}
 
@@ -167,7 +222,13 @@ type stackIterator struct {
 	g           *G     // the goroutine being stacktraced, nil if we are stacktracing a goroutine-less thread
 	g0_sched_sp uint64 // value of g0.sched.sp (see comments around its use)
 
+	// fullSystemStack is true if the runtime functions executing on the
+	// system stack should be unwound, see G.SystemStacktrace.
+	fullSystemStack bool
+
 	dwarfReader *dwarf.Reader
+
+	lineCache pcLineCache // cache for pcToLine, can be nil
 }
 
 type savedLR struct {
@@ -312,11 +373,17 @@ func (it *stackIterator) switchStack() bool {
 		return true
 
 	case "runtime.goexit", "runtime.rt0_go", "runtime.mcall":
+		if it.switchFromSystemStack() {
+			return true
+		}
 		// Look for "top of stack" functions.
 		it.atend = true
 		return true
 
 	default:
+		if it.fullSystemStack {
+			return it.switchFromSystemStack()
+		}
 		if it.systemstack && it.top && it.g != nil && strings.HasPrefix(it.frame.Current.Fn.Name, "runtime.") {
 			// The runtime switches to the system stack in multiple places.
 			// This usually happens through a call to runtime.systemstack but there
@@ -325,11 +392,7 @@ func (it *stackIterator) switchStack() bool {
 			// Since we are only interested in printing the system stack for cgo
 			// calls we switch directly to the goroutine stack if we detect that the
 			// function at the top of the stack is a runtime function.
-			it.systemstack = false
-			it.top = false
-			it.pc = it.g.PC
-			it.regs.Reg(it.regs.SPRegNum).Uint64Val = it.g.SP
-			it.regs.Reg(it.regs.BPRegNum).Uint64Val = it.g.BP
+			it.switchToGoroutineStack()
 			return true
 		}
 
@@ -337,6 +400,34 @@ func (it *stackIterator) switchStack() bool {
 	}
 }
 
+// switchFromSystemStack switches to the goroutine stack, when the whole
+// system stack is unwound, if the current frame belongs to one of the
+// runtime functions that switch from the goroutine stack to the system
+// stack and call a function on it: their frame is the last one of the
+// system stack and the registers of the goroutine stack were saved in its
+// g struct.
+func (it *stackIterator) switchFromSystemStack() bool {
+	if !it.fullSystemStack || !it.systemstack || it.top || it.g == nil {
+		return false
+	}
+	switch it.frame.Current.Fn.Name {
+	case "runtime.systemstack", "runtime.morestack", "runtime.mcall":
+		it.switchToGoroutineStack()
+		return true
+	}
+	return false
+}
+
+// switchToGoroutineStack moves the iterator from the system stack to the
+// registers saved in the g struct of the goroutine being stacktraced.
+func (it *stackIterator) switchToGoroutineStack() {
+	it.systemstack = false
+	it.top = false
+	it.pc = it.g.PC
+	it.regs.Reg(it.regs.SPRegNum).Uint64Val = it.g.SP
+	it.regs.Reg(it.regs.BPRegNum).Uint64Val = it.g.BP
+}
+
 // Frame returns the frame the iterator is pointing at.
 func (it *stackIterator) Frame() Stackframe {
 	return it.frame
@@ -359,12 +450,26 @@ func (it *stackIterator) frameBase(fn *Function) int64 {
 	return fb
 }
 
+// pcToLine is like BinaryInfo.PCToLine but uses the line cache of the
+// iterator, if it has one.
+func (it *stackIterator) pcToLine(pc uint64) (string, int, *Function) {
+	if it.lineCache == nil {
+		return it.bi.PCToLine(pc)
+	}
+	if e, ok := it.lineCache[pc]; ok {
+		return e.file, e.line, e.fn
+	}
+	f, l, fn := it.bi.PCToLine(pc)
+	it.lineCache[pc] = pcLine{f, l, fn}
+	return f, l, fn
+}
+
 func (it *stackIterator) newStackframe(ret, retaddr uint64) Stackframe {
 	if retaddr == 0 {
 		it.err = NullAddrError{}
 		return Stackframe{}
 	}
-	f, l, fn := it.bi.PCToLine(it.pc)
+	f, l, fn := it.pcToLine(it.pc)
 	if fn == nil {
 		f = "?"
 		l = -1
@@ -392,7 +497,7 @@ func (it *stackIterator) newStackframe(ret, retaddr uint64) Stackframe {
 				r.Call = r.Current
 			} else {
 				r.lastpc = it.pc - 1
-				r.Call.File, r.Call.Line, r.Call.Fn = it.bi.PCToLine(it.pc - 1)
+				r.Call.File, r.Call.Line, r.Call.Fn = it.pcToLine(it.pc - 1)
 				if r.Call.Fn == nil {
 					r.Call.File = "?"
 					r.Call.Line = -1
@@ -592,6 +697,7 @@ type Defer struct {
 	DeferPC    uint64 // PC address of instruction that added this defer
 	SP         uint64 // Value of SP register when this function was deferred (this field gets adjusted when the stack is moved to match the new stack space)
 	link       *Defer // Next deferred function
+	argSz      int64  // Size of the arguments of the deferred call, stored after the _defer struct
 
 	variable   *Variable
 	Unreadable error
@@ -642,7 +748,7 @@ func (g *G) readDefers(frames []Stackframe) {
 }
 
 func (d *Defer) load() {
-	d.variable.loadValue(LoadConfig{false, 1, 0, 0, -1})
+	d.variable.loadValue(LoadConfig{MaxVariableRecurse: 1, MaxStructFields: -1})
 	if d.variable.Unreadable != nil {
 		d.Unreadable = d.variable.Unreadable
 		return
@@ -658,6 +764,9 @@ func (d *Defer) load() {
 
 	d.DeferPC, _ = constant.Uint64Val(d.variable.fieldVariable("pc").Value)
 	d.SP, _ = constant.Uint64Val(d.variable.fieldVariable("sp").Value)
+	if sizvar := d.variable.fieldVariable("siz"); sizvar != nil && sizvar.Value != nil {
+		d.argSz, _ = constant.Int64Val(sizvar.Value)
+	}
 
 	linkvar := d.variable.fieldVariable("link").maybeDereference()
 	if linkvar.Addr != 0 {
@@ -665,6 +774,71 @@ func (d *Defer) load() {
 	}
 }
 
+// EvalScope returns an EvalScope relative to the argument frame of this
+// deferred call, scope must be the scope of the frame that deferred it.
+// The arguments of a deferred call are stored in memory immediately after
+// the _defer struct, since the CFA of a Go function is the address of its
+// first argument that is what the CFA of the returned scope is set to,
+// and SP is set to the CFA minus the size of the return address pushed by
+// the CALL instruction.
+// Since Go 1.17 deferred calls with arguments are wrapped in closures
+// without arguments, the variables of the returned scope are the ones
+// captured by the closure, see closureVars, which are only described by
+// the debug information since Go 1.23. Open-coded defers do not store
+// their arguments in the _defer struct, an error is returned for those.
+func (d *Defer) EvalScope(scope *EvalScope) (*EvalScope, error) {
+	if d.Unreadable != nil {
+		return nil, d.Unreadable
+	}
+	bi := scope.BinInfo
+	if opendefer := d.variable.fieldVariable("openDefer"); opendefer != nil && opendefer.Value != nil && constant.BoolVal(opendefer.Value) {
+		return nil, errors.New("arguments of open-coded deferred calls are not available")
+	}
+	file, line, fn := bi.PCToLine(d.DeferredPC)
+	if fn == nil {
+		return nil, fmt.Errorf("could not find function at %#x", d.DeferredPC)
+	}
+
+	ds := *scope
+	ds.Location = Location{PC: d.DeferredPC, File: file, Line: line, Fn: fn}
+	ds.aordr = nil
+	ds.callResults = nil
+
+	if producer := bi.Producer(); producer != "" && goversion.ProducerAfterOrEqual(producer, 1, 17) {
+		if !goversion.ProducerAfterOrEqual(producer, 1, 23) {
+			return nil, errors.New("arguments of deferred calls are not available for Go 1.17 to 1.22")
+		}
+		cvs, err := d.variable.fieldVariable("fn").closureVars()
+		if err != nil {
+			return nil, err
+		}
+		ds.closureVars = make([]*Variable, 0, len(cvs))
+		for _, cv := range cvs {
+			cv.Flags |= VariableArgument
+			ds.closureVars = append(ds.closureVars, cv)
+		}
+		return &ds, nil
+	}
+
+	ds.Regs.Regs = make([]*op.DwarfRegister, len(scope.Regs.Regs))
+	copy(ds.Regs.Regs, scope.Regs.Regs)
+	ds.Regs.CFA = int64(d.variable.Addr) + d.variable.RealType.Common().ByteSize
+	ds.Regs.AddReg(ds.Regs.SPRegNum, op.DwarfRegisterFromUint64(uint64(ds.Regs.CFA-int64(bi.Arch.PtrSize()))))
+	ds.frameOffset += ds.Regs.CFA - scope.Regs.CFA
+
+	rdr := bi.dwarf.Reader()
+	rdr.Seek(fn.offset)
+	e, err := rdr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("could not read DWARF function entry: %v", err)
+	}
+	ds.Regs.FrameBase, _, _, _ = bi.Location(e, dwarf.AttrFrameBase, ds.PC, ds.Regs)
+	if d.argSz > 0 {
+		ds.Mem = cacheMemory(scope.Mem, uintptr(ds.Regs.CFA), int(d.argSz))
+	}
+	return &ds, nil
+}
+
 // spDecreasedErr is used when (*Defer).Next detects a corrupted linked
 // list, specifically when after followin a link pointer the value of SP
 // decreases rather than increasing or staying the same (the defer list is a
@@ -0,0 +1,128 @@
+package proc
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SetStepFilters sets the list of patterns used to hide functions from
+// Step, Next and StepOut. A function is hidden if its name or the path of
+// the file containing it matches one of the patterns, the only special
+// character in a pattern is '*', which matches any sequence of characters,
+// including '/'. For example:
+//
+//	runtime.*     hides all functions of package runtime
+//	fmt.*         hides all functions of package fmt
+//	*/vendor/*    hides vendored packages
+//	*.pb.go       hides functions of generated protobuf files
+//
+// Step will not enter hidden functions and if a step operation stops
+// inside a hidden function it continues until the function returns.
+func (p *CommonProcess) SetStepFilters(filters []string) error {
+	rxs := make([]*regexp.Regexp, len(filters))
+	for i, filter := range filters {
+		rx, err := regexp.Compile("^" + strings.Replace(regexp.QuoteMeta(filter), `\*`, ".*", -1) + "$")
+		if err != nil {
+			return err
+		}
+		rxs[i] = rx
+	}
+	p.stepFilters = filters
+	p.stepFilterRx = rxs
+	return nil
+}
+
+// StepFilters returns the list of patterns set with SetStepFilters.
+func (p *CommonProcess) StepFilters() []string {
+	return p.stepFilters
+}
+
+// SetBlackbox sets the list of functions, and files, that Step, Next and
+// StepOut never stop inside, like the step filters. Unlike a step filter
+// each entry names a single function, by its fully qualified name, or a
+// single file, either by its full path or by a suffix of its path that
+// starts after a '/'.
+func (p *CommonProcess) SetBlackbox(entries []string) {
+	p.blackbox = entries
+}
+
+// Blackbox returns the list of functions and files set with SetBlackbox.
+func (p *CommonProcess) Blackbox() []string {
+	return p.blackbox
+}
+
+// SetStepIntoRuntime determines whether Step enters unexported functions
+// of the runtime, for example runtime.mallocgc or runtime.chansend, which
+// are normally stepped over.
+func (p *CommonProcess) SetStepIntoRuntime(enabled bool) {
+	p.stepIntoRuntime = enabled
+}
+
+// StepIntoRuntime returns true if Step enters unexported functions of the
+// runtime, see SetStepIntoRuntime.
+func (p *CommonProcess) StepIntoRuntime() bool {
+	return p.stepIntoRuntime
+}
+
+// skipRuntimeFunction returns true if fn is an unexported runtime function
+// and Step should not enter it.
+func skipRuntimeFunction(dbp Process, fn *Function) bool {
+	return !dbp.Common().stepIntoRuntime && strings.HasPrefix(fn.Name, "runtime.") && !isExportedRuntime(fn.Name)
+}
+
+// hasStepFilters returns true if there are any step filters or blackboxed
+// functions.
+func (p *CommonProcess) hasStepFilters() bool {
+	return len(p.stepFilterRx) > 0 || len(p.blackbox) > 0
+}
+
+// blackboxed returns true if the function called fnname, in file, is in
+// the blackbox list.
+func (p *CommonProcess) blackboxed(fnname, file string) bool {
+	for _, entry := range p.blackbox {
+		if entry == fnname {
+			return true
+		}
+		if file != "" && (file == entry || strings.HasSuffix(file, "/"+entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+// stepFiltered returns true if the function called fnname, in file, is
+// hidden by one of the step filters or is blackboxed.
+func (p *CommonProcess) stepFiltered(fnname, file string) bool {
+	if p.blackboxed(fnname, file) {
+		return true
+	}
+	for _, rx := range p.stepFilterRx {
+		if rx.MatchString(fnname) || (file != "" && rx.MatchString(file)) {
+			return true
+		}
+	}
+	return false
+}
+
+// stepFilteredFunction returns true if fn is hidden by one of the step
+// filters or is blackboxed.
+func stepFilteredFunction(dbp Process, fn *Function) bool {
+	if !dbp.Common().hasStepFilters() {
+		return false
+	}
+	file, _, _ := dbp.BinInfo().PCToLine(fn.Entry)
+	return dbp.Common().stepFiltered(fn.Name, file)
+}
+
+// stoppedInFilteredFunction returns true if thread is stopped inside a
+// function hidden by the step filters or blackboxed.
+func stoppedInFilteredFunction(dbp Process, thread Thread) bool {
+	if !dbp.Common().hasStepFilters() {
+		return false
+	}
+	loc, err := thread.Location()
+	if err != nil || loc.Fn == nil {
+		return false
+	}
+	return dbp.Common().stepFiltered(loc.Fn.Name, loc.File)
+}
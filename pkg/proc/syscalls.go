@@ -0,0 +1,92 @@
+package proc
+
+import "bytes"
+
+// SyscallEvent describes a syscall catchpoint stop, see
+// CommonProcess.SetSyscallCatchpoints.
+type SyscallEvent struct {
+	Num  int
+	Name string
+	Args [6]uint64
+	// Exit is true if the thread stopped when exiting the syscall, false if
+	// it stopped when entering it.
+	Exit bool
+	// Ret is the return value of the syscall, only valid if Exit is true.
+	Ret int64
+	// Path is the value of the path argument of the syscall, for the
+	// syscalls taking one.
+	Path string
+}
+
+// syscallNames maps linux/amd64 syscall numbers to their names, it only
+// contains the most commonly used syscalls.
+var syscallNames = map[int]string{
+	0: "read", 1: "write", 2: "open", 3: "close", 4: "stat", 5: "fstat",
+	6: "lstat", 7: "poll", 8: "lseek", 9: "mmap", 10: "mprotect",
+	11: "munmap", 12: "brk", 13: "rt_sigaction", 14: "rt_sigprocmask",
+	16: "ioctl", 17: "pread64", 18: "pwrite64", 19: "readv", 20: "writev",
+	21: "access", 22: "pipe", 23: "select", 24: "sched_yield", 32: "dup",
+	33: "dup2", 35: "nanosleep", 39: "getpid", 41: "socket", 42: "connect",
+	43: "accept", 44: "sendto", 45: "recvfrom", 46: "sendmsg", 47: "recvmsg",
+	48: "shutdown", 49: "bind", 50: "listen", 56: "clone", 57: "fork",
+	59: "execve", 60: "exit", 61: "wait4", 62: "kill", 72: "fcntl",
+	74: "fsync", 76: "truncate", 77: "ftruncate", 78: "getdents",
+	79: "getcwd", 80: "chdir", 82: "rename", 83: "mkdir", 84: "rmdir",
+	85: "creat", 86: "link", 87: "unlink", 88: "symlink", 89: "readlink",
+	90: "chmod", 202: "futex", 217: "getdents64", 228: "clock_gettime",
+	231: "exit_group", 232: "epoll_wait", 233: "epoll_ctl", 234: "tgkill",
+	257: "openat", 258: "mkdirat", 262: "newfstatat", 263: "unlinkat",
+	264: "renameat", 281: "epoll_pwait", 288: "accept4", 291: "epoll_create1",
+	292: "dup3", 293: "pipe2",
+}
+
+// syscallPathArg maps syscall numbers to the 1-based index of their path
+// argument.
+var syscallPathArg = map[int]int{
+	2: 1, 4: 1, 6: 1, 21: 1, 59: 1, 76: 1, 80: 1, 82: 1, 83: 1, 84: 1,
+	85: 1, 86: 1, 87: 1, 88: 1, 89: 1, 90: 1,
+	257: 2, 258: 2, 262: 2, 263: 2, 264: 2,
+}
+
+// SyscallName returns the name of linux/amd64 syscall num, or the empty
+// string if it is not known.
+func SyscallName(num int) string {
+	return syscallNames[num]
+}
+
+// SyscallNumber returns the linux/amd64 number of the syscall called name.
+func SyscallNumber(name string) (int, bool) {
+	for num := range syscallNames {
+		if syscallNames[num] == name {
+			return num, true
+		}
+	}
+	return 0, false
+}
+
+// SyscallPathArg returns the index of the path argument of syscall num,
+// or -1 if it doesn't have one.
+func SyscallPathArg(num int) int {
+	return syscallPathArg[num] - 1
+}
+
+// maxSyscallPathLen is the maximum length of path arguments of syscalls
+// read by ReadSyscallPath.
+const maxSyscallPathLen = 256
+
+// ReadSyscallPath reads the zero terminated path argument of a syscall
+// at addr.
+func ReadSyscallPath(mem MemoryReadWriter, addr uint64) string {
+	var path []byte
+	buf := make([]byte, 16)
+	for len(path) < maxSyscallPathLen {
+		if _, err := mem.ReadMemory(buf, uintptr(addr)+uintptr(len(path))); err != nil {
+			break
+		}
+		if i := bytes.IndexByte(buf, 0); i >= 0 {
+			return string(append(path, buf[:i]...))
+		}
+		path = append(path, buf...)
+	}
+	return string(path)
+}
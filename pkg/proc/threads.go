@@ -1,6 +1,7 @@
 package proc
 
 import (
+	"debug/dwarf"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -8,7 +9,7 @@ import (
 	"go/token"
 	"path/filepath"
 	"reflect"
-	"strings"
+	"strconv"
 
 	"github.com/derekparker/delve/pkg/dwarf/godwarf"
 	"github.com/derekparker/delve/pkg/dwarf/reader"
@@ -61,6 +62,18 @@ func (tbe ThreadBlockedError) Error() string {
 // implementations of the Thread interface.
 type CommonThread struct {
 	returnValues []*Variable
+	// Signal is the signal that stopped the thread, because its policy was
+	// SignalStop. It will be delivered to the thread when it is resumed.
+	Signal int
+	// Syscall is the syscall that stopped the thread, if it stopped on a
+	// syscall catchpoint.
+	Syscall *SyscallEvent
+	// Cgo describes the crossing of the Go/C boundary that stopped the
+	// thread, if it stopped on a cgo catchpoint.
+	Cgo *CgoEvent
+	// heldGoroutine is the ID of the frozen goroutine the thread is held
+	// for, or zero.
+	heldGoroutine int
 }
 
 func (t *CommonThread) ReturnValues(cfg LoadConfig) []*Variable {
@@ -94,6 +107,28 @@ func topframe(g *G, thread Thread) (Stackframe, Stackframe, error) {
 	}
 }
 
+// selectedFrames returns the frame of the selected goroutine selected by
+// SwitchFrame and its caller, like topframe does for the topmost frame.
+func selectedFrames(dbp Process) (Stackframe, Stackframe, error) {
+	frame := SelectedFrame(dbp)
+	selg := dbp.SelectedGoroutine()
+	if frame == 0 {
+		return topframe(selg, dbp.CurrentThread())
+	}
+	frames, err := selg.Stacktrace(frame+1, true)
+	if err != nil {
+		return Stackframe{}, Stackframe{}, err
+	}
+	switch {
+	case frame >= len(frames):
+		return Stackframe{}, Stackframe{}, fmt.Errorf("Frame %d does not exist in goroutine %d", frame, selg.ID)
+	case frame+1 == len(frames):
+		return frames[frame], Stackframe{}, nil
+	default:
+		return frames[frame], frames[frame+1], nil
+	}
+}
+
 type NoSourceForPCError struct {
 	pc uint64
 }
@@ -102,15 +137,24 @@ func (err *NoSourceForPCError) Error() string {
 	return fmt.Sprintf("no source for pc %#x", err.pc)
 }
 
-// Set breakpoints at every line, and the return address. Also look for
-// a deferred function and set a breakpoint there too.
-// If stepInto is true it will also set breakpoints inside all
+// nextOptions are the options of next.
+type nextOptions struct {
+	stepInto       bool
+	inlinedStepOut bool
+	until          bool
+	defers         DeferMode
+}
+
+// Set breakpoints at every line, and the return address, of the frame
+// selected by SwitchFrame. Also look for a deferred function and set a
+// breakpoint there too.
+// If opts.stepInto is true it will also set breakpoints inside all
 // functions called on the current source line, for non-absolute CALLs
 // a breakpoint of kind StepBreakpoint is set on the CALL instruction,
 // Continue will take care of setting a breakpoint to the destination
 // once the CALL is reached.
 //
-// Regardless of stepInto the following breakpoints will be set:
+// Regardless of opts.stepInto the following breakpoints will be set:
 // - a breakpoint on the first deferred function with NextDeferBreakpoint
 //   kind, the list of all the addresses to deferreturn calls in this function
 //   and condition checking that we remain on the same goroutine
@@ -125,14 +169,21 @@ func (err *NoSourceForPCError) Error() string {
 // where the inlining happened and the second set of breakpoints will also
 // cover the "return address".
 //
-// If inlinedStepOut is true this function implements the StepOut operation
+// If opts.inlinedStepOut is true this function implements the StepOut operation
 // for an inlined function call. Everything works the same as normal except
 // when removing instructions belonging to inlined calls we also remove all
 // instructions belonging to the current inlined call.
-func next(dbp Process, stepInto, inlinedStepOut bool) error {
+//
+// opts.defers determines where the breakpoint on the most recently
+// deferred function is set, if at all.
+//
+// If opts.until is true no breakpoint is set on the lines of the current
+// function that do not follow the current line, this implements the Until
+// operation.
+func next(dbp Process, opts nextOptions) error {
 	selg := dbp.SelectedGoroutine()
 	curthread := dbp.CurrentThread()
-	topframe, retframe, err := topframe(selg, curthread)
+	topframe, retframe, err := selectedFrames(dbp)
 	if err != nil {
 		return err
 	}
@@ -142,7 +193,7 @@ func next(dbp Process, stepInto, inlinedStepOut bool) error {
 	}
 
 	// sanity check
-	if inlinedStepOut && !topframe.Inlined {
+	if opts.inlinedStepOut && !topframe.Inlined {
 		panic("next called with inlinedStepOut but topframe was not inlined")
 	}
 
@@ -166,7 +217,7 @@ func next(dbp Process, stepInto, inlinedStepOut bool) error {
 	}
 
 	text, err := disassemble(thread, regs, dbp.Breakpoints(), dbp.BinInfo(), topframe.Current.Fn.Entry, topframe.Current.Fn.End, false)
-	if err != nil && stepInto {
+	if err != nil && opts.stepInto {
 		return err
 	}
 
@@ -174,23 +225,24 @@ func next(dbp Process, stepInto, inlinedStepOut bool) error {
 	retFrameCond := andFrameoffCondition(sameGCond, retframe.FrameOffset())
 	sameFrameCond := andFrameoffCondition(sameGCond, topframe.FrameOffset())
 	var sameOrRetFrameCond ast.Expr
-	if sameGCond != nil {
-		if topframe.Inlined {
-			sameOrRetFrameCond = sameFrameCond
-		} else {
+	if topframe.Inlined {
+		sameOrRetFrameCond = sameFrameCond
+	} else {
+		sameOrRetFrameCond = &ast.BinaryExpr{
+			Op: token.LOR,
+			X:  frameoffCondition(topframe.FrameOffset()),
+			Y:  frameoffCondition(retframe.FrameOffset()),
+		}
+		if sameGCond != nil {
 			sameOrRetFrameCond = &ast.BinaryExpr{
 				Op: token.LAND,
 				X:  sameGCond,
-				Y: &ast.BinaryExpr{
-					Op: token.LOR,
-					X:  frameoffCondition(topframe.FrameOffset()),
-					Y:  frameoffCondition(retframe.FrameOffset()),
-				},
+				Y:  sameOrRetFrameCond,
 			}
 		}
 	}
 
-	if stepInto {
+	if opts.stepInto {
 		for _, instr := range text {
 			if instr.Loc.File != topframe.Current.File || instr.Loc.Line != topframe.Current.Line || !instr.IsCall() {
 				continue
@@ -224,12 +276,16 @@ func next(dbp Process, stepInto, inlinedStepOut bool) error {
 
 		// Set breakpoint on the most recently deferred function (if any)
 		var deferpc uint64 = 0
-		if topframe.TopmostDefer != nil && topframe.TopmostDefer.DeferredPC != 0 {
+		if topframe.TopmostDefer != nil && topframe.TopmostDefer.DeferredPC != 0 && opts.defers != DeferSkip {
 			deferfn := dbp.BinInfo().PCToFunc(topframe.TopmostDefer.DeferredPC)
-			var err error
-			deferpc, err = FirstPCAfterPrologue(dbp, deferfn, false)
-			if err != nil {
-				return err
+			if opts.defers == DeferStopAtEntry && deferfn != nil {
+				deferpc = deferfn.Entry
+			} else {
+				var err error
+				deferpc, err = FirstPCAfterPrologue(dbp, deferfn, false)
+				if err != nil {
+					return err
+				}
 			}
 		}
 		if deferpc != 0 && deferpc != topframe.Current.PC {
@@ -239,7 +295,7 @@ func next(dbp Process, stepInto, inlinedStepOut bool) error {
 					return err
 				}
 			}
-			if bp != nil && stepInto {
+			if bp != nil && opts.stepInto {
 				bp.DeferReturns = deferreturns
 			}
 		}
@@ -251,10 +307,10 @@ func next(dbp Process, stepInto, inlinedStepOut bool) error {
 		return err
 	}
 
-	if !stepInto {
+	if !opts.stepInto {
 		// Removing any PC range belonging to an inlined call
 		frame := topframe
-		if inlinedStepOut {
+		if opts.inlinedStepOut {
 			frame = retframe
 		}
 		pcs, err = removeInlinedCalls(dbp, pcs, frame)
@@ -263,6 +319,20 @@ func next(dbp Process, stepInto, inlinedStepOut bool) error {
 		}
 	}
 
+	if topframe.Inlined && !opts.inlinedStepOut {
+		// Stepping through the body of an inlined call: instructions of the
+		// caller can be interleaved with the instructions of the inlined call,
+		// only stop on lines of the inlined call and where execution leaves it.
+		pcs, err = restrictToInlinedCall(dbp.BinInfo(), pcs, text, topframe, retframe)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.until {
+		pcs = removePrecedingLines(dbp.BinInfo(), pcs, &topframe)
+	}
+
 	if !csource {
 		var covered bool
 		for i := range pcs {
@@ -292,8 +362,8 @@ func next(dbp Process, stepInto, inlinedStepOut bool) error {
 	if !topframe.Inlined {
 		// Add a breakpoint on the return address for the current frame.
 		// For inlined functions there is no need to do this, the set of PCs
-		// returned by the AllPCsBetween call above already cover all instructions
-		// of the containing function.
+		// computed above already covers the locations where the inlined call
+		// returns, see restrictToInlinedCall.
 		bp, err := dbp.SetBreakpoint(topframe.Ret, NextBreakpoint, retFrameCond)
 		if err != nil {
 			if _, isexists := err.(BreakpointExistsError); isexists {
@@ -320,6 +390,199 @@ func next(dbp Process, stepInto, inlinedStepOut bool) error {
 	return nil
 }
 
+const (
+	// maxRangeStepInstructions is the maximum number of instructions the
+	// current line can have for rangeStep to single step through it.
+	maxRangeStepInstructions = 24
+	// maxRangeSteps is the maximum number of instructions rangeStep will
+	// execute before giving up, it is needed for lines containing loops.
+	maxRangeSteps = 64
+)
+
+// rangeStep executes next, or step, by single stepping the selected
+// goroutine over the instructions of the current line, instead of setting
+// breakpoints on all the lines of the current function, when the current
+// line is short and doesn't contain any call or return instruction. This
+// avoids writing breakpoints into the memory of the target, which code
+// that reads its own instructions would see.
+// Returns true if the line was stepped over, false if the caller should
+// fall back to the breakpoint based implementation starting from the
+// current position, which is always on the same line and in the same frame
+// as when rangeStep was called.
+func rangeStep(dbp Process) (bool, error) {
+	if SelectedFrame(dbp) != 0 {
+		return false, nil
+	}
+	selg := dbp.SelectedGoroutine()
+	thread := dbp.CurrentThread()
+	if selg != nil {
+		if selg.Thread == nil {
+			return false, nil
+		}
+		thread = selg.Thread
+	}
+	topframe, _, err := topframe(selg, thread)
+	if err != nil || topframe.Current.Fn == nil || topframe.Inlined {
+		return false, nil
+	}
+	fn := topframe.Current.Fn
+	file, line := topframe.Current.File, topframe.Current.Line
+	text, err := disassemble(thread, nil, dbp.Breakpoints(), dbp.BinInfo(), fn.Entry, fn.End, false)
+	if err != nil {
+		return false, nil
+	}
+
+	online := make(map[uint64]bool)
+	for _, instr := range text {
+		if instr.Loc.File != file || instr.Loc.Line != line {
+			continue
+		}
+		if instr.IsCall() || instr.IsRet() {
+			return false, nil
+		}
+		if _, hasbp := dbp.Breakpoints().M[instr.Loc.PC]; hasbp {
+			// The breakpoint on the current instruction is stepped over by
+			// StepInstruction, unless the instruction is as long as a breakpoint
+			// and the address after it would be mistaken for a breakpoint hit.
+			if instr.Loc.PC != topframe.Current.PC || len(instr.Bytes) <= dbp.BinInfo().Arch.BreakpointSize() {
+				return false, nil
+			}
+		}
+		online[instr.Loc.PC] = true
+	}
+	if len(online) == 0 || len(online) > maxRangeStepInstructions {
+		return false, nil
+	}
+
+	for _, th := range dbp.ThreadList() {
+		th.Common().returnValues = nil
+		th.Common().Cgo = nil
+	}
+	dbp.Common().manualStop = false
+
+	for steps := 0; steps < maxRangeSteps; steps++ {
+		if err := dbp.StepInstruction(); err != nil {
+			return false, err
+		}
+		if err := disableExhaustedBreakpoints(dbp); err != nil {
+			return false, err
+		}
+		if bp := thread.Breakpoint(); bp.Breakpoint != nil && bp.Active {
+			// stopped by a watchpoint or by a breakpoint where execution left
+			// the current line, their conditions, hit counts and actions are
+			// handled like Continue does.
+			resume, err := userBreakpointHit(dbp, thread, bp.Breakpoint)
+			if err != nil {
+				return false, err
+			}
+			if !resume {
+				return true, conditionErrors([]Thread{thread})
+			}
+		}
+		regs, err := thread.Registers(false)
+		if err != nil {
+			return false, err
+		}
+		if !online[regs.PC()] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setReverseNextBreakpoints sets the breakpoints used by ReverseNext and
+// ReverseStep: on the first instruction of every line of the current
+// function, except the current line, and on the CALL instruction that
+// called the current function.
+// If stepInto is true breakpoints are also set on the return instructions
+// of the functions called by the current function, so that moving
+// backwards over a call stops inside the called function.
+func setReverseNextBreakpoints(dbp Process, stepInto bool) error {
+	selg := dbp.SelectedGoroutine()
+	curthread := dbp.CurrentThread()
+	topframe, retframe, err := topframe(selg, curthread)
+	if err != nil {
+		return err
+	}
+
+	fn := topframe.Current.Fn
+	if fn == nil {
+		return &NoSourceForPCError{topframe.Current.PC}
+	}
+
+	sameGCond := SameGoroutineCondition(selg)
+	sameFrameCond := andFrameoffCondition(sameGCond, topframe.FrameOffset())
+	retFrameCond := andFrameoffCondition(sameGCond, retframe.FrameOffset())
+
+	setbp := func(pc uint64, cond ast.Expr) error {
+		if _, err := dbp.SetBreakpoint(pc, NextBreakpoint, cond); err != nil {
+			if _, ok := err.(BreakpointExistsError); !ok {
+				return err
+			}
+		}
+		return nil
+	}
+
+	pcs, err := fn.cu.lineInfo.AllPCsBetween(fn.Entry, fn.End-1, topframe.Current.File, topframe.Current.Line)
+	if err != nil {
+		return err
+	}
+	if !stepInto {
+		pcs, err = removeInlinedCalls(dbp, pcs, topframe)
+		if err != nil {
+			return err
+		}
+	}
+	for _, pc := range pcs {
+		if err := setbp(pc, sameFrameCond); err != nil {
+			return err
+		}
+	}
+
+	if stepInto {
+		text, err := disassemble(curthread, nil, dbp.Breakpoints(), dbp.BinInfo(), fn.Entry, fn.End, false)
+		if err != nil {
+			return err
+		}
+		for _, instr := range text {
+			if !instr.IsCall() || instr.DestLoc == nil || instr.DestLoc.Fn == nil {
+				continue
+			}
+			destfn := instr.DestLoc.Fn
+			if destfn.Entry != instr.DestLoc.PC || skipRuntimeFunction(dbp, destfn) {
+				continue
+			}
+			rets, err := FunctionReturnLocations(dbp, destfn.Name)
+			if err != nil {
+				continue
+			}
+			for _, pc := range rets {
+				if err := setbp(pc, sameGCond); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if topframe.Inlined || topframe.Ret == 0 {
+		return nil
+	}
+	callerfn := dbp.BinInfo().PCToFunc(topframe.Ret)
+	if callerfn == nil || callerfn.Name == "runtime.goexit" {
+		return nil
+	}
+	text, err := disassemble(curthread, nil, dbp.Breakpoints(), dbp.BinInfo(), callerfn.Entry, topframe.Ret, false)
+	if err != nil {
+		return err
+	}
+	if len(text) > 0 {
+		if call := text[len(text)-1]; call.IsCall() && call.Loc.PC+uint64(len(call.Bytes)) == topframe.Ret {
+			return setbp(call.Loc.PC, retFrameCond)
+		}
+	}
+	return nil
+}
+
 // Removes instructions belonging to inlined calls of topframe from pcs.
 // If includeCurrentFn is true it will also remove all instructions
 // belonging to the current function.
@@ -343,6 +606,86 @@ func removeInlinedCalls(dbp Process, pcs []uint64, topframe Stackframe) ([]uint6
 	return pcs, irdr.Err()
 }
 
+// restrictToInlinedCall returns the subset of pcs that belong to the
+// innermost inlined call containing topframe.Current.PC, plus the
+// locations where the inlined call returns to its caller, retframe:
+// the end of each of its address ranges that isn't covered by another one
+// of its ranges, the lines of the caller following the call and the return
+// instructions of the function containing the call, in text, for calls
+// whose result is returned directly.
+// If the inlined call can not be found pcs is returned unchanged.
+func restrictToInlinedCall(bi *BinaryInfo, pcs []uint64, text []AsmInstruction, topframe, retframe Stackframe) ([]uint64, error) {
+	fn := topframe.Current.Fn
+	var ranges [][2]uint64
+	irdr := reader.InlineStack(bi.dwarf, fn.offset, topframe.Current.PC)
+	for irdr.Next() {
+		// entries are returned from the outermost to the innermost call
+		e := irdr.Entry()
+		if e.Tag != dwarf.TagInlinedSubroutine {
+			continue
+		}
+		rngs, err := bi.dwarf.Ranges(e)
+		if err != nil {
+			return pcs, err
+		}
+		if rangesContain(rngs, topframe.Current.PC) {
+			ranges = rngs
+		}
+	}
+	if err := irdr.Err(); err != nil {
+		return pcs, err
+	}
+	if len(ranges) == 0 {
+		return pcs, nil
+	}
+
+	out := make([]uint64, 0, len(pcs))
+	for _, pc := range pcs {
+		if rangesContain(ranges, pc) {
+			out = append(out, pc)
+			continue
+		}
+		if file, line, _ := bi.PCToLine(pc); file == retframe.Call.File && line > retframe.Call.Line {
+			out = append(out, pc)
+		}
+	}
+	for _, rng := range ranges {
+		if end := rng[1]; !rangesContain(ranges, end) && end >= fn.Entry && end < fn.End {
+			out = append(out, end)
+		}
+	}
+	for _, instr := range text {
+		if instr.IsRet() && !rangesContain(ranges, instr.Loc.PC) {
+			out = append(out, instr.Loc.PC)
+		}
+	}
+	return out, nil
+}
+
+// removePrecedingLines returns the subset of pcs that belong to lines of
+// the current file following the current line of topframe.
+func removePrecedingLines(bi *BinaryInfo, pcs []uint64, topframe *Stackframe) []uint64 {
+	out := pcs[:0]
+	for _, pc := range pcs {
+		file, line, _ := bi.PCToLine(pc)
+		if file == topframe.Current.File && line > topframe.Current.Line {
+			out = append(out, pc)
+		}
+	}
+	return out
+}
+
+// rangesContain returns true if one of the address ranges in ranges
+// contains pc.
+func rangesContain(ranges [][2]uint64, pc uint64) bool {
+	for _, rng := range ranges {
+		if pc >= rng[0] && pc < rng[1] {
+			return true
+		}
+	}
+	return false
+}
+
 func removePCsBetween(pcs []uint64, start, end uint64) []uint64 {
 	out := pcs[:0]
 	for _, pc := range pcs {
@@ -353,6 +696,71 @@ func removePCsBetween(pcs []uint64, start, end uint64) []uint64 {
 	return out
 }
 
+// StepIntoTarget is a function called by the current source line, see
+// StepIntoTargets.
+type StepIntoTarget struct {
+	// CallPC is the address of the CALL instruction.
+	CallPC uint64
+	Fn     *Function
+}
+
+// StepIntoTargets returns the list of functions called by the current
+// source line of the selected goroutine, in the order their CALL
+// instructions appear in the instruction stream. Calls that can not be
+// resolved statically and calls to functions that Step would not enter
+// are omitted.
+func StepIntoTargets(dbp Process) ([]StepIntoTarget, error) {
+	if _, err := dbp.Valid(); err != nil {
+		return nil, err
+	}
+	calls, err := stepIntoCalls(dbp)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]StepIntoTarget, len(calls))
+	for i := range calls {
+		r[i] = StepIntoTarget{CallPC: calls[i].Loc.PC, Fn: calls[i].DestLoc.Fn}
+	}
+	return r, nil
+}
+
+// stepIntoCalls returns the CALL instructions of the current source line
+// that setStepIntoBreakpoint would step into.
+func stepIntoCalls(dbp Process) ([]AsmInstruction, error) {
+	selg := dbp.SelectedGoroutine()
+	topframe, _, err := topframe(selg, dbp.CurrentThread())
+	if err != nil {
+		return nil, err
+	}
+	fn := topframe.Current.Fn
+	if fn == nil {
+		return nil, &NoSourceForPCError{topframe.Current.PC}
+	}
+	var thread MemoryReadWriter = dbp.CurrentThread()
+	if selg != nil && selg.Thread != nil {
+		thread = selg.Thread
+	}
+	text, err := disassemble(thread, nil, dbp.Breakpoints(), dbp.BinInfo(), fn.Entry, fn.End, false)
+	if err != nil {
+		return nil, err
+	}
+	var r []AsmInstruction
+	for _, instr := range text {
+		if instr.Loc.File != topframe.Current.File || instr.Loc.Line != topframe.Current.Line || !instr.IsCall() {
+			continue
+		}
+		if instr.DestLoc == nil || instr.DestLoc.Fn == nil {
+			continue
+		}
+		destfn := instr.DestLoc.Fn
+		if destfn.Entry != instr.DestLoc.PC || skipRuntimeFunction(dbp, destfn) || stepFilteredFunction(dbp, destfn) {
+			continue
+		}
+		r = append(r, instr)
+	}
+	return r, nil
+}
+
 func setStepIntoBreakpoint(dbp Process, text []AsmInstruction, cond ast.Expr) error {
 	if len(text) <= 0 {
 		return nil
@@ -374,13 +782,13 @@ func setStepIntoBreakpoint(dbp Process, text []AsmInstruction, cond ast.Expr) er
 	}
 
 	// Skip unexported runtime functions
-	if strings.HasPrefix(fn.Name, "runtime.") && !isExportedRuntime(fn.Name) {
+	if skipRuntimeFunction(dbp, fn) {
 		return nil
 	}
 
-	//TODO(aarzilli): if we want to let users hide functions
-	// or entire packages from being stepped into with 'step'
-	// those extra checks should be done here.
+	if stepFilteredFunction(dbp, fn) {
+		return nil
+	}
 
 	// Set a breakpoint after the function's prologue
 	pc, _ := FirstPCAfterPrologue(dbp, fn, false)
@@ -389,6 +797,7 @@ func setStepIntoBreakpoint(dbp Process, text []AsmInstruction, cond ast.Expr) er
 			return err
 		}
 	}
+	dbp.Breakpoints().setInternalOrigin("step")
 
 	return nil
 }
@@ -527,11 +936,43 @@ func onNextGoroutine(thread Thread, breakpoints *BreakpointMap) (bool, error) {
 	// function or by returning from the function:
 	//   runtime.curg.goid == X && (runtime.frameoff == Y || runtime.frameoff == Z)
 	// Here we are only interested in testing the runtime.curg.goid clause.
+	if bp.internalGoroutineID != 0 {
+		return bp.onInternalGoroutine(thread), nil
+	}
 	w := onNextGoroutineWalker{thread: thread}
 	ast.Walk(&w, bp.internalCond)
 	return w.ret, w.err
 }
 
+// condGoroutineID returns the goroutine ID X of the runtime.curg.goid == X
+// clause of an internal breakpoint condition, created by
+// SameGoroutineCondition, or 0 if there is no such clause.
+func condGoroutineID(cond ast.Expr) int {
+	if cond == nil {
+		return 0
+	}
+	w := condGoroutineIDWalker{}
+	ast.Walk(&w, cond)
+	return w.id
+}
+
+type condGoroutineIDWalker struct {
+	id int
+}
+
+func (w *condGoroutineIDWalker) Visit(n ast.Node) ast.Visitor {
+	if w.id != 0 {
+		return nil
+	}
+	if binx, isbin := n.(*ast.BinaryExpr); isbin && binx.Op == token.EQL && exprToString(binx.X) == "runtime.curg.goid" {
+		if lit, islit := binx.Y.(*ast.BasicLit); islit && lit.Kind == token.INT {
+			w.id, _ = strconv.Atoi(lit.Value)
+		}
+		return nil
+	}
+	return w
+}
+
 type onNextGoroutineWalker struct {
 	thread Thread
 	ret    bool
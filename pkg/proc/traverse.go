@@ -0,0 +1,177 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/derekparker/delve/pkg/dwarf/godwarf"
+)
+
+// TraversalNode is a node of a linked data structure visited by
+// EvalScope.Traverse.
+type TraversalNode struct {
+	// Addr is the address of the node.
+	Addr uintptr
+	// Path is the sequence of fields followed from the root to reach the
+	// node, like .next.next or .children[1].left, empty for the root.
+	Path string
+	// Value is the node, loaded with the configuration passed to Traverse.
+	Value *Variable
+	// Edges are the pointers to other nodes contained in the followed
+	// fields of the node.
+	Edges []TraversalEdge
+}
+
+// TraversalEdge is a pointer from a node to another node, see
+// TraversalNode.
+type TraversalEdge struct {
+	// Field is the field containing the pointer, followed by the index of
+	// the pointer if the field is a slice.
+	Field string
+	// Addr is the address of the node pointed to, zero for nil pointers.
+	Addr uintptr
+	// Cycle is true if the pointer points to the node itself or to one of
+	// the nodes on the path from the root to it.
+	Cycle bool
+}
+
+type traversal struct {
+	nodeType godwarf.Type
+	fields   []*godwarf.StructField
+	maxNodes int
+	cfg      LoadConfig
+	root     *Variable
+
+	visited   map[uintptr]bool
+	onPath    map[uintptr]bool
+	nodes     []TraversalNode
+	truncated bool
+}
+
+// Traverse visits the nodes of the linked data structure, a list, a tree
+// or a graph, starting at the struct or pointer to struct expr. From each
+// node the pointers contained in the fields called fields are followed,
+// fields must be pointers or slices of pointers to the type of the node.
+// If fields is empty all such fields are followed. Nodes are returned in
+// depth first order, each node is visited once even if it is reachable
+// through multiple paths, and edges that close a cycle are marked. At most
+// maxNodes nodes are visited, the second return value is true if there
+// were further nodes to visit.
+func (scope *EvalScope) Traverse(expr string, fields []string, maxNodes int, cfg LoadConfig) ([]TraversalNode, bool, error) {
+	root, err := scope.EvalVariable(expr, LoadConfig{})
+	if err != nil {
+		return nil, false, err
+	}
+	if root.Unreadable != nil {
+		return nil, false, root.Unreadable
+	}
+	if _, isptr := root.RealType.(*godwarf.PtrType); isptr {
+		root = root.maybeDereference()
+		if root.Unreadable != nil {
+			return nil, false, root.Unreadable
+		}
+	}
+	st, isstruct := root.RealType.(*godwarf.StructType)
+	if !isstruct {
+		return nil, false, fmt.Errorf("%s is not a struct or a pointer to a struct", expr)
+	}
+
+	tr := &traversal{
+		nodeType: root.DwarfType,
+		maxNodes: maxNodes,
+		cfg:      cfg,
+		root:     root,
+		visited:  make(map[uintptr]bool),
+		onPath:   make(map[uintptr]bool),
+	}
+	for _, field := range st.Field {
+		if len(fields) == 0 && tr.isEdgeType(field.Type) {
+			tr.fields = append(tr.fields, field)
+		}
+	}
+	for _, name := range fields {
+		var found *godwarf.StructField
+		for _, field := range st.Field {
+			if field.Name == name {
+				found = field
+				break
+			}
+		}
+		if found == nil {
+			return nil, false, fmt.Errorf("%s has no field %s", root.TypeString(), name)
+		}
+		if !tr.isEdgeType(found.Type) {
+			return nil, false, fmt.Errorf("field %s is not a pointer or a slice of pointers to %s", name, root.TypeString())
+		}
+		tr.fields = append(tr.fields, found)
+	}
+	if len(tr.fields) == 0 {
+		return nil, false, errors.New("no fields to follow")
+	}
+
+	if root.Addr != 0 {
+		tr.visit(root.Addr, "")
+	}
+	return tr.nodes, tr.truncated, nil
+}
+
+// isEdgeType returns true if typ is a pointer or a slice of pointers to
+// the type of the nodes.
+func (tr *traversal) isEdgeType(typ godwarf.Type) bool {
+	switch t := resolveTypedef(typ).(type) {
+	case *godwarf.PtrType:
+		return t.Type.String() == tr.nodeType.String()
+	case *godwarf.SliceType:
+		if pt, ok := resolveTypedef(t.ElemType).(*godwarf.PtrType); ok {
+			return pt.Type.String() == tr.nodeType.String()
+		}
+	}
+	return false
+}
+
+func (tr *traversal) visit(addr uintptr, path string) {
+	if len(tr.nodes) >= tr.maxNodes {
+		tr.truncated = true
+		return
+	}
+	tr.visited[addr] = true
+	tr.onPath[addr] = true
+	defer delete(tr.onPath, addr)
+
+	v := tr.root.newVariable("", addr, tr.nodeType, tr.root.mem)
+	v.loadValue(tr.cfg)
+	i := len(tr.nodes)
+	tr.nodes = append(tr.nodes, TraversalNode{Addr: addr, Path: path, Value: v})
+
+	node := tr.root.newVariable("", addr, tr.nodeType, tr.root.mem)
+	var edges []TraversalEdge
+	for _, field := range tr.fields {
+		fv, err := node.toField(field)
+		if err != nil || fv.Unreadable != nil {
+			continue
+		}
+		if _, isptr := fv.RealType.(*godwarf.PtrType); isptr {
+			if p := fv.maybeDereference(); p.Unreadable == nil {
+				edges = append(edges, TraversalEdge{Field: field.Name, Addr: p.Addr})
+			}
+			continue
+		}
+		fv.loadValue(LoadConfig{MaxArrayValues: tr.maxNodes})
+		for j := range fv.Children {
+			if p := &fv.Children[j]; p.Unreadable == nil && len(p.Children) > 0 {
+				edges = append(edges, TraversalEdge{Field: field.Name + "[" + strconv.Itoa(j) + "]", Addr: p.Children[0].Addr})
+			}
+		}
+	}
+	for j := range edges {
+		edges[j].Cycle = tr.onPath[edges[j].Addr]
+	}
+	tr.nodes[i].Edges = edges
+
+	for _, edge := range edges {
+		if edge.Addr != 0 && !tr.visited[edge.Addr] {
+			tr.visit(edge.Addr, path+"."+edge.Field)
+		}
+	}
+}
@@ -118,6 +118,80 @@ func (bi *BinaryInfo) findTypeExpr(expr ast.Expr) (godwarf.Type, error) {
 	return bi.findType(exprToString(expr))
 }
 
+// sameUnderlyingType returns true if a and b have the same underlying type,
+// values of one can be converted to the other. Named basic types, like
+// 'type celsius float64', are described by their own base type entry,
+// basic types are compared by kind, encoding and size, composite types by
+// their element or field types.
+func sameUnderlyingType(a, b godwarf.Type) bool {
+	return sameUnderlyingTypeInternal(a, b, make(map[[2]godwarf.Type]bool))
+}
+
+// sameUnderlyingTypeInternal implements sameUnderlyingType, visited
+// contains the pairs of types that are being compared, it stops the
+// recursion on recursive types.
+func sameUnderlyingTypeInternal(a, b godwarf.Type, visited map[[2]godwarf.Type]bool) bool {
+	a, b = resolveTypedef(a), resolveTypedef(b)
+	if a == b {
+		return true
+	}
+	if visited[[2]godwarf.Type{a, b}] {
+		return true
+	}
+	visited[[2]godwarf.Type{a, b}] = true
+	if a.Size() != b.Size() || a.Common().ReflectKind != b.Common().ReflectKind || reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	same := func(a, b godwarf.Type) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return sameUnderlyingTypeInternal(a, b, visited)
+	}
+	switch ta := a.(type) {
+	case *godwarf.IntType, *godwarf.UintType, *godwarf.FloatType, *godwarf.ComplexType, *godwarf.BoolType, *godwarf.CharType, *godwarf.UcharType, *godwarf.StringType:
+		// kind and size already compared
+		return true
+	case *godwarf.PtrType:
+		return same(ta.Type, b.(*godwarf.PtrType).Type)
+	case *godwarf.ArrayType:
+		tb := b.(*godwarf.ArrayType)
+		return ta.Count == tb.Count && same(ta.Type, tb.Type)
+	case *godwarf.SliceType:
+		return same(ta.ElemType, b.(*godwarf.SliceType).ElemType)
+	case *godwarf.MapType:
+		tb := b.(*godwarf.MapType)
+		return same(ta.KeyType, tb.KeyType) && same(ta.ElemType, tb.ElemType)
+	case *godwarf.ChanType:
+		return same(ta.ElemType, b.(*godwarf.ChanType).ElemType)
+	case *godwarf.FuncType:
+		tb := b.(*godwarf.FuncType)
+		if len(ta.ParamType) != len(tb.ParamType) || !same(ta.ReturnType, tb.ReturnType) {
+			return false
+		}
+		for i := range ta.ParamType {
+			if !same(ta.ParamType[i], tb.ParamType[i]) {
+				return false
+			}
+		}
+		return true
+	case *godwarf.StructType:
+		tb := b.(*godwarf.StructType)
+		if ta.Kind != tb.Kind || len(ta.Field) != len(tb.Field) {
+			return false
+		}
+		for i := range ta.Field {
+			fa, fb := ta.Field[i], tb.Field[i]
+			if fa.Name != fb.Name || fa.ByteOffset != fb.ByteOffset || !same(fa.Type, fb.Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.String() == b.String()
+	}
+}
+
 func complexType(typename string) bool {
 	for _, ch := range typename {
 		switch ch {
@@ -181,6 +255,45 @@ func (v packageVarsByAddr) Len() int               { return len(v) }
 func (v packageVarsByAddr) Less(i int, j int) bool { return v[i].addr < v[j].addr }
 func (v packageVarsByAddr) Swap(i int, j int)      { v[i], v[j] = v[j], v[i] }
 
+// inlinedCall is an inlined call of the function described by the DIE at
+// offset origin, entering it at pc.
+type inlinedCall struct {
+	origin dwarf.Offset
+	pc     uint64
+}
+
+// readInlinedCalls reads the children of the subprogram entry that was
+// just read by rdr and appends all inlined calls it finds to calls. It
+// also returns true if the subprogram has variables captured from a
+// closure context, see closureVars.
+func readInlinedCalls(d *dwarf.Data, rdr *reader.Reader, calls []inlinedCall) ([]inlinedCall, bool) {
+	closure := false
+	depth := 1
+	for depth > 0 {
+		entry, err := rdr.Next()
+		if entry == nil || err != nil {
+			break
+		}
+		if entry.Tag == 0 {
+			depth--
+			continue
+		}
+		if entry.Tag == dwarf.TagInlinedSubroutine {
+			origin, ok := entry.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset)
+			if ranges, _ := d.Ranges(entry); ok && len(ranges) > 0 {
+				calls = append(calls, inlinedCall{origin, ranges[0][0]})
+			}
+		}
+		if _, ok := entry.Val(godwarf.AttrGoClosureOffset).(int64); ok && depth == 1 {
+			closure = true
+		}
+		if entry.Children {
+			depth++
+		}
+	}
+	return calls, closure
+}
+
 func (bi *BinaryInfo) loadDebugInfoMaps(debugLineBytes []byte, wg *sync.WaitGroup, cont func()) {
 	if wg != nil {
 		defer wg.Done()
@@ -195,6 +308,13 @@ func (bi *BinaryInfo) loadDebugInfoMaps(debugLineBytes []byte, wg *sync.WaitGrou
 	var cu *compileUnit = nil
 	var pu *partialUnit = nil
 	var partialUnits = make(map[dwarf.Offset]*partialUnit)
+	// abstractOriginNames maps the offsets of abstract subprograms (the
+	// DIEs describing inlined functions) to their names.
+	abstractOriginNames := make(map[dwarf.Offset]string)
+	var inlinedCalls []inlinedCall
+	// concreteFunctions maps the offsets of out-of-line copies of inlined
+	// functions, which are unnamed, to the offsets of their abstract origin.
+	concreteFunctions := make(map[dwarf.Offset]dwarf.Offset)
 	for entry, err := reader.Next(); entry != nil; entry, err = reader.Next() {
 		if err != nil {
 			break
@@ -364,6 +484,7 @@ func (bi *BinaryInfo) loadDebugInfoMaps(debugLineBytes []byte, wg *sync.WaitGrou
 			}
 
 		case dwarf.TagSubprogram:
+			fnidx := -1
 			ok1 := false
 			var lowpc, highpc uint64
 			if ranges, _ := bi.dwarf.Ranges(entry); len(ranges) == 1 {
@@ -372,6 +493,21 @@ func (bi *BinaryInfo) loadDebugInfoMaps(debugLineBytes []byte, wg *sync.WaitGrou
 				highpc = ranges[0][1]
 			}
 			name, ok2 := entry.Val(dwarf.AttrName).(string)
+			if _, inline := entry.Val(dwarf.AttrInline).(int64); inline && ok2 && pu == nil {
+				if !cu.isgo {
+					name = "C." + name
+				}
+				abstractOriginNames[entry.Offset] = name
+			}
+			if origin, ok := entry.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset); ok1 && !ok2 && ok && pu == nil {
+				concreteFunctions[entry.Offset] = origin
+				fnidx = len(bi.Functions)
+				bi.Functions = append(bi.Functions, Function{
+					Entry: lowpc, End: highpc,
+					offset: entry.Offset,
+					cu:     cu,
+				})
+			}
 			if ok1 && ok2 {
 				if pu != nil {
 					pu.functions = append(pu.functions, Function{
@@ -384,6 +520,7 @@ func (bi *BinaryInfo) loadDebugInfoMaps(debugLineBytes []byte, wg *sync.WaitGrou
 					if !cu.isgo {
 						name = "C." + name
 					}
+					fnidx = len(bi.Functions)
 					bi.Functions = append(bi.Functions, Function{
 						Name:  name,
 						Entry: lowpc, End: highpc,
@@ -392,10 +529,20 @@ func (bi *BinaryInfo) loadDebugInfoMaps(debugLineBytes []byte, wg *sync.WaitGrou
 					})
 				}
 			}
-			reader.SkipChildren()
+			if entry.Children && pu == nil {
+				var closure bool
+				inlinedCalls, closure = readInlinedCalls(bi.dwarf, reader, inlinedCalls)
+				if closure && fnidx >= 0 {
+					bi.Functions[fnidx].closure = true
+				}
+			} else {
+				reader.SkipChildren()
+			}
 
 		}
 	}
+
+	bi.resolveAbstractOrigins(abstractOriginNames, concreteFunctions, inlinedCalls)
 	sort.Sort(compileUnitsByLowpc(bi.compileUnits))
 	sort.Sort(functionsDebugInfoByEntry(bi.Functions))
 	sort.Sort(packageVarsByAddr(bi.packageVars))
@@ -421,6 +568,35 @@ func (bi *BinaryInfo) loadDebugInfoMaps(debugLineBytes []byte, wg *sync.WaitGrou
 	}
 }
 
+// resolveAbstractOrigins names the out-of-line copies of inlined
+// functions using the name of their abstract origin and fills
+// bi.InlinedCalls.
+func (bi *BinaryInfo) resolveAbstractOrigins(abstractOriginNames map[dwarf.Offset]string, concreteFunctions map[dwarf.Offset]dwarf.Offset, inlinedCalls []inlinedCall) {
+	if len(concreteFunctions) > 0 {
+		fns := bi.Functions[:0]
+		for _, fn := range bi.Functions {
+			if fn.Name == "" {
+				fn.Name = abstractOriginNames[concreteFunctions[fn.offset]]
+				if fn.Name == "" {
+					continue
+				}
+			}
+			fns = append(fns, fn)
+		}
+		bi.Functions = fns
+	}
+
+	bi.InlinedCalls = make(map[string][]uint64)
+	for _, call := range inlinedCalls {
+		if name, ok := abstractOriginNames[call.origin]; ok {
+			bi.InlinedCalls[name] = append(bi.InlinedCalls[name], call.pc)
+		}
+	}
+	for _, pcs := range bi.InlinedCalls {
+		sort.Slice(pcs, func(i, j int) bool { return pcs[i] < pcs[j] })
+	}
+}
+
 func uniq(s []string) []string {
 	if len(s) <= 0 {
 		return s
@@ -501,7 +677,7 @@ func runtimeTypeToDIE(_type *Variable, dataAddr uintptr) (typ godwarf.Type, kind
 		if typestring == nil || typestring.Addr == 0 || typestring.Kind != reflect.String {
 			return nil, 0, fmt.Errorf("invalid interface type")
 		}
-		typestring.loadValue(LoadConfig{false, 0, 512, 0, 0})
+		typestring.loadValue(LoadConfig{MaxStringLen: 512})
 		if typestring.Unreadable != nil {
 			return nil, 0, fmt.Errorf("invalid interface type: %v", typestring.Unreadable)
 		}
@@ -559,6 +735,22 @@ func runtimeTypeToDIE(_type *Variable, dataAddr uintptr) (typ godwarf.Type, kind
 	return typ, kind, nil
 }
 
+// runtimeTypeAddr returns the address of the runtime._type describing typ,
+// it is only known for binaries built with Go 1.11 or later.
+func runtimeTypeAddr(bi *BinaryInfo, mem MemoryReadWriter, typ godwarf.Type) (uintptr, error) {
+	if err := loadModuleData(bi, mem); err != nil {
+		return 0, err
+	}
+	if len(bi.moduleData) > 0 {
+		for off, rtdie := range bi.runtimeTypeToDIE {
+			if rtdie.offset == typ.Common().Offset {
+				return bi.moduleData[0].types + uintptr(off), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("could not find the runtime type of %s", typ.String())
+}
+
 type nameOfRuntimeTypeEntry struct {
 	typename string
 	kind     int64
@@ -807,7 +999,7 @@ func nameOfInterfaceRuntimeType(_type *Variable, kind, tflag int64) (string, err
 	buf.WriteString("interface {")
 
 	methods, _ := _type.structMember(interfacetypeFieldMhdr)
-	methods.loadArrayValues(0, LoadConfig{false, 1, 0, 4096, -1})
+	methods.loadArrayValues(0, LoadConfig{MaxVariableRecurse: 1, MaxArrayValues: 4096, MaxStructFields: -1})
 	if methods.Unreadable != nil {
 		return "", nil
 	}
@@ -869,7 +1061,7 @@ func nameOfStructRuntimeType(_type *Variable, kind, tflag int64) (string, error)
 	buf.WriteString("struct {")
 
 	fields, _ := _type.structMember("fields")
-	fields.loadArrayValues(0, LoadConfig{false, 2, 0, 4096, -1})
+	fields.loadArrayValues(0, LoadConfig{MaxVariableRecurse: 2, MaxArrayValues: 4096, MaxStructFields: -1})
 	if fields.Unreadable != nil {
 		return "", fields.Unreadable
 	}
@@ -6,12 +6,14 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"go/ast"
 	"go/constant"
-	"go/parser"
 	"go/token"
 	"math"
 	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"unsafe"
 
@@ -62,6 +64,21 @@ const (
 	VariableArgument
 	// VariableReturnArgument means this variable is a function return value
 	VariableReturnArgument
+	// VariableTruncated means that the value of this variable was only
+	// partially loaded because of the limits of the LoadConfig used: some of
+	// its characters, elements, entries or fields were not read.
+	VariableTruncated
+	// VariableFormatted means that the Value of this struct variable is a
+	// summary returned by the formatter registered for its type, see
+	// Formatters.
+	VariableFormatted
+	// VariableUninitialized is set for local variables declared on the
+	// current line of their frame, the statement declaring them could still
+	// have to run and their value could be garbage.
+	VariableUninitialized
+	// VariableRawString means that the value of this string variable should
+	// be rendered as is, without quoting it, see LoadConfig.RawStrings.
+	VariableRawString
 )
 
 // Variable represents a variable. It contains the address, name,
@@ -104,6 +121,13 @@ type Variable struct {
 
 	LocationExpr string // location expression
 	DeclLine     int64  // line number of this variable's declaration
+
+	// verb used to render the value of integers, see LoadConfig.IntFormat
+	intFormat byte
+
+	// Handle identifies this variable in the VariableHandles it was
+	// registered with, it is zero if all its children were loaded.
+	Handle int
 }
 
 type LoadConfig struct {
@@ -117,10 +141,24 @@ type LoadConfig struct {
 	MaxArrayValues int
 	// MaxStructFields is the maximum number of fields read from a struct, -1 will read all fields.
 	MaxStructFields int
+	// MaxMapEntries is the maximum number of entries read from a map, if it
+	// is zero MaxArrayValues is used instead.
+	MaxMapEntries int
+	// IntFormat is the verb used to render the values of integers by
+	// Variable.IntString: 'x' for hexadecimal, 'o' for octal, 'b' for binary
+	// and 'c' for characters, zero or 'd' for decimal.
+	IntFormat byte
+	// RawStrings requests the values of strings to be rendered without
+	// quoting them, strings loaded with it have the VariableRawString flag.
+	RawStrings bool
 }
 
-var loadSingleValue = LoadConfig{false, 0, 64, 0, 0}
-var loadFullValue = LoadConfig{true, 1, 64, 64, -1}
+var loadSingleValue = LoadConfig{MaxStringLen: 64}
+var loadFullValue = LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+
+// maxComparisonStringLen is the maximum length of strings that can be
+// compared by the expression evaluator.
+const maxComparisonStringLen = 1 << 20
 
 // G status, from: src/runtime/runtime2.go
 const (
@@ -133,6 +171,7 @@ const (
 	Gdead                         // 6
 	Genqueue                      // 7 Only the Gscanenqueue is used.
 	Gcopystack                    // 8 in this state when newstack is moving the stack
+	Gpreempted                    // 9 since Go 1.14, stopped by a preemption request
 )
 
 // G represents a runtime G (goroutine) structure (at least the
@@ -145,6 +184,7 @@ type G struct {
 	GoPC       uint64 // PC of 'go' statement that created this goroutine.
 	StartPC    uint64 // PC of the first function run on this goroutine.
 	WaitReason string // Reason for goroutine being parked.
+	WaitSince  int64  // Value of runtime.nanotime when the goroutine was parked, zero if unknown.
 	Status     uint64
 	stkbarVar  *Variable // stkbar field of g struct
 	stkbarPos  int       // stkbarPos field of g struct
@@ -160,6 +200,9 @@ type G struct {
 	Thread Thread
 
 	variable *Variable
+
+	labels       map[string]string // pprof labels, loaded by Labels
+	labelsLoaded bool
 }
 
 // EvalScope is the scope for variable evaluation. Contains the thread,
@@ -174,6 +217,10 @@ type EvalScope struct {
 	frameOffset int64
 
 	aordr *dwarf.Reader // extra reader to load DW_AT_abstract_origin entries, do not initialize
+
+	callResults map[*ast.CallExpr]*Variable // results of the function calls already executed, if calls are enabled
+
+	closureVars []*Variable // arguments of a deferred call captured by a closure, see Defer.EvalScope
 }
 
 // IsNilErr is returned when a variable is nil.
@@ -287,9 +334,12 @@ func newVariable(name string, addr uintptr, dwarfType godwarf.Type, bi *BinaryIn
 
 func resolveTypedef(typ godwarf.Type) godwarf.Type {
 	for {
-		if tt, ok := typ.(*godwarf.TypedefType); ok {
+		switch tt := typ.(type) {
+		case *godwarf.TypedefType:
 			typ = tt.Type
-		} else {
+		case *godwarf.ParametricType:
+			typ = tt.Type
+		default:
 			return typ
 		}
 	}
@@ -406,7 +456,7 @@ func (gvar *Variable) parseG() (*G, error) {
 		}
 		gvar = gvar.maybeDereference()
 	}
-	gvar.loadValue(LoadConfig{false, 2, 64, 0, -1})
+	gvar.loadValue(LoadConfig{MaxVariableRecurse: 2, MaxStringLen: 64, MaxStructFields: -1})
 	if gvar.Unreadable != nil {
 		return nil, gvar.Unreadable
 	}
@@ -426,9 +476,12 @@ func (gvar *Variable) parseG() (*G, error) {
 		case reflect.String:
 			waitReason = constant.StringVal(wrvar.Value)
 		case reflect.Uint:
-			waitReason = wrvar.ConstDescr()
+			waitReason = readableWaitReason(wrvar.ConstDescr())
 		}
-
+	}
+	var waitSince int64
+	if wsvar := gvar.fieldVariable("waitsince"); wsvar != nil && wsvar.Value != nil {
+		waitSince, _ = constant.Int64Val(wsvar.Value)
 	}
 	var stackhi, stacklo uint64
 	if stackVar := gvar.fieldVariable("stack"); stackVar != nil {
@@ -457,6 +510,7 @@ func (gvar *Variable) parseG() (*G, error) {
 		SP:         uint64(sp),
 		BP:         uint64(bp),
 		WaitReason: waitReason,
+		WaitSince:  waitSince,
 		Status:     uint64(status),
 		CurrentLoc: Location{PC: uint64(pc), File: f, Line: l, Fn: fn},
 		variable:   gvar,
@@ -480,6 +534,40 @@ func (v *Variable) loadFieldNamed(name string) *Variable {
 	return v
 }
 
+// loadFullString reloads the value of a string variable that was
+// truncated by its load configuration, up to maxComparisonStringLen bytes.
+func (v *Variable) loadFullString() {
+	if v.Kind != reflect.String || v.Unreadable != nil || v.Base == 0 || v.Value == nil {
+		return
+	}
+	if int64(len(constant.StringVal(v.Value))) == v.Len || v.Len > maxComparisonStringLen {
+		return
+	}
+	val, err := readStringValue(DereferenceMemory(v.mem), v.Base, v.Len, LoadConfig{MaxStringLen: int(v.Len)})
+	if err != nil {
+		return
+	}
+	v.Value = constant.MakeString(val)
+}
+
+// stringRange returns n bytes of the string v starting at offset off, the
+// bytes are read from the target unless they are part of the loaded value
+// of v.
+func (v *Variable) stringRange(off, n int64) (string, error) {
+	if n == 0 {
+		return "", nil
+	}
+	if v.Value != nil {
+		if s := constant.StringVal(v.Value); int64(len(s)) >= off+n {
+			return s[off : off+n], nil
+		}
+	}
+	if v.Base == 0 {
+		return "", fmt.Errorf("string value not available")
+	}
+	return readStringValue(DereferenceMemory(v.mem), v.Base+uintptr(off), n, LoadConfig{MaxStringLen: int(n)})
+}
+
 func (v *Variable) fieldVariable(name string) *Variable {
 	for i := range v.Children {
 		if child := &v.Children[i]; child.Name == name {
@@ -551,12 +639,148 @@ func (g *G) StartLoc() Location {
 	return Location{PC: g.StartPC, File: f, Line: l, Fn: fn}
 }
 
+// Labels returns the pprof labels of the goroutine, set with
+// runtime/pprof.SetGoroutineLabels or runtime/pprof.Do.
+func (g *G) Labels() map[string]string {
+	if !g.labelsLoaded {
+		g.labels = loadGoroutineLabels(g.variable)
+		g.labelsLoaded = true
+	}
+	return g.labels
+}
+
+// Ancestor describes a goroutine that created, directly or indirectly,
+// another goroutine. The runtime only records ancestors when the target
+// runs with the GODEBUG=tracebackancestors=N environment variable.
+type Ancestor struct {
+	ID         int    // Goroutine ID of the ancestor.
+	GoPC       uint64 // PC of the 'go' statement that created the ancestor.
+	Unreadable error
+
+	pcsVar *Variable
+}
+
+// Ancestors returns the ancestors of the goroutine, starting from the
+// goroutine that created it, up to n of them.
+func (g *G) Ancestors(n int) ([]Ancestor, error) {
+	if g.variable == nil || g.variable.Unreadable != nil {
+		return nil, nil
+	}
+	av, err := g.variable.structMember("ancestors")
+	if err != nil {
+		// ancestors were introduced in Go 1.11
+		return nil, nil
+	}
+	av = av.maybeDereference()
+	if av.Unreadable != nil {
+		return nil, av.Unreadable
+	}
+	if av.Addr == 0 {
+		return nil, nil
+	}
+	av.loadValue(LoadConfig{MaxVariableRecurse: 1, MaxArrayValues: n, MaxStructFields: -1})
+	if av.Unreadable != nil {
+		return nil, av.Unreadable
+	}
+	r := make([]Ancestor, len(av.Children))
+	for i := range av.Children {
+		child := &av.Children[i]
+		if child.Unreadable != nil {
+			r[i].Unreadable = child.Unreadable
+			continue
+		}
+		goidVar, gopcVar, pcsVar := child.fieldVariable("goid"), child.fieldVariable("gopc"), child.fieldVariable("pcs")
+		if goidVar == nil || goidVar.Value == nil || gopcVar == nil || gopcVar.Value == nil || pcsVar == nil {
+			r[i].Unreadable = errors.New("malformed ancestor")
+			continue
+		}
+		id, _ := constant.Int64Val(goidVar.Value)
+		gopc, _ := constant.Int64Val(gopcVar.Value)
+		r[i].ID = int(id)
+		r[i].GoPC = uint64(gopc)
+		r[i].pcsVar = pcsVar
+	}
+	return r, nil
+}
+
+// Stack returns the stack of the ancestor at the time it created its
+// child, up to n frames. Only the locations of the returned frames are
+// known.
+func (a *Ancestor) Stack(n int) ([]Stackframe, error) {
+	if a.Unreadable != nil {
+		return nil, a.Unreadable
+	}
+	pcsVar := a.pcsVar.clone()
+	pcsVar.loadValue(LoadConfig{MaxArrayValues: n})
+	if pcsVar.Unreadable != nil {
+		return nil, pcsVar.Unreadable
+	}
+	r := make([]Stackframe, len(pcsVar.Children))
+	for i := range pcsVar.Children {
+		if pcsVar.Children[i].Unreadable != nil {
+			r[i].Err = pcsVar.Children[i].Unreadable
+			continue
+		}
+		pc, _ := constant.Int64Val(pcsVar.Children[i].Value)
+		// the pcs saved by the runtime are return addresses, except for the
+		// topmost frame.
+		callpc := uint64(pc)
+		if i > 0 {
+			callpc--
+		}
+		f, l, fn := pcsVar.bi.PCToLine(callpc)
+		r[i].Current = Location{PC: uint64(pc), File: f, Line: l, Fn: fn}
+		r[i].Call = r[i].Current
+		r[i].Call.PC = callpc
+	}
+	return r, nil
+}
+
+// loadGoroutineLabels reads the pprof labels of the goroutine described by
+// the g struct gvar. The labels field of the g struct points to a
+// runtime/pprof.labelMap.
+func loadGoroutineLabels(gvar *Variable) map[string]string {
+	if gvar == nil || gvar.Unreadable != nil {
+		return nil
+	}
+	lv, err := gvar.structMember("labels")
+	if err != nil {
+		return nil
+	}
+	addr, err := readUintRaw(lv.mem, lv.Addr, int64(gvar.bi.Arch.PtrSize()))
+	if err != nil || addr == 0 {
+		return nil
+	}
+	typ, err := gvar.bi.findType("runtime/pprof.labelMap")
+	if err != nil {
+		return nil
+	}
+	mv := newVariable("labels", uintptr(addr), typ, gvar.bi, gvar.mem)
+	it := mv.mapIterator()
+	if it == nil {
+		return nil
+	}
+	labels := make(map[string]string)
+	for it.next() {
+		k, v := it.key(), it.value()
+		k.loadValue(loadFullValue)
+		v.loadValue(loadFullValue)
+		if k.Unreadable != nil || v.Unreadable != nil || k.Value == nil || v.Value == nil {
+			continue
+		}
+		k.loadFullString()
+		v.loadFullString()
+		labels[constant.StringVal(k.Value)] = constant.StringVal(v.Value)
+	}
+	return labels
+}
+
 // Returns the list of saved return addresses used by stack barriers
 func (g *G) stkbar() ([]savedLR, error) {
 	if g.stkbarVar == nil { // stack barriers were removed in Go 1.9
 		return nil, nil
 	}
-	g.stkbarVar.loadValue(LoadConfig{false, 1, 0, int(g.stkbarVar.Len), 3})
+	g.stkbarVar.loadValue(LoadConfig{MaxVariableRecurse: 1, MaxArrayValues: int(g.stkbarVar.Len), MaxStructFields: 3})
 	if g.stkbarVar.Unreadable != nil {
 		return nil, fmt.Errorf("unreadable stkbar: %v\n", g.stkbarVar.Unreadable)
 	}
@@ -583,7 +807,7 @@ func (scope *EvalScope) EvalVariable(name string, cfg LoadConfig) (*Variable, er
 
 // SetVariable sets the value of the named variable
 func (scope *EvalScope) SetVariable(name, value string) error {
-	t, err := parser.ParseExpr(name)
+	t, err := ParseExpr(name)
 	if err != nil {
 		return err
 	}
@@ -601,27 +825,38 @@ func (scope *EvalScope) SetVariable(name, value string) error {
 		return fmt.Errorf("Expression \"%s\" is unreadable: %v", name, xv.Unreadable)
 	}
 
-	t, err = parser.ParseExpr(value)
+	yv, err := scope.evalAssignedValue(value, xv)
 	if err != nil {
 		return err
 	}
 
+	return xv.setValue(yv)
+}
+
+// evalAssignedValue evaluates value and checks that it can be assigned to
+// xv.
+func (scope *EvalScope) evalAssignedValue(value string, xv *Variable) (*Variable, error) {
+	t, err := ParseExpr(value)
+	if err != nil {
+		return nil, err
+	}
+
 	yv, err := scope.evalAST(t)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	yv.loadValue(loadSingleValue)
 
 	if err := yv.isType(xv.RealType, xv.Kind); err != nil {
-		return err
+		return nil, err
 	}
 
 	if yv.Unreadable != nil {
-		return fmt.Errorf("Expression \"%s\" is unreadable: %v", value, yv.Unreadable)
+		return nil, fmt.Errorf("Expression \"%s\" is unreadable: %v", value, yv.Unreadable)
 	}
 
-	return xv.setValue(yv)
+	return yv, nil
 }
 
 // LocalVariables returns all local variables from the current function scope.
@@ -662,6 +897,16 @@ func filterVariables(vars []*Variable, pred func(v *Variable) bool) []*Variable
 
 // PackageVariables returns the name, value, and type of all package variables in the application.
 func (scope *EvalScope) PackageVariables(cfg LoadConfig) ([]*Variable, error) {
+	return scope.FilterPackageVariables("", nil, &cfg)
+}
+
+// FilterPackageVariables returns the package variables of the package with
+// path pkg whose name matches filter. If pkg is empty variables of all
+// packages are returned, if filter is nil they are not filtered by name.
+// Values are loaded with cfg, if cfg is nil they are not loaded at all and
+// only the name, type and address of variables are returned, their values
+// can be read later by evaluating their names.
+func (scope *EvalScope) FilterPackageVariables(pkg string, filter *regexp.Regexp, cfg *LoadConfig) ([]*Variable, error) {
 	var vars []*Variable
 	reader := scope.DwarfReader()
 
@@ -679,13 +924,21 @@ func (scope *EvalScope) PackageVariables(cfg LoadConfig) ([]*Variable, error) {
 		if typoff, ok := entry.Val(dwarf.AttrType).(dwarf.Offset); !ok || typoff == utypoff {
 			continue
 		}
+		name, _ := entry.Val(dwarf.AttrName).(string)
+		if (pkg != "" && packageName(name) != pkg) || (filter != nil && !filter.MatchString(name)) {
+			continue
+		}
 
 		// Ignore errors trying to extract values
 		val, err := scope.extractVarInfoFromEntry(entry)
 		if err != nil {
 			continue
 		}
-		val.loadValue(cfg)
+		if cfg != nil {
+			val.loadValue(*cfg)
+		} else {
+			val.OnlyAddr = true
+		}
 		vars = append(vars, val)
 	}
 
@@ -701,6 +954,11 @@ func (scope *EvalScope) findGlobal(name string) (*Variable, error) {
 			if err != nil {
 				return nil, err
 			}
+			if isdecl, _ := entry.Val(dwarf.AttrDeclaration).(bool); isdecl {
+				// extern declaration of a C variable, the definition is in
+				// a different compile unit
+				continue
+			}
 			return scope.extractVarInfoFromEntry(entry)
 		}
 	}
@@ -752,6 +1010,17 @@ func (v *Variable) structMember(memberName string) (*Variable, error) {
 		if len(v.Children) > 0 {
 			v = &v.Children[0]
 		}
+	case reflect.Func:
+		cvs, err := v.closureVars()
+		if err != nil {
+			return nil, err
+		}
+		for _, cv := range cvs {
+			if cv.Name == memberName {
+				return cv, nil
+			}
+		}
+		return nil, fmt.Errorf("%s has no captured variable named %s", v.Name, memberName)
 	}
 	structVar := v.maybeDereference()
 	structVar.Name = v.Name
@@ -844,6 +1113,7 @@ func (scope *EvalScope) extractVarInfoFromEntry(varEntry *dwarf.Entry) (*Variabl
 	if err != nil {
 		return nil, err
 	}
+	t = scope.resolveParametricTypes(t)
 
 	addr, pieces, descr, err := scope.BinInfo.Location(entry, dwarf.AttrLocation, scope.PC, scope.Regs)
 	mem := scope.Mem
@@ -861,6 +1131,211 @@ func (scope *EvalScope) extractVarInfoFromEntry(varEntry *dwarf.Entry) (*Variabl
 	return v, nil
 }
 
+// dictArgName is the name of the argument of generic functions containing
+// the address of their dictionary.
+const dictArgName = ".dict"
+
+// resolveParametricType returns the concrete type of pt, a type parameter of
+// the generic function of scope, read from the dictionary of the function.
+// The dictionary is an array of pointers to runtime._type structs. If the
+// dictionary can not be read the shape type of pt is returned.
+func (scope *EvalScope) resolveParametricType(pt *godwarf.ParametricType) godwarf.Type {
+	dict, err := scope.dictAddr()
+	if err != nil || dict == 0 {
+		return pt.Type
+	}
+	ptrSize := int64(scope.BinInfo.Arch.PtrSize())
+	rtypeAddr, err := readUintRaw(scope.Mem, uintptr(dict+uint64(pt.DictIndex*ptrSize)), ptrSize)
+	if err != nil || rtypeAddr == 0 {
+		return pt.Type
+	}
+	rtyp, err := scope.BinInfo.findType("runtime._type")
+	if err != nil {
+		return pt.Type
+	}
+	typ, _, err := runtimeTypeToDIE(scope.newVariable("", uintptr(rtypeAddr), rtyp, scope.Mem), 0)
+	if err != nil {
+		return pt.Type
+	}
+	return typ
+}
+
+// resolveParametricTypes returns typ with the type parameters of the
+// generic function of scope replaced by their concrete types, see
+// resolveParametricType. Pointer, array, slice, map, channel, struct and
+// named types containing type parameters are copied, as are the names
+// mentioning the shape types of the parameters.
+func (scope *EvalScope) resolveParametricTypes(typ godwarf.Type) godwarf.Type {
+	if pt, ok := typ.(*godwarf.ParametricType); ok {
+		return scope.resolveParametricType(pt)
+	}
+	if scope.Fn == nil || !strings.Contains(scope.Fn.Name, "[") {
+		// only instantiations of generic functions have type parameters
+		return typ
+	}
+	r := &parametricResolver{
+		scope:    scope,
+		hasParam: make(map[godwarf.Type]bool),
+		params:   make(map[*godwarf.ParametricType]godwarf.Type),
+		resolved: make(map[godwarf.Type]godwarf.Type),
+	}
+	if !r.visit(typ) {
+		return typ
+	}
+	oldnew := make([]string, 0, 2*len(r.params))
+	for pt, rt := range r.params {
+		oldnew = append(oldnew, pt.Name, rt.String())
+	}
+	r.names = strings.NewReplacer(oldnew...)
+	return r.resolve(typ)
+}
+
+// parametricResolver replaces the type parameters contained in a type
+// with their concrete types.
+type parametricResolver struct {
+	scope    *EvalScope
+	hasParam map[godwarf.Type]bool
+	params   map[*godwarf.ParametricType]godwarf.Type
+	resolved map[godwarf.Type]godwarf.Type
+	names    *strings.Replacer
+}
+
+// visit returns true if typ contains a type parameter and resolves all the
+// type parameters it contains.
+func (r *parametricResolver) visit(typ godwarf.Type) bool {
+	if has, ok := r.hasParam[typ]; ok {
+		return has
+	}
+	r.hasParam[typ] = false // breaks cycles
+	has := false
+	switch t := typ.(type) {
+	case *godwarf.ParametricType:
+		r.params[t] = r.scope.resolveParametricType(t)
+		has = true
+	case *godwarf.PtrType:
+		has = r.visit(t.Type)
+	case *godwarf.ArrayType:
+		has = r.visit(t.Type)
+	case *godwarf.SliceType:
+		has = r.visitFields(t.Field)
+		has = r.visit(t.ElemType) || has
+	case *godwarf.StructType:
+		has = r.visitFields(t.Field)
+	case *godwarf.MapType:
+		has = r.visit(t.Type)
+		has = r.visit(t.KeyType) || has
+		has = r.visit(t.ElemType) || has
+	case *godwarf.ChanType:
+		has = r.visit(t.Type)
+		has = r.visit(t.ElemType) || has
+	case *godwarf.TypedefType:
+		has = r.visit(t.Type)
+	}
+	r.hasParam[typ] = has
+	return has
+}
+
+func (r *parametricResolver) visitFields(fields []*godwarf.StructField) bool {
+	has := false
+	for _, field := range fields {
+		has = r.visit(field.Type) || has
+	}
+	return has
+}
+
+// resolve returns a copy of typ with its type parameters replaced by their
+// concrete types, types not containing type parameters are returned as is.
+func (r *parametricResolver) resolve(typ godwarf.Type) godwarf.Type {
+	if !r.hasParam[typ] {
+		return typ
+	}
+	if rt, ok := r.resolved[typ]; ok {
+		return rt
+	}
+	switch t := typ.(type) {
+	case *godwarf.ParametricType:
+		r.resolved[typ] = r.params[t]
+	case *godwarf.PtrType:
+		nt := *t
+		r.resolved[typ] = &nt
+		nt.Name = r.names.Replace(t.Name)
+		nt.Type = r.resolve(t.Type)
+	case *godwarf.ArrayType:
+		nt := *t
+		r.resolved[typ] = &nt
+		nt.Name = r.names.Replace(t.Name)
+		nt.Type = r.resolve(t.Type)
+	case *godwarf.SliceType:
+		nt := *t
+		r.resolved[typ] = &nt
+		nt.Name = r.names.Replace(t.Name)
+		nt.StructName = r.names.Replace(t.StructName)
+		nt.Field = r.resolveFields(t.Field)
+		nt.ElemType = r.resolve(t.ElemType)
+	case *godwarf.StructType:
+		nt := *t
+		r.resolved[typ] = &nt
+		nt.Name = r.names.Replace(t.Name)
+		nt.StructName = r.names.Replace(t.StructName)
+		nt.Field = r.resolveFields(t.Field)
+	case *godwarf.MapType:
+		nt := *t
+		r.resolved[typ] = &nt
+		nt.Name = r.names.Replace(t.Name)
+		nt.Type = r.resolve(t.Type)
+		nt.KeyType = r.resolve(t.KeyType)
+		nt.ElemType = r.resolve(t.ElemType)
+	case *godwarf.ChanType:
+		nt := *t
+		r.resolved[typ] = &nt
+		nt.Name = r.names.Replace(t.Name)
+		nt.Type = r.resolve(t.Type)
+		nt.ElemType = r.resolve(t.ElemType)
+	case *godwarf.TypedefType:
+		nt := *t
+		r.resolved[typ] = &nt
+		nt.Name = r.names.Replace(t.Name)
+		nt.Type = r.resolve(t.Type)
+	default:
+		return typ
+	}
+	return r.resolved[typ]
+}
+
+func (r *parametricResolver) resolveFields(fields []*godwarf.StructField) []*godwarf.StructField {
+	r2 := make([]*godwarf.StructField, len(fields))
+	for i, field := range fields {
+		nfield := *field
+		nfield.Type = r.resolve(field.Type)
+		r2[i] = &nfield
+	}
+	return r2
+}
+
+// dictAddr returns the address of the dictionary of the generic function of
+// scope, or 0 if the function is not generic.
+func (scope *EvalScope) dictAddr() (uint64, error) {
+	if scope.Fn == nil {
+		return 0, nil
+	}
+	varReader := reader.Variables(scope.BinInfo.dwarf, scope.Fn.offset, scope.PC, scope.Line, false)
+	for varReader.Next() {
+		entry := varReader.Entry()
+		if name, _ := entry.Val(dwarf.AttrName).(string); name != dictArgName || entry.Tag != dwarf.TagFormalParameter {
+			continue
+		}
+		v, err := scope.extractVarInfoFromEntry(entry)
+		if err != nil {
+			return 0, err
+		}
+		if v.Unreadable != nil {
+			return 0, v.Unreadable
+		}
+		return readUintRaw(v.mem, v.Addr, int64(scope.BinInfo.Arch.PtrSize()))
+	}
+	return 0, varReader.Err()
+}
+
 // If v is a pointer a new variable is returned containing the value pointed by v.
 func (v *Variable) maybeDereference() *Variable {
 	if v.Unreadable != nil {
@@ -898,6 +1373,7 @@ func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 	}
 
 	v.loaded = true
+	v.intFormat = cfg.IntFormat
 	switch v.Kind {
 	case reflect.Ptr, reflect.UnsafePointer:
 		v.Len = 1
@@ -929,12 +1405,21 @@ func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 		} else {
 			// loads length so that the client knows that the map isn't empty
 			v.mapIterator()
+			if v.Len > 0 {
+				v.Flags |= VariableTruncated
+			}
 		}
 
 	case reflect.String:
 		var val string
 		val, v.Unreadable = readStringValue(DereferenceMemory(v.mem), v.Base, v.Len, cfg)
 		v.Value = constant.MakeString(val)
+		if v.Unreadable == nil && int64(len(val)) < v.Len {
+			v.Flags |= VariableTruncated
+		}
+		if cfg.RawStrings {
+			v.Flags |= VariableRawString
+		}
 
 	case reflect.Slice, reflect.Array:
 		v.loadArrayValues(recurseLevel, cfg)
@@ -957,6 +1442,10 @@ func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 				v.Children[i].loadValueInternal(recurseLevel+1, cfg)
 			}
 		}
+		if int64(len(v.Children)) < v.Len {
+			v.Flags |= VariableTruncated
+		}
+		v.applyFormatter()
 
 	case reflect.Interface:
 		v.loadInterface(recurseLevel, true, cfg)
@@ -993,6 +1482,15 @@ func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 		}
 	case reflect.Func:
 		v.readFunctionPtr()
+		if v.Unreadable == nil && v.Base != 0 && recurseLevel <= cfg.MaxVariableRecurse {
+			// the function name is still useful if the captured variables
+			// can not be read.
+			cvs, _ := v.closureVars()
+			for _, cv := range cvs {
+				cv.loadValueInternal(recurseLevel+1, cfg)
+				v.Children = append(v.Children, *cv)
+			}
+		}
 	default:
 		v.Unreadable = fmt.Errorf("unknown or unsupported kind: \"%s\"", v.Kind.String())
 	}
@@ -1020,13 +1518,48 @@ func (v *Variable) setValue(y *Variable) error {
 		if t, isptr := v.RealType.(*godwarf.PtrType); isptr {
 			err = v.writeUint(uint64(y.Children[0].Addr), int64(t.ByteSize))
 		} else {
-			return fmt.Errorf("can not set variables of type %s (not implemented)", v.Kind.String())
+			err = v.writeCopy(y)
 		}
 	}
 
 	return err
 }
 
+// writeCopy sets v, which does not have a basic type, to y by copying the
+// memory of y. Nil is written as the zero value, slices and strings created
+// by slicing a variable are written from their fields.
+func (v *Variable) writeCopy(y *Variable) error {
+	buf := make([]byte, v.RealType.Size())
+	ptrSize := v.bi.Arch.PtrSize()
+	putUint := func(off int, n uint64) {
+		switch ptrSize {
+		case 4:
+			binary.LittleEndian.PutUint32(buf[off:], uint32(n))
+		case 8:
+			binary.LittleEndian.PutUint64(buf[off:], n)
+		}
+	}
+	switch {
+	case y == nilVariable:
+		// zero value
+	case y.Addr != 0:
+		if _, err := y.mem.ReadMemory(buf, y.Addr); err != nil {
+			return err
+		}
+	case y.Kind == reflect.Slice && len(buf) >= 3*ptrSize:
+		putUint(0, uint64(y.Base))
+		putUint(ptrSize, uint64(y.Len))
+		putUint(2*ptrSize, uint64(y.Cap))
+	case y.Kind == reflect.String && y.Flags&VariableConstant == 0 && len(buf) >= 2*ptrSize:
+		putUint(0, uint64(y.Base))
+		putUint(ptrSize, uint64(y.Len))
+	default:
+		return fmt.Errorf("can not set variables of type %s (not implemented)", v.Kind.String())
+	}
+	_, err := v.mem.WriteMemory(v.Addr, buf)
+	return err
+}
+
 func readStringInfo(mem MemoryReadWriter, arch Arch, addr uintptr) (uintptr, int64, error) {
 	// string data structure is always two ptrs in size. Addr, followed by len
 	// http://research.swtch.com/godata
@@ -1224,6 +1757,9 @@ func (v *Variable) loadArrayValues(recurseLevel int, cfg LoadConfig) {
 			break
 		}
 	}
+	if int64(len(v.Children)) < v.Len {
+		v.Flags |= VariableTruncated
+	}
 }
 
 func (v *Variable) readComplex(size int64) {
@@ -1398,6 +1934,62 @@ func (v *Variable) readFunctionPtr() {
 	v.Value = constant.MakeString(fn.Name)
 }
 
+// closureVars returns the variables captured by the closure v. They are
+// stored in the closure context, after the pointer to the function, at the
+// offsets recorded by the compiler in the DW_AT_go_closure_offset attribute
+// of the variables of the function, emitted since Go 1.23. Variables
+// captured by reference are dereferenced. Functions without variables
+// with that attribute are not scanned.
+func (v *Variable) closureVars() ([]*Variable, error) {
+	ptrSize := int64(v.bi.Arch.PtrSize())
+	ctxaddr, err := readUintRaw(v.mem, v.Addr, ptrSize)
+	if err != nil || ctxaddr == 0 {
+		return nil, err
+	}
+	mem := DereferenceMemory(v.mem)
+	pc, err := readUintRaw(mem, uintptr(ctxaddr), ptrSize)
+	if err != nil {
+		return nil, err
+	}
+	fn := v.bi.PCToFunc(pc)
+	if fn == nil {
+		return nil, fmt.Errorf("could not find function for %#x", pc)
+	}
+	if !fn.closure {
+		return nil, nil
+	}
+
+	rdr := v.bi.dwarf.Reader()
+	rdr.Seek(fn.offset)
+	e, err := rdr.Next()
+	if err != nil || e == nil || !e.Children {
+		return nil, err
+	}
+	var r []*Variable
+	for {
+		e, err := rdr.Next()
+		if err != nil {
+			return nil, err
+		}
+		if e == nil || e.Tag == 0 {
+			break
+		}
+		if e.Children {
+			rdr.SkipChildren()
+		}
+		off, ok := e.Val(godwarf.AttrGoClosureOffset).(int64)
+		if !ok || e.Tag != dwarf.TagVariable {
+			continue
+		}
+		_, name, typ, err := readVarEntry(e, v.bi)
+		if err != nil {
+			continue
+		}
+		r = append(r, escapedVariable(newVariable(name, uintptr(int64(ctxaddr)+off), typ, v.bi, mem)))
+	}
+	return r, nil
+}
+
 func (v *Variable) loadMap(recurseLevel int, cfg LoadConfig) {
 	it := v.mapIterator()
 	if it == nil {
@@ -1411,10 +2003,15 @@ func (v *Variable) loadMap(recurseLevel int, cfg LoadConfig) {
 		}
 	}
 
+	maxEntries := cfg.MaxMapEntries
+	if maxEntries == 0 {
+		maxEntries = cfg.MaxArrayValues
+	}
+
 	count := 0
 	errcount := 0
 	for it.next() {
-		if count >= cfg.MaxArrayValues {
+		if count >= maxEntries {
 			break
 		}
 		key := it.key()
@@ -1436,6 +2033,9 @@ func (v *Variable) loadMap(recurseLevel int, cfg LoadConfig) {
 			break
 		}
 	}
+	if int64(v.mapSkip+count) < v.Len {
+		v.Flags |= VariableTruncated
+	}
 }
 
 type mapIterator struct {
@@ -1794,14 +2394,66 @@ func (v *Variable) ConstDescr() string {
 
 	switch v.Kind {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		fallthrough
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		n, _ := constant.Int64Val(v.Value)
 		return ctyp.describe(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// the values of constants are stored as int64, values of unsigned
+		// types with the highest bit set are negative
+		n, _ := constant.Uint64Val(v.Value)
+		return ctyp.describe(int64(n))
 	}
 	return ""
 }
 
+// IntString returns the value of v rendered according to the IntFormat of
+// the LoadConfig used to load it, or by the formatter of its type, like
+// time.Duration, if it was loaded without an IntFormat. It returns the
+// empty string if v is not an integer or if neither applies.
+func (v *Variable) IntString() string {
+	if v.Value == nil || v.Value.Kind() != constant.Int {
+		return ""
+	}
+	switch v.Kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		return ""
+	}
+	if (v.intFormat == 0 || v.intFormat == 'd') && v.DwarfType != nil {
+		if fn := intFormatters[v.DwarfType.Common().Name]; fn != nil {
+			n, _ := constant.Int64Val(v.Value)
+			return fn(n)
+		}
+	}
+	return formatInt(v.Value, v.intFormat)
+}
+
+// formatInt renders the integer constant val with verb, one of 'x', 'o',
+// 'b' and 'c', for other verbs it returns the empty string.
+func formatInt(val constant.Value, verb byte) string {
+	var prefix string
+	var base int
+	switch verb {
+	case 'x':
+		prefix, base = "0x", 16
+	case 'o':
+		prefix, base = "0", 8
+	case 'b':
+		prefix, base = "0b", 2
+	case 'c':
+		n, _ := constant.Int64Val(val)
+		return strconv.QuoteRune(rune(n))
+	default:
+		return ""
+	}
+	if constant.Sign(val) < 0 {
+		n, _ := constant.Int64Val(val)
+		return "-" + prefix + strconv.FormatUint(uint64(-n), base)
+	}
+	n, _ := constant.Uint64Val(val)
+	return prefix + strconv.FormatUint(n, base)
+}
+
 // popcnt is the number of bits set to 1 in x.
 // It's the same as math/bits.OnesCount64, copied here so that we can build
 // on versions of go that don't have math/bits.
@@ -1889,6 +2541,15 @@ func (scope *EvalScope) Locals() ([]*Variable, error) {
 	if scope.Fn == nil {
 		return nil, errors.New("unable to find function context")
 	}
+	if scope.closureVars != nil {
+		// the deferred call did not start, its only variables are the
+		// ones captured by its closure
+		vars := make([]*Variable, len(scope.closureVars))
+		for i := range scope.closureVars {
+			vars[i] = scope.closureVars[i].clone()
+		}
+		return vars, nil
+	}
 
 	var vars []*Variable
 	var depths []int
@@ -1896,6 +2557,10 @@ func (scope *EvalScope) Locals() ([]*Variable, error) {
 	hasScopes := false
 	for varReader.Next() {
 		entry := varReader.Entry()
+		if name, _ := entry.Val(dwarf.AttrName).(string); name == dictArgName {
+			// dictionary of generic functions, see resolveParametricType
+			continue
+		}
 		val, err := scope.extractVarInfoFromEntry(entry)
 		if err != nil {
 			// skip variables that we can't parse yet
@@ -1935,24 +2600,144 @@ func (scope *EvalScope) Locals() ([]*Variable, error) {
 	lvn := map[string]*Variable{} // lvn[n] is the last variable we saw named n
 
 	for i, v := range vars {
-		if name := v.Name; len(name) > 1 && name[0] == '&' {
-			v = v.maybeDereference()
-			if v.Addr == 0 {
-				v.Unreadable = fmt.Errorf("no address for escaped variable")
+		v = escapedVariable(v)
+		vars[i] = v
+		scope.markUninitialized(v)
+		otherv := lvn[v.Name]
+		switch {
+		case otherv == nil:
+			lvn[v.Name] = v
+		case !hasScopes && v.DeclLine < otherv.DeclLine:
+			// Without lexical blocks the declaration closest to the current
+			// line is the one in scope.
+			v.Flags |= VariableShadowed
+		default:
+			otherv.Flags |= VariableShadowed
+			lvn[v.Name] = v
+		}
+	}
+
+	return vars, nil
+}
+
+// escapedVariable returns the variable pointed to by v if v is the pointer
+// to a variable that escaped to the heap, these pointers are called '&'
+// followed by the name of the variable.
+func escapedVariable(v *Variable) *Variable {
+	name := v.Name
+	if len(name) <= 1 || name[0] != '&' {
+		return v
+	}
+	declLine := v.DeclLine
+	v = v.maybeDereference()
+	if v.Addr == 0 {
+		v.Unreadable = fmt.Errorf("no address for escaped variable")
+	}
+	v.Name = name[1:]
+	v.DeclLine = declLine
+	v.Flags |= VariableEscaped
+	return v
+}
+
+// markUninitialized sets VariableUninitialized on v if it is a local
+// variable declared on the current line of scope. Variables declared after
+// the current line, or in lexical blocks that do not contain the current
+// PC, are not returned by Locals at all.
+func (scope *EvalScope) markUninitialized(v *Variable) {
+	if v.Flags&(VariableArgument|VariableReturnArgument) == 0 && v.DeclLine == int64(scope.Line) {
+		v.Flags |= VariableUninitialized
+	}
+}
+
+// VariableInstance is one of the declarations of a local variable or
+// argument, see EvalScope.LocalInstances.
+type VariableInstance struct {
+	*Variable
+	// Ranges contains the address ranges of the lexical block, or function,
+	// the variable is declared in.
+	Ranges [][2]uint64
+	// InScope is true for the declaration the name refers to at the PC of
+	// the scope, there is at most one such declaration.
+	InScope bool
+}
+
+// LocalInstances returns every declaration of the local variable or
+// argument called name in the function of scope, including the ones
+// shadowed by a declaration in a nested lexical block and the ones in
+// lexical blocks that do not contain the current PC. The declaration used
+// by the evaluator at the current PC is marked as InScope, only the
+// declarations visible at the current PC are loaded.
+func (scope *EvalScope) LocalInstances(name string, cfg LoadConfig) ([]VariableInstance, error) {
+	if scope.Fn == nil {
+		return nil, errors.New("unable to find function context")
+	}
+
+	var r []VariableInstance
+	var depths []int
+	inscope := -1
+	varReader := reader.Variables(scope.BinInfo.dwarf, scope.Fn.offset, scope.PC, int(^uint(0)>>1), false)
+	for varReader.Next() {
+		entry := varReader.Entry()
+		if n, _ := entry.Val(dwarf.AttrName).(string); n != name && n != "&"+name {
+			continue
+		}
+		v, err := scope.extractVarInfoFromEntry(entry)
+		if err != nil {
+			continue
+		}
+		depth := varReader.Depth()
+		if entry.Tag == dwarf.TagFormalParameter {
+			if depth <= 1 {
+				depth = 0
+			}
+			isret, _ := entry.Val(dwarf.AttrVarParam).(bool)
+			if isret {
+				v.Flags |= VariableReturnArgument
+			} else {
+				v.Flags |= VariableArgument
+			}
+		}
+		ranges, err := varReader.ScopeRanges()
+		if err != nil {
+			return nil, err
+		}
+
+		visible := v.DeclLine <= int64(scope.Line)
+		if visible && len(ranges) > 0 {
+			visible = false
+			for _, rng := range ranges {
+				if scope.PC >= rng[0] && scope.PC < rng[1] {
+					visible = true
+					break
+				}
 			}
-			v.Name = name[1:]
-			v.Flags |= VariableEscaped
-			vars[i] = v
 		}
-		if hasScopes {
-			if otherv := lvn[v.Name]; otherv != nil {
-				otherv.Flags |= VariableShadowed
+		if visible {
+			v = escapedVariable(v)
+			scope.markUninitialized(v)
+			v.loadValue(cfg)
+			if inscope < 0 || depth > depths[inscope] || (depth == depths[inscope] && v.DeclLine >= r[inscope].DeclLine) {
+				inscope = len(r)
 			}
-			lvn[v.Name] = v
+		} else {
+			v.Name = name
+			v.Unreadable = fmt.Errorf("variable not in scope at %#x", scope.PC)
 		}
+		r = append(r, VariableInstance{Variable: v, Ranges: ranges})
+		depths = append(depths, depth)
+	}
+	if err := varReader.Err(); err != nil {
+		return nil, err
 	}
 
-	return vars, nil
+	for i := range r {
+		if i == inscope {
+			r[i].InScope = true
+		} else if r[i].Unreadable == nil {
+			r[i].Flags |= VariableShadowed
+		}
+	}
+	return r, nil
 }
 
 type constantValuesByValue []constantValue
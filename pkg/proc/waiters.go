@@ -0,0 +1,202 @@
+package proc
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/derekparker/delve/pkg/dwarf/godwarf"
+)
+
+// semTabSize is the number of entries of runtime.semtable.
+const semTabSize = 251
+
+// maxWaiters is the maximum number of sudogs read from a wait queue.
+const maxWaiters = 10000
+
+// Waiter is a goroutine blocked on a channel or a mutex, see Waiters.
+type Waiter struct {
+	G *G
+	// Op is the operation the goroutine is blocked in, one of "chan
+	// receive", "chan send", "lock" and "rlock".
+	Op string
+}
+
+// Waiters returns the goroutines blocked on v, which must be a channel, a
+// sync.Mutex or a sync.RWMutex, or a pointer to one of them. Goroutines
+// blocked on a channel are read from its wait queues, goroutines blocked
+// on a mutex are found in the wait queues of the runtime semaphores used by
+// the mutex.
+func Waiters(p Process, v *Variable) ([]Waiter, error) {
+	if v.Unreadable != nil {
+		return nil, v.Unreadable
+	}
+	if v.Kind == reflect.Ptr {
+		v = v.maybeDereference()
+		if v.Unreadable != nil {
+			return nil, v.Unreadable
+		}
+	}
+
+	type queue struct {
+		gaddrs []uint64
+		op     string
+	}
+	var queues []queue
+
+	switch t := v.RealType.(type) {
+	case *godwarf.ChanType:
+		for _, q := range []struct{ field, op string }{{"recvq", "chan receive"}, {"sendq", "chan send"}} {
+			qv, err := v.structMember(q.field)
+			if err == nil {
+				qv, err = qv.structMember("first")
+			}
+			if err != nil {
+				return nil, err
+			}
+			if qv.Unreadable != nil {
+				return nil, qv.Unreadable
+			}
+			queues = append(queues, queue{sudogList(qv, "next", 0), q.op})
+		}
+	case *godwarf.StructType:
+		type sema struct {
+			v     *Variable
+			field string
+			op    string
+		}
+		var sems []sema
+		switch {
+		case isMutex(v):
+			sems = []sema{{v, "sema", "lock"}}
+		case t.StructName == "sync.RWMutex":
+			w, err := v.structMember("w")
+			if err != nil {
+				return nil, err
+			}
+			sems = []sema{{w, "sema", "lock"}, {v, "writerSem", "lock"}, {v, "readerSem", "rlock"}}
+		default:
+			return nil, fmt.Errorf("%s is not a channel or a mutex", v.TypeString())
+		}
+		scope, err := ThreadScope(p.CurrentThread())
+		if err != nil {
+			return nil, err
+		}
+		for _, sem := range sems {
+			sv, err := mutexField(sem.v, sem.field)
+			if err != nil {
+				return nil, err
+			}
+			gaddrs, err := semaWaiters(scope, uint64(sv.Addr))
+			if err != nil {
+				return nil, err
+			}
+			queues = append(queues, queue{gaddrs, sem.op})
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a channel or a mutex", v.TypeString())
+	}
+
+	gs, err := GoroutinesInfo(p)
+	if err != nil {
+		return nil, err
+	}
+	gsByAddr := make(map[uint64]*G, len(gs))
+	for _, g := range gs {
+		if g.variable != nil {
+			gsByAddr[uint64(g.variable.Addr)] = g
+		}
+	}
+	r := []Waiter{}
+	for _, q := range queues {
+		for _, gaddr := range q.gaddrs {
+			if g := gsByAddr[gaddr]; g != nil {
+				r = append(r, Waiter{G: g, Op: q.op})
+			}
+		}
+	}
+	return r, nil
+}
+
+// sudogList returns the addresses of the goroutines of the list of sudogs
+// starting at sv, a pointer to a sudog, and linked by field link. If elem
+// isn't zero only the sudogs waiting on elem are returned.
+func sudogList(sv *Variable, link string, elem uint64) []uint64 {
+	var r []uint64
+	for i := 0; i < maxWaiters; i++ {
+		sv = sv.maybeDereference()
+		if sv.Unreadable != nil || sv.Addr == 0 {
+			break
+		}
+		if elem == 0 || sudogPtrField(sv, "elem") == elem {
+			if g := sudogPtrField(sv, "g"); g != 0 {
+				r = append(r, g)
+			}
+		}
+		var err error
+		sv, err = sv.structMember(link)
+		if err != nil {
+			break
+		}
+	}
+	return r
+}
+
+// sudogPtrField returns the value of the pointer field called name of sv.
+func sudogPtrField(sv *Variable, name string) uint64 {
+	fv, err := sv.structMember(name)
+	if err != nil || fv.Unreadable != nil {
+		return 0
+	}
+	ptr, _ := readUintRaw(fv.mem, uintptr(fv.Addr), int64(sv.bi.Arch.PtrSize()))
+	return ptr
+}
+
+// semaWaiters returns the addresses of the goroutines waiting on the
+// runtime semaphore at addr, read from runtime.semtable.
+func semaWaiters(scope *EvalScope, addr uint64) ([]uint64, error) {
+	semtable, err := scope.findGlobal("runtime.semtable")
+	if err != nil {
+		return nil, err
+	}
+	t, ok := semtable.RealType.(*godwarf.ArrayType)
+	if !ok || t.Count != semTabSize {
+		return nil, fmt.Errorf("unknown runtime.semtable type %s", semtable.TypeString())
+	}
+	entry := semtable.newVariable("", semtable.Addr+uintptr(int64((addr>>3)%semTabSize)*t.Type.Size()), t.Type, semtable.mem)
+	root, err := entry.structMember("root")
+	if err != nil {
+		return nil, err
+	}
+	if head, err := root.structMember("head"); err == nil {
+		// before Go 1.9 all sudogs of a semaRoot are in a single list
+		return sudogList(head, "next", addr), nil
+	}
+	// since Go 1.9 the sudogs of a semaRoot are in a treap ordered by
+	// address, sudogs waiting on the same address are linked by waitlink
+	node, err := root.structMember("treap")
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < maxWaiters; i++ {
+		node = node.maybeDereference()
+		if node.Unreadable != nil {
+			return nil, node.Unreadable
+		}
+		if node.Addr == 0 {
+			break
+		}
+		elem := sudogPtrField(node, "elem")
+		child := "next"
+		switch {
+		case elem == addr:
+			return sudogList(node, "waitlink", 0), nil
+		case addr < elem:
+			child = "prev"
+		}
+		node, err = node.structMember(child)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
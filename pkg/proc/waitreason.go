@@ -0,0 +1,85 @@
+package proc
+
+import (
+	"strings"
+	"time"
+)
+
+// waitReasonStrings maps the names of the runtime.waitReason constants,
+// used since Go 1.11, to the strings the runtime prints for them in
+// tracebacks.
+var waitReasonStrings = map[string]string{
+	"waitReasonZero":                  "",
+	"waitReasonGCAssistMarking":       "GC assist marking",
+	"waitReasonIOWait":                "IO wait",
+	"waitReasonChanReceiveNilChan":    "chan receive (nil chan)",
+	"waitReasonChanSendNilChan":       "chan send (nil chan)",
+	"waitReasonDumpingHeap":           "dumping heap",
+	"waitReasonGarbageCollection":     "garbage collection",
+	"waitReasonGarbageCollectionScan": "garbage collection scan",
+	"waitReasonPanicWait":             "panicwait",
+	"waitReasonSelect":                "select",
+	"waitReasonSelectNoCases":         "select (no cases)",
+	"waitReasonGCAssistWait":          "GC assist wait",
+	"waitReasonGCSweepWait":           "GC sweep wait",
+	"waitReasonGCScavengeWait":        "GC scavenge wait",
+	"waitReasonChanReceive":           "chan receive",
+	"waitReasonChanSend":              "chan send",
+	"waitReasonFinalizerWait":         "finalizer wait",
+	"waitReasonForceGGIdle":           "force gc (idle)",
+	"waitReasonSemacquire":            "semacquire",
+	"waitReasonSleep":                 "sleep",
+	"waitReasonSyncCondWait":          "sync.Cond.Wait",
+	"waitReasonTimerGoroutineIdle":    "timer goroutine (idle)",
+	"waitReasonTraceReaderBlocked":    "trace reader (blocked)",
+	"waitReasonWaitForGCCycle":        "wait for GC cycle",
+	"waitReasonGCWorkerIdle":          "GC worker (idle)",
+	"waitReasonPreempted":             "preempted",
+	"waitReasonDebugCall":             "debug call",
+}
+
+// readableWaitReason converts the name of a runtime.waitReason constant
+// into the string used by the runtime to describe it. Unknown constants
+// are converted by removing the waitReason prefix and splitting the rest
+// of the name into lower case words.
+func readableWaitReason(name string) string {
+	name = strings.TrimPrefix(name, "runtime.")
+	if s, ok := waitReasonStrings[name]; ok {
+		return s
+	}
+	if !strings.HasPrefix(name, "waitReason") {
+		return name
+	}
+	name = name[len("waitReason"):]
+	var buf []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'A' && c <= 'Z' {
+			if i > 0 {
+				buf = append(buf, ' ')
+			}
+			c += 'a' - 'A'
+		}
+		buf = append(buf, c)
+	}
+	return string(buf)
+}
+
+// Waiting returns true if the goroutine is parked, its wait reason is
+// only meaningful in this case.
+func (g *G) Waiting() bool {
+	return g.Status&^gscanStatus == Gwaiting
+}
+
+// WaitDuration returns how long the goroutine has been parked, now must be
+// the current value of the monotonic clock used by the runtime of the
+// target (runtime.nanotime).
+// The runtime only records when a goroutine was parked during garbage
+// collections, so the returned duration is a lower bound and is zero if no
+// garbage collection happened since the goroutine was parked.
+func (g *G) WaitDuration(now int64) time.Duration {
+	if g.WaitSince <= 0 || now < g.WaitSince || !g.Waiting() {
+		return 0
+	}
+	return time.Duration(now - g.WaitSince)
+}
@@ -6,9 +6,10 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
-	"go/parser"
 	"go/scanner"
+	"go/token"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
 	"os/exec"
@@ -18,8 +19,10 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/cosiner/argv"
+	"github.com/derekparker/delve/pkg/proc"
 	"github.com/derekparker/delve/service"
 	"github.com/derekparker/delve/service/api"
 	"github.com/derekparker/delve/service/debugger"
@@ -41,7 +44,7 @@ type callContext struct {
 }
 
 func (ctx *callContext) scoped() bool {
-	return ctx.Scope.GoroutineID >= 0 || ctx.Scope.Frame > 0
+	return ctx.Scope.GoroutineID >= 0 || ctx.Scope.Frame > 0 || ctx.Scope.DeferredCall > 0
 }
 
 type frameDirection int
@@ -81,8 +84,8 @@ type Commands struct {
 }
 
 var (
-	LongLoadConfig  = api.LoadConfig{true, 1, 64, 64, -1}
-	ShortLoadConfig = api.LoadConfig{false, 0, 64, 0, 3}
+	LongLoadConfig  = api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+	ShortLoadConfig = api.LoadConfig{MaxStringLen: 64, MaxStructFields: 3}
 )
 
 type ByFirstAlias []command
@@ -103,32 +106,84 @@ func DebugCommands(client service.Client) *Commands {
 Type "help" followed by the name of a command for more information about it.`},
 		{aliases: []string{"break", "b"}, cmdFn: breakpoint, helpMsg: `Sets a breakpoint.
 
-	break [name] <linespec>
+	break [-hw] [name] <linespec>
 
 See $GOPATH/src/github.com/derekparker/delve/Documentation/cli/locspec.md for the syntax of linespec.
 
+If -hw is specified the breakpoint is implemented using a hardware debug register instead of patching the code of the target process. Only a few hardware breakpoints (including watchpoints) can be set at the same time and they are only supported by the native backend on linux.
+
 See also: "help on", "help cond" and "help clear"`},
 		{aliases: []string{"trace", "t"}, cmdFn: tracepoint, helpMsg: `Set tracepoint.
 
-	trace [name] <linespec>
+	trace [-stack <depth>] [name] <linespec>
 
 A tracepoint is a breakpoint that does not stop the execution of the program, instead when the tracepoint is hit a notification is displayed. See $GOPATH/src/github.com/derekparker/delve/Documentation/cli/locspec.md for the syntax of linespec.
 
+If -stack is specified no notification is displayed, instead a stacktrace <depth> frames deep is recorded every time the tracepoint is hit and the program continues without stopping. Recorded stacktraces can be displayed with the tracestacks command.
+
 See also: "help on", "help cond" and "help clear"`},
 		{aliases: []string{"restart", "r"}, cmdFn: restart, helpMsg: `Restart process.
 
-  restart [checkpoint]
+  restart [checkpoint | event]
   restart [-noargs] newargv...
 
-  For recorded processes restarts from the start or from the specified
-  checkpoint.  For normal processes restarts the process, optionally changing
+  For recorded processes restarts from the start, from the specified
+  checkpoint or from the start of the specified event number, as printed
+  when the process stops.  For normal processes restarts the process, optionally changing
   the arguments.  With -noargs, the process starts with an empty commandline.
+  On linux normal processes can also be restored to a checkpoint created
+  with the checkpoint command, without restarting them from the start.
 `},
-		{aliases: []string{"continue", "c"}, cmdFn: c.cont, helpMsg: "Run until breakpoint or program termination."},
-		{aliases: []string{"step", "s"}, cmdFn: c.step, helpMsg: "Single step through program."},
-		{aliases: []string{"step-instruction", "si"}, cmdFn: c.stepInstruction, helpMsg: "Single step a single cpu instruction."},
-		{aliases: []string{"next", "n"}, cmdFn: c.next, helpMsg: "Step over to next source line."},
-		{aliases: []string{"stepout"}, cmdFn: c.stepout, helpMsg: "Step out of the current function."},
+		{aliases: []string{"continue", "c"}, cmdFn: c.cont, helpMsg: `Run until breakpoint or program termination.
+
+	continue [<linespec>]
+	continue -recover
+	continue -goroutine <id>
+
+If a linespec is specified execution stops when it is reached, by any goroutine, or when a breakpoint is hit, whichever comes first. See $GOPATH/src/github.com/derekparker/delve/Documentation/cli/locspec.md for the syntax of linespec.
+
+With -recover the current goroutine must be panicking, execution continues until the deferred function that will recover the panic is called. Since all deferred functions have already been called when the unrecovered-panic breakpoint is hit this is only useful while the panic is in progress, for example after 'break runtime.gopanic'.
+
+With -goroutine execution continues until the specified goroutine runs again, stopping when it returns to code outside of the runtime. This is useful when a step command stopped on a different goroutine than the one it started on, for example because the original goroutine parked.`},
+		{aliases: []string{"step", "s"}, cmdFn: c.step, helpMsg: `Single step through program.
+
+	step [-defer <mode>] [-count <n>]
+	step [-list | <target>]
+
+With -list prints the functions called by the current source line. If a target is specified only that function is entered, all other calls on the current line are stepped over. The target can be the name of the function, with or without its package, or its index in the output of 'step -list'.
+
+With -defer, mode determines what happens when the current function returns and its deferred functions are called: with 'skip' execution does not stop inside deferred functions, with 'entry' it stops at the entry point of the deferred function, with 'stop', the default, it stops after the prologue of the deferred function.
+
+With -count the step is repeated n times, execution stops earlier if a breakpoint is hit.`},
+		{aliases: []string{"step-instruction", "si"}, cmdFn: c.stepInstruction, helpMsg: `Single step a single cpu instruction.
+
+	step-instruction [count]
+
+If count is specified count instructions are executed, execution stops earlier if a breakpoint is hit.`},
+		{aliases: []string{"next-instruction", "ni"}, cmdFn: c.nextInstruction, helpMsg: `Single step a single cpu instruction, stepping over calls.
+
+If the current instruction is a CALL execution continues until the called function returns.`},
+		{aliases: []string{"next", "n"}, cmdFn: c.next, helpMsg: `Step over to next source line.
+
+	next [-defer <mode>] [count]
+
+With -defer, mode determines what happens when the current function returns and its deferred functions are called, see 'help step'.
+
+If count is specified next is repeated count times, execution stops earlier if a breakpoint is hit.`},
+		{aliases: []string{"until"}, cmdFn: c.until, helpMsg: `Continue until a source line greater than the current one is reached.
+
+Like next, but does not stop on the previous lines of a loop: execution continues until a line of the current function after the current one is reached or the current function returns.`},
+		{aliases: []string{"stepout", "so"}, cmdFn: c.stepout, helpMsg: `Step out of the current function.
+
+Continues until the current function returns to its caller, the return values of the function are printed when execution stops. If the current function panics execution stops at the start of its topmost deferred function.`},
+		{aliases: []string{"jump", "j"}, cmdFn: c.jump, helpMsg: `Moves execution to another line of the current function.
+
+	jump <linespec>
+
+The current goroutine is moved to the specified line, which must be in the current function, without executing the code in between. Jumps to a location where the size of the stack frame is different from the current one are refused. Execution is not resumed.`},
+		{aliases: []string{"step-to-return", "sr"}, cmdFn: c.stepToReturn, helpMsg: `Continue until the current function is about to return.
+
+Execution stops on the return instruction of the current function, after its deferred functions have been called, and the values it is going to return are printed.`},
 		{aliases: []string{"call"}, cmdFn: c.call, helpMsg: `Resumes process, injecting a function call (EXPERIMENTAL!!!)
 	
 Current limitations:
@@ -139,7 +194,8 @@ Current limitations:
 - no automatic type conversions are supported, including automatically
   converting to an interface type.
 - functions can only be called on running goroutines that are not
-  executing the runtime.
+  executing the runtime, 'goroutine <id> call <expr>' resumes the program
+  until goroutine <id> runs if it is parked.
 - the current goroutine needs to have at least 256 bytes of free space on
   the stack.
 - functions can only be called when the goroutine is stopped at a safe
@@ -153,7 +209,10 @@ Current limitations:
 	thread <id>`},
 		{aliases: []string{"clear"}, cmdFn: clear, helpMsg: `Deletes breakpoint.
 
-	clear <breakpoint name or id>`},
+	clear <breakpoint name or id>
+	clear -internal
+
+With -internal all the internal breakpoints set by next, step and stepout are cleared, cancelling the operation that set them.`},
 		{aliases: []string{"clearall"}, cmdFn: clearAll, helpMsg: `Deletes multiple breakpoints.
 
 	clearall [<linespec>]
@@ -161,7 +220,7 @@ Current limitations:
 If called with the linespec argument it will delete all the breakpoints matching the linespec. If linespec is omitted all breakpoints are deleted.`},
 		{aliases: []string{"goroutines"}, cmdFn: goroutines, helpMsg: `List program goroutines.
 
-	goroutines [-u (default: user location)|-r (runtime location)|-g (go statement location)|-s (start location)] [ -t (stack trace)]
+	goroutines [-u (default: user location)|-r (runtime location)|-g (go statement location)|-s (start location)] [ -t (stack trace)] [-l (labels)] [<filters>] [-n <count>] [-start <index>]
 
 Print out info for every goroutine. The flag controls what information is shown along with each goroutine:
 
@@ -170,8 +229,27 @@ Print out info for every goroutine. The flag controls what information is shown
 	-g	displays location of go instruction that created the goroutine
 	-s	displays location of the start function
 	-t	displays stack trace of goroutine
+	-l	displays the pprof labels of goroutine
+
+If no flag is specified the default is -u.
+
+The list of goroutines can be restricted with the following filters, a goroutine is listed if it satisfies all of them:
 
-If no flag is specified the default is -u.`},
+	-state <states>		comma separated list of states, one of idle, runnable, running, syscall, waiting, dead and copystack
+	-with <function>	lists goroutines that have a function whose name contains <function> in their stack
+	-label <key>=<value>	lists goroutines that have the specified pprof label, can be repeated
+	-system			also lists the goroutines started by the runtime, like the garbage collector workers and the finalizer goroutine, which are hidden by default
+
+With -n at most <count> goroutines are listed, the command prints the value of -start that lists the following ones.
+
+	goroutines -group <current|user|reason> [<filters>]
+
+Prints the number of goroutines that share the same function in their topmost frame (current), in their topmost frame outside of the runtime (user) or the same wait reason (reason), along with some of their IDs.
+
+	goroutines -mark
+	goroutines -diff [-u|-r|-g|-s]
+
+The first form records the current goroutines as a baseline, the second form lists the goroutines created and the goroutines that exited since the baseline was recorded. Unless another flag is specified the location of the go statement that created each goroutine is displayed.`},
 		{aliases: []string{"goroutine"}, allowedPrefixes: onPrefix, cmdFn: c.goroutine, helpMsg: `Shows or changes current goroutine
 
 	goroutine
@@ -181,20 +259,121 @@ If no flag is specified the default is -u.`},
 Called without arguments it will show information about the current goroutine.
 Called with a single argument it will switch to the specified goroutine.
 Called with more arguments it will execute a command on the specified goroutine.`},
-		{aliases: []string{"breakpoints", "bp"}, cmdFn: breakpoints, helpMsg: "Print out info for active breakpoints."},
+		{aliases: []string{"waiters"}, allowedPrefixes: onPrefix, cmdFn: waiters, helpMsg: `Lists the goroutines blocked on a channel or a mutex.
+
+	[goroutine <n>] [frame <m>] waiters <expression>
+
+The expression must evaluate to a channel, a sync.Mutex or a sync.RWMutex, or to a pointer to one of them. Each blocked goroutine is printed together with the operation it is blocked in: chan receive, chan send, lock or rlock. For example:
+
+	waiters ch
+	waiters (*sync.Mutex)(0xc000012345)`},
+		{aliases: []string{"mutex"}, allowedPrefixes: onPrefix, cmdFn: mutexCmd, helpMsg: `Shows the state of a mutex and the goroutines that could be holding it.
+
+	[goroutine <n>] [frame <m>] mutex [<expression>]
+
+The expression must evaluate to a sync.Mutex or to a pointer to one. Without an expression the mutex the current goroutine is blocked on is used, for example 'goroutine 12 mutex' shows the mutex goroutine 12 is waiting for.
+
+The runtime does not record which goroutine locked a mutex: the goroutines printed as possible holders are the ones that are not waiting for the mutex and have a variable that contains it, or points to a value that contains it, in one of their frames.`},
+		{aliases: []string{"freeze"}, cmdFn: freezeGoroutine, helpMsg: `Freezes a goroutine.
+
+	freeze [<id>]
+	goroutine <id> freeze
+
+Threads running, or about to run, a frozen goroutine are held stopped while the rest of the program runs, until the goroutine is thawed. Called without arguments, outside of a goroutine prefix, it lists the frozen goroutines.
+
+Held threads own one of the Ps of the Go scheduler: a garbage collection, or any other stop-the-world, started while they are held waits for them and the program hangs. Stopping the program (for example with ctrl-C) and resuming it releases the held threads, the frozen goroutines run until the runtime stops them and are held again the next time they are scheduled.
+Only supported by the native backend on linux.`},
+		{aliases: []string{"thaw"}, cmdFn: thawGoroutine, helpMsg: `Thaws a goroutine frozen by the freeze command.
+
+	thaw [<id>]
+	goroutine <id> thaw`},
+		{aliases: []string{"sample"}, cmdFn: sampleCmd, helpMsg: `Samples the stacks of running goroutines, stopping the whole program for each sample.
+
+	sample [<duration>]
+
+Resumes the program for the specified duration, one second by default, stopping it every 10 milliseconds to record the topmost frames of the goroutines running on its threads. The recorded stacks are then printed, starting from the most frequent, giving an approximate profile of where the program spends its time. Sampling ends early if a breakpoint is hit.
+
+Every sample stops all the threads of the program, like a breakpoint does, and resumes them once the stacks are read, the program runs slower while it is sampled.`},
+		{aliases: []string{"sched-history"}, cmdFn: schedHistory, helpMsg: `Records which goroutines run on which threads.
+
+	sched-history
+	sched-history <on|off>
+
+The second form enables or disables the recording of the scheduling history: while it is enabled the program is stopped every time a goroutine is scheduled on a thread, which makes it run considerably slower. The first form prints the goroutines that were scheduled since the program was last resumed, in the order they were scheduled, together with the thread they ran on.`},
+		{aliases: []string{"breakpoints", "bp"}, cmdFn: breakpoints, helpMsg: `Print out info for active breakpoints.
+
+	breakpoints [-internal]
+
+With -internal the internal breakpoints set by next, step and stepout are printed instead, along with the operation that set them.`},
 		{aliases: []string{"print", "p"}, allowedPrefixes: onPrefix, cmdFn: printVar, helpMsg: `Evaluate an expression.
 
-	[goroutine <n>] [frame <m>] print <expression>
+	[goroutine <n>] [frame <m>] print [%<format>] <expression>
+
+See $GOPATH/src/github.com/derekparker/delve/Documentation/cli/expr.md for a description of supported expressions.
+
+The optional format changes how integers and strings are printed:
 
-See $GOPATH/src/github.com/derekparker/delve/Documentation/cli/expr.md for a description of supported expressions.`},
+	%x	integers in hexadecimal
+	%o	integers in octal
+	%b	integers in binary
+	%c	integers as characters
+	%d	integers in decimal (the default)
+	%s	strings without quotes`},
 		{aliases: []string{"whatis"}, cmdFn: whatisCommand, helpMsg: `Prints type of an expression.
 
 		whatis <expression>.`},
+		{aliases: []string{"diff"}, cmdFn: diffCmd, helpMsg: `Compares two values.
+
+	[goroutine <n>] [frame <m>] diff [-depth <n>] <expression1>, <expression2>
+	[goroutine <n>] [frame <m>] diff -save [-depth <n>] <expression>
+	[goroutine <n>] [frame <m>] diff [-depth <n>] <expression>
+
+The first form compares the values of two expressions and prints the fields, elements and map entries where they differ. Pointers are followed and values that point to each other in a cycle are only compared once.
+
+The second form saves the value of an expression, the third form compares the value of an expression with the value saved by the last diff -save of the same expression, for example at a previous stop.
+
+Values are compared up to -depth levels deep, 5 by default.`},
+		{aliases: []string{"export"}, cmdFn: exportCmd, helpMsg: `Prints the value of an expression as Go code or JSON.
+
+	[goroutine <n>] [frame <m>] export [-json] [-depth <n>] [-o <file>] <expression>
+
+Without -json the value is printed as a Go expression: structs, arrays, slices and maps are written as composite literals, pointers as the address of the value they point to. With -json the value is printed as a JSON document. Pointers are followed up to -depth levels deep, 5 by default. If -o is specified the output is written to file instead.
+
+Values that can not be written in Go, like functions and values reached through pointers deeper than -depth, are written as nil or zero values followed by a comment.`},
+		{aliases: []string{"traverse"}, cmdFn: traverseCmd, helpMsg: `Visits a linked list, a tree or a graph.
+
+	[goroutine <n>] [frame <m>] traverse [-n <max>] [-f <field>[,<field>...]] <expression>
+
+Starting from expression, a struct or a pointer to a struct, follows the pointers to other structs of the same type and prints each node reached, with the path of fields leading to it. Fields can be pointers or slices of pointers; with -f only the specified fields are followed, otherwise all fields of the right type are. Each node is printed once, pointers that lead back to one of the nodes on the path to the current node are printed as cycles.
+
+At most -n nodes are visited, 100 by default.`},
+		{aliases: []string{"display"}, cmdFn: displayCmd, helpMsg: `Prints the value of an expression every time the program stops.
+
+	display -a <expression>
+	display -d <id>
+	display
+
+The first form adds an expression to the display list and prints its identifier, the second form removes the expression with the given identifier from the list. Without arguments the values of the expressions in the display list are printed.
+
+Expressions are evaluated in the scope of the selected goroutine. Values that changed since the previous stop are marked, with the difference between the two values for numbers.`},
 		{aliases: []string{"set"}, cmdFn: setVar, helpMsg: `Changes the value of a variable.
 
 	[goroutine <n>] [frame <m>] set <variable> = <value>
 
-See $GOPATH/src/github.com/derekparker/delve/Documentation/cli/expr.md for a description of supported expressions. Only numerical variables and pointers can be changed.`},
+See $GOPATH/src/github.com/derekparker/delve/Documentation/cli/expr.md for a description of supported expressions. Structs, arrays, slices, maps and other composite values can be set to the value of a variable of the same type or to nil, new keys can be added to maps with string or integer keys.`},
+		{aliases: []string{"printer"}, cmdFn: printerCmd, helpMsg: `Manages pretty-printers for struct types.
+
+	printer
+	printer <type> <template>
+	printer -clear <type>
+
+Values of a type with a pretty-printer are printed as a one line summary instead of a list of fields, the summary is also used when the value is compared with a string in an expression or in a breakpoint condition. Pretty-printers are predefined for time.Time, math/big.Int, net/netip.Addr and sync.Mutex.
+
+The second form defines a pretty-printer for the struct type <type>, its summary is <template> with every occurrence of {path} replaced by the value of the field path, which can select fields of nested structs separated by dots. For example:
+
+	printer main.Person {Name} ({Age} years old)
+
+The first form lists the pretty-printers defined with the printer command, the third form removes one.`},
 		{aliases: []string{"sources"}, cmdFn: sources, helpMsg: `Print list of source files.
 
 	sources [<regex>]
@@ -218,15 +397,18 @@ If regex is specified only function arguments with a name matching it will be re
 		{aliases: []string{"locals"}, allowedPrefixes: onPrefix, cmdFn: locals, helpMsg: `Print local variables.
 
 	[goroutine <n>] [frame <m>] locals [-v] [<regex>]
+	[goroutine <n>] [frame <m>] locals -decls <name>
 
-The name of variables that are shadowed in the current scope will be shown in parenthesis.
+The name of variables that are shadowed in the current scope will be shown in parenthesis. Only the variables declared in the lexical blocks containing the current PC, up to the current line, are shown; variables declared on the current line are marked as maybe uninitialized, since the statement declaring them may not have run yet.
 
-If regex is specified only local variables with a name matching it will be returned. If -v is specified more information about each local variable will be shown.`},
+If regex is specified only local variables with a name matching it will be returned. If -v is specified more information about each local variable will be shown.
+
+The second form prints every declaration of the local variable or argument called name in the current function, with the line it is declared at and the address ranges of its lexical block, including declarations shadowed by a nested block and declarations in blocks that do not contain the current PC. The declaration the name refers to is marked with '=>'.`},
 		{aliases: []string{"vars"}, cmdFn: vars, helpMsg: `Print package variables.
 
-	vars [-v] [<regex>]
+	vars [-v] [-n] [-pkg <path>] [<regex>]
 
-If regex is specified only package variables with a name matching it will be returned. If -v is specified more information about each package variable will be shown.`},
+If regex is specified only package variables with a name matching it will be returned. If -pkg is specified only the variables of the package with the given import path will be returned. If -v is specified more information about each package variable will be shown, if -n is specified only the name and type of each package variable will be shown, without reading its value.`},
 		{aliases: []string{"regs"}, cmdFn: regs, helpMsg: `Print contents of CPU registers.
 
 	regs [-a]
@@ -244,10 +426,13 @@ When connected to a headless instance started with the --accept-multiclient, pas
 Show source around current point or provided linespec.`},
 		{aliases: []string{"stack", "bt"}, allowedPrefixes: onPrefix, cmdFn: stackCommand, helpMsg: `Print stack trace.
 
-	[goroutine <n>] [frame <m>] stack [<depth>] [-full] [-g] [-s] [-offsets]
+	[goroutine <n>] [frame <m>] stack [<depth>] [-full] [-g] [-s] [-offsets] [-system] [-a <n>] [-adepth <depth>]
 
 	-full		every stackframe is decorated with the value of its local variables and arguments.
 	-offsets	prints frame offset of each frame
+	-system		prints all the frames of the system stack the goroutine is executing on, either the g0 stack of its thread or the stack of a cgo call, instead of only the topmost one. The point where the goroutine switched to the system stack is marked.
+	-a <n>		prints the stacktraces of up to n ancestors of the goroutine, the goroutines that created it, only available if the target was started with GODEBUG=tracebackancestors=N
+	-adepth <depth>	configures the depth of ancestor stacktraces
 `},
 		{aliases: []string{"frame"},
 			cmdFn: func(t *Term, ctx callContext, arg string) error {
@@ -258,7 +443,7 @@ Show source around current point or provided linespec.`},
   frame <m>
   frame <m> <command>
 
-The first form sets frame used by subsequent commands such as "print" or "set".
+The first form sets frame used by subsequent commands such as "print" or "set". When a frame other than the topmost one is selected next and step stop on the next line of the selected frame and stepout continues until the selected frame returns.
 The second form runs the command on the given frame.`},
 		{aliases: []string{"up"},
 			cmdFn: func(t *Term, ctx callContext, arg string) error {
@@ -280,6 +465,11 @@ Move the current frame up by <m>. The second form runs the command on the given
   down [<m>] <command>
 
 Move the current frame down by <m>. The second form runs the command on the given frame.`},
+		{aliases: []string{"deferred"}, cmdFn: c.deferredCommand, helpMsg: `Executes command in the context of a deferred call.
+
+  deferred <n> <command>
+
+Executes the specified command (print, args, locals) in the context of the n-th deferred call in the current frame, deferred calls are numbered in the order they will be called, starting from 1, as shown by 'stack -defer'. Only the arguments of the deferred function are available. On targets built with Go 1.17 or later deferred calls are wrapped in closures and the variables captured by the closure are available instead, which requires Go 1.23 or later.`},
 		{aliases: []string{"source"}, cmdFn: c.sourceCommand, helpMsg: `Executes a file containing a list of delve commands
 
 	source <path>`},
@@ -299,8 +489,118 @@ Supported commands: print, stack and goroutine)`},
 		{aliases: []string{"condition", "cond"}, cmdFn: conditionCmd, helpMsg: `Set breakpoint condition.
 
 	condition <breakpoint name or id> <boolean expression>.
+	condition -hitcount <breakpoint name or id> <operator> <argument>.
+	condition -maxhits <breakpoint name or id> <n>.
+	condition -thread <breakpoint name or id> <thread id>.
+
+Specifies that the breakpoint or tracepoint should break only if the boolean expression is true.
+
+With the -hitcount option the breakpoint will only break when its hit count satisfies the condition, supported operators are ==, !=, >, <, >=, <= and %, the last one specifies that the breakpoint should break every <argument> hits. For example:
+
+	condition -hitcount 1 >= 40000
+	condition -hitcount mybp % 10
+
+With the -maxhits option the breakpoint is disabled once it has been hit n times, use 0 to remove the limit.
+
+With the -thread option the breakpoint will only be triggered by the specified thread, other threads will step over it transparently. Use 0 to remove the restriction.`},
+		{aliases: []string{"logpoint"}, cmdFn: logpointCmd, helpMsg: `Turns a breakpoint into a logpoint.
+
+	logpoint <breakpoint name or id> <message>
+
+A logpoint does not stop the execution of the program, instead every time it is hit the message is recorded along with the ID of the goroutine that hit it, the recorded messages are printed when the command that resumed the program returns. Expressions enclosed in curly braces are evaluated, for example:
+
+	logpoint 1 i = {i} len(s) = {len(s)}
+
+If the message is omitted the logpoint is turned back into a breakpoint.`},
+		{aliases: []string{"action"}, cmdFn: actionCmd, helpMsg: `Adds an action to a breakpoint.
+
+	action <breakpoint name or id> print <expression>
+	action <breakpoint name or id> stack [<depth>]
+	action <breakpoint name or id> enable <breakpoint id>
+	action <breakpoint name or id> continue
+	action <breakpoint name or id> -clear
+
+Unlike the commands added with 'on', which are executed by the client after the program stops, actions are executed, in order, by the debugger every time the breakpoint is triggered and their results are recorded along with the ID of the goroutine that hit the breakpoint, the recorded results are printed when the command that resumed the program returns. The print action evaluates an expression, stack prints a stacktrace, enable enables another breakpoint and continue resumes execution after all other actions have been executed instead of stopping.
+
+The -clear option removes all actions from the breakpoint.`},
+		{aliases: []string{"toggle"}, cmdFn: toggleCmd, helpMsg: `Toggles on or off a breakpoint.
+
+	toggle <breakpoint name or id>
+
+A disabled breakpoint keeps its condition, hit count and other attributes but does not stop the program until it is enabled again.`},
+		{aliases: []string{"group"}, cmdFn: groupCmd, helpMsg: `Manages breakpoint groups.
+
+	group <breakpoint name or id> <group name>
+	group -disable <group name>
+	group -enable <group name>
+
+The first form adds the breakpoint to the specified group, if the group name is omitted the breakpoint is removed from its group. The other two forms disable or enable all breakpoints belonging to the group.`},
+		{aliases: []string{"tracestacks"}, cmdFn: tracestacks, helpMsg: `Prints the stacktraces recorded by a tracepoint.
+
+	tracestacks <breakpoint name or id>
+
+Prints the stacktraces recorded by a tracepoint created with trace -stack since the last time tracestacks was called, identical stacktraces are printed once together with the number of times they were recorded.`},
+		{aliases: []string{"catch"}, cmdFn: catchCmd, helpMsg: `Sets a catchpoint.
+
+	catch syscall [<syscall name or number> ...]
+	catch cgo [off]
+
+The first form stops the program every time it enters or exits one of the specified syscalls, the syscall arguments and return value are printed when the program stops. Without arguments all syscall catchpoints are removed. Only supported by the native backend on linux.
+
+The second form stops the program every time it calls a C function, returns from it or C code calls back into Go, the address of the C function being called is printed when the program stops. With off the cgo catchpoints are removed.`},
+		{aliases: []string{"deadlock"}, cmdFn: deadlockCmd, helpMsg: `Detects deadlocks.
+
+	deadlock
+	deadlock <on|off>
+
+The first form checks whether the program is deadlocked: all its goroutines, except the ones started by the runtime, are blocked on channels, select statements, mutexes or condition variables, and no timer or signal can wake them up. The blocked goroutines are printed together with the addresses of the channels they are waiting on.
+
+The second form enables or disables deadlock detection during continue: the program is periodically stopped to check whether it is deadlocked, and continue returns when it is. Deadlocks detected by the runtime are always reported.`},
+		{aliases: []string{"handle"}, cmdFn: handleCmd, helpMsg: `Sets what happens when the program receives a signal.
+
+	handle <signal> <stop|pass|ignore>
+
+With stop the program is stopped when it receives the signal, the signal is delivered when the program is resumed. With pass, the default, the signal is delivered to the program without stopping it. With ignore the signal is discarded. The signal can be specified by number or by name, for example:
+
+	handle SIGPIPE ignore
+	handle 10 stop
 
-Specifies that the breakpoint or tracepoint should break only if the boolean expression is true.`},
+Only supported by the native backend on linux.`},
+		{aliases: []string{"resume-mode"}, cmdFn: resumeModeCmd, helpMsg: `Sets which threads run when the program is continued.
+
+	resume-mode <all|selected|nonstop>
+
+With all, the default, all threads are resumed when the program is continued and all threads are stopped when one of them stops. With selected only the thread running the selected goroutine is resumed, all other threads stay halted. With nonstop all threads are resumed and when a thread hits a breakpoint all other threads keep running.
+
+Only supported by the native backend on linux.`},
+		{aliases: []string{"skip"}, cmdFn: skipCmd, helpMsg: `Hides functions from next, step and stepout.
+
+	skip
+	skip <pattern>
+	skip -remove <pattern>
+	skip -clear
+
+Without arguments prints the list of patterns. Step will not enter a function whose name, or whose file, matches one of the patterns and if a next or step stops inside one of them execution continues until it returns. The only special character in a pattern is '*', which matches any sequence of characters. For example:
+
+	skip runtime.*
+	skip */vendor/*
+	skip *.pb.go`},
+		{aliases: []string{"step-runtime"}, cmdFn: stepRuntimeCmd, helpMsg: `Enables or disables stepping into runtime functions.
+
+	step-runtime on|off
+
+By default step does not enter unexported functions of the runtime, like runtime.mallocgc, runtime.chansend or the scheduler. With on step enters them like any other function.`},
+		{aliases: []string{"blackbox"}, cmdFn: blackboxCmd, helpMsg: `Blackboxes functions, or files, for next, step and stepout.
+
+	blackbox
+	blackbox <function or file>
+	blackbox -remove <function or file>
+	blackbox -clear
+
+Without arguments prints the list of blackboxed functions and files. Step will not enter a blackboxed function and if a next or step stops inside one of them execution continues until it returns. Unlike the patterns of 'skip' each entry names a single function, by its fully qualified name, or a single file, by its path or by the last components of its path. For example:
+
+	blackbox main.(*T).DeepCopy
+	blackbox zz_generated.deepcopy.go`},
 		{aliases: []string{"config"}, cmdFn: configureCmd, helpMsg: `Changes configuration parameters.
 
 	config -list
@@ -326,6 +626,15 @@ Adds or removes a path substitution rule.
 Defines <alias> as an alias to <command> or removes an alias.`},
 
 		{aliases: []string{"edit", "ed"}, cmdFn: edit, helpMsg: `Open where you are in $DELVE_EDITOR or $EDITOR`},
+		{aliases: []string{"check", "checkpoint"}, cmdFn: checkpoint, helpMsg: `Creates a checkpoint at the current position.
+
+	checkpoint [where]
+
+On linux/amd64 checkpoints can also be created for normal processes, only the current thread is saved in the checkpoint and the checkpoint can not be created while goroutines are running on other threads. Because the other threads of the runtime are missing from the restored process it can hang, for example if the runtime waits for one of them to schedule a goroutine or to release a lock.`},
+		{aliases: []string{"checkpoints"}, cmdFn: checkpoints, helpMsg: "Print out info for existing checkpoints."},
+		{aliases: []string{"clear-checkpoint", "clearcheck"}, cmdFn: clearCheckpoint, helpMsg: `Deletes checkpoint.
+
+	clear-checkpoint <id>`},
 	}
 
 	if client == nil || client.Recorded() {
@@ -335,23 +644,17 @@ Defines <alias> as an alias to <command> or removes an alias.`},
 			helpMsg: "Run backwards until breakpoint or program termination.",
 		})
 		c.cmds = append(c.cmds, command{
-			aliases: []string{"check", "checkpoint"},
-			cmdFn:   checkpoint,
-			helpMsg: `Creates a checkpoint at the current position.
+			aliases: []string{"rev"},
+			cmdFn:   c.reverse,
+			helpMsg: `Moves the execution of the recording backwards.
 
-	checkpoint [where]`,
-		})
-		c.cmds = append(c.cmds, command{
-			aliases: []string{"checkpoints"},
-			cmdFn:   checkpoints,
-			helpMsg: "Print out info for existing checkpoints.",
-		})
-		c.cmds = append(c.cmds, command{
-			aliases: []string{"clear-checkpoint", "clearcheck"},
-			cmdFn:   clearCheckpoint,
-			helpMsg: `Deletes checkpoint.
+	rev next
+	rev step
+	rev step-instruction
 
-	clear-checkpoint <id>`,
+With next execution moves back to the start of the previous source line, without entering function calls. With step, if the previous source line called a function, execution stops at the return instruction of the called function. The operation is canceled if a breakpoint is hit.
+
+With step-instruction execution moves back by a single cpu instruction and the registers that were modified by that instruction are printed, with their value before and after it was executed.`,
 		})
 		for i := range c.cmds {
 			v := &c.cmds[i]
@@ -550,44 +853,112 @@ func (a byGoroutineID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a byGoroutineID) Less(i, j int) bool { return a[i].ID < a[j].ID }
 
 func goroutines(t *Term, ctx callContext, argstr string) error {
-	args := strings.Split(argstr, " ")
+	args := strings.Fields(argstr)
 	var fgl = fglUserCurrent
-	bPrintStack := false
-
-	switch len(args) {
-	case 0:
-		// nothing to do
-	case 1, 2:
-		for _, arg := range args {
+	fglSet := false
+	bPrintStack, bPrintLabels := false, false
+	bMark, bDiff := false, false
+	var filter api.GoroutineFilter
+	start, count := 0, 0
+	groupBy := ""
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-u":
+			fgl, fglSet = fglUserCurrent, true
+		case "-r":
+			fgl, fglSet = fglRuntimeCurrent, true
+		case "-g":
+			fgl, fglSet = fglGo, true
+		case "-s":
+			fgl, fglSet = fglStart, true
+		case "-mark":
+			bMark = true
+		case "-diff":
+			bDiff = true
+		case "-t":
+			bPrintStack = true
+		case "-l":
+			bPrintLabels = true
+		case "-system":
+			filter.IncludeSystem = true
+		case "-state", "-with", "-label", "-n", "-start", "-group":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires an argument", arg)
+			}
+			i++
+			val := args[i]
 			switch arg {
-			case "-u":
-				fgl = fglUserCurrent
-			case "-r":
-				fgl = fglRuntimeCurrent
-			case "-g":
-				fgl = fglGo
-			case "-s":
-				fgl = fglStart
-			case "-t":
-				bPrintStack = true
-			case "":
-				// nothing to do
-			default:
-				return fmt.Errorf("wrong argument: '%s'", arg)
+			case "-state":
+				filter.States = append(filter.States, strings.Split(val, ",")...)
+			case "-with":
+				filter.Function = val
+			case "-group":
+				groupBy = val
+			case "-label":
+				kv := strings.SplitN(val, "=", 2)
+				if len(kv) != 2 {
+					return fmt.Errorf("wrong label %q, expected key=value", val)
+				}
+				if filter.Labels == nil {
+					filter.Labels = make(map[string]string)
+				}
+				filter.Labels[kv[0]] = kv[1]
+			case "-n", "-start":
+				n, err := strconv.Atoi(val)
+				if err != nil || n < 0 {
+					return fmt.Errorf("wrong argument to %s: %q", arg, val)
+				}
+				if arg == "-n" {
+					count = n
+				} else {
+					start = n
+				}
 			}
+		default:
+			return fmt.Errorf("wrong argument: '%s'", arg)
 		}
-	default:
-		return fmt.Errorf("too many arguments")
+	}
+	if bMark {
+		n, err := t.client.MarkGoroutines()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Marked %d goroutines\n", n)
+		return nil
+	}
+	if bDiff {
+		if !fglSet {
+			fgl = fglGo
+		}
+		return printGoroutineDiff(t, fgl)
+	}
+	if groupBy != "" {
+		return printGoroutineGroups(t, filter, groupBy)
 	}
 	state, err := t.client.GetState()
 	if err != nil {
 		return err
 	}
-	gs, err := t.client.ListGoroutines()
+	gs, nextg, err := t.client.ListGoroutinesFiltered(filter, start, count)
 	if err != nil {
 		return err
 	}
 	sort.Sort(byGoroutineID(gs))
+	var stacks map[int]api.GoroutineStacktrace
+	if bPrintStack && start == 0 && nextg < 0 {
+		// all matching goroutines are listed, get their stacks with a single
+		// call
+		allstacks, err := t.client.Stacktraces(filter, 10, false, nil)
+		if err != nil {
+			return err
+		}
+		stacks = make(map[int]api.GoroutineStacktrace)
+		for _, stack := range allstacks {
+			stacks[stack.GoroutineID] = stack
+		}
+	}
 	fmt.Printf("[%d goroutines]\n", len(gs))
 	for _, g := range gs {
 		prefix := "  "
@@ -595,14 +966,113 @@ func goroutines(t *Term, ctx callContext, argstr string) error {
 			prefix = "* "
 		}
 		fmt.Printf("%sGoroutine %s\n", prefix, formatGoroutine(g, fgl))
+		if bPrintLabels && len(g.Labels) > 0 {
+			fmt.Printf("\tLabels: %s\n", formatGoroutineLabels(g.Labels))
+		}
 		if bPrintStack {
-			stack, err := t.client.Stacktrace(g.ID, 10, false, nil)
-			if err != nil {
-				return err
+			stack, ok := stacks[g.ID]
+			if !ok {
+				stack.Stacktrace, err = t.client.Stacktrace(g.ID, 10, false, nil)
+				if err != nil {
+					return err
+				}
+			}
+			if stack.Err != "" {
+				fmt.Printf("\tcould not read stack: %s\n", stack.Err)
+				continue
 			}
-			printStack(stack, "\t", false)
+			printStack(stack.Stacktrace, "\t", false)
 		}
 	}
+	if nextg >= 0 {
+		fmt.Printf("[more goroutines available, use -start %d to list them]\n", nextg)
+	}
+	return nil
+}
+
+// maxGroupGoroutineIDs is the number of goroutine IDs printed for each
+// group by 'goroutines -group'.
+const maxGroupGoroutineIDs = 5
+
+func printGoroutineGroups(t *Term, filter api.GoroutineFilter, groupBy string) error {
+	groups, err := t.client.GroupGoroutines(filter, groupBy, maxGroupGoroutineIDs)
+	if err != nil {
+		return err
+	}
+	total := 0
+	for _, group := range groups {
+		total += group.Count
+	}
+	fmt.Printf("[%d goroutines in %d groups]\n", total, len(groups))
+	for _, group := range groups {
+		ids := make([]string, len(group.GoroutineIDs))
+		for i, id := range group.GoroutineIDs {
+			ids[i] = strconv.Itoa(id)
+		}
+		if group.Count > len(ids) {
+			ids = append(ids, "...")
+		}
+		fmt.Printf("  %d goroutines - %s [%s]\n", group.Count, group.Name, strings.Join(ids, " "))
+	}
+	return nil
+}
+
+func printGoroutineDiff(t *Term, fgl formatGoroutineLoc) error {
+	diff, err := t.client.GoroutineDiff()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[%d goroutines created]\n", len(diff.Created))
+	for _, g := range diff.Created {
+		fmt.Printf("  Goroutine %s\n", formatGoroutine(g, fgl))
+	}
+	fmt.Printf("[%d goroutines exited]\n", len(diff.Exited))
+	for _, g := range diff.Exited {
+		fmt.Printf("  Goroutine %s\n", formatGoroutine(g, fgl))
+	}
+	return nil
+}
+
+func waiters(t *Term, ctx callContext, args string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("not enough arguments")
+	}
+	ws, err := t.client.ListWaiters(ctx.Scope, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[%d waiting goroutines]\n", len(ws))
+	for _, w := range ws {
+		fmt.Printf("  Goroutine %s - %s\n", formatGoroutine(w.Goroutine, fglUserCurrent), w.Op)
+	}
+	return nil
+}
+
+func mutexCmd(t *Term, ctx callContext, args string) error {
+	ms, err := t.client.MutexInfo(ctx.Scope, args)
+	if err != nil {
+		return err
+	}
+	if !ms.Locked {
+		fmt.Printf("Mutex %#x is unlocked\n", ms.Addr)
+		return nil
+	}
+	var flags string
+	if ms.Woken {
+		flags += ", woken"
+	}
+	if ms.Starving {
+		flags += ", starving"
+	}
+	fmt.Printf("Mutex %#x is locked%s, %d waiters\n", ms.Addr, flags, ms.Waiters)
+	if len(ms.Holders) == 0 {
+		fmt.Println("No possible holder found")
+		return nil
+	}
+	fmt.Println("Possible holders:")
+	for _, g := range ms.Holders {
+		fmt.Printf("  Goroutine %s\n", formatGoroutine(g, fglUserCurrent))
+	}
 	return nil
 }
 
@@ -654,6 +1124,51 @@ func (c *Commands) goroutine(t *Term, ctx callContext, argstr string) error {
 	return c.CallWithContext(args[1], t, ctx)
 }
 
+func freezeGoroutine(t *Term, ctx callContext, argstr string) error {
+	return freezeOrThaw(t, ctx, argstr, false)
+}
+
+func thawGoroutine(t *Term, ctx callContext, argstr string) error {
+	return freezeOrThaw(t, ctx, argstr, true)
+}
+
+// freezeOrThaw implements the freeze and thaw commands.
+func freezeOrThaw(t *Term, ctx callContext, argstr string, thaw bool) error {
+	gid := ctx.Scope.GoroutineID
+	if argstr != "" {
+		var err error
+		gid, err = strconv.Atoi(argstr)
+		if err != nil {
+			return fmt.Errorf("invalid goroutine ID %q", argstr)
+		}
+	}
+	var frozen []int
+	var err error
+	switch {
+	case gid < 0 && !thaw:
+		frozen, err = t.client.ListFrozenGoroutines()
+	case gid < 0:
+		return errors.New("not enough arguments")
+	case thaw:
+		frozen, err = t.client.ThawGoroutine(gid)
+	default:
+		frozen, err = t.client.FreezeGoroutine(gid)
+	}
+	if err != nil {
+		return err
+	}
+	if len(frozen) == 0 {
+		fmt.Println("No frozen goroutines")
+		return nil
+	}
+	ids := make([]string, len(frozen))
+	for i := range frozen {
+		ids[i] = strconv.Itoa(frozen[i])
+	}
+	fmt.Printf("Frozen goroutines: %s\n", strings.Join(ids, ", "))
+	return nil
+}
+
 // Handle "frame", "up", "down" commands.
 func (c *Commands) frameCommand(t *Term, ctx callContext, argstr string, direction frameDirection) error {
 	frame := 1
@@ -692,6 +1207,11 @@ func (c *Commands) frameCommand(t *Term, ctx callContext, argstr string, directi
 	if frame >= len(stack) {
 		return fmt.Errorf("Invalid frame %d", frame)
 	}
+	if ctx.Scope.GoroutineID < 0 {
+		if _, err := t.client.SwitchFrame(frame); err != nil {
+			return err
+		}
+	}
 	c.frame = frame
 	state, err := t.client.GetState()
 	if err != nil {
@@ -704,6 +1224,22 @@ func (c *Commands) frameCommand(t *Term, ctx callContext, argstr string, directi
 	return nil
 }
 
+func (c *Commands) deferredCommand(t *Term, ctx callContext, argstr string) error {
+	args := strings.SplitN(argstr, " ", 2)
+	if len(args) != 2 {
+		return errors.New("not enough arguments")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return fmt.Errorf("argument of deferred must be a number greater than 0")
+	}
+	ctx.Scope.DeferredCall = n
+	return c.CallWithContext(args[1], t, ctx)
+}
+
 func printscope(t *Term) error {
 	state, err := t.client.GetState()
 	if err != nil {
@@ -761,7 +1297,24 @@ func formatGoroutine(g *api.Goroutine, fgl formatGoroutineLoc) string {
 	if g.ThreadID != 0 {
 		thread = fmt.Sprintf(" (thread %d)", g.ThreadID)
 	}
-	return fmt.Sprintf("%d - %s: %s%s", g.ID, locname, formatLocation(loc), thread)
+	wait := ""
+	if g.WaitReason != "" {
+		wait = fmt.Sprintf(" [%s]", formatGoroutineWait(g))
+	}
+	system := ""
+	if g.System {
+		system = " (system)"
+	}
+	return fmt.Sprintf("%d - %s: %s%s%s%s", g.ID, locname, formatLocation(loc), thread, wait, system)
+}
+
+// formatGoroutineWait describes why a goroutine is parked and, if known,
+// for how long.
+func formatGoroutineWait(g *api.Goroutine) string {
+	if g.WaitDuration <= 0 {
+		return g.WaitReason
+	}
+	return fmt.Sprintf("%s, %v", g.WaitReason, g.WaitDuration.Round(time.Second))
 }
 
 func writeGoroutineLong(w io.Writer, g *api.Goroutine, prefix string) {
@@ -771,6 +1324,34 @@ func writeGoroutineLong(w io.Writer, g *api.Goroutine, prefix string) {
 		prefix, formatLocation(g.UserCurrentLoc),
 		prefix, formatLocation(g.GoStatementLoc),
 		prefix, formatLocation(g.StartLoc))
+	if g.WaitReason != "" {
+		fmt.Fprintf(w, "%s\tWaiting: %s\n", prefix, formatGoroutineWait(g))
+	}
+	for _, cas := range g.SelectCases {
+		op := "receive from"
+		if cas.Send {
+			op = "send to"
+		}
+		fmt.Fprintf(w, "%s\tSelect case: %s %#x\n", prefix, op, cas.Chan)
+	}
+	if len(g.Labels) > 0 {
+		fmt.Fprintf(w, "%s\tLabels: %s\n", prefix, formatGoroutineLabels(g.Labels))
+	}
+}
+
+// formatGoroutineLabels formats the pprof labels of a goroutine, sorted by
+// key.
+func formatGoroutineLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	r := make([]string, len(keys))
+	for i, k := range keys {
+		r[i] = fmt.Sprintf("%q:%q", k, labels[k])
+	}
+	return strings.Join(r, ", ")
 }
 
 func parseArgs(args string) ([]string, error) {
@@ -805,6 +1386,9 @@ func restart(t *Term, ctx callContext, args string) error {
 			restartPos = v[0]
 			v = nil
 		}
+	} else if len(v) == 1 && isCheckpointID(v[0]) {
+		restartPos = v[0]
+		v = nil
 	} else if len(v) > 0 {
 		resetArgs = true
 		if v[0] == "-noargs" {
@@ -818,13 +1402,13 @@ func restart(t *Term, ctx callContext, args string) error {
 	if err != nil {
 		return err
 	}
-	if !t.client.Recorded() {
+	if !t.client.Recorded() && restartPos == "" {
 		fmt.Println("Process restarted with PID", t.client.ProcessPid())
 	}
 	for i := range discarded {
 		fmt.Printf("Discarded %s at %s: %v\n", formatBreakpointName(discarded[i].Breakpoint, false), formatBreakpointLocation(discarded[i].Breakpoint), discarded[i].Reason)
 	}
-	if t.client.Recorded() {
+	if t.client.Recorded() || restartPos != "" {
 		state, err := t.client.GetState()
 		if err != nil {
 			return err
@@ -835,6 +1419,16 @@ func restart(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+// isCheckpointID returns true if s is a checkpoint ID, as printed by the
+// checkpoint command.
+func isCheckpointID(s string) bool {
+	if !strings.HasPrefix(s, "c") {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
 func printfileNoState(t *Term) {
 	if state, _ := t.client.GetState(); state != nil && state.CurrentThread != nil {
 		printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
@@ -843,21 +1437,100 @@ func printfileNoState(t *Term) {
 
 func (c *Commands) cont(t *Term, ctx callContext, args string) error {
 	c.frame = 0
+	if args != "" {
+		var state *api.DebuggerState
+		var err error
+		if args == "-recover" {
+			state, err = exitedToError(t.client.ContinueToRecover())
+		} else if v := strings.Fields(args); len(v) > 0 && v[0] == "-goroutine" {
+			if len(v) != 2 {
+				return errors.New("-goroutine requires a single argument")
+			}
+			gid, parseErr := strconv.Atoi(v[1])
+			if parseErr != nil {
+				return fmt.Errorf("invalid goroutine ID %q", v[1])
+			}
+			state, err = exitedToError(t.client.ContinueToGoroutine(gid))
+		} else {
+			state, err = exitedToError(t.client.ContinueUntil(args))
+		}
+		if err != nil {
+			printfileNoState(t)
+			return err
+		}
+		printcontext(t, state)
+		printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
+		return nil
+	}
 	stateChan := t.client.Continue()
 	var state *api.DebuggerState
 	for state = range stateChan {
 		if state.Err != nil {
+			printLogpointMessages(state)
+			printBreakpointActionResults(state)
 			printfileNoState(t)
 			return state.Err
 		}
 		printcontext(t, state)
+		printDeadlock(state.Deadlock)
 	}
 	printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
 	return nil
 }
 
+// printLogpointMessages prints the messages of the logpoints hit while the
+// command that returned state was running.
+func printLogpointMessages(state *api.DebuggerState) {
+	for _, msg := range state.LogpointMessages {
+		fmt.Printf("> [logpoint %d] goroutine(%d): %s\n", msg.BreakpointID, msg.GoroutineID, msg.Message)
+	}
+}
+
+// printBreakpointActionResults prints the results of the breakpoint
+// actions executed while the command that returned state was running.
+func printBreakpointActionResults(state *api.DebuggerState) {
+	for _, result := range state.BreakpointActionResults {
+		prefix := fmt.Sprintf("> [breakpoint %d] goroutine(%d):", result.BreakpointID, result.GoroutineID)
+		if result.Err != "" {
+			fmt.Printf("%s error: %s\n", prefix, result.Err)
+			continue
+		}
+		switch result.Action.Kind {
+		case "eval":
+			fmt.Printf("%s %s = %s\n", prefix, result.Action.Expr, result.Variable.SinglelineString())
+		case "stack":
+			fmt.Printf("%s stack\n", prefix)
+			printStack(result.Stack, "\t", false)
+		case "enable":
+			fmt.Printf("%s enabled breakpoint %d\n", prefix, result.Action.BreakpointID)
+		}
+	}
+}
+
+func printDeadlock(dl *api.Deadlock) {
+	if dl == nil {
+		return
+	}
+	fmt.Printf("Deadlock detected, all goroutines are blocked:\n")
+	for _, bg := range dl.Goroutines {
+		fmt.Printf("\t%s%s\n", formatGoroutine(bg.Goroutine, fglUserCurrent), formatChans(bg.Chans))
+	}
+}
+
+func formatChans(chans []uint64) string {
+	if len(chans) == 0 {
+		return ""
+	}
+	addrs := make([]string, len(chans))
+	for i := range chans {
+		addrs[i] = fmt.Sprintf("%#x", chans[i])
+	}
+	return " on " + strings.Join(addrs, ", ")
+}
+
 func continueUntilCompleteNext(t *Term, state *api.DebuggerState, op string) error {
 	if !state.NextInProgress {
+		printGoroutineSwitch(state)
 		printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
 		return nil
 	}
@@ -867,6 +1540,8 @@ func continueUntilCompleteNext(t *Term, state *api.DebuggerState, op string) err
 		var state *api.DebuggerState
 		for state = range stateChan {
 			if state.Err != nil {
+				printLogpointMessages(state)
+				printBreakpointActionResults(state)
 				printfileNoState(t)
 				return state.Err
 			}
@@ -891,6 +1566,8 @@ func scopePrefixSwitch(t *Term, ctx callContext) error {
 
 func exitedToError(state *api.DebuggerState, err error) (*api.DebuggerState, error) {
 	if err == nil && state.Exited {
+		printLogpointMessages(state)
+		printBreakpointActionResults(state)
 		return nil, fmt.Errorf("Process has exited with status %d", state.ExitStatus)
 	}
 	return state, err
@@ -901,15 +1578,83 @@ func (c *Commands) step(t *Term, ctx callContext, args string) error {
 		return err
 	}
 	c.frame = 0
-	state, err := exitedToError(t.client.Step())
+	deferMode, args, err := parseDeferFlag(args)
+	if err != nil {
+		return err
+	}
+	if args == "-list" {
+		return printStepIntoTargets(t)
+	}
+	count := 0
+	if v := strings.Fields(args); len(v) > 0 && v[0] == "-count" {
+		if len(v) != 2 {
+			return errors.New("-count requires a single argument")
+		}
+		if count, err = parseStepCount(v[1]); err != nil {
+			return err
+		}
+		args = ""
+	}
+	var state *api.DebuggerState
+	if args == "" {
+		state, err = exitedToError(t.client.StepCount(deferMode, count))
+	} else if deferMode != "" {
+		return errors.New("-defer can not be used with a step target")
+	} else {
+		var callPC uint64
+		callPC, err = findStepIntoTarget(t, args)
+		if err != nil {
+			return err
+		}
+		state, err = exitedToError(t.client.StepInto(callPC))
+	}
 	if err != nil {
 		printfileNoState(t)
 		return err
 	}
+	printStepCount(state, count)
 	printcontext(t, state)
 	return continueUntilCompleteNext(t, state, "step")
 }
 
+func printStepIntoTargets(t *Term) error {
+	tgts, err := t.client.ListStepIntoTargets()
+	if err != nil {
+		return err
+	}
+	if len(tgts) == 0 {
+		fmt.Println("No calls on the current line.")
+		return nil
+	}
+	for i, tgt := range tgts {
+		fmt.Printf("%d. %s() call at %#x\n", i+1, tgt.Function.Name(), tgt.CallPC)
+	}
+	return nil
+}
+
+// findStepIntoTarget returns the address of the CALL instruction of the
+// call on the current line specified by target, either as a function name
+// or as an index in the output of printStepIntoTargets.
+func findStepIntoTarget(t *Term, target string) (uint64, error) {
+	tgts, err := t.client.ListStepIntoTargets()
+	if err != nil {
+		return 0, err
+	}
+	if n, err := strconv.Atoi(target); err == nil {
+		if n < 1 || n > len(tgts) {
+			return 0, fmt.Errorf("call index %d out of range", n)
+		}
+		return tgts[n-1].CallPC, nil
+	}
+	for _, tgt := range tgts {
+		name := tgt.Function.Name()
+		if name == target || strings.HasSuffix(name, "."+target) {
+			return tgt.CallPC, nil
+		}
+	}
+	return 0, fmt.Errorf("no call to %s on the current line", target)
+}
+
 var notOnFrameZeroErr = errors.New("not on topmost frame")
 
 func (c *Commands) stepInstruction(t *Term, ctx callContext, args string) error {
@@ -919,39 +1664,199 @@ func (c *Commands) stepInstruction(t *Term, ctx callContext, args string) error
 	if c.frame != 0 {
 		return notOnFrameZeroErr
 	}
-	state, err := exitedToError(t.client.StepInstruction())
+	count, err := parseStepCount(args)
+	if err != nil {
+		return err
+	}
+	state, err := exitedToError(t.client.StepInstructionCount(count))
 	if err != nil {
 		printfileNoState(t)
 		return err
 	}
+	printStepCount(state, count)
 	printcontext(t, state)
+	printGoroutineSwitch(state)
 	printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
 	return nil
 }
 
-func (c *Commands) next(t *Term, ctx callContext, args string) error {
+func (c *Commands) nextInstruction(t *Term, ctx callContext, args string) error {
 	if err := scopePrefixSwitch(t, ctx); err != nil {
 		return err
 	}
 	if c.frame != 0 {
 		return notOnFrameZeroErr
 	}
-	state, err := exitedToError(t.client.Next())
+	state, err := exitedToError(t.client.NextInstruction())
+	if err != nil {
+		printfileNoState(t)
+		return err
+	}
+	printcontext(t, state)
+	return continueUntilCompleteNext(t, state, "next-instruction")
+}
+
+func (c *Commands) next(t *Term, ctx callContext, args string) error {
+	if err := scopePrefixSwitch(t, ctx); err != nil {
+		return err
+	}
+	c.frame = 0
+	deferMode, args, err := parseDeferFlag(args)
+	if err != nil {
+		return err
+	}
+	count, err := parseStepCount(args)
+	if err != nil {
+		return err
+	}
+	state, err := exitedToError(t.client.NextCount(deferMode, count))
 	if err != nil {
 		printfileNoState(t)
 		return err
 	}
+	printStepCount(state, count)
 	printcontext(t, state)
 	return continueUntilCompleteNext(t, state, "next")
 }
 
-func (c *Commands) stepout(t *Term, ctx callContext, args string) error {
+// parseStepCount parses the optional repeat count argument of next, step
+// and step-instruction, returning zero if it is not specified.
+func parseStepCount(arg string) (int, error) {
+	if arg == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid count %q", arg)
+	}
+	return n, nil
+}
+
+// printStepCount lists the locations reached by the intermediate steps of a
+// repeated step command and reports how many steps were executed if it was
+// interrupted.
+func printStepCount(state *api.DebuggerState, count int) {
+	for i := 0; i+1 < len(state.StepLocations); i++ {
+		fmt.Printf("Step %d: %s\n", i+1, formatLocation(state.StepLocations[i]))
+	}
+	if count > 1 && state.Steps < count {
+		fmt.Printf("Stopped after %d of %d steps.\n", state.Steps, count)
+	}
+}
+
+// printGoroutineSwitch reports that a step command stopped on a different
+// goroutine than the one it started on.
+func printGoroutineSwitch(state *api.DebuggerState) {
+	if state.StepGoroutineID == 0 || state.SelectedGoroutine == nil {
+		return
+	}
+	fmt.Printf("Switched from goroutine %d to goroutine %d, use 'continue -goroutine %d' to continue until goroutine %d runs again.\n", state.StepGoroutineID, state.SelectedGoroutine.ID, state.StepGoroutineID, state.StepGoroutineID)
+}
+
+// parseDeferFlag parses the optional '-defer <mode>' flag of next and
+// step, returning the mode and the rest of args.
+func parseDeferFlag(args string) (mode, rest string, err error) {
+	v := strings.Fields(args)
+	if len(v) == 0 || v[0] != "-defer" {
+		return "", strings.TrimSpace(args), nil
+	}
+	if len(v) < 2 {
+		return "", "", errors.New("-defer requires one of stop, skip or entry")
+	}
+	switch v[1] {
+	case "stop", "skip", "entry":
+	default:
+		return "", "", fmt.Errorf("unknown defer mode %q, expected stop, skip or entry", v[1])
+	}
+	return v[1], strings.Join(v[2:], " "), nil
+}
+
+func (c *Commands) until(t *Term, ctx callContext, args string) error {
+	if err := scopePrefixSwitch(t, ctx); err != nil {
+		return err
+	}
+	if c.frame != 0 {
+		return notOnFrameZeroErr
+	}
+	state, err := exitedToError(t.client.Until())
+	if err != nil {
+		printfileNoState(t)
+		return err
+	}
+	printcontext(t, state)
+	return continueUntilCompleteNext(t, state, "until")
+}
+
+// reverseStepInstruction moves back by one instruction and prints the
+// registers that the instruction changed.
+func reverseStepInstruction(t *Term) error {
+	state, err := t.client.GetState()
+	if err != nil {
+		return err
+	}
+	if state.CurrentThread == nil {
+		return errors.New("no current thread")
+	}
+	after, err := t.client.ListRegisters(state.CurrentThread.ID, false)
+	if err != nil {
+		return err
+	}
+	state, err = exitedToError(t.client.ReverseStepInstruction())
+	if err != nil {
+		printfileNoState(t)
+		return err
+	}
+	before, err := t.client.ListRegisters(state.CurrentThread.ID, false)
+	if err != nil {
+		return err
+	}
+	printcontext(t, state)
+	afterValues := make(map[string]string, len(after))
+	for _, reg := range after {
+		afterValues[reg.Name] = reg.Value
+	}
+	for _, reg := range before {
+		if v, ok := afterValues[reg.Name]; ok && v != reg.Value {
+			fmt.Printf("%10s = %s -> %s\n", reg.Name, reg.Value, v)
+		}
+	}
+	printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
+	return nil
+}
+
+func (c *Commands) reverse(t *Term, ctx callContext, args string) error {
 	if err := scopePrefixSwitch(t, ctx); err != nil {
 		return err
 	}
 	if c.frame != 0 {
 		return notOnFrameZeroErr
 	}
+	var state *api.DebuggerState
+	var err error
+	switch args {
+	case "next", "n":
+		state, err = exitedToError(t.client.ReverseNext())
+	case "step", "s":
+		state, err = exitedToError(t.client.ReverseStep())
+	case "step-instruction", "si":
+		return reverseStepInstruction(t)
+	default:
+		return fmt.Errorf("wrong argument %q, expected next, step or step-instruction", args)
+	}
+	if err != nil {
+		printfileNoState(t)
+		return err
+	}
+	printcontext(t, state)
+	printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
+	return nil
+}
+
+func (c *Commands) stepout(t *Term, ctx callContext, args string) error {
+	if err := scopePrefixSwitch(t, ctx); err != nil {
+		return err
+	}
+	c.frame = 0
 	state, err := exitedToError(t.client.StepOut())
 	if err != nil {
 		printfileNoState(t)
@@ -961,11 +1866,50 @@ func (c *Commands) stepout(t *Term, ctx callContext, args string) error {
 	return continueUntilCompleteNext(t, state, "stepout")
 }
 
-func (c *Commands) call(t *Term, ctx callContext, args string) error {
+func (c *Commands) jump(t *Term, ctx callContext, args string) error {
+	if err := scopePrefixSwitch(t, ctx); err != nil {
+		return err
+	}
+	if c.frame != 0 {
+		return notOnFrameZeroErr
+	}
+	if args == "" {
+		return errors.New("not enough arguments")
+	}
+	state, err := t.client.Jump(args)
+	if err != nil {
+		return err
+	}
+	printcontext(t, state)
+	printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
+	return nil
+}
+
+func (c *Commands) stepToReturn(t *Term, ctx callContext, args string) error {
 	if err := scopePrefixSwitch(t, ctx); err != nil {
 		return err
 	}
-	state, err := exitedToError(t.client.Call(args))
+	if c.frame != 0 {
+		return notOnFrameZeroErr
+	}
+	state, err := exitedToError(t.client.StepToReturn())
+	if err != nil {
+		printfileNoState(t)
+		return err
+	}
+	printcontext(t, state)
+	return continueUntilCompleteNext(t, state, "step-to-return")
+}
+
+func (c *Commands) call(t *Term, ctx callContext, args string) error {
+	var state *api.DebuggerState
+	var err error
+	if ctx.Scope.GoroutineID > 0 {
+		// the goroutine could be parked, let the debugger resume it
+		state, err = exitedToError(t.client.CallGoroutine(ctx.Scope.GoroutineID, args))
+	} else {
+		state, err = exitedToError(t.client.Call(args))
+	}
 	c.frame = 0
 	if err != nil {
 		printfileNoState(t)
@@ -979,6 +1923,9 @@ func clear(t *Term, ctx callContext, args string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("not enough arguments")
 	}
+	if args == "-internal" {
+		return t.client.CancelNext()
+	}
 	id, err := strconv.Atoi(args)
 	var bp *api.Breakpoint
 	if err == nil {
@@ -1039,21 +1986,49 @@ func (a ByID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a ByID) Less(i, j int) bool { return a[i].ID < a[j].ID }
 
 func breakpoints(t *Term, ctx callContext, args string) error {
+	if args == "-internal" {
+		return internalBreakpoints(t)
+	}
 	breakPoints, err := t.client.ListBreakpoints()
 	if err != nil {
 		return err
 	}
 	sort.Sort(ByID(breakPoints))
 	for _, bp := range breakPoints {
-		fmt.Printf("%s at %v (%d)\n", formatBreakpointName(bp, true), formatBreakpointLocation(bp), bp.TotalHitCount)
+		disabled := ""
+		if bp.Disabled {
+			disabled = " (disabled)"
+		}
+		fmt.Printf("%s at %v (%d)%s\n", formatBreakpointName(bp, true), formatBreakpointLocation(bp), bp.TotalHitCount, disabled)
 
 		var attrs []string
+		if bp.Group != "" {
+			attrs = append(attrs, fmt.Sprintf("\tgroup %s", bp.Group))
+		}
 		if bp.Cond != "" {
 			attrs = append(attrs, fmt.Sprintf("\tcond %s", bp.Cond))
 		}
+		if bp.LogMessage != "" {
+			attrs = append(attrs, fmt.Sprintf("\tlogpoint %s", bp.LogMessage))
+		}
+		for _, action := range bp.Actions {
+			attrs = append(attrs, fmt.Sprintf("\taction %s", formatBreakpointAction(action)))
+		}
+		if bp.HitCond != "" {
+			attrs = append(attrs, fmt.Sprintf("\tcond -hitcount %s", bp.HitCond))
+		}
+		if bp.MaxHits > 0 {
+			attrs = append(attrs, fmt.Sprintf("\tcond -maxhits %d", bp.MaxHits))
+		}
+		if bp.ThreadID != 0 {
+			attrs = append(attrs, fmt.Sprintf("\tcond -thread %d", bp.ThreadID))
+		}
 		if bp.Stacktrace > 0 {
 			attrs = append(attrs, fmt.Sprintf("\tstack %d", bp.Stacktrace))
 		}
+		if bp.RecordStack > 0 {
+			attrs = append(attrs, fmt.Sprintf("\ttrace -stack %d", bp.RecordStack))
+		}
 		if bp.Goroutine {
 			attrs = append(attrs, "\tgoroutine")
 		}
@@ -1081,10 +2056,41 @@ func breakpoints(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func internalBreakpoints(t *Term) error {
+	breakPoints, err := t.client.ListInternalBreakpoints()
+	if err != nil {
+		return err
+	}
+	sort.Sort(ByID(breakPoints))
+	for _, bp := range breakPoints {
+		fmt.Printf("Internal breakpoint %d (%s, set by %s) at %s\n", bp.ID, bp.InternalKind, bp.Origin, formatBreakpointLocation(bp))
+		if bp.Cond != "" {
+			fmt.Printf("\tcond %s\n", bp.Cond)
+		}
+	}
+	return nil
+}
+
 func setBreakpoint(t *Term, ctx callContext, tracepoint bool, argstr string) error {
+	requestedBp := &api.Breakpoint{}
+	if !tracepoint && strings.HasPrefix(argstr, "-hw ") {
+		requestedBp.Hardware = true
+		argstr = strings.TrimSpace(argstr[len("-hw "):])
+	}
+	if tracepoint && strings.HasPrefix(argstr, "-stack ") {
+		v := strings.SplitN(strings.TrimSpace(argstr[len("-stack "):]), " ", 2)
+		if len(v) != 2 {
+			return fmt.Errorf("not enough arguments")
+		}
+		depth, err := strconv.Atoi(v[0])
+		if err != nil || depth <= 0 {
+			return fmt.Errorf("wrong stack depth %q", v[0])
+		}
+		requestedBp.RecordStack = depth
+		argstr = v[1]
+	}
 	args := strings.SplitN(argstr, " ", 2)
 
-	requestedBp := &api.Breakpoint{}
 	locspec := ""
 	switch len(args) {
 	case 1:
@@ -1186,11 +2192,30 @@ func printVar(t *Term, ctx callContext, args string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("not enough arguments")
 	}
+	cfg := t.loadConfig()
+	if args[0] == '%' {
+		v := strings.SplitN(args, " ", 2)
+		if len(v) != 2 || len(v[0]) != 2 {
+			return fmt.Errorf("wrong format %q", v[0])
+		}
+		switch v[0][1] {
+		case 'x', 'o', 'b', 'c', 'd':
+			cfg.IntFormat = v[0][1]
+		case 's':
+			cfg.RawStrings = true
+		default:
+			return fmt.Errorf("unknown format %q", v[0])
+		}
+		if ctx.Prefix == onPrefix {
+			return fmt.Errorf("formats can not be used with on")
+		}
+		args = strings.TrimSpace(v[1])
+	}
 	if ctx.Prefix == onPrefix {
 		ctx.Breakpoint.Variables = append(ctx.Breakpoint.Variables, args)
 		return nil
 	}
-	val, err := t.client.EvalVariable(ctx.Scope, args, t.loadConfig())
+	val, err := t.client.EvalVariable(ctx.Scope, args, cfg)
 	if err != nil {
 		return err
 	}
@@ -1222,9 +2247,42 @@ func whatisCommand(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func printerCmd(t *Term, ctx callContext, argstr string) error {
+	argv := strings.SplitN(strings.TrimSpace(argstr), " ", 2)
+	switch {
+	case argv[0] == "":
+		formatters, err := t.client.ListFormatters()
+		if err != nil {
+			return err
+		}
+		if len(formatters) == 0 {
+			fmt.Println("No pretty-printers defined")
+			return nil
+		}
+		typenames := make([]string, 0, len(formatters))
+		for typename := range formatters {
+			typenames = append(typenames, typename)
+		}
+		sort.Strings(typenames)
+		for _, typename := range typenames {
+			fmt.Printf("%s %s\n", typename, formatters[typename])
+		}
+		return nil
+	case argv[0] == "-clear":
+		if len(argv) < 2 {
+			return fmt.Errorf("not enough arguments")
+		}
+		return t.client.SetFormatter(strings.TrimSpace(argv[1]), "")
+	case len(argv) < 2 || strings.TrimSpace(argv[1]) == "":
+		return fmt.Errorf("not enough arguments")
+	default:
+		return t.client.SetFormatter(argv[0], strings.TrimSpace(argv[1]))
+	}
+}
+
 func setVar(t *Term, ctx callContext, args string) error {
 	// HACK: in go '=' is not an operator, we detect the error and try to recover from it by splitting the input string
-	_, err := parser.ParseExpr(args)
+	_, err := proc.ParseExpr(args)
 	if err == nil {
 		return fmt.Errorf("syntax error '=' not found")
 	}
@@ -1252,10 +2310,14 @@ func printFilteredVariables(varType string, vars []api.Variable, filter string,
 			if v.Flags&api.VariableShadowed != 0 {
 				name = "(" + name + ")"
 			}
+			uninit := ""
+			if v.Flags&api.VariableUninitialized != 0 {
+				uninit = " (maybe uninitialized)"
+			}
 			if cfg == ShortLoadConfig {
-				fmt.Printf("%s = %s\n", name, v.SinglelineString())
+				fmt.Printf("%s = %s%s\n", name, v.SinglelineString(), uninit)
 			} else {
-				fmt.Printf("%s = %s\n", name, v.MultilineString(""))
+				fmt.Printf("%s = %s%s\n", name, v.MultilineString(""), uninit)
 			}
 		}
 	}
@@ -1265,6 +2327,28 @@ func printFilteredVariables(varType string, vars []api.Variable, filter string,
 	return nil
 }
 
+func printLocalInstances(t *Term, ctx callContext, name string) error {
+	instances, err := t.client.ListLocalInstances(ctx.Scope, name, ShortLoadConfig)
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		return fmt.Errorf("no local variable or argument called %s", name)
+	}
+	for _, v := range instances {
+		marker := "  "
+		if v.InScope {
+			marker = "=>"
+		}
+		ranges := make([]string, len(v.Ranges))
+		for i, rng := range v.Ranges {
+			ranges[i] = fmt.Sprintf("%#x-%#x", rng[0], rng[1])
+		}
+		fmt.Printf("%s line %d [%s] %s = %s\n", marker, v.DeclLine, strings.Join(ranges, " "), v.Name, v.SinglelineString())
+	}
+	return nil
+}
+
 func printSortedStrings(v []string, err error) error {
 	if err != nil {
 		return err
@@ -1316,6 +2400,15 @@ func args(t *Term, ctx callContext, args string) error {
 }
 
 func locals(t *Term, ctx callContext, args string) error {
+	if v := strings.SplitN(args, " ", 2); v[0] == "-decls" {
+		if ctx.Prefix == onPrefix {
+			return fmt.Errorf("-decls not supported on breakpoint")
+		}
+		if len(v) < 2 || strings.TrimSpace(v[1]) == "" {
+			return fmt.Errorf("not enough arguments")
+		}
+		return printLocalInstances(t, ctx, strings.TrimSpace(v[1]))
+	}
 	filter, cfg := parseVarArguments(args, t)
 	if ctx.Prefix == onPrefix {
 		if filter != "" {
@@ -1332,14 +2425,46 @@ func locals(t *Term, ctx callContext, args string) error {
 }
 
 func vars(t *Term, ctx callContext, args string) error {
+	pkg, noValues := "", false
+	for {
+		v := strings.SplitN(args, " ", 2)
+		if v[0] == "-pkg" {
+			if len(v) < 2 {
+				return errors.New("not enough arguments to -pkg")
+			}
+			v = strings.SplitN(strings.TrimSpace(v[1]), " ", 2)
+			pkg = v[0]
+		} else if v[0] == "-n" {
+			noValues = true
+		} else {
+			break
+		}
+		args = ""
+		if len(v) == 2 {
+			args = strings.TrimSpace(v[1])
+		}
+	}
 	filter, cfg := parseVarArguments(args, t)
-	vars, err := t.client.ListPackageVariables(filter, cfg)
+	if !noValues {
+		vars, err := t.client.FindPackageVariables(pkg, filter, &cfg)
+		if err != nil {
+			return err
+		}
+		return printFilteredVariables("vars", vars, filter, cfg)
+	}
+	vars, err := t.client.FindPackageVariables(pkg, filter, nil)
 	if err != nil {
 		return err
 	}
-	return printFilteredVariables("vars", vars, filter, cfg)
-}
-
+	if len(vars) == 0 {
+		fmt.Println("(no vars)")
+	}
+	for _, v := range vars {
+		fmt.Printf("%s %s\n", v.Name, v.Type)
+	}
+	return nil
+}
+
 func regs(t *Term, ctx callContext, args string) error {
 	includeFp := false
 	if args == "-a" {
@@ -1366,29 +2491,63 @@ func stackCommand(t *Term, ctx callContext, args string) error {
 	if sa.full {
 		cfg = &ShortLoadConfig
 	}
-	stack, err := t.client.Stacktrace(ctx.Scope.GoroutineID, sa.depth, sa.readDefers, cfg)
+	var stack []api.Stackframe
+	if sa.systemStack {
+		stack, err = t.client.SystemStacktrace(ctx.Scope.GoroutineID, sa.depth, sa.readDefers, cfg)
+	} else {
+		stack, err = t.client.Stacktrace(ctx.Scope.GoroutineID, sa.depth, sa.readDefers, cfg)
+	}
 	if err != nil {
 		return err
 	}
 	printStack(stack, "", sa.offsets)
+	if sa.ancestors > 0 {
+		ancestors, err := t.client.Ancestors(ctx.Scope.GoroutineID, sa.ancestors, sa.ancestorDepth)
+		if err != nil {
+			return err
+		}
+		for _, ancestor := range ancestors {
+			fmt.Printf("Created by Goroutine %d:\n", ancestor.ID)
+			if ancestor.Unreadable != "" {
+				fmt.Printf("\t%s\n", ancestor.Unreadable)
+				continue
+			}
+			printStack(ancestor.Stack, "\t", false)
+		}
+	}
 	return nil
 }
 
 type stackArgs struct {
-	depth      int
-	full       bool
-	offsets    bool
-	readDefers bool
+	depth       int
+	full        bool
+	offsets     bool
+	readDefers  bool
+	systemStack bool
+
+	ancestors     int
+	ancestorDepth int
 }
 
 func parseStackArgs(argstr string) (stackArgs, error) {
 	r := stackArgs{
-		depth: 10,
-		full:  false,
+		depth:         10,
+		full:          false,
+		ancestorDepth: 10,
 	}
 	if argstr != "" {
 		args := strings.Split(argstr, " ")
-		for i := range args {
+		numarg := func(i int, name string) (int, error) {
+			if i+1 >= len(args) {
+				return 0, fmt.Errorf("%s requires an argument", name)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return 0, fmt.Errorf("argument of %s must be a number", name)
+			}
+			return n, nil
+		}
+		for i := 0; i < len(args); i++ {
 			switch args[i] {
 			case "-full":
 				r.full = true
@@ -1396,6 +2555,22 @@ func parseStackArgs(argstr string) (stackArgs, error) {
 				r.offsets = true
 			case "-defer":
 				r.readDefers = true
+			case "-system":
+				r.systemStack = true
+			case "-a":
+				n, err := numarg(i, "-a")
+				if err != nil {
+					return stackArgs{}, err
+				}
+				r.ancestors = n
+				i++
+			case "-adepth":
+				n, err := numarg(i, "-adepth")
+				if err != nil {
+					return stackArgs{}, err
+				}
+				r.ancestorDepth = n
+				i++
 			default:
 				n, err := strconv.Atoi(args[i])
 				if err != nil {
@@ -1550,6 +2725,9 @@ func printStack(stack []api.Stackframe, ind string, offsets bool) {
 	s := ind + strings.Repeat(" ", d+2+len(ind))
 
 	for i := range stack {
+		if i > 0 && stack[i-1].SystemStack && !stack[i].SystemStack {
+			fmt.Printf("%s--- switch to system stack ---\n", s)
+		}
 		if stack[i].Err != "" {
 			fmt.Printf("%serror: %s\n", s, stack[i].Err)
 			continue
@@ -1562,7 +2740,7 @@ func printStack(stack []api.Stackframe, ind string, offsets bool) {
 		}
 
 		for j, d := range stack[i].Defers {
-			deferHeader := fmt.Sprintf("%s    defer %d: ", s, j)
+			deferHeader := fmt.Sprintf("%s    defer %d: ", s, j+1)
 			s2 := strings.Repeat(" ", len(deferHeader))
 			if d.Unreadable != "" {
 				fmt.Printf("%s(unreadable defer: %s)\n", deferHeader, d.Unreadable)
@@ -1587,6 +2765,8 @@ func printStack(stack []api.Stackframe, ind string, offsets bool) {
 }
 
 func printcontext(t *Term, state *api.DebuggerState) error {
+	printLogpointMessages(state)
+	printBreakpointActionResults(state)
 	for i := range state.Threads {
 		if (state.CurrentThread != nil) && (state.Threads[i].ID == state.CurrentThread.ID) {
 			continue
@@ -1629,9 +2809,29 @@ func printcontext(t *Term, state *api.DebuggerState) error {
 		fmt.Println(state.When)
 	}
 
+	printWatchExpressions(state.WatchExpressions)
+
 	return nil
 }
 
+// printWatchExpressions prints the values of the watch expressions, added
+// with the display command.
+func printWatchExpressions(display []api.WatchExpression) {
+	for _, de := range display {
+		changed := ""
+		switch {
+		case de.Delta != "" && de.Delta != "0":
+			if !strings.HasPrefix(de.Delta, "-") {
+				de.Delta = "+" + de.Delta
+			}
+			changed = " (" + de.Delta + ")"
+		case de.Changed:
+			changed = " (changed)"
+		}
+		fmt.Printf("%d: %s = %s%s\n", de.ID, de.Expr, de.Value.SinglelineString(), changed)
+	}
+}
+
 func printcontextLocation(loc api.Location) {
 	fmt.Printf("> %s() %s:%d (PC: %#v)\n", loc.Function.Name(), ShortenFilePath(loc.File), loc.Line, loc.PC)
 	if loc.Function != nil && loc.Function.Optimized {
@@ -1655,6 +2855,12 @@ func printcontextThread(t *Term, th *api.Thread) {
 	fn := th.Function
 
 	if th.Breakpoint == nil {
+		if th.Signal != 0 {
+			fmt.Printf("received signal %d\n", th.Signal)
+		}
+		if th.Syscall != nil {
+			fmt.Println(formatSyscall(th.Syscall))
+		}
 		printcontextLocation(api.Location{PC: th.PC, File: th.File, Line: th.Line, Function: th.Function})
 		printReturnValues(th)
 		return
@@ -1699,6 +2905,10 @@ func printcontextThread(t *Term, th *api.Thread) {
 		fmt.Println(optimizedFunctionWarning)
 	}
 
+	if th.Cgo != nil {
+		fmt.Println(formatCgoEvent(th.Cgo))
+	}
+
 	printReturnValues(th)
 
 	if th.BreakpointInfo != nil {
@@ -1709,6 +2919,10 @@ func printcontextThread(t *Term, th *api.Thread) {
 			writeGoroutineLong(os.Stdout, bpi.Goroutine, "\t")
 		}
 
+		if bpi.PanicLocation != nil {
+			fmt.Printf("\tpanic location: %s:%d %s\n", ShortenFilePath(bpi.PanicLocation.File), bpi.PanicLocation.Line, bpi.PanicLocation.Function.Name())
+		}
+
 		for _, v := range bpi.Variables {
 			fmt.Printf("\t%s: %s\n", v.Name, v.MultilineString("\t"))
 		}
@@ -1807,6 +3021,45 @@ func getBreakpointByIDOrName(t *Term, arg string) (*api.Breakpoint, error) {
 	return t.client.GetBreakpointByName(arg)
 }
 
+func tracestacks(t *Term, ctx callContext, argstr string) error {
+	if argstr == "" {
+		return errors.New("not enough arguments")
+	}
+	bp, err := getBreakpointByIDOrName(t, argstr)
+	if err != nil {
+		return err
+	}
+	recs, err := t.client.TakeStackRecords(bp.ID)
+	if err != nil {
+		return err
+	}
+	// group identical stacktraces, keeping the order of their first
+	// occurrence.
+	var stacks [][]api.Stackframe
+	counts := map[string]int{}
+	for _, rec := range recs {
+		key := stackKey(rec.Stack)
+		if counts[key] == 0 {
+			stacks = append(stacks, rec.Stack)
+		}
+		counts[key]++
+	}
+	for _, stack := range stacks {
+		fmt.Printf("%d hits:\n", counts[stackKey(stack)])
+		printStack(stack, "\t", false)
+	}
+	return nil
+}
+
+// stackKey returns a string identifying the sequence of PCs of stack.
+func stackKey(stack []api.Stackframe) string {
+	pcs := make([]string, len(stack))
+	for i := range stack {
+		pcs[i] = strconv.FormatUint(stack[i].PC, 16)
+	}
+	return strings.Join(pcs, ",")
+}
+
 func (c *Commands) onCmd(t *Term, ctx callContext, argstr string) error {
 	args := strings.SplitN(argstr, " ", 2)
 
@@ -1835,6 +3088,51 @@ func conditionCmd(t *Term, ctx callContext, argstr string) error {
 		return fmt.Errorf("not enough arguments")
 	}
 
+	if args[0] == "-hitcount" {
+		args = strings.SplitN(args[1], " ", 2)
+		if len(args) < 2 {
+			return fmt.Errorf("not enough arguments")
+		}
+		bp, err := getBreakpointByIDOrName(t, args[0])
+		if err != nil {
+			return err
+		}
+		bp.HitCond = args[1]
+		return t.client.AmendBreakpoint(bp)
+	}
+
+	if args[0] == "-maxhits" {
+		args = strings.SplitN(args[1], " ", 2)
+		if len(args) < 2 {
+			return fmt.Errorf("not enough arguments")
+		}
+		bp, err := getBreakpointByIDOrName(t, args[0])
+		if err != nil {
+			return err
+		}
+		bp.MaxHits, err = strconv.ParseUint(strings.TrimSpace(args[1]), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid max hits %q: %v", args[1], err)
+		}
+		return t.client.AmendBreakpoint(bp)
+	}
+
+	if args[0] == "-thread" {
+		args = strings.SplitN(args[1], " ", 2)
+		if len(args) < 2 {
+			return fmt.Errorf("not enough arguments")
+		}
+		bp, err := getBreakpointByIDOrName(t, args[0])
+		if err != nil {
+			return err
+		}
+		bp.ThreadID, err = strconv.Atoi(strings.TrimSpace(args[1]))
+		if err != nil {
+			return fmt.Errorf("invalid thread id %q: %v", args[1], err)
+		}
+		return t.client.AmendBreakpoint(bp)
+	}
+
 	bp, err := getBreakpointByIDOrName(t, args[0])
 	if err != nil {
 		return err
@@ -1844,6 +3142,632 @@ func conditionCmd(t *Term, ctx callContext, argstr string) error {
 	return t.client.AmendBreakpoint(bp)
 }
 
+func logpointCmd(t *Term, ctx callContext, argstr string) error {
+	args := strings.SplitN(argstr, " ", 2)
+
+	if len(args) < 1 || args[0] == "" {
+		return fmt.Errorf("not enough arguments")
+	}
+
+	bp, err := getBreakpointByIDOrName(t, args[0])
+	if err != nil {
+		return err
+	}
+	bp.LogMessage = ""
+	if len(args) > 1 {
+		bp.LogMessage = strings.TrimSpace(args[1])
+	}
+
+	return t.client.AmendBreakpoint(bp)
+}
+
+func actionCmd(t *Term, ctx callContext, argstr string) error {
+	args := strings.SplitN(argstr, " ", 3)
+
+	if len(args) < 2 {
+		return fmt.Errorf("not enough arguments")
+	}
+
+	bp, err := getBreakpointByIDOrName(t, args[0])
+	if err != nil {
+		return err
+	}
+
+	arg := ""
+	if len(args) > 2 {
+		arg = strings.TrimSpace(args[2])
+	}
+
+	var action api.BreakpointAction
+	switch args[1] {
+	case "-clear":
+		bp.Actions = nil
+		return t.client.AmendBreakpoint(bp)
+	case "print", "p":
+		if arg == "" {
+			return fmt.Errorf("not enough arguments")
+		}
+		action = api.BreakpointAction{Kind: "eval", Expr: arg}
+	case "stack", "bt":
+		action = api.BreakpointAction{Kind: "stack", Depth: 10}
+		if arg != "" {
+			if action.Depth, err = strconv.Atoi(arg); err != nil {
+				return fmt.Errorf("invalid stack depth %q", arg)
+			}
+		}
+	case "enable":
+		action = api.BreakpointAction{Kind: "enable"}
+		other, err := getBreakpointByIDOrName(t, arg)
+		if err != nil {
+			return err
+		}
+		action.BreakpointID = other.ID
+	case "continue", "c":
+		action = api.BreakpointAction{Kind: "continue"}
+	default:
+		return fmt.Errorf("unknown action %q", args[1])
+	}
+
+	bp.Actions = append(bp.Actions, action)
+	return t.client.AmendBreakpoint(bp)
+}
+
+func formatBreakpointAction(action api.BreakpointAction) string {
+	switch action.Kind {
+	case "eval":
+		return "print " + action.Expr
+	case "stack":
+		return fmt.Sprintf("stack %d", action.Depth)
+	case "enable":
+		return fmt.Sprintf("enable %d", action.BreakpointID)
+	default:
+		return action.Kind
+	}
+}
+
+func formatSyscall(sc *api.Syscall) string {
+	name := sc.Name
+	if name == "" {
+		name = fmt.Sprintf("syscall %d", sc.Num)
+	}
+	args := make([]string, len(sc.Args))
+	for i := range sc.Args {
+		args[i] = fmt.Sprintf("%#x", sc.Args[i])
+	}
+	s := fmt.Sprintf("%s(%s)", name, strings.Join(args, ", "))
+	if sc.Exit {
+		s = fmt.Sprintf("syscall exit %s = %d", s, sc.Ret)
+	} else {
+		s = "syscall entry " + s
+	}
+	if sc.Path != "" {
+		s += " path " + strconv.Quote(sc.Path)
+	}
+	return s
+}
+
+func formatCgoEvent(ev *api.CgoEvent) string {
+	switch ev.Direction {
+	case "call":
+		if ev.FnName != "" {
+			return fmt.Sprintf("cgo call to %s (%#x)", ev.FnName, ev.Fn)
+		}
+		return fmt.Sprintf("cgo call to %#x", ev.Fn)
+	case "return":
+		return "cgo return to Go"
+	default:
+		return "cgo callback into Go"
+	}
+}
+
+func catchCmd(t *Term, ctx callContext, argstr string) error {
+	args := strings.Fields(argstr)
+	if len(args) < 1 {
+		return fmt.Errorf("not enough arguments")
+	}
+	switch args[0] {
+	case "syscall":
+		return t.client.SetSyscallCatchpoints(args[1:])
+	case "cgo":
+		switch {
+		case len(args) == 1:
+			return t.client.SetCgoCatchpoints(true)
+		case len(args) == 2 && args[1] == "off":
+			return t.client.SetCgoCatchpoints(false)
+		default:
+			return fmt.Errorf("wrong arguments for catch cgo")
+		}
+	default:
+		return fmt.Errorf("unknown catchpoint type %q", args[0])
+	}
+}
+
+// signalNumbers maps signal names to their number on linux.
+var signalNumbers = map[string]int{
+	"SIGHUP": 1, "SIGINT": 2, "SIGQUIT": 3, "SIGILL": 4, "SIGTRAP": 5,
+	"SIGABRT": 6, "SIGBUS": 7, "SIGFPE": 8, "SIGKILL": 9, "SIGUSR1": 10,
+	"SIGSEGV": 11, "SIGUSR2": 12, "SIGPIPE": 13, "SIGALRM": 14, "SIGTERM": 15,
+	"SIGSTKFLT": 16, "SIGCHLD": 17, "SIGCONT": 18, "SIGSTOP": 19, "SIGTSTP": 20,
+	"SIGTTIN": 21, "SIGTTOU": 22, "SIGURG": 23, "SIGXCPU": 24, "SIGXFSZ": 25,
+	"SIGVTALRM": 26, "SIGPROF": 27, "SIGWINCH": 28, "SIGIO": 29, "SIGPWR": 30,
+	"SIGSYS": 31,
+}
+
+func sampleCmd(t *Term, ctx callContext, argstr string) error {
+	duration := time.Second
+	if argstr = strings.TrimSpace(argstr); argstr != "" {
+		var err error
+		duration, err = time.ParseDuration(argstr)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("invalid duration %q", argstr)
+		}
+	}
+	state, err := exitedToError(t.client.SampleStacks(duration))
+	if err != nil {
+		printfileNoState(t)
+		return err
+	}
+	printSamples(state.Samples)
+	printcontext(t, state)
+	printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
+	return nil
+}
+
+// printSamples prints the stacks recorded by the sample command with the
+// percentage of samples they appear in.
+func printSamples(samples []api.StackSample) {
+	total := 0
+	for _, sample := range samples {
+		total += sample.Count
+	}
+	if total == 0 {
+		fmt.Println("No running goroutines sampled")
+		return
+	}
+	fmt.Printf("%d samples:\n", total)
+	for _, sample := range samples {
+		fmt.Printf("%5.1f%% (%d)\n", 100*float64(sample.Count)/float64(total), sample.Count)
+		for _, loc := range sample.Stack {
+			fmt.Printf("\t%s\n", formatLocation(loc))
+		}
+	}
+}
+
+func schedHistory(t *Term, ctx callContext, argstr string) error {
+	switch strings.TrimSpace(argstr) {
+	case "":
+		events, err := t.client.SchedHistory()
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			fmt.Println("No goroutines scheduled")
+			return nil
+		}
+		for _, ev := range events {
+			fmt.Printf("Goroutine %d on thread %d\n", ev.GoroutineID, ev.ThreadID)
+		}
+		return nil
+	case "on":
+		return t.client.SetSchedHistory(true)
+	case "off":
+		return t.client.SetSchedHistory(false)
+	default:
+		return fmt.Errorf("wrong arguments for sched-history")
+	}
+}
+
+func displayCmd(t *Term, ctx callContext, argstr string) error {
+	v := strings.SplitN(strings.TrimSpace(argstr), " ", 2)
+	state, err := t.client.GetState()
+	if err != nil {
+		return err
+	}
+	exprs := make([]string, 0, len(state.WatchExpressions))
+	for _, we := range state.WatchExpressions {
+		exprs = append(exprs, we.Expr)
+	}
+	switch v[0] {
+	case "":
+		if len(state.WatchExpressions) == 0 {
+			fmt.Println("Display list is empty")
+			return nil
+		}
+		printWatchExpressions(state.WatchExpressions)
+		return nil
+	case "-a":
+		if len(v) != 2 {
+			return errors.New("not enough arguments to -a")
+		}
+		expr := strings.TrimSpace(v[1])
+		for _, we := range state.WatchExpressions {
+			if we.Expr == expr {
+				return fmt.Errorf("%s is already displayed as %d", expr, we.ID)
+			}
+		}
+		ids, err := t.client.SetWatchExpressions(append(exprs, expr))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Display %d added\n", ids[len(ids)-1])
+		return nil
+	case "-d":
+		if len(v) != 2 {
+			return errors.New("not enough arguments to -d")
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(v[1]))
+		if err != nil {
+			return fmt.Errorf("invalid display id %q", v[1])
+		}
+		for i, we := range state.WatchExpressions {
+			if we.ID == id {
+				_, err := t.client.SetWatchExpressions(append(exprs[:i], exprs[i+1:]...))
+				return err
+			}
+		}
+		return fmt.Errorf("no display expression with id %d", id)
+	default:
+		return fmt.Errorf("wrong arguments for display")
+	}
+}
+
+func traverseCmd(t *Term, ctx callContext, argstr string) error {
+	maxNodes := 0
+	var fields []string
+	args := strings.TrimSpace(argstr)
+	for {
+		v := strings.SplitN(args, " ", 2)
+		if v[0] != "-n" && v[0] != "-f" {
+			break
+		}
+		if len(v) < 2 {
+			return fmt.Errorf("not enough arguments to %s", v[0])
+		}
+		opt := v[0]
+		v = strings.SplitN(strings.TrimSpace(v[1]), " ", 2)
+		if opt == "-f" {
+			fields = strings.Split(v[0], ",")
+		} else {
+			n, err := strconv.Atoi(v[0])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of nodes %q", v[0])
+			}
+			maxNodes = n
+		}
+		args = ""
+		if len(v) == 2 {
+			args = strings.TrimSpace(v[1])
+		}
+	}
+	if args == "" {
+		return errors.New("not enough arguments")
+	}
+	nodes, truncated, err := t.client.Traverse(ctx.Scope, args, fields, maxNodes, &ShortLoadConfig)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		fmt.Printf("%s%s %#x %s\n", args, node.Path, node.Addr, node.Value.SinglelineString())
+		for _, edge := range node.Edges {
+			if edge.Cycle {
+				fmt.Printf("\t.%s -> %#x (cycle)\n", edge.Field, edge.Addr)
+			}
+		}
+	}
+	if truncated {
+		fmt.Println("(more nodes not visited, use -n to visit more)")
+	}
+	return nil
+}
+
+func exportCmd(t *Term, ctx callContext, argstr string) error {
+	asJSON, depth, outFile := false, 0, ""
+	args := strings.TrimSpace(argstr)
+	for {
+		v := strings.SplitN(args, " ", 2)
+		switch v[0] {
+		case "-json":
+			asJSON = true
+		case "-depth", "-o":
+			if len(v) < 2 {
+				return fmt.Errorf("not enough arguments to %s", v[0])
+			}
+			opt := v[0]
+			v = strings.SplitN(strings.TrimSpace(v[1]), " ", 2)
+			if opt == "-o" {
+				outFile = v[0]
+				break
+			}
+			n, err := strconv.Atoi(v[0])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid depth %q", v[0])
+			}
+			depth = n
+		default:
+			if args == "" {
+				return errors.New("not enough arguments")
+			}
+			text, err := t.client.ExportVariable(ctx.Scope, args, asJSON, depth)
+			if err != nil {
+				return err
+			}
+			if outFile != "" {
+				return ioutil.WriteFile(outFile, []byte(text+"\n"), 0644)
+			}
+			fmt.Println(text)
+			return nil
+		}
+		args = ""
+		if len(v) == 2 {
+			args = strings.TrimSpace(v[1])
+		}
+	}
+}
+
+func diffCmd(t *Term, ctx callContext, argstr string) error {
+	save, depth := false, 0
+	args := strings.TrimSpace(argstr)
+	for {
+		v := strings.SplitN(args, " ", 2)
+		if v[0] == "-save" {
+			save = true
+		} else if v[0] == "-depth" {
+			if len(v) < 2 {
+				return errors.New("not enough arguments to -depth")
+			}
+			v = strings.SplitN(strings.TrimSpace(v[1]), " ", 2)
+			n, err := strconv.Atoi(v[0])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid depth %q", v[0])
+			}
+			depth = n
+		} else {
+			break
+		}
+		args = ""
+		if len(v) == 2 {
+			args = strings.TrimSpace(v[1])
+		}
+	}
+	exprs, err := splitDiffExprs(args)
+	if err != nil {
+		return err
+	}
+	if save {
+		if len(exprs) != 1 {
+			return errors.New("-save requires a single expression")
+		}
+		return t.client.SaveVariableSnapshot(ctx.Scope, exprs[0], depth)
+	}
+	expr2 := ""
+	if len(exprs) == 2 {
+		expr2 = exprs[1]
+	}
+	diffs, err := t.client.DiffVariables(ctx.Scope, exprs[0], expr2, depth)
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		fmt.Println("No differences")
+		return nil
+	}
+	for _, diff := range diffs {
+		fmt.Printf("%s%s: %s != %s\n", exprs[0], diff.Path, diffValueString(diff.A), diffValueString(diff.B))
+	}
+	return nil
+}
+
+// splitDiffExprs splits the comma separated list of expressions of the
+// diff command, commas nested inside parentheses, brackets, braces or
+// literals of the expressions are allowed.
+func splitDiffExprs(args string) ([]string, error) {
+	if strings.TrimSpace(args) == "" {
+		return nil, errors.New("not enough arguments")
+	}
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(args))
+	var s scanner.Scanner
+	s.Init(file, []byte(args), func(token.Position, string) {}, 0)
+
+	var r []string
+	depth, start := 0, 0
+	for {
+		pos, tok, _ := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		switch tok {
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			depth--
+		case token.COMMA:
+			if depth == 0 {
+				off := file.Offset(pos)
+				r = append(r, strings.TrimSpace(args[start:off]))
+				start = off + 1
+			}
+		}
+	}
+	r = append(r, strings.TrimSpace(args[start:]))
+	if len(r) > 2 {
+		return nil, fmt.Errorf("wrong arguments for diff: %q", args)
+	}
+	for _, expr := range r {
+		if _, err := proc.ParseExpr(expr); err != nil {
+			return nil, fmt.Errorf("wrong arguments for diff: %q", args)
+		}
+	}
+	return r, nil
+}
+
+func diffValueString(v *api.Variable) string {
+	if v == nil {
+		return "<missing>"
+	}
+	return v.SinglelineString()
+}
+
+func deadlockCmd(t *Term, ctx callContext, argstr string) error {
+	switch strings.TrimSpace(argstr) {
+	case "":
+		dl, err := t.client.DetectDeadlock()
+		if err != nil {
+			return err
+		}
+		if dl == nil {
+			fmt.Println("No deadlock detected")
+			return nil
+		}
+		printDeadlock(dl)
+		return nil
+	case "on":
+		return t.client.SetDeadlockDetection(true)
+	case "off":
+		return t.client.SetDeadlockDetection(false)
+	default:
+		return fmt.Errorf("wrong arguments for deadlock")
+	}
+}
+
+func handleCmd(t *Term, ctx callContext, argstr string) error {
+	args := strings.Fields(argstr)
+	if len(args) != 2 {
+		return fmt.Errorf("wrong number of arguments")
+	}
+	name := strings.ToUpper(args[0])
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := signalNumbers[name]
+	if !ok {
+		var err error
+		sig, err = strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("unknown signal %q", args[0])
+		}
+	}
+	return t.client.SetSignalPolicy(sig, args[1])
+}
+
+func resumeModeCmd(t *Term, ctx callContext, argstr string) error {
+	args := strings.Fields(argstr)
+	if len(args) != 1 {
+		return errors.New("wrong number of arguments")
+	}
+	return t.client.SetResumeMode(args[0])
+}
+
+func skipCmd(t *Term, ctx callContext, argstr string) error {
+	return editStepFilterList(argstr, "step filter", t.client.GetStepFilters, t.client.SetStepFilters)
+}
+
+func stepRuntimeCmd(t *Term, ctx callContext, argstr string) error {
+	switch strings.TrimSpace(argstr) {
+	case "on":
+		return t.client.SetStepIntoRuntime(true)
+	case "off":
+		return t.client.SetStepIntoRuntime(false)
+	default:
+		return errors.New("wrong argument, expected on or off")
+	}
+}
+
+func blackboxCmd(t *Term, ctx callContext, argstr string) error {
+	return editStepFilterList(argstr, "blackboxed function", t.client.GetBlackbox, t.client.SetBlackbox)
+}
+
+// editStepFilterList implements the skip and blackbox commands: without
+// arguments it prints the list returned by get, otherwise it adds or
+// removes an entry, or clears the list.
+func editStepFilterList(argstr, what string, get func() ([]string, error), set func([]string) error) error {
+	list, err := get()
+	if err != nil {
+		return err
+	}
+	args := strings.Fields(argstr)
+	switch {
+	case len(args) == 0:
+		if len(list) == 0 {
+			fmt.Printf("No %ss.\n", what)
+		}
+		for _, entry := range list {
+			fmt.Println(entry)
+		}
+		return nil
+	case args[0] == "-clear":
+		list = nil
+	case args[0] == "-remove":
+		if len(args) != 2 {
+			return errors.New("wrong number of arguments")
+		}
+		found := false
+		for i := range list {
+			if list[i] == args[1] {
+				list = append(list[:i], list[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no %s %q", what, args[1])
+		}
+	default:
+		if len(args) != 1 {
+			return errors.New("wrong number of arguments")
+		}
+		list = append(list, args[0])
+	}
+	return set(list)
+}
+
+func toggleCmd(t *Term, ctx callContext, argstr string) error {
+	if argstr == "" {
+		return fmt.Errorf("not enough arguments")
+	}
+	bp, err := getBreakpointByIDOrName(t, argstr)
+	if err != nil {
+		return err
+	}
+	bp.Disabled = !bp.Disabled
+	if err := t.client.AmendBreakpoint(bp); err != nil {
+		return err
+	}
+	state := "enabled"
+	if bp.Disabled {
+		state = "disabled"
+	}
+	fmt.Printf("%s %s\n", formatBreakpointName(bp, true), state)
+	return nil
+}
+
+func groupCmd(t *Term, ctx callContext, argstr string) error {
+	args := strings.SplitN(argstr, " ", 2)
+
+	if len(args) < 1 || args[0] == "" {
+		return fmt.Errorf("not enough arguments")
+	}
+
+	switch args[0] {
+	case "-disable", "-enable":
+		if len(args) < 2 {
+			return fmt.Errorf("not enough arguments")
+		}
+		return t.client.SetBreakpointGroupDisabled(strings.TrimSpace(args[1]), args[0] == "-disable")
+	}
+
+	bp, err := getBreakpointByIDOrName(t, args[0])
+	if err != nil {
+		return err
+	}
+	bp.Group = ""
+	if len(args) > 1 {
+		bp.Group = strings.TrimSpace(args[1])
+	}
+
+	return t.client.AmendBreakpoint(bp)
+}
+
 // ShortenFilePath take a full file path and attempts to shorten
 // it by replacing the current directory to './'.
 func ShortenFilePath(fullPath string) string {
@@ -1944,6 +3868,8 @@ func formatBreakpointName(bp *api.Breakpoint, upcase bool) string {
 	thing := "breakpoint"
 	if bp.Tracepoint {
 		thing = "tracepoint"
+	} else if bp.Hardware {
+		thing = "hardware breakpoint"
 	}
 	if upcase {
 		thing = strings.Title(thing)
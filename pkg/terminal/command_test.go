@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strconv"
@@ -224,7 +225,7 @@ func TestExecuteFile(t *testing.T) {
 
 func TestIssue354(t *testing.T) {
 	printStack([]api.Stackframe{}, "", false)
-	printStack([]api.Stackframe{{api.Location{PC: 0, File: "irrelevant.go", Line: 10, Function: nil}, nil, nil, 0, 0, nil, ""}}, "", false)
+	printStack([]api.Stackframe{{api.Location{PC: 0, File: "irrelevant.go", Line: 10, Function: nil}, nil, nil, 0, 0, nil, false, ""}}, "", false)
 }
 
 func TestIssue411(t *testing.T) {
@@ -827,3 +828,34 @@ func TestOptimizationCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitDiffExprs(t *testing.T) {
+	testcases := []struct {
+		in  string
+		out []string
+	}{
+		{"a, b", []string{"a", "b"}},
+		{"a", []string{"a"}},
+		{"$rax, $rbx", []string{"$rax", "$rbx"}},
+		{"f(a, b), m[\"x,y\"]", []string{"f(a, b)", "m[\"x,y\"]"}},
+		{"a, b, c", nil},
+		{"a +, b", nil},
+		{"", nil},
+	}
+	for _, tc := range testcases {
+		out, err := splitDiffExprs(tc.in)
+		if tc.out == nil {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", tc.in, out)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(out, tc.out) {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.out, out)
+		}
+	}
+}
@@ -355,7 +355,7 @@ func (t *Term) handleExit() (int, error) {
 // loadConfig returns an api.LoadConfig with the parameterss specified in
 // the configuration file.
 func (t *Term) loadConfig() api.LoadConfig {
-	r := api.LoadConfig{true, 1, 64, 64, -1}
+	r := api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
 
 	if t.conf != nil && t.conf.MaxStringLen != nil {
 		r.MaxStringLen = *t.conf.MaxStringLen
@@ -363,6 +363,12 @@ func (t *Term) loadConfig() api.LoadConfig {
 	if t.conf != nil && t.conf.MaxArrayValues != nil {
 		r.MaxArrayValues = *t.conf.MaxArrayValues
 	}
+	if t.conf != nil && t.conf.MaxVariableRecurse != nil {
+		r.MaxVariableRecurse = *t.conf.MaxVariableRecurse
+	}
+	if t.conf != nil && t.conf.MaxMapEntries != nil {
+		r.MaxMapEntries = *t.conf.MaxMapEntries
+	}
 
 	return r
 }
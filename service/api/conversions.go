@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"fmt"
 	"go/constant"
 	"go/printer"
 	"go/token"
@@ -17,6 +18,8 @@ import (
 func ConvertBreakpoint(bp *proc.Breakpoint) *Breakpoint {
 	b := &Breakpoint{
 		Name:          bp.Name,
+		Group:         bp.Group,
+		Disabled:      bp.Disabled,
 		ID:            bp.ID,
 		FunctionName:  bp.FunctionName,
 		File:          bp.File,
@@ -24,11 +27,19 @@ func ConvertBreakpoint(bp *proc.Breakpoint) *Breakpoint {
 		Addr:          bp.Addr,
 		Tracepoint:    bp.Tracepoint,
 		Stacktrace:    bp.Stacktrace,
+		RecordStack:   bp.RecordStack,
 		Goroutine:     bp.Goroutine,
 		Variables:     bp.Variables,
+		LogMessage:    bp.LogMessage,
+		SuspendThread: bp.Suspend == proc.SuspendThread,
+		Hardware:      bp.WatchType.Execute(),
+		CgoCatch:      bp.CgoCatch != 0,
 		LoadArgs:      LoadConfigFromProc(bp.LoadArgs),
 		LoadLocals:    LoadConfigFromProc(bp.LoadLocals),
 		TotalHitCount: bp.TotalHitCount,
+		MaxHits:       bp.MaxHits,
+		ThreadID:      bp.ThreadID,
+		Actions:       ConvertBreakpointActions(bp.Actions),
 	}
 
 	b.HitCount = map[string]uint64{}
@@ -40,6 +51,31 @@ func ConvertBreakpoint(bp *proc.Breakpoint) *Breakpoint {
 	printer.Fprint(&buf, token.NewFileSet(), bp.Cond)
 	b.Cond = buf.String()
 
+	if bp.HitCond != nil {
+		b.HitCond = bp.HitCond.String()
+	}
+
+	return b
+}
+
+// ConvertInternalBreakpoint converts an internal proc.Breakpoint to an
+// api.Breakpoint, describing its kind and origin.
+func ConvertInternalBreakpoint(bp *proc.Breakpoint) *Breakpoint {
+	b := ConvertBreakpoint(bp)
+	switch bp.Kind &^ proc.UserBreakpoint {
+	case proc.NextBreakpoint:
+		b.InternalKind = "next"
+	case proc.NextDeferBreakpoint:
+		b.InternalKind = "next-defer"
+	case proc.StepBreakpoint:
+		b.InternalKind = "step"
+	case proc.FreezeBreakpoint:
+		b.InternalKind = "freeze"
+	}
+	b.Origin = bp.Origin
+	var buf bytes.Buffer
+	printer.Fprint(&buf, token.NewFileSet(), bp.InternalCond())
+	b.Cond = buf.String()
 	return b
 }
 
@@ -80,6 +116,45 @@ func ConvertThread(th proc.Thread) *Thread {
 		Function:    function,
 		GoroutineID: gid,
 		Breakpoint:  bp,
+		Signal:      th.Common().Signal,
+		Syscall:     ConvertSyscall(th.Common().Syscall),
+		Cgo:         ConvertCgoEvent(th.Common().Cgo),
+	}
+}
+
+// ConvertCgoEvent converts from a proc.CgoEvent to an api.CgoEvent.
+func ConvertCgoEvent(ev *proc.CgoEvent) *CgoEvent {
+	if ev == nil {
+		return nil
+	}
+	return &CgoEvent{
+		Direction: ev.Direction.String(),
+		Fn:        ev.Fn,
+		FnName:    ev.FnName,
+	}
+}
+
+// ConvertStackRecord converts from a proc.StackRecord to an api.StackRecord.
+func ConvertStackRecord(rec proc.StackRecord) StackRecord {
+	r := StackRecord{GoroutineID: rec.GoroutineID, ThreadID: rec.ThreadID, Stack: make([]Stackframe, len(rec.Stack))}
+	for i := range rec.Stack {
+		r.Stack[i].Location = ConvertLocation(rec.Stack[i])
+	}
+	return r
+}
+
+// ConvertSyscall converts from a proc.SyscallEvent to an api.Syscall.
+func ConvertSyscall(ev *proc.SyscallEvent) *Syscall {
+	if ev == nil {
+		return nil
+	}
+	return &Syscall{
+		Num:  ev.Num,
+		Name: ev.Name,
+		Args: append([]uint64(nil), ev.Args[:]...),
+		Exit: ev.Exit,
+		Ret:  ev.Ret,
+		Path: ev.Path,
 	}
 }
 
@@ -124,6 +199,7 @@ func ConvertVar(v *proc.Variable) *Variable {
 
 		LocationExpr: v.LocationExpr,
 		DeclLine:     v.DeclLine,
+		Handle:       v.Handle,
 	}
 
 	r.Type = prettyTypeName(v.DwarfType)
@@ -139,10 +215,13 @@ func ConvertVar(v *proc.Variable) *Variable {
 			r.Value = convertFloatValue(v, 32)
 		case reflect.Float64:
 			r.Value = convertFloatValue(v, 64)
-		case reflect.String, reflect.Func:
+		case reflect.String, reflect.Func, reflect.Struct:
 			r.Value = constant.StringVal(v.Value)
 		default:
-			r.Value = v.ConstDescr()
+			r.Value = v.IntString()
+			if r.Value == "" {
+				r.Value = v.ConstDescr()
+			}
 			if r.Value == "" {
 				r.Value = v.Value.String()
 			}
@@ -230,14 +309,28 @@ func ConvertGoroutine(g *proc.G) *Goroutine {
 	if th != nil {
 		tid = th.ThreadID()
 	}
-	return &Goroutine{
+	r := &Goroutine{
 		ID:             g.ID,
+		Status:         g.StatusName(),
 		CurrentLoc:     ConvertLocation(g.CurrentLoc),
 		UserCurrentLoc: ConvertLocation(g.UserCurrent()),
 		GoStatementLoc: ConvertLocation(g.Go()),
 		StartLoc:       ConvertLocation(g.StartLoc()),
 		ThreadID:       tid,
+		Labels:         g.Labels(),
+		System:         g.System(),
 	}
+	if g.Waiting() {
+		r.WaitReason = g.WaitReason
+		r.WaitSince = g.WaitSince
+		if g.WaitReason == "select" {
+			cases, _ := g.SelectCases()
+			for _, cas := range cases {
+				r.SelectCases = append(r.SelectCases, SelectCase{Chan: cas.Chan, Send: cas.Send})
+			}
+		}
+	}
+	return r
 }
 
 // ConvertLocation converts from proc.Location to api.Location.
@@ -266,16 +359,76 @@ func ConvertAsmInstruction(inst proc.AsmInstruction, text string) AsmInstruction
 	}
 }
 
+var breakpointActionKinds = map[proc.BreakpointActionKind]string{
+	proc.EvalAction:     "eval",
+	proc.StackAction:    "stack",
+	proc.ContinueAction: "continue",
+	proc.EnableAction:   "enable",
+}
+
+// ConvertBreakpointActions converts a slice of proc.BreakpointAction to
+// api.BreakpointAction.
+func ConvertBreakpointActions(actions []proc.BreakpointAction) []BreakpointAction {
+	if len(actions) == 0 {
+		return nil
+	}
+	r := make([]BreakpointAction, len(actions))
+	for i, action := range actions {
+		r[i] = ConvertBreakpointAction(action)
+	}
+	return r
+}
+
+// ConvertBreakpointAction converts a proc.BreakpointAction to
+// api.BreakpointAction.
+func ConvertBreakpointAction(action proc.BreakpointAction) BreakpointAction {
+	return BreakpointAction{
+		Kind:         breakpointActionKinds[action.Kind],
+		Expr:         action.Expr,
+		Depth:        action.Depth,
+		BreakpointID: action.BreakpointID,
+	}
+}
+
+// BreakpointActionsToProc converts a slice of api.BreakpointAction to
+// proc.BreakpointAction.
+func BreakpointActionsToProc(actions []BreakpointAction) ([]proc.BreakpointAction, error) {
+	if len(actions) == 0 {
+		return nil, nil
+	}
+	r := make([]proc.BreakpointAction, len(actions))
+	for i, action := range actions {
+		found := false
+		for kind, name := range breakpointActionKinds {
+			if name == action.Kind {
+				r[i].Kind = kind
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown breakpoint action %q", action.Kind)
+		}
+		r[i].Expr = action.Expr
+		r[i].Depth = action.Depth
+		r[i].BreakpointID = action.BreakpointID
+	}
+	return r, nil
+}
+
 func LoadConfigToProc(cfg *LoadConfig) *proc.LoadConfig {
 	if cfg == nil {
 		return nil
 	}
 	return &proc.LoadConfig{
-		cfg.FollowPointers,
-		cfg.MaxVariableRecurse,
-		cfg.MaxStringLen,
-		cfg.MaxArrayValues,
-		cfg.MaxStructFields,
+		FollowPointers:     cfg.FollowPointers,
+		MaxVariableRecurse: cfg.MaxVariableRecurse,
+		MaxStringLen:       cfg.MaxStringLen,
+		MaxArrayValues:     cfg.MaxArrayValues,
+		MaxStructFields:    cfg.MaxStructFields,
+		MaxMapEntries:      cfg.MaxMapEntries,
+		IntFormat:          cfg.IntFormat,
+		RawStrings:         cfg.RawStrings,
 	}
 }
 
@@ -284,11 +437,14 @@ func LoadConfigFromProc(cfg *proc.LoadConfig) *LoadConfig {
 		return nil
 	}
 	return &LoadConfig{
-		cfg.FollowPointers,
-		cfg.MaxVariableRecurse,
-		cfg.MaxStringLen,
-		cfg.MaxArrayValues,
-		cfg.MaxStructFields,
+		FollowPointers:     cfg.FollowPointers,
+		MaxVariableRecurse: cfg.MaxVariableRecurse,
+		MaxStringLen:       cfg.MaxStringLen,
+		MaxArrayValues:     cfg.MaxArrayValues,
+		MaxStructFields:    cfg.MaxStructFields,
+		MaxMapEntries:      cfg.MaxMapEntries,
+		IntFormat:          cfg.IntFormat,
+		RawStrings:         cfg.RawStrings,
 	}
 }
 
@@ -303,3 +459,31 @@ func ConvertRegisters(in []proc.Register) (out []Register) {
 func ConvertCheckpoint(in proc.Checkpoint) (out Checkpoint) {
 	return Checkpoint(in)
 }
+
+// ConvertStepIntoTarget converts a proc.StepIntoTarget to an api.StepIntoTarget.
+func ConvertStepIntoTarget(in proc.StepIntoTarget) StepIntoTarget {
+	return StepIntoTarget{CallPC: in.CallPC, Function: ConvertFunction(in.Fn)}
+}
+
+// ConvertVariableDifference converts a proc.VariableDifference to an
+// api.VariableDifference.
+func ConvertVariableDifference(in proc.VariableDifference) VariableDifference {
+	r := VariableDifference{Path: in.Path}
+	if in.A != nil {
+		r.A = ConvertVar(in.A)
+	}
+	if in.B != nil {
+		r.B = ConvertVar(in.B)
+	}
+	return r
+}
+
+// ConvertTraversalNode converts a proc.TraversalNode to an
+// api.TraversalNode.
+func ConvertTraversalNode(in proc.TraversalNode) TraversalNode {
+	r := TraversalNode{Addr: in.Addr, Path: in.Path, Value: *ConvertVar(in.Value)}
+	for _, edge := range in.Edges {
+		r.Edges = append(r.Edges, TraversalEdge(edge))
+	}
+	return r
+}
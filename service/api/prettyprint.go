@@ -76,6 +76,13 @@ func (v *Variable) writeTo(buf io.Writer, top, newlines, includeType bool, inden
 			}
 		}
 	case reflect.Struct:
+		if v.Flags&VariableFormatted != 0 {
+			if includeType {
+				fmt.Fprintf(buf, "%s ", v.Type)
+			}
+			fmt.Fprint(buf, v.Value)
+			return
+		}
 		v.writeStructTo(buf, newlines, includeType, indent)
 	case reflect.Interface:
 		if v.Addr == 0 {
@@ -118,6 +125,11 @@ func (v *Variable) writeTo(buf io.Writer, top, newlines, includeType bool, inden
 			fmt.Fprint(buf, "nil")
 		} else {
 			fmt.Fprintf(buf, "%s", v.Value)
+			if len(v.Children) > 0 {
+				// variables captured by the closure
+				fmt.Fprint(buf, " ")
+				v.writeStructTo(buf, newlines, false, indent)
+			}
 		}
 	case reflect.Complex64, reflect.Complex128:
 		fmt.Fprintf(buf, "(%s + %si)", v.Children[0].Value, v.Children[1].Value)
@@ -135,6 +147,10 @@ func (v *Variable) writeStringTo(buf io.Writer) {
 	if len(s) != int(v.Len) {
 		s = fmt.Sprintf("%s...+%d more", s, int(v.Len)-len(s))
 	}
+	if v.Flags&VariableRawString != 0 {
+		fmt.Fprint(buf, s)
+		return
+	}
 	fmt.Fprintf(buf, "%q", s)
 }
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 	"unicode"
 
 	"github.com/derekparker/delve/pkg/proc"
@@ -23,6 +24,24 @@ type DebuggerState struct {
 	SelectedGoroutine *Goroutine `json:"currentGoroutine,omitempty"`
 	// List of all the process threads
 	Threads []*Thread
+	// ManualStop is true if the last continue, or step, operation was
+	// interrupted by a halt request rather than by a breakpoint or another
+	// event.
+	ManualStop bool `json:"manualStop,omitempty"`
+	// Steps is the number of steps completed by a Next, Step or
+	// StepInstruction command with a Count, it is less than Count if the
+	// command was interrupted.
+	Steps int `json:"steps,omitempty"`
+	// StepLocations is the location of the current thread after each of the
+	// Steps completed steps.
+	StepLocations []Location `json:"stepLocations,omitempty"`
+	// StepGoroutineID is the ID of the goroutine that was selected when a
+	// step command started, it is only set if the command stopped on a
+	// different goroutine.
+	StepGoroutineID int `json:"stepGoroutineID,omitempty"`
+	// SelectedFrame is the frame of the selected goroutine selected with the
+	// SwitchFrame command, it is reset to 0 when the target is resumed.
+	SelectedFrame int `json:"selectedFrame,omitempty"`
 	// NextInProgress indicates that a next or step operation was interrupted by another breakpoint
 	// or a manual stop and is waiting to complete.
 	// While NextInProgress is set further requests for next or step may be rejected.
@@ -33,6 +52,25 @@ type DebuggerState struct {
 	ExitStatus int  `json:"exitStatus"`
 	// When contains a description of the current position in a recording
 	When string
+	// Event is the number of the current event in a recording, it can be
+	// passed to SeekEvent to return to the current position.
+	Event int64 `json:"event,omitempty"`
+	// WatchExpressions contains the values of the watch expressions,
+	// evaluated in the scope of the selected goroutine.
+	WatchExpressions []WatchExpression `json:"watchExpressions,omitempty"`
+	// Deadlock is set if the target stopped because all its goroutines are
+	// blocked, either because the runtime detected it or because deadlock
+	// detection was enabled with SetDeadlockDetection.
+	Deadlock *Deadlock `json:"deadlock,omitempty"`
+	// Samples are the stacks recorded by the Sample command, sorted by
+	// decreasing number of occurrences.
+	Samples []StackSample `json:"samples,omitempty"`
+	// LogpointMessages are the messages of the logpoints hit since the
+	// previous command returned.
+	LogpointMessages []LogpointMessage `json:"logpointMessages,omitempty"`
+	// BreakpointActionResults are the results of the breakpoint actions
+	// executed since the previous command returned.
+	BreakpointActionResults []BreakpointActionResult `json:"breakpointActionResults,omitempty"`
 	// Filled by RPCClient.Continue, indicates an error
 	Err error `json:"-"`
 }
@@ -44,6 +82,12 @@ type Breakpoint struct {
 	ID int `json:"id"`
 	// User defined name of the breakpoint
 	Name string `json:"name"`
+	// Group is the name of the breakpoint group this breakpoint belongs to.
+	Group string `json:"group,omitempty"`
+	// Disabled is true if the breakpoint is disabled. A disabled breakpoint
+	// keeps its condition and hit counts but does not stop the target, it
+	// can be disabled and enabled again with AmendBreakpoint.
+	Disabled bool `json:"disabled,omitempty"`
 	// Addr is the address of the breakpoint.
 	Addr uint64 `json:"addr"`
 	// File is the source file for the breakpoint.
@@ -56,6 +100,15 @@ type Breakpoint struct {
 
 	// Breakpoint condition
 	Cond string
+	// Breakpoint hit count condition.
+	// Supported hit count conditions are "NUMBER" and "OP NUMBER".
+	HitCond string `json:"hitCond,omitempty"`
+	// MaxHits, if not zero, disables the breakpoint once TotalHitCount
+	// reaches it.
+	MaxHits uint64 `json:"maxHits,omitempty"`
+	// ThreadID, if not zero, restricts the breakpoint to the thread with
+	// this ID.
+	ThreadID int `json:"threadID,omitempty"`
 
 	// tracepoint flag
 	Tracepoint bool `json:"continue"`
@@ -63,8 +116,30 @@ type Breakpoint struct {
 	Goroutine bool `json:"goroutine"`
 	// number of stack frames to retrieve
 	Stacktrace int `json:"stacktrace"`
+	// RecordStack, if not zero, records a stacktrace of RecordStack frames
+	// every time the breakpoint is hit, without stopping. The recorded
+	// stacks are retrieved with TakeStackRecords.
+	RecordStack int `json:"recordStack,omitempty"`
 	// expressions to evaluate
 	Variables []string `json:"variables,omitempty"`
+	// Hardware: if true the breakpoint is implemented using a debug register
+	// instead of a breakpoint instruction, code containing it does not need
+	// to be patched. Only supported by the native backend on linux.
+	Hardware bool `json:"hardware,omitempty"`
+	// CgoCatch is true if this breakpoint is one of the breakpoints set by
+	// a cgo catchpoint.
+	CgoCatch bool `json:"cgoCatch,omitempty"`
+	// SuspendThread: if true only the thread that hits the breakpoint is
+	// stopped, all other threads keep running. Only supported by the native
+	// backend on linux.
+	SuspendThread bool `json:"suspendThread,omitempty"`
+	// LogMessage: if not empty the breakpoint is a logpoint, LogMessage is
+	// printed every time the breakpoint is hit and execution continues
+	// without stopping. Expressions enclosed in curly braces are evaluated.
+	LogMessage string `json:"logMessage,omitempty"`
+	// Actions are executed, in order, every time the breakpoint is
+	// triggered, their results are printed by the server.
+	Actions []BreakpointAction `json:"actions,omitempty"`
 	// LoadArgs requests loading function arguments when the breakpoint is hit
 	LoadArgs *LoadConfig
 	// LoadLocals requests loading function locals when the breakpoint is hit
@@ -73,6 +148,44 @@ type Breakpoint struct {
 	HitCount map[string]uint64 `json:"hitCount"`
 	// number of times a breakpoint has been reached
 	TotalHitCount uint64 `json:"totalHitCount"`
+
+	// InternalKind is the kind of internal breakpoint ("next", "next-defer",
+	// "step" or "freeze") set at this address, only returned by
+	// ListInternalBreakpoints.
+	InternalKind string `json:"internalKind,omitempty"`
+	// Origin is the operation that set the internal breakpoint, only
+	// returned by ListInternalBreakpoints.
+	Origin string `json:"origin,omitempty"`
+}
+
+// BreakpointAction is an action executed every time a breakpoint is
+// triggered.
+type BreakpointAction struct {
+	// Kind is one of "eval", "stack", "continue" or "enable".
+	Kind string `json:"kind"`
+	// Expr is the expression evaluated by "eval" actions.
+	Expr string `json:"expr,omitempty"`
+	// Depth is the depth of the stacktrace collected by "stack" actions.
+	Depth int `json:"depth,omitempty"`
+	// BreakpointID is the ID of the breakpoint enabled by "enable" actions.
+	BreakpointID int `json:"breakpointID,omitempty"`
+}
+
+// BreakpointActionResult is the result of a breakpoint action executed
+// when a breakpoint was hit.
+type BreakpointActionResult struct {
+	// BreakpointID is the ID of the breakpoint the action belongs to.
+	BreakpointID int `json:"breakpointID"`
+	// GoroutineID is the ID of the goroutine that hit the breakpoint, or -1
+	// if it could not be determined.
+	GoroutineID int              `json:"goroutineID"`
+	Action      BreakpointAction `json:"action"`
+	// Variable is the result of "eval" actions.
+	Variable *Variable `json:"variable,omitempty"`
+	// Stack is the result of "stack" actions.
+	Stack []Stackframe `json:"stack,omitempty"`
+	// Err is the error that occurred executing the action, if any.
+	Err string `json:"err,omitempty"`
 }
 
 func ValidBreakpointName(name string) error {
@@ -112,6 +225,40 @@ type Thread struct {
 
 	// ReturnValues contains the return values of the function we just stepped out of
 	ReturnValues []Variable
+
+	// Signal is the signal that stopped this thread, if any.
+	Signal int `json:"signal,omitempty"`
+	// Syscall is the syscall that stopped this thread, if it stopped on a
+	// syscall catchpoint.
+	Syscall *Syscall `json:"syscall,omitempty"`
+	// Cgo describes the crossing of the Go/C boundary that stopped this
+	// thread, if it stopped on a cgo catchpoint.
+	Cgo *CgoEvent `json:"cgo,omitempty"`
+}
+
+// CgoEvent describes a crossing of the boundary between Go and C code.
+type CgoEvent struct {
+	// Direction is one of "call" (Go calling C), "return" (C returning to
+	// Go) or "callback" (C calling Go).
+	Direction string `json:"direction"`
+	// Fn is the address of the C function being called, only set if
+	// Direction is "call".
+	Fn     uint64 `json:"fn,omitempty"`
+	FnName string `json:"fnName,omitempty"`
+}
+
+// Syscall describes a syscall entry or exit.
+type Syscall struct {
+	Num  int      `json:"num"`
+	Name string   `json:"name,omitempty"`
+	Args []uint64 `json:"args"`
+	// Exit is true if the thread is exiting the syscall, false if it is
+	// entering it.
+	Exit bool `json:"exit"`
+	// Ret is the return value of the syscall, valid only if Exit is true.
+	Ret int64 `json:"ret"`
+	// Path is the path argument of the syscall, for syscalls taking one.
+	Path string `json:"path,omitempty"`
 }
 
 type Location struct {
@@ -131,6 +278,10 @@ type Stackframe struct {
 
 	Defers []Defer
 
+	// SystemStack is true if the frame is on a system stack, the g0 stack
+	// of a thread or the stack of a cgo call.
+	SystemStack bool `json:"systemStack,omitempty"`
+
 	Err string
 }
 
@@ -190,13 +341,29 @@ const (
 	VariableShadowed = VariableFlags(proc.VariableShadowed)
 
 	// VariableConstant means this variable is a constant value
-	VariableConstant
+	VariableConstant = VariableFlags(proc.VariableConstant)
 
 	// VariableArgument means this variable is a function argument
-	VariableArgument
+	VariableArgument = VariableFlags(proc.VariableArgument)
 
 	// VariableReturnArgument means this variable is a function return value
-	VariableReturnArgument
+	VariableReturnArgument = VariableFlags(proc.VariableReturnArgument)
+
+	// VariableTruncated means that the value of this variable was only
+	// partially loaded because of the limits of the LoadConfig used.
+	VariableTruncated = VariableFlags(proc.VariableTruncated)
+
+	// VariableFormatted means that Value contains the summary of a struct
+	// returned by the formatter registered for its type.
+	VariableFormatted = VariableFlags(proc.VariableFormatted)
+
+	// VariableUninitialized means that this local variable is declared on
+	// the current line and its value could be uninitialized.
+	VariableUninitialized = VariableFlags(proc.VariableUninitialized)
+
+	// VariableRawString means that this string should be printed as is,
+	// without quoting it.
+	VariableRawString = VariableFlags(proc.VariableRawString)
 )
 
 // Variable describes a variable.
@@ -218,6 +385,7 @@ type Variable struct {
 
 	//Strings have their length capped at proc.maxArrayValues, use Len for the real length of a string
 	//Function variables will store the name of the function in this field
+	//Structs formatted by a pretty-printer will store their summary in this field
 	Value string `json:"value"`
 
 	// Number of elements in an array or a slice, number of keys for a map, number of struct members for a struct, length of strings
@@ -245,6 +413,23 @@ type Variable struct {
 	LocationExpr string
 	// DeclLine is the line number of this variable's declaration
 	DeclLine int64
+
+	// Handle is set if some of the children of this variable were not
+	// loaded, they can be loaded by passing it to ExpandVariable. Handles
+	// are invalidated when the target is resumed.
+	Handle int `json:"handle,omitempty"`
+}
+
+// VariableInstance is one of the declarations of a local variable or
+// argument.
+type VariableInstance struct {
+	Variable
+	// Ranges contains the address ranges of the lexical block, or function,
+	// the variable is declared in.
+	Ranges [][2]uint64
+	// InScope is true for the declaration the name refers to at the current
+	// PC of the scope.
+	InScope bool
 }
 
 // LoadConfig describes how to load values from target's memory
@@ -259,6 +444,15 @@ type LoadConfig struct {
 	MaxArrayValues int
 	// MaxStructFields is the maximum number of fields read from a struct, -1 will read all fields.
 	MaxStructFields int
+	// MaxMapEntries is the maximum number of entries read from a map, if it
+	// is zero MaxArrayValues is used instead.
+	MaxMapEntries int
+	// IntFormat is the verb used to format the values of integers: 'x' for
+	// hexadecimal, 'o' for octal, 'b' for binary and 'c' for characters,
+	// zero or 'd' for decimal.
+	IntFormat byte
+	// RawStrings requests strings to be printed without quoting them.
+	RawStrings bool
 }
 
 // Goroutine represents the information relevant to Delve from the runtime's
@@ -266,6 +460,9 @@ type LoadConfig struct {
 type Goroutine struct {
 	// ID is a unique identifier for the goroutine.
 	ID int `json:"id"`
+	// Status is the status of the goroutine, one of idle, runnable,
+	// running, syscall, waiting, dead, copystack and unknown.
+	Status string `json:"status"`
 	// Current location of the goroutine
 	CurrentLoc Location `json:"currentLoc"`
 	// Current location of the goroutine, excluding calls inside runtime
@@ -276,8 +473,211 @@ type Goroutine struct {
 	StartLoc Location `json:"startLoc"`
 	// ID of the associated thread for running goroutines
 	ThreadID int `json:"threadID"`
+	// Labels are the pprof labels of the goroutine, set with
+	// runtime/pprof.Do or runtime/pprof.SetGoroutineLabels.
+	Labels map[string]string `json:"labels,omitempty"`
+	// WaitReason is the reason the goroutine is parked, empty if the
+	// goroutine is not waiting.
+	WaitReason string `json:"waitReason,omitempty"`
+	// WaitSince is the value of runtime.nanotime in the target when the
+	// goroutine was parked, zero if not known.
+	WaitSince int64 `json:"waitSince,omitempty"`
+	// WaitDuration is how long the goroutine has been parked, it is only
+	// known for goroutines of live processes and is zero otherwise.
+	WaitDuration time.Duration `json:"waitDuration,omitempty"`
+	// System is true if the goroutine was started by the runtime, like the
+	// garbage collector workers and the finalizer goroutine.
+	System bool `json:"system,omitempty"`
+	// SelectCases are the cases of the select statement the goroutine is
+	// blocked in, if any.
+	SelectCases []SelectCase `json:"selectCases,omitempty"`
+}
+
+// SelectCase is a case of a select statement, see proc.SelectCase.
+type SelectCase struct {
+	// Chan is the address of the channel of the case.
+	Chan uint64 `json:"chan"`
+	// Send is true for send cases, false for receive cases.
+	Send bool `json:"send,omitempty"`
+}
+
+// GoroutineFilter selects the goroutines returned by ListGoroutines, a
+// goroutine is returned if it satisfies every non-empty field.
+type GoroutineFilter struct {
+	// States is a list of goroutine states, one of idle, runnable, running,
+	// syscall, waiting, dead or copystack.
+	States []string `json:"states,omitempty"`
+	// Function must be a substring of the name of one of the functions in
+	// the stack of the goroutine.
+	Function string `json:"function,omitempty"`
+	// Labels are pprof labels the goroutine must have.
+	Labels map[string]string `json:"labels,omitempty"`
+	// IncludeSystem includes the goroutines started by the runtime, which
+	// are excluded by default.
+	IncludeSystem bool `json:"includeSystem,omitempty"`
+}
+
+// GoroutineStacktrace is the stacktrace of a goroutine, returned by
+// Stacktraces.
+type GoroutineStacktrace struct {
+	GoroutineID int          `json:"goroutineID"`
+	Stacktrace  []Stackframe `json:"stacktrace"`
+	// Err is the error that stopped the stacktrace, if any.
+	Err string `json:"err,omitempty"`
+}
+
+// Ancestor is an ancestor of a goroutine, see proc.Ancestor.
+type Ancestor struct {
+	ID    int          `json:"id"`
+	Stack []Stackframe `json:"stack"`
+
+	Unreadable string `json:"unreadable,omitempty"`
+}
+
+// GoroutineGroup is a group of goroutines returned by GroupGoroutines.
+type GoroutineGroup struct {
+	// Name is the function, or the wait reason, shared by all goroutines in
+	// the group.
+	Name string `json:"name"`
+	// Count is the number of goroutines in the group.
+	Count int `json:"count"`
+	// GoroutineIDs contains the IDs of some of the goroutines in the group.
+	GoroutineIDs []int `json:"goroutineIDs"`
+}
+
+// GoroutineDiff contains the goroutines created and the goroutines that
+// exited since a goroutine baseline was marked.
+type GoroutineDiff struct {
+	Created []*Goroutine `json:"created"`
+	// Exited contains the exited goroutines as they were when the baseline
+	// was marked.
+	Exited []*Goroutine `json:"exited"`
 }
 
+// Waiter is a goroutine blocked on a channel or a mutex, see proc.Waiter.
+type Waiter struct {
+	Goroutine *Goroutine `json:"goroutine"`
+	// Op is the operation the goroutine is blocked in, one of "chan
+	// receive", "chan send", "lock" and "rlock".
+	Op string `json:"op"`
+}
+
+// MutexState describes a sync.Mutex, see proc.MutexState.
+type MutexState struct {
+	Addr     uint64 `json:"addr"`
+	Locked   bool   `json:"locked"`
+	Woken    bool   `json:"woken,omitempty"`
+	Starving bool   `json:"starving,omitempty"`
+	// Waiters is the number of waiters recorded in the state of the mutex.
+	Waiters int `json:"waiters"`
+	// Holders contains the goroutines that could be holding the mutex, the
+	// runtime does not record which goroutine locked it.
+	Holders []*Goroutine `json:"holders"`
+}
+
+// StackSample is a stack recorded by the Sample command, see
+// proc.StackSample.
+type StackSample struct {
+	// Stack contains the call locations of the topmost frames of the stack.
+	Stack []Location `json:"stack"`
+	// Count is the number of times the stack was sampled.
+	Count int `json:"count"`
+}
+
+// LogpointMessage is the message printed by a logpoint when it was hit.
+type LogpointMessage struct {
+	// BreakpointID is the ID of the logpoint.
+	BreakpointID int `json:"breakpointID"`
+	// GoroutineID is the ID of the goroutine that hit the logpoint, or -1
+	// if it could not be determined.
+	GoroutineID int `json:"goroutineID"`
+	// Message is the log message of the logpoint with the expressions
+	// enclosed in curly braces replaced by their values.
+	Message string `json:"message"`
+}
+
+// WatchExpression is the value of a watch expression, see
+// SetWatchExpressions.
+type WatchExpression struct {
+	// ID is the identifier assigned to the expression by SetWatchExpressions.
+	ID    int      `json:"id"`
+	Expr  string   `json:"expr"`
+	Value Variable `json:"value"`
+	// Changed is true if the value is different from the value the
+	// expression had at the previous stop.
+	Changed bool `json:"changed,omitempty"`
+	// Delta is the difference between the value and the value the
+	// expression had at the previous stop, for numeric values.
+	Delta string `json:"delta,omitempty"`
+}
+
+// VariableDifference is a difference between two values, see
+// proc.VariableDifference.
+type VariableDifference struct {
+	// Path is the path of the differing values relative to the compared
+	// values, like .a[2].b, empty if the compared values themselves differ.
+	Path string `json:"path"`
+	// A and B are the differing values, one of them is nil if the element
+	// only exists in one of the compared values.
+	A *Variable `json:"a,omitempty"`
+	B *Variable `json:"b,omitempty"`
+}
+
+// TraversalNode is a node of a linked data structure, see
+// proc.TraversalNode.
+type TraversalNode struct {
+	Addr uintptr `json:"addr"`
+	// Path is the sequence of fields followed from the root to reach the
+	// node, empty for the root.
+	Path  string          `json:"path"`
+	Value Variable        `json:"value"`
+	Edges []TraversalEdge `json:"edges,omitempty"`
+}
+
+// TraversalEdge is a pointer from a node to another node, see
+// proc.TraversalEdge.
+type TraversalEdge struct {
+	Field string  `json:"field"`
+	Addr  uintptr `json:"addr"`
+	// Cycle is true if the pointer points to the node itself or to one of
+	// its ancestors.
+	Cycle bool `json:"cycle,omitempty"`
+}
+
+// SchedEvent records that a goroutine was scheduled on a thread, see
+// proc.SchedEvent.
+type SchedEvent struct {
+	GoroutineID int `json:"goroutineID"`
+	ThreadID    int `json:"threadID"`
+}
+
+// Deadlock describes a deadlock of the target, see proc.Deadlock.
+type Deadlock struct {
+	Goroutines []BlockedGoroutine `json:"goroutines"`
+}
+
+// BlockedGoroutine is a goroutine that is part of a deadlock.
+type BlockedGoroutine struct {
+	Goroutine *Goroutine `json:"goroutine"`
+	// Reason is the wait reason of the goroutine.
+	Reason string `json:"reason"`
+	// Chans contains the addresses of the channels the goroutine is waiting
+	// on.
+	Chans []uint64 `json:"chans,omitempty"`
+}
+
+const (
+	// GroupByCurrentFunc groups goroutines by the function of their
+	// topmost frame.
+	GroupByCurrentFunc = "current"
+	// GroupByUserFunc groups goroutines by the function of their topmost
+	// frame outside of the runtime.
+	GroupByUserFunc = "user"
+	// GroupByWaitReason groups goroutines by wait reason, goroutines that
+	// aren't parked are grouped by state.
+	GroupByWaitReason = "reason"
+)
+
 // DebuggerCommand is a command which changes the debugger's execution state.
 type DebuggerCommand struct {
 	// Name is the command to run.
@@ -286,13 +686,32 @@ type DebuggerCommand struct {
 	// command.
 	ThreadID int `json:"threadID,omitempty"`
 	// GoroutineID is used to specify which thread to use with the SwitchGoroutine
-	// command.
+	// and ContinueToGoroutine commands.
+	// If it is set for the step commands, like Next and Step, or for Call,
+	// the command operates on that goroutine instead of the selected one.
+	// Parked goroutines are resumed until they run.
 	GoroutineID int `json:"goroutineID,omitempty"`
+	// Frame is the frame selected by the SwitchFrame command.
+	Frame int `json:"frame,omitempty"`
 	// When ReturnInfoLoadConfig is not nil it will be used to load the value
 	// of any return variables.
 	ReturnInfoLoadConfig *LoadConfig
-	// Expr is the expression argument for a Call command
+	// Expr is the expression argument for a Call command or the location
+	// argument for the ContinueUntil and Jump commands.
 	Expr string `json:"expr,omitempty"`
+	// CallPC is the address of the CALL instruction to step into for the
+	// StepInto command.
+	CallPC uint64 `json:"callPC,omitempty"`
+	// DeferMode determines whether the Next and Step commands stop inside
+	// deferred functions: with "skip" they don't, with "entry" they stop at
+	// the entry point of the deferred function. The default is to stop after
+	// the prologue of the deferred function.
+	DeferMode string `json:"deferMode,omitempty"`
+	// Count is the number of times the Next, Step and StepInstruction
+	// commands are repeated, zero is the same as one.
+	Count int `json:"count,omitempty"`
+	// SampleDuration is how long the Sample command runs the target.
+	SampleDuration time.Duration `json:"sampleDuration,omitempty"`
 }
 
 // Informations about the current breakpoint
@@ -302,34 +721,82 @@ type BreakpointInfo struct {
 	Variables  []Variable   `json:"variables,omitempty"`
 	Arguments  []Variable   `json:"arguments,omitempty"`
 	Locals     []Variable   `json:"locals,omitempty"`
+	// PanicLocation is the location of the call to panic or runtime.throw
+	// that caused the target to stop on the unrecovered-panic or
+	// fatal-throw breakpoints.
+	PanicLocation *Location `json:"panicLocation,omitempty"`
 }
 
 type EvalScope struct {
 	GoroutineID int
 	Frame       int
+	// DeferredCall, when greater than zero, makes the scope relative to the
+	// arguments of the DeferredCall-th function deferred by Frame, in the
+	// order they will be called.
+	DeferredCall int
 }
 
 const (
 	// Continue resumes process execution.
 	Continue = "continue"
+	// ContinueUntil resumes process execution until the location in Expr is
+	// reached or a breakpoint is hit.
+	ContinueUntil = "continueUntil"
+	// Jump moves the execution of the selected goroutine to the location in
+	// Expr, which must be in the current function, without resuming it.
+	Jump = "jump"
+	// ContinueToRecover resumes process execution until the deferred
+	// function that will recover the current panic is called.
+	ContinueToRecover = "continueToRecover"
+	// ContinueToGoroutine resumes process execution until the goroutine
+	// specified by GoroutineID runs again.
+	ContinueToGoroutine = "continueToGoroutine"
 	// Rewind resumes process execution backwards (target must be a recording).
 	Rewind = "rewind"
 	// Step continues to next source line, entering function calls.
 	Step = "step"
 	// StepOut continues to the return address of the current function
 	StepOut = "stepOut"
+	// StepToReturn continues until the current function is about to return
+	// and reports its return values.
+	StepToReturn = "stepToReturn"
 	// SingleStep continues for exactly 1 cpu instruction.
 	StepInstruction = "stepInstruction"
 	// Next continues to the next source line, not entering function calls.
 	Next = "next"
+	// ReverseNext moves backwards to the previous source line, not entering
+	// function calls (target must be a recording).
+	ReverseNext = "reverseNext"
+	// ReverseStep moves backwards to the previous source line, entering
+	// function calls (target must be a recording).
+	ReverseStep = "reverseStep"
+	// ReverseStepInstruction moves backwards by exactly 1 cpu instruction
+	// (target must be a recording).
+	ReverseStepInstruction = "reverseStepInstruction"
+	// Until continues to a source line of the current function greater than
+	// the current one, or until the current function returns.
+	Until = "until"
+	// NextInstruction continues to the next CPU instruction, stepping over
+	// CALL instructions.
+	NextInstruction = "nextInstruction"
+	// StepInto continues to the next source line, entering only the
+	// function called by the CALL instruction at CallPC.
+	StepInto = "stepInto"
 	// SwitchThread switches the debugger's current thread context.
 	SwitchThread = "switchThread"
 	// SwitchGoroutine switches the debugger's current thread context to the thread running the specified goroutine
 	SwitchGoroutine = "switchGoroutine"
+	// SwitchFrame selects a frame of the selected goroutine, next, step and
+	// stepout operate relative to the selected frame.
+	SwitchFrame = "switchFrame"
 	// Halt suspends the process.
 	Halt = "halt"
 	// Call resumes process execution injecting a function call.
 	Call = "call"
+	// Sample resumes process execution for SampleDuration, stopping all
+	// its threads periodically to record the stacks of the goroutines
+	// running on them.
+	Sample = "sample"
 )
 
 type AssemblyFlavour int
@@ -394,6 +861,22 @@ func (regs Registers) String() string {
 	return buf.String()
 }
 
+// StackRecord is a stacktrace recorded by a breakpoint with RecordStack
+// set.
+type StackRecord struct {
+	GoroutineID int          `json:"goroutineID"`
+	ThreadID    int          `json:"threadID"`
+	Stack       []Stackframe `json:"stack"`
+}
+
+// StepIntoTarget is a function called by the current source line, that
+// can be entered with the StepInto command.
+type StepIntoTarget struct {
+	// CallPC is the address of the CALL instruction.
+	CallPC   uint64    `json:"callPC"`
+	Function *Function `json:"function,omitempty"`
+}
+
 type DiscardedBreakpoint struct {
 	Breakpoint *Breakpoint
 	Reason     string
@@ -30,23 +30,86 @@ type Client interface {
 
 	// Continue resumes process execution.
 	Continue() <-chan *api.DebuggerState
+	// ContinueUntil resumes process execution until the location loc is
+	// reached or a breakpoint is hit.
+	ContinueUntil(loc string) (*api.DebuggerState, error)
+	// Jump moves the execution of the current goroutine to the location loc,
+	// in the current function.
+	Jump(loc string) (*api.DebuggerState, error)
+	// ContinueToRecover resumes process execution until the deferred
+	// function that will recover the current panic is called.
+	ContinueToRecover() (*api.DebuggerState, error)
+	// ContinueToGoroutine resumes process execution until goroutine gid
+	// runs again.
+	ContinueToGoroutine(gid int) (*api.DebuggerState, error)
+	// SampleStacks resumes process execution for duration, periodically
+	// stopping all its threads to record the stacks of the goroutines
+	// running on them, which are returned in DebuggerState.Samples.
+	SampleStacks(duration time.Duration) (*api.DebuggerState, error)
 	// Rewind resumes process execution backwards.
 	Rewind() <-chan *api.DebuggerState
 	// Next continues to the next source line, not entering function calls.
 	Next() (*api.DebuggerState, error)
 	// Step continues to the next source line, entering function calls.
 	Step() (*api.DebuggerState, error)
+	// NextGoroutine is like Next but steps goroutine goroutineID, which is
+	// resumed until it runs if it is parked.
+	NextGoroutine(goroutineID int) (*api.DebuggerState, error)
+	// StepGoroutine is like Step but steps goroutine goroutineID.
+	StepGoroutine(goroutineID int) (*api.DebuggerState, error)
+	// NextDefer is like Next but mode, "stop", "skip" or "entry", determines
+	// whether to stop inside deferred functions.
+	NextDefer(mode string) (*api.DebuggerState, error)
+	// StepDefer is like Step but mode, "stop", "skip" or "entry", determines
+	// whether to stop inside deferred functions.
+	StepDefer(mode string) (*api.DebuggerState, error)
+	// NextCount is like NextDefer but repeats the operation count times,
+	// stopping early if a breakpoint is hit.
+	NextCount(mode string, count int) (*api.DebuggerState, error)
+	// StepCount is like StepDefer but repeats the operation count times,
+	// stopping early if a breakpoint is hit.
+	StepCount(mode string, count int) (*api.DebuggerState, error)
 	// StepOut continues to the return address of the current function
 	StepOut() (*api.DebuggerState, error)
+	// StepToReturn continues until the current function is about to return.
+	StepToReturn() (*api.DebuggerState, error)
+	// Until continues to a source line of the current function greater than
+	// the current one, not entering function calls.
+	Until() (*api.DebuggerState, error)
+	// StepInto continues to the next source line, entering only the function
+	// called by the CALL instruction at callPC.
+	StepInto(callPC uint64) (*api.DebuggerState, error)
+	// ListStepIntoTargets returns the functions called by the current source
+	// line.
+	ListStepIntoTargets() ([]api.StepIntoTarget, error)
+	// ReverseNext moves backwards to the previous source line, not entering
+	// function calls.
+	ReverseNext() (*api.DebuggerState, error)
+	// ReverseStep moves backwards to the previous source line, entering
+	// function calls.
+	ReverseStep() (*api.DebuggerState, error)
+	// ReverseStepInstruction moves backwards by a single cpu instruction.
+	ReverseStepInstruction() (*api.DebuggerState, error)
 	// Call resumes process execution while making a function call.
 	Call(expr string) (*api.DebuggerState, error)
+	// CallGoroutine is like Call but makes the call on goroutine
+	// goroutineID, continuing until it runs if it is parked.
+	CallGoroutine(goroutineID int, expr string) (*api.DebuggerState, error)
 
 	// SingleStep will step a single cpu instruction.
 	StepInstruction() (*api.DebuggerState, error)
+	// StepInstructionCount steps count cpu instructions.
+	StepInstructionCount(count int) (*api.DebuggerState, error)
+	// NextInstruction will step a single cpu instruction, stepping over
+	// CALL instructions.
+	NextInstruction() (*api.DebuggerState, error)
 	// SwitchThread switches the current thread context.
 	SwitchThread(threadID int) (*api.DebuggerState, error)
 	// SwitchGoroutine switches the current goroutine (and the current thread as well)
 	SwitchGoroutine(goroutineID int) (*api.DebuggerState, error)
+	// SwitchFrame selects a frame of the current goroutine, next, step and
+	// stepout operate relative to the selected frame.
+	SwitchFrame(frame int) (*api.DebuggerState, error)
 	// Halt suspends the process.
 	Halt() (*api.DebuggerState, error)
 
@@ -58,6 +121,13 @@ type Client interface {
 	CreateBreakpoint(*api.Breakpoint) (*api.Breakpoint, error)
 	// ListBreakpoints gets all breakpoints.
 	ListBreakpoints() ([]*api.Breakpoint, error)
+	// ListInternalBreakpoints gets the internal breakpoints set by next, step
+	// and stepout, they can be cleared with CancelNext, and the one used to
+	// freeze goroutines.
+	ListInternalBreakpoints() ([]*api.Breakpoint, error)
+	// TakeStackRecords returns the stacktraces recorded by a breakpoint with
+	// RecordStack set since the last call.
+	TakeStackRecords(id int) ([]api.StackRecord, error)
 	// ClearBreakpoint deletes a breakpoint by ID.
 	ClearBreakpoint(id int) (*api.Breakpoint, error)
 	// ClearBreakpointByName deletes a breakpoint by name
@@ -65,6 +135,46 @@ type Client interface {
 	// Allows user to update an existing breakpoint for example to change the information
 	// retrieved when the breakpoint is hit or to change, add or remove the break condition
 	AmendBreakpoint(*api.Breakpoint) error
+	// SaveBreakpoints saves all user breakpoints to the specified file, on
+	// the machine running the server.
+	SaveBreakpoints(path string) error
+	// LoadBreakpoints sets the breakpoints saved in the specified file by
+	// SaveBreakpoints.
+	LoadBreakpoints(path string) ([]*api.Breakpoint, []api.DiscardedBreakpoint, error)
+	// SetBreakpointGroupDisabled disables or enables all the breakpoints in
+	// the specified group.
+	SetBreakpointGroupDisabled(group string, disabled bool) error
+	// SetSignalPolicy sets what happens when the target receives signal sig,
+	// policy is one of "stop", "pass" or "ignore".
+	SetSignalPolicy(sig int, policy string) error
+	// SetSyscallCatchpoints sets the syscalls, by name or number, that stop
+	// the target when they are entered or exited.
+	SetSyscallCatchpoints(syscalls []string) error
+	// SetResumeMode sets which threads run when the target is continued,
+	// mode is one of "all", "selected" or "nonstop".
+	SetResumeMode(mode string) error
+	// SetStepFilters sets the patterns of the functions hidden from next,
+	// step and stepout.
+	SetStepFilters(filters []string) error
+	// GetStepFilters returns the patterns set with SetStepFilters.
+	GetStepFilters() ([]string, error)
+	// SetBlackbox sets the functions and files that next, step and stepout
+	// never stop inside.
+	SetBlackbox(entries []string) error
+	// GetBlackbox returns the functions and files set with SetBlackbox.
+	GetBlackbox() ([]string, error)
+	// SetStepIntoRuntime determines whether step enters unexported runtime
+	// functions.
+	SetStepIntoRuntime(enabled bool) error
+	// SetCgoCatchpoints enables or disables stopping the target every time
+	// it crosses the boundary between Go and C code.
+	SetCgoCatchpoints(enabled bool) error
+	// SetDeadlockDetection enables or disables stopping the target when
+	// Continue finds it deadlocked.
+	SetDeadlockDetection(enabled bool) error
+	// DetectDeadlock returns a description of the deadlock the target is
+	// in, or nil if it isn't deadlocked.
+	DetectDeadlock() (*api.Deadlock, error)
 	// Cancels a Next or Step call that was interrupted by a manual stop or by another breakpoint
 	CancelNext() error
 
@@ -75,8 +185,19 @@ type Client interface {
 
 	// ListPackageVariables lists all package variables in the context of the current thread.
 	ListPackageVariables(filter string, cfg api.LoadConfig) ([]api.Variable, error)
+	// FindPackageVariables lists the package variables of package pkg, or
+	// of all packages if pkg is empty, with a name matching the regular
+	// expression filter. If cfg is nil only the name, type and address of
+	// each variable is returned.
+	FindPackageVariables(pkg, filter string, cfg *api.LoadConfig) ([]api.Variable, error)
 	// EvalVariable returns a variable in the context of the current thread.
 	EvalVariable(scope api.EvalScope, symbol string, cfg api.LoadConfig) (*api.Variable, error)
+	// EvalVariableChildren evaluates a map, a slice or an array loading at
+	// most limit of its children, starting from offset.
+	EvalVariableChildren(scope api.EvalScope, expr string, offset, limit int, cfg api.LoadConfig) (*api.Variable, error)
+	// ExpandVariable loads the children of the variable with the given
+	// handle, returned by a previous call because they were not loaded.
+	ExpandVariable(handle int, cfg api.LoadConfig) (*api.Variable, error)
 
 	// SetVariable sets the value of a variable
 	SetVariable(scope api.EvalScope, symbol, value string) error
@@ -89,6 +210,9 @@ type Client interface {
 	ListTypes(filter string) ([]string, error)
 	// ListLocals lists all local variables in scope.
 	ListLocalVariables(scope api.EvalScope, cfg api.LoadConfig) ([]api.Variable, error)
+	// ListLocalInstances lists every declaration of the local variable or
+	// argument called name, including shadowed ones.
+	ListLocalInstances(scope api.EvalScope, name string, cfg api.LoadConfig) ([]api.VariableInstance, error)
 	// ListFunctionArgs lists all arguments to the current function.
 	ListFunctionArgs(scope api.EvalScope, cfg api.LoadConfig) ([]api.Variable, error)
 	// ListRegisters lists registers and their values.
@@ -96,9 +220,37 @@ type Client interface {
 
 	// ListGoroutines lists all goroutines.
 	ListGoroutines() ([]*api.Goroutine, error)
+	// ListGoroutinesFiltered lists at most count goroutines matching filter,
+	// starting from start. Returns the value of start for the next call, or
+	// -1 if there are no more goroutines.
+	ListGoroutinesFiltered(filter api.GoroutineFilter, start, count int) ([]*api.Goroutine, int, error)
+	// GroupGoroutines groups the goroutines matching filter by groupBy, see
+	// api.GroupByCurrentFunc, api.GroupByUserFunc and api.GroupByWaitReason.
+	GroupGoroutines(filter api.GoroutineFilter, groupBy string, maxGoroutineIDs int) ([]api.GoroutineGroup, error)
+	// MarkGoroutines records the current goroutines as the baseline of
+	// GoroutineDiff and returns their number.
+	MarkGoroutines() (int, error)
+	// GoroutineDiff returns the goroutines created and exited since the
+	// last call to MarkGoroutines.
+	GoroutineDiff() (*api.GoroutineDiff, error)
+	// ListWaiters returns the goroutines blocked on the channel or mutex
+	// expr evaluates to.
+	ListWaiters(scope api.EvalScope, expr string) ([]api.Waiter, error)
+	// MutexInfo returns the state of the sync.Mutex expr evaluates to, or of
+	// the mutex the goroutine of scope is blocked on if expr is empty.
+	MutexInfo(scope api.EvalScope, expr string) (*api.MutexState, error)
 
 	// Returns stacktrace
 	Stacktrace(goroutineID int, depth int, readDefers bool, cfg *api.LoadConfig) ([]api.Stackframe, error)
+	// SystemStacktrace is like Stacktrace but, if the goroutine is executing
+	// on a system stack, returns all the frames of the system stack instead
+	// of only its topmost frame.
+	SystemStacktrace(goroutineID int, depth int, readDefers bool, cfg *api.LoadConfig) ([]api.Stackframe, error)
+	// Stacktraces returns the stacktraces of all goroutines matching filter.
+	Stacktraces(filter api.GoroutineFilter, depth int, readDefers bool, cfg *api.LoadConfig) ([]api.GoroutineStacktrace, error)
+	// Ancestors returns ancestor stacktraces of goroutineID, recorded by the
+	// runtime when GODEBUG=tracebackancestors=N is set.
+	Ancestors(goroutineID int, numAncestors int, depth int) ([]api.Ancestor, error)
 
 	// Returns whether we attached to a running process or not
 	AttachedToExistingProcess() bool
@@ -131,10 +283,58 @@ type Client interface {
 	ListCheckpoints() ([]api.Checkpoint, error)
 	// ClearCheckpoint removes a checkpoint
 	ClearCheckpoint(id int) error
+	// FreezeGoroutine freezes a goroutine, leaving the threads running it
+	// stopped while the rest of the target runs. Returns the list of frozen
+	// goroutines.
+	FreezeGoroutine(goroutineID int) ([]int, error)
+	// ThawGoroutine thaws a goroutine frozen by FreezeGoroutine.
+	ThawGoroutine(goroutineID int) ([]int, error)
+	// ListFrozenGoroutines returns the list of frozen goroutines.
+	ListFrozenGoroutines() ([]int, error)
+	// SetSchedHistory enables or disables the recording of the goroutines
+	// scheduled on each thread between two stops.
+	SetSchedHistory(enabled bool) error
+	// SchedHistory returns the scheduling history recorded since the target
+	// was last resumed.
+	SchedHistory() ([]api.SchedEvent, error)
+	// SeekEvent moves a recording to the start of the specified event.
+	SeekEvent(event int64) (*api.DebuggerState, error)
 
 	// SetReturnValuesLoadConfig sets the load configuration for return values.
 	SetReturnValuesLoadConfig(*api.LoadConfig)
 
+	// SetWatchExpressions sets the list of expressions that are evaluated
+	// every time the target stops, their values are returned in the
+	// WatchExpressions field of the debugger state. Returns the IDs of the
+	// expressions, expressions that were already in the list keep their ID.
+	SetWatchExpressions(exprs []string) ([]int, error)
+
+	// SaveVariableSnapshot saves the value of expr, maxDepth levels deep,
+	// to compare it later with DiffVariables.
+	SaveVariableSnapshot(scope api.EvalScope, expr string, maxDepth int) error
+	// DiffVariables compares the values of expr1 and expr2, up to maxDepth
+	// levels deep, and returns where they differ. If expr2 is empty expr1
+	// is compared with the value saved by SaveVariableSnapshot.
+	DiffVariables(scope api.EvalScope, expr1, expr2 string, maxDepth int) ([]api.VariableDifference, error)
+
+	// ExportVariable returns the value of expr, following pointers up to
+	// maxDepth levels deep, written as a Go expression or, if asJSON is
+	// set, as a JSON document.
+	ExportVariable(scope api.EvalScope, expr string, asJSON bool, maxDepth int) (string, error)
+
+	// Traverse visits at most maxNodes nodes of the linked data structure
+	// starting at expr, following the pointers in fields or, if it is
+	// empty, in all the fields pointing to nodes. The boolean is true if
+	// more nodes are reachable.
+	Traverse(scope api.EvalScope, expr string, fields []string, maxNodes int, cfg *api.LoadConfig) ([]api.TraversalNode, bool, error)
+
+	// SetFormatter registers a pretty-printer for the struct type typename,
+	// an empty template removes it.
+	SetFormatter(typename, template string) error
+	// ListFormatters returns the templates of the registered
+	// pretty-printers, by type name.
+	ListFormatters() (map[string]string, error)
+
 	// IsMulticlien returns true if the headless instance is multiclient.
 	IsMulticlient() bool
 
@@ -35,6 +35,10 @@ type Config struct {
 	// Foreground lets target process access stdin.
 	Foreground bool
 
+	// StopOnEntry is the name of the function that a newly launched process
+	// is continued to before the server starts accepting commands.
+	StopOnEntry string
+
 	// DisconnectChan will be closed by the server when the client disconnects
 	DisconnectChan chan<- struct{}
 }
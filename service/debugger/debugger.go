@@ -1,14 +1,21 @@
 package debugger
 
 import (
+	"bytes"
 	"debug/dwarf"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"go/parser"
+	"go/constant"
+	"go/token"
+	"io"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -42,8 +49,85 @@ type Debugger struct {
 
 	running      bool
 	runningMutex sync.Mutex
+
+	// watchExprs is the list of expressions evaluated every time the
+	// debugger state is returned.
+	watchExprs  []*watchExpr
+	lastWatchID int
+
+	// signalPolicy contains the signal policies set with SetSignalPolicy,
+	// they are applied again to the new process when restarting.
+	signalPolicy map[int]proc.SignalPolicy
+	// syscallCatch is the list of syscalls set with SetSyscallCatchpoints.
+	syscallCatch []int
+	// cgoCatch is true if cgo catchpoints were enabled with
+	// SetCgoCatchpoints.
+	cgoCatch bool
+	// schedHistory is true if the scheduling history was enabled with
+	// SetSchedHistory.
+	schedHistory bool
+	// stepFilters is the list of patterns set with SetStepFilters.
+	stepFilters []string
+	// blackbox is the list of functions and files set with SetBlackbox.
+	blackbox []string
+	// stepIntoRuntime is the value set with SetStepIntoRuntime.
+	stepIntoRuntime bool
+	// resumeMode is the mode set with SetResumeMode.
+	resumeMode proc.ResumeMode
+	// deadlockDetection is the value set with SetDeadlockDetection.
+	deadlockDetection bool
+	// haltRequested is set when a Halt command is received, it tells halt
+	// requests apart from the stops done to check for deadlocks. Protected
+	// by runningMutex.
+	haltRequested bool
+	// goroutineBaseline contains the goroutines that existed when
+	// MarkGoroutines was last called, indexed by ID.
+	goroutineBaseline map[int]*api.Goroutine
+	// snapshots contains the values saved by SaveVariableSnapshot, indexed
+	// by expression.
+	snapshots map[string]*proc.Variable
+	// handles contains the variables returned by the last evaluations whose
+	// children can be loaded with ExpandVariable, it is reset when the
+	// target is resumed.
+	handles proc.VariableHandles
+	// logpointMessages contains the messages of the logpoints hit while
+	// the current command was running, they are returned with the debugger
+	// state. Protected by processMutex.
+	logpointMessages []api.LogpointMessage
+	// breakpointActionResults contains the results of the breakpoint
+	// actions executed while the current command was running, they are
+	// returned with the debugger state. Protected by processMutex.
+	breakpointActionResults []api.BreakpointActionResult
+	// formatters contains the pretty-printers registered with SetFormatter,
+	// it is shared by all the targets started by the debugger.
+	formatters *proc.Formatters
 }
 
+// deadlockCheckInterval is how often the target is stopped to check for
+// deadlocks while it is continued with deadlock detection enabled.
+const deadlockCheckInterval = time.Second
+
+const (
+	// sampleInterval is how often the target is stopped by the Sample
+	// command to record stacks.
+	sampleInterval = 10 * time.Millisecond
+	// sampleDepth is the number of frames of each stack recorded by the
+	// Sample command.
+	sampleDepth = 10
+)
+
+const (
+	// defaultDiffDepth is how deep values are compared by DiffVariables
+	// when the depth is not specified.
+	defaultDiffDepth = 5
+	// defaultExportDepth is how deep values are exported by ExportVariable
+	// when the depth is not specified.
+	defaultExportDepth = 5
+	// defaultTraverseNodes is the number of nodes visited by Traverse when
+	// the maximum is not specified.
+	defaultTraverseNodes = 100
+)
+
 // Config provides the configuration to start a Debugger.
 //
 // Only one of ProcessArgs or AttachPid should be specified. If ProcessArgs is
@@ -65,6 +149,15 @@ type Config struct {
 
 	// Foreground lets target process access stdin.
 	Foreground bool
+
+	// LogpointOutput, if not nil, is where the messages of logpoints are
+	// written, in addition to being returned with the debugger state.
+	LogpointOutput io.Writer
+
+	// StopOnEntry is the name of a function, for example runtime.main, that
+	// a newly launched process is continued to before any other command is
+	// executed. If empty the process is left stopped at its entry point.
+	StopOnEntry string
 }
 
 // New creates a new Debugger. ProcessArgs specify the commandline arguments for the
@@ -79,6 +172,7 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 		config:      config,
 		processArgs: processArgs,
 		log:         logger,
+		formatters:  proc.NewFormatters(),
 	}
 
 	// Create the process by either attaching or launching.
@@ -123,8 +217,15 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 			}
 			return nil, err
 		}
+		if err := d.stopOnEntry(p); err != nil {
+			p.Detach(true)
+			return nil, err
+		}
 		d.target = p
 	}
+	d.target.Common().SetLogpointFunc(d.logpoint)
+	d.target.Common().SetBreakpointActionsFunc(d.breakpointActions)
+	d.target.BinInfo().SetFormatters(d.formatters)
 	return d, nil
 }
 
@@ -214,7 +315,10 @@ func (d *Debugger) detach(kill bool) error {
 // and then exec'ing it again.
 // If the target process is a recording it will restart it from the given
 // position. If pos starts with 'c' it's a checkpoint ID, otherwise it's an
-// event number. If resetArgs is true, newArgs will replace the process args.
+// event number. If the target process is not a recording and pos is not
+// empty it must be a checkpoint ID, the process is restored to the
+// checkpoint instead of being restarted.
+// If resetArgs is true, newArgs will replace the process args.
 func (d *Debugger) Restart(pos string, resetArgs bool, newArgs []string) ([]api.DiscardedBreakpoint, error) {
 	d.processMutex.Lock()
 	defer d.processMutex.Unlock()
@@ -224,7 +328,7 @@ func (d *Debugger) Restart(pos string, resetArgs bool, newArgs []string) ([]api.
 	}
 
 	if pos != "" {
-		return nil, proc.NotRecordedErr
+		return nil, d.target.Restart(pos)
 	}
 
 	if valid, _ := d.target.Valid(); valid {
@@ -239,13 +343,18 @@ func (d *Debugger) Restart(pos string, resetArgs bool, newArgs []string) ([]api.
 	if resetArgs {
 		d.processArgs = append([]string{d.processArgs[0]}, newArgs...)
 	}
+	d.goroutineBaseline = nil
 	p, err := d.Launch(d.processArgs, d.config.WorkingDir)
 	if err != nil {
 		return nil, fmt.Errorf("could not launch process: %s", err)
 	}
+	if err := d.stopOnEntry(p); err != nil {
+		p.Detach(true)
+		return nil, err
+	}
 	discarded := []api.DiscardedBreakpoint{}
 	for _, oldBp := range d.breakpoints() {
-		if oldBp.ID < 0 {
+		if oldBp.ID < 0 || oldBp.CgoCatch {
 			continue
 		}
 		if len(oldBp.File) > 0 {
@@ -256,18 +365,68 @@ func (d *Debugger) Restart(pos string, resetArgs bool, newArgs []string) ([]api.
 				continue
 			}
 		}
-		newBp, err := p.SetBreakpoint(oldBp.Addr, proc.UserBreakpoint, nil)
+		var newBp *proc.Breakpoint
+		if oldBp.Hardware {
+			newBp, err = proc.SetHardwareBreakpoint(p, oldBp.Addr, nil)
+		} else {
+			newBp, err = p.SetBreakpoint(oldBp.Addr, proc.UserBreakpoint, nil)
+		}
 		if err != nil {
 			return nil, err
 		}
 		if err := copyBreakpointInfo(newBp, oldBp); err != nil {
 			return nil, err
 		}
+		if oldBp.Disabled {
+			if err := p.SetBreakpointsDisabled([]*proc.Breakpoint{newBp}, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.Common().SetLogpointFunc(d.logpoint)
+	p.Common().SetBreakpointActionsFunc(d.breakpointActions)
+	for sig, policy := range d.signalPolicy {
+		p.Common().SetSignalPolicy(sig, policy)
+	}
+	p.Common().SetSyscallCatchpoints(d.syscallCatch)
+	p.Common().SetStepFilters(d.stepFilters)
+	p.Common().SetBlackbox(d.blackbox)
+	p.Common().SetStepIntoRuntime(d.stepIntoRuntime)
+	p.Common().SetResumeMode(d.resumeMode)
+	p.BinInfo().SetFormatters(d.formatters)
+	if d.cgoCatch {
+		if _, err := proc.SetCgoCatchpoints(p); err != nil {
+			// the new executable does not use cgo
+			d.cgoCatch = false
+		}
+	}
+	if d.schedHistory {
+		if err := proc.RecordSchedHistory(p, true); err != nil {
+			d.schedHistory = false
+		}
 	}
 	d.target = p
+	d.handles.Reset()
 	return discarded, nil
 }
 
+// stopOnEntry continues the newly launched process p until the function
+// specified by Config.StopOnEntry is reached.
+func (d *Debugger) stopOnEntry(p proc.Process) error {
+	if d.config.StopOnEntry == "" {
+		return nil
+	}
+	d.log.Infof("continuing to %s", d.config.StopOnEntry)
+	pc, err := proc.FindFunctionLocation(p, d.config.StopOnEntry, true, 0)
+	if err != nil {
+		return fmt.Errorf("could not find entry function %s: %v", d.config.StopOnEntry, err)
+	}
+	if err := proc.ContinueUntil(p, []uint64{pc}); err != nil {
+		return fmt.Errorf("could not continue to entry function %s: %v", d.config.StopOnEntry, err)
+	}
+	return nil
+}
+
 // State returns the current state of the debugger.
 func (d *Debugger) State(nowait bool) (*api.DebuggerState, error) {
 	if d.isRunning() && nowait {
@@ -276,7 +435,11 @@ func (d *Debugger) State(nowait bool) (*api.DebuggerState, error) {
 
 	d.processMutex.Lock()
 	defer d.processMutex.Unlock()
-	return d.state(nil)
+	state, err := d.state(nil)
+	if err == nil {
+		d.evalWatchExpressions(state, false)
+	}
+	return state, err
 }
 
 func (d *Debugger) state(retLoadCfg *proc.LoadConfig) (*api.DebuggerState, error) {
@@ -301,6 +464,8 @@ func (d *Debugger) state(retLoadCfg *proc.LoadConfig) (*api.DebuggerState, error
 	state = &api.DebuggerState{
 		SelectedGoroutine: goroutine,
 		Exited:            exited,
+		ManualStop:        d.target.Common().StoppedManually(),
+		SelectedFrame:     proc.SelectedFrame(d.target),
 	}
 
 	for _, thread := range d.target.ThreadList() {
@@ -320,11 +485,288 @@ func (d *Debugger) state(retLoadCfg *proc.LoadConfig) (*api.DebuggerState, error
 
 	if recorded, _ := d.target.Recorded(); recorded {
 		state.When, _ = d.target.When()
+		state.Event, _ = proc.CurrentEvent(d.target)
 	}
 
 	return state, nil
 }
 
+// watchExpr is a watch expression, see SetWatchExpressions.
+type watchExpr struct {
+	id   int
+	expr string
+	// cur and prev are the values of the expression at the last stop and
+	// at the stop before it.
+	cur, prev *proc.Variable
+}
+
+// SetWatchExpressions sets the list of expressions that will be evaluated,
+// in the scope of the selected goroutine, every time the debugger state is
+// returned, and returns their IDs. Expressions that were already in the
+// list keep their ID and their previous values.
+func (d *Debugger) SetWatchExpressions(exprs []string) ([]int, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	for _, expr := range exprs {
+		if _, err := proc.ParseExpr(expr); err != nil {
+			return nil, fmt.Errorf("could not parse %q: %v", expr, err)
+		}
+	}
+	old := d.watchExprs
+	d.watchExprs = make([]*watchExpr, len(exprs))
+	ids := make([]int, len(exprs))
+	for i, expr := range exprs {
+		for j, we := range old {
+			if we != nil && we.expr == expr {
+				d.watchExprs[i] = we
+				old[j] = nil
+				break
+			}
+		}
+		if d.watchExprs[i] == nil {
+			d.lastWatchID++
+			d.watchExprs[i] = &watchExpr{id: d.lastWatchID, expr: expr}
+		}
+		ids[i] = d.watchExprs[i].id
+	}
+	return ids, nil
+}
+
+// evalWatchExpressions evaluates the watch expressions in the scope of the
+// selected goroutine and stores their values in state. Record must be set
+// when the target stopped after being resumed: the values are recorded as
+// the values at the last stop. Otherwise the target did not move since the
+// last stop and the values are compared with the ones of the stop before.
+func (d *Debugger) evalWatchExpressions(state *api.DebuggerState, record bool) {
+	if len(d.watchExprs) == 0 || state.Exited {
+		return
+	}
+	state.WatchExpressions = make([]api.WatchExpression, len(d.watchExprs))
+	s, err := proc.SelectedScope(d.target)
+	for i, we := range d.watchExprs {
+		r := &state.WatchExpressions[i]
+		r.ID, r.Expr = we.id, we.expr
+		if err != nil {
+			r.Value = api.Variable{Name: we.expr, Unreadable: fmt.Sprintf("could not create scope: %v", err)}
+			continue
+		}
+		prev := we.prev
+		if record {
+			prev = we.cur
+		}
+		v, everr := s.EvalVariable(we.expr, proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1})
+		if record {
+			we.prev, we.cur = we.cur, v
+		}
+		if everr != nil {
+			r.Value = api.Variable{Name: we.expr, Unreadable: fmt.Sprintf("eval error: %v", everr)}
+			continue
+		}
+		r.Value = *api.ConvertVar(v)
+		r.Value.Name = we.expr
+		if prev != nil {
+			r.Changed = !watchValuesEqual(prev, v)
+			if delta := watchDelta(prev, v); delta != nil {
+				r.Delta = delta.String()
+			}
+		}
+	}
+}
+
+// watchValuesEqual returns true if a and b, two values of the same watch
+// expression, are the same. Children are also compared by address, so
+// that pointers to different objects with the same contents are
+// different.
+func watchValuesEqual(a, b *proc.Variable) bool {
+	if a.Kind != b.Kind || a.Len != b.Len || a.Cap != b.Cap || a.Base != b.Base || a.OnlyAddr != b.OnlyAddr {
+		return false
+	}
+	if (a.Unreadable == nil) != (b.Unreadable == nil) || (a.Value == nil) != (b.Value == nil) {
+		return false
+	}
+	if a.Unreadable != nil && a.Unreadable.Error() != b.Unreadable.Error() {
+		return false
+	}
+	if a.Value != nil && (a.Value.Kind() != b.Value.Kind() || !constant.Compare(a.Value, token.EQL, b.Value)) {
+		return false
+	}
+	if len(a.Children) != len(b.Children) {
+		return false
+	}
+	for i := range a.Children {
+		if a.Children[i].Addr != b.Children[i].Addr || !watchValuesEqual(&a.Children[i], &b.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// watchDelta returns cur - prev if both are numbers, nil otherwise.
+func watchDelta(prev, cur *proc.Variable) constant.Value {
+	if prev.Kind != cur.Kind || prev.Value == nil || cur.Value == nil {
+		return nil
+	}
+	switch cur.Kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return constant.BinaryOp(cur.Value, token.SUB, prev.Value)
+	}
+	return nil
+}
+
+// SetFormatter registers a pretty-printer for the struct type typename that
+// prints tmpl, see proc.Formatters.RegisterTemplate. If tmpl is empty the
+// pretty-printer of typename is removed.
+func (d *Debugger) SetFormatter(typename, tmpl string) error {
+	if tmpl == "" {
+		d.formatters.Register(typename, nil)
+		return nil
+	}
+	return d.formatters.RegisterTemplate(typename, tmpl)
+}
+
+// Formatters returns the pretty-printers registered with SetFormatter, as a
+// map from type names to templates.
+func (d *Debugger) Formatters() map[string]string {
+	r := make(map[string]string)
+	for _, f := range d.formatters.Templates() {
+		r[f[0]] = f[1]
+	}
+	return r
+}
+
+// SetSignalPolicy sets what happens when the target receives signal sig,
+// policy can be "stop", "pass" or "ignore".
+func (d *Debugger) SetSignalPolicy(sig int, policy string) error {
+	var p proc.SignalPolicy
+	switch policy {
+	case "stop":
+		p = proc.SignalStop
+	case "pass":
+		p = proc.SignalPass
+	case "ignore":
+		p = proc.SignalIgnore
+	default:
+		return fmt.Errorf("unknown signal policy %q", policy)
+	}
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	if d.signalPolicy == nil {
+		d.signalPolicy = make(map[int]proc.SignalPolicy)
+	}
+	d.signalPolicy[sig] = p
+	d.target.Common().SetSignalPolicy(sig, p)
+	return nil
+}
+
+// SetSyscallCatchpoints sets the syscalls, specified by name or number,
+// that will stop the target when entered or exited. An empty list removes
+// all syscall catchpoints.
+func (d *Debugger) SetSyscallCatchpoints(syscalls []string) error {
+	nums := make([]int, 0, len(syscalls))
+	for _, name := range syscalls {
+		num, ok := proc.SyscallNumber(name)
+		if !ok {
+			var err error
+			num, err = strconv.Atoi(name)
+			if err != nil {
+				return fmt.Errorf("unknown syscall %q", name)
+			}
+		}
+		nums = append(nums, num)
+	}
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	d.syscallCatch = nums
+	d.target.Common().SetSyscallCatchpoints(nums)
+	return nil
+}
+
+// SetResumeMode sets which threads run when the target is continued, mode
+// can be "all", "selected" or "nonstop", see proc.ResumeMode.
+func (d *Debugger) SetResumeMode(mode string) error {
+	var m proc.ResumeMode
+	switch mode {
+	case "all":
+		m = proc.ResumeAll
+	case "selected":
+		m = proc.ResumeSelected
+	case "nonstop":
+		m = proc.ResumeNonStop
+	default:
+		return fmt.Errorf("unknown resume mode %q", mode)
+	}
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	d.resumeMode = m
+	d.target.Common().SetResumeMode(m)
+	return nil
+}
+
+// SetStepFilters sets the patterns of the functions, and files, hidden
+// from next, step and stepout, see proc.(*CommonProcess).SetStepFilters.
+func (d *Debugger) SetStepFilters(filters []string) error {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	if err := d.target.Common().SetStepFilters(filters); err != nil {
+		return err
+	}
+	d.stepFilters = filters
+	return nil
+}
+
+// StepFilters returns the patterns set with SetStepFilters.
+func (d *Debugger) StepFilters() []string {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	return d.stepFilters
+}
+
+// SetBlackbox sets the list of functions and files that next, step and
+// stepout never stop inside, see proc.(*CommonProcess).SetBlackbox.
+func (d *Debugger) SetBlackbox(entries []string) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	d.target.Common().SetBlackbox(entries)
+	d.blackbox = entries
+}
+
+// Blackbox returns the list of functions and files set with SetBlackbox.
+func (d *Debugger) Blackbox() []string {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	return d.blackbox
+}
+
+// SetStepIntoRuntime determines whether step enters unexported runtime
+// functions, see proc.(*CommonProcess).SetStepIntoRuntime.
+func (d *Debugger) SetStepIntoRuntime(enabled bool) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	d.target.Common().SetStepIntoRuntime(enabled)
+	d.stepIntoRuntime = enabled
+}
+
+// SetCgoCatchpoints enables or disables the catchpoints stopping the
+// target every time it crosses the boundary between Go and C code.
+func (d *Debugger) SetCgoCatchpoints(enabled bool) error {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	if enabled == d.cgoCatch {
+		return nil
+	}
+	if enabled {
+		if _, err := proc.SetCgoCatchpoints(d.target); err != nil {
+			return err
+		}
+	} else if err := proc.ClearCgoCatchpoints(d.target); err != nil {
+		return err
+	}
+	d.cgoCatch = enabled
+	return nil
+}
+
 // CreateBreakpoint creates a breakpoint.
 func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoint, error) {
 	d.processMutex.Lock()
@@ -370,14 +812,28 @@ func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoin
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, d.locationNotFound(err)
 	}
 
-	bp, err := d.target.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+	var bp *proc.Breakpoint
+	if requestedBp.Hardware {
+		bp, err = proc.SetHardwareBreakpoint(d.target, addr, nil)
+	} else {
+		bp, err = d.target.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if err := copyBreakpointInfo(bp, requestedBp); err != nil {
+	if name := d.target.BinInfo().InlinedCallName(bp.Addr); name != "" {
+		// report the inlined function rather than the function containing
+		// the inlined call.
+		bp.FunctionName = name
+	}
+	err = copyBreakpointInfo(bp, requestedBp)
+	if err == nil && requestedBp.Disabled {
+		err = d.target.SetBreakpointsDisabled([]*proc.Breakpoint{bp}, true)
+	}
+	if err != nil {
 		if _, err1 := d.target.ClearBreakpoint(bp.Addr); err1 != nil {
 			err = fmt.Errorf("error while creating breakpoint: %v, additionally the breakpoint could not be properly rolled back: %v", err, err1)
 		}
@@ -399,28 +855,153 @@ func (d *Debugger) AmendBreakpoint(amend *api.Breakpoint) error {
 	if err := api.ValidBreakpointName(amend.Name); err != nil {
 		return err
 	}
-	return copyBreakpointInfo(original, amend)
+	if err := copyBreakpointInfo(original, amend); err != nil {
+		return err
+	}
+	if amend.Disabled != original.Disabled {
+		return d.target.SetBreakpointsDisabled([]*proc.Breakpoint{original}, amend.Disabled)
+	}
+	return nil
 }
 
+// CancelNext clears all internal breakpoints, cancelling a next, step or
+// stepout operation that was interrupted.
 func (d *Debugger) CancelNext() error {
 	return d.target.ClearInternalBreakpoints()
 }
 
+// SetBreakpointGroupDisabled disables or enables all the breakpoints
+// belonging to group.
+func (d *Debugger) SetBreakpointGroupDisabled(group string, disabled bool) error {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	return proc.SetBreakpointGroupDisabled(d.target, group, disabled)
+}
+
 func copyBreakpointInfo(bp *proc.Breakpoint, requested *api.Breakpoint) (err error) {
 	bp.Name = requested.Name
+	bp.Group = requested.Group
 	bp.Tracepoint = requested.Tracepoint
 	bp.Goroutine = requested.Goroutine
 	bp.Stacktrace = requested.Stacktrace
+	bp.RecordStack = requested.RecordStack
 	bp.Variables = requested.Variables
+	bp.LogMessage = requested.LogMessage
+	bp.Suspend = proc.SuspendAll
+	if requested.SuspendThread {
+		bp.Suspend = proc.SuspendThread
+	}
 	bp.LoadArgs = api.LoadConfigToProc(requested.LoadArgs)
 	bp.LoadLocals = api.LoadConfigToProc(requested.LoadLocals)
+	bp.MaxHits = requested.MaxHits
+	bp.ThreadID = requested.ThreadID
+	bp.Actions, err = api.BreakpointActionsToProc(requested.Actions)
+	if err != nil {
+		return err
+	}
 	bp.Cond = nil
 	if requested.Cond != "" {
-		bp.Cond, err = parser.ParseExpr(requested.Cond)
+		bp.Cond, err = proc.ParseExpr(requested.Cond)
+		if err != nil {
+			return err
+		}
+	}
+	bp.HitCond = nil
+	if requested.HitCond != "" {
+		bp.HitCond, err = proc.ParseHitCondition(requested.HitCond)
 	}
 	return err
 }
 
+// logpoint records the message of logpoint bp, hit by thread, so that it
+// is returned with the debugger state, and writes it to the logpoint
+// output. Expressions enclosed in curly braces inside the message are
+// evaluated in the scope of the current goroutine of thread.
+func (d *Debugger) logpoint(thread proc.Thread, bp *proc.Breakpoint) {
+	gid := -1
+	if g, _ := proc.GetG(thread); g != nil {
+		gid = g.ID
+	}
+	scope, err := proc.GoroutineScope(thread)
+	var msg string
+	if err != nil {
+		msg = fmt.Sprintf("%s (could not evaluate: %v)", bp.LogMessage, err)
+	} else {
+		msg = formatLogMessage(scope, bp.LogMessage)
+	}
+	d.logpointMessages = append(d.logpointMessages, api.LogpointMessage{BreakpointID: bp.ID, GoroutineID: gid, Message: msg})
+	if out := d.config.LogpointOutput; out != nil {
+		fmt.Fprintf(out, "> [logpoint %d] goroutine(%d): %s\n", bp.ID, gid, msg)
+	}
+}
+
+// takeLogpointMessages returns the logpoint messages recorded since it was
+// last called.
+func (d *Debugger) takeLogpointMessages() []api.LogpointMessage {
+	msgs := d.logpointMessages
+	d.logpointMessages = nil
+	return msgs
+}
+
+// breakpointActions records the results of the actions of bp, hit by
+// thread, so that they are returned with the debugger state.
+func (d *Debugger) breakpointActions(thread proc.Thread, bp *proc.Breakpoint, results []proc.BreakpointActionResult) {
+	gid := -1
+	if g, _ := proc.GetG(thread); g != nil {
+		gid = g.ID
+	}
+	for _, result := range results {
+		r := api.BreakpointActionResult{BreakpointID: bp.ID, GoroutineID: gid, Action: api.ConvertBreakpointAction(*result.Action)}
+		switch {
+		case result.Err != nil:
+			r.Err = result.Err.Error()
+		case result.Action.Kind == proc.EvalAction:
+			r.Variable = api.ConvertVar(result.Variable)
+		case result.Action.Kind == proc.StackAction:
+			stack, err := d.convertStacktrace(result.Stack, nil)
+			if err != nil {
+				r.Err = err.Error()
+			}
+			r.Stack = stack
+		}
+		d.breakpointActionResults = append(d.breakpointActionResults, r)
+	}
+}
+
+// takeBreakpointActionResults returns the breakpoint action results
+// recorded since it was last called.
+func (d *Debugger) takeBreakpointActionResults() []api.BreakpointActionResult {
+	results := d.breakpointActionResults
+	d.breakpointActionResults = nil
+	return results
+}
+
+func formatLogMessage(scope *proc.EvalScope, logMessage string) string {
+	var buf bytes.Buffer
+	for {
+		start := strings.Index(logMessage, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(logMessage[start:], "}")
+		if end < 0 {
+			break
+		}
+		end += start
+		buf.WriteString(logMessage[:start])
+		expr := logMessage[start+1 : end]
+		v, err := scope.EvalVariable(expr, proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1})
+		if err != nil {
+			fmt.Fprintf(&buf, "<eval error: %v>", err)
+		} else {
+			buf.WriteString(api.ConvertVar(v).SinglelineString())
+		}
+		logMessage = logMessage[end+1:]
+	}
+	buf.WriteString(logMessage)
+	return buf.String()
+}
+
 // ClearBreakpoint clears a breakpoint.
 func (d *Debugger) ClearBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoint, error) {
 	d.processMutex.Lock()
@@ -453,6 +1034,114 @@ func (d *Debugger) breakpoints() []*api.Breakpoint {
 	return bps
 }
 
+// InternalBreakpoints returns the internal breakpoints set by next, step
+// and stepout, and the one used to freeze goroutines.
+func (d *Debugger) InternalBreakpoints() []*api.Breakpoint {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	bps := []*api.Breakpoint{}
+	for _, bp := range d.target.Breakpoints().M {
+		if bp.IsInternal() {
+			bps = append(bps, api.ConvertInternalBreakpoint(bp))
+		}
+	}
+	return bps
+}
+
+// TakeStackRecords returns the stacktraces recorded by the breakpoint with
+// the given ID since the last call to TakeStackRecords.
+func (d *Debugger) TakeStackRecords(id int) ([]api.StackRecord, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	bp := d.findBreakpoint(id)
+	if bp == nil {
+		return nil, fmt.Errorf("no breakpoint with ID %d", id)
+	}
+	recs := bp.TakeStackRecords()
+	r := make([]api.StackRecord, len(recs))
+	for i := range recs {
+		r[i] = api.ConvertStackRecord(recs[i])
+	}
+	return r, nil
+}
+
+// SaveBreakpoints writes all user breakpoints to the file at path so that
+// they can be restored with LoadBreakpoints, possibly in a different
+// session.
+// Breakpoints are saved as file:line specifications together with their
+// name, condition and the information they retrieve, addresses and hit
+// counts are not saved.
+func (d *Debugger) SaveBreakpoints(path string) error {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	bps := []*api.Breakpoint{}
+	for _, bp := range d.breakpoints() {
+		if bp.ID < 0 || bp.File == "" {
+			continue
+		}
+		bp.ID = 0
+		bp.Addr = 0
+		bp.FunctionName = ""
+		bp.HitCount = nil
+		bp.TotalHitCount = 0
+		bps = append(bps, bp)
+	}
+
+	buf, err := json.MarshalIndent(bps, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf, 0644)
+}
+
+// LoadBreakpoints reads the breakpoints saved by SaveBreakpoints from the
+// file at path and sets them on the current process. Breakpoints are
+// resolved using their file:line specification, breakpoints that can not
+// be resolved, or that conflict with existing breakpoints, are discarded.
+func (d *Debugger) LoadBreakpoints(path string) ([]*api.Breakpoint, []api.DiscardedBreakpoint, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var saved []*api.Breakpoint
+	if err := json.Unmarshal(buf, &saved); err != nil {
+		return nil, nil, fmt.Errorf("could not read breakpoints from %s: %v", path, err)
+	}
+
+	created := []*api.Breakpoint{}
+	discarded := []api.DiscardedBreakpoint{}
+	for _, savedBp := range saved {
+		if savedBp.Name != "" && d.findBreakpointByName(savedBp.Name) != nil {
+			discarded = append(discarded, api.DiscardedBreakpoint{savedBp, "breakpoint name already exists"})
+			continue
+		}
+		addr, err := proc.FindFileLocation(d.target, savedBp.File, savedBp.Line)
+		if err != nil {
+			discarded = append(discarded, api.DiscardedBreakpoint{savedBp, err.Error()})
+			continue
+		}
+		bp, err := d.target.SetBreakpoint(addr, proc.UserBreakpoint, nil)
+		if err != nil {
+			discarded = append(discarded, api.DiscardedBreakpoint{savedBp, err.Error()})
+			continue
+		}
+		if err := copyBreakpointInfo(bp, savedBp); err != nil {
+			if _, err1 := d.target.ClearBreakpoint(bp.Addr); err1 != nil {
+				return nil, nil, fmt.Errorf("error while restoring breakpoint: %v, additionally the breakpoint could not be properly rolled back: %v", err, err1)
+			}
+			discarded = append(discarded, api.DiscardedBreakpoint{savedBp, err.Error()})
+			continue
+		}
+		created = append(created, api.ConvertBreakpoint(bp))
+	}
+	d.log.Infof("loaded %d breakpoints from %s", len(created), path)
+	return created, discarded, nil
+}
+
 // FindBreakpoint returns the breakpoint specified by 'id'.
 func (d *Debugger) FindBreakpoint(id int) *api.Breakpoint {
 	d.processMutex.Lock()
@@ -535,6 +1224,22 @@ func (d *Debugger) isRunning() bool {
 	return d.running
 }
 
+// StepIntoTargets returns the functions called by the current source line
+// that can be entered with the StepInto command.
+func (d *Debugger) StepIntoTargets() ([]api.StepIntoTarget, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	tgts, err := proc.StepIntoTargets(d.target)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.StepIntoTarget, len(tgts))
+	for i := range tgts {
+		r[i] = api.ConvertStepIntoTarget(tgts[i])
+	}
+	return r, nil
+}
+
 // Command handles commands which control the debugger lifecycle
 func (d *Debugger) Command(command *api.DebuggerCommand) (*api.DebuggerState, error) {
 	var err error
@@ -543,10 +1248,17 @@ func (d *Debugger) Command(command *api.DebuggerCommand) (*api.DebuggerState, er
 		// RequestManualStop does not invoke any ptrace syscalls, so it's safe to
 		// access the process directly.
 		d.log.Debug("halting")
+		d.runningMutex.Lock()
+		d.haltRequested = true
+		d.runningMutex.Unlock()
 		err = d.target.RequestManualStop()
 	}
 
 	withBreakpointInfo := true
+	var stepLocations []api.Location
+	stepGoroutineID := 0
+	var deadlock *proc.Deadlock
+	var samples []api.StackSample
 
 	d.processMutex.Lock()
 	defer d.processMutex.Unlock()
@@ -554,12 +1266,61 @@ func (d *Debugger) Command(command *api.DebuggerCommand) (*api.DebuggerState, er
 	d.setRunning(true)
 	defer d.setRunning(false)
 
+	if command.Name != api.SwitchThread && command.Name != api.SwitchGoroutine && command.Name != api.SwitchFrame {
+		d.handles.Reset()
+	}
+
+	if command.GoroutineID != 0 && isStepCommand(command.Name) {
+		// step functions set breakpoints conditioned on the selected
+		// goroutine, parked goroutines are stepped when they run again
+		if err := d.target.SwitchGoroutine(command.GoroutineID); err != nil {
+			return nil, err
+		}
+	}
+
+	if isStepCommand(command.Name) {
+		if selg := d.target.SelectedGoroutine(); selg != nil {
+			stepGoroutineID = selg.ID
+		}
+	}
+
 	switch command.Name {
 	case api.Continue:
 		d.log.Debug("continuing")
-		err = proc.Continue(d.target)
+		deadlock, err = d.continueDetectingDeadlocks()
+	case api.ContinueUntil:
+		d.log.Debugf("continuing until %s", command.Expr)
+		var pcs []uint64
+		pcs, err = d.findLocationPCs(command.Expr)
+		if err == nil {
+			err = proc.ContinueUntil(d.target, pcs)
+		}
+	case api.Jump:
+		d.log.Debugf("jumping to %s", command.Expr)
+		var pcs []uint64
+		pcs, err = d.findLocationPCs(command.Expr)
+		if err == nil {
+			if len(pcs) != 1 {
+				err = fmt.Errorf("location %q is ambiguous", command.Expr)
+			} else {
+				err = proc.Jump(d.target, pcs[0])
+			}
+		}
+		withBreakpointInfo = false
+	case api.ContinueToRecover:
+		d.log.Debug("continuing to recover point")
+		err = proc.ContinueToRecover(d.target)
+	case api.ContinueToGoroutine:
+		d.log.Debugf("continuing until goroutine %d runs", command.GoroutineID)
+		err = proc.ContinueToGoroutine(d.target, command.GoroutineID)
 	case api.Call:
 		d.log.Debugf("function call %s", command.Expr)
+		if command.GoroutineID != 0 {
+			err = proc.RunGoroutine(d.target, command.GoroutineID)
+			if err != nil {
+				break
+			}
+		}
 		err = proc.CallFunction(d.target, command.Expr, api.LoadConfigToProc(command.ReturnInfoLoadConfig))
 	case api.Rewind:
 		d.log.Debug("rewinding")
@@ -572,16 +1333,48 @@ func (d *Debugger) Command(command *api.DebuggerCommand) (*api.DebuggerState, er
 		err = proc.Continue(d.target)
 	case api.Next:
 		d.log.Debug("nexting")
-		err = proc.Next(d.target)
+		var defers proc.DeferMode
+		defers, err = parseDeferMode(command.DeferMode)
+		if err == nil {
+			stepLocations, err = d.repeatStep(command, func(p proc.Process) error { return proc.NextDefer(p, defers) })
+		}
 	case api.Step:
 		d.log.Debug("stepping")
-		err = proc.Step(d.target)
+		var defers proc.DeferMode
+		defers, err = parseDeferMode(command.DeferMode)
+		if err == nil {
+			stepLocations, err = d.repeatStep(command, func(p proc.Process) error { return proc.StepDefer(p, defers) })
+		}
+	case api.StepInto:
+		d.log.Debugf("stepping into call at %#x", command.CallPC)
+		err = proc.StepInto(d.target, command.CallPC)
+	case api.ReverseNext:
+		d.log.Debug("reverse nexting")
+		err = proc.ReverseNext(d.target)
+	case api.ReverseStep:
+		d.log.Debug("reverse stepping")
+		err = proc.ReverseStep(d.target)
+	case api.ReverseStepInstruction:
+		d.log.Debug("reverse single stepping")
+		err = proc.ReverseStepInstruction(d.target)
 	case api.StepInstruction:
 		d.log.Debug("single stepping")
-		err = d.target.StepInstruction()
+		if err = proc.SwitchFrame(d.target, 0); err != nil {
+			break
+		}
+		stepLocations, err = d.repeatStep(command, func(p proc.Process) error { return p.StepInstruction() })
+	case api.Until:
+		d.log.Debug("until")
+		err = proc.Until(d.target)
+	case api.NextInstruction:
+		d.log.Debug("next instruction")
+		err = proc.NextInstruction(d.target)
 	case api.StepOut:
 		d.log.Debug("step out")
 		err = proc.StepOut(d.target)
+	case api.StepToReturn:
+		d.log.Debug("step to return")
+		err = proc.StepToReturn(d.target)
 	case api.SwitchThread:
 		d.log.Debugf("switching to thread %d", command.ThreadID)
 		err = d.target.SwitchThread(command.ThreadID)
@@ -590,17 +1383,28 @@ func (d *Debugger) Command(command *api.DebuggerCommand) (*api.DebuggerState, er
 		d.log.Debugf("switching to goroutine %d", command.GoroutineID)
 		err = d.target.SwitchGoroutine(command.GoroutineID)
 		withBreakpointInfo = false
+	case api.SwitchFrame:
+		d.log.Debugf("switching to frame %d", command.Frame)
+		err = proc.SwitchFrame(d.target, command.Frame)
+		withBreakpointInfo = false
 	case api.Halt:
-		// RequestManualStop already called
+		// RequestManualStop already called, if the target was already stopped
+		// the request must not interrupt the next command.
+		d.target.CheckAndClearManualStopRequest()
 		withBreakpointInfo = false
+	case api.Sample:
+		d.log.Debugf("sampling stacks for %v", command.SampleDuration)
+		samples, err = d.sampleStacks(command.SampleDuration)
 	}
 
 	if err != nil {
-		if exitedErr, exited := err.(proc.ProcessExitedError); command.Name != api.SwitchGoroutine && command.Name != api.SwitchThread && exited {
+		if exitedErr, exited := err.(proc.ProcessExitedError); command.Name != api.SwitchGoroutine && command.Name != api.SwitchThread && command.Name != api.SwitchFrame && exited {
 			state := &api.DebuggerState{}
 			state.Exited = true
 			state.ExitStatus = exitedErr.Status
 			state.Err = errors.New(exitedErr.Error())
+			state.LogpointMessages = d.takeLogpointMessages()
+			state.BreakpointActionResults = d.takeBreakpointActionResults()
 			return state, nil
 		}
 		return nil, err
@@ -609,12 +1413,207 @@ func (d *Debugger) Command(command *api.DebuggerCommand) (*api.DebuggerState, er
 	if stateErr != nil {
 		return state, stateErr
 	}
+	d.evalWatchExpressions(state, command.Name != api.SwitchThread && command.Name != api.SwitchGoroutine && command.Name != api.SwitchFrame)
+	state.Steps = len(stepLocations)
+	state.StepLocations = stepLocations
+	state.Deadlock = d.convertDeadlock(deadlock)
+	state.Samples = samples
+	state.LogpointMessages = d.takeLogpointMessages()
+	state.BreakpointActionResults = d.takeBreakpointActionResults()
+	if stepGoroutineID != 0 && state.SelectedGoroutine != nil && state.SelectedGoroutine.ID != stepGoroutineID {
+		state.StepGoroutineID = stepGoroutineID
+	}
 	if withBreakpointInfo {
 		err = d.collectBreakpointInformation(state)
 	}
 	return state, err
 }
 
+// continueDetectingDeadlocks continues the target, see proc.Continue, and
+// returns a description of the deadlock that stopped it, if any. If
+// deadlock detection is enabled the target is periodically stopped to check
+// whether it is deadlocked and resumed if it isn't, otherwise only the
+// deadlocks detected by the runtime are reported.
+func (d *Debugger) continueDetectingDeadlocks() (*proc.Deadlock, error) {
+	if !d.deadlockDetection || d.target.Breakpoints().HasInternalBreakpoints() {
+		if err := proc.Continue(d.target); err != nil {
+			return nil, err
+		}
+		return d.runtimeDeadlock(), nil
+	}
+	var deadlock *proc.Deadlock
+	detected, err := d.continuePeriodically(deadlockCheckInterval, func() (bool, error) {
+		var err error
+		deadlock, err = proc.DetectDeadlock(d.target)
+		if err == nil && deadlock == nil {
+			d.log.Debug("no deadlock detected, continuing")
+		}
+		return deadlock != nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !detected {
+		return d.runtimeDeadlock(), nil
+	}
+	return deadlock, nil
+}
+
+// continuePeriodically continues the target, see proc.Continue, stopping it
+// every interval to call tick and resuming it until tick returns true.
+// Returns false if the target stopped for any other reason, for example
+// because a breakpoint was hit or a halt was requested.
+func (d *Debugger) continuePeriodically(interval time.Duration, tick func() (bool, error)) (bool, error) {
+	d.runningMutex.Lock()
+	d.haltRequested = false
+	d.runningMutex.Unlock()
+	for {
+		fired := make(chan struct{})
+		timer := time.AfterFunc(interval, func() {
+			d.target.RequestManualStop()
+			close(fired)
+		})
+		err := proc.Continue(d.target)
+		ticked := !timer.Stop()
+		if ticked {
+			<-fired
+			// the timer can fire after the target stopped for a different
+			// reason, the request must not interrupt the next command
+			d.target.CheckAndClearManualStopRequest()
+		}
+		if err != nil {
+			return false, err
+		}
+		d.runningMutex.Lock()
+		halted := d.haltRequested
+		d.runningMutex.Unlock()
+		if !ticked || halted || !d.target.Common().StoppedManually() {
+			return false, nil
+		}
+		if bpstate := d.target.CurrentThread().Breakpoint(); bpstate.Breakpoint != nil && bpstate.Active {
+			// a breakpoint was hit at the same time as the manual stop
+			return false, nil
+		}
+		done, err := tick()
+		if err != nil || done {
+			return done, err
+		}
+	}
+}
+
+// sampleStacks resumes the target for duration, stopping all its threads
+// every sampleInterval to record the stacks of the goroutines running on
+// them. Sampling ends early if the target stops for any other reason,
+// for example because a breakpoint was hit or a halt was requested.
+func (d *Debugger) sampleStacks(duration time.Duration) ([]api.StackSample, error) {
+	if d.target.Breakpoints().HasInternalBreakpoints() {
+		return nil, errors.New("can not sample stacks while next, step or stepout are in progress")
+	}
+	sampler := proc.NewStackSampler(sampleDepth)
+	deadline := time.Now().Add(duration)
+	_, err := d.continuePeriodically(sampleInterval, func() (bool, error) {
+		if err := sampler.Sample(d.target); err != nil {
+			return false, err
+		}
+		return !time.Now().Before(deadline), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.log.Debugf("%d stacks sampled", sampler.Total())
+	samples := sampler.Samples()
+	r := make([]api.StackSample, len(samples))
+	for i := range samples {
+		r[i].Count = samples[i].Count
+		r[i].Stack = make([]api.Location, len(samples[i].Stack))
+		for j := range samples[i].Stack {
+			r[i].Stack[j] = api.ConvertLocation(samples[i].Stack[j])
+		}
+	}
+	return r, nil
+}
+
+// runtimeDeadlock returns a description of the deadlock detected by the
+// runtime, if the target is stopped on the fatal throw breakpoint because
+// all goroutines are asleep.
+func (d *Debugger) runtimeDeadlock() *proc.Deadlock {
+	bpstate := d.target.CurrentThread().Breakpoint()
+	if bpstate.Breakpoint == nil || bpstate.Breakpoint.Name != proc.FatalThrow {
+		return nil
+	}
+	deadlock, _ := proc.DetectDeadlock(d.target)
+	return deadlock
+}
+
+// convertDeadlock converts dl to api.Deadlock.
+func (d *Debugger) convertDeadlock(dl *proc.Deadlock) *api.Deadlock {
+	if dl == nil {
+		return nil
+	}
+	gs := make([]*proc.G, len(dl.Goroutines))
+	for i := range dl.Goroutines {
+		gs[i] = dl.Goroutines[i].G
+	}
+	goroutines := d.convertGoroutines(gs)
+	r := &api.Deadlock{Goroutines: make([]api.BlockedGoroutine, len(dl.Goroutines))}
+	for i, bg := range dl.Goroutines {
+		r.Goroutines[i] = api.BlockedGoroutine{Goroutine: goroutines[i], Reason: bg.Reason, Chans: bg.Chans}
+	}
+	return r
+}
+
+// DetectDeadlock returns a description of the deadlock the target is in,
+// or nil if the target is not deadlocked, see proc.DetectDeadlock.
+func (d *Debugger) DetectDeadlock() (*api.Deadlock, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	deadlock, err := proc.DetectDeadlock(d.target)
+	if err != nil {
+		return nil, err
+	}
+	return d.convertDeadlock(deadlock), nil
+}
+
+// SetDeadlockDetection enables or disables deadlock detection: when it is
+// enabled the continue command periodically checks whether the target is
+// deadlocked and stops it if it is.
+func (d *Debugger) SetDeadlockDetection(enabled bool) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	d.deadlockDetection = enabled
+}
+
+// isStepCommand returns true if name is one of the commands that step
+// through the code of the selected goroutine.
+func isStepCommand(name string) bool {
+	switch name {
+	case api.Next, api.Step, api.StepInto, api.StepInstruction, api.NextInstruction, api.StepOut, api.StepToReturn, api.Until, api.ReverseNext, api.ReverseStep, api.ReverseStepInstruction:
+		return true
+	}
+	return false
+}
+
+// repeatStep calls step command.Count times, see proc.RepeatStep. It
+// returns the location of the current thread after each completed step, or
+// nil if command.Count is not greater than one.
+func (d *Debugger) repeatStep(command *api.DebuggerCommand, step func(proc.Process) error) ([]api.Location, error) {
+	if command.Count <= 1 {
+		return nil, step(d.target)
+	}
+	locs := make([]api.Location, 0, command.Count)
+	_, err := proc.RepeatStep(d.target, command.Count, step, func(n int) {
+		d.log.Debugf("%s: completed step %d of %d", command.Name, n, command.Count)
+		loc, err := d.target.CurrentThread().Location()
+		if err != nil {
+			// keep one entry per completed step
+			locs = append(locs, api.Location{})
+			return
+		}
+		locs = append(locs, api.ConvertLocation(*loc))
+	})
+	return locs, err
+}
+
 func (d *Debugger) collectBreakpointInformation(state *api.DebuggerState) error {
 	if state == nil {
 		return nil
@@ -653,6 +1652,15 @@ func (d *Debugger) collectBreakpointInformation(state *api.DebuggerState) error
 			return fmt.Errorf("could not find thread %d", state.Threads[i].ID)
 		}
 
+		if bp.Name == proc.UnrecoveredPanic || bp.Name == proc.FatalThrow {
+			if frames, err := proc.ThreadStacktrace(thread, 50); err == nil {
+				if idx := proc.PanicFrame(frames); idx >= 0 {
+					loc := api.ConvertLocation(frames[idx].Call)
+					bpi.PanicLocation = &loc
+				}
+			}
+		}
+
 		if len(bp.Variables) == 0 && bp.LoadArgs == nil && bp.LoadLocals == nil {
 			// don't try to create goroutine scope if there is nothing to load
 			continue
@@ -667,7 +1675,7 @@ func (d *Debugger) collectBreakpointInformation(state *api.DebuggerState) error
 			bpi.Variables = make([]api.Variable, len(bp.Variables))
 		}
 		for i := range bp.Variables {
-			v, err := s.EvalVariable(bp.Variables[i], proc.LoadConfig{true, 1, 64, 64, -1})
+			v, err := s.EvalVariable(bp.Variables[i], proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1})
 			if err != nil {
 				bpi.Variables[i] = api.Variable{Name: bp.Variables[i], Unreadable: fmt.Sprintf("eval error: %v", err)}
 			} else {
@@ -756,8 +1764,10 @@ func regexFilterFuncs(filter string, allFuncs []proc.Function) ([]string, error)
 }
 
 // PackageVariables returns a list of package variables for the thread,
-// optionally regexp filtered using regexp described in 'filter'.
-func (d *Debugger) PackageVariables(threadID int, filter string, cfg proc.LoadConfig) ([]api.Variable, error) {
+// optionally regexp filtered using regexp described in 'filter' and
+// restricted to the package with path pkg, if it is not empty. If cfg is
+// nil the values of the variables are not loaded.
+func (d *Debugger) PackageVariables(threadID int, pkg, filter string, cfg *proc.LoadConfig) ([]api.Variable, error) {
 	d.processMutex.Lock()
 	defer d.processMutex.Unlock()
 
@@ -775,14 +1785,12 @@ func (d *Debugger) PackageVariables(threadID int, filter string, cfg proc.LoadCo
 	if err != nil {
 		return nil, err
 	}
-	pv, err := scope.PackageVariables(cfg)
+	pv, err := scope.FilterPackageVariables(pkg, regex, cfg)
 	if err != nil {
 		return nil, err
 	}
 	for _, v := range pv {
-		if regex.Match([]byte(v.Name)) {
-			vars = append(vars, *api.ConvertVar(v))
-		}
+		vars = append(vars, *api.ConvertVar(v))
 	}
 	return vars, err
 }
@@ -819,7 +1827,7 @@ func (d *Debugger) LocalVariables(scope api.EvalScope, cfg proc.LoadConfig) ([]a
 	d.processMutex.Lock()
 	defer d.processMutex.Unlock()
 
-	s, err := proc.ConvertEvalScope(d.target, scope.GoroutineID, scope.Frame)
+	s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
 	if err != nil {
 		return nil, err
 	}
@@ -827,15 +1835,38 @@ func (d *Debugger) LocalVariables(scope api.EvalScope, cfg proc.LoadConfig) ([]a
 	if err != nil {
 		return nil, err
 	}
+	d.registerVars(pv)
 	return convertVars(pv), err
 }
 
+// LocalInstances returns every declaration of the local variable or
+// argument called name in the function of scope, see
+// proc.EvalScope.LocalInstances.
+func (d *Debugger) LocalInstances(scope api.EvalScope, name string, cfg proc.LoadConfig) ([]api.VariableInstance, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
+	if err != nil {
+		return nil, err
+	}
+	pv, err := s.LocalInstances(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.VariableInstance, len(pv))
+	for i := range pv {
+		r[i] = api.VariableInstance{Variable: *api.ConvertVar(pv[i].Variable), Ranges: pv[i].Ranges, InScope: pv[i].InScope}
+	}
+	return r, nil
+}
+
 // FunctionArguments returns the arguments to the current function.
 func (d *Debugger) FunctionArguments(scope api.EvalScope, cfg proc.LoadConfig) ([]api.Variable, error) {
 	d.processMutex.Lock()
 	defer d.processMutex.Unlock()
 
-	s, err := proc.ConvertEvalScope(d.target, scope.GoroutineID, scope.Frame)
+	s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
 	if err != nil {
 		return nil, err
 	}
@@ -843,16 +1874,33 @@ func (d *Debugger) FunctionArguments(scope api.EvalScope, cfg proc.LoadConfig) (
 	if err != nil {
 		return nil, err
 	}
+	d.registerVars(pv)
 	return convertVars(pv), nil
 }
 
 // EvalVariableInScope will attempt to evaluate the variable represented by 'symbol'
-// in the scope provided.
+// in the scope provided. Expressions evaluated in the topmost frame of the
+// selected goroutine can call functions, see proc.EvalExpressionWithCalls.
 func (d *Debugger) EvalVariableInScope(scope api.EvalScope, symbol string, cfg proc.LoadConfig) (*api.Variable, error) {
 	d.processMutex.Lock()
 	defer d.processMutex.Unlock()
 
-	s, err := proc.ConvertEvalScope(d.target, scope.GoroutineID, scope.Frame)
+	if selg := d.target.SelectedGoroutine(); scope.Frame == 0 && scope.DeferredCall == 0 && (scope.GoroutineID == -1 || (selg != nil && selg.ID == scope.GoroutineID)) {
+		// function calls can only be injected on the selected goroutine
+		d.setRunning(true)
+		v, resumed, err := proc.EvalExpressionWithCalls(d.target, symbol, cfg)
+		d.setRunning(false)
+		if resumed {
+			d.handles.Reset()
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.handles.Register(v)
+		return api.ConvertVar(v), nil
+	}
+
+	s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
 	if err != nil {
 		return nil, err
 	}
@@ -860,16 +1908,260 @@ func (d *Debugger) EvalVariableInScope(scope api.EvalScope, symbol string, cfg p
 	if err != nil {
 		return nil, err
 	}
+	d.handles.Register(v)
 	return api.ConvertVar(v), err
 }
 
+// ExpandVariable loads the children of the variable with the given handle,
+// returned by a previous evaluation because they were not loaded with the
+// configuration it used. See proc.VariableHandles.
+func (d *Debugger) ExpandVariable(handle int, cfg proc.LoadConfig) (*api.Variable, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	v, err := d.handles.Expand(handle, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return api.ConvertVar(v), nil
+}
+
+func (d *Debugger) registerVars(pv []*proc.Variable) {
+	for _, v := range pv {
+		d.handles.Register(v)
+	}
+}
+
+// EvalVariableChildren evaluates expr, a map, a slice or an array, in the
+// given scope, loading limit of its children starting from offset. See
+// proc.EvalExpressionChildren.
+func (d *Debugger) EvalVariableChildren(scope api.EvalScope, expr string, offset, limit int, cfg proc.LoadConfig) (*api.Variable, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.EvalExpressionChildren(expr, offset, limit, cfg)
+	if err != nil {
+		return nil, err
+	}
+	d.handles.Register(v)
+	return api.ConvertVar(v), nil
+}
+
+// SaveVariableSnapshot saves the value of expr in the given scope, loaded
+// maxDepth levels deep, DiffVariables can compare it with the value of
+// expr at a later stop.
+func (d *Debugger) SaveVariableSnapshot(scope api.EvalScope, expr string, maxDepth int) error {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	v, err := d.evalDiffOperand(scope, expr, maxDepth)
+	if err != nil {
+		return err
+	}
+	if d.snapshots == nil {
+		d.snapshots = make(map[string]*proc.Variable)
+	}
+	d.snapshots[expr] = v
+	return nil
+}
+
+// DiffVariables compares the values of expr1 and expr2 in the given scope,
+// up to maxDepth levels deep, and returns their differences, see
+// proc.DiffVariables. If expr2 is empty the value of expr1 is compared
+// with the value saved by the last call to SaveVariableSnapshot for expr1.
+func (d *Debugger) DiffVariables(scope api.EvalScope, expr1, expr2 string, maxDepth int) ([]api.VariableDifference, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	if maxDepth <= 0 {
+		maxDepth = defaultDiffDepth
+	}
+	var a, b *proc.Variable
+	var err error
+	if expr2 == "" {
+		a = d.snapshots[expr1]
+		if a == nil {
+			return nil, fmt.Errorf("no snapshot of %s", expr1)
+		}
+		expr2 = expr1
+	} else if a, err = d.evalDiffOperand(scope, expr1, maxDepth); err != nil {
+		return nil, err
+	}
+	if b, err = d.evalDiffOperand(scope, expr2, maxDepth); err != nil {
+		return nil, err
+	}
+	diffs := proc.DiffVariables(a, b, maxDepth)
+	r := make([]api.VariableDifference, len(diffs))
+	for i := range diffs {
+		r[i] = api.ConvertVariableDifference(diffs[i])
+	}
+	return r, nil
+}
+
+// ExportVariable evaluates expr in the given scope, following pointers up
+// to maxDepth levels deep, and returns its value written as a Go
+// expression or, if asJSON is set, as a JSON document. See
+// proc.Variable.GoLiteral and proc.Variable.JSON.
+func (d *Debugger) ExportVariable(scope api.EvalScope, expr string, asJSON bool, maxDepth int) (string, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	if maxDepth <= 0 {
+		maxDepth = defaultExportDepth
+	}
+	s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
+	if err != nil {
+		return "", err
+	}
+	v, err := s.EvalVariable(expr, proc.ExportLoadConfig(maxDepth))
+	if err != nil {
+		return "", err
+	}
+	if v.Unreadable != nil {
+		return "", v.Unreadable
+	}
+	if asJSON {
+		return string(v.JSON()), nil
+	}
+	return v.GoLiteral(), nil
+}
+
+// Traverse visits the nodes of the linked data structure starting at expr,
+// following the pointers in fields, see proc.EvalScope.Traverse. The
+// second return value is true if more than maxNodes nodes are reachable.
+func (d *Debugger) Traverse(scope api.EvalScope, expr string, fields []string, maxNodes int, cfg proc.LoadConfig) ([]api.TraversalNode, bool, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	if maxNodes <= 0 {
+		maxNodes = defaultTraverseNodes
+	}
+	s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
+	if err != nil {
+		return nil, false, err
+	}
+	nodes, truncated, err := s.Traverse(expr, fields, maxNodes, cfg)
+	if err != nil {
+		return nil, false, err
+	}
+	r := make([]api.TraversalNode, len(nodes))
+	for i := range nodes {
+		r[i] = api.ConvertTraversalNode(nodes[i])
+	}
+	return r, truncated, nil
+}
+
+func (d *Debugger) evalDiffOperand(scope api.EvalScope, expr string, maxDepth int) (*proc.Variable, error) {
+	if maxDepth <= 0 {
+		maxDepth = defaultDiffDepth
+	}
+	s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.EvalVariable(expr, proc.DiffLoadConfig(maxDepth))
+	if err != nil {
+		return nil, err
+	}
+	if v.Unreadable != nil {
+		return nil, v.Unreadable
+	}
+	return v, nil
+}
+
+// Waiters returns the goroutines blocked on the channel or mutex that
+// expr evaluates to, in the given scope. See proc.Waiters.
+func (d *Debugger) Waiters(scope api.EvalScope, expr string) ([]api.Waiter, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.EvalVariable(expr, proc.LoadConfig{})
+	if err != nil {
+		return nil, err
+	}
+	waiters, err := proc.Waiters(d.target, v)
+	if err != nil {
+		return nil, err
+	}
+	gs := make([]*proc.G, len(waiters))
+	for i := range waiters {
+		gs[i] = waiters[i].G
+	}
+	goroutines := d.convertGoroutines(gs)
+	r := make([]api.Waiter, len(waiters))
+	for i := range waiters {
+		r[i] = api.Waiter{Goroutine: goroutines[i], Op: waiters[i].Op}
+	}
+	return r, nil
+}
+
+// MutexInfo returns the state of the sync.Mutex expr evaluates to, in the
+// given scope, and the goroutines that could be holding it. If expr is
+// empty the mutex the goroutine of scope is blocked on is used.
+func (d *Debugger) MutexInfo(scope api.EvalScope, expr string) (*api.MutexState, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	var v *proc.Variable
+	if expr == "" {
+		g, err := proc.FindGoroutine(d.target, scope.GoroutineID)
+		if err != nil {
+			return nil, err
+		}
+		if g == nil {
+			return nil, errors.New("no goroutine selected")
+		}
+		v, err = proc.BlockingMutex(d.target, g)
+		if err != nil {
+			return nil, fmt.Errorf("goroutine %d: %v", g.ID, err)
+		}
+	} else {
+		s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
+		if err != nil {
+			return nil, err
+		}
+		v, err = s.EvalVariable(expr, proc.LoadConfig{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	ms, err := proc.MutexInfo(d.target, v)
+	if err != nil {
+		return nil, err
+	}
+	return &api.MutexState{
+		Addr:     ms.Addr,
+		Locked:   ms.Locked,
+		Woken:    ms.Woken,
+		Starving: ms.Starving,
+		Waiters:  ms.Waiters,
+		Holders:  d.convertGoroutines(ms.Holders),
+	}, nil
+}
+
 // SetVariableInScope will set the value of the variable represented by
 // 'symbol' to the value given, in the given scope.
 func (d *Debugger) SetVariableInScope(scope api.EvalScope, symbol, value string) error {
 	d.processMutex.Lock()
 	defer d.processMutex.Unlock()
 
-	s, err := proc.ConvertEvalScope(d.target, scope.GoroutineID, scope.Frame)
+	if selg := d.target.SelectedGoroutine(); scope.Frame == 0 && scope.DeferredCall == 0 && (scope.GoroutineID == -1 || (selg != nil && selg.ID == scope.GoroutineID)) {
+		// new map keys are allocated by calling the runtime on the selected
+		// goroutine
+		d.setRunning(true)
+		defer d.setRunning(false)
+		return proc.SetVariableWithCalls(d.target, symbol, value)
+	}
+
+	s, err := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
 	if err != nil {
 		return err
 	}
@@ -881,21 +2173,153 @@ func (d *Debugger) Goroutines() ([]*api.Goroutine, error) {
 	d.processMutex.Lock()
 	defer d.processMutex.Unlock()
 
-	goroutines := []*api.Goroutine{}
 	gs, err := proc.GoroutinesInfo(d.target)
 	if err != nil {
 		return nil, err
 	}
+	return d.convertGoroutines(gs), nil
+}
+
+// MarkGoroutines records the goroutines that currently exist as the
+// baseline GoroutineDiff compares against, it returns their number.
+func (d *Debugger) MarkGoroutines() (int, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	gs, err := proc.GoroutinesInfo(d.target)
+	if err != nil {
+		return 0, err
+	}
+	d.goroutineBaseline = make(map[int]*api.Goroutine, len(gs))
+	for _, g := range d.convertGoroutines(gs) {
+		d.goroutineBaseline[g.ID] = g
+	}
+	return len(d.goroutineBaseline), nil
+}
+
+// GoroutineDiff returns the goroutines created and the goroutines that
+// exited since the last call to MarkGoroutines. Exited goroutines are
+// described as they were when MarkGoroutines was called.
+func (d *Debugger) GoroutineDiff() (*api.GoroutineDiff, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	if d.goroutineBaseline == nil {
+		return nil, errors.New("no goroutine baseline, call MarkGoroutines first")
+	}
+	gs, err := proc.GoroutinesInfo(d.target)
+	if err != nil {
+		return nil, err
+	}
+	diff := &api.GoroutineDiff{Created: []*api.Goroutine{}, Exited: []*api.Goroutine{}}
+	alive := make(map[int]bool, len(gs))
+	for _, g := range d.convertGoroutines(gs) {
+		alive[g.ID] = true
+		if d.goroutineBaseline[g.ID] == nil {
+			diff.Created = append(diff.Created, g)
+		}
+	}
+	for id, g := range d.goroutineBaseline {
+		if !alive[id] {
+			diff.Exited = append(diff.Exited, g)
+		}
+	}
+	sort.Slice(diff.Created, func(i, j int) bool { return diff.Created[i].ID < diff.Created[j].ID })
+	sort.Slice(diff.Exited, func(i, j int) bool { return diff.Exited[i].ID < diff.Exited[j].ID })
+	return diff, nil
+}
+
+// convertGoroutines converts gs to api.Goroutine, filling in how long each
+// goroutine has been waiting when the clock of the target is known.
+func (d *Debugger) convertGoroutines(gs []*proc.G) []*api.Goroutine {
+	now, nowok := int64(0), false
+	if recorded, _ := d.target.Recorded(); !recorded && d.config.CoreFile == "" {
+		now, nowok = monotonicTime()
+	}
+	goroutines := make([]*api.Goroutine, 0, len(gs))
 	for _, g := range gs {
-		goroutines = append(goroutines, api.ConvertGoroutine(g))
+		ag := api.ConvertGoroutine(g)
+		if nowok {
+			ag.WaitDuration = g.WaitDuration(now)
+		}
+		goroutines = append(goroutines, ag)
+	}
+	return goroutines
+}
+
+// FilterGoroutines returns the goroutines matching filter, see
+// proc.FilterGoroutines for the meaning of start and count and of the
+// second return value.
+func (d *Debugger) FilterGoroutines(filter api.GoroutineFilter, start, count int) ([]*api.Goroutine, int, error) {
+	pfilter, err := convertGoroutineFilter(filter)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	gs, nextg, err := proc.FilterGoroutines(d.target, pfilter, start, count)
+	if err != nil {
+		return nil, -1, err
 	}
-	return goroutines, err
+	return d.convertGoroutines(gs), nextg, nil
+}
+
+// GroupGoroutines groups the goroutines matching filter by groupBy, one of
+// api.GroupByCurrentFunc, api.GroupByUserFunc and api.GroupByWaitReason.
+// Each group contains at most maxIDs goroutine IDs.
+func (d *Debugger) GroupGoroutines(filter api.GoroutineFilter, groupBy string, maxIDs int) ([]api.GoroutineGroup, error) {
+	var pgroupBy proc.GoroutineGroupBy
+	switch groupBy {
+	case api.GroupByCurrentFunc:
+		pgroupBy = proc.GroupByCurrentFunc
+	case api.GroupByUserFunc, "":
+		pgroupBy = proc.GroupByUserFunc
+	case api.GroupByWaitReason:
+		pgroupBy = proc.GroupByWaitReason
+	default:
+		return nil, fmt.Errorf("unknown goroutine grouping %q", groupBy)
+	}
+	pfilter, err := convertGoroutineFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	groups, err := proc.GroupGoroutines(d.target, pfilter, pgroupBy, maxIDs)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.GoroutineGroup, len(groups))
+	for i := range groups {
+		r[i] = api.GoroutineGroup{Name: groups[i].Name, Count: groups[i].Count, GoroutineIDs: groups[i].IDs}
+	}
+	return r, nil
+}
+
+// convertGoroutineFilter converts an api.GoroutineFilter into a
+// proc.GoroutineFilter.
+func convertGoroutineFilter(filter api.GoroutineFilter) (proc.GoroutineFilter, error) {
+	pfilter := proc.GoroutineFilter{Function: filter.Function, Labels: filter.Labels, ExcludeSystem: !filter.IncludeSystem}
+	for _, state := range filter.States {
+		status, err := proc.GoroutineStatusByName(state)
+		if err != nil {
+			return proc.GoroutineFilter{}, err
+		}
+		pfilter.States = append(pfilter.States, status)
+	}
+	return pfilter, nil
 }
 
 // Stacktrace returns a list of Stackframes for the given goroutine. The
 // length of the returned list will be min(stack_len, depth).
 // If 'full' is true, then local vars, function args, etc will be returned as well.
-func (d *Debugger) Stacktrace(goroutineID, depth int, readDefers bool, cfg *proc.LoadConfig) ([]api.Stackframe, error) {
+// If systemStack is true all the frames of the system stack the goroutine
+// is executing on are returned, see proc.G.SystemStacktrace.
+func (d *Debugger) Stacktrace(goroutineID, depth int, readDefers, systemStack bool, cfg *proc.LoadConfig) ([]api.Stackframe, error) {
 	d.processMutex.Lock()
 	defer d.processMutex.Unlock()
 
@@ -910,9 +2334,12 @@ func (d *Debugger) Stacktrace(goroutineID, depth int, readDefers bool, cfg *proc
 		return nil, err
 	}
 
-	if g == nil {
+	switch {
+	case g == nil:
 		rawlocs, err = proc.ThreadStacktrace(d.target.CurrentThread(), depth)
-	} else {
+	case systemStack:
+		rawlocs, err = g.SystemStacktrace(depth, readDefers)
+	default:
 		rawlocs, err = g.Stacktrace(depth, readDefers)
 	}
 	if err != nil {
@@ -922,6 +2349,66 @@ func (d *Debugger) Stacktrace(goroutineID, depth int, readDefers bool, cfg *proc
 	return d.convertStacktrace(rawlocs, cfg)
 }
 
+// Stacktraces returns the stacktraces, up to depth frames, of all the
+// goroutines matching filter.
+func (d *Debugger) Stacktraces(filter api.GoroutineFilter, depth int, readDefers bool, cfg *proc.LoadConfig) ([]api.GoroutineStacktrace, error) {
+	pfilter, err := convertGoroutineFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	stacks, err := proc.Stacktraces(d.target, pfilter, depth, readDefers)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.GoroutineStacktrace, len(stacks))
+	for i := range stacks {
+		r[i].GoroutineID = stacks[i].G.ID
+		err := stacks[i].Err
+		if err == nil {
+			r[i].Stacktrace, err = d.convertStacktrace(stacks[i].Frames, cfg)
+		}
+		if err != nil {
+			r[i].Err = err.Error()
+		}
+	}
+	return r, nil
+}
+
+// Ancestors returns at most numAncestors ancestors of the goroutine
+// goroutineID, with stacks of at most depth frames.
+func (d *Debugger) Ancestors(goroutineID, numAncestors, depth int) ([]api.Ancestor, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+
+	g, err := proc.FindGoroutine(d.target, goroutineID)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, errors.New("no selected goroutine")
+	}
+	ancestors, err := g.Ancestors(numAncestors)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.Ancestor, len(ancestors))
+	for i := range ancestors {
+		r[i].ID = ancestors[i].ID
+		frames, err := ancestors[i].Stack(depth)
+		if err == nil {
+			r[i].Stack, err = d.convertStacktrace(frames, nil)
+		}
+		if err != nil {
+			r[i].Unreadable = err.Error()
+		}
+	}
+	return r, nil
+}
+
 func (d *Debugger) convertStacktrace(rawlocs []proc.Stackframe, cfg *proc.LoadConfig) ([]api.Stackframe, error) {
 	locations := make([]api.Stackframe, 0, len(rawlocs))
 	for i := range rawlocs {
@@ -932,6 +2419,8 @@ func (d *Debugger) convertStacktrace(rawlocs []proc.Stackframe, cfg *proc.LoadCo
 			FramePointerOffset: rawlocs[i].FramePointerOffset(),
 
 			Defers: d.convertDefers(rawlocs[i].Defers),
+
+			SystemStack: rawlocs[i].SystemStack,
 		}
 		if rawlocs[i].Err != nil {
 			frame.Err = rawlocs[i].Err.Error()
@@ -1001,7 +2490,7 @@ func (d *Debugger) FindLocation(scope api.EvalScope, locStr string) ([]api.Locat
 		return nil, err
 	}
 
-	s, _ := proc.ConvertEvalScope(d.target, scope.GoroutineID, scope.Frame)
+	s, _ := proc.ConvertEvalScopeDeferred(d.target, scope.GoroutineID, scope.Frame, scope.DeferredCall)
 
 	locs, err := loc.Find(d, s, locStr)
 	for i := range locs {
@@ -1013,6 +2502,49 @@ func (d *Debugger) FindLocation(scope api.EvalScope, locStr string) ([]api.Locat
 	return locs, err
 }
 
+// parseDeferMode parses the DeferMode field of api.DebuggerCommand.
+// locationNotFound returns err, the error returned by a failed location
+// lookup, with a note explaining that the code of plugins and shared
+// libraries can not be found, if the target loaded any.
+func (d *Debugger) locationNotFound(err error) error {
+	if !proc.HasDynamicModules(d.target) {
+		return err
+	}
+	return fmt.Errorf("%s (the program loaded plugins or shared libraries, their code can not be found because only the debug information of the executable is read)", strings.TrimSpace(err.Error()))
+}
+
+func parseDeferMode(mode string) (proc.DeferMode, error) {
+	switch mode {
+	case "", "stop":
+		return proc.DeferStop, nil
+	case "skip":
+		return proc.DeferSkip, nil
+	case "entry":
+		return proc.DeferStopAtEntry, nil
+	default:
+		return proc.DeferStop, fmt.Errorf("unknown defer mode %q", mode)
+	}
+}
+
+// findLocationPCs returns the addresses of the location specified by
+// locStr, in the scope of the current goroutine.
+func (d *Debugger) findLocationPCs(locStr string) ([]uint64, error) {
+	loc, err := parseLocationSpec(locStr)
+	if err != nil {
+		return nil, err
+	}
+	s, _ := proc.SelectedScope(d.target)
+	locs, err := loc.Find(d, s, locStr)
+	if err != nil {
+		return nil, err
+	}
+	pcs := make([]uint64, len(locs))
+	for i := range locs {
+		pcs[i] = locs[i].PC
+	}
+	return pcs, nil
+}
+
 // Disassemble code between startPC and endPC
 // if endPC == 0 it will find the function containing startPC and disassemble the whole function
 func (d *Debugger) Disassemble(scope api.EvalScope, startPC, endPC uint64, flavour api.AssemblyFlavour) (api.AsmInstructions, error) {
@@ -1083,6 +2615,68 @@ func (d *Debugger) ClearCheckpoint(id int) error {
 	return d.target.ClearCheckpoint(id)
 }
 
+// FreezeGoroutine freezes, or thaws if thaw is true, the goroutine
+// goroutineID and returns the list of frozen goroutines.
+func (d *Debugger) FreezeGoroutine(goroutineID int, thaw bool) ([]int, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	var err error
+	if thaw {
+		err = proc.ThawGoroutine(d.target, goroutineID)
+	} else {
+		err = proc.FreezeGoroutine(d.target, goroutineID)
+	}
+	return d.target.Common().FrozenGoroutines(), err
+}
+
+// FrozenGoroutines returns the list of frozen goroutines.
+func (d *Debugger) FrozenGoroutines() []int {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	return d.target.Common().FrozenGoroutines()
+}
+
+// SetSchedHistory enables or disables the recording of the scheduling
+// history of the target.
+func (d *Debugger) SetSchedHistory(enabled bool) error {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	if err := proc.RecordSchedHistory(d.target, enabled); err != nil {
+		return err
+	}
+	d.schedHistory = enabled
+	return nil
+}
+
+// SchedHistory returns the scheduling history recorded since the target
+// was last resumed.
+func (d *Debugger) SchedHistory() []api.SchedEvent {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	events := d.target.Common().SchedHistory()
+	r := make([]api.SchedEvent, len(events))
+	for i := range events {
+		r[i] = api.SchedEvent{GoroutineID: events[i].GoroutineID, ThreadID: events[i].ThreadID}
+	}
+	return r
+}
+
+// SeekEvent moves the recording to the start of event and returns the new
+// state of the target.
+func (d *Debugger) SeekEvent(event int64) (*api.DebuggerState, error) {
+	d.processMutex.Lock()
+	defer d.processMutex.Unlock()
+	if err := proc.SeekEvent(d.target, event); err != nil {
+		return nil, err
+	}
+	d.handles.Reset()
+	state, err := d.state(nil)
+	if err == nil {
+		d.evalWatchExpressions(state, true)
+	}
+	return state, err
+}
+
 func go11DecodeErrorCheck(err error) error {
 	if _, isdecodeerr := err.(dwarf.DecodeError); !isdecodeerr {
 		return err
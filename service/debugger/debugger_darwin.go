@@ -13,3 +13,9 @@ func attachErrorMessage(pid int, err error) error {
 func stopProcess(pid int) error {
 	return sys.Kill(pid, sys.SIGSTOP)
 }
+
+// monotonicTime returns the current value of the clock used by
+// runtime.nanotime, it is not supported on this platform.
+func monotonicTime() (int64, bool) {
+	return 0, false
+}
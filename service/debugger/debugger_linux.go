@@ -34,3 +34,13 @@ func attachErrorMessage(pid int, err error) error {
 func stopProcess(pid int) error {
 	return sys.Kill(pid, sys.SIGSTOP)
 }
+
+// monotonicTime returns the current value of the clock used by
+// runtime.nanotime.
+func monotonicTime() (int64, bool) {
+	var ts sys.Timespec
+	if err := sys.ClockGettime(sys.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0, false
+	}
+	return ts.Nano(), true
+}
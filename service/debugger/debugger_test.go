@@ -0,0 +1,45 @@
+package debugger
+
+import (
+	"go/constant"
+	"reflect"
+	"testing"
+
+	"github.com/derekparker/delve/pkg/proc"
+)
+
+func TestWatchValuesEqual(t *testing.T) {
+	intv := func(n int64) *proc.Variable {
+		return &proc.Variable{Kind: reflect.Int, Value: constant.MakeInt64(n)}
+	}
+	ptrv := func(addr uintptr, n int64) *proc.Variable {
+		child := intv(n)
+		child.Addr = addr
+		return &proc.Variable{Kind: reflect.Ptr, Children: []proc.Variable{*child}}
+	}
+	testcases := []struct {
+		a, b  *proc.Variable
+		equal bool
+		delta string
+	}{
+		{intv(1), intv(1), true, "0"},
+		{intv(1), intv(4), false, "3"},
+		{intv(4), intv(1), false, "-3"},
+		{ptrv(0x100, 1), ptrv(0x100, 1), true, ""},
+		{ptrv(0x100, 1), ptrv(0x100, 2), false, ""},
+		{ptrv(0x100, 1), ptrv(0x200, 1), false, ""},
+		{&proc.Variable{Kind: reflect.String, Value: constant.MakeString("a")}, &proc.Variable{Kind: reflect.String, Value: constant.MakeString("b")}, false, ""},
+	}
+	for i, tc := range testcases {
+		if equal := watchValuesEqual(tc.a, tc.b); equal != tc.equal {
+			t.Errorf("%d: expected equal=%v got %v", i, tc.equal, equal)
+		}
+		delta := ""
+		if d := watchDelta(tc.a, tc.b); d != nil {
+			delta = d.String()
+		}
+		if delta != tc.delta {
+			t.Errorf("%d: expected delta %q got %q", i, tc.delta, delta)
+		}
+	}
+}
@@ -14,3 +14,9 @@ func stopProcess(pid int) error {
 	// the process.
 	return nil
 }
+
+// monotonicTime returns the current value of the clock used by
+// runtime.nanotime, it is not supported on this platform.
+func monotonicTime() (int64, bool) {
+	return 0, false
+}
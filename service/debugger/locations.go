@@ -23,6 +23,15 @@ type NormalLocationSpec struct {
 	Base       string
 	FuncBase   *FuncLocationSpec
 	LineOffset int
+	// Column is the column of the statement on line LineOffset, 0 if no
+	// column was specified.
+	Column int
+}
+
+// ReturnsLocationSpec is the location of every return instruction of the
+// function containing the location specified by Base.
+type ReturnsLocationSpec struct {
+	Base *NormalLocationSpec
 }
 
 type RegexLocationSpec struct {
@@ -90,12 +99,34 @@ func parseLocationSpec(locStr string) (LocationSpec, error) {
 	}
 }
 
+// returnsSuffix is appended to a function location to request all its
+// return instructions.
+const returnsSuffix = ":return"
+
 func parseLocationSpecDefault(locStr, rest string) (LocationSpec, error) {
 	malformed := func(reason string) error {
 		return fmt.Errorf("Malformed breakpoint location \"%s\" at %d: %s", locStr, len(locStr)-len(rest), reason)
 	}
 
+	if strings.HasSuffix(rest, returnsSuffix) {
+		base, err := parseLocationSpecDefault(locStr, rest[:len(rest)-len(returnsSuffix)])
+		if err != nil {
+			return nil, err
+		}
+		nls, ok := base.(*NormalLocationSpec)
+		if !ok {
+			return nil, malformed("return locations must specify a function")
+		}
+		return &ReturnsLocationSpec{nls}, nil
+	}
+
 	v := strings.Split(rest, ":")
+	column := ""
+	if len(v) > 2 && isNumber(v[len(v)-1]) && isNumber(v[len(v)-2]) {
+		// file:line:column
+		column = v[len(v)-1]
+		v = v[:len(v)-1]
+	}
 	if len(v) > 2 {
 		// On Windows, path may contain ":", so split only on last ":"
 		v = []string{strings.Join(v[0:len(v)-1], ":"), v[len(v)-1]}
@@ -126,9 +157,21 @@ func parseLocationSpecDefault(locStr, rest string) (LocationSpec, error) {
 		return nil, malformed("line offset negative or not a number")
 	}
 
+	if column != "" {
+		spec.Column, err = strconv.Atoi(column)
+		if err != nil || spec.Column <= 0 {
+			return nil, malformed("column not a positive number")
+		}
+	}
+
 	return spec, nil
 }
 
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
 func readRegex(in string) (rx string, rest string) {
 	out := make([]rune, 0, len(in))
 	escaped := false
@@ -257,6 +300,30 @@ func (loc *RegexLocationSpec) Find(d *Debugger, scope *proc.EvalScope, locStr st
 	return r, nil
 }
 
+func (loc *ReturnsLocationSpec) Find(d *Debugger, scope *proc.EvalScope, locStr string) ([]api.Location, error) {
+	locs, err := loc.Base.Find(d, scope, locStr)
+	if err != nil {
+		return nil, err
+	}
+	if len(locs) != 1 {
+		return nil, fmt.Errorf("Location \"%s\" ambiguous", locStr)
+	}
+	fn := d.target.BinInfo().PCToFunc(locs[0].PC)
+	if fn == nil {
+		return nil, fmt.Errorf("could not find function containing %#x", locs[0].PC)
+	}
+	pcs, err := proc.FunctionReturnLocations(d.target, fn.Name)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.Location, len(pcs))
+	for i := range pcs {
+		file, line, _ := d.target.BinInfo().PCToLine(pcs[i])
+		r[i] = api.Location{PC: pcs[i], File: file, Line: line, Function: api.ConvertFunction(fn)}
+	}
+	return r, nil
+}
+
 func (loc *AddrLocationSpec) Find(d *Debugger, scope *proc.EvalScope, locStr string) ([]api.Location, error) {
 	if scope == nil {
 		addr, err := strconv.ParseInt(loc.AddrExpr, 0, 64)
@@ -265,7 +332,7 @@ func (loc *AddrLocationSpec) Find(d *Debugger, scope *proc.EvalScope, locStr str
 		}
 		return []api.Location{{PC: uint64(addr)}}, nil
 	} else {
-		v, err := scope.EvalExpression(loc.AddrExpr, proc.LoadConfig{true, 0, 0, 0, 0})
+		v, err := scope.EvalExpression(loc.AddrExpr, proc.LoadConfig{FollowPointers: true})
 		if err != nil {
 			return nil, err
 		}
@@ -355,6 +422,19 @@ func (loc *NormalLocationSpec) Find(d *Debugger, scope *proc.EvalScope, locStr s
 				break
 			}
 		}
+		// functions that have been inlined everywhere they are used do not
+		// have an entry in Functions.
+		bi := d.target.BinInfo()
+		for name := range bi.InlinedCalls {
+			if bi.LookupFunc[name] != nil || !loc.FuncBase.Match(proc.Function{Name: name}) || len(candidateFuncs) >= limit {
+				continue
+			}
+			if loc.Base == name {
+				candidateFuncs = []string{name}
+				break
+			}
+			candidateFuncs = append(candidateFuncs, name)
+		}
 	}
 
 	if matching := len(candidateFiles) + len(candidateFuncs); matching == 0 {
@@ -364,7 +444,7 @@ func (loc *NormalLocationSpec) Find(d *Debugger, scope *proc.EvalScope, locStr s
 		addrSpec := &AddrLocationSpec{locStr}
 		locs, err := addrSpec.Find(d, scope, locStr)
 		if err != nil {
-			return nil, fmt.Errorf("Location \"%s\" not found", locStr)
+			return nil, d.locationNotFound(fmt.Errorf("Location \"%s\" not found", locStr))
 		}
 		return locs, nil
 	} else if matching > 1 {
@@ -378,13 +458,16 @@ func (loc *NormalLocationSpec) Find(d *Debugger, scope *proc.EvalScope, locStr s
 		if loc.LineOffset < 0 {
 			return nil, fmt.Errorf("Malformed breakpoint location, no line offset specified")
 		}
-		addr, err = proc.FindFileLocation(d.target, candidateFiles[0], loc.LineOffset)
-	} else { // len(candidateFUncs) == 1
-		if loc.LineOffset < 0 {
-			addr, err = proc.FindFunctionLocation(d.target, candidateFuncs[0], true, 0)
+		if loc.Column > 0 {
+			addr, err = proc.FindFileLocationColumn(d.target, candidateFiles[0], loc.LineOffset, loc.Column)
 		} else {
-			addr, err = proc.FindFunctionLocation(d.target, candidateFuncs[0], false, loc.LineOffset)
+			addr, err = proc.FindFileLocation(d.target, candidateFiles[0], loc.LineOffset)
+		}
+	} else { // len(candidateFUncs) == 1
+		if loc.Column > 0 {
+			return nil, fmt.Errorf("Malformed breakpoint location, column can only be specified for file:line locations")
 		}
+		return loc.findFunction(d, candidateFuncs[0])
 	}
 
 	if err != nil {
@@ -393,6 +476,35 @@ func (loc *NormalLocationSpec) Find(d *Debugger, scope *proc.EvalScope, locStr s
 	return []api.Location{{PC: addr}}, nil
 }
 
+// findFunction returns the locations for function fnName, if the function
+// has been inlined the entry point of each of its inlined calls is also
+// returned.
+func (loc *NormalLocationSpec) findFunction(d *Debugger, fnName string) ([]api.Location, error) {
+	bi := d.target.BinInfo()
+	inlinedCalls := bi.InlinedCalls[fnName]
+	if loc.LineOffset >= 0 && len(inlinedCalls) > 0 {
+		return nil, fmt.Errorf("line offsets are not supported for inlined function %s", fnName)
+	}
+	var r []api.Location
+	if bi.LookupFunc[fnName] != nil {
+		var addr uint64
+		var err error
+		if loc.LineOffset < 0 {
+			addr, err = proc.FindFunctionLocation(d.target, fnName, true, 0)
+		} else {
+			addr, err = proc.FindFunctionLocation(d.target, fnName, false, loc.LineOffset)
+		}
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, api.Location{PC: addr})
+	}
+	for _, pc := range inlinedCalls {
+		r = append(r, api.Location{PC: pc})
+	}
+	return r, nil
+}
+
 func (loc *OffsetLocationSpec) Find(d *Debugger, scope *proc.EvalScope, locStr string) ([]api.Location, error) {
 	if scope == nil {
 		return nil, fmt.Errorf("could not determine current location (scope is nil)")
@@ -28,6 +28,10 @@ func assertNormalLocationSpec(t *testing.T, locstr string, tgt NormalLocationSpe
 		t.Fatalf("Location %q: expected 'LineOffset' %d got %d", locstr, tgt.LineOffset, nls.LineOffset)
 	}
 
+	if nls.Column != tgt.Column {
+		t.Fatalf("Location %q: expected 'Column' %d got %d", locstr, tgt.Column, nls.Column)
+	}
+
 	if tgt.FuncBase == nil {
 		return
 	}
@@ -43,26 +47,52 @@ func assertNormalLocationSpec(t *testing.T, locstr string, tgt NormalLocationSpe
 
 func TestFunctionLocationParsing(t *testing.T) {
 	// Function locations, simple package names, no line offset
-	assertNormalLocationSpec(t, "proc.(*Process).Continue", NormalLocationSpec{"proc.(*Process).Continue", &FuncLocationSpec{PackageName: "proc", ReceiverName: "Process", BaseName: "Continue"}, -1})
-	assertNormalLocationSpec(t, "proc.Process.Continue", NormalLocationSpec{"proc.Process.Continue", &FuncLocationSpec{PackageName: "proc", ReceiverName: "Process", BaseName: "Continue"}, -1})
-	assertNormalLocationSpec(t, "proc.Continue", NormalLocationSpec{"proc.Continue", &FuncLocationSpec{PackageOrReceiverName: "proc", BaseName: "Continue"}, -1})
-	assertNormalLocationSpec(t, "(*Process).Continue", NormalLocationSpec{"(*Process).Continue", &FuncLocationSpec{ReceiverName: "Process", BaseName: "Continue"}, -1})
-	assertNormalLocationSpec(t, "Continue", NormalLocationSpec{"Continue", &FuncLocationSpec{BaseName: "Continue"}, -1})
+	assertNormalLocationSpec(t, "proc.(*Process).Continue", NormalLocationSpec{"proc.(*Process).Continue", &FuncLocationSpec{PackageName: "proc", ReceiverName: "Process", BaseName: "Continue"}, -1, 0})
+	assertNormalLocationSpec(t, "proc.Process.Continue", NormalLocationSpec{"proc.Process.Continue", &FuncLocationSpec{PackageName: "proc", ReceiverName: "Process", BaseName: "Continue"}, -1, 0})
+	assertNormalLocationSpec(t, "proc.Continue", NormalLocationSpec{"proc.Continue", &FuncLocationSpec{PackageOrReceiverName: "proc", BaseName: "Continue"}, -1, 0})
+	assertNormalLocationSpec(t, "(*Process).Continue", NormalLocationSpec{"(*Process).Continue", &FuncLocationSpec{ReceiverName: "Process", BaseName: "Continue"}, -1, 0})
+	assertNormalLocationSpec(t, "Continue", NormalLocationSpec{"Continue", &FuncLocationSpec{BaseName: "Continue"}, -1, 0})
 
 	// Function locations, simple package names, line offsets
-	assertNormalLocationSpec(t, "proc.(*Process).Continue:10", NormalLocationSpec{"proc.(*Process).Continue", &FuncLocationSpec{PackageName: "proc", ReceiverName: "Process", BaseName: "Continue"}, 10})
-	assertNormalLocationSpec(t, "proc.Process.Continue:10", NormalLocationSpec{"proc.Process.Continue", &FuncLocationSpec{PackageName: "proc", ReceiverName: "Process", BaseName: "Continue"}, 10})
-	assertNormalLocationSpec(t, "proc.Continue:10", NormalLocationSpec{"proc.Continue", &FuncLocationSpec{PackageOrReceiverName: "proc", BaseName: "Continue"}, 10})
-	assertNormalLocationSpec(t, "(*Process).Continue:10", NormalLocationSpec{"(*Process).Continue", &FuncLocationSpec{ReceiverName: "Process", BaseName: "Continue"}, 10})
-	assertNormalLocationSpec(t, "Continue:10", NormalLocationSpec{"Continue", &FuncLocationSpec{BaseName: "Continue"}, 10})
+	assertNormalLocationSpec(t, "proc.(*Process).Continue:10", NormalLocationSpec{"proc.(*Process).Continue", &FuncLocationSpec{PackageName: "proc", ReceiverName: "Process", BaseName: "Continue"}, 10, 0})
+	assertNormalLocationSpec(t, "proc.Process.Continue:10", NormalLocationSpec{"proc.Process.Continue", &FuncLocationSpec{PackageName: "proc", ReceiverName: "Process", BaseName: "Continue"}, 10, 0})
+	assertNormalLocationSpec(t, "proc.Continue:10", NormalLocationSpec{"proc.Continue", &FuncLocationSpec{PackageOrReceiverName: "proc", BaseName: "Continue"}, 10, 0})
+	assertNormalLocationSpec(t, "(*Process).Continue:10", NormalLocationSpec{"(*Process).Continue", &FuncLocationSpec{ReceiverName: "Process", BaseName: "Continue"}, 10, 0})
+	assertNormalLocationSpec(t, "Continue:10", NormalLocationSpec{"Continue", &FuncLocationSpec{BaseName: "Continue"}, 10, 0})
 
 	// Function locations, package paths, no line offsets
-	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.(*Process).Continue", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.(*Process).Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", ReceiverName: "Process", BaseName: "Continue"}, -1})
-	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.Process.Continue", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.Process.Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", ReceiverName: "Process", BaseName: "Continue"}, -1})
-	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.Continue", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", BaseName: "Continue"}, -1})
+	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.(*Process).Continue", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.(*Process).Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", ReceiverName: "Process", BaseName: "Continue"}, -1, 0})
+	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.Process.Continue", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.Process.Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", ReceiverName: "Process", BaseName: "Continue"}, -1, 0})
+	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.Continue", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", BaseName: "Continue"}, -1, 0})
 
 	// Function locations, package paths, line offsets
-	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.(*Process).Continue:10", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.(*Process).Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", ReceiverName: "Process", BaseName: "Continue"}, 10})
-	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.Process.Continue:10", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.Process.Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", ReceiverName: "Process", BaseName: "Continue"}, 10})
-	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.Continue:10", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", BaseName: "Continue"}, 10})
+	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.(*Process).Continue:10", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.(*Process).Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", ReceiverName: "Process", BaseName: "Continue"}, 10, 0})
+	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.Process.Continue:10", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.Process.Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", ReceiverName: "Process", BaseName: "Continue"}, 10, 0})
+	assertNormalLocationSpec(t, "github.com/derekparker/delve/pkg/proc.Continue:10", NormalLocationSpec{"github.com/derekparker/delve/pkg/proc.Continue", &FuncLocationSpec{PackageName: "github.com/derekparker/delve/pkg/proc", BaseName: "Continue"}, 10, 0})
+}
+
+func TestFileLineColumnLocationParsing(t *testing.T) {
+	assertNormalLocationSpec(t, "main.go:10", NormalLocationSpec{Base: "main.go", LineOffset: 10})
+	assertNormalLocationSpec(t, "main.go:10:5", NormalLocationSpec{Base: "main.go", LineOffset: 10, Column: 5})
+	assertNormalLocationSpec(t, "/home/user/main.go:10:5", NormalLocationSpec{Base: "/home/user/main.go", LineOffset: 10, Column: 5})
+	assertNormalLocationSpec(t, "C:/home/user/main.go:10", NormalLocationSpec{Base: "C:/home/user/main.go", LineOffset: 10})
+	assertNormalLocationSpec(t, "C:/home/user/main.go:10:5", NormalLocationSpec{Base: "C:/home/user/main.go", LineOffset: 10, Column: 5})
+
+	if _, err := parseLocationSpec("main.go:10:0"); err == nil {
+		t.Fatalf("expected error parsing column 0")
+	}
+}
+
+func TestReturnsLocationParsing(t *testing.T) {
+	spec := parseLocationSpecNoError(t, "proc.(*Process).Continue:return")
+	rls, ok := spec.(*ReturnsLocationSpec)
+	if !ok {
+		t.Fatalf("expected ReturnsLocationSpec got %#v", spec)
+	}
+	if rls.Base.Base != "proc.(*Process).Continue" || rls.Base.LineOffset != -1 {
+		t.Fatalf("wrong base location %#v", rls.Base)
+	}
+	if _, err := parseLocationSpec("10:return"); err == nil {
+		t.Fatalf("expected error parsing line number return location")
+	}
 }
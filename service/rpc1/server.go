@@ -10,7 +10,7 @@ import (
 	"github.com/derekparker/delve/service/debugger"
 )
 
-var defaultLoadConfig = proc.LoadConfig{true, 1, 64, 64, -1}
+var defaultLoadConfig = proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
 
 type RPCServer struct {
 	// config is all the information necessary to start the debugger and server.
@@ -87,7 +87,7 @@ func (s *RPCServer) StacktraceGoroutine(args *StacktraceGoroutineArgs, locations
 	if args.Full {
 		loadcfg = &defaultLoadConfig
 	}
-	locs, err := s.debugger.Stacktrace(args.Id, args.Depth, false, loadcfg)
+	locs, err := s.debugger.Stacktrace(args.Id, args.Depth, false, false, loadcfg)
 	if err != nil {
 		return err
 	}
@@ -168,7 +168,7 @@ func (s *RPCServer) ListPackageVars(filter string, variables *[]api.Variable) er
 		return fmt.Errorf("no current thread")
 	}
 
-	vars, err := s.debugger.PackageVariables(current.ID, filter, defaultLoadConfig)
+	vars, err := s.debugger.PackageVariables(current.ID, "", filter, &defaultLoadConfig)
 	if err != nil {
 		return err
 	}
@@ -190,7 +190,7 @@ func (s *RPCServer) ListThreadPackageVars(args *ThreadListArgs, variables *[]api
 		return fmt.Errorf("no thread with id %d", args.Id)
 	}
 
-	vars, err := s.debugger.PackageVariables(args.Id, args.Filter, defaultLoadConfig)
+	vars, err := s.debugger.PackageVariables(args.Id, "", args.Filter, &defaultLoadConfig)
 	if err != nil {
 		return err
 	}
@@ -79,6 +79,38 @@ func (c *RPCClient) Continue() <-chan *api.DebuggerState {
 	return c.continueDir(api.Continue)
 }
 
+func (c *RPCClient) ContinueUntil(loc string) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ContinueUntil, Expr: loc, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) Jump(loc string) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Jump, Expr: loc}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) ContinueToRecover() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ContinueToRecover, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) ContinueToGoroutine(gid int) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ContinueToGoroutine, GoroutineID: gid, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+// SampleStacks resumes the target for duration, periodically stopping it
+// to record the stacks of the goroutines running on its threads.
+func (c *RPCClient) SampleStacks(duration time.Duration) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Sample, SampleDuration: duration, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) Rewind() <-chan *api.DebuggerState {
 	return c.continueDir(api.Rewind)
 }
@@ -133,24 +165,120 @@ func (c *RPCClient) Step() (*api.DebuggerState, error) {
 	return &out.State, err
 }
 
+func (c *RPCClient) NextGoroutine(goroutineID int) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Next, GoroutineID: goroutineID, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) StepGoroutine(goroutineID int) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Step, GoroutineID: goroutineID, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) NextDefer(mode string) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Next, DeferMode: mode, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) StepDefer(mode string) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Step, DeferMode: mode, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) NextCount(mode string, count int) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Next, DeferMode: mode, Count: count, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) StepCount(mode string, count int) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Step, DeferMode: mode, Count: count, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) Until() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Until, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) StepInto(callPC uint64) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.StepInto, CallPC: callPC, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) ListStepIntoTargets() ([]api.StepIntoTarget, error) {
+	var out ListStepIntoTargetsOut
+	err := c.call("ListStepIntoTargets", ListStepIntoTargetsIn{}, &out)
+	return out.Targets, err
+}
+
+func (c *RPCClient) ReverseNext() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ReverseNext, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) ReverseStep() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ReverseStep, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) ReverseStepInstruction() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ReverseStepInstruction}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) StepOut() (*api.DebuggerState, error) {
 	var out CommandOut
 	err := c.call("Command", &api.DebuggerCommand{Name: api.StepOut, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
 	return &out.State, err
 }
 
+func (c *RPCClient) StepToReturn() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", &api.DebuggerCommand{Name: api.StepToReturn, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) Call(expr string) (*api.DebuggerState, error) {
 	var out CommandOut
 	err := c.call("Command", &api.DebuggerCommand{Name: api.Call, ReturnInfoLoadConfig: c.retValLoadCfg, Expr: expr}, &out)
 	return &out.State, err
 }
 
+func (c *RPCClient) CallGoroutine(goroutineID int, expr string) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", &api.DebuggerCommand{Name: api.Call, GoroutineID: goroutineID, ReturnInfoLoadConfig: c.retValLoadCfg, Expr: expr}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) StepInstruction() (*api.DebuggerState, error) {
 	var out CommandOut
 	err := c.call("Command", api.DebuggerCommand{Name: api.StepInstruction}, &out)
 	return &out.State, err
 }
 
+func (c *RPCClient) StepInstructionCount(count int) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.StepInstruction, Count: count}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) NextInstruction() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.NextInstruction}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) SwitchThread(threadID int) (*api.DebuggerState, error) {
 	var out CommandOut
 	cmd := api.DebuggerCommand{
@@ -171,6 +299,16 @@ func (c *RPCClient) SwitchGoroutine(goroutineID int) (*api.DebuggerState, error)
 	return &out.State, err
 }
 
+func (c *RPCClient) SwitchFrame(frame int) (*api.DebuggerState, error) {
+	var out CommandOut
+	cmd := api.DebuggerCommand{
+		Name:  api.SwitchFrame,
+		Frame: frame,
+	}
+	err := c.call("Command", cmd, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) Halt() (*api.DebuggerState, error) {
 	var out CommandOut
 	err := c.call("Command", api.DebuggerCommand{Name: api.Halt}, &out)
@@ -201,6 +339,18 @@ func (c *RPCClient) ListBreakpoints() ([]*api.Breakpoint, error) {
 	return out.Breakpoints, err
 }
 
+func (c *RPCClient) ListInternalBreakpoints() ([]*api.Breakpoint, error) {
+	var out ListInternalBreakpointsOut
+	err := c.call("ListInternalBreakpoints", ListInternalBreakpointsIn{}, &out)
+	return out.Breakpoints, err
+}
+
+func (c *RPCClient) TakeStackRecords(id int) ([]api.StackRecord, error) {
+	var out TakeStackRecordsOut
+	err := c.call("TakeStackRecords", TakeStackRecordsIn{id}, &out)
+	return out.Records, err
+}
+
 func (c *RPCClient) ClearBreakpoint(id int) (*api.Breakpoint, error) {
 	var out ClearBreakpointOut
 	err := c.call("ClearBreakpoint", ClearBreakpointIn{id, ""}, &out)
@@ -219,6 +369,80 @@ func (c *RPCClient) AmendBreakpoint(bp *api.Breakpoint) error {
 	return err
 }
 
+func (c *RPCClient) SaveBreakpoints(path string) error {
+	out := new(SaveBreakpointsOut)
+	return c.call("SaveBreakpoints", SaveBreakpointsIn{path}, out)
+}
+
+func (c *RPCClient) LoadBreakpoints(path string) ([]*api.Breakpoint, []api.DiscardedBreakpoint, error) {
+	out := new(LoadBreakpointsOut)
+	err := c.call("LoadBreakpoints", LoadBreakpointsIn{path}, out)
+	return out.Breakpoints, out.DiscardedBreakpoints, err
+}
+
+func (c *RPCClient) SetBreakpointGroupDisabled(group string, disabled bool) error {
+	out := new(SetBreakpointGroupDisabledOut)
+	return c.call("SetBreakpointGroupDisabled", SetBreakpointGroupDisabledIn{group, disabled}, out)
+}
+
+func (c *RPCClient) SetSignalPolicy(sig int, policy string) error {
+	out := new(SetSignalPolicyOut)
+	return c.call("SetSignalPolicy", SetSignalPolicyIn{sig, policy}, out)
+}
+
+func (c *RPCClient) SetSyscallCatchpoints(syscalls []string) error {
+	out := new(SetSyscallCatchpointsOut)
+	return c.call("SetSyscallCatchpoints", SetSyscallCatchpointsIn{syscalls}, out)
+}
+
+func (c *RPCClient) SetResumeMode(mode string) error {
+	out := new(SetResumeModeOut)
+	return c.call("SetResumeMode", SetResumeModeIn{mode}, out)
+}
+
+func (c *RPCClient) SetStepFilters(filters []string) error {
+	out := new(SetStepFiltersOut)
+	return c.call("SetStepFilters", SetStepFiltersIn{filters}, out)
+}
+
+func (c *RPCClient) GetStepFilters() ([]string, error) {
+	var out GetStepFiltersOut
+	err := c.call("GetStepFilters", GetStepFiltersIn{}, &out)
+	return out.Filters, err
+}
+
+func (c *RPCClient) SetBlackbox(entries []string) error {
+	out := new(SetBlackboxOut)
+	return c.call("SetBlackbox", SetBlackboxIn{entries}, out)
+}
+
+func (c *RPCClient) GetBlackbox() ([]string, error) {
+	var out GetBlackboxOut
+	err := c.call("GetBlackbox", GetBlackboxIn{}, &out)
+	return out.Entries, err
+}
+
+func (c *RPCClient) SetStepIntoRuntime(enabled bool) error {
+	out := new(SetStepIntoRuntimeOut)
+	return c.call("SetStepIntoRuntime", SetStepIntoRuntimeIn{enabled}, out)
+}
+
+func (c *RPCClient) SetCgoCatchpoints(enabled bool) error {
+	out := new(SetCgoCatchpointsOut)
+	return c.call("SetCgoCatchpoints", SetCgoCatchpointsIn{enabled}, out)
+}
+
+func (c *RPCClient) SetDeadlockDetection(enabled bool) error {
+	out := new(SetDeadlockDetectionOut)
+	return c.call("SetDeadlockDetection", SetDeadlockDetectionIn{enabled}, out)
+}
+
+func (c *RPCClient) DetectDeadlock() (*api.Deadlock, error) {
+	var out DetectDeadlockOut
+	err := c.call("DetectDeadlock", DetectDeadlockIn{}, &out)
+	return out.Deadlock, err
+}
+
 func (c *RPCClient) CancelNext() error {
 	var out CancelNextOut
 	return c.call("CancelNext", CancelNextIn{}, &out)
@@ -242,6 +466,30 @@ func (c *RPCClient) EvalVariable(scope api.EvalScope, expr string, cfg api.LoadC
 	return out.Variable, err
 }
 
+func (c *RPCClient) EvalVariableChildren(scope api.EvalScope, expr string, offset, limit int, cfg api.LoadConfig) (*api.Variable, error) {
+	var out EvalChildrenOut
+	err := c.call("EvalChildren", EvalChildrenIn{scope, expr, offset, limit, &cfg}, &out)
+	return out.Variable, err
+}
+
+func (c *RPCClient) ExpandVariable(handle int, cfg api.LoadConfig) (*api.Variable, error) {
+	var out ExpandVariableOut
+	err := c.call("ExpandVariable", ExpandVariableIn{handle, &cfg}, &out)
+	return out.Variable, err
+}
+
+func (c *RPCClient) ListWaiters(scope api.EvalScope, expr string) ([]api.Waiter, error) {
+	var out ListWaitersOut
+	err := c.call("ListWaiters", ListWaitersIn{scope, expr}, &out)
+	return out.Waiters, err
+}
+
+func (c *RPCClient) MutexInfo(scope api.EvalScope, expr string) (*api.MutexState, error) {
+	var out MutexInfoOut
+	err := c.call("MutexInfo", MutexInfoIn{scope, expr}, &out)
+	return &out.Mutex, err
+}
+
 func (c *RPCClient) SetVariable(scope api.EvalScope, symbol, value string) error {
 	out := new(SetOut)
 	return c.call("Set", SetIn{scope, symbol, value}, out)
@@ -267,7 +515,17 @@ func (c *RPCClient) ListTypes(filter string) ([]string, error) {
 
 func (c *RPCClient) ListPackageVariables(filter string, cfg api.LoadConfig) ([]api.Variable, error) {
 	var out ListPackageVarsOut
-	err := c.call("ListPackageVars", ListPackageVarsIn{filter, cfg}, &out)
+	err := c.call("ListPackageVars", ListPackageVarsIn{Filter: filter, Cfg: cfg}, &out)
+	return out.Variables, err
+}
+
+func (c *RPCClient) FindPackageVariables(pkg, filter string, cfg *api.LoadConfig) ([]api.Variable, error) {
+	var out ListPackageVarsOut
+	in := ListPackageVarsIn{Filter: filter, Package: pkg, NoValues: cfg == nil}
+	if cfg != nil {
+		in.Cfg = *cfg
+	}
+	err := c.call("ListPackageVars", in, &out)
 	return out.Variables, err
 }
 
@@ -277,6 +535,12 @@ func (c *RPCClient) ListLocalVariables(scope api.EvalScope, cfg api.LoadConfig)
 	return out.Variables, err
 }
 
+func (c *RPCClient) ListLocalInstances(scope api.EvalScope, name string, cfg api.LoadConfig) ([]api.VariableInstance, error) {
+	var out ListLocalInstancesOut
+	err := c.call("ListLocalInstances", ListLocalInstancesIn{scope, name, cfg}, &out)
+	return out.Instances, err
+}
+
 func (c *RPCClient) ListRegisters(threadID int, includeFp bool) (api.Registers, error) {
 	out := new(ListRegistersOut)
 	err := c.call("ListRegisters", ListRegistersIn{ThreadID: threadID, IncludeFp: includeFp}, out)
@@ -295,9 +559,53 @@ func (c *RPCClient) ListGoroutines() ([]*api.Goroutine, error) {
 	return out.Goroutines, err
 }
 
+func (c *RPCClient) ListGoroutinesFiltered(filter api.GoroutineFilter, start, count int) ([]*api.Goroutine, int, error) {
+	var out ListGoroutinesOut
+	err := c.call("ListGoroutines", ListGoroutinesIn{filter, start, count}, &out)
+	return out.Goroutines, out.Nextg, err
+}
+
+func (c *RPCClient) Stacktraces(filter api.GoroutineFilter, depth int, readDefers bool, cfg *api.LoadConfig) ([]api.GoroutineStacktrace, error) {
+	var out StacktracesOut
+	err := c.call("Stacktraces", StacktracesIn{filter, depth, false, readDefers, cfg}, &out)
+	return out.Stacktraces, err
+}
+
+func (c *RPCClient) Ancestors(goroutineID int, numAncestors int, depth int) ([]api.Ancestor, error) {
+	var out AncestorsOut
+	err := c.call("Ancestors", AncestorsIn{goroutineID, numAncestors, depth}, &out)
+	return out.Ancestors, err
+}
+
+func (c *RPCClient) GroupGoroutines(filter api.GoroutineFilter, groupBy string, maxGoroutineIDs int) ([]api.GoroutineGroup, error) {
+	var out GroupGoroutinesOut
+	err := c.call("GroupGoroutines", GroupGoroutinesIn{filter, groupBy, maxGoroutineIDs}, &out)
+	return out.Groups, err
+}
+
+func (c *RPCClient) MarkGoroutines() (int, error) {
+	var out MarkGoroutinesOut
+	err := c.call("MarkGoroutines", MarkGoroutinesIn{}, &out)
+	return out.Count, err
+}
+
+func (c *RPCClient) GoroutineDiff() (*api.GoroutineDiff, error) {
+	var out GoroutineDiffOut
+	err := c.call("GoroutineDiff", GoroutineDiffIn{}, &out)
+	return &out.Diff, err
+}
+
 func (c *RPCClient) Stacktrace(goroutineId, depth int, readDefers bool, cfg *api.LoadConfig) ([]api.Stackframe, error) {
 	var out StacktraceOut
-	err := c.call("Stacktrace", StacktraceIn{goroutineId, depth, false, readDefers, cfg}, &out)
+	err := c.call("Stacktrace", StacktraceIn{goroutineId, depth, false, readDefers, cfg, false}, &out)
+	return out.Locations, err
+}
+
+// SystemStacktrace is like Stacktrace but returns all the frames of the
+// system stack the goroutine is executing on.
+func (c *RPCClient) SystemStacktrace(goroutineID, depth int, readDefers bool, cfg *api.LoadConfig) ([]api.Stackframe, error) {
+	var out StacktraceOut
+	err := c.call("Stacktrace", StacktraceIn{goroutineID, depth, false, readDefers, cfg, true}, &out)
 	return out.Locations, err
 }
 
@@ -362,10 +670,93 @@ func (c *RPCClient) ClearCheckpoint(id int) error {
 	return err
 }
 
+// FreezeGoroutine freezes goroutine goroutineID.
+func (c *RPCClient) FreezeGoroutine(goroutineID int) ([]int, error) {
+	var out FreezeGoroutineOut
+	err := c.call("FreezeGoroutine", FreezeGoroutineIn{goroutineID, false}, &out)
+	return out.Frozen, err
+}
+
+// ThawGoroutine thaws goroutine goroutineID.
+func (c *RPCClient) ThawGoroutine(goroutineID int) ([]int, error) {
+	var out FreezeGoroutineOut
+	err := c.call("FreezeGoroutine", FreezeGoroutineIn{goroutineID, true}, &out)
+	return out.Frozen, err
+}
+
+// ListFrozenGoroutines returns the list of frozen goroutines.
+func (c *RPCClient) ListFrozenGoroutines() ([]int, error) {
+	var out ListFrozenGoroutinesOut
+	err := c.call("ListFrozenGoroutines", ListFrozenGoroutinesIn{}, &out)
+	return out.Frozen, err
+}
+
+// SetSchedHistory enables or disables the recording of the scheduling
+// history.
+func (c *RPCClient) SetSchedHistory(enabled bool) error {
+	var out SetSchedHistoryOut
+	return c.call("SetSchedHistory", SetSchedHistoryIn{enabled}, &out)
+}
+
+// SchedHistory returns the scheduling history recorded since the target
+// was last resumed.
+func (c *RPCClient) SchedHistory() ([]api.SchedEvent, error) {
+	var out SchedHistoryOut
+	err := c.call("SchedHistory", SchedHistoryIn{}, &out)
+	return out.Events, err
+}
+
+// SeekEvent moves a recording to the start of the specified event.
+func (c *RPCClient) SeekEvent(event int64) (*api.DebuggerState, error) {
+	var out SeekEventOut
+	err := c.call("SeekEvent", SeekEventIn{event}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) SetReturnValuesLoadConfig(cfg *api.LoadConfig) {
 	c.retValLoadCfg = cfg
 }
 
+func (c *RPCClient) SetWatchExpressions(exprs []string) ([]int, error) {
+	var out SetWatchExpressionsOut
+	err := c.call("SetWatchExpressions", SetWatchExpressionsIn{exprs}, &out)
+	return out.IDs, err
+}
+
+func (c *RPCClient) SaveVariableSnapshot(scope api.EvalScope, expr string, maxDepth int) error {
+	var out SaveVariableSnapshotOut
+	return c.call("SaveVariableSnapshot", SaveVariableSnapshotIn{scope, expr, maxDepth}, &out)
+}
+
+func (c *RPCClient) DiffVariables(scope api.EvalScope, expr1, expr2 string, maxDepth int) ([]api.VariableDifference, error) {
+	var out DiffVariablesOut
+	err := c.call("DiffVariables", DiffVariablesIn{scope, expr1, expr2, maxDepth}, &out)
+	return out.Differences, err
+}
+
+func (c *RPCClient) ExportVariable(scope api.EvalScope, expr string, asJSON bool, maxDepth int) (string, error) {
+	var out ExportVariableOut
+	err := c.call("ExportVariable", ExportVariableIn{scope, expr, asJSON, maxDepth}, &out)
+	return out.Text, err
+}
+
+func (c *RPCClient) Traverse(scope api.EvalScope, expr string, fields []string, maxNodes int, cfg *api.LoadConfig) ([]api.TraversalNode, bool, error) {
+	var out TraverseOut
+	err := c.call("Traverse", TraverseIn{scope, expr, fields, maxNodes, cfg}, &out)
+	return out.Nodes, out.Truncated, err
+}
+
+func (c *RPCClient) SetFormatter(typename, tmpl string) error {
+	out := new(SetFormatterOut)
+	return c.call("SetFormatter", SetFormatterIn{typename, tmpl}, out)
+}
+
+func (c *RPCClient) ListFormatters() (map[string]string, error) {
+	var out ListFormattersOut
+	err := c.call("ListFormatters", ListFormattersIn{}, &out)
+	return out.Formatters, err
+}
+
 func (c *RPCClient) IsMulticlient() bool {
 	var out IsMulticlientOut
 	c.call("IsMulticlient", IsMulticlientIn{}, &out)
@@ -124,6 +124,22 @@ func (s *RPCServer) Command(command api.DebuggerCommand, cb service.RPCCallback)
 	cb.Return(out, nil)
 }
 
+type ListStepIntoTargetsIn struct {
+}
+
+type ListStepIntoTargetsOut struct {
+	Targets []api.StepIntoTarget
+}
+
+// ListStepIntoTargets returns the functions called by the current source
+// line, the CALL instruction of one of them can be passed to the stepInto
+// command.
+func (s *RPCServer) ListStepIntoTargets(arg ListStepIntoTargetsIn, out *ListStepIntoTargetsOut) error {
+	var err error
+	out.Targets, err = s.debugger.StepIntoTargets()
+	return err
+}
+
 type GetBreakpointIn struct {
 	Id   int
 	Name string
@@ -157,6 +173,9 @@ type StacktraceIn struct {
 	Full   bool
 	Defers bool // read deferred functions
 	Cfg    *api.LoadConfig
+	// SystemStack unwinds all the frames of the system stack the goroutine
+	// is executing on, if any, instead of only its topmost frame.
+	SystemStack bool
 }
 
 type StacktraceOut struct {
@@ -167,12 +186,15 @@ type StacktraceOut struct {
 //
 // If Full is set it will also the variable of all local variables
 // and function arguments of all stack frames.
+//
+// Frames executing on a system stack, the g0 stack of a thread or the
+// stack of a cgo call, have SystemStack set.
 func (s *RPCServer) Stacktrace(arg StacktraceIn, out *StacktraceOut) error {
 	cfg := arg.Cfg
 	if cfg == nil && arg.Full {
-		cfg = &api.LoadConfig{true, 1, 64, 64, -1}
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
 	}
-	locs, err := s.debugger.Stacktrace(arg.Id, arg.Depth, arg.Defers, api.LoadConfigToProc(cfg))
+	locs, err := s.debugger.Stacktrace(arg.Id, arg.Depth, arg.Defers, arg.SystemStack, api.LoadConfigToProc(cfg))
 	if err != nil {
 		return err
 	}
@@ -180,6 +202,58 @@ func (s *RPCServer) Stacktrace(arg StacktraceIn, out *StacktraceOut) error {
 	return nil
 }
 
+type StacktracesIn struct {
+	// Filter selects the goroutines, all goroutines are selected if it is
+	// empty.
+	Filter api.GoroutineFilter
+	Depth  int
+	Full   bool
+	Defers bool // read deferred functions
+	Cfg    *api.LoadConfig
+}
+
+type StacktracesOut struct {
+	Stacktraces []api.GoroutineStacktrace
+}
+
+// Stacktraces returns the stacktraces of all goroutines matching Filter,
+// up to the specified Depth, with a single call. Errors encountered while
+// computing the stacktrace of a goroutine are returned in its Err field.
+//
+// If Full is set it will also the variable of all local variables
+// and function arguments of all stack frames.
+func (s *RPCServer) Stacktraces(arg StacktracesIn, out *StacktracesOut) error {
+	cfg := arg.Cfg
+	if cfg == nil && arg.Full {
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+	}
+	stacks, err := s.debugger.Stacktraces(arg.Filter, arg.Depth, arg.Defers, api.LoadConfigToProc(cfg))
+	if err != nil {
+		return err
+	}
+	out.Stacktraces = stacks
+	return nil
+}
+
+type AncestorsIn struct {
+	GoroutineID  int
+	NumAncestors int
+	Depth        int
+}
+
+type AncestorsOut struct {
+	Ancestors []api.Ancestor
+}
+
+// Ancestors returns the stacktraces of the ancestors of a goroutine, the
+// goroutines that created it. Ancestors are only recorded by the runtime if
+// the target was started with GODEBUG=tracebackancestors=N.
+func (s *RPCServer) Ancestors(arg AncestorsIn, out *AncestorsOut) error {
+	var err error
+	out.Ancestors, err = s.debugger.Ancestors(arg.GoroutineID, arg.NumAncestors, arg.Depth)
+	return err
+}
+
 type ListBreakpointsIn struct {
 }
 
@@ -193,6 +267,38 @@ func (s *RPCServer) ListBreakpoints(arg ListBreakpointsIn, out *ListBreakpointsO
 	return nil
 }
 
+type ListInternalBreakpointsIn struct {
+}
+
+type ListInternalBreakpointsOut struct {
+	Breakpoints []*api.Breakpoint
+}
+
+// ListInternalBreakpoints gets all the internal breakpoints set by next,
+// step and stepout, and the one used to freeze goroutines. Use CancelNext
+// to clear the former.
+func (s *RPCServer) ListInternalBreakpoints(arg ListInternalBreakpointsIn, out *ListInternalBreakpointsOut) error {
+	out.Breakpoints = s.debugger.InternalBreakpoints()
+	return nil
+}
+
+type TakeStackRecordsIn struct {
+	Id int
+}
+
+type TakeStackRecordsOut struct {
+	Records []api.StackRecord
+}
+
+// TakeStackRecords returns the stacktraces recorded by breakpoint Id,
+// which must have RecordStack set, since the last call to
+// TakeStackRecords. At most 4096 stacktraces are kept for each breakpoint.
+func (s *RPCServer) TakeStackRecords(arg TakeStackRecordsIn, out *TakeStackRecordsOut) error {
+	var err error
+	out.Records, err = s.debugger.TakeStackRecords(arg.Id)
+	return err
+}
+
 type CreateBreakpointIn struct {
 	Breakpoint api.Breakpoint
 }
@@ -270,12 +376,247 @@ func (s *RPCServer) AmendBreakpoint(arg AmendBreakpointIn, out *AmendBreakpointO
 	return s.debugger.AmendBreakpoint(&arg.Breakpoint)
 }
 
+type SaveBreakpointsIn struct {
+	// Path of the file, on the machine running the server, where the
+	// breakpoints will be saved.
+	Path string
+}
+
+type SaveBreakpointsOut struct {
+}
+
+// SaveBreakpoints saves all user breakpoints to a file so that they can
+// be restored with LoadBreakpoints.
+func (s *RPCServer) SaveBreakpoints(arg SaveBreakpointsIn, out *SaveBreakpointsOut) error {
+	return s.debugger.SaveBreakpoints(arg.Path)
+}
+
+type LoadBreakpointsIn struct {
+	// Path of the file, on the machine running the server, where the
+	// breakpoints were saved.
+	Path string
+}
+
+type LoadBreakpointsOut struct {
+	Breakpoints          []*api.Breakpoint
+	DiscardedBreakpoints []api.DiscardedBreakpoint
+}
+
+// LoadBreakpoints sets the breakpoints saved by SaveBreakpoints.
+// Breakpoints are resolved using their file:line specification, the ones
+// that can not be resolved are returned in DiscardedBreakpoints.
+func (s *RPCServer) LoadBreakpoints(arg LoadBreakpointsIn, out *LoadBreakpointsOut) error {
+	var err error
+	out.Breakpoints, out.DiscardedBreakpoints, err = s.debugger.LoadBreakpoints(arg.Path)
+	return err
+}
+
+type SetBreakpointGroupDisabledIn struct {
+	Group    string
+	Disabled bool
+}
+
+type SetBreakpointGroupDisabledOut struct {
+}
+
+// SetBreakpointGroupDisabled disables or enables all the breakpoints
+// belonging to the specified group.
+func (s *RPCServer) SetBreakpointGroupDisabled(arg SetBreakpointGroupDisabledIn, out *SetBreakpointGroupDisabledOut) error {
+	return s.debugger.SetBreakpointGroupDisabled(arg.Group, arg.Disabled)
+}
+
+type SetSignalPolicyIn struct {
+	Signal int
+	// Policy is one of "stop", "pass" or "ignore".
+	Policy string
+}
+
+type SetSignalPolicyOut struct {
+}
+
+// SetSignalPolicy sets what happens when the target receives the
+// specified signal.
+//
+// With the "stop" policy the target is stopped and the signal is delivered
+// when it is resumed, with "pass" the signal is delivered without stopping
+// (this is the default) and with "ignore" the signal is discarded.
+// Currently only the native backend on linux supports signal policies.
+func (s *RPCServer) SetSignalPolicy(arg SetSignalPolicyIn, out *SetSignalPolicyOut) error {
+	return s.debugger.SetSignalPolicy(arg.Signal, arg.Policy)
+}
+
+type SetSyscallCatchpointsIn struct {
+	// Syscalls is the list of syscalls, by name or number, that will stop
+	// the target. An empty list removes all syscall catchpoints.
+	Syscalls []string
+}
+
+type SetSyscallCatchpointsOut struct {
+}
+
+// SetSyscallCatchpoints sets the syscalls that stop the target when they
+// are entered or exited, the syscall is reported in the Syscall field of
+// the thread that stopped.
+// Currently only the native backend on linux supports syscall catchpoints.
+func (s *RPCServer) SetSyscallCatchpoints(arg SetSyscallCatchpointsIn, out *SetSyscallCatchpointsOut) error {
+	return s.debugger.SetSyscallCatchpoints(arg.Syscalls)
+}
+
+type SetResumeModeIn struct {
+	// Mode is one of "all", "selected" or "nonstop".
+	Mode string
+}
+
+type SetResumeModeOut struct {
+}
+
+// SetResumeMode sets which threads run when the target is continued: with
+// "all", the default, all threads are resumed and stopped together, with
+// "selected" only the thread running the selected goroutine is resumed and
+// with "nonstop" all threads are resumed but only the thread hitting a
+// breakpoint is stopped.
+// Currently only the native backend on linux supports resume modes.
+func (s *RPCServer) SetResumeMode(arg SetResumeModeIn, out *SetResumeModeOut) error {
+	return s.debugger.SetResumeMode(arg.Mode)
+}
+
+type SetStepFiltersIn struct {
+	// Filters is the list of patterns matching the names, or the files, of
+	// the functions that next, step and stepout should not stop in. An
+	// empty list removes all filters.
+	Filters []string
+}
+
+type SetStepFiltersOut struct {
+}
+
+// SetStepFilters sets the list of step filters. Step will not enter the
+// functions matching a filter and a step operation stopping inside one of
+// them continues until it returns. The only special character in a
+// pattern is '*', which matches any sequence of characters.
+func (s *RPCServer) SetStepFilters(arg SetStepFiltersIn, out *SetStepFiltersOut) error {
+	return s.debugger.SetStepFilters(arg.Filters)
+}
+
+type GetStepFiltersIn struct {
+}
+
+type GetStepFiltersOut struct {
+	Filters []string
+}
+
+// GetStepFilters returns the list of step filters.
+func (s *RPCServer) GetStepFilters(arg GetStepFiltersIn, out *GetStepFiltersOut) error {
+	out.Filters = s.debugger.StepFilters()
+	return nil
+}
+
+type SetBlackboxIn struct {
+	// Entries is the list of functions, specified by their fully qualified
+	// name, and files that next, step and stepout should not stop in. An
+	// empty list clears the blackbox.
+	Entries []string
+}
+
+type SetBlackboxOut struct {
+}
+
+// SetBlackbox sets the list of blackboxed functions and files. Unlike
+// step filters each entry names a single function or file, step
+// operations never stop inside them.
+func (s *RPCServer) SetBlackbox(arg SetBlackboxIn, out *SetBlackboxOut) error {
+	s.debugger.SetBlackbox(arg.Entries)
+	return nil
+}
+
+type GetBlackboxIn struct {
+}
+
+type GetBlackboxOut struct {
+	Entries []string
+}
+
+// GetBlackbox returns the list of blackboxed functions and files.
+func (s *RPCServer) GetBlackbox(arg GetBlackboxIn, out *GetBlackboxOut) error {
+	out.Entries = s.debugger.Blackbox()
+	return nil
+}
+
+type SetStepIntoRuntimeIn struct {
+	Enabled bool
+}
+
+type SetStepIntoRuntimeOut struct {
+}
+
+// SetStepIntoRuntime determines whether step enters unexported functions
+// of the runtime, like runtime.mallocgc and the scheduler, which are
+// skipped by default.
+func (s *RPCServer) SetStepIntoRuntime(arg SetStepIntoRuntimeIn, out *SetStepIntoRuntimeOut) error {
+	s.debugger.SetStepIntoRuntime(arg.Enabled)
+	return nil
+}
+
+type SetCgoCatchpointsIn struct {
+	Enabled bool
+}
+
+type SetCgoCatchpointsOut struct {
+}
+
+// SetCgoCatchpoints enables or disables the catchpoints that stop the
+// target every time it calls a C function, returns from it or C code
+// calls back into Go. The crossing is reported in the Cgo field of the
+// thread that stopped.
+func (s *RPCServer) SetCgoCatchpoints(arg SetCgoCatchpointsIn, out *SetCgoCatchpointsOut) error {
+	return s.debugger.SetCgoCatchpoints(arg.Enabled)
+}
+
+type SetDeadlockDetectionIn struct {
+	Enabled bool
+}
+
+type SetDeadlockDetectionOut struct {
+}
+
+// SetDeadlockDetection enables or disables deadlock detection. When it is
+// enabled Command(continue) periodically checks whether all goroutines of
+// the target are blocked and stops it if they are, the deadlock is
+// described by the Deadlock field of the returned state.
+func (s *RPCServer) SetDeadlockDetection(arg SetDeadlockDetectionIn, out *SetDeadlockDetectionOut) error {
+	s.debugger.SetDeadlockDetection(arg.Enabled)
+	return nil
+}
+
+type DetectDeadlockIn struct {
+}
+
+type DetectDeadlockOut struct {
+	// Deadlock is nil if the target is not deadlocked.
+	Deadlock *api.Deadlock
+}
+
+// DetectDeadlock checks whether all goroutines of the target, except the
+// ones started by the runtime, are blocked on channels, select statements,
+// semaphores or condition variables.
+func (s *RPCServer) DetectDeadlock(arg DetectDeadlockIn, out *DetectDeadlockOut) error {
+	dl, err := s.debugger.DetectDeadlock()
+	if err != nil {
+		return err
+	}
+	out.Deadlock = dl
+	return nil
+}
+
 type CancelNextIn struct {
 }
 
 type CancelNextOut struct {
 }
 
+// CancelNext clears all internal breakpoints, cancelling a next, step or
+// stepout operation that was interrupted by a manual stop or by another
+// breakpoint.
 func (s *RPCServer) CancelNext(arg CancelNextIn, out *CancelNextOut) error {
 	return s.debugger.CancelNext()
 }
@@ -317,13 +658,20 @@ func (s *RPCServer) GetThread(arg GetThreadIn, out *GetThreadOut) error {
 type ListPackageVarsIn struct {
 	Filter string
 	Cfg    api.LoadConfig
+	// Package, if not empty, is the path of the package the variables must
+	// belong to.
+	Package string
+	// NoValues requests only the name, type and address of the variables,
+	// without loading their values, Cfg is ignored.
+	NoValues bool
 }
 
 type ListPackageVarsOut struct {
 	Variables []api.Variable
 }
 
-// ListPackageVars lists all package variables in the context of the current thread.
+// ListPackageVars lists the package variables in the context of the
+// current thread with a name matching arg.Filter, a regular expression.
 func (s *RPCServer) ListPackageVars(arg ListPackageVarsIn, out *ListPackageVarsOut) error {
 	state, err := s.debugger.State(false)
 	if err != nil {
@@ -335,7 +683,11 @@ func (s *RPCServer) ListPackageVars(arg ListPackageVarsIn, out *ListPackageVarsO
 		return fmt.Errorf("no current thread")
 	}
 
-	vars, err := s.debugger.PackageVariables(current.ID, arg.Filter, *api.LoadConfigToProc(&arg.Cfg))
+	cfg := api.LoadConfigToProc(&arg.Cfg)
+	if arg.NoValues {
+		cfg = nil
+	}
+	vars, err := s.debugger.PackageVariables(current.ID, arg.Package, arg.Filter, cfg)
 	if err != nil {
 		return err
 	}
@@ -392,6 +744,30 @@ func (s *RPCServer) ListLocalVars(arg ListLocalVarsIn, out *ListLocalVarsOut) er
 	return nil
 }
 
+type ListLocalInstancesIn struct {
+	Scope api.EvalScope
+	Name  string
+	Cfg   api.LoadConfig
+}
+
+type ListLocalInstancesOut struct {
+	Instances []api.VariableInstance
+}
+
+// ListLocalInstances lists every declaration of the local variable or
+// argument called Name in the function of Scope, including the ones that
+// are shadowed or declared in lexical blocks that do not contain the
+// current PC. The declaration Name refers to at the current PC has
+// InScope set.
+func (s *RPCServer) ListLocalInstances(arg ListLocalInstancesIn, out *ListLocalInstancesOut) error {
+	vars, err := s.debugger.LocalInstances(arg.Scope, arg.Name, *api.LoadConfigToProc(&arg.Cfg))
+	if err != nil {
+		return err
+	}
+	out.Instances = vars
+	return nil
+}
+
 type ListFunctionArgsIn struct {
 	Scope api.EvalScope
 	Cfg   api.LoadConfig
@@ -428,7 +804,7 @@ type EvalOut struct {
 func (s *RPCServer) Eval(arg EvalIn, out *EvalOut) error {
 	cfg := arg.Cfg
 	if cfg == nil {
-		cfg = &api.LoadConfig{true, 1, 64, 64, -1}
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
 	}
 	v, err := s.debugger.EvalVariableInScope(arg.Scope, arg.Expr, *api.LoadConfigToProc(cfg))
 	if err != nil {
@@ -438,6 +814,114 @@ func (s *RPCServer) Eval(arg EvalIn, out *EvalOut) error {
 	return nil
 }
 
+type EvalChildrenIn struct {
+	Scope api.EvalScope
+	// Expr must evaluate to a map, a slice or an array.
+	Expr string
+	// Offset is the index of the first child loaded, for maps it counts
+	// key/value pairs.
+	Offset int
+	// Limit is the maximum number of children loaded.
+	Limit int
+	Cfg   *api.LoadConfig
+}
+
+type EvalChildrenOut struct {
+	// Variable is the value of Expr, its Children contain only the
+	// requested page while its Len is the length of the whole value.
+	Variable *api.Variable
+}
+
+// EvalChildren evaluates a map, a slice or an array loading a page of its
+// children, so that values too large to be loaded at once can be
+// inspected one page at a time. MaxArrayValues and MaxMapEntries of
+// arg.Cfg are replaced by arg.Limit.
+func (s *RPCServer) EvalChildren(arg EvalChildrenIn, out *EvalChildrenOut) error {
+	cfg := arg.Cfg
+	if cfg == nil {
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+	}
+	v, err := s.debugger.EvalVariableChildren(arg.Scope, arg.Expr, arg.Offset, arg.Limit, *api.LoadConfigToProc(cfg))
+	if err != nil {
+		return err
+	}
+	out.Variable = v
+	return nil
+}
+
+type ExpandVariableIn struct {
+	// Handle is the Handle field of a variable returned by a previous call.
+	Handle int
+	Cfg    *api.LoadConfig
+}
+
+type ExpandVariableOut struct {
+	Variable *api.Variable
+}
+
+// ExpandVariable loads the children of a variable that were not loaded by
+// the call that returned it, because of the limits of its LoadConfig.
+// Variables with unloaded children have a non-zero Handle, the children
+// returned by this call can have handles of their own so that nested
+// values can be expanded one level at a time. Handles are invalidated when
+// the target is resumed.
+func (s *RPCServer) ExpandVariable(arg ExpandVariableIn, out *ExpandVariableOut) error {
+	cfg := arg.Cfg
+	if cfg == nil {
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+	}
+	v, err := s.debugger.ExpandVariable(arg.Handle, *api.LoadConfigToProc(cfg))
+	if err != nil {
+		return err
+	}
+	out.Variable = v
+	return nil
+}
+
+type ListWaitersIn struct {
+	Scope api.EvalScope
+	// Expr must evaluate to a channel, a sync.Mutex or a sync.RWMutex, or
+	// to a pointer to one of them.
+	Expr string
+}
+
+type ListWaitersOut struct {
+	Waiters []api.Waiter
+}
+
+// ListWaiters returns the goroutines blocked on a channel or a mutex,
+// together with the operation they are blocked in.
+func (s *RPCServer) ListWaiters(arg ListWaitersIn, out *ListWaitersOut) error {
+	waiters, err := s.debugger.Waiters(arg.Scope, arg.Expr)
+	if err != nil {
+		return err
+	}
+	out.Waiters = waiters
+	return nil
+}
+
+type MutexInfoIn struct {
+	Scope api.EvalScope
+	// Expr must evaluate to a sync.Mutex or to a pointer to one, if it is
+	// empty the mutex the goroutine of Scope is blocked on is used.
+	Expr string
+}
+
+type MutexInfoOut struct {
+	Mutex api.MutexState
+}
+
+// MutexInfo decodes the state of a sync.Mutex and returns the goroutines
+// that could be holding it.
+func (s *RPCServer) MutexInfo(arg MutexInfoIn, out *MutexInfoOut) error {
+	ms, err := s.debugger.MutexInfo(arg.Scope, arg.Expr)
+	if err != nil {
+		return err
+	}
+	out.Mutex = *ms
+	return nil
+}
+
 type SetIn struct {
 	Scope  api.EvalScope
 	Symbol string
@@ -508,19 +992,99 @@ func (s *RPCServer) ListTypes(arg ListTypesIn, out *ListTypesOut) error {
 }
 
 type ListGoroutinesIn struct {
+	// Filter selects which goroutines are returned, all goroutines are
+	// returned if it is empty.
+	Filter api.GoroutineFilter
+	// Start is the index of the first goroutine to consider, it should be
+	// zero or the value of Nextg returned by a previous call.
+	Start int
+	// Count is the maximum number of goroutines returned, if it is zero all
+	// matching goroutines are returned.
+	Count int
 }
 
 type ListGoroutinesOut struct {
 	Goroutines []*api.Goroutine
+	// Nextg is the value of Start that returns the following goroutines,
+	// it is -1 if there are no more goroutines.
+	Nextg int
 }
 
-// ListGoroutines lists all goroutines.
+// ListGoroutines lists all goroutines, or the goroutines selected by a
+// filter. The filter is evaluated by the debugger, with Start and Count
+// clients can list a large number of goroutines one page at a time.
 func (s *RPCServer) ListGoroutines(arg ListGoroutinesIn, out *ListGoroutinesOut) error {
-	gs, err := s.debugger.Goroutines()
+	gs, nextg, err := s.debugger.FilterGoroutines(arg.Filter, arg.Start, arg.Count)
 	if err != nil {
 		return err
 	}
 	out.Goroutines = gs
+	out.Nextg = nextg
+	return nil
+}
+
+type GroupGoroutinesIn struct {
+	// Filter selects the goroutines that are grouped.
+	Filter api.GoroutineFilter
+	// GroupBy is one of api.GroupByCurrentFunc, api.GroupByUserFunc or
+	// api.GroupByWaitReason, the default is api.GroupByUserFunc.
+	GroupBy string
+	// MaxGoroutineIDs is the maximum number of goroutine IDs returned for
+	// each group, if it is negative all IDs are returned.
+	MaxGoroutineIDs int
+}
+
+type GroupGoroutinesOut struct {
+	Groups []api.GoroutineGroup
+}
+
+// GroupGoroutines groups goroutines by function or wait reason and returns
+// the number of goroutines in each group, sorted by decreasing size, and
+// the IDs of some of them.
+func (s *RPCServer) GroupGoroutines(arg GroupGoroutinesIn, out *GroupGoroutinesOut) error {
+	groups, err := s.debugger.GroupGoroutines(arg.Filter, arg.GroupBy, arg.MaxGoroutineIDs)
+	if err != nil {
+		return err
+	}
+	out.Groups = groups
+	return nil
+}
+
+type MarkGoroutinesIn struct {
+}
+
+type MarkGoroutinesOut struct {
+	// Count is the number of goroutines in the baseline.
+	Count int
+}
+
+// MarkGoroutines records the goroutines that currently exist as the
+// baseline used by GoroutineDiff.
+func (s *RPCServer) MarkGoroutines(arg MarkGoroutinesIn, out *MarkGoroutinesOut) error {
+	n, err := s.debugger.MarkGoroutines()
+	if err != nil {
+		return err
+	}
+	out.Count = n
+	return nil
+}
+
+type GoroutineDiffIn struct {
+}
+
+type GoroutineDiffOut struct {
+	Diff api.GoroutineDiff
+}
+
+// GoroutineDiff returns the goroutines created and the goroutines that
+// exited since the last call to MarkGoroutines, the GoStatementLoc field
+// of each goroutine is the location where it was created.
+func (s *RPCServer) GoroutineDiff(arg GoroutineDiffIn, out *GoroutineDiffOut) error {
+	diff, err := s.debugger.GoroutineDiff()
+	if err != nil {
+		return err
+	}
+	out.Diff = *diff
 	return nil
 }
 
@@ -641,6 +1205,248 @@ func (s *RPCServer) ClearCheckpoint(arg ClearCheckpointIn, out *ClearCheckpointO
 	return s.debugger.ClearCheckpoint(arg.ID)
 }
 
+type FreezeGoroutineIn struct {
+	GoroutineID int
+	// Thaw undoes a previous freeze of the goroutine.
+	Thaw bool
+}
+
+type FreezeGoroutineOut struct {
+	// Frozen is the list of frozen goroutines, after the operation.
+	Frozen []int
+}
+
+// FreezeGoroutine freezes goroutine GoroutineID, or thaws it if Thaw is
+// set. Threads that run, or are about to run, a frozen goroutine are held
+// stopped while all other threads are resumed by continue and stepping
+// commands.
+// Only supported by the native backend on linux.
+func (s *RPCServer) FreezeGoroutine(arg FreezeGoroutineIn, out *FreezeGoroutineOut) error {
+	var err error
+	out.Frozen, err = s.debugger.FreezeGoroutine(arg.GoroutineID, arg.Thaw)
+	return err
+}
+
+type ListFrozenGoroutinesIn struct {
+}
+
+type ListFrozenGoroutinesOut struct {
+	Frozen []int
+}
+
+// ListFrozenGoroutines returns the list of goroutines frozen by
+// FreezeGoroutine.
+func (s *RPCServer) ListFrozenGoroutines(arg ListFrozenGoroutinesIn, out *ListFrozenGoroutinesOut) error {
+	out.Frozen = s.debugger.FrozenGoroutines()
+	return nil
+}
+
+type SetSchedHistoryIn struct {
+	Enabled bool
+}
+
+type SetSchedHistoryOut struct {
+}
+
+// SetSchedHistory enables or disables the recording of the goroutines
+// scheduled on each thread between two stops of the target. The target
+// runs considerably slower while the history is recorded.
+func (s *RPCServer) SetSchedHistory(arg SetSchedHistoryIn, out *SetSchedHistoryOut) error {
+	return s.debugger.SetSchedHistory(arg.Enabled)
+}
+
+type SchedHistoryIn struct {
+}
+
+type SchedHistoryOut struct {
+	Events []api.SchedEvent
+}
+
+// SchedHistory returns the goroutines scheduled, and the threads they ran
+// on, since the target was last resumed, in the order they were scheduled.
+func (s *RPCServer) SchedHistory(arg SchedHistoryIn, out *SchedHistoryOut) error {
+	out.Events = s.debugger.SchedHistory()
+	return nil
+}
+
+type SeekEventIn struct {
+	Event int64
+}
+
+type SeekEventOut struct {
+	State api.DebuggerState
+}
+
+// SeekEvent moves a recording to the start of the specified event, as
+// reported by the Event field of the debugger state, going either backward
+// or forward. Breakpoints are not hit while seeking.
+func (s *RPCServer) SeekEvent(arg SeekEventIn, out *SeekEventOut) error {
+	st, err := s.debugger.SeekEvent(arg.Event)
+	if err != nil {
+		return err
+	}
+	out.State = *st
+	return nil
+}
+
+type SetWatchExpressionsIn struct {
+	Exprs []string
+}
+
+type SetWatchExpressionsOut struct {
+	IDs []int
+}
+
+// SetWatchExpressions sets the list of watch expressions, they will be
+// evaluated automatically every time the target stops and returned, with
+// their change since the previous stop, in the WatchExpressions field of
+// the debugger state. The IDs of the expressions are returned, expressions
+// that were already in the list keep their ID.
+func (s *RPCServer) SetWatchExpressions(arg SetWatchExpressionsIn, out *SetWatchExpressionsOut) error {
+	ids, err := s.debugger.SetWatchExpressions(arg.Exprs)
+	if err != nil {
+		return err
+	}
+	out.IDs = ids
+	return nil
+}
+
+type SaveVariableSnapshotIn struct {
+	Scope api.EvalScope
+	Expr  string
+	// MaxDepth is how deep the value is saved, the default is used if it
+	// is zero.
+	MaxDepth int
+}
+
+type SaveVariableSnapshotOut struct {
+}
+
+// SaveVariableSnapshot saves the value of an expression, so that it can be
+// compared with its value at a later stop by DiffVariables.
+func (s *RPCServer) SaveVariableSnapshot(arg SaveVariableSnapshotIn, out *SaveVariableSnapshotOut) error {
+	return s.debugger.SaveVariableSnapshot(arg.Scope, arg.Expr, arg.MaxDepth)
+}
+
+type DiffVariablesIn struct {
+	Scope api.EvalScope
+	Expr1 string
+	// Expr2 is the expression compared with Expr1, if it is empty Expr1 is
+	// compared with the value saved by SaveVariableSnapshot.
+	Expr2 string
+	// MaxDepth is how deep values are compared, the default is used if it
+	// is zero.
+	MaxDepth int
+}
+
+type DiffVariablesOut struct {
+	Differences []api.VariableDifference
+}
+
+// DiffVariables compares two values structurally and returns the paths
+// where they differ: pointers are followed, slices and arrays are compared
+// element by element and maps key by key.
+func (s *RPCServer) DiffVariables(arg DiffVariablesIn, out *DiffVariablesOut) error {
+	diffs, err := s.debugger.DiffVariables(arg.Scope, arg.Expr1, arg.Expr2, arg.MaxDepth)
+	if err != nil {
+		return err
+	}
+	out.Differences = diffs
+	return nil
+}
+
+type ExportVariableIn struct {
+	Scope api.EvalScope
+	Expr  string
+	// JSON requests a JSON document instead of a Go expression.
+	JSON bool
+	// MaxDepth is how deep pointers are followed, the default is used if it
+	// is zero.
+	MaxDepth int
+}
+
+type ExportVariableOut struct {
+	Text string
+}
+
+// ExportVariable returns the value of an expression written as a Go
+// expression, with composite literals for structs, arrays, slices and
+// maps, or as a JSON document.
+func (s *RPCServer) ExportVariable(arg ExportVariableIn, out *ExportVariableOut) error {
+	text, err := s.debugger.ExportVariable(arg.Scope, arg.Expr, arg.JSON, arg.MaxDepth)
+	if err != nil {
+		return err
+	}
+	out.Text = text
+	return nil
+}
+
+type TraverseIn struct {
+	Scope api.EvalScope
+	Expr  string
+	// Fields are the fields followed from each node, all the fields that
+	// point to nodes are followed if it is empty.
+	Fields []string
+	// MaxNodes is the maximum number of nodes visited, the default is used
+	// if it is zero.
+	MaxNodes int
+	Cfg      *api.LoadConfig
+}
+
+type TraverseOut struct {
+	Nodes []api.TraversalNode
+	// Truncated is true if more than MaxNodes nodes are reachable.
+	Truncated bool
+}
+
+// Traverse visits a linked list, a tree or a graph starting from a struct
+// or a pointer to struct, following the pointers to other nodes of the
+// same type. Each node is visited once and the pointers that close a cycle
+// are marked.
+func (s *RPCServer) Traverse(arg TraverseIn, out *TraverseOut) error {
+	cfg := arg.Cfg
+	if cfg == nil {
+		cfg = &api.LoadConfig{MaxStringLen: 64, MaxStructFields: 3}
+	}
+	nodes, truncated, err := s.debugger.Traverse(arg.Scope, arg.Expr, arg.Fields, arg.MaxNodes, *api.LoadConfigToProc(cfg))
+	if err != nil {
+		return err
+	}
+	out.Nodes = nodes
+	out.Truncated = truncated
+	return nil
+}
+
+type SetFormatterIn struct {
+	TypeName string
+	Template string
+}
+
+type SetFormatterOut struct {
+}
+
+// SetFormatter registers a pretty-printer for a struct type: values of the
+// type are summarized by Template, where every occurrence of {path} is
+// replaced by the value of the field path. An empty Template removes the
+// pretty-printer of the type.
+func (s *RPCServer) SetFormatter(arg SetFormatterIn, out *SetFormatterOut) error {
+	return s.debugger.SetFormatter(arg.TypeName, arg.Template)
+}
+
+type ListFormattersIn struct {
+}
+
+type ListFormattersOut struct {
+	// Formatters maps type names to the templates of their pretty-printers.
+	Formatters map[string]string
+}
+
+// ListFormatters returns the pretty-printers registered with SetFormatter.
+func (s *RPCServer) ListFormatters(arg ListFormattersIn, out *ListFormattersOut) error {
+	out.Formatters = s.debugger.Formatters()
+	return nil
+}
+
 type IsMulticlientIn struct {
 }
 
@@ -24,7 +24,7 @@ import (
 	"github.com/derekparker/delve/service/rpccommon"
 )
 
-var normalLoadConfig = api.LoadConfig{true, 1, 64, 64, -1}
+var normalLoadConfig = api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
 var testBackend string
 
 func TestMain(m *testing.M) {
@@ -233,6 +233,31 @@ func TestClientServer_step(t *testing.T) {
 	})
 }
 
+func TestClientServer_nextCount(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("testnextprog", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.testnext", Line: -1})
+		assertNoError(err, t, "CreateBreakpoint()")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		state, err = c.NextCount("", 4)
+		assertNoError(err, t, "NextCount()")
+		if state.Steps != 4 || len(state.StepLocations) != 4 {
+			t.Fatalf("wrong number of steps %d (locations %v)", state.Steps, state.StepLocations)
+		}
+		last := state.StepLocations[len(state.StepLocations)-1]
+		if last.Line != state.CurrentThread.Line || last.PC != state.CurrentThread.PC {
+			t.Errorf("last step location %s:%d %#x does not match the current thread %s:%d %#x", last.File, last.Line, last.PC, state.CurrentThread.File, state.CurrentThread.Line, state.CurrentThread.PC)
+		}
+		for i := 1; i < len(state.StepLocations); i++ {
+			if state.StepLocations[i].PC == state.StepLocations[i-1].PC {
+				t.Errorf("step %d did not move: %#x", i+1, state.StepLocations[i].PC)
+			}
+		}
+	})
+}
+
 func TestClientServer_stepout(t *testing.T) {
 	protest.AllowRecording(t)
 	withTestClient2("testnextprog", t, func(c service.Client) {
@@ -476,7 +501,7 @@ func TestClientServer_infoLocals(t *testing.T) {
 		if state.Err != nil {
 			t.Fatalf("Unexpected error: %v, state: %#v", state.Err, state)
 		}
-		locals, err := c.ListLocalVariables(api.EvalScope{-1, 0}, normalLoadConfig)
+		locals, err := c.ListLocalVariables(api.EvalScope{GoroutineID: -1}, normalLoadConfig)
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -505,7 +530,7 @@ func TestClientServer_infoArgs(t *testing.T) {
 		if len(regs) == 0 {
 			t.Fatal("Expected string showing registers values, got empty string")
 		}
-		locals, err := c.ListFunctionArgs(api.EvalScope{-1, 0}, normalLoadConfig)
+		locals, err := c.ListFunctionArgs(api.EvalScope{GoroutineID: -1}, normalLoadConfig)
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -728,6 +753,25 @@ func TestClientServer_FindLocationsAddr(t *testing.T) {
 	})
 }
 
+func TestClientServer_FindLocationsReturns(t *testing.T) {
+	withTestClient2("locationsprog", t, func(c service.Client) {
+		addrs := findLocationHelper(t, c, "main.anotherFunction:return", false, -1, 0)
+		if len(addrs) == 0 {
+			t.Fatal("no return locations found for main.anotherFunction")
+		}
+		for _, addr := range addrs {
+			locs, err := c.FindLocation(api.EvalScope{GoroutineID: -1}, fmt.Sprintf("*%#x", addr))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if locs[0].Function == nil || locs[0].Function.Name() != "main.anotherFunction" {
+				t.Fatalf("return location %#x outside of main.anotherFunction: %#v", addr, locs[0])
+			}
+		}
+		findLocationHelper(t, c, "main.nonexistent:return", true, 0, 0)
+	})
+}
+
 func TestClientServer_FindLocationsExactMatch(t *testing.T) {
 	// if an expression matches multiple functions but one of them is an exact
 	// match it should be used anyway.
@@ -748,7 +792,7 @@ func TestClientServer_EvalVariable(t *testing.T) {
 			t.Fatalf("Continue(): %v\n", state.Err)
 		}
 
-		var1, err := c.EvalVariable(api.EvalScope{-1, 0}, "a1", normalLoadConfig)
+		var1, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "a1", normalLoadConfig)
 		assertNoError(err, t, "EvalVariable")
 
 		t.Logf("var1: %s", var1.SinglelineString())
@@ -767,9 +811,9 @@ func TestClientServer_SetVariable(t *testing.T) {
 			t.Fatalf("Continue(): %v\n", state.Err)
 		}
 
-		assertNoError(c.SetVariable(api.EvalScope{-1, 0}, "a2", "8"), t, "SetVariable()")
+		assertNoError(c.SetVariable(api.EvalScope{GoroutineID: -1}, "a2", "8"), t, "SetVariable()")
 
-		a2, err := c.EvalVariable(api.EvalScope{-1, 0}, "a2", normalLoadConfig)
+		a2, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "a2", normalLoadConfig)
 		if err != nil {
 			t.Fatalf("Could not evaluate variable: %v", err)
 		}
@@ -902,10 +946,10 @@ func TestIssue355(t *testing.T) {
 		assertError(err, t, "ListThreads()")
 		_, err = c.GetThread(tid)
 		assertError(err, t, "GetThread()")
-		assertError(c.SetVariable(api.EvalScope{gid, 0}, "a", "10"), t, "SetVariable()")
-		_, err = c.ListLocalVariables(api.EvalScope{gid, 0}, normalLoadConfig)
+		assertError(c.SetVariable(api.EvalScope{GoroutineID: gid}, "a", "10"), t, "SetVariable()")
+		_, err = c.ListLocalVariables(api.EvalScope{GoroutineID: gid}, normalLoadConfig)
 		assertError(err, t, "ListLocalVariables()")
-		_, err = c.ListFunctionArgs(api.EvalScope{gid, 0}, normalLoadConfig)
+		_, err = c.ListFunctionArgs(api.EvalScope{GoroutineID: gid}, normalLoadConfig)
 		assertError(err, t, "ListFunctionArgs()")
 		_, err = c.ListRegisters(0, false)
 		assertError(err, t, "ListRegisters()")
@@ -913,9 +957,9 @@ func TestIssue355(t *testing.T) {
 		assertError(err, t, "ListGoroutines()")
 		_, err = c.Stacktrace(gid, 10, false, &normalLoadConfig)
 		assertError(err, t, "Stacktrace()")
-		_, err = c.FindLocation(api.EvalScope{gid, 0}, "+1")
+		_, err = c.FindLocation(api.EvalScope{GoroutineID: gid}, "+1")
 		assertError(err, t, "FindLocation()")
-		_, err = c.DisassemblePC(api.EvalScope{-1, 0}, 0x40100, api.IntelFlavour)
+		_, err = c.DisassemblePC(api.EvalScope{GoroutineID: -1}, 0x40100, api.IntelFlavour)
 		assertError(err, t, "DisassemblePC()")
 	})
 }
@@ -930,12 +974,12 @@ func TestDisasm(t *testing.T) {
 		state := <-ch
 		assertNoError(state.Err, t, "Continue()")
 
-		locs, err := c.FindLocation(api.EvalScope{-1, 0}, "main.main")
+		locs, err := c.FindLocation(api.EvalScope{GoroutineID: -1}, "main.main")
 		assertNoError(err, t, "FindLocation()")
 		if len(locs) != 1 {
 			t.Fatalf("wrong number of locations for main.main: %d", len(locs))
 		}
-		d1, err := c.DisassemblePC(api.EvalScope{-1, 0}, locs[0].PC, api.IntelFlavour)
+		d1, err := c.DisassemblePC(api.EvalScope{GoroutineID: -1}, locs[0].PC, api.IntelFlavour)
 		assertNoError(err, t, "DisassemblePC()")
 		if len(d1) < 2 {
 			t.Fatalf("wrong size of disassembly: %d", len(d1))
@@ -943,7 +987,7 @@ func TestDisasm(t *testing.T) {
 
 		pcstart := d1[0].Loc.PC
 		pcend := d1[len(d1)-1].Loc.PC + uint64(len(d1[len(d1)-1].Bytes))
-		d2, err := c.DisassembleRange(api.EvalScope{-1, 0}, pcstart, pcend, api.IntelFlavour)
+		d2, err := c.DisassembleRange(api.EvalScope{GoroutineID: -1}, pcstart, pcend, api.IntelFlavour)
 		assertNoError(err, t, "DisassembleRange()")
 
 		if len(d1) != len(d2) {
@@ -952,7 +996,7 @@ func TestDisasm(t *testing.T) {
 			t.Fatal("mismatched length between disassemble pc and disassemble range")
 		}
 
-		d3, err := c.DisassemblePC(api.EvalScope{-1, 0}, state.CurrentThread.PC, api.IntelFlavour)
+		d3, err := c.DisassemblePC(api.EvalScope{GoroutineID: -1}, state.CurrentThread.PC, api.IntelFlavour)
 		assertNoError(err, t, "DisassemblePC() - second call")
 
 		if len(d1) != len(d3) {
@@ -996,7 +1040,7 @@ func TestDisasm(t *testing.T) {
 			state, err := c.StepInstruction()
 			assertNoError(err, t, fmt.Sprintf("StepInstruction() %d", count))
 
-			d3, err = c.DisassemblePC(api.EvalScope{-1, 0}, state.CurrentThread.PC, api.IntelFlavour)
+			d3, err = c.DisassemblePC(api.EvalScope{GoroutineID: -1}, state.CurrentThread.PC, api.IntelFlavour)
 			assertNoError(err, t, fmt.Sprintf("StepInstruction() %d", count))
 
 			curinstr := getCurinstr(d3)
@@ -1061,7 +1105,7 @@ func TestClientServer_CondBreakpoint(t *testing.T) {
 		state := <-c.Continue()
 		assertNoError(state.Err, t, "Continue()")
 
-		nvar, err := c.EvalVariable(api.EvalScope{-1, 0}, "n", normalLoadConfig)
+		nvar, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "n", normalLoadConfig)
 		assertNoError(err, t, "EvalVariable()")
 
 		if nvar.SinglelineString() != "7" {
@@ -1167,14 +1211,14 @@ func TestTypesCommand(t *testing.T) {
 func TestIssue406(t *testing.T) {
 	protest.AllowRecording(t)
 	withTestClient2("issue406", t, func(c service.Client) {
-		locs, err := c.FindLocation(api.EvalScope{-1, 0}, "issue406.go:146")
+		locs, err := c.FindLocation(api.EvalScope{GoroutineID: -1}, "issue406.go:146")
 		assertNoError(err, t, "FindLocation()")
 		_, err = c.CreateBreakpoint(&api.Breakpoint{Addr: locs[0].PC})
 		assertNoError(err, t, "CreateBreakpoint()")
 		ch := c.Continue()
 		state := <-ch
 		assertNoError(state.Err, t, "Continue()")
-		v, err := c.EvalVariable(api.EvalScope{-1, 0}, "cfgtree", normalLoadConfig)
+		v, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "cfgtree", normalLoadConfig)
 		assertNoError(err, t, "EvalVariable()")
 		vs := v.MultilineString("")
 		t.Logf("cfgtree formats to: %s\n", vs)
@@ -1186,7 +1230,7 @@ func TestEvalExprName(t *testing.T) {
 		state := <-c.Continue()
 		assertNoError(state.Err, t, "Continue()")
 
-		var1, err := c.EvalVariable(api.EvalScope{-1, 0}, "i1+1", normalLoadConfig)
+		var1, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "i1+1", normalLoadConfig)
 		assertNoError(err, t, "EvalVariable")
 
 		const name = "i1+1"
@@ -1501,7 +1545,7 @@ func TestAcceptMulticlient(t *testing.T) {
 }
 
 func mustHaveDebugCalls(t *testing.T, c service.Client) {
-	locs, err := c.FindLocation(api.EvalScope{-1, 0}, "runtime.debugCallV1")
+	locs, err := c.FindLocation(api.EvalScope{GoroutineID: -1}, "runtime.debugCallV1")
 	if len(locs) == 0 || err != nil {
 		t.Skip("function calls not supported on this version of go")
 	}
@@ -1546,7 +1590,7 @@ func TestClientServerFunctionCallBadPos(t *testing.T) {
 	}
 	withTestClient2("fncall", t, func(c service.Client) {
 		mustHaveDebugCalls(t, c)
-		loc, err := c.FindLocation(api.EvalScope{-1, 0}, "fmt/print.go:649")
+		loc, err := c.FindLocation(api.EvalScope{GoroutineID: -1}, "fmt/print.go:649")
 		assertNoError(err, t, "could not find location")
 
 		_, err = c.CreateBreakpoint(&api.Breakpoint{File: loc[0].File, Line: loc[0].Line})
@@ -1599,7 +1643,7 @@ func TestClientServerFunctionCallStacktrace(t *testing.T) {
 	}
 	withTestClient2("fncall", t, func(c service.Client) {
 		mustHaveDebugCalls(t, c)
-		c.SetReturnValuesLoadConfig(&api.LoadConfig{false, 0, 2048, 0, 0})
+		c.SetReturnValuesLoadConfig(&api.LoadConfig{MaxStringLen: 2048})
 		state := <-c.Continue()
 		assertNoError(state.Err, t, "Continue()")
 		state, err := c.Call("callstacktrace()")
@@ -1619,3 +1663,606 @@ func TestClientServerFunctionCallStacktrace(t *testing.T) {
 		}
 	})
 }
+
+func TestClientServerFunctionCallInExpression(t *testing.T) {
+	if runtime.GOOS != "linux" || testBackend != "native" {
+		t.Skip("unsupported")
+	}
+	withTestClient2("fncall", t, func(c service.Client) {
+		mustHaveDebugCalls(t, c)
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		beforeCallFn := state.CurrentThread.Function.Name()
+
+		testcases := []struct {
+			expr string
+			val  string
+		}{
+			{"call1(one, two)", "3"},
+			{"call1(one, 10)", "11"},
+			{"call1(call1(one, two), two) + 1", "6"},
+			{"a.VRcvrable(1)", "4"},
+			{"pa.PRcvrable(call1(one, one))", "8"},
+			{"pa.VRcvrable(2)", "8"},
+			{"pb.VRcvrable(1)", "10"},
+			{"pb.PRcvrable(2)", "11"},
+			{"pc.VRcvrable(2)", "8"},
+			{"pc.PRcvrable(2)", "3"},
+			{"ipc.(main.vrcvrabler).VRcvrable(3)", "12"},
+			{"err.Error()", "custom: failed"},
+			{"len(err.Error())", "14"},
+		}
+		for _, tc := range testcases {
+			v, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, tc.expr, normalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%q)", tc.expr))
+			if v.Value != tc.val {
+				t.Errorf("%s: got %s expected %s", tc.expr, v.Value, tc.val)
+			}
+		}
+
+		if _, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "callpanic()", normalLoadConfig); err == nil {
+			t.Fatal("no error evaluating callpanic()")
+		}
+
+		if _, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "e.VRcvrable(1)", normalLoadConfig); err == nil || !strings.Contains(err.Error(), "ambiguous selector") {
+			t.Errorf("e.VRcvrable(1): wrong error %v", err)
+		}
+		if _, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "ie.(main.vrcvrabler)", normalLoadConfig); err == nil || !strings.Contains(err.Error(), "missing method VRcvrable") {
+			t.Errorf("ie.(main.vrcvrabler): wrong error %v", err)
+		}
+
+		for _, expr := range []string{"&makeastruct(1)", "&makeastruct(1).X"} {
+			if _, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, expr, normalLoadConfig); err == nil || !strings.Contains(err.Error(), "result of a function call") {
+				t.Errorf("%s: wrong error %v", expr, err)
+			}
+		}
+
+		state, err := c.GetState()
+		assertNoError(err, t, "GetState()")
+		if state.CurrentThread.Function.Name() != beforeCallFn {
+			t.Fatalf("did not return to the calling function %q %q", beforeCallFn, state.CurrentThread.Function.Name())
+		}
+	})
+}
+
+func TestClientServerSetMapKey(t *testing.T) {
+	if runtime.GOOS != "linux" || testBackend != "native" {
+		t.Skip("unsupported")
+	}
+	withTestClient2("fncall", t, func(c service.Client) {
+		mustHaveDebugCalls(t, c)
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		assertNoError(c.SetVariable(api.EvalScope{GoroutineID: -1}, "mi[1]", "10"), t, "SetVariable(mi[1])")
+		assertNoError(c.SetVariable(api.EvalScope{GoroutineID: -1}, "mi[-5]", "25"), t, "SetVariable(mi[-5])")
+		assertNoError(c.SetVariable(api.EvalScope{GoroutineID: -1}, "m[s]", "2"), t, "SetVariable(m[s])")
+		if err := c.SetVariable(api.EvalScope{GoroutineID: -1}, "m[\"three\"]", "3"); err == nil {
+			t.Fatal("no error adding a constant string key")
+		}
+
+		for _, tc := range []struct{ expr, val string }{{"mi[1]", "10"}, {"mi[-5]", "25"}, {"m[\"two\"]", "2"}, {"len(m)", "2"}} {
+			v, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, tc.expr, normalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%q)", tc.expr))
+			if v.Value != tc.val {
+				t.Errorf("%s: got %s expected %s", tc.expr, v.Value, tc.val)
+			}
+		}
+	})
+}
+
+func TestClientServerEvalChildren(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("testvariables2", t, func(c service.Client) {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		keys := map[string]bool{}
+		for offset := 0; offset < 50; offset += 10 {
+			m1, err := c.EvalVariableChildren(api.EvalScope{GoroutineID: -1}, "m1", offset, 10, normalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariableChildren(m1, %d)", offset))
+			if m1.Len != 41 {
+				t.Fatalf("wrong length of m1 %d", m1.Len)
+			}
+			if n := len(m1.Children) / 2; n != 10 && !(offset == 40 && n == 1) {
+				t.Fatalf("wrong number of entries at offset %d: %d", offset, n)
+			}
+			for i := 0; i < len(m1.Children); i += 2 {
+				keys[m1.Children[i].Value] = true
+			}
+		}
+		if len(keys) != 41 {
+			t.Fatalf("wrong number of distinct keys %d", len(keys))
+		}
+
+		for _, tc := range []struct{ offset, n int }{{0, 30}, {30, 30}, {60, 4}, {64, 0}} {
+			arr, err := c.EvalVariableChildren(api.EvalScope{GoroutineID: -1}, "bencharr", tc.offset, 30, normalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariableChildren(bencharr, %d)", tc.offset))
+			if arr.Len != 64 || len(arr.Children) != tc.n {
+				t.Fatalf("offset %d: wrong length %d or number of children %d", tc.offset, arr.Len, len(arr.Children))
+			}
+			if tc.n > 0 && arr.Flags&api.VariableTruncated == 0 {
+				t.Fatalf("offset %d: truncated flag not set", tc.offset)
+			}
+		}
+
+		if _, err := c.EvalVariableChildren(api.EvalScope{GoroutineID: -1}, "bencharr", 65, 30, normalLoadConfig); err == nil {
+			t.Fatal("no error for offset out of bounds")
+		}
+		if _, err := c.EvalVariableChildren(api.EvalScope{GoroutineID: -1}, "i1", 0, 30, normalLoadConfig); err == nil {
+			t.Fatal("no error for non-container variable")
+		}
+	})
+}
+
+func TestClientServer_SaveLoadBreakpoints(t *testing.T) {
+	protest.AllowRecording(t)
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("dlvbps%d.json", os.Getpid()))
+	defer os.Remove(path)
+
+	withTestClient2("testprog", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.helloworld", Line: 1, Name: "hello", Cond: "true"})
+		assertNoError(err, t, "CreateBreakpoint()")
+		assertNoError(c.SaveBreakpoints(path), t, "SaveBreakpoints()")
+	})
+
+	withTestClient2("testprog", t, func(c service.Client) {
+		bps, discarded, err := c.LoadBreakpoints(path)
+		assertNoError(err, t, "LoadBreakpoints()")
+		if len(discarded) != 0 {
+			t.Fatalf("discarded breakpoints: %v", discarded)
+		}
+		if len(bps) != 1 || bps[0].Name != "hello" || bps[0].Cond != "true" || bps[0].FunctionName != "main.helloworld" {
+			t.Fatalf("wrong breakpoints loaded: %#v", bps)
+		}
+
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		if state.CurrentThread.Breakpoint == nil || state.CurrentThread.Breakpoint.Name != "hello" {
+			t.Fatalf("stopped at wrong breakpoint: %v", state.CurrentThread.Breakpoint)
+		}
+
+		// loading them twice should discard them all
+		_, discarded, err = c.LoadBreakpoints(path)
+		assertNoError(err, t, "LoadBreakpoints()")
+		if len(discarded) != 1 {
+			t.Fatalf("expected one discarded breakpoint, got %v", discarded)
+		}
+	})
+}
+
+func TestClientServer_DisableBreakpoint(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("testprog", t, func(c service.Client) {
+		bphello, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.helloworld", Line: 1})
+		assertNoError(err, t, "CreateBreakpoint(main.helloworld)")
+		bpsleepy, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.sleepytime", Line: 1, Cond: "true"})
+		assertNoError(err, t, "CreateBreakpoint(main.sleepytime)")
+
+		assertBreakpoint := func(id int) {
+			state := <-c.Continue()
+			assertNoError(state.Err, t, "Continue()")
+			if state.CurrentThread.Breakpoint == nil || state.CurrentThread.Breakpoint.ID != id {
+				t.Fatalf("stopped at wrong breakpoint %v, expected %d", state.CurrentThread.Breakpoint, id)
+			}
+		}
+
+		assertBreakpoint(bpsleepy.ID)
+
+		bpsleepy, err = c.GetBreakpoint(bpsleepy.ID)
+		assertNoError(err, t, "GetBreakpoint()")
+		bpsleepy.Disabled = true
+		assertNoError(c.AmendBreakpoint(bpsleepy), t, "AmendBreakpoint()")
+
+		assertBreakpoint(bphello.ID)
+		assertBreakpoint(bphello.ID)
+
+		bpsleepy, err = c.GetBreakpoint(bpsleepy.ID)
+		assertNoError(err, t, "GetBreakpoint()")
+		if !bpsleepy.Disabled || bpsleepy.TotalHitCount != 1 || bpsleepy.Cond != "true" {
+			t.Fatalf("wrong disabled breakpoint %#v", bpsleepy)
+		}
+
+		bpsleepy.Disabled = false
+		assertNoError(c.AmendBreakpoint(bpsleepy), t, "AmendBreakpoint()")
+		assertBreakpoint(bpsleepy.ID)
+	})
+}
+
+func TestClientServer_WatchExpressions(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("testnextprog", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.helloworld", Line: 1})
+		assertNoError(err, t, "CreateBreakpoint()")
+		_, err = c.SetWatchExpressions([]string{"1 + 2", "nonexistentvariable"})
+		assertNoError(err, t, "SetWatchExpressions()")
+
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		if len(state.WatchExpressions) != 2 {
+			t.Fatalf("wrong number of watch expressions: %v", state.WatchExpressions)
+		}
+		if v := state.WatchExpressions[0]; v.Value.Name != "1 + 2" || v.Value.Value != "3" {
+			t.Fatalf("wrong value for watch expression: %#v", v)
+		}
+		if v := state.WatchExpressions[1]; v.Value.Unreadable == "" {
+			t.Fatalf("expected error for watch expression: %#v", v)
+		}
+
+		state, err = c.Next()
+		assertNoError(err, t, "Next()")
+		if len(state.WatchExpressions) != 2 {
+			t.Fatalf("watch expressions not evaluated after next: %v", state.WatchExpressions)
+		}
+	})
+}
+
+func TestClientServer_Display(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("testnextprog", t, func(c service.Client) {
+		fp := testProgPath(t, "testnextprog")
+		_, err := c.CreateBreakpoint(&api.Breakpoint{File: fp, Line: 24})
+		assertNoError(err, t, "CreateBreakpoint()")
+		ids, err := c.SetWatchExpressions([]string{"i", "f", "nonexistentvariable"})
+		assertNoError(err, t, "SetWatchExpressions()")
+		idi, idf := ids[0], ids[1]
+
+		assertDisplay := func(state *api.DebuggerState, i string, changed bool, delta string) {
+			if len(state.WatchExpressions) != 3 {
+				t.Fatalf("wrong number of display expressions: %#v", state.WatchExpressions)
+			}
+			if de := state.WatchExpressions[0]; de.ID != idi || de.Value.Value != i || de.Changed != changed || de.Delta != delta {
+				t.Errorf("wrong value for i: %#v", de)
+			}
+			if de := state.WatchExpressions[1]; de.ID != idf || de.Value.Value != "2" || de.Changed {
+				t.Errorf("wrong value for f: %#v", de)
+			}
+			if de := state.WatchExpressions[2]; de.Value.Unreadable == "" {
+				t.Errorf("expected error for nonexistentvariable: %#v", de)
+			}
+		}
+
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		assertDisplay(state, "0", false, "")
+
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		assertDisplay(state, "1", true, "1")
+
+		state, err = c.GetState()
+		assertNoError(err, t, "GetState()")
+		assertDisplay(state, "1", true, "1")
+
+		ids, err = c.SetWatchExpressions([]string{"i", "nonexistentvariable"})
+		assertNoError(err, t, "SetWatchExpressions()")
+		if len(ids) != 2 || ids[0] != idi || ids[1] == idf {
+			t.Errorf("wrong ids after removing f: %v (i: %d, f: %d)", ids, idi, idf)
+		}
+		state, err = c.GetState()
+		assertNoError(err, t, "GetState()")
+		if len(state.WatchExpressions) != 2 || state.WatchExpressions[0].Value.Value != "1" {
+			t.Errorf("watch expression not removed: %#v", state.WatchExpressions)
+		}
+	})
+}
+
+func TestDiffVariables(t *testing.T) {
+	withTestClient2("diffvars", t, func(c service.Client) {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		scope := api.EvalScope{GoroutineID: -1}
+
+		diffs, err := c.DiffVariables(scope, "c1", "c2", 0)
+		assertNoError(err, t, "DiffVariables(c1, c2)")
+		expected := map[string][2]string{
+			".Retries":        {"3", "4"},
+			".Tags[1]":        {`"y"`, `"z"`},
+			".Tags[2]":        {"", `"w"`},
+			`.Limits["mem"]`:  {"2", ""},
+			`.Limits["disk"]`: {"", "3"},
+		}
+		if len(diffs) != len(expected) {
+			t.Errorf("wrong number of differences: %d", len(diffs))
+		}
+		for _, diff := range diffs {
+			values := [2]string{}
+			for i, v := range []*api.Variable{diff.A, diff.B} {
+				if v != nil {
+					values[i] = v.SinglelineString()
+				}
+			}
+			if exp, ok := expected[diff.Path]; !ok || values != exp {
+				t.Errorf("wrong difference %q: %v, expected %v", diff.Path, values, exp)
+			}
+		}
+
+		diffs, err = c.DiffVariables(scope, "c1.Head", "c2.Head", 0)
+		assertNoError(err, t, "DiffVariables(c1.Head, c2.Head)")
+		if len(diffs) != 0 {
+			t.Errorf("differences found in equal cyclic lists: %#v", diffs)
+		}
+
+		assertNoError(c.SaveVariableSnapshot(scope, "c1", 0), t, "SaveVariableSnapshot(c1)")
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		diffs, err = c.DiffVariables(scope, "c1", "", 0)
+		assertNoError(err, t, "DiffVariables(c1)")
+		if len(diffs) != 1 || diffs[0].Path != ".Retries" || diffs[0].A.Value != "3" || diffs[0].B.Value != "5" {
+			t.Errorf("wrong differences from snapshot: %#v", diffs)
+		}
+		if _, err := c.DiffVariables(scope, "c2", "", 0); err == nil {
+			t.Error("no error comparing with a missing snapshot")
+		}
+	})
+}
+
+func TestExpandVariable(t *testing.T) {
+	withTestClient2("diffvars", t, func(c service.Client) {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		cfg := api.LoadConfig{FollowPointers: true, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+
+		c1, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "c1", api.LoadConfig{FollowPointers: true, MaxStringLen: 64, MaxArrayValues: 1, MaxStructFields: -1})
+		assertNoError(err, t, "EvalVariable(c1)")
+		if c1.Handle != 0 {
+			t.Errorf("handle assigned to completely loaded struct: %d", c1.Handle)
+		}
+		var head, tags *api.Variable
+		for i := range c1.Children {
+			switch c1.Children[i].Name {
+			case "Head":
+				head = &c1.Children[i].Children[0]
+			case "Tags":
+				tags = &c1.Children[i]
+			}
+		}
+		if head == nil || head.Handle == 0 || len(head.Children) != 0 {
+			t.Fatalf("wrong c1.Head: %#v", head)
+		}
+		if tags == nil || tags.Handle == 0 || len(tags.Children) != 1 {
+			t.Fatalf("wrong c1.Tags: %#v", tags)
+		}
+
+		v, err := c.ExpandVariable(head.Handle, cfg)
+		assertNoError(err, t, "ExpandVariable(c1.Head)")
+		if len(v.Children) != 3 || v.Children[0].Value != "a" {
+			t.Fatalf("wrong expanded c1.Head: %#v", v)
+		}
+		// c1.Head.next is c1.Head itself
+		if next := v.Children[2].Children[0]; next.Handle != head.Handle {
+			t.Errorf("wrong handle for c1.Head.next: %d, expected %d", next.Handle, head.Handle)
+		}
+
+		v, err = c.ExpandVariable(tags.Handle, cfg)
+		assertNoError(err, t, "ExpandVariable(c1.Tags)")
+		if len(v.Children) != 2 || v.Children[1].Value != "y" {
+			t.Errorf("wrong expanded c1.Tags: %#v", v)
+		}
+
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		if _, err := c.ExpandVariable(head.Handle, cfg); err == nil {
+			t.Error("handle still valid after continue")
+		}
+	})
+}
+
+func TestStopOnEntry(t *testing.T) {
+	if testBackend == "rr" {
+		protest.MustHaveRecordingAllowed(t)
+	}
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("couldn't start listener: %s\n", err)
+	}
+	defer listener.Close()
+	server := rpccommon.NewServer(&service.Config{
+		Listener:    listener,
+		ProcessArgs: []string{protest.BuildFixture("continuetestprog", 0).Path},
+		Backend:     testBackend,
+		StopOnEntry: "runtime.main",
+	})
+	if err := server.Run(); err != nil {
+		t.Fatal(err)
+	}
+	c := rpc2.NewClient(listener.Addr().String())
+	defer func() {
+		dir, _ := c.TraceDirectory()
+		c.Detach(true)
+		if dir != "" {
+			protest.SafeRemoveAll(dir)
+		}
+	}()
+
+	assertStoppedAt := func(descr string) {
+		state, err := c.GetState()
+		assertNoError(err, t, "GetState")
+		if state.CurrentThread == nil || state.CurrentThread.Function == nil || state.CurrentThread.Function.Name() != "runtime.main" {
+			t.Fatalf("not stopped in runtime.main %s: %#v", descr, state.CurrentThread)
+		}
+	}
+	assertStoppedAt("after launch")
+	_, err = c.Restart()
+	assertNoError(err, t, "Restart")
+	assertStoppedAt("after restart")
+}
+
+func TestGoroutineLabels(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("goroutinelabels", t, func(c service.Client) {
+		fp := testProgPath(t, "goroutinelabels")
+		_, err := c.CreateBreakpoint(&api.Breakpoint{File: fp, Line: 11, Cond: `labels["request-id"] == "abc122"`})
+		assertNoError(err, t, "CreateBreakpoint")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+
+		if g := state.SelectedGoroutine; g == nil || g.Labels["request-id"] != "abc122" {
+			t.Fatalf("wrong labels for the selected goroutine %v", g)
+		}
+
+		gs, _, err := c.ListGoroutinesFiltered(api.GoroutineFilter{Labels: map[string]string{"request-id": "abc122"}}, 0, 0)
+		assertNoError(err, t, "ListGoroutinesFiltered")
+		if len(gs) != 1 || gs[0].ID != state.SelectedGoroutine.ID {
+			t.Fatalf("wrong goroutines with label request-id=abc122: %v", gs)
+		}
+	})
+}
+
+func TestGoroutineDiff(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("goroutinestackprog", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.main"})
+		assertNoError(err, t, "CreateBreakpoint(main.main)")
+		_, err = c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.stacktraceme"})
+		assertNoError(err, t, "CreateBreakpoint(main.stacktraceme)")
+
+		countAgoroutines := func(gs []*api.Goroutine) int {
+			n := 0
+			for _, g := range gs {
+				if g.StartLoc.Function != nil && g.StartLoc.Function.Name() == "main.agoroutine" {
+					if g.GoStatementLoc.Function == nil || g.GoStatementLoc.Function.Name() != "main.main" {
+						t.Errorf("wrong creation location for goroutine %d: %#v", g.ID, g.GoStatementLoc)
+					}
+					n++
+				}
+			}
+			return n
+		}
+
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		_, err = c.MarkGoroutines()
+		assertNoError(err, t, "MarkGoroutines")
+
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		diff, err := c.GoroutineDiff()
+		assertNoError(err, t, "GoroutineDiff")
+		if n := countAgoroutines(diff.Created); n != 10 {
+			t.Errorf("wrong number of created goroutines %d: %v", n, diff.Created)
+		}
+		_, err = c.MarkGoroutines()
+		assertNoError(err, t, "MarkGoroutines")
+
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		diff, err = c.GoroutineDiff()
+		assertNoError(err, t, "GoroutineDiff")
+		if n := countAgoroutines(diff.Exited); n != 10 {
+			t.Errorf("wrong number of exited goroutines %d: %v", n, diff.Exited)
+		}
+		if n := countAgoroutines(diff.Created); n != 0 {
+			t.Errorf("wrong number of created goroutines %d: %v", n, diff.Created)
+		}
+	})
+}
+
+func TestSampleStacks(t *testing.T) {
+	withTestClient2("loopprog", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.loop"})
+		assertNoError(err, t, "CreateBreakpoint(main.loop)")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		state, err = c.SampleStacks(200 * time.Millisecond)
+		assertNoError(err, t, "SampleStacks")
+		if len(state.Samples) == 0 {
+			t.Fatal("no stacks sampled")
+		}
+		found := false
+		for _, sample := range state.Samples {
+			for _, loc := range sample.Stack {
+				if loc.Function != nil && loc.Function.Name() == "main.loop" {
+					found = true
+				}
+			}
+		}
+		if !found {
+			t.Errorf("main.loop not sampled: %#v", state.Samples)
+		}
+	})
+}
+
+func TestFindPackageVariables(t *testing.T) {
+	withTestClient2("databpeasy", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.main"})
+		assertNoError(err, t, "CreateBreakpoint(main.main)")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		vars, err := c.FindPackageVariables("main", "globalvar", nil)
+		assertNoError(err, t, "FindPackageVariables(main)")
+		if len(vars) != 2 {
+			t.Fatalf("wrong number of variables: %#v", vars)
+		}
+		for _, v := range vars {
+			if v.Type != "int" || v.Value != "" || v.Addr == 0 {
+				t.Errorf("wrong variable %s: type %q value %q addr %#x", v.Name, v.Type, v.Value, v.Addr)
+			}
+		}
+
+		vars, err = c.FindPackageVariables("main", "globalvar1", &normalLoadConfig)
+		assertNoError(err, t, "FindPackageVariables(main, cfg)")
+		if len(vars) != 1 || vars[0].Name != "main.globalvar1" || vars[0].Value != "0" {
+			t.Errorf("wrong variables: %#v", vars)
+		}
+
+		vars, err = c.FindPackageVariables("runtime", "globalvar", nil)
+		assertNoError(err, t, "FindPackageVariables(runtime)")
+		if len(vars) != 0 {
+			t.Errorf("variables of main returned for package runtime: %#v", vars)
+		}
+	})
+}
+
+func TestClientServer_logpointMessages(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("bpcountstest", t, func(c service.Client) {
+		fp := testProgPath(t, "bpcountstest")
+		_, err := c.CreateBreakpoint(&api.Breakpoint{File: fp, Line: 12, LogMessage: "i = {i}"})
+		assertNoError(err, t, "CreateBreakpoint()")
+
+		var state *api.DebuggerState
+		for state = range c.Continue() {
+		}
+		if !state.Exited {
+			t.Fatalf("expected the process to exit, got %v", state.Err)
+		}
+		if len(state.LogpointMessages) != 200 {
+			t.Fatalf("wrong number of logpoint messages %d", len(state.LogpointMessages))
+		}
+		for _, msg := range state.LogpointMessages {
+			if !strings.HasPrefix(msg.Message, "i = ") || msg.GoroutineID <= 0 {
+				t.Fatalf("wrong logpoint message %#v", msg)
+			}
+		}
+	})
+}
+
+func TestClientServer_breakpointActionResults(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestClient2("bpcountstest", t, func(c service.Client) {
+		fp := testProgPath(t, "bpcountstest")
+		bp, err := c.CreateBreakpoint(&api.Breakpoint{File: fp, Line: 12, Actions: []api.BreakpointAction{{Kind: "eval", Expr: "i"}, {Kind: "stack", Depth: 5}}})
+		assertNoError(err, t, "CreateBreakpoint()")
+
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		results := state.BreakpointActionResults
+		if len(results) != 2 {
+			t.Fatalf("wrong number of breakpoint action results %d", len(results))
+		}
+		for _, result := range results {
+			if result.Err != "" || result.BreakpointID != bp.ID {
+				t.Fatalf("wrong breakpoint action result %#v", result)
+			}
+		}
+		if results[0].Variable == nil || results[0].Variable.Name != "i" {
+			t.Fatalf("wrong eval result %#v", results[0].Variable)
+		}
+		if len(results[1].Stack) == 0 {
+			t.Fatalf("empty stacktrace")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package service_test
 
 import (
 	"fmt"
+	"reflect"
 	"runtime"
 	"sort"
 	"strings"
@@ -16,8 +17,8 @@ import (
 	protest "github.com/derekparker/delve/pkg/proc/test"
 )
 
-var pnormalLoadConfig = proc.LoadConfig{true, 1, 64, 64, -1}
-var pshortLoadConfig = proc.LoadConfig{false, 0, 64, 0, 3}
+var pnormalLoadConfig = proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+var pshortLoadConfig = proc.LoadConfig{MaxStringLen: 64, MaxStructFields: 3}
 
 type varTest struct {
 	name         string
@@ -216,6 +217,47 @@ func TestVariableEvaluation(t *testing.T) {
 	})
 }
 
+func TestSetVariableComposite(t *testing.T) {
+	testcases := []struct {
+		name, value string
+		expected    string
+		err         error
+	}{
+		{"a6", "a11[1]", "main.FooBar {Baz: 2, Bur: \"b\"}", nil},
+		{"a11[0]", "*a7", "main.FooBar {Baz: 5, Bur: \"strum\"}", nil},
+		{"a12[1]", "a11[2]", "main.FooBar {Baz: 3, Bur: \"c\"}", nil},
+		{"a5", "a5[1:3]", "[]int len: 2, cap: 4, [2,3]", nil},
+		{"a9", "a7", "*main.FooBar {Baz: 5, Bur: \"strum\"}", nil},
+		{"a13", "nil", "[]*main.FooBar len: 0, cap: 0, nil", nil},
+		{"a1", "a10", "\"ofo\"", nil},
+		{"a1", "baz", "\"bazburzum\"", nil},
+		{"a6", "a8", "", fmt.Errorf("can not convert value of type main.FooBar2 to main.FooBar")},
+	}
+
+	protest.AllowRecording(t)
+	withTestProcess("testvariables", t, func(p proc.Process, fixture protest.Fixture) {
+		if testBackend == "rr" {
+			t.Skip("can not set variables on recordings")
+		}
+		assertNoError(proc.Continue(p), t, "Continue()")
+		for _, tc := range testcases {
+			err := setVariable(p, tc.name, tc.value)
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Fatalf("%s = %s: expected error %q got %v", tc.name, tc.value, tc.err, err)
+				}
+				continue
+			}
+			assertNoError(err, t, fmt.Sprintf("SetVariable(%s, %s)", tc.name, tc.value))
+			variable, err := evalVariable(p, tc.name, pnormalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", tc.name))
+			if ss := api.ConvertVar(variable).SinglelineString(); ss != tc.expected {
+				t.Fatalf("%s = %s: expected %s got %s", tc.name, tc.value, tc.expected, ss)
+			}
+		}
+	})
+}
+
 func TestVariableEvaluationShort(t *testing.T) {
 	testcases := []varTest{
 		{"a1", true, "\"foofoofoofoofoofoo\"", "", "string", nil},
@@ -565,6 +607,10 @@ func TestEvalExpression(t *testing.T) {
 		{"err1.(*main.astruct)", false, "*main.astruct {A: 1, B: 2}", "(*main.astruct)(0x…", "*main.astruct", nil},
 		{"err1.(*main.bstruct)", false, "", "", "", fmt.Errorf("interface conversion: error is *main.astruct, not *main.bstruct")},
 		{"errnil.(*main.astruct)", false, "", "", "", fmt.Errorf("interface conversion: error is nil, not *main.astruct")},
+		{"iface1.(error)", false, "error(*main.astruct) *{A: 1, B: 2}", "error(*main.astruct) 0x…", "error", nil},
+		{"iface1.(error).(*main.astruct).B", false, "2", "2", "int", nil},
+		{"err1.(interface {})", false, "interface {}(*main.astruct) *{A: 1, B: 2}", "interface {}(*main.astruct) 0x…", "interface {}", nil},
+		{"iface2.(error)", false, "", "", "", fmt.Errorf("interface conversion: string is not error: missing method Error")},
 		{"const1", true, "go/constant.Value(go/constant.int64Val) 3", "go/constant.Value(go/constant.int64Val) 3", "go/constant.Value", nil},
 
 		// combined expressions
@@ -616,6 +662,25 @@ func TestEvalExpression(t *testing.T) {
 		{"c1.pb.a == *(c1.sa[1])", false, "false", "false", "", nil},
 		{"c1.pb.a != *(c1.sa[1])", false, "true", "true", "", nil},
 		{`longstr == "not this"`, false, "false", "false", "", nil},
+		{`longstr == "very long string 0123456789a0123456789b0123456789c0123456789d0123456789e0123456789f0123456789g012345678h90123456789i0123456789j0123456789"`, false, "true", "true", "", nil},
+		{`longstr > "very long string"`, false, "true", "true", "", nil},
+		{`longstr != "very long string 0123456789a0123456789b0123456789c0123456789d0123456789e0123456789f0123456789g012345678h90123456789i0123456789j012345678X"`, false, "true", "true", "", nil},
+		{`len(longstr + "!")`, false, "138", "138", "", nil},
+		{`(longstr + "!")[137]`, false, "33", "33", "", nil},
+		{`str1 + str1 == "0123456789001234567890"`, false, "true", "true", "", nil},
+		{`strings.HasPrefix(longstr, "very long")`, false, "true", "true", "", nil},
+		{`strings.HasPrefix(str1, "1")`, false, "false", "false", "", nil},
+		{`strings.HasSuffix(longstr, "j0123456789")`, false, "true", "true", "", nil},
+		{`strings.HasSuffix(str1, "01234567890123")`, false, "false", "false", "", nil},
+		{`strings.Contains(longstr, "h9012")`, false, "true", "true", "", nil},
+		{`strings.Index(longstr, "h9012")`, false, "103", "103", "", nil},
+		{`strings.Index(str1, "x")`, false, "-1", "-1", "", nil},
+		{`strings.HasPrefix(str1)`, false, "", "", "", fmt.Errorf("wrong number of arguments to strings.HasPrefix: 1")},
+		{`strings.Contains(str1, i1)`, false, "", "", "", fmt.Errorf("invalid argument i1 (type int) for strings.Contains")},
+		{`errnew.Error() == "something went wrong"`, false, "true", "true", "", nil},
+		{`errnew.Error()`, false, `"something went wrong"`, `"something went wrong"`, "string", nil},
+		{`errnew != nil && errnew.Error() != ""`, false, "true", "true", "", nil},
+		{`i1.Error()`, false, "", "", "", fmt.Errorf("i1 is not an error value")},
 
 		// builtins
 		{"cap(parr)", false, "4", "4", "", nil},
@@ -661,6 +726,9 @@ func TestEvalExpression(t *testing.T) {
 		{"nilptr != nil", false, "false", "false", "", nil},
 		{"p1 == nil", false, "false", "false", "", nil},
 		{"p1 != nil", false, "true", "true", "", nil},
+		{"&i1 == p1", false, "true", "true", "", nil},
+		{"&i2 == p1", false, "false", "false", "", nil},
+		{"*&i2", false, "2", "2", "int", nil},
 		{"ch1 == nil", false, "false", "false", "", nil},
 		{"chnil == nil", false, "true", "true", "", nil},
 		{"ch1 == chnil", false, "", "", "", fmt.Errorf("can not compare chan variables")},
@@ -840,6 +908,449 @@ func TestMapEvaluation(t *testing.T) {
 	})
 }
 
+func TestLoadConfigLimits(t *testing.T) {
+	testcases := []struct {
+		name      string
+		cfg       proc.LoadConfig
+		children  int
+		truncated bool
+	}{
+		{"m1", proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1, MaxMapEntries: 10}, 20, true},
+		{"m1", proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 10, MaxStructFields: -1, MaxMapEntries: 64}, 82, false},
+		{"m3", pnormalLoadConfig, 4, false},
+		{"a1", proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 3, MaxStructFields: -1}, 3, true},
+		{"a1", pnormalLoadConfig, 5, false},
+		{"longstr", pnormalLoadConfig, 0, true},
+		{"str1", pnormalLoadConfig, 0, false},
+	}
+
+	protest.AllowRecording(t)
+	withTestProcess("testvariables2", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue() returned an error")
+		for _, tc := range testcases {
+			v, err := evalVariable(p, tc.name, tc.cfg)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", tc.name))
+			if len(v.Children) != tc.children {
+				t.Errorf("%s: wrong number of children %d, expected %d", tc.name, len(v.Children), tc.children)
+			}
+			if truncated := v.Flags&proc.VariableTruncated != 0; truncated != tc.truncated {
+				t.Errorf("%s: truncated flag is %v, expected %v", tc.name, truncated, tc.truncated)
+			}
+		}
+	})
+}
+
+func TestFormatters(t *testing.T) {
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 18, -1, 0, 0, ""}) {
+		t.Skip("fixture uses net/netip")
+	}
+	testcases := []struct {
+		expr  string
+		value string
+	}{
+		{"tm", "2018-03-04 10:30:00.0000005 +0000 UTC"},
+		{"tz", "2018-03-04 10:30:00 +0100 CET"},
+		{"*n", "-123456789012345678901234567890"},
+		{"ip4", "192.168.1.10"},
+		{"ip6", "2001:db8::1"},
+		{"mu", "locked"},
+		{"p", "Alice (30), boss Bob"},
+		{"*boss", "Bob (50), boss nil"},
+		{"tm == \"2018-03-04 10:30:00.0000005 +0000 UTC\"", "true"},
+		{"p != \"Alice (30), boss Bob\"", "false"},
+	}
+
+	formatters := proc.NewFormatters()
+	assertNoError(formatters.RegisterTemplate("main.person", "{Name} ({Age}), boss {Boss.Name}"), t, "RegisterTemplate")
+
+	protest.AllowRecording(t)
+	withTestProcess("prettyprint", t, func(p proc.Process, fixture protest.Fixture) {
+		p.BinInfo().SetFormatters(formatters)
+		assertNoError(proc.Continue(p), t, "Continue() returned an error")
+		for _, tc := range testcases {
+			v, err := evalVariable(p, tc.expr, pnormalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", tc.expr))
+			if value := api.ConvertVar(v).Value; value != tc.value {
+				t.Errorf("%s: expected %q got %q", tc.expr, tc.value, value)
+			}
+		}
+	})
+}
+
+func TestStdlibFormatters(t *testing.T) {
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 21, -1, 0, 0, ""}) {
+		t.Skip("fixture uses errors.Join and the root contexts of go1.21")
+	}
+	testcases := []struct {
+		expr  string
+		value string
+	}{
+		{"d", "1.5s"},
+		{"wg", "counter: 2, waiters: 0"},
+		{"n", "-42"},
+		{"b", "true"},
+		{"p", "nil"},
+		{"v", "hello"},
+		{"*ctx.(*context.valueCtx)", "user: alice -> cancel -> background"},
+		{"*werr.(*fmt.wrapError)", "loading config: open /nonexistent: no such file or directory (wraps *io/fs.PathError -> syscall.Errno(2))"},
+		{"*jerr.(*errors.joinError)", `[*errors.errorString("first"), *fmt.wrapError("loading config: open /nonexistent: no such file or directory")]`},
+		{`wg == "counter: 2, waiters: 0"`, "true"},
+	}
+
+	protest.AllowRecording(t)
+	withTestProcess("stdlibtypes", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue() returned an error")
+		for _, tc := range testcases {
+			v, err := evalVariable(p, tc.expr, pnormalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", tc.expr))
+			if value := api.ConvertVar(v).Value; value != tc.value {
+				t.Errorf("%s: expected %q got %q", tc.expr, tc.value, value)
+			}
+		}
+	})
+}
+
+func TestExportVariables(t *testing.T) {
+	testcases := []struct {
+		expr     string
+		depth    int
+		literal  string
+		jsonText string
+	}{
+		{"c1.Retries", 1, "3", "3"},
+		{"c1.Tags", 1, `[]string{"x", "y"}`, "[\n\t\"x\",\n\t\"y\"\n]"},
+		{"c1.Limits", 1, "map[string]int{\n\t\"cpu\": 1,\n\t\"mem\": 2,\n}", "{\n\t\"cpu\": 1,\n\t\"mem\": 2\n}"},
+		{"*c1.Head", 0, "node{\n\tname: \"a\",\n\tval:  1,\n\tnext: &node{ /* not loaded */ },\n}", "{\n\t\"name\": \"a\",\n\t\"val\": 1,\n\t\"next\": null\n}"},
+		{"c1.Head.name", 0, `"a"`, `"a"`},
+	}
+
+	protest.AllowRecording(t)
+	withTestProcess("diffvars", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue() returned an error")
+		for _, tc := range testcases {
+			v, err := evalVariable(p, tc.expr, proc.ExportLoadConfig(tc.depth))
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", tc.expr))
+			if literal := v.GoLiteral(); literal != tc.literal {
+				t.Errorf("%s: expected Go literal %q got %q", tc.expr, tc.literal, literal)
+			}
+			if jsonText := string(v.JSON()); jsonText != tc.jsonText {
+				t.Errorf("%s: expected JSON %q got %q", tc.expr, tc.jsonText, jsonText)
+			}
+		}
+	})
+}
+
+func TestTraverse(t *testing.T) {
+	testcases := []struct {
+		expr      string
+		fields    []string
+		maxNodes  int
+		paths     []string
+		cycles    map[string][]string
+		truncated bool
+	}{
+		{"list", nil, 10, []string{"", ".next", ".next.next", ".next.next.next", ".next.next.next.next"}, map[string][]string{".next.next.next.next": {"next"}}, false},
+		{"*list", []string{"next"}, 3, []string{"", ".next", ".next.next"}, nil, true},
+		{"root", []string{"left", "right"}, 10, []string{"", ".left", ".left.left", ".right"}, nil, false},
+		{"root", nil, 10, []string{"", ".left", ".left.left", ".right"}, map[string][]string{".left": {"parent"}, ".left.left": {"parent"}, ".right": {"parent"}}, false},
+		{"g[0]", nil, 10, []string{"", ".edges[0]", ".edges[0].edges[0]"}, map[string][]string{".edges[0].edges[0]": {"edges[0]"}}, false},
+	}
+
+	protest.AllowRecording(t)
+	withTestProcess("linkedstructs", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue() returned an error")
+		scope, err := proc.GoroutineScope(p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope()")
+		for _, tc := range testcases {
+			nodes, truncated, err := scope.Traverse(tc.expr, tc.fields, tc.maxNodes, pshortLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("Traverse(%s, %v)", tc.expr, tc.fields))
+			if truncated != tc.truncated {
+				t.Errorf("%s %v: expected truncated %v", tc.expr, tc.fields, tc.truncated)
+			}
+			paths := []string{}
+			cycles := map[string][]string{}
+			for _, node := range nodes {
+				paths = append(paths, node.Path)
+				for _, edge := range node.Edges {
+					if edge.Cycle {
+						cycles[node.Path] = append(cycles[node.Path], edge.Field)
+					}
+				}
+			}
+			if !reflect.DeepEqual(paths, tc.paths) {
+				t.Errorf("%s %v: expected paths %q got %q", tc.expr, tc.fields, tc.paths, paths)
+			}
+			if tc.cycles == nil {
+				tc.cycles = map[string][]string{}
+			}
+			if !reflect.DeepEqual(cycles, tc.cycles) {
+				t.Errorf("%s %v: expected cycles %v got %v", tc.expr, tc.fields, tc.cycles, cycles)
+			}
+		}
+
+		if _, _, err := scope.Traverse("list.val", nil, 10, pnormalLoadConfig); err == nil {
+			t.Error("no error traversing an int")
+		}
+		if _, _, err := scope.Traverse("list", []string{"val"}, 10, pnormalLoadConfig); err == nil {
+			t.Error("no error following a field that is not a pointer")
+		}
+	})
+}
+
+func TestTypeConversions(t *testing.T) {
+	testcases := []struct {
+		expr     string
+		expected string
+		err      error
+	}{
+		{"int8(i)", "44", nil},
+		{"int(f)", "2", nil},
+		{"float32(f)", "2.75", nil},
+		{"float64(c) > 36", "true", nil},
+		{"celsius(f)", "2.75", nil},
+		{"string(b) == \"hello\"", "true", nil},
+		{"string(b[1:3])", "\"el\"", nil},
+		{"string(r)", "\"héllo\"", nil},
+		{"string(n) == \"alice\"", "true", nil},
+		{"name(\"bob\")", "\"bob\"", nil},
+		{"bytes(b)", "main.bytes len: 5, cap: 5, [104,101,108,108,111]", nil},
+		{"vec(pt)", "main.vec {X: 1, Y: 2}", nil},
+		{"(*vec)(ppt).Y", "2", nil},
+		{"point(v).X", "3", nil},
+		{"*(*celsius)(&f)", "2.75", nil},
+		{"*(*float64)(&c) > 36", "true", nil},
+		{"(*int)(&f)", "", fmt.Errorf("can not convert \"&f\" to *int")},
+		{"celsius(pt)", "", fmt.Errorf("can not convert \"pt\" to float64")},
+
+		// indexing and slicing, including values that are not in memory
+		{"string(b)[1:3]", "\"el\"", nil},
+		{"string(b)[0]", "104", nil},
+		{"\"hello\"[1:]", "\"ello\"", nil},
+		{"b[i-298]", "108", nil},
+		{"b[uint8(1)]", "101", nil},
+		{"b[1:3:4]", "[]uint8 len: 2, cap: 3, [101,108]", nil},
+		{"b[5:]", "[]uint8 len: 0, cap: 0, []", nil},
+		{"b[1:3:6]", "", fmt.Errorf("index out of bounds")},
+		{"string(b)[1:2:3]", "", fmt.Errorf("3-index slice of string")},
+	}
+
+	protest.AllowRecording(t)
+	withTestProcess("typeconv", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue() returned an error")
+		for _, tc := range testcases {
+			v, err := evalVariable(p, tc.expr, pnormalLoadConfig)
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("%s: expected error %q got %v", tc.expr, tc.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.expr, err)
+				continue
+			}
+			if ss := api.ConvertVar(v).SinglelineString(); ss != tc.expected {
+				t.Errorf("%s: expected %s got %s", tc.expr, tc.expected, ss)
+			}
+		}
+	})
+}
+
+func TestVariableFormats(t *testing.T) {
+	testcases := []struct {
+		expr      string
+		intFormat byte
+		raw       bool
+		expected  string
+	}{
+		{"i1", 'x', false, "0x1"},
+		{"ni8", 'x', false, "-0x5"},
+		{"i2", 'b', false, "0b10"},
+		{"i2", 'o', false, "02"},
+		{"i2", 'd', false, "2"},
+		{"runeslice", 'c', false, "[]int32 len: 4, cap: 4, ['t','è','s','t']"},
+		{"str1", 0, true, "01234567890"},
+		{"str1", 'x', false, `"01234567890"`},
+		{"s1", 0, true, "[]string len: 5, cap: 5, [one,two,three,four,five]"},
+	}
+
+	protest.AllowRecording(t)
+	withTestProcess("testvariables2", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue() returned an error")
+		for _, tc := range testcases {
+			cfg := pnormalLoadConfig
+			cfg.IntFormat = tc.intFormat
+			cfg.RawStrings = tc.raw
+			v, err := evalVariable(p, tc.expr, cfg)
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.expr, err)
+				continue
+			}
+			if ss := api.ConvertVar(v).SinglelineString(); ss != tc.expected {
+				t.Errorf("%s: expected %s got %s", tc.expr, tc.expected, ss)
+			}
+		}
+	})
+}
+
+func TestUnsafeOperations(t *testing.T) {
+	testcases := []struct {
+		expr     string
+		expected string
+		err      error
+	}{
+		{"(*point)(unsafe.Pointer(ppt)).Y", "2", nil},
+		{"(*vec)(unsafe.Pointer(&pt)).X", "1", nil},
+		{"*(*int)(unsafe.Pointer(uintptr(unsafe.Pointer(ppt)) + 8))", "2", nil},
+		{"*(*int)(unsafe.Add(unsafe.Pointer(ppt), 8))", "2", nil},
+		{"uintptr(unsafe.Pointer(ppt)) == uintptr(unsafe.Pointer(&pt))", "true", nil},
+		{"unsafe.Sizeof(pt)", "16", nil},
+		{"unsafe.Sizeof(b)", "24", nil},
+		{"unsafe.Offsetof(pt.Y)", "8", nil},
+		{"unsafe.Offsetof(ppt.Y)", "8", nil},
+		{"unsafe.Add(ppt, 8)", "", fmt.Errorf("invalid argument ppt (type *main.point) for unsafe.Add")},
+		{"unsafe.Offsetof(i)", "", fmt.Errorf("invalid argument i for unsafe.Offsetof")},
+		{"unsafe.Alignof(i)", "", fmt.Errorf("unsafe.Alignof not supported")},
+	}
+
+	protest.AllowRecording(t)
+	withTestProcess("typeconv", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue() returned an error")
+		for _, tc := range testcases {
+			v, err := evalVariable(p, tc.expr, pnormalLoadConfig)
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Errorf("%s: expected error %q got %v", tc.expr, tc.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.expr, err)
+				continue
+			}
+			if ss := api.ConvertVar(v).SinglelineString(); ss != tc.expected {
+				t.Errorf("%s: expected %s got %s", tc.expr, tc.expected, ss)
+			}
+		}
+	})
+}
+
+func TestRegisterVariables(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testvariables2", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue() returned an error")
+		regs, err := p.CurrentThread().Registers(false)
+		assertNoError(err, t, "Registers()")
+		scope, err := proc.GoroutineScope(p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope()")
+
+		testcases := []struct {
+			expr     string
+			expected string
+		}{
+			{"$pc", fmt.Sprintf("%d", regs.PC())},
+			{"$rip", fmt.Sprintf("%d", regs.PC())},
+			{"$RSP", fmt.Sprintf("%d", regs.SP())},
+			{"$rbp", fmt.Sprintf("%d", regs.BP())},
+			{fmt.Sprintf("$pc == %#x", regs.PC()), "true"},
+			{"$rsp + 8 > $rsp", "true"},
+		}
+		for _, tc := range testcases {
+			v, err := scope.EvalExpression(tc.expr, pnormalLoadConfig)
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.expr, err)
+				continue
+			}
+			if ss := api.ConvertVar(v).SinglelineString(); ss != tc.expected {
+				t.Errorf("%s: expected %s got %s", tc.expr, tc.expected, ss)
+			}
+		}
+
+		_, err = scope.EvalExpression("$foo", pnormalLoadConfig)
+		if err == nil || err.Error() != "unknown register $foo" {
+			t.Errorf("$foo: wrong error %v", err)
+		}
+	})
+}
+
+func TestClosureVariables(t *testing.T) {
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 23, -1, 0, 0, ""}) {
+		t.Skip("closure offsets of captured variables not emitted before go1.23")
+	}
+	protest.AllowRecording(t)
+	withTestProcess("closurevars", t, func(p proc.Process, fixture protest.Fixture) {
+		assertNoError(proc.Continue(p), t, "Continue() returned an error")
+		assertVariable := func(expr, expected string) {
+			v, err := evalVariable(p, expr, pnormalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", expr))
+			if ss := api.ConvertVar(v).SinglelineString(); ss != expected {
+				t.Errorf("%s: expected %s got %s", expr, expected, ss)
+			}
+		}
+		assertVariable("f", `main.main.func1 {x: 1, y: "hello"}`)
+		assertVariable("f.y", `"hello"`)
+		assertNoError(setVariable(p, "f.x", "5"), t, "SetVariable(f.x)")
+		assertVariable("x", "5")
+		if _, err := evalVariable(p, "f.z", pnormalLoadConfig); err == nil {
+			t.Errorf("f.z: expected error")
+		}
+	})
+}
+
+func TestGenericVariables(t *testing.T) {
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 18, -1, 0, 0, ""}) {
+		t.Skip("generics not supported before go1.18")
+	}
+	testcases := [][]struct {
+		expr     string
+		expected string
+	}{
+		{
+			{"x", "1"},
+			{"y", "[]int len: 2, cap: 2, [1,1]"},
+			{"p", `main.pair[string,int] {k: "a", v: 1}`},
+			{"px", "*1"},
+			{"m", `map[string]int ["a": 1, ]`},
+		},
+		{
+			{"x", `"hello"`},
+			{"p.v", `"hello"`},
+			{`m["a"]`, `"hello"`},
+		},
+		{
+			{"x", "main.point {X: 1, Y: 2}"},
+			{"y[1].Y", "2"},
+			{"px.X", "1"},
+		},
+		{
+			{"x", "*main.point {X: 3, Y: 4}"},
+			{"p", "main.pair[string,*main.point] {k: \"a\", v: *main.point {X: 3, Y: 4}}"},
+		},
+	}
+	protest.AllowRecording(t)
+	withTestProcess("genericvars", t, func(p proc.Process, fixture protest.Fixture) {
+		for _, tcs := range testcases {
+			assertNoError(proc.Continue(p), t, "Continue() returned an error")
+			for _, tc := range tcs {
+				v, err := evalVariable(p, tc.expr, pnormalLoadConfig)
+				assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", tc.expr))
+				if ss := api.ConvertVar(v).SinglelineString(); ss != tc.expected {
+					t.Errorf("%s: expected %s got %s", tc.expr, tc.expected, ss)
+				}
+			}
+			scope, err := proc.GoroutineScope(p.CurrentThread())
+			assertNoError(err, t, "GoroutineScope")
+			args, err := scope.FunctionArguments(pnormalLoadConfig)
+			assertNoError(err, t, "FunctionArguments")
+			for _, arg := range args {
+				if arg.Name == ".dict" {
+					t.Errorf("dictionary listed as an argument")
+				}
+			}
+		}
+	})
+}
+
 func TestUnsafePointer(t *testing.T) {
 	protest.AllowRecording(t)
 	withTestProcess("testvariables2", t, func(p proc.Process, fixture protest.Fixture) {
@@ -972,6 +1483,11 @@ func TestConstants(t *testing.T) {
 		{"bitOne", true, "2", "", "main.BitFieldType", nil},
 		{"constTwo", true, "2", "", "main.ConstType", nil},
 		{"pkg.SomeConst", true, "2", "", "int", nil},
+		{"g", true, "flagHigh|flagLow", "", "main.FlagType", nil},
+		{"h", true, "flagHigh", "", "main.FlagType", nil},
+		{"flagHigh", true, "9223372036854775808", "", "main.FlagType", nil},
+		{"h == flagHigh", false, "true", "", "", nil},
+		{"constTwo + 1", false, "constThree", "", "main.ConstType", nil},
 	}
 	ver, _ := goversion.Parse(runtime.Version())
 	if ver.Major > 0 && !ver.AfterOrEqual(goversion.GoVersion{1, 10, -1, 0, 0, ""}) {